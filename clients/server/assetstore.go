@@ -0,0 +1,171 @@
+// assetstore.go — Optional on-disk persistence for the asset manager.
+//
+// Layout under the directory given to openAssetManager:
+//
+//	index.json   {"<id>": {"name": "Inter.ttf", "mime": "font/ttf"}, ...}
+//	<id>         raw asset bytes
+//
+// index.json is the source of truth: a blob is written before its index
+// entry and removed after it, so a crash leaves at worst an orphaned blob,
+// which the next startup deletes.
+//
+// IDs are kept as found, so presets saved against random IDs from before
+// content deduplication still resolve. If such a dir holds the same bytes
+// under several IDs, all of them load and new uploads of that content
+// return the lowest ID.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const assetIndexFile = "index.json"
+
+// assetMeta is an asset's index entry.
+type assetMeta struct {
+	Name string `json:"name"`
+	Mime string `json:"mime"`
+}
+
+// openAssetManager loads the assets persisted in dir (creating it if
+// needed) and returns a manager that keeps dir in sync.
+func openAssetManager(dir string) (*assetManager, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("asset dir: %w", err)
+	}
+	am := newAssetManager()
+	am.dir = dir
+
+	index := make(map[string]assetMeta)
+	raw, err := os.ReadFile(filepath.Join(dir, assetIndexFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("asset index: %w", err)
+	default:
+		if err := json.Unmarshal(raw, &index); err != nil {
+			return nil, fmt.Errorf("asset index %s: %w", filepath.Join(dir, assetIndexFile), err)
+		}
+	}
+
+	dirty := false
+	for id, meta := range index {
+		if !validAssetID(id) {
+			slog.Warn("invalid asset ID in index, dropping", "id", id)
+			delete(index, id)
+			dirty = true
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, id))
+		if err != nil {
+			slog.Warn("asset missing from data dir, dropping", "id", id, "name", meta.Name, "err", err)
+			delete(index, id)
+			dirty = true
+			continue
+		}
+		a := newAsset(meta.Name, data, meta.Mime)
+		am.assets[id] = a
+		if cur, ok := am.bySum[a.sum]; !ok || id < cur {
+			am.bySum[a.sum] = id
+		}
+	}
+
+	// Remove blobs with no index entry (an add or remove interrupted mid-way).
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("asset dir: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if _, ok := index[name]; ok || name == assetIndexFile || e.IsDir() {
+			continue
+		}
+		slog.Warn("removing orphaned asset blob", "file", name)
+		os.Remove(filepath.Join(dir, name))
+	}
+
+	if dirty {
+		if err := am.saveIndexLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return am, nil
+}
+
+// persistLocked writes a new asset's blob, then its index entry.
+// Callers hold am.mu.
+func (am *assetManager) persistLocked(id string, a *asset) error {
+	if am.dir == "" {
+		return nil
+	}
+	if err := writeFileAtomic(filepath.Join(am.dir, id), a.Data); err != nil {
+		return fmt.Errorf("persist asset: %w", err)
+	}
+	am.assets[id] = a
+	if err := am.saveIndexLocked(); err != nil {
+		delete(am.assets, id)
+		os.Remove(filepath.Join(am.dir, id))
+		return err
+	}
+	return nil
+}
+
+// unpersistLocked drops an asset's index entry, then its blob. The asset
+// must already be removed from am.assets. Callers hold am.mu.
+func (am *assetManager) unpersistLocked(id string) error {
+	if am.dir == "" {
+		return nil
+	}
+	if err := am.saveIndexLocked(); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(am.dir, id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("could not remove asset blob", "id", id, "err", err)
+	}
+	return nil
+}
+
+// saveIndexLocked rewrites index.json from am.assets. Callers hold am.mu.
+func (am *assetManager) saveIndexLocked() error {
+	index := make(map[string]assetMeta, len(am.assets))
+	for id, a := range am.assets {
+		index[id] = assetMeta{Name: a.Name, Mime: a.Mime}
+	}
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(am.dir, assetIndexFile), b); err != nil {
+		return fmt.Errorf("save asset index: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file beside path and renames it
+// into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// auth.go — Optional access token for the API.
+//
+// With --token set, every /api/ request must carry the token, either as
+// "Authorization: Bearer <token>" (scripts) or in the session cookie the
+// editor receives by opening "/?token=<token>" once. The browser opened by
+// serve gets that URL; the static UI itself needs no token.
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// tokenCookie holds the access token for the embedded editor.
+const tokenCookie = "gostencil_token"
+
+// requireToken guards /api/ routes with token and exchanges a valid
+// ?token= on any other path for the session cookie.
+func requireToken(token string, next http.Handler) http.Handler {
+	valid := func(got string) bool {
+		return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			if q := r.URL.Query(); q.Has("token") {
+				if !valid(q.Get("token")) {
+					http.Error(w, "invalid token", http.StatusUnauthorized)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{
+					Name:     tokenCookie,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+				// Drop the token from the address bar and history.
+				q.Del("token")
+				u := *r.URL
+				u.RawQuery = q.Encode()
+				http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			if c, err := r.Cookie(tokenCookie); err == nil {
+				got = c.Value
+			}
+		}
+		if !valid(got) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gostencil"`)
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing or invalid access token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// TestRequireToken sends requests through the server's handler with
+// --token set.
+func TestRequireToken(t *testing.T) {
+	const token = "s3cret"
+	web := fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}
+	h := newTestServer(t).handler(web, token, nil)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string // Authorization
+		cookie string // session cookie value
+		want   int
+	}{
+		{"missing token", "/api/canvas-presets", "", "", http.StatusUnauthorized},
+		{"wrong token", "/api/canvas-presets", "Bearer nope", "", http.StatusUnauthorized},
+		{"token prefix", "/api/canvas-presets", "Bearer s3c", "", http.StatusUnauthorized},
+		{"not a bearer token", "/api/canvas-presets", "Basic " + token, "", http.StatusUnauthorized},
+		{"bearer token", "/api/canvas-presets", "Bearer " + token, "", http.StatusOK},
+		{"session cookie", "/api/canvas-presets", "", token, http.StatusOK},
+		{"wrong session cookie", "/api/canvas-presets", "", "nope", http.StatusUnauthorized},
+		{"OpenAPI document", "/api/openapi.json", "", "", http.StatusUnauthorized},
+
+		{"editor", "/", "", "", http.StatusOK},
+		{"health check", "/healthz", "", "", http.StatusOK},
+		{"metrics", "/metrics", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: tokenCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+// TestRequireTokenCookieExchange opens the editor with ?token= as the
+// browser serve launches does, and uses the cookie it gets for the API.
+func TestRequireTokenCookieExchange(t *testing.T) {
+	const token = "s3cret"
+	web := fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}
+	h := newTestServer(t).handler(web, token, nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=nope", nil))
+	if rec.Code != http.StatusUnauthorized || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("wrong ?token=: status %d, cookies %v; want 401 and none", rec.Code, rec.Result().Cookies())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+token+"&view=grid", nil))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("?token=: status %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/?view=grid" {
+		t.Errorf("redirect to %q, want the token dropped from the URL", loc)
+	}
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == tokenCookie {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatal("no session cookie")
+	}
+	if !session.HttpOnly || session.SameSite != http.SameSiteStrictMode || session.Path != "/" {
+		t.Errorf("cookie %+v: want HttpOnly, SameSite=Strict, Path=/", session)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/canvas-presets", nil)
+	req.AddCookie(session)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("API with the session cookie: status %d, want 200", rec.Code)
+	}
+}
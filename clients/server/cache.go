@@ -0,0 +1,148 @@
+// cache.go — Render cache and ETags for POST /api/render.
+//
+// A render's key hashes the canonical preset and data JSON together with
+// the content digest of every asset they mention or carry inline, so identical requests
+// share an ETag (If-None-Match → 304) and recent PNGs are served from a
+// small LRU without rendering again.
+package server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"slices"
+	"sync"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// renderCacheMaxBytes caps the PNG bytes held by the render cache.
+const renderCacheMaxBytes = 64 << 20
+
+// cachedRender is one cached /api/render result.
+type cachedRender struct {
+	key      string
+	png      []byte
+	bounds   image.Rectangle
+	warnings []template.RenderWarning
+	order    []string // component IDs in paint order
+	profile  *template.RenderProfile
+	used     []template.AssetUse // assets the render read
+	assets   []string            // asset IDs the render depends on
+}
+
+// renderCache is a bounded LRU of recent renders.
+type renderCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	bytes      int
+	order      *list.List // front = most recently used
+	entries    map[string]*list.Element
+}
+
+// newRenderCache returns a cache of up to n entries; n ≤ 0 disables it.
+func newRenderCache(n int) *renderCache {
+	return &renderCache{maxEntries: n, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *renderCache) get(key string) (*cachedRender, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedRender), true
+}
+
+func (c *renderCache) put(e *cachedRender) {
+	if c.maxEntries <= 0 || len(e.png) > renderCacheMaxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.removeLocked(el)
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	c.bytes += len(e.png)
+	for c.order.Len() > c.maxEntries || c.bytes > renderCacheMaxBytes {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// invalidate drops every entry that depends on asset id.
+func (c *renderCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if slices.Contains(el.Value.(*cachedRender).assets, id) {
+			c.removeLocked(el)
+		}
+		el = next
+	}
+}
+
+func (c *renderCache) removeLocked(el *list.Element) {
+	e := c.order.Remove(el).(*cachedRender)
+	delete(c.entries, e.key)
+	c.bytes -= len(e.png)
+}
+
+// renderKey hashes a request's canonical JSON and the digests of the
+// assets it references. It returns the key and those asset IDs, or "" when
+// the request is not valid JSON (the render reports that).
+func (s *srv) renderKey(req renderRequest) (string, []string) {
+	h := sha256.New()
+	var assets []string
+	for _, raw := range []json.RawMessage{req.Preset, req.Data} {
+		var v any
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return "", nil
+			}
+		}
+		canon, _ := json.Marshal(v) // map keys come out sorted
+		h.Write(canon)
+		h.Write([]byte{0})
+		assets = s.collectAssetRefs(v, assets)
+	}
+
+	hashInlineAssets(h, req.Assets)
+	fmt.Fprintf(h, "%t %t\x00", req.ShowMissingAssets, req.StrictAssets)
+	fmt.Fprintf(h, "%q %t %d\x00", req.OnlyComponents, req.Crop, req.CropPadding)
+
+	slices.Sort(assets)
+	assets = slices.Compact(assets)
+	for _, id := range assets {
+		if a, ok := s.assets.get(id); ok {
+			h.Write([]byte(id))
+			h.Write(a.sum[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), assets
+}
+
+// collectAssetRefs appends every string in v that names a stored asset.
+func (s *srv) collectAssetRefs(v any, ids []string) []string {
+	switch v := v.(type) {
+	case string:
+		if _, ok := s.assets.get(v); ok {
+			ids = append(ids, v)
+		}
+	case []any:
+		for _, e := range v {
+			ids = s.collectAssetRefs(e, ids)
+		}
+	case map[string]any:
+		for _, e := range v {
+			ids = s.collectAssetRefs(e, ids)
+		}
+	}
+	return ids
+}
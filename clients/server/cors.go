@@ -0,0 +1,86 @@
+// cors.go — Cross-origin access to the API (--cors-origin).
+//
+// Without the flag the server sends no CORS headers, so browsers only let
+// the embedded editor call the API. Each --cors-origin adds an origin
+// ("https://app.example.com") allowed to call /api/ routes; "*" allows any.
+// Tokens travel in the Authorization header: the session cookie is
+// SameSite=Strict and is never sent cross-origin.
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, If-None-Match, X-Request-ID"
+	corsExposeHeaders = "Content-Disposition, ETag, Location, Retry-After, X-GoStencil-Cache, X-GoStencil-Profile, X-GoStencil-Warnings, X-Request-ID"
+	corsMaxAge        = "600" // seconds browsers may cache a preflight
+)
+
+// originList is a repeatable flag of allowed origins. A value may also hold
+// several comma-separated origins.
+type originList []string
+
+func (o *originList) String() string { return strings.Join(*o, ",") }
+
+func (o *originList) Set(v string) error {
+	for _, origin := range strings.Split(v, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+				return fmt.Errorf("invalid origin %q (want scheme://host[:port] or *)", origin)
+			}
+		}
+		*o = append(*o, origin)
+	}
+	return nil
+}
+
+// cors answers preflight requests and adds CORS headers to /api/ responses
+// for allowed origins. A preflight from any other origin gets 403; other
+// requests from it proceed without CORS headers, so the browser withholds
+// the response.
+func cors(origins []string, next http.Handler) http.Handler {
+	anyOrigin := slices.Contains(origins, "*")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !anyOrigin && !slices.Contains(origins, origin) {
+			if preflight {
+				writeError(w, http.StatusForbidden, "FORBIDDEN", "origin "+origin+" is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestCORS sends preflight and actual requests through the server's
+// handler with and without --cors-origin.
+func TestCORS(t *testing.T) {
+	const (
+		allowed = "https://app.example.com"
+		other   = "https://evil.example.com"
+	)
+	web := fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}
+
+	type want struct {
+		status  int
+		allow   string // Access-Control-Allow-Origin, "" for none
+		methods bool   // preflight headers present
+		expose  bool   // Access-Control-Expose-Headers present
+	}
+	tests := []struct {
+		name      string
+		origins   []string
+		token     string
+		origin    string
+		preflight bool
+		path      string
+		auth      string
+		want      want
+	}{
+		// No route takes OPTIONS, so it falls through to the static files.
+		{name: "no flag preflight", origin: allowed, preflight: true,
+			want: want{status: http.StatusNotFound}},
+		{name: "no flag request", origin: allowed,
+			want: want{status: http.StatusOK}},
+
+		{name: "allowed preflight", origins: []string{allowed}, origin: allowed, preflight: true,
+			want: want{status: http.StatusNoContent, allow: allowed, methods: true}},
+		{name: "allowed request", origins: []string{allowed}, origin: allowed,
+			want: want{status: http.StatusOK, allow: allowed, expose: true}},
+		{name: "second allowed origin", origins: []string{other, allowed}, origin: allowed,
+			want: want{status: http.StatusOK, allow: allowed, expose: true}},
+
+		{name: "disallowed preflight", origins: []string{allowed}, origin: other, preflight: true,
+			want: want{status: http.StatusForbidden}},
+		{name: "disallowed request", origins: []string{allowed}, origin: other,
+			want: want{status: http.StatusOK}},
+
+		{name: "wildcard preflight", origins: []string{"*"}, origin: other, preflight: true,
+			want: want{status: http.StatusNoContent, allow: "*", methods: true}},
+		{name: "wildcard request", origins: []string{"*"}, origin: other,
+			want: want{status: http.StatusOK, allow: "*", expose: true}},
+
+		{name: "same-origin request", origins: []string{allowed},
+			want: want{status: http.StatusOK}},
+		{name: "static files", origins: []string{allowed}, origin: allowed, path: "/",
+			want: want{status: http.StatusOK}},
+
+		// Browsers send preflights without credentials, so they must not
+		// need the token; the 401 for a missing token stays readable.
+		{name: "token preflight", origins: []string{allowed}, token: "s3cret", origin: allowed, preflight: true,
+			want: want{status: http.StatusNoContent, allow: allowed, methods: true}},
+		{name: "token missing", origins: []string{allowed}, token: "s3cret", origin: allowed,
+			want: want{status: http.StatusUnauthorized, allow: allowed, expose: true}},
+		{name: "token sent", origins: []string{allowed}, token: "s3cret", origin: allowed, auth: "Bearer s3cret",
+			want: want{status: http.StatusOK, allow: allowed, expose: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestServer(t).handler(web, tt.token, tt.origins)
+			path := tt.path
+			if path == "" {
+				path = "/api/canvas-presets"
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.preflight {
+				req.Method = http.MethodOptions
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				req.Header.Set("Access-Control-Request-Headers", "authorization")
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			got := rec.Header()
+			if rec.Code != tt.want.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.want.status)
+			}
+			if allow := got.Get("Access-Control-Allow-Origin"); allow != tt.want.allow {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", allow, tt.want.allow)
+			}
+			if tt.want.methods {
+				if m := got.Get("Access-Control-Allow-Methods"); !strings.Contains(m, "POST") || !strings.Contains(m, "DELETE") {
+					t.Errorf("Access-Control-Allow-Methods %q", m)
+				}
+				if h := got.Get("Access-Control-Allow-Headers"); !strings.Contains(h, "Authorization") || !strings.Contains(h, "Content-Type") {
+					t.Errorf("Access-Control-Allow-Headers %q", h)
+				}
+			} else if got.Get("Access-Control-Allow-Methods") != "" || got.Get("Access-Control-Allow-Headers") != "" {
+				t.Errorf("unexpected preflight headers %v", got)
+			}
+			if expose := got.Get("Access-Control-Expose-Headers"); (expose != "") != tt.want.expose {
+				t.Errorf("Access-Control-Expose-Headers %q, want present: %v", expose, tt.want.expose)
+			}
+			if tt.want.allow != "" && !slices.Contains(got.Values("Vary"), "Origin") {
+				t.Errorf("Vary %q, want Origin", got.Values("Vary"))
+			}
+			if len(tt.origins) == 0 {
+				for name := range got {
+					if strings.HasPrefix(name, "Access-Control-") {
+						t.Errorf("%s sent without --cors-origin", name)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestOriginListSet parses repeated and comma-separated --cors-origin
+// values and rejects anything that is not an origin.
+func TestOriginListSet(t *testing.T) {
+	var o originList
+	for _, v := range []string{"https://a.example.com/", "http://localhost:5173, *"} {
+		if err := o.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	if want := []string{"https://a.example.com", "http://localhost:5173", "*"}; !slices.Equal(o, want) {
+		t.Errorf("origins %q, want %q", o, want)
+	}
+	for _, bad := range []string{"a.example.com", "ftp://a.example.com", "https://a.example.com/app", "https://a.example.com?x=1", "https://"} {
+		if err := new(originList).Set(bad); err == nil {
+			t.Errorf("Set(%q) accepted an invalid origin", bad)
+		}
+	}
+}
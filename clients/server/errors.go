@@ -0,0 +1,98 @@
+// errors.go — JSON error envelope for API responses.
+//
+//	{"error": {"code": "BAD_PRESET", "message": "...", "component": "title"}}
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// apiError is an error with the HTTP status and machine-readable code it
+// should be reported with.
+type apiError struct {
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Component string `json:"component,omitempty"` // component ID, for render failures
+
+	RetryAfter int `json:"-"` // seconds; sent as Retry-After when > 0
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+// errorf builds an apiError with a formatted message.
+func errorf(status int, code, format string, args ...any) *apiError {
+	return &apiError{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError sends a JSON error body with the given status and code.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIError(w, &apiError{Status: status, Code: code, Message: message})
+}
+
+func writeAPIError(w http.ResponseWriter, e *apiError) {
+	metrics.countError(e.Code)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(map[string]*apiError{"error": e})
+}
+
+// writeErr reports any error: apiErrors as themselves, body-limit errors
+// as 413, assets missing under strictAssets and component render failures
+// as 422 naming the component, and anything else as a 500.
+func writeErr(w http.ResponseWriter, err error) {
+	var (
+		ae       *apiError
+		tooLarge *http.MaxBytesError
+		ce       *template.ComponentError
+		missing  *template.AssetError
+	)
+	switch {
+	case errors.As(err, &ae):
+		writeAPIError(w, ae)
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "TOO_LARGE",
+			fmt.Sprintf("request body exceeds %s", (*byteSize)(&tooLarge.Limit)))
+	case errors.As(err, &missing):
+		e := &apiError{Status: http.StatusUnprocessableEntity, Code: "MISSING_ASSET", Message: err.Error()}
+		if errors.As(err, &ce) {
+			e.Component = ce.ID
+		}
+		writeAPIError(w, e)
+	case errors.As(err, &ce):
+		writeAPIError(w, &apiError{
+			Status:    http.StatusUnprocessableEntity,
+			Code:      "RENDER_FAILED",
+			Message:   err.Error(),
+			Component: ce.ID,
+		})
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+	}
+}
+
+// writeBodyError reports a failure to read or decode the request body:
+// 413 when the size limit was hit, 400 otherwise.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeErr(w, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+}
+
+// writeNotFound reports a missing asset, job, or preset.
+func writeNotFound(w http.ResponseWriter, what, id string) {
+	writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("%s %q not found", what, id))
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+	"golang.org/x/image/webp"
+)
+
+// newTestServer is a memory-only server with one render slot, no render
+// cache and one export worker.
+func newTestServer(t *testing.T) *srv {
+	t.Helper()
+	s := &srv{
+		assets:        newAssetManager(),
+		presets:       newPresetStore(),
+		tmpDir:        t.TempDir(),
+		maxBody:       20 << 20,
+		maxUpload:     50 << 20,
+		limiter:       newRenderLimiter(1),
+		renderTimeout: 30 * time.Second,
+		cache:         newRenderCache(0),
+		images:        template.NewImageCache(template.DefaultImageCacheBytes),
+	}
+	s.jobs = newJobQueue(s.tmpDir, 1, time.Minute, s.render)
+	t.Cleanup(s.jobs.closeStreams)
+	return s
+}
+
+// testPNG is a w×h PNG of horizontal color bands.
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(255 * y / h), uint8(255 * x / w), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestExportGSPresetsRoundTrip exports a preset whose assets are stored
+// on the server, loads the bundle with template.LoadPreset and checks
+// that it renders as the server renders the original.
+func TestExportGSPresetsRoundTrip(t *testing.T) {
+	s := newTestServer(t)
+	bg, _, err := s.assets.add("backdrop.png", testPNG(t, 64, 36), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sticker, _, err := s.assets.add("sticker.png", testPNG(t, 16, 16), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.assets.add("unused.png", testPNG(t, 8, 8), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The title equals an asset ID: only reference fields may change.
+	preset := `{
+  "canvas": {"width": 320, "height": 180},
+  "background": {"type": "image", "source": "` + bg + `"},
+  "font": {},
+  "components": [
+    {"id": "card", "x": 0.1, "y": 0.1, "width": 0.5, "height": 0.5, "padding": 8,
+     "style": {"backgroundImage": "` + sticker + `", "fontSize": 18, "color": "#ffffff"},
+     "defaults": {"visible": true, "title": "` + sticker + `"}}
+  ]
+}`
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/export/gspresets", strings.NewReader(`{"preset": `+preset+`}`))
+	s.handleExportGSPresets(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", rec.Code, rec.Body)
+	}
+
+	bundle, err := template.LoadPresetFromReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := template.ReferencedAssets(bundle.Preset); len(got) != 2 || !strings.HasPrefix(got[0], "assets/") || !strings.HasPrefix(got[1], "assets/") {
+		t.Errorf("bundle references %q, want two assets/ paths", got)
+	}
+	if title := bundle.Preset.Components[0].Defaults.Title; title != sticker {
+		t.Errorf("title rewritten to %q, want %q", title, sticker)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.gspresets")
+	if err := os.WriteFile(path, rec.Body.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := template.LoadPreset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := template.NewRendererForFont(loaded.Preset.Font, loaded.Resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderer.RenderPreset(loaded.Preset, template.MergeData(loaded.Preset, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := renderer.Warnings(); len(w) > 0 {
+		t.Errorf("bundle render warnings: %v", w)
+	}
+
+	want, err := s.render(context.Background(), renderRequest{Preset: []byte(preset)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.release()
+	if diff := template.CompareImages(want.img, got, template.CompareOptions{}); !diff.Match {
+		t.Errorf("bundle render differs from the server's: %d pixels, max delta %d", diff.DiffPixels, diff.MaxDelta)
+	}
+}
+
+// TestExportMediaWebP exports a render as WebP and checks that it decodes
+// to the server's render, and that an unknown format lists webp.
+func TestExportMediaWebP(t *testing.T) {
+	s := newTestServer(t)
+	preset := `{"canvas": {"width": 120, "height": 80}, "background": {"type": "transparent"}, "font": {},
+  "components": [{"id": "t", "x": 0.1, "y": 0.1, "width": 0.8, "height": 0.8,
+    "style": {"backgroundColor": "#3366cc80", "cornerRadius": 10, "fontSize": 16, "color": "#ffffff"},
+    "defaults": {"visible": true, "title": "WebP"}}]}`
+
+	export := func(format string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/export/"+format, strings.NewReader(`{"preset": `+preset+`}`))
+		req.SetPathValue("format", format)
+		s.handleExportMedia(rec, req)
+		return rec
+	}
+
+	rec := export("webp")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if typ := rec.Header().Get("Content-Type"); typ != "image/webp" {
+		t.Errorf("Content-Type %q, want image/webp", typ)
+	}
+	got, err := webp.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.render(context.Background(), renderRequest{Preset: []byte(preset)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.release()
+	if diff := template.CompareImages(want.img, got, template.CompareOptions{Tolerance: 1}); !diff.Match {
+		t.Errorf("WebP differs from the render: %d pixels, max delta %d", diff.DiffPixels, diff.MaxDelta)
+	}
+
+	rec = export("tiff")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "webp") {
+		t.Errorf("tiff: status %d, body %s; want 400 listing webp", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,120 @@
+// grid.go — Render several requests and stitch them into one PNG.
+//
+//	POST /api/compose/grid  {"renders": [{label?, preset, data, assets}, …],
+//	                         cols?, rows?, gap?, fit?, …} → image/png
+//
+// Each render is an ordinary render request, so A/B variants of one
+// preset can be reviewed side by side. Layout is template.ComposeGrid's.
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// maxGridRenders bounds the renders one grid request may ask for.
+const maxGridRenders = 64
+
+// gridRequest is the body of POST /api/compose/grid.
+type gridRequest struct {
+	Renders    []gridCell `json:"renders"`
+	Cols       int        `json:"cols,omitempty"`
+	Rows       int        `json:"rows,omitempty"`
+	CellWidth  int        `json:"cellWidth,omitempty"`
+	CellHeight int        `json:"cellHeight,omitempty"`
+	Gap        *int       `json:"gap,omitempty"`
+	Background string     `json:"background,omitempty"`
+	Fit        string     `json:"fit,omitempty"`
+	LabelSize  float64    `json:"labelSize,omitempty"`
+	LabelColor string     `json:"labelColor,omitempty"`
+}
+
+// gridCell is one render of a grid and its caption.
+type gridCell struct {
+	Label string `json:"label,omitempty"`
+	renderRequest
+}
+
+func (s *srv) handleComposeGrid(w http.ResponseWriter, r *http.Request) {
+	var req gridRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.Renders) == 0 || len(req.Renders) > maxGridRenders {
+		writeError(w, http.StatusBadRequest, "BAD_GRID", fmt.Sprintf("\"renders\" must hold 1 to %d render requests", maxGridRenders))
+		return
+	}
+
+	// Renders run one after another, each in its own limiter slot, and
+	// together may cover no more pixels than the grid itself may.
+	side := cmp.Or(s.maxCanvas, template.MaxCanvasSize)
+	limit := int64(side) * int64(side)
+	var (
+		pixels   int64
+		images   = make([]image.Image, 0, len(req.Renders))
+		labels   = make([]string, 0, len(req.Renders))
+		warnings []template.RenderWarning
+	)
+	for i, cell := range req.Renders {
+		res, err := s.render(r.Context(), cell.renderRequest)
+		if err != nil {
+			var ae *apiError
+			if errors.As(err, &ae) {
+				ae.Message = fmt.Sprintf("render %d: %s", i+1, ae.Message)
+			}
+			writeErr(w, err)
+			return
+		}
+		defer res.release()
+		b := res.img.Bounds()
+		if pixels += int64(b.Dx()) * int64(b.Dy()); pixels > limit {
+			writeError(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", fmt.Sprintf("render %d: the renders cover more pixels than a %d×%d canvas", i+1, side, side))
+			return
+		}
+		images = append(images, res.img)
+		labels = append(labels, cell.Label)
+		for _, rw := range res.warnings {
+			rw.Message = fmt.Sprintf("render %d: %s", i+1, rw.Message)
+			warnings = append(warnings, rw)
+		}
+	}
+
+	sheet, err := template.ComposeGrid(images, template.GridOptions{
+		Cols: req.Cols, Rows: req.Rows,
+		CellWidth: req.CellWidth, CellHeight: req.CellHeight,
+		Gap:        req.Gap,
+		Background: req.Background,
+		Fit:        req.Fit,
+		Labels:     labels,
+		LabelSize:  req.LabelSize,
+		LabelColor: req.LabelColor,
+
+		MaxCanvasSize: s.maxCanvas,
+	})
+	switch {
+	case errors.Is(err, template.ErrGridTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "BAD_GRID", err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := generator.GenerateToWriter(&buf, ".png", generator.Config{Image: sheet}); err != nil {
+		writeErr(w, err)
+		return
+	}
+	setWarningsHeader(w, warnings)
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
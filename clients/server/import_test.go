@@ -0,0 +1,132 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// zipFiles is a ZIP archive of files, in the order given.
+func zipFiles(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// postFile uploads data as the multipart "file" field to path through the
+// server's handler.
+func postFile(t *testing.T, s *srv, path, name string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.handler(fstest.MapFS{}, "", nil).ServeHTTP(rec, req)
+	return rec
+}
+
+// TestImportGSPresetsErrors checks the status and code each rejected
+// bundle gets from POST /api/import/gspresets.
+func TestImportGSPresetsErrors(t *testing.T) {
+	const preset = `{"canvas": {"width": 32, "height": 32}, "font": {}, "components": []}`
+	many := [][2]string{{"preset.json", preset}}
+	for range 1000 {
+		many = append(many, [2]string{"assets/x.txt", ""})
+	}
+
+	tests := []struct {
+		name      string
+		maxUpload byteSize
+		data      []byte
+		status    int
+		code      string
+		message   string
+	}{
+		{"not a ZIP", 0, []byte("not a zip"), http.StatusBadRequest, "BAD_ARCHIVE", "zip"},
+		{"empty", 0, zipFiles(t), http.StatusBadRequest, "BAD_ARCHIVE", "archive is empty"},
+		{"no preset.json", 0, zipFiles(t, [2]string{"theme/preset.json", preset}), http.StatusBadRequest, "BAD_ARCHIVE", "found: theme/preset.json"},
+		{"bad preset.json", 0, zipFiles(t, [2]string{"preset.json", `{"canvas": 1}`}), http.StatusBadRequest, "BAD_ARCHIVE", "preset.json"},
+		{"zip slip", 0, zipFiles(t, [2]string{"preset.json", preset}, [2]string{"../evil.txt", "x"}), http.StatusBadRequest, "BAD_ARCHIVE", "illegal path"},
+		{"too many entries", 0, zipFiles(t, many...), http.StatusRequestEntityTooLarge, "TOO_LARGE", "1001 entries"},
+		{"file over --max-upload", 64 << 10, zipFiles(t, [2]string{"preset.json", preset}, [2]string{"assets/big.txt", strings.Repeat("x", 100<<10)}), http.StatusRequestEntityTooLarge, "TOO_LARGE", "assets/big.txt"},
+		{"bad asset", 0, zipFiles(t, [2]string{"preset.json", preset}, [2]string{"assets/bg.png", "not a png"}), http.StatusUnsupportedMediaType, "BAD_ASSET", "assets/bg.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.maxUpload != 0 {
+				s.maxUpload = tt.maxUpload
+			}
+			rec := postFile(t, s, "/api/import/gspresets", "theme.gspresets", tt.data)
+			var resp struct {
+				Error struct{ Code, Message string }
+			}
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if rec.Code != tt.status || resp.Error.Code != tt.code {
+				t.Errorf("status %d %s, want %d %s", rec.Code, resp.Error.Code, tt.status, tt.code)
+			}
+			if !strings.Contains(resp.Error.Message, tt.message) {
+				t.Errorf("message %q, want it to mention %q", resp.Error.Message, tt.message)
+			}
+			if n := len(s.assets.listAll(nil)); n != 0 {
+				t.Errorf("%d assets added by a rejected import", n)
+			}
+		})
+	}
+}
+
+// TestImportGSPresets imports a bundle and checks that its asset
+// references now point at the stored assets.
+func TestImportGSPresets(t *testing.T) {
+	s := newTestServer(t)
+	bundle := zipFiles(t,
+		[2]string{"preset.json", `{"canvas": {"width": 32, "height": 32}, "background": {"type": "image", "source": "assets/bg.png"}, "font": {}, "components": []}`},
+		[2]string{"assets/bg.png", string(testPNG(t, 8, 8))},
+	)
+	rec := postFile(t, s, "/api/import/gspresets", "theme.gspresets", bundle)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Preset struct {
+			Background struct{ Source string }
+		}
+		Assets []struct{ ID, OriginalPath string }
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Assets) != 1 || resp.Assets[0].OriginalPath != "assets/bg.png" {
+		t.Fatalf("assets %+v, want assets/bg.png", resp.Assets)
+	}
+	if got := resp.Preset.Background.Source; got != resp.Assets[0].ID {
+		t.Errorf("background source %q, want the asset ID %q", got, resp.Assets[0].ID)
+	}
+}
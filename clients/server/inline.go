@@ -0,0 +1,61 @@
+// inline.go — Assets sent with a render request instead of uploaded first.
+//
+//	{"preset": {…"backgroundImage": "logo"…}, "assets": {"logo": {"mime": "image/png", "data": "<base64>"}}}
+//
+// Inline assets exist only for the request that carries them: they are
+// held in memory, referenced from the preset by key (a key shadows a
+// stored asset ID of the same name), and dropped when the request
+// finishes. They never enter the shared asset manager.
+package server
+
+import (
+	"crypto/sha256"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// inlineAsset is one entry of a request's "assets" map.
+type inlineAsset struct {
+	Mime string `json:"mime"` // "font/…" for fonts; anything else is checked as an image
+	Data []byte `json:"data"` // base64 in JSON
+}
+
+// checkInlineAssets checks each asset like an upload and returns key →
+// data for the request's asset resolver.
+func (s *srv) checkInlineAssets(assets map[string]inlineAsset) (map[string][]byte, error) {
+	if len(assets) == 0 {
+		return nil, nil
+	}
+	data := make(map[string][]byte, len(assets))
+	for _, key := range slices.Sorted(maps.Keys(assets)) {
+		a := assets[key]
+		if key == "" {
+			return nil, errorf(http.StatusBadRequest, "BAD_ASSET", "inline asset with an empty key")
+		}
+		if int64(len(a.Data)) > int64(s.maxUpload) {
+			return nil, errorf(http.StatusRequestEntityTooLarge, "TOO_LARGE", "inline asset %q is larger than %s", key, s.maxUpload.String())
+		}
+		if strings.HasPrefix(a.Mime, "font/") {
+			if _, err := checkFont(a.Data); err != nil {
+				return nil, errorf(http.StatusUnsupportedMediaType, "BAD_FONT", "inline asset %q: %v", key, err)
+			}
+		} else if _, err := s.checkImage(a.Data); err != nil {
+			return nil, errorf(http.StatusUnsupportedMediaType, "BAD_IMAGE", "inline asset %q: %v", key, err)
+		}
+		data[key] = a.Data
+	}
+	return data, nil
+}
+
+// hashInlineAssets adds the keys and content digests of assets to a cache key.
+func hashInlineAssets(h io.Writer, assets map[string]inlineAsset) {
+	for _, key := range slices.Sorted(maps.Keys(assets)) {
+		sum := sha256.Sum256(assets[key].Data)
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write(sum[:])
+	}
+}
@@ -0,0 +1,390 @@
+// jobs.go — Background export jobs for work too slow for one request.
+//
+//	POST /api/jobs              {"format":"avi","preset":…,"data":…,"duration":60} → 202 {"id":…}
+//	                            (any /api/export/{format} format and options)
+//	GET  /api/jobs/{id}         status and percent of frames written
+//	GET  /api/jobs/{id}/events  the same as Server-Sent Events until the job ends
+//	GET  /api/jobs/{id}/result  the finished file
+//
+// Jobs run on a fixed worker pool; finished jobs (and their files) are
+// dropped after the configured TTL. A job never depends on a client
+// connection: closing an event stream or a poll loop leaves it running.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// jobQueueSize is how many jobs may wait for a worker before POST /api/jobs
+// answers 503.
+const jobQueueSize = 64
+
+var errQueueFull = errors.New("job queue is full")
+
+type jobStatus string
+
+const (
+	jobQueued  jobStatus = "queued"
+	jobRunning jobStatus = "running"
+	jobDone    jobStatus = "done"
+	jobFailed  jobStatus = "failed"
+)
+
+// job is one queued export. Fields other than the immutable request are
+// guarded by jobQueue.mu.
+type job struct {
+	ID       string    `json:"id"`
+	Format   string    `json:"format"`
+	Status   jobStatus `json:"status"`
+	Progress float64   `json:"progress"` // percent, 0–100
+	Frames   int       `json:"framesDone"`
+	Total    int       `json:"framesTotal,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Result   string    `json:"resultUrl,omitempty"`
+	Created  time.Time `json:"createdAt"`
+	Finished time.Time `json:"finishedAt,omitzero"`
+
+	Warnings []template.RenderWarning `json:"warnings,omitempty"`
+
+	req        exportRequest
+	media      mediaFormat
+	resultPath string
+	changed    chan struct{} // closed and replaced on every update
+}
+
+// jobQueue owns all jobs and the worker pool that runs them.
+type jobQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	pending chan *job
+	ttl     time.Duration
+	dir     string
+	render  func(context.Context, renderRequest) (*renderResult, error)
+
+	stopping  chan struct{} // closed at shutdown to end event streams
+	closeOnce sync.Once
+}
+
+// newJobQueue starts workers and the expiry loop. Results are written to dir.
+func newJobQueue(dir string, workers int, ttl time.Duration, render func(context.Context, renderRequest) (*renderResult, error)) *jobQueue {
+	q := &jobQueue{
+		jobs:    make(map[string]*job),
+		pending: make(chan *job, jobQueueSize),
+		ttl:     ttl,
+		dir:     dir,
+		render:  render,
+
+		stopping: make(chan struct{}),
+	}
+	for range workers {
+		go q.worker()
+	}
+	go q.expireLoop()
+	return q
+}
+
+// submit enqueues a job without blocking.
+func (q *jobQueue) submit(format string, media mediaFormat, req exportRequest) (job, error) {
+	j := &job{
+		ID:      randomID(),
+		Format:  format,
+		Status:  jobQueued,
+		Created: time.Now(),
+		req:     req,
+		media:   media,
+		changed: make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.pending <- j:
+	default:
+		return job{}, errQueueFull
+	}
+	q.jobs[j.ID] = j
+	return *j, nil
+}
+
+// get returns a copy of the job's current state.
+func (q *jobQueue) get(id string) (job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return job{}, false
+	}
+	return *j, true
+}
+
+// watch returns the job's current state and a channel closed at its next
+// update.
+func (q *jobQueue) watch(id string) (job, <-chan struct{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return job{}, nil, false
+	}
+	return *j, j.changed, true
+}
+
+// notifyLocked wakes the watchers of j. q.mu must be held.
+func (q *jobQueue) notifyLocked(j *job) {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// closeStreams ends every event stream; called at shutdown so open streams
+// don't hold up the drain.
+func (q *jobQueue) closeStreams() {
+	q.closeOnce.Do(func() { close(q.stopping) })
+}
+
+// unfinished counts queued and running jobs.
+func (q *jobQueue) unfinished() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, j := range q.jobs {
+		if j.Finished.IsZero() {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *jobQueue) worker() {
+	for j := range q.pending {
+		q.run(j)
+	}
+}
+
+// run renders and encodes one job, recording progress and the outcome.
+func (q *jobQueue) run(j *job) {
+	q.mu.Lock()
+	j.Status = jobRunning
+	q.notifyLocked(j)
+	q.mu.Unlock()
+
+	start := time.Now()
+	path := filepath.Join(q.dir, "job_"+j.ID+j.media.ext)
+	err := q.export(j, path)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.notifyLocked(j)
+	j.Finished = time.Now()
+	j.req = exportRequest{}
+	if err != nil {
+		os.Remove(path)
+		j.Status = jobFailed
+		j.Error = err.Error()
+		slog.Warn("job failed", "job_id", j.ID, "error", err)
+		return
+	}
+	j.Status = jobDone
+	j.Progress = 100
+	j.Result = "/api/jobs/" + j.ID + "/result"
+	j.resultPath = path
+	if fi, err := os.Stat(path); err == nil {
+		metrics.addExport(j.media.ext[1:], fi.Size())
+	}
+	slog.Info("job done", "job_id", j.ID, "format", j.Format, "elapsed", time.Since(start).Round(time.Millisecond))
+}
+
+func (q *jobQueue) export(j *job, path string) error {
+	res, err := q.render(context.Background(), j.req.renderRequest)
+	if err != nil {
+		return err
+	}
+	defer res.release()
+	q.mu.Lock()
+	j.Warnings = res.warnings
+	q.notifyLocked(j)
+	q.mu.Unlock()
+
+	cfg := j.req.config(res.img)
+	cfg.Progress = func(done, total int) {
+		q.mu.Lock()
+		j.Frames, j.Total = done, total
+		j.Progress = float64(done) * 100 / float64(total)
+		q.notifyLocked(j)
+		q.mu.Unlock()
+	}
+	cfg.Warn = func(msg string) {
+		q.mu.Lock()
+		j.Warnings = append(j.Warnings, template.RenderWarning{Message: msg})
+		q.notifyLocked(j)
+		q.mu.Unlock()
+	}
+	return generator.Generate(path, cfg)
+}
+
+// expireLoop periodically drops finished jobs older than the TTL.
+func (q *jobQueue) expireLoop() {
+	interval := min(max(q.ttl/4, time.Second), time.Minute)
+	for now := range time.Tick(interval) {
+		q.mu.Lock()
+		for id, j := range q.jobs {
+			if !j.Finished.IsZero() && now.Sub(j.Finished) > q.ttl {
+				if j.resultPath != "" {
+					os.Remove(j.resultPath)
+				}
+				delete(q.jobs, id)
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// ── Handlers ──
+
+func (s *srv) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		exportRequest
+		Format string `json:"format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Format == "" {
+		req.Format = "avi"
+	}
+	media, err := lookupFormat(req.Format)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := req.check(); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	j, err := s.jobs.submit(req.Format, media, req.exportRequest)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "QUEUE_FULL", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+j.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(j)
+}
+
+func (s *srv) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeNotFound(w, "job", r.PathValue("id"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j)
+}
+
+// Event stream timing: at most one event per jobEventInterval (frame
+// updates in between are coalesced), and a comment every jobKeepAlive so
+// proxies don't close an idle stream while a job waits in the queue.
+const (
+	jobEventInterval = 100 * time.Millisecond
+	jobKeepAlive     = 15 * time.Second
+)
+
+// handleJobEvents streams the job as Server-Sent Events: a "progress" event
+// whenever it changes, then "done" or "failed". Each event's data is the
+// job object as returned by GET /api/jobs/{id}.
+func (s *srv) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j, changed, ok := s.jobs.watch(id)
+	if !ok {
+		writeNotFound(w, "job", id)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(jobKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		event := "progress"
+		switch j.Status {
+		case jobDone:
+			event = "done"
+		case jobFailed:
+			event = "failed"
+		}
+		b, _ := json.Marshal(j)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil || event != "progress" {
+			return
+		}
+
+		select {
+		case <-time.After(jobEventInterval):
+		case <-r.Context().Done():
+			return
+		case <-s.jobs.stopping:
+			return
+		}
+		for waiting := true; waiting; {
+			select {
+			case <-changed:
+				waiting = false
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || rc.Flush() != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-s.jobs.stopping:
+				return
+			}
+		}
+		if j, changed, ok = s.jobs.watch(id); !ok {
+			return // expired
+		}
+	}
+}
+
+func (s *srv) handleJobResult(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeNotFound(w, "job", r.PathValue("id"))
+		return
+	}
+	if j.Status != jobDone {
+		writeError(w, http.StatusConflict, "JOB_NOT_READY", "job is "+string(j.Status))
+		return
+	}
+
+	f, err := os.Open(j.resultPath)
+	if err != nil {
+		writeError(w, http.StatusGone, "JOB_EXPIRED", "job result expired")
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", j.media.mime)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="output%s"`, j.media.ext))
+	http.ServeContent(w, r, "", j.Finished, f)
+}
@@ -0,0 +1,191 @@
+// limits.go — Request size limits, render concurrency limits and upload
+// validation.
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// byteSize is a flag.Value accepting sizes like "512KB", "20MB", "1GB"
+// (binary multiples) or a plain byte count.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	n := int64(*b)
+	for _, u := range []struct {
+		suffix string
+		shift  uint
+	}{{"GB", 30}, {"MB", 20}, {"KB", 10}} {
+		if n >= 1<<u.shift && n%(1<<u.shift) == 0 {
+			return strconv.FormatInt(n>>u.shift, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	shift := uint(0)
+	for _, u := range []struct {
+		suffix string
+		shift  uint
+	}{{"GB", 30}, {"MB", 20}, {"KB", 10}, {"B", 0}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, shift = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.shift
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid size %q: use e.g. 20MB", s)
+	}
+	*b = byteSize(n << shift)
+	return nil
+}
+
+// maxMultipartMemory is how much of a multipart form is held in memory;
+// the rest spills to temp files (still bounded by the body limit).
+const maxMultipartMemory = 10 << 20
+
+// isUploadPath reports whether a request path carries multipart uploads.
+func isUploadPath(p string) bool {
+	return strings.HasPrefix(p, "/api/upload/") || strings.HasPrefix(p, "/api/import/")
+}
+
+// limitBodies caps every request body: upload and import routes at
+// s.maxUpload, everything else at s.maxBody.
+func (s *srv) limitBodies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := int64(s.maxBody)
+		if isUploadPath(r.URL.Path) {
+			limit = int64(s.maxUpload)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// renderWaitFactor bounds how many renders may wait for a slot, as a
+// multiple of the concurrency limit; beyond that requests get 503.
+const renderWaitFactor = 4
+
+// retryAfterBusy is the Retry-After hint, in seconds, on 503 responses.
+const retryAfterBusy = 2
+
+// renderLimiter bounds concurrent renders with a semaphore and a bounded
+// wait queue.
+type renderLimiter struct {
+	slots      chan struct{}
+	waiting    atomic.Int64
+	maxWaiting int64
+}
+
+func newRenderLimiter(n int) *renderLimiter {
+	return &renderLimiter{slots: make(chan struct{}, n), maxWaiting: int64(n * renderWaitFactor)}
+}
+
+// acquire takes a render slot, waiting until ctx is done if all are busy.
+// It fails at once with SERVER_BUSY when too many renders are waiting.
+func (l *renderLimiter) acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if l.waiting.Add(1) > l.maxWaiting {
+		l.waiting.Add(-1)
+		e := errorf(http.StatusServiceUnavailable, "SERVER_BUSY", "too many renders in progress, retry shortly")
+		e.RetryAfter = retryAfterBusy
+		return nil, e
+	}
+	defer l.waiting.Add(-1)
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// renderStats is the limiter state reported by /api/health.
+type renderStats struct {
+	Active  int `json:"active"`
+	Waiting int `json:"waiting"`
+	Limit   int `json:"limit"`
+}
+
+func (l *renderLimiter) stats() renderStats {
+	return renderStats{Active: len(l.slots), Waiting: int(l.waiting.Load()), Limit: cap(l.slots)}
+}
+
+// readUpload parses a multipart request and returns the "file" part's
+// contents. On failure it writes the error response and returns false.
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, *multipart.FileHeader, bool) {
+	if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+		writeBodyError(w, err)
+		return nil, nil, false
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "NO_FILE", "no file uploaded (expected form field \"file\")")
+		return nil, nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeBodyError(w, err)
+		return nil, nil, false
+	}
+	return data, header, true
+}
+
+// bundleLimits is template.MaxBundle with the total, and so each file,
+// capped at --max-upload.
+func bundleLimits(maxUpload byteSize) template.BundleLimits {
+	limits := template.MaxBundle
+	limits.TotalSize = int64(maxUpload)
+	limits.FileSize = min(limits.FileSize, int64(maxUpload))
+	return limits
+}
+
+// isFontName reports whether a file name has a font extension, including
+// the web font ones checkFont refuses with an explanation.
+func isFontName(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ttf", ".otf", ".ttc", ".woff", ".woff2":
+		return true
+	}
+	return false
+}
+
+// checkFont reports whether data is a usable TrueType/OpenType font or
+// collection and returns its MIME type.
+func checkFont(data []byte) (string, error) {
+	if _, err := template.ParseFont(data, 0, ""); err != nil {
+		return "", fmt.Errorf("not a usable font: %w", err)
+	}
+	return template.FontMime(template.FontFormat(data)), nil
+}
+
+// checkImage reports whether data is a PNG, JPEG or SVG image that decodes
+// in full, within --max-canvas, and returns its MIME type.
+func (s *srv) checkImage(data []byte) (string, error) {
+	mimeType, err := template.ImageMimeWithin(data, s.maxCanvas)
+	if err != nil {
+		return "", fmt.Errorf("not a supported image: %w", err)
+	}
+	return mimeType, nil
+}
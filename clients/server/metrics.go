@@ -0,0 +1,176 @@
+// metrics.go — Health check and Prometheus metrics.
+//
+//	GET /healthz   {"status":"ok","version":…,"uptime_seconds":…,"assets":…,"renders":{…},"jobs":…}
+//	GET /metrics   Prometheus text exposition format (version 0.0.4)
+//
+// Both live outside /api/ so probes and scrapers need no access token.
+// The metrics are hand-rolled to keep the server free of dependencies.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is reported by /healthz and /metrics. Release builds set it with
+// -ldflags "-X github.com/xob0t/GoStencil/clients/server.Version=v1.2.3";
+// otherwise it comes from the module build info.
+var Version = ""
+
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+			return "devel-" + s.Value[:12]
+		}
+	}
+	return "devel"
+}
+
+// renderBuckets are the upper bounds, in seconds, of the render duration
+// histogram.
+var renderBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// serverMetrics accumulates counters for /metrics. It is package-level
+// because errors are written from free functions (see errors.go).
+type serverMetrics struct {
+	mu          sync.Mutex
+	started     time.Time
+	renders     map[string]uint64 // by result: "ok", "error"
+	durBuckets  []uint64          // per renderBuckets, not cumulative
+	durSum      float64
+	durCount    uint64
+	exportBytes map[string]uint64 // by format
+	errors      map[string]uint64 // by API error code
+}
+
+var metrics = &serverMetrics{
+	started:     time.Now(),
+	renders:     make(map[string]uint64),
+	durBuckets:  make([]uint64, len(renderBuckets)),
+	exportBytes: make(map[string]uint64),
+	errors:      make(map[string]uint64),
+}
+
+func (m *serverMetrics) observeRender(d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	sec := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.renders[result]++
+	m.durSum += sec
+	m.durCount++
+	if i, _ := slices.BinarySearch(renderBuckets, sec); i < len(renderBuckets) {
+		m.durBuckets[i]++
+	}
+}
+
+func (m *serverMetrics) addExport(format string, n int64) {
+	m.mu.Lock()
+	m.exportBytes[format] += uint64(n)
+	m.mu.Unlock()
+}
+
+func (m *serverMetrics) countError(code string) {
+	m.mu.Lock()
+	m.errors[code]++
+	m.mu.Unlock()
+}
+
+// ── Handlers ──
+
+func (s *srv) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.assets.mu.RLock()
+	assets := len(s.assets.assets)
+	s.assets.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "ok",
+		"version":        buildVersion(),
+		"uptime_seconds": int64(time.Since(metrics.started).Seconds()),
+		"assets":         assets,
+		"renders":        s.limiter.stats(),
+		"jobs":           s.jobs.unfinished(),
+	})
+}
+
+func (s *srv) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.writeMetrics(w)
+}
+
+// writeMetrics writes every metric in Prometheus text format.
+func (s *srv) writeMetrics(w io.Writer) {
+	rs := s.limiter.stats()
+	s.assets.mu.RLock()
+	assets := len(s.assets.assets)
+	s.assets.mu.RUnlock()
+
+	m := metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gauge := func(name, help string, v float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(v))
+	}
+	labeled := func(name, help, kind, label string, values map[string]uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, k := range slices.Sorted(maps.Keys(values)) {
+			fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, escapeLabel(k), values[k])
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP gostencil_build_info Build version.\n# TYPE gostencil_build_info gauge\n")
+	fmt.Fprintf(w, "gostencil_build_info{version=\"%s\"} 1\n", escapeLabel(buildVersion()))
+	gauge("gostencil_start_time_seconds", "Server start time in Unix seconds.", float64(m.started.Unix()))
+	gauge("gostencil_assets", "Uploaded assets held by the server.", float64(assets))
+	gauge("gostencil_renders_active", "Renders currently running.", float64(rs.Active))
+	gauge("gostencil_renders_waiting", "Renders waiting for a slot.", float64(rs.Waiting))
+	gauge("gostencil_jobs_unfinished", "Background jobs queued or running.", float64(s.jobs.unfinished()))
+
+	labeled("gostencil_renders_total", "Renders by result.", "counter", "result", m.renders)
+
+	name := "gostencil_render_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Render time, including the wait for a slot.\n# TYPE %s histogram\n", name, name)
+	var cum uint64
+	for i, le := range renderBuckets {
+		cum += m.durBuckets[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(le), cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", name, m.durCount, name, formatFloat(m.durSum), name, m.durCount)
+
+	labeled("gostencil_export_bytes_total", "Bytes of exported files by format.", "counter", "format", m.exportBytes)
+	labeled("gostencil_api_errors_total", "API error responses by code.", "counter", "code", m.errors)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes a label value as the text format requires.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// sample is one sample line of the Prometheus text format.
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+var (
+	metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// parseExposition parses text in the Prometheus text exposition format
+// (version 0.0.4), failing on anything a scraper would reject: unknown
+// comments, a family typed twice or after its samples, a sample of an
+// undeclared family, bad names, bad escapes and unparsable values. It
+// returns the samples and the type of each family.
+func parseExposition(text string) ([]sample, map[string]string, error) {
+	types := make(map[string]string)
+	seen := make(map[string]bool) // families with samples so far
+	var samples []sample
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "# "); ok {
+			kind, rest, _ := strings.Cut(rest, " ")
+			name, arg, _ := strings.Cut(rest, " ")
+			switch kind {
+			case "HELP":
+			case "TYPE":
+				if _, dup := types[name]; dup || seen[name] {
+					return nil, nil, fmt.Errorf("line %d: TYPE of %s repeated or after its samples", n, name)
+				}
+				switch arg {
+				case "counter", "gauge", "histogram", "summary", "untyped":
+				default:
+					return nil, nil, fmt.Errorf("line %d: unknown type %q", n, arg)
+				}
+				types[name] = arg
+			default:
+				return nil, nil, fmt.Errorf("line %d: unknown comment %q", n, kind)
+			}
+			if !metricName.MatchString(name) {
+				return nil, nil, fmt.Errorf("line %d: bad metric name %q", n, name)
+			}
+			continue
+		}
+
+		s, err := parseSample(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		family := s.name
+		if _, ok := types[family]; !ok {
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				if base, ok := strings.CutSuffix(s.name, suffix); ok && types[base] == "histogram" {
+					family = base
+				}
+			}
+		}
+		if _, ok := types[family]; !ok {
+			return nil, nil, fmt.Errorf("line %d: sample of undeclared family %s", n, s.name)
+		}
+		seen[family] = true
+		samples = append(samples, s)
+	}
+	return samples, types, sc.Err()
+}
+
+// parseSample parses `name{label="value",...} value`.
+func parseSample(line string) (sample, error) {
+	s := sample{labels: make(map[string]string)}
+	i := strings.IndexAny(line, "{ ")
+	if i < 0 {
+		return s, fmt.Errorf("no value in %q", line)
+	}
+	s.name, line = line[:i], line[i:]
+	if !metricName.MatchString(s.name) {
+		return s, fmt.Errorf("bad metric name %q", s.name)
+	}
+	if rest, ok := strings.CutPrefix(line, "{"); ok {
+		for {
+			if rest, ok = strings.CutPrefix(rest, "}"); ok {
+				break
+			}
+			name, after, ok := strings.Cut(rest, `="`)
+			if !ok || !labelName.MatchString(name) {
+				return s, fmt.Errorf("bad label in %q", rest)
+			}
+			var value strings.Builder
+			for {
+				if after == "" {
+					return s, fmt.Errorf("unterminated value of label %s", name)
+				}
+				c := after[0]
+				after = after[1:]
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					if after == "" {
+						return s, fmt.Errorf("unterminated value of label %s", name)
+					}
+					switch after[0] {
+					case '\\', '"':
+						c = after[0]
+					case 'n':
+						c = '\n'
+					default:
+						return s, fmt.Errorf("bad escape \\%c in label %s", after[0], name)
+					}
+					after = after[1:]
+				}
+				value.WriteByte(c)
+			}
+			if _, dup := s.labels[name]; dup {
+				return s, fmt.Errorf("label %s repeated", name)
+			}
+			s.labels[name] = value.String()
+			rest, _ = strings.CutPrefix(after, ",")
+		}
+		line = rest
+	}
+	v, ok := strings.CutPrefix(line, " ")
+	if !ok {
+		return s, fmt.Errorf("no space before the value of %s", s.name)
+	}
+	var err error
+	if s.value, err = strconv.ParseFloat(v, 64); err != nil {
+		return s, fmt.Errorf("value of %s: %v", s.name, err)
+	}
+	return s, nil
+}
+
+// TestMetricsExposition renders, fails a request and exports through the
+// handler, then scrapes /metrics and checks that it parses as the text
+// format and that the counters and histogram are consistent.
+func TestMetricsExposition(t *testing.T) {
+	s := newTestServer(t)
+	h := s.handler(fstest.MapFS{}, "", nil)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	const preset = `{"canvas": {"width": 32, "height": 32}, "font": {}, "components": []}`
+	if rec := do(http.MethodPost, "/api/render", `{"preset": `+preset+`}`); rec.Code != http.StatusOK {
+		t.Fatalf("render: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/render", `{"preset": `); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad render: status %d, want 400", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/export/gspresets", `{"preset": `+preset+`}`); rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", rec.Code, rec.Body)
+	}
+	// A label value needing every escape.
+	metrics.countError("TEST_\"QUOTED\"\\CODE\n")
+
+	rec := do(http.MethodGet, "/metrics", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q, want text/plain; version=0.0.4", ct)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "\n") {
+		t.Error("exposition does not end with a newline")
+	}
+	samples, types, err := parseExposition(rec.Body.String())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, rec.Body)
+	}
+
+	for name, typ := range map[string]string{
+		"gostencil_build_info":              "gauge",
+		"gostencil_start_time_seconds":      "gauge",
+		"gostencil_assets":                  "gauge",
+		"gostencil_renders_active":          "gauge",
+		"gostencil_renders_waiting":         "gauge",
+		"gostencil_jobs_unfinished":         "gauge",
+		"gostencil_renders_total":           "counter",
+		"gostencil_render_duration_seconds": "histogram",
+		"gostencil_export_bytes_total":      "counter",
+		"gostencil_api_errors_total":        "counter",
+	} {
+		if types[name] != typ {
+			t.Errorf("%s has type %q, want %q", name, types[name], typ)
+		}
+	}
+
+	find := func(name, label, value string) (float64, bool) {
+		for _, s := range samples {
+			if s.name == name && (label == "" || s.labels[label] == value) {
+				return s.value, true
+			}
+		}
+		return 0, false
+	}
+	for _, want := range []struct{ name, label, value string }{
+		{"gostencil_build_info", "version", buildVersion()},
+		{"gostencil_renders_total", "result", "ok"},
+		{"gostencil_api_errors_total", "code", "BAD_REQUEST"},
+		{"gostencil_api_errors_total", "code", "TEST_\"QUOTED\"\\CODE\n"},
+		{"gostencil_export_bytes_total", "format", "gspresets"},
+	} {
+		if v, ok := find(want.name, want.label, want.value); !ok || v < 1 {
+			t.Errorf("%s{%s=%q} = %v (found %v), want at least 1", want.name, want.label, want.value, v, ok)
+		}
+	}
+
+	// Buckets are cumulative, end at +Inf and agree with _count.
+	var buckets []sample
+	for _, s := range samples {
+		if s.name == "gostencil_render_duration_seconds_bucket" {
+			buckets = append(buckets, s)
+		}
+	}
+	if len(buckets) != len(renderBuckets)+1 || buckets[len(buckets)-1].labels["le"] != "+Inf" {
+		t.Fatalf("%d buckets, want %d ending in +Inf", len(buckets), len(renderBuckets)+1)
+	}
+	last := -1.0
+	for i, b := range buckets {
+		if b.value < last {
+			t.Errorf("bucket le=%s is %v, below the previous %v", b.labels["le"], b.value, last)
+		}
+		last = b.value
+		if i < len(renderBuckets) {
+			if le, err := strconv.ParseFloat(b.labels["le"], 64); err != nil || le != renderBuckets[i] {
+				t.Errorf("bucket %d le=%q, want %v", i, b.labels["le"], renderBuckets[i])
+			}
+		}
+	}
+	count, _ := find("gostencil_render_duration_seconds_count", "", "")
+	total := 0.0
+	for _, s := range samples {
+		if s.name == "gostencil_renders_total" {
+			total += s.value
+		}
+	}
+	if last != count || count != total || count < 1 {
+		t.Errorf("+Inf bucket %v, _count %v, renders_total %v: want equal and at least 1", last, count, total)
+	}
+}
@@ -0,0 +1,499 @@
+// openapi.go — Route table and the OpenAPI 3.1 document built from it.
+//
+//	GET /api/openapi.json
+//
+// Every route is registered from s.routes(), and each entry carries its own
+// documentation, so a route cannot be added without appearing in the
+// document. Schemas are hand-written below; keep them in step with the
+// request and response types they describe.
+package server
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// route is one API route and its documentation.
+type route struct {
+	method, pattern string
+	handler         http.HandlerFunc
+	doc             apiDoc
+}
+
+// apiDoc describes a route for the OpenAPI document.
+type apiDoc struct {
+	summary  string
+	body     string   // request body: a schema name, "multipart" for uploads, "" for none
+	response string   // success body: a schema name or a media type such as "image/png"
+	jsonAlt  string   // schema of an alternative JSON success body (see query)
+	etag     bool     // honors If-None-Match with 304
+	status   int      // success status (default 200)
+	errors   []int    // statuses returned with the error envelope
+	query    []string // optional query parameters, "name: description"
+}
+
+// routes lists every route the server registers, outside the static files.
+func (s *srv) routes() []route {
+	body := []int{400, 413}
+	render := []int{400, 413, 415, 422, 503}
+	return []route{
+		{"GET", "/healthz", s.handleHealthz, apiDoc{summary: "Health check", response: "Health"}},
+		{"GET", "/metrics", s.handleMetrics, apiDoc{summary: "Prometheus metrics", response: "text/plain"}},
+		{"GET", "/api/openapi.json", s.handleOpenAPI, apiDoc{summary: "This document", response: "application/json"}},
+
+		{"POST", "/api/render", s.handleRender, apiDoc{summary: "Render a preset to PNG", body: "RenderRequest", response: "image/png", errors: render,
+			jsonAlt: "RenderJSON", etag: true, query: []string{"format: \"json\" returns RenderJSON instead of PNG bytes"}}},
+		{"POST", "/api/validate", s.handleValidate, apiDoc{summary: "Lint a preset and data", body: "RenderRequest", response: "ValidateResponse", errors: []int{400, 413, 415}}},
+		{"POST", "/api/resolve", s.handleResolve, apiDoc{summary: "Explain how data merges onto a preset", body: "RenderRequest", response: "Resolution", errors: []int{400, 413, 415}}},
+		{"POST", "/api/schema", s.handleSchema, apiDoc{summary: "Describe a preset's data.json", body: "SchemaRequest", response: "SchemaResponse", errors: body}},
+		{"POST", "/api/compose/grid", s.handleComposeGrid, apiDoc{summary: "Render several requests into one labeled grid PNG", body: "GridRequest", response: "image/png", errors: render}},
+		{"GET", "/api/canvas-presets", s.handleCanvasPresets, apiDoc{summary: "List canvas preset names and sizes", response: "CanvasPresetList"}},
+
+		{"POST", "/api/export/{format}", s.handleExportMedia, apiDoc{summary: "Render and encode as png, jpeg, bmp, webp, gif, avi or another registered format", body: "ExportRequest", response: "application/octet-stream", errors: render}},
+		{"POST", "/api/export/gspresets", s.handleExportGSPresets, apiDoc{summary: "Download a .gspresets bundle", body: "BundleRequest", response: "application/zip", errors: []int{400, 404, 413}}},
+		{"POST", "/api/export/json", s.handleExportJSON, apiDoc{summary: "Download JSON as a file", body: "ExportJSONRequest", response: "application/json", errors: body}},
+
+		{"POST", "/api/upload/font", s.handleUploadFont, apiDoc{summary: "Upload a TTF, OTF or TTC font", body: "multipart", response: "AssetRef", errors: []int{400, 413, 415}}},
+		{"POST", "/api/upload/image", s.handleUploadImage, apiDoc{summary: "Upload an image", body: "multipart", response: "AssetRef", errors: []int{400, 413, 415}}},
+		{"POST", "/api/import/gspresets", s.handleImportGSPresets, apiDoc{summary: "Import a .gspresets bundle", body: "multipart", response: "ImportResponse", errors: []int{400, 413, 415}}},
+
+		{"GET", "/api/assets/{id}", s.handleGetAsset, apiDoc{summary: "Download an asset", response: "application/octet-stream", errors: []int{404}}},
+		{"DELETE", "/api/assets/{id}", s.handleDeleteAsset, apiDoc{summary: "Delete an asset not used by a stored preset", response: "Deleted", errors: []int{404, 409}}},
+		{"GET", "/api/assets", s.handleListAssets, apiDoc{summary: "List assets", response: "AssetList"}},
+		{"POST", "/api/assets/prune", s.handlePruneAssets, apiDoc{summary: "Delete every asset no stored preset references", response: "PruneResponse",
+			query: []string{"dry_run: \"true\" lists the assets without deleting them"}}},
+		{"GET", "/api/fonts/system", s.handleSystemFonts, apiDoc{summary: "List installed fonts (--allow-system-fonts)", response: "SystemFontList", errors: []int{403}}},
+		{"POST", "/api/fonts/use", s.handleUseSystemFont, apiDoc{summary: "Copy an installed font into the asset store", body: "UseFontRequest", response: "AssetRef", errors: []int{400, 403, 404, 413, 415}}},
+
+		{"GET", "/api/presets", s.handleListPresets, apiDoc{summary: "List stored presets", response: "PresetList"}},
+		{"POST", "/api/presets", s.handleCreatePreset, apiDoc{summary: "Store a preset", body: "PresetRequest", response: "PresetSummary", status: http.StatusCreated, errors: body}},
+		{"GET", "/api/presets/{id}", s.handleGetPreset, apiDoc{summary: "Get a stored preset", response: "StoredPreset", errors: []int{404}}},
+		{"PUT", "/api/presets/{id}", s.handleUpdatePreset, apiDoc{summary: "Replace a stored preset", body: "PresetRequest", response: "PresetSummary", errors: []int{400, 404, 413}}},
+		{"DELETE", "/api/presets/{id}", s.handleDeletePreset, apiDoc{summary: "Delete a stored preset", response: "Deleted", errors: []int{404}}},
+		{"GET", "/api/presets/{id}/schema", s.handlePresetSchema, apiDoc{summary: "Describe a stored preset's data.json", response: "SchemaDescription", errors: []int{404}}},
+		{"GET", "/api/presets/{id}/thumbnail", s.handlePresetThumbnail, apiDoc{summary: "Preset thumbnail", response: "image/png", errors: []int{404}}},
+
+		{"POST", "/api/jobs", s.handleCreateJob, apiDoc{summary: "Queue an export", body: "JobRequest", response: "Job", status: http.StatusAccepted, errors: []int{400, 413, 503}}},
+		{"GET", "/api/jobs/{id}", s.handleGetJob, apiDoc{summary: "Job status", response: "Job", errors: []int{404}}},
+		{"GET", "/api/jobs/{id}/events", s.handleJobEvents, apiDoc{summary: "Job progress as Server-Sent Events (progress, then done or failed; data is a Job)", response: "text/event-stream", errors: []int{404}}},
+		{"GET", "/api/jobs/{id}/result", s.handleJobResult, apiDoc{summary: "Download a finished job's file", response: "application/octet-stream", errors: []int{404, 409, 410}}},
+	}
+}
+
+// mux registers s.routes() and serves web, the editor's static files,
+// for every other path.
+func (s *srv) mux(web fs.FS) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.method+" "+rt.pattern, rt.handler)
+	}
+	mux.Handle("/", http.FileServer(http.FS(web)))
+	return mux
+}
+
+func (s *srv) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(openAPIDocument(s.routes()))
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPIDocument emits the OpenAPI 3.1 document for routes.
+func openAPIDocument(routes []route) map[string]any {
+	paths := make(map[string]any)
+	for _, rt := range routes {
+		item, _ := paths[rt.pattern].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[rt.pattern] = item
+		}
+		item[strings.ToLower(rt.method)] = operation(rt)
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "GoStencil API",
+			"version":     buildVersion(),
+			"description": "HTTP API of `gostencil serve`. With --token, /api/ routes need `Authorization: Bearer <token>`.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": openAPISchemas,
+			"securitySchemes": map[string]any{
+				"token": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func operation(rt route) map[string]any {
+	d := rt.doc
+	op := map[string]any{
+		"operationId": operationID(rt),
+		"summary":     d.summary,
+	}
+	if strings.HasPrefix(rt.pattern, "/api/") {
+		op["security"] = []any{map[string]any{}, map[string]any{"token": []string{}}}
+	}
+
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(rt.pattern, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, q := range d.query {
+		name, desc, _ := strings.Cut(q, ": ")
+		params = append(params, map[string]any{"name": name, "in": "query", "description": desc, "schema": map[string]any{"type": "string"}})
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+
+	switch d.body {
+	case "":
+	case "multipart":
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{"multipart/form-data": map[string]any{"schema": object(map[string]any{
+				"file": map[string]any{"type": "string", "contentMediaType": "application/octet-stream"},
+			}, "file")}},
+		}
+	default:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": ref(d.body)}},
+		}
+	}
+
+	status := d.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var content map[string]any
+	if strings.Contains(d.response, "/") {
+		schema := map[string]any{"type": "string"}
+		if !strings.HasPrefix(d.response, "text/") && d.response != "application/json" {
+			schema["contentMediaType"] = d.response
+		}
+		content = map[string]any{d.response: map[string]any{"schema": schema}}
+	} else {
+		content = map[string]any{"application/json": map[string]any{"schema": ref(d.response)}}
+	}
+	if d.jsonAlt != "" {
+		content["application/json"] = map[string]any{"schema": ref(d.jsonAlt)}
+	}
+	responses := map[string]any{
+		strconv.Itoa(status): map[string]any{"description": http.StatusText(status), "content": content},
+	}
+	errs := d.errors
+	if strings.HasPrefix(rt.pattern, "/api/") {
+		errs = append(errs[:len(errs):len(errs)], http.StatusUnauthorized)
+	}
+	for _, code := range errs {
+		responses[strconv.Itoa(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{"application/json": map[string]any{"schema": ref("Error")}},
+		}
+	}
+	if d.etag {
+		responses["304"] = map[string]any{"description": "Not Modified (If-None-Match matched the ETag)"}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// operationID derives a stable ID such as "getPresetsIdThumbnail".
+func operationID(rt route) string {
+	id := strings.ToLower(rt.method)
+	for _, part := range strings.FieldsFunc(rt.pattern, func(r rune) bool { return r == '/' || r == '.' || r == '{' || r == '}' }) {
+		if part == "api" {
+			continue
+		}
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// ── Schema helpers ──
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func object(props map[string]any, required ...string) map[string]any {
+	o := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = required
+	}
+	return o
+}
+
+func typed(t, desc string) map[string]any {
+	m := map[string]any{"type": t}
+	if desc != "" {
+		m["description"] = desc
+	}
+	return m
+}
+
+func arrayOf(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// openAPISchemas are the named request and response bodies.
+var openAPISchemas = map[string]any{
+	"Error": object(map[string]any{
+		"error": object(map[string]any{
+			"code":      typed("string", "Machine-readable code, e.g. BAD_PRESET"),
+			"message":   typed("string", ""),
+			"component": typed("string", "Component ID, for render failures"),
+		}, "code", "message"),
+	}, "error"),
+	"Preset": typed("object", "A preset (see the Preset System documentation); asset references are asset IDs or inline asset keys"),
+	"Data":   typed("object", "data.json overrides"),
+	"InlineAsset": object(map[string]any{
+		"mime": typed("string", "font/… for fonts; anything else is checked as an image"),
+		"data": map[string]any{"type": "string", "contentEncoding": "base64"},
+	}, "data"),
+	"RenderRequest": object(map[string]any{
+		"preset":            ref("Preset"),
+		"data":              ref("Data"),
+		"assets":            map[string]any{"type": "object", "additionalProperties": ref("InlineAsset")},
+		"showMissingAssets": typed("boolean", "Draw a labeled placeholder where a component image cannot be loaded"),
+		"strictAssets":      typed("boolean", "Fail with MISSING_ASSET instead of substituting a missing image or font"),
+		"onlyComponents":    arrayOf(typed("string", "ID of a component to draw, at its canvas position; the others are left out")),
+		"crop":              typed("boolean", "Crop the image to the drawn components' boxes"),
+		"cropPadding":       typed("integer", "Pixels of canvas kept around the boxes when cropping (default 0)"),
+	}, "preset"),
+	"ExportRequest": map[string]any{
+		"allOf": []any{ref("RenderRequest"), object(map[string]any{
+			"duration": typed("number", "Seconds, gif and avi; fractions allowed (default 1)"),
+			"fps":      typed("integer", "gif, max 50"),
+			"quality":  typed("integer", "jpeg, 1–100"),
+			"oddSize":  map[string]any{"type": "string", "enum": []string{"pad", "crop"}, "description": "avi: make an odd width or height even by adding (default) or dropping a pixel"},
+			"matte":    typed("string", "jpeg, gif and avi: \"#rrggbb\" color translucent pixels are composited over (default #000000)"),
+		})},
+	},
+	"GridRequest": object(map[string]any{
+		"renders": arrayOf(map[string]any{
+			"allOf": []any{ref("RenderRequest"), object(map[string]any{
+				"label": typed("string", "Caption under the cell, ellipsized to fit"),
+			})},
+		}),
+		"cols":       typed("integer", "Cells per row (default: square root of the render count, rounded up)"),
+		"rows":       typed("integer", "Rows (default: as many as needed)"),
+		"cellWidth":  typed("integer", "Cell width in pixels (default: widest render)"),
+		"cellHeight": typed("integer", "Cell height in pixels (default: tallest render)"),
+		"gap":        typed("integer", "Pixels between and around cells (default 16)"),
+		"background": typed("string", "\"#rrggbb\" behind the cells (default #1a1a2e)"),
+		"fit":        map[string]any{"type": "string", "enum": []string{"contain", "cover"}, "description": "How a render is scaled into its cell (default contain)"},
+		"labelSize":  typed("number", "Label font size in pixels (default 14)"),
+		"labelColor": typed("string", "Label color (default #e0e0e0)"),
+	}, "renders"),
+	"JobRequest": map[string]any{
+		"allOf": []any{ref("ExportRequest"), object(map[string]any{
+			"format": typed("string", "Export format (default avi)"),
+		})},
+	},
+	"RenderWarning": object(map[string]any{
+		"component": typed("string", ""),
+		"message":   typed("string", ""),
+	}, "message"),
+	"RenderJSON": object(map[string]any{
+		"image_base64": map[string]any{"type": "string", "contentEncoding": "base64", "contentMediaType": "image/png"},
+		"warnings":     arrayOf(ref("RenderWarning")),
+		"paint_order":  arrayOf(typed("string", "Component ID")), // bottom to top: by zIndex, then ID
+		"width":        typed("integer", ""),
+		"height":       typed("integer", ""),
+		"elapsed_ms":   typed("integer", ""),
+		"profile":      ref("RenderProfile"),
+		"assets_used":  arrayOf(ref("AssetUse")),
+	}),
+	"RenderProfile": object(map[string]any{
+		"total":      typed("integer", "Nanoseconds, as are the other durations"),
+		"background": typed("integer", "Preset background"),
+		"components": arrayOf(object(map[string]any{
+			"id":         typed("string", ""),
+			"total":      typed("integer", ""),
+			"background": typed("integer", "Backdrop blur and background color"),
+			"image":      typed("integer", "Background image and mask"),
+			"border":     typed("integer", ""),
+			"text":       typed("integer", ""),
+		})), // paint order
+	}),
+	"Issue": object(map[string]any{
+		"severity":  map[string]any{"enum": []string{"warning", "info"}},
+		"component": typed("string", ""),
+		"field":     typed("string", ""),
+		"message":   typed("string", ""),
+	}, "severity", "message"),
+	"ValidateResponse": object(map[string]any{
+		"issues":   arrayOf(ref("Issue")),
+		"warnings": typed("integer", "Issues with severity warning"),
+	}),
+	"Resolution": object(map[string]any{
+		"width":  typed("integer", "Canvas width in pixels"),
+		"height": typed("integer", ""),
+		"locale": typed("string", "Active locale overlay"),
+		"components": arrayOf(object(map[string]any{
+			"id":      typed("string", ""),
+			"x":       typed("integer", "Pixel box, clipped to the canvas"),
+			"y":       typed("integer", ""),
+			"width":   typed("integer", ""),
+			"height":  typed("integer", ""),
+			"zIndex":  typed("integer", ""),
+			"padding": typed("integer", ""),
+			"style":   typed("object", "Final style after the preset and data merge"),
+			"title":   typed("string", ""),
+			"items":   arrayOf(typed("object", "")),
+			"variant": typed("string", "The component variant applied, if any"),
+			"classes": arrayOf(typed("string", "The style classes applied, in order")),
+			"sources": map[string]any{"type": "object", "description": "Overridden field (title, items, visible, variant, classes, style.<name>, x, y, …) → class, responsive, variant, data or locale; unlisted fields are the preset's", "additionalProperties": map[string]any{"enum": []string{"class", "responsive", "variant", "data", "locale"}}},
+		})),
+		"hidden": arrayOf(object(map[string]any{
+			"id":     typed("string", ""),
+			"source": map[string]any{"enum": []string{"preset", "data", "locale"}, "description": "Who set visible: false; absent when the box is off the canvas"},
+			"reason": typed("string", ""),
+		})),
+		"ignored": arrayOf(object(map[string]any{
+			"path":   typed("string", "data.json path, e.g. components.card.style.borderWidth"),
+			"reason": typed("string", ""),
+		})),
+	}),
+	"SchemaRequest": object(map[string]any{"preset": ref("Preset")}, "preset"),
+	"SchemaResponse": object(map[string]any{
+		"text":        typed("string", "Output of `gostencil schema`"),
+		"jsonSchema":  typed("object", "JSON Schema (draft 2020-12) for data.json"),
+		"description": ref("SchemaDescription"),
+	}),
+	"SchemaDescription": object(map[string]any{
+		"version":     typed("integer", "Changes only when a key is removed or changes meaning"),
+		"meta":        typed("object", "The preset's meta"),
+		"description": typed("string", "The schema's description"),
+		"components": arrayOf(object(map[string]any{
+			"id":          typed("string", ""),
+			"description": typed("string", ""),
+			"fields": arrayOf(object(map[string]any{
+				"name":        map[string]any{"enum": []string{"title", "items", "style", "visible"}},
+				"type":        map[string]any{"enum": []string{"string", "array", "object", "boolean"}},
+				"description": typed("string", "\"\" when the schema does not document the field"),
+				"required":    typed("boolean", ""),
+				"default":     map[string]any{"description": "Value used when data does not set the field"},
+			})),
+			"variants": arrayOf(typed("string", "")),
+			"variant":  typed("string", "Variant used when data selects none; \"\" for none"),
+		})),
+	}),
+	"CanvasPresetList": arrayOf(object(map[string]any{
+		"name":   typed("string", "Value for canvas.preset"),
+		"width":  typed("integer", ""),
+		"height": typed("integer", ""),
+	})),
+	"BundleRequest": object(map[string]any{
+		"id":     typed("string", "Stored preset ID"),
+		"preset": ref("Preset"),
+	}),
+	"ExportJSONRequest": object(map[string]any{
+		"type":    typed("string", "File name without .json"),
+		"content": typed("object", ""),
+	}),
+	"AssetRef": object(map[string]any{
+		"id":       typed("string", ""),
+		"name":     typed("string", ""),
+		"url":      typed("string", ""),
+		"existing": typed("boolean", "The content was already stored under this ID"),
+	}),
+	"AssetList": arrayOf(object(map[string]any{
+		"id":       typed("string", ""),
+		"name":     typed("string", ""),
+		"mime":     typed("string", ""),
+		"size":     typed("integer", ""),
+		"refCount": typed("integer", "Stored presets that reference the asset"),
+	})),
+	"ImportResponse": object(map[string]any{
+		"preset": ref("Preset"),
+		"assets": arrayOf(object(map[string]any{
+			"id":           typed("string", ""),
+			"name":         typed("string", ""),
+			"originalPath": typed("string", "Path inside the bundle"),
+			"mime":         typed("string", ""),
+			"size":         typed("integer", ""),
+			"url":          typed("string", ""),
+		})),
+		"warnings": arrayOf(ref("Issue")),
+		"preview":  typed("string", "The bundle's preview.png as a data: URL, if it has one"),
+	}),
+	"AssetUse": object(map[string]any{
+		"kind":       map[string]any{"type": "string", "enum": []string{"font", "image"}},
+		"ref":        typed("string", "Asset ID or inline key; \"\" for the embedded default font"),
+		"source":     map[string]any{"type": "string", "enum": []string{"resolver", "embedded", "missing"}},
+		"background": typed("boolean", "Drawn as the preset background"),
+		"components": arrayOf(typed("string", "Component ID")),
+	}, "kind", "ref", "source"),
+	"PruneResponse": object(map[string]any{
+		"dry_run": typed("boolean", ""),
+		"deleted": arrayOf(object(map[string]any{
+			"id":   typed("string", ""),
+			"name": typed("string", ""),
+			"size": typed("integer", ""),
+		})),
+		"bytes": typed("integer", "Total size of the deleted assets"),
+	}),
+	"Deleted": object(map[string]any{
+		"status": typed("string", "\"deleted\""),
+		"id":     typed("string", ""),
+	}),
+	"SystemFontList": arrayOf(object(map[string]any{
+		"family": typed("string", ""),
+		"style":  typed("string", ""),
+		"path":   typed("string", ""),
+	})),
+	"UseFontRequest": object(map[string]any{"path": typed("string", "A path from /api/fonts/system")}, "path"),
+	"PresetRequest": object(map[string]any{
+		"name":   typed("string", "Defaults to meta.name"),
+		"preset": ref("Preset"),
+	}, "preset"),
+	"PresetSummary": object(map[string]any{
+		"id":           typed("string", ""),
+		"name":         typed("string", ""),
+		"updatedAt":    map[string]any{"type": "string", "format": "date-time"},
+		"thumbnailUrl": typed("string", ""),
+	}),
+	"PresetList": arrayOf(ref("PresetSummary")),
+	"StoredPreset": object(map[string]any{
+		"id":        typed("string", ""),
+		"name":      typed("string", ""),
+		"updatedAt": map[string]any{"type": "string", "format": "date-time"},
+		"preset":    ref("Preset"),
+	}),
+	"Job": object(map[string]any{
+		"id":          typed("string", ""),
+		"format":      typed("string", ""),
+		"status":      map[string]any{"enum": []string{"queued", "running", "done", "failed"}},
+		"progress":    typed("number", "Percent, 0–100"),
+		"framesDone":  typed("integer", ""),
+		"framesTotal": typed("integer", ""),
+		"error":       typed("string", ""),
+		"resultUrl":   typed("string", "Set when status is done"),
+		"createdAt":   map[string]any{"type": "string", "format": "date-time"},
+		"finishedAt":  map[string]any{"type": "string", "format": "date-time"},
+		"warnings":    arrayOf(ref("RenderWarning")),
+	}),
+	"Health": object(map[string]any{
+		"status":         typed("string", ""),
+		"version":        typed("string", ""),
+		"uptime_seconds": typed("integer", ""),
+		"assets":         typed("integer", ""),
+		"renders": object(map[string]any{
+			"active":  typed("integer", ""),
+			"waiting": typed("integer", ""),
+			"limit":   typed("integer", ""),
+		}),
+		"jobs": typed("integer", "Queued or running"),
+	}),
+}
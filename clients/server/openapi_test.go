@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// openAPIMethods are the operation keys a path item may hold.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// servedOpenAPI fetches /api/openapi.json through the server's mux and
+// decodes it.
+func servedOpenAPI(t *testing.T, mux http.Handler) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json: status %d", rec.Code)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// TestOpenAPIDocument checks the served document's structure against the
+// OpenAPI 3.1 rules that matter to clients: the version, info, that every
+// operation has a unique operationId, declares its path parameters and
+// describes its responses, and that every $ref resolves. The official
+// metaschema is not vendored, so the checks are written out here.
+func TestOpenAPIDocument(t *testing.T) {
+	s := newTestServer(t)
+	doc := servedOpenAPI(t, s.mux(fstest.MapFS{}))
+
+	if v, _ := doc["openapi"].(string); !regexp.MustCompile(`^3\.1\.\d+$`).MatchString(v) {
+		t.Errorf("openapi %q, want 3.1.x", v)
+	}
+	info, _ := doc["info"].(map[string]any)
+	for _, key := range []string{"title", "version"} {
+		if v, _ := info[key].(string); v == "" {
+			t.Errorf("info.%s is missing", key)
+		}
+	}
+
+	paths, _ := doc["paths"].(map[string]any)
+	if len(paths) == 0 {
+		t.Fatal("no paths")
+	}
+	ids := make(map[string]string)
+	for path, v := range paths {
+		item, _ := v.(map[string]any)
+		if !strings.HasPrefix(path, "/") || len(item) == 0 {
+			t.Errorf("path %q: want a leading slash and at least one operation", path)
+			continue
+		}
+		var wantParams []string
+		for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+			wantParams = append(wantParams, m[1])
+		}
+		for method, v := range item {
+			where := strings.ToUpper(method) + " " + path
+			op, _ := v.(map[string]any)
+			if !slices.Contains(openAPIMethods, method) || op == nil {
+				t.Errorf("%s: not an operation", where)
+				continue
+			}
+
+			id, _ := op["operationId"].(string)
+			if id == "" {
+				t.Errorf("%s: no operationId", where)
+			} else if prev, dup := ids[id]; dup {
+				t.Errorf("%s: operationId %q already used by %s", where, id, prev)
+			}
+			ids[id] = where
+
+			var gotParams []string
+			params, _ := op["parameters"].([]any)
+			for _, p := range params {
+				p, _ := p.(map[string]any)
+				name, _ := p["name"].(string)
+				switch p["in"] {
+				case "path":
+					if p["required"] != true {
+						t.Errorf("%s: path parameter %q is not required", where, name)
+					}
+					gotParams = append(gotParams, name)
+				case "query", "header", "cookie":
+				default:
+					t.Errorf("%s: parameter %q is in %v", where, name, p["in"])
+				}
+				if name == "" || p["schema"] == nil {
+					t.Errorf("%s: parameter %v needs a name and a schema", where, p)
+				}
+			}
+			if !slices.Equal(gotParams, wantParams) {
+				t.Errorf("%s: path parameters %q, want %q", where, gotParams, wantParams)
+			}
+
+			responses, _ := op["responses"].(map[string]any)
+			if len(responses) == 0 {
+				t.Errorf("%s: no responses", where)
+			}
+			for code, v := range responses {
+				resp, _ := v.(map[string]any)
+				if !regexp.MustCompile(`^([1-5]\d\d|default)$`).MatchString(code) {
+					t.Errorf("%s: response key %q", where, code)
+				}
+				if desc, _ := resp["description"].(string); desc == "" {
+					t.Errorf("%s: response %s has no description", where, code)
+				}
+			}
+		}
+	}
+
+	var walk func(at string, v any)
+	walk = func(at string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if r, ok := v["$ref"]; ok {
+				if ref, _ := r.(string); resolveRef(doc, ref) == nil {
+					t.Errorf("%s: $ref %v does not resolve", at, r)
+				}
+			}
+			for k, child := range v {
+				walk(at+"/"+k, child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(at+"/[]", child)
+			}
+		}
+	}
+	walk("#", doc)
+}
+
+// resolveRef follows a local JSON pointer such as
+// "#/components/schemas/Error" in doc; nil if it leads nowhere.
+func resolveRef(doc map[string]any, ref string) any {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil
+	}
+	var at any = doc
+	for _, tok := range strings.Split(pointer, "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		m, _ := at.(map[string]any)
+		if at = m[tok]; at == nil {
+			return nil
+		}
+	}
+	return at
+}
+
+// TestOpenAPICoversRoutes checks that the document and the mux agree:
+// every registered route is documented under its method, and every
+// documented operation reaches that route rather than the static files.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := newTestServer(t)
+	mux := s.mux(fstest.MapFS{})
+	paths, _ := servedOpenAPI(t, mux)["paths"].(map[string]any)
+
+	documented := 0
+	for path, item := range paths {
+		for method := range item.(map[string]any) {
+			documented++
+			url := pathParam.ReplaceAllString(path, "x")
+			_, pattern := mux.Handler(httptest.NewRequest(strings.ToUpper(method), url, nil))
+			if want := strings.ToUpper(method) + " " + path; pattern != want {
+				t.Errorf("%s %s is served by %q, want %q", strings.ToUpper(method), url, pattern, want)
+			}
+		}
+	}
+
+	routes := s.routes()
+	for _, rt := range routes {
+		item, _ := paths[rt.pattern].(map[string]any)
+		if item[strings.ToLower(rt.method)] == nil {
+			t.Errorf("%s %s is registered but not documented", rt.method, rt.pattern)
+		}
+	}
+	if documented != len(routes) {
+		t.Errorf("%d operations documented, %d routes registered", documented, len(routes))
+	}
+}
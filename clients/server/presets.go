@@ -0,0 +1,330 @@
+// presets.go — Server-side preset library.
+//
+//	GET    /api/presets                 list (id, name, updatedAt, thumbnailUrl)
+//	POST   /api/presets                 {"name"?, "preset": {...}} → 201
+//	GET    /api/presets/{id}            full entry including the preset
+//	PUT    /api/presets/{id}            replace name/preset
+//	DELETE /api/presets/{id}
+//	GET    /api/presets/{id}/schema     the preset's data fields (template.DescribeSchema)
+//	GET    /api/presets/{id}/thumbnail  PNG rendered with the preset's defaults
+//
+// Presets live in memory, or under <data-dir>/presets as <id>.json plus
+// <id>.png when --data-dir is set.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// storedPreset is one library entry. thumb is kept out of the JSON file.
+type storedPreset struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Updated time.Time       `json:"updatedAt"`
+	Preset  json.RawMessage `json:"preset"`
+
+	thumb []byte
+}
+
+// presetSummary is the list view of a storedPreset.
+type presetSummary struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Updated      time.Time `json:"updatedAt"`
+	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
+}
+
+func (p *storedPreset) summary() presetSummary {
+	sum := presetSummary{ID: p.ID, Name: p.Name, Updated: p.Updated}
+	if p.thumb != nil {
+		sum.ThumbnailURL = "/api/presets/" + p.ID + "/thumbnail"
+	}
+	return sum
+}
+
+// presetStore holds the library, optionally mirrored to dir.
+type presetStore struct {
+	mu      sync.RWMutex
+	presets map[string]*storedPreset
+	dir     string // "" = memory only
+}
+
+func newPresetStore() *presetStore {
+	return &presetStore{presets: make(map[string]*storedPreset)}
+}
+
+// openPresetStore loads every <id>.json (and thumbnail) in dir.
+func openPresetStore(dir string) (*presetStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("preset dir: %w", err)
+	}
+	ps := &presetStore{presets: make(map[string]*storedPreset), dir: dir}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("preset library: %w", err)
+		}
+		var p storedPreset
+		if err := json.Unmarshal(raw, &p); err != nil || p.ID == "" {
+			slog.Warn("skipping unreadable preset file", "file", path, "err", err)
+			continue
+		}
+		p.thumb, _ = os.ReadFile(filepath.Join(dir, p.ID+".png"))
+		ps.presets[p.ID] = &p
+	}
+	return ps, nil
+}
+
+func (ps *presetStore) list() []presetSummary {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	out := make([]presetSummary, 0, len(ps.presets))
+	for _, p := range ps.presets {
+		out = append(out, p.summary())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Updated.After(out[j].Updated) })
+	return out
+}
+
+func (ps *presetStore) get(id string) (*storedPreset, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	p, ok := ps.presets[id]
+	return p, ok
+}
+
+// put inserts or replaces an entry and writes it through to disk.
+func (ps *presetStore) put(p *storedPreset) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.dir != "" {
+		b, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(ps.dir, p.ID+".json"), b); err != nil {
+			return fmt.Errorf("save preset: %w", err)
+		}
+		thumbPath := filepath.Join(ps.dir, p.ID+".png")
+		if p.thumb != nil {
+			if err := writeFileAtomic(thumbPath, p.thumb); err != nil {
+				return fmt.Errorf("save thumbnail: %w", err)
+			}
+		} else {
+			os.Remove(thumbPath)
+		}
+	}
+	ps.presets[p.ID] = p
+	return nil
+}
+
+func (ps *presetStore) remove(id string) (bool, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.presets[id]; !ok {
+		return false, nil
+	}
+	if ps.dir != "" {
+		if err := os.Remove(filepath.Join(ps.dir, id+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return true, fmt.Errorf("delete preset: %w", err)
+		}
+		os.Remove(filepath.Join(ps.dir, id+".png"))
+	}
+	delete(ps.presets, id)
+	return true, nil
+}
+
+// assetRefs maps each asset ID referenced by a stored preset to the names
+// of the presets that reference it, sorted.
+func (ps *presetStore) assetRefs() map[string][]string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	refs := make(map[string][]string)
+	for _, p := range ps.presets {
+		var preset template.Preset
+		if err := json.Unmarshal(p.Preset, &preset); err != nil {
+			continue
+		}
+		for _, id := range template.ReferencedAssets(&preset) {
+			refs[id] = append(refs[id], p.Name)
+		}
+	}
+	for _, names := range refs {
+		sort.Strings(names)
+	}
+	return refs
+}
+
+// ── Handlers ──
+
+// presetRequest is the body of POST and PUT /api/presets.
+type presetRequest struct {
+	Name   string          `json:"name"`
+	Preset json.RawMessage `json:"preset"`
+}
+
+// buildStoredPreset validates a request and renders its thumbnail.
+func (s *srv) buildStoredPreset(ctx context.Context, id string, req presetRequest) (*storedPreset, error) {
+	var meta struct {
+		Meta template.Meta `json:"meta"`
+	}
+	if len(req.Preset) == 0 || json.Unmarshal(req.Preset, &meta) != nil {
+		return nil, errorf(http.StatusBadRequest, "BAD_PRESET", "\"preset\" must be a preset object")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = meta.Meta.Name
+	}
+	if name == "" {
+		name = "Untitled"
+	}
+
+	p := &storedPreset{ID: id, Name: name, Updated: time.Now().UTC(), Preset: req.Preset}
+	thumb, err := s.presetThumbnail(ctx, req.Preset)
+	if err != nil {
+		// Bad presets are rejected; a busy or failing renderer only
+		// costs the thumbnail.
+		var ae *apiError
+		if errors.As(err, &ae) && ae.Status < http.StatusInternalServerError {
+			return nil, err
+		}
+		slog.Warn("preset thumbnail failed", "preset_id", id, "err", err)
+	}
+	p.thumb = thumb
+	return p, nil
+}
+
+// presetThumbnail renders a preset with its defaults at
+// template.PreviewWidth, the preview stored in exported bundles.
+func (s *srv) presetThumbnail(ctx context.Context, preset json.RawMessage) ([]byte, error) {
+	res, err := s.render(ctx, renderRequest{Preset: preset})
+	if err != nil {
+		return nil, err
+	}
+	defer res.release()
+	return template.EncodePreview(res.img, template.PreviewWidth)
+}
+
+func (s *srv) handleListPresets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.presets.list())
+}
+
+func (s *srv) handleCreatePreset(w http.ResponseWriter, r *http.Request) {
+	var req presetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	p, err := s.buildStoredPreset(r.Context(), randomID(), req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.presets.put(p); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/presets/"+p.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p.summary())
+}
+
+func (s *srv) handleGetPreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := s.presets.get(id)
+	if !ok {
+		writeNotFound(w, "preset", id)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func (s *srv) handleUpdatePreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.presets.get(id); !ok {
+		writeNotFound(w, "preset", id)
+		return
+	}
+	var req presetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	p, err := s.buildStoredPreset(r.Context(), id, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.presets.put(p); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.summary())
+}
+
+func (s *srv) handleDeletePreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	found, err := s.presets.remove(id)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if !found {
+		writeNotFound(w, "preset", id)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+}
+
+func (s *srv) handlePresetSchema(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := s.presets.get(id)
+	if !ok {
+		writeNotFound(w, "preset", id)
+		return
+	}
+	preset, err := template.DecodePreset(p.Preset)
+	if err != nil {
+		writeErr(w, fmt.Errorf("stored preset %s: %w", id, err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template.DescribeSchema(preset))
+}
+
+func (s *srv) handlePresetThumbnail(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := s.presets.get(id)
+	if !ok || p.thumb == nil {
+		writeNotFound(w, "thumbnail for preset", id)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeContent(w, r, "", p.Updated, bytes.NewReader(p.thumb))
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// TestRenderReadsNoFiles renders a preset whose font and image references
+// name files that exist, by absolute path and by "../" traversal, and
+// checks that the server reads none of them.
+func TestRenderReadsNoFiles(t *testing.T) {
+	dir := t.TempDir()
+	pngPath, ttfPath := filepath.Join(dir, "bg.png"), filepath.Join(dir, "font.ttf")
+	if err := os.WriteFile(pngPath, testPNG(t, 8, 8), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ttfPath, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relPNG, _ := filepath.Rel(wd, pngPath)
+	relTTF, _ := filepath.Rel(wd, ttfPath)
+
+	s := newTestServer(t)
+	for _, refs := range [][2]string{{pngPath, ttfPath}, {relPNG, relTTF}} {
+		png, ttf := refs[0], refs[1]
+		preset, _ := json.Marshal(map[string]any{
+			"canvas":     map[string]any{"width": 64, "height": 48},
+			"background": map[string]any{"type": "image", "source": png},
+			"font":       map[string]any{"path": ttf},
+			"components": []any{map[string]any{
+				"id": "c", "x": 0.1, "y": 0.1, "width": 0.8, "height": 0.8,
+				"style":    map[string]any{"backgroundImage": png, "maskImage": png, "fontPath": ttf},
+				"defaults": map[string]any{"visible": true, "title": "Hi"},
+			}},
+		})
+		res, err := s.render(context.Background(), renderRequest{Preset: preset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.release()
+		for _, u := range res.used {
+			if u.Ref != "" && u.Source != template.AssetMissing {
+				t.Errorf("%s %q read from %s, want missing", u.Kind, u.Ref, u.Source)
+			}
+		}
+		if len(res.used) < 2 {
+			t.Errorf("assets used %+v, want the font and image listed as missing", res.used)
+		}
+
+		if _, err := s.render(context.Background(), renderRequest{Preset: preset, StrictAssets: true}); err == nil {
+			t.Errorf("strict render of %s succeeded, want a missing asset", png)
+		}
+	}
+}
+
+// TestRenderOversizedCanvas renders the canvas the CLI and WASM tests
+// refuse, then the same preset on servers with a larger and a smaller
+// --max-canvas.
+func TestRenderOversizedCanvas(t *testing.T) {
+	preset := func(w int) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"canvas": {"width": %d, "height": 16}, "font": {}, "components": []}`, w))
+	}
+	tests := []struct {
+		maxCanvas int
+		width     int
+		want      string // "" for success
+	}{
+		{0, template.MaxCanvasSize + 1, fmt.Sprintf("exceeds the maximum of %d pixels per side", template.MaxCanvasSize)},
+		{0, template.MaxCanvasSize, ""},
+		{template.MaxCanvasSize + 100, template.MaxCanvasSize + 1, ""},
+		{32, 64, "exceeds the maximum of 32 pixels per side"},
+	}
+	for _, tt := range tests {
+		s := newTestServer(t)
+		s.maxCanvas = tt.maxCanvas
+		res, err := s.render(context.Background(), renderRequest{Preset: preset(tt.width)})
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("--max-canvas %d, width %d: %v", tt.maxCanvas, tt.width, err)
+			} else {
+				res.release()
+			}
+			continue
+		}
+		var ae *apiError
+		if !errors.As(err, &ae) || ae.Status != http.StatusBadRequest || ae.Code != "BAD_PRESET" || !strings.Contains(ae.Message, tt.want) {
+			t.Errorf("--max-canvas %d, width %d: error %v, want 400 BAD_PRESET saying %q", tt.maxCanvas, tt.width, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestPresetSchemaSnapshot stores the preset of pkg/template's schema
+// snapshot test and expects GET /api/presets/{id}/schema to return that
+// snapshot, so the API and `gostencil schema --format json` cannot drift
+// apart.
+func TestPresetSchemaSnapshot(t *testing.T) {
+	preset, err := os.ReadFile("../../pkg/template/testdata/schema/preset.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../../pkg/template/testdata/snapshots/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestServer(t).handler(fstest.MapFS{}, "", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/presets",
+		strings.NewReader(`{"name": "Event card", "preset": `+string(preset)+`}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	var created struct{ ID string }
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/presets/"+created.ID+"/schema", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("schema: status %d: %s", rec.Code, rec.Body)
+	}
+	var got bytes.Buffer
+	if err := json.Indent(&got, rec.Body.Bytes(), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("schema of the stored preset:\n%s\nwant the snapshot:\n%s", got.String(), want)
+	}
+}
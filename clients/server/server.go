@@ -2,26 +2,38 @@
 package server
 
 import (
-	"archive/zip"
 	"bytes"
+	"cmp"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"embed"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"image/png"
+	"image"
 	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
+	"unicode/utf16"
 
+	"github.com/xob0t/GoStencil/internal/desktop"
 	"github.com/xob0t/GoStencil/pkg/generator"
 	"github.com/xob0t/GoStencil/pkg/template"
 )
@@ -35,23 +47,50 @@ type asset struct {
 	Name string
 	Data []byte
 	Mime string
+
+	sum [sha256.Size]byte // content digest: dedup key and render cache key
+}
+
+func newAsset(name string, data []byte, mimeType string) *asset {
+	return &asset{Name: name, Data: data, Mime: mimeType, sum: sha256.Sum256(data)}
 }
 
+// assetManager stores assets keyed by ID. Content is deduplicated: adding
+// bytes that are already stored returns the existing ID.
 type assetManager struct {
 	mu     sync.RWMutex
 	assets map[string]*asset
+	bySum  map[[sha256.Size]byte]string // content digest → ID
+	dir    string                       // persistence directory ("" = memory only), see assetstore.go
 }
 
 func newAssetManager() *assetManager {
-	return &assetManager{assets: make(map[string]*asset)}
+	return &assetManager{assets: make(map[string]*asset), bySum: make(map[[sha256.Size]byte]string)}
 }
 
-func (am *assetManager) add(name string, data []byte, mimeType string) string {
-	id := randomID()
+// add stores an asset and returns its ID. If the same content is already
+// stored, nothing is written and the existing ID is returned with
+// created == false; the first upload's name is kept.
+//
+// New IDs are the first 8 bytes of the content's SHA-256 in hex, the same
+// shape as the random IDs earlier versions assigned (which stay valid).
+func (am *assetManager) add(name string, data []byte, mimeType string) (id string, created bool, err error) {
+	a := newAsset(name, data, mimeType)
 	am.mu.Lock()
-	am.assets[id] = &asset{Name: name, Data: data, Mime: mimeType}
-	am.mu.Unlock()
-	return id
+	defer am.mu.Unlock()
+	if id, ok := am.bySum[a.sum]; ok {
+		return id, false, nil
+	}
+	id = hex.EncodeToString(a.sum[:8])
+	for am.assets[id] != nil {
+		id = randomID() // a legacy random ID already holds this prefix
+	}
+	if err := am.persistLocked(id, a); err != nil {
+		return "", false, err
+	}
+	am.assets[id] = a
+	am.bySum[a.sum] = id
+	return id, true, nil
 }
 
 func (am *assetManager) get(id string) (*asset, bool) {
@@ -61,25 +100,72 @@ func (am *assetManager) get(id string) (*asset, bool) {
 	return a, ok
 }
 
-func (am *assetManager) listAll() []map[string]interface{} {
+// listAll describes every asset; refs maps asset IDs to the stored presets
+// that reference them.
+func (am *assetManager) listAll(refs map[string][]string) []map[string]interface{} {
 	am.mu.RLock()
 	defer am.mu.RUnlock()
 	result := make([]map[string]interface{}, 0, len(am.assets))
 	for id, a := range am.assets {
 		result = append(result, map[string]interface{}{
-			"id":   id,
-			"name": a.Name,
-			"mime": a.Mime,
-			"size": len(a.Data),
+			"id":       id,
+			"name":     a.Name,
+			"mime":     a.Mime,
+			"size":     len(a.Data),
+			"refCount": len(refs[id]),
 		})
 	}
 	return result
 }
 
-func (am *assetManager) remove(id string) {
+// prunedAsset describes an asset handlePruneAssets deletes.
+type prunedAsset struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+// unreferenced describes the assets refs, as from presetStore.assetRefs,
+// does not list, sorted by ID.
+func (am *assetManager) unreferenced(refs map[string][]string) []prunedAsset {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	unused := []prunedAsset{}
+	for id, a := range am.assets {
+		if len(refs[id]) == 0 {
+			unused = append(unused, prunedAsset{ID: id, Name: a.Name, Size: len(a.Data)})
+		}
+	}
+	slices.SortFunc(unused, func(a, b prunedAsset) int { return strings.Compare(a.ID, b.ID) })
+	return unused
+}
+
+func (am *assetManager) remove(id string) error {
 	am.mu.Lock()
+	defer am.mu.Unlock()
+	a, ok := am.assets[id]
+	if !ok {
+		return nil
+	}
 	delete(am.assets, id)
-	am.mu.Unlock()
+	if am.bySum[a.sum] == id {
+		am.indexSumLocked(a.sum)
+	}
+	return am.unpersistLocked(id)
+}
+
+// indexSumLocked points sum at the lowest remaining ID with that content,
+// or drops it. Only data dirs written before deduplication hold several
+// IDs per digest. Callers hold am.mu.
+func (am *assetManager) indexSumLocked(sum [sha256.Size]byte) {
+	delete(am.bySum, sum)
+	for id, a := range am.assets {
+		if a.sum == sum {
+			if cur, ok := am.bySum[sum]; !ok || id < cur {
+				am.bySum[sum] = id
+			}
+		}
+	}
 }
 
 func randomID() string {
@@ -91,229 +177,793 @@ func randomID() string {
 // ── Server ──
 
 type srv struct {
-	assets *assetManager
-	tmpDir string
+	assets    *assetManager
+	jobs      *jobQueue
+	presets   *presetStore
+	tmpDir    string
+	maxBody   byteSize // JSON request bodies
+	maxUpload byteSize // multipart uploads and imports
+
+	limiter       *renderLimiter
+	renderTimeout time.Duration
+	cache         *renderCache
+	maxCanvas     int                  // see RendererOptions.MaxCanvasSize
+	maxImageSize  int                  // see Renderer.SetMaxImageSize
+	images        *template.ImageCache // reduced images, shared by renders
+	sysFonts      *systemFontIndex     // nil unless --allow-system-fonts
+
+	buffers template.ImagePool // render canvases, reused via renderResult.release
 }
 
-// RunServe starts the web UI server on the given port.
-func RunServe(args []string) error {
-	port := "8080"
-	for i, a := range args {
-		if (a == "--port" || a == "-p") && i+1 < len(args) {
-			port = args[i+1]
+// RunServe starts the web UI server. Each configure
+// function may set flag defaults (e.g. from a config file) before args are
+// parsed.
+func RunServe(args []string, configure ...func(*flag.FlagSet) error) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		host      string
+		port      string
+		tlsCert   string
+		tlsKey    string
+		token     string
+		origins   originList
+		noBrowser bool
+		sysFonts  bool
+		drain     time.Duration
+		renders   int
+		renderTTL time.Duration
+		cacheSize int
+		dataDir   string
+		workers   int
+		jobTTL    time.Duration
+		maxBody   = byteSize(20 << 20)
+		maxUpload = byteSize(50 << 20)
+		maxCanvas int
+		maxImage  int
+	)
+	flags.StringVar(&port, "port", "8080", "Listen port")
+	flags.StringVar(&port, "p", "8080", "Listen port (shorthand)")
+	flags.StringVar(&host, "host", "127.0.0.1", "Listen address (0.0.0.0 for all interfaces)")
+	flags.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file (serve HTTPS; needs --tls-key)")
+	flags.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	flags.StringVar(&token, "token", "", "Require this access token on /api/ routes")
+	flags.Var(&origins, "cors-origin", "Allow browser API calls from this origin (repeatable; * for any)")
+	flags.BoolVar(&noBrowser, "no-browser", false, "Don't open the editor in a browser")
+	flags.DurationVar(&drain, "shutdown-timeout", 10*time.Second, "How long to let in-flight requests finish on Ctrl-C/SIGTERM")
+	flags.BoolVar(&sysFonts, "allow-system-fonts", false, "Let the editor list and use fonts installed on this machine (exposes their paths)")
+	flags.StringVar(&dataDir, "data-dir", "", "Persist uploaded assets and the preset library in this directory (default: memory only)")
+	flags.IntVar(&renders, "max-concurrent", runtime.NumCPU(), "Maximum renders running at once")
+	flags.DurationVar(&renderTTL, "render-timeout", 30*time.Second, "Maximum time for one render, including waiting for a slot")
+	flags.IntVar(&cacheSize, "render-cache", 64, "Recent renders kept for identical requests (0 disables)")
+	flags.IntVar(&workers, "workers", 2, "Background export workers")
+	flags.DurationVar(&jobTTL, "job-ttl", 30*time.Minute, "How long finished job results are kept")
+	flags.Var(&maxBody, "max-body", "Maximum JSON request body size (e.g. 20MB)")
+	flags.Var(&maxUpload, "max-upload", "Maximum upload/import size (e.g. 50MB)")
+	flags.IntVar(&maxCanvas, "max-canvas", template.MaxCanvasSize, "Largest canvas width or height in pixels")
+	flags.IntVar(&maxImage, "max-image-size", 4096, "Reduce images larger than this many pixels that are drawn much smaller (0 disables)")
+	for _, fn := range configure {
+		if err := fn(flags); err != nil {
+			return err
 		}
 	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
 
 	tmpDir, err := os.MkdirTemp("", "gostencil-serve-*")
 	if err != nil {
 		return fmt.Errorf("create temp dir: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			slog.Warn("temp dir cleanup failed", "dir", tmpDir, "error", err)
+			return
+		}
+		slog.Debug("temp dir removed", "dir", tmpDir)
+	}()
+
+	if workers < 1 {
+		return fmt.Errorf("--workers must be ≥ 1")
+	}
+	if renders < 1 {
+		return fmt.Errorf("--max-concurrent must be ≥ 1")
+	}
+	if maxCanvas < template.MinCanvasSize {
+		return fmt.Errorf("--max-canvas must be ≥ %d", template.MinCanvasSize)
+	}
+	if maxImage < 0 {
+		return fmt.Errorf("--max-image-size must be ≥ 0")
+	}
+	assets, presets := newAssetManager(), newPresetStore()
+	if dataDir != "" {
+		if assets, err = openAssetManager(filepath.Join(dataDir, "assets")); err != nil {
+			return err
+		}
+		if presets, err = openPresetStore(filepath.Join(dataDir, "presets")); err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Loaded %d assets and %d presets from %s", len(assets.assets), len(presets.presets), dataDir))
+	}
 
 	s := &srv{
-		assets: newAssetManager(),
-		tmpDir: tmpDir,
+		assets:    assets,
+		presets:   presets,
+		tmpDir:    tmpDir,
+		maxBody:   maxBody,
+		maxUpload: maxUpload,
+
+		limiter:       newRenderLimiter(renders),
+		renderTimeout: renderTTL,
+		cache:         newRenderCache(cacheSize),
+		maxCanvas:     maxCanvas,
+		maxImageSize:  maxImage,
+		images:        template.NewImageCache(template.DefaultImageCacheBytes),
+	}
+	if sysFonts {
+		s.sysFonts = newSystemFontIndex()
 	}
+	s.jobs = newJobQueue(tmpDir, workers, jobTTL, s.render)
 
 	webFS, err := fs.Sub(webContent, "web")
 	if err != nil {
 		return fmt.Errorf("embed web: %w", err)
 	}
 
-	mux := http.NewServeMux()
+	handler := logRequests(s.handler(webFS, token, origins))
+
+	addr := net.JoinHostPort(host, port)
+	uiURL := "http://"
+	if tlsCert != "" {
+		uiURL = "https://"
+	}
+	switch host {
+	case "", "0.0.0.0", "::":
+		uiURL += net.JoinHostPort("localhost", port)
+	default:
+		uiURL += addr
+	}
+	slog.Info("GoStencil UI → " + uiURL)
+
+	if !noBrowser {
+		if token != "" {
+			uiURL += "/?token=" + url.QueryEscape(token)
+		}
+		if err := (desktop.System{}).Open(uiURL); err != nil {
+			slog.Warn("could not open a browser: " + err.Error())
+		}
+	}
+
+	return listenUntilSignal(&http.Server{Addr: addr, Handler: handler}, tlsCert, tlsKey, drain, s.jobs)
+}
+
+// handler is the server's mux behind its middleware: body limits, then the
+// token check if token is set, then CORS if any origins are allowed.
+func (s *srv) handler(web fs.FS, token string, origins []string) http.Handler {
+	var h http.Handler = s.limitBodies(s.mux(web))
+	if token != "" {
+		h = requireToken(token, h)
+	}
+	if len(origins) > 0 {
+		h = cors(origins, h)
+	}
+	return h
+}
+
+// listenUntilSignal serves until the listener fails or SIGINT/SIGTERM
+// arrives, then gives in-flight requests up to drain to finish. A second
+// signal during the drain exits immediately.
+func listenUntilSignal(hs *http.Server, tlsCert, tlsKey string, drain time.Duration, jobs *jobQueue) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		if tlsCert != "" {
+			errc <- hs.ListenAndServeTLS(tlsCert, tlsKey)
+		} else {
+			errc <- hs.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("Shutting down: draining requests", "timeout", drain)
+	jobs.closeStreams()
+	if n := jobs.unfinished(); n > 0 {
+		slog.Warn("abandoning unfinished jobs", "count", n)
+	}
+	dctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := hs.Shutdown(dctx); err != nil {
+		slog.Warn("drain timed out; closing remaining connections", "error", err)
+		hs.Close()
+	}
+	slog.Info("Server stopped")
+	return nil
+}
 
-	// API routes.
-	mux.HandleFunc("POST /api/render", s.handleRender)
-	mux.HandleFunc("POST /api/export/png", s.handleExportPNG)
-	mux.HandleFunc("POST /api/export/avi", s.handleExportAVI)
-	mux.HandleFunc("POST /api/export/gspresets", s.handleExportGSPresets)
-	mux.HandleFunc("POST /api/export/json", s.handleExportJSON)
-	mux.HandleFunc("POST /api/upload/font", s.handleUploadFont)
-	mux.HandleFunc("POST /api/upload/image", s.handleUploadImage)
-	mux.HandleFunc("POST /api/import/gspresets", s.handleImportGSPresets)
-	mux.HandleFunc("GET /api/assets/{id}", s.handleGetAsset)
-	mux.HandleFunc("DELETE /api/assets/{id}", s.handleDeleteAsset)
-	mux.HandleFunc("GET /api/assets", s.handleListAssets)
+// ── Request logging ──
 
-	// Static files.
-	mux.Handle("/", http.FileServer(http.FS(webFS)))
+// statusRecorder captures the status code and size written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
 
-	addr := ":" + port
-	log.Printf("GoStencil UI → http://localhost%s", addr)
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
 
-	// Open browser.
-	go openBrowser("http://localhost" + addr)
+// Unwrap lets http.ResponseController reach the underlying writer (for
+// Flush in event streams).
+func (sr *statusRecorder) Unwrap() http.ResponseWriter { return sr.ResponseWriter }
 
-	return http.ListenAndServe(addr, mux)
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+// logRequests assigns each request an ID (honoring an incoming X-Request-ID),
+// echoes it in the response, and logs API requests through slog. Static file
+// requests are logged at Debug only.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = randomID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			level = slog.LevelDebug
+		}
+		if rec.status >= 500 {
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "request",
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"elapsed", time.Since(start).Round(time.Microsecond))
+	})
 }
 
 // ── Render (core) ──
 
 type renderRequest struct {
-	Preset json.RawMessage `json:"preset"`
-	Data   json.RawMessage `json:"data"`
+	Preset json.RawMessage        `json:"preset"`
+	Data   json.RawMessage        `json:"data"`
+	Assets map[string]inlineAsset `json:"assets,omitempty"` // see inline.go
+
+	// See Renderer.SetShowMissingAssets and SetStrictAssets.
+	ShowMissingAssets bool `json:"showMissingAssets,omitempty"`
+	StrictAssets      bool `json:"strictAssets,omitempty"`
+
+	// See template.OnlyComponents and ComponentBounds.
+	OnlyComponents []string `json:"onlyComponents,omitempty"`
+	Crop           bool     `json:"crop,omitempty"`
+	CropPadding    int      `json:"cropPadding,omitempty"`
 }
 
-func (s *srv) renderImage(body []byte) ([]byte, error) {
-	var req renderRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		return nil, fmt.Errorf("decode request: %w", err)
-	}
+// renderResult is a rendered image and the non-fatal problems met producing it.
+type renderResult struct {
+	img      image.Image
+	warnings []template.RenderWarning
+	order    []string // IDs of the drawn components, bottom to top
+	profile  *template.RenderProfile
+	used     []template.AssetUse // assets the render read
 
-	var preset template.Preset
-	if err := json.Unmarshal(req.Preset, &preset); err != nil {
-		return nil, fmt.Errorf("parse preset: %w", err)
-	}
+	buf  *image.RGBA         // the canvas img is, or is cropped from
+	pool *template.ImagePool // buf goes back here on release
+}
 
-	// Apply canvas preset.
-	if dims, ok := template.Presets[preset.Canvas.Preset]; ok {
-		preset.Canvas.Width = dims[0]
-		preset.Canvas.Height = dims[1]
+// release returns the image's buffer for the next render once the caller
+// has encoded it; img must not be used afterwards.
+func (res *renderResult) release() {
+	if res.buf != nil && res.pool != nil {
+		res.pool.Put(res.buf)
 	}
-	preset.Canvas.Width = max(preset.Canvas.Width, 320)
-	preset.Canvas.Height = max(preset.Canvas.Height, 240)
+	res.img, res.buf = nil, nil
+}
+
+// render renders a decoded request within a limiter slot and the render
+// timeout. Errors are apiErrors, *template.ComponentError (see writeErr),
+// or ctx's error when the caller went away.
+func (s *srv) render(ctx context.Context, req renderRequest) (res *renderResult, err error) {
+	start := time.Now()
+	defer func() { metrics.observeRender(time.Since(start), err) }()
 
-	if preset.Background.Color == "" {
-		preset.Background.Color = "#1a1a2e"
+	ctx, cancel := context.WithTimeout(ctx, s.renderTimeout)
+	defer cancel()
+	release, err := s.limiter.acquire(ctx)
+	if err != nil {
+		return nil, s.timeoutError(err)
 	}
+	defer release()
 
-	// Resolve asset references to temp files.
-	fontPath := s.resolveAssetPath(preset.Font.Path)
-	preset.Background.Source = s.resolveAssetPath(preset.Background.Source)
-	for i := range preset.Components {
-		preset.Components[i].Style.BackgroundImage = s.resolveAssetPath(preset.Components[i].Style.BackgroundImage)
-		preset.Components[i].Style.FontPath = s.resolveAssetPath(preset.Components[i].Style.FontPath)
-		applyCompDefaults(&preset.Components[i])
+	if req.CropPadding < 0 {
+		return nil, errorf(http.StatusBadRequest, "BAD_REQUEST", "cropPadding %d: must not be negative", req.CropPadding)
+	}
+	inline, err := s.checkInlineAssets(req.Assets)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := parsePreset(req.Preset)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse data.
-	var data *template.DataSpec
-	if len(req.Data) > 0 && string(req.Data) != "null" && string(req.Data) != "{}" {
-		var d template.DataSpec
-		if err := json.Unmarshal(req.Data, &d); err == nil {
-			data = &d
+	// Unusable data falls back to preset defaults, with a warning.
+	var warnings []template.RenderWarning
+	data, err := parseData(req.Data)
+	if err != nil {
+		warnings = append(warnings, template.RenderWarning{Message: err.Error()})
+	}
+	for _, w := range template.ValidateData(data, preset) {
+		if w.Level == template.SeverityWarning { // /api/validate reports the rest
+			warnings = append(warnings, template.RenderWarning{Message: w.Message})
 		}
 	}
 
 	// Merge + render.
-	components := template.MergeData(&preset, data)
-	renderer, err := template.NewRenderer(fontPath)
+	components := template.MergeData(preset, data)
+	if len(req.OnlyComponents) > 0 {
+		var unknown []string
+		components, unknown = template.OnlyComponents(preset, components, req.OnlyComponents)
+		for _, msg := range unknown {
+			warnings = append(warnings, template.RenderWarning{Message: msg})
+		}
+	}
+	renderer, err := template.NewRendererWithOptions(template.RendererOptions{
+		Font:          preset.Font,
+		Resolve:       s.resolver(inline),
+		NoAssetFiles:  true,
+		MaxCanvasSize: s.maxCanvas,
+	})
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "BAD_FONT", "renderer: %v", err)
+	}
+	if err := renderer.CheckCanvas(preset.Canvas); err != nil {
+		return nil, errorf(http.StatusBadRequest, "BAD_PRESET", "%v", err)
+	}
+	renderer.SetShowMissingAssets(req.ShowMissingAssets)
+	renderer.SetStrictAssets(req.StrictAssets)
+	renderer.SetProfile(true)
+	renderer.SetMaxImageSize(s.maxImageSize)
+	renderer.SetImageCache(s.images)
+
+	dst := s.buffers.Get(preset.Canvas.Width, preset.Canvas.Height)
+	img, err := renderer.RenderPresetInto(ctx, dst, preset, components)
 	if err != nil {
-		return nil, fmt.Errorf("renderer: %w", err)
+		s.buffers.Put(dst)
+		return nil, s.timeoutError(err)
+	}
+	order := make([]string, len(components))
+	for i, c := range components {
+		order[i] = c.ID
+	}
+	res = &renderResult{
+		img:      img,
+		warnings: append(warnings, renderer.Warnings()...),
+		order:    order,
+		profile:  renderer.Profile(),
+		used:     renderer.AssetsUsed(),
+		buf:      img,
+		pool:     &s.buffers,
+	}
+	if req.Crop {
+		if box := template.ComponentBounds(preset, components, req.CropPadding); !box.Empty() {
+			res.img = img.SubImage(box)
+		} else {
+			res.warnings = append(res.warnings, template.RenderWarning{Message: "crop: no components drawn; returning the whole canvas"})
+		}
 	}
+	return res, nil
+}
 
-	img, err := renderer.RenderPreset(&preset, components)
+// parsePreset decodes and normalizes an editor preset. Its asset
+// references stay as they are, for the resolver.
+func parsePreset(raw json.RawMessage) (*template.Preset, error) {
+	preset, err := template.DecodePreset(raw)
 	if err != nil {
-		return nil, fmt.Errorf("render: %w", err)
+		return nil, errorf(http.StatusBadRequest, "BAD_PRESET", "parse preset: %v", err)
+	}
+	return preset, nil
+}
+
+// resolver serves a request's asset references from memory: inline assets
+// by key, then stored assets by ID. Anything else is a missing asset: the
+// server's renderers and lints are made with NoAssetFiles, so no reference
+// is read as a file.
+func (s *srv) resolver(inline map[string][]byte) template.AssetResolverFunc {
+	return func(ref string) []byte {
+		if data, ok := inline[ref]; ok {
+			return data
+		}
+		if !validAssetID(ref) {
+			return nil
+		}
+		if a, ok := s.assets.get(ref); ok {
+			return a.Data
+		}
+		return nil
 	}
+}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, fmt.Errorf("encode PNG: %w", err)
+// parseData decodes request data; empty data is nil. The error is a
+// "data ignored" message callers report as a warning.
+func parseData(raw json.RawMessage) (*template.DataSpec, error) {
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == "{}" {
+		return nil, nil
+	}
+	var d template.DataSpec
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("data ignored: %v", err)
 	}
-	return buf.Bytes(), nil
+	return &d, nil
 }
 
-func (s *srv) handleRender(w http.ResponseWriter, r *http.Request) {
-	body, _ := io.ReadAll(r.Body)
-	data, err := s.renderImage(body)
+// timeoutError reports a render deadline as 503 RENDER_TIMEOUT; other
+// errors pass through.
+func (s *srv) timeoutError(err error) error {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	e := errorf(http.StatusServiceUnavailable, "RENDER_TIMEOUT", "render did not finish within %s", s.renderTimeout)
+	e.RetryAfter = retryAfterBusy
+	return e
+}
+
+// setWarningsHeader reports render warnings in X-GoStencil-Warnings as a
+// JSON array (non-ASCII escaped so the header stays valid).
+func setWarningsHeader(w http.ResponseWriter, warnings []template.RenderWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	b, err := json.Marshal(warnings)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(data)
+	w.Header().Set("X-GoStencil-Warnings", asciiJSON(b))
 }
 
-// ── Export ──
-
-func (s *srv) handleExportPNG(w http.ResponseWriter, r *http.Request) {
-	body, _ := io.ReadAll(r.Body)
-	data, err := s.renderImage(body)
+// setProfileHeader reports a render's timings in X-GoStencil-Profile as
+// JSON, durations in nanoseconds (non-ASCII escaped, like the warnings).
+func setProfileHeader(w http.ResponseWriter, profile *template.RenderProfile) {
+	if profile == nil {
+		return
+	}
+	b, err := json.Marshal(profile)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Disposition", `attachment; filename="output.png"`)
-	w.Write(data)
+	w.Header().Set("X-GoStencil-Profile", asciiJSON(b))
 }
 
-func (s *srv) handleExportAVI(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Preset   json.RawMessage `json:"preset"`
-		Data     json.RawMessage `json:"data"`
-		Duration int             `json:"duration"`
+// asciiJSON rewrites non-ASCII characters in JSON text as \uXXXX escapes.
+func asciiJSON(b []byte) string {
+	var sb strings.Builder
+	for _, r := range string(b) {
+		switch {
+		case r < 0x80:
+			sb.WriteRune(r)
+		case r > 0xFFFF:
+			hi, lo := utf16.EncodeRune(r)
+			fmt.Fprintf(&sb, "\\u%04x\\u%04x", hi, lo)
+		default:
+			fmt.Fprintf(&sb, "\\u%04x", r)
+		}
 	}
-	body, _ := io.ReadAll(r.Body)
-	if err := json.Unmarshal(body, &req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	return sb.String()
+}
+
+func (s *srv) handleRender(w http.ResponseWriter, r *http.Request) {
+	var req renderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
 		return
 	}
+	asJSON := r.URL.Query().Get("format") == "json"
+	start := time.Now()
+
+	// Identical requests share an ETag and, while cached, the PNG.
+	key, assets := s.renderKey(req)
+	var (
+		out *cachedRender
+		hit bool
+	)
+	if key != "" {
+		etag := `"` + key + `"`
+		if asJSON {
+			etag = `"` + key + `-json"`
+		}
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		out, hit = s.cache.get(key)
+	}
+	if !hit {
+		res, err := s.render(r.Context(), req)
+		if err != nil {
+			w.Header().Del("ETag")
+			writeErr(w, err)
+			return
+		}
+		var buf bytes.Buffer
+		err = generator.GenerateToWriter(&buf, ".png", generator.Config{Image: res.img})
+		bounds := res.img.Bounds()
+		res.release()
+		if err != nil {
+			w.Header().Del("ETag")
+			writeErr(w, err)
+			return
+		}
+		out = &cachedRender{key: key, png: buf.Bytes(), bounds: bounds, warnings: res.warnings, order: res.order, profile: res.profile, used: res.used, assets: assets}
+		if key != "" {
+			s.cache.put(out)
+		}
+	}
+	if hit {
+		w.Header().Set("X-GoStencil-Cache", "hit")
+	} else {
+		w.Header().Set("X-GoStencil-Cache", "miss")
+	}
 
-	pngData, err := s.renderImage(body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// ?format=json returns the image inline with its warnings and the
+	// assets it read. A cache hit reports the profile of the render that
+	// was cached.
+	if asJSON {
+		warnings, used := out.warnings, out.used
+		if warnings == nil {
+			warnings = []template.RenderWarning{}
+		}
+		if used == nil {
+			used = []template.AssetUse{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"image_base64": base64.StdEncoding.EncodeToString(out.png),
+			"warnings":     warnings,
+			"paint_order":  out.order,
+			"width":        out.bounds.Dx(),
+			"height":       out.bounds.Dy(),
+			"elapsed_ms":   time.Since(start).Milliseconds(),
+			"profile":      out.profile,
+			"assets_used":  used,
+		})
 		return
 	}
 
-	img, err := png.Decode(bytes.NewReader(pngData))
+	setWarningsHeader(w, out.warnings)
+	setProfileHeader(w, out.profile)
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(out.png)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ── Export ──
+
+// mediaFormat is a rendered-output format served by /api/export/{format}
+// and background jobs.
+type mediaFormat struct {
+	ext, mime string
+}
+
+// mediaTypes are the Content-Types of the built-in formats, by extension.
+// Other registered formats get mime.TypeByExtension's, or a generic one.
+var mediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".avi":  "video/avi",
+}
+
+// lookupFormat resolves a format name, any extension registered with the
+// generator without its dot ("jpeg" is written as .jpg).
+func lookupFormat(name string) (mediaFormat, error) {
+	ext := generator.NormalizeExt(name)
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	if slices.Contains(generator.SupportedFormats(), ext) {
+		typ := cmp.Or(mediaTypes[ext], mime.TypeByExtension(ext), "application/octet-stream")
+		return mediaFormat{ext, typ}, nil
+	}
+	var supported []string
+	for _, ext := range generator.SupportedFormats() {
+		supported = append(supported, ext[1:])
+	}
+	return mediaFormat{}, errorf(http.StatusBadRequest, "UNSUPPORTED_FORMAT", "unsupported format %q: use %s", name, strings.Join(supported, ", "))
+}
+
+// exportRequest is a renderRequest plus encoder options.
+type exportRequest struct {
+	renderRequest
+	Duration float64 `json:"duration"` // seconds, avi and gif
+	FPS      int     `json:"fps"`      // gif
+	Quality  int     `json:"quality"`  // 1–100, jpeg
+	OddSize  string  `json:"oddSize"`  // "pad" or "crop", avi
+	Matte    string  `json:"matte"`    // "#rrggbb" under transparency, jpeg, gif and avi
+}
+
+// check rejects encoder options the generator would refuse, before any
+// rendering is done.
+func (req exportRequest) check() error {
+	switch req.OddSize {
+	case "", generator.OddSizePad, generator.OddSizeCrop:
+	default:
+		return errorf(http.StatusBadRequest, "BAD_REQUEST", "oddSize %q: use %q or %q", req.OddSize, generator.OddSizePad, generator.OddSizeCrop)
+	}
+	if _, err := generator.ParseMatte(req.Matte); err != nil {
+		return errorf(http.StatusBadRequest, "BAD_REQUEST", "matte: %v", err)
+	}
+	if _, err := generator.FrameCount(req.Duration, generator.MaxGIFFPS); err != nil || req.Duration < 0 {
+		return errorf(http.StatusBadRequest, "BAD_REQUEST", "duration %g: use 0 to %d seconds", req.Duration, generator.MaxFrames/generator.MaxGIFFPS)
+	}
+	return nil
+}
+
+func (req exportRequest) config(img image.Image) generator.Config {
+	return generator.Config{
+		Image:           img,
+		Duration:        1,
+		DurationSeconds: req.Duration,
+		FPS:             req.FPS,
+		Quality:         req.Quality,
+		OddSize:         req.OddSize,
+		Matte:           req.Matte,
+	}
+}
+
+// handleExportMedia renders the request and streams it in the path's format.
+func (s *srv) handleExportMedia(w http.ResponseWriter, r *http.Request) {
+	format, err := lookupFormat(r.PathValue("format"))
 	if err != nil {
-		http.Error(w, "decode rendered image: "+err.Error(), http.StatusInternalServerError)
+		writeErr(w, err)
 		return
 	}
-
-	dur := max(req.Duration, 1)
-	tmpPath := filepath.Join(s.tmpDir, "export_"+randomID()+".avi")
-	cfg := generator.Config{Image: img, Duration: dur}
-	if err := generator.Generate(tmpPath, cfg); err != nil {
-		http.Error(w, "generate AVI: "+err.Error(), http.StatusInternalServerError)
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if err := req.check(); err != nil {
+		writeErr(w, err)
 		return
 	}
-	defer os.Remove(tmpPath)
 
-	aviData, err := os.ReadFile(tmpPath)
+	res, err := s.render(r.Context(), req.renderRequest)
 	if err != nil {
-		http.Error(w, "read AVI: "+err.Error(), http.StatusInternalServerError)
+		writeErr(w, err)
 		return
 	}
+	defer res.release()
+
+	// Stream straight to the client. Until the first byte goes out a
+	// failure can still be reported as a normal error response.
+	setWarningsHeader(w, res.warnings)
+	w.Header().Set("Content-Type", format.mime)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="output%s"`, format.ext))
+	sw := &sentWriter{w: w}
+	cfg := req.config(res.img)
+	cfg.Warn = func(msg string) {
+		// Encoder notes come before the first byte, so the header can
+		// still be updated.
+		res.warnings = append(res.warnings, template.RenderWarning{Message: msg})
+		setWarningsHeader(w, res.warnings)
+	}
+	err = generator.GenerateToWriter(sw, format.ext, cfg)
+	metrics.addExport(format.ext[1:], sw.n)
+	if err != nil {
+		if sw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			writeErr(w, fmt.Errorf("generate %s: %w", r.PathValue("format"), err))
+			return
+		}
+		slog.Warn("export aborted", "format", r.PathValue("format"), "error", err)
+	}
+}
+
+// sentWriter counts the bytes written through it.
+type sentWriter struct {
+	w io.Writer
+	n int64
+}
 
-	w.Header().Set("Content-Type", "video/avi")
-	w.Header().Set("Content-Disposition", `attachment; filename="output.avi"`)
-	w.Write(aviData)
+func (sw *sentWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	sw.n += int64(n)
+	return n, err
 }
 
 func (s *srv) handleExportGSPresets(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Preset json.RawMessage `json:"preset"`
+		ID     string          `json:"id"` // stored preset to export instead of Preset
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
-	var buf bytes.Buffer
-	zw := zip.NewWriter(&buf)
-
-	// Write preset.json (pretty-printed).
-	pw, _ := zw.Create("preset.json")
-	var prettyPreset bytes.Buffer
-	json.Indent(&prettyPreset, req.Preset, "", "  ")
-	pw.Write(prettyPreset.Bytes())
+	filename := "preset.gspresets"
+	var preview []byte
+	if req.ID != "" {
+		stored, ok := s.presets.get(req.ID)
+		if !ok {
+			writeNotFound(w, "preset", req.ID)
+			return
+		}
+		req.Preset, preview = stored.Preset, stored.thumb
+		filename = sanitizeFilename(stored.Name) + ".gspresets"
+	}
 
-	// Write all uploaded assets.
-	s.assets.mu.RLock()
-	for id, a := range s.assets.assets {
-		ext := extensionForMime(a.Mime)
-		aw, _ := zw.Create("assets/" + id + ext)
-		aw.Write(a.Data)
+	// A preset that cannot be rendered is still exported, without a
+	// preview.
+	var warnings []template.RenderWarning
+	if preview == nil {
+		var err error
+		if preview, err = s.presetThumbnail(r.Context(), req.Preset); err != nil {
+			slog.Warn("bundle preview failed", "err", err)
+			warnings = append(warnings, template.RenderWarning{Message: "preview omitted: " + err.Error()})
+		}
 	}
-	s.assets.mu.RUnlock()
 
-	zw.Close()
+	// Bundle only the referenced assets, under readable names, and point
+	// the preset at them so LoadPreset resolves them relative to the bundle.
+	var buf bytes.Buffer
+	err := template.WriteBundleWithPreview(&buf, req.Preset, func(id string) (template.BundleAsset, bool) {
+		a, ok := s.assets.get(id)
+		if !ok {
+			return template.BundleAsset{}, false
+		}
+		return template.BundleAsset{Name: a.Name, Mime: a.Mime, Data: a.Data}, true
+	}, preview)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_PRESET", err.Error())
+		return
+	}
 
+	metrics.addExport("gspresets", int64(buf.Len()))
+	setWarningsHeader(w, warnings)
 	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", `attachment; filename="preset.gspresets"`)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
 	w.Write(buf.Bytes())
 }
 
@@ -323,7 +973,7 @@ func (s *srv) handleExportJSON(w http.ResponseWriter, r *http.Request) {
 		Content json.RawMessage `json:"content"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
@@ -339,57 +989,88 @@ func (s *srv) handleExportJSON(w http.ResponseWriter, r *http.Request) {
 // ── Import ──
 
 func (s *srv) handleImportGSPresets(w http.ResponseWriter, r *http.Request) {
-	r.ParseMultipartForm(50 << 20)
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "no file uploaded", http.StatusBadRequest)
+	data, _, ok := readUpload(w, r)
+	if !ok {
 		return
 	}
-	defer file.Close()
-
-	data, _ := io.ReadAll(file)
-	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
-	if err != nil {
-		http.Error(w, "invalid ZIP: "+err.Error(), http.StatusBadRequest)
+	bundle, err := template.LoadPresetFromReaderWithLimits(bytes.NewReader(data), int64(len(data)), bundleLimits(s.maxUpload))
+	switch {
+	case errors.Is(err, template.ErrBundleTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "BAD_ARCHIVE", err.Error())
 		return
 	}
 
-	var presetJSON json.RawMessage
-	importedAssets := make([]map[string]string, 0)
-
-	for _, f := range zr.File {
-		if f.FileInfo().IsDir() {
+	// Check every asset before adding any.
+	type entry struct {
+		name, mime string
+		data       []byte
+	}
+	var (
+		presetJSON = json.RawMessage(bundle.Resolve("preset.json"))
+		preview    []byte
+		entries    []entry
+	)
+	// A preview that does not decode is dropped, not an error.
+	if m, err := s.checkImage(bundle.Preset.Preview); err == nil && m == "image/png" {
+		preview = bundle.Preset.Preview
+	}
+	for _, name := range bundle.Files() {
+		if name == "preset.json" || name == template.PreviewName {
 			continue
 		}
-		rc, _ := f.Open()
-		fdata, _ := io.ReadAll(rc)
-		rc.Close()
-
-		if f.Name == "preset.json" {
-			presetJSON = fdata
-		} else {
-			mimeType := mime.TypeByExtension(filepath.Ext(f.Name))
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
-			id := s.assets.add(filepath.Base(f.Name), fdata, mimeType)
-			importedAssets = append(importedAssets, map[string]string{
-				"id":           id,
-				"name":         filepath.Base(f.Name),
-				"originalPath": f.Name,
-				"url":          "/api/assets/" + id,
-			})
+		fdata := bundle.Resolve(name)
+		mimeType := mime.TypeByExtension(filepath.Ext(name))
+		switch {
+		case isFontName(name):
+			mimeType, err = checkFont(fdata)
+		case strings.HasPrefix(mimeType, "image/"):
+			mimeType, err = s.checkImage(fdata)
+		case mimeType == "":
+			mimeType = "application/octet-stream"
 		}
+		if err != nil {
+			writeError(w, http.StatusUnsupportedMediaType, "BAD_ASSET", fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		entries = append(entries, entry{name: name, mime: mimeType, data: fdata})
 	}
 
-	if presetJSON == nil {
-		http.Error(w, "no preset.json found in archive", http.StatusBadRequest)
-		return
+	importedAssets := make([]map[string]interface{}, 0, len(entries))
+	ids := make(map[string]string, len(entries))
+	for _, e := range entries {
+		id, _, err := s.assets.add(filepath.Base(e.name), e.data, e.mime)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		ids[e.name] = id
+		importedAssets = append(importedAssets, map[string]interface{}{
+			"id":           id,
+			"name":         filepath.Base(e.name),
+			"originalPath": e.name,
+			"mime":         e.mime,
+			"size":         len(e.data),
+			"url":          "/api/assets/" + id,
+		})
 	}
 
+	// Point bundle-relative references back at the new asset IDs, then
+	// check the result the way a render would see it.
+	presetJSON = template.RewriteAssetRefs(presetJSON, ids)
+	warnings := []template.Issue{}
+	if preset, err := parsePreset(presetJSON); err == nil {
+		warnings = append(warnings, template.LintWithOptions(preset, nil, template.LintOptions{Resolve: s.resolver(nil), NoAssetFiles: true, MaxCanvasSize: s.maxCanvas})...)
+	}
 	resp := map[string]interface{}{
-		"preset": presetJSON,
-		"assets": importedAssets,
+		"preset":   presetJSON,
+		"assets":   importedAssets,
+		"warnings": warnings,
+	}
+	if preview != nil {
+		resp["preview"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(preview)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -398,47 +1079,56 @@ func (s *srv) handleImportGSPresets(w http.ResponseWriter, r *http.Request) {
 // ── Upload ──
 
 func (s *srv) handleUploadFont(w http.ResponseWriter, r *http.Request) {
-	r.ParseMultipartForm(10 << 20)
-	file, header, err := r.FormFile("file")
+	data, header, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+	mimeType, err := checkFont(data)
 	if err != nil {
-		http.Error(w, "no file", http.StatusBadRequest)
+		writeError(w, http.StatusUnsupportedMediaType, "BAD_FONT", err.Error())
 		return
 	}
-	defer file.Close()
-
-	data, _ := io.ReadAll(file)
-	id := s.assets.add(header.Filename, data, "font/ttf")
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"id":   id,
-		"name": header.Filename,
-		"url":  "/api/assets/" + id,
-	})
+	id, created, err := s.assets.add(header.Filename, data, mimeType)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	s.writeUploaded(w, id, created)
 }
 
 func (s *srv) handleUploadImage(w http.ResponseWriter, r *http.Request) {
-	r.ParseMultipartForm(10 << 20)
-	file, header, err := r.FormFile("file")
+	data, header, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+	mimeType, err := s.checkImage(data)
 	if err != nil {
-		http.Error(w, "no file", http.StatusBadRequest)
+		writeError(w, http.StatusUnsupportedMediaType, "BAD_IMAGE", err.Error())
 		return
 	}
-	defer file.Close()
-
-	data, _ := io.ReadAll(file)
-	mimeType := mime.TypeByExtension(filepath.Ext(header.Filename))
-	if mimeType == "" {
-		mimeType = "image/png"
+	id, created, err := s.assets.add(header.Filename, data, mimeType)
+	if err != nil {
+		writeErr(w, err)
+		return
 	}
-	id := s.assets.add(header.Filename, data, mimeType)
+	s.writeUploaded(w, id, created)
+}
 
+// writeUploaded answers an upload. A re-upload of stored content gets the
+// existing asset (and its original name) with "existing": true.
+func (s *srv) writeUploaded(w http.ResponseWriter, id string, created bool) {
+	resp := map[string]interface{}{
+		"id":  id,
+		"url": "/api/assets/" + id,
+	}
+	if a, ok := s.assets.get(id); ok {
+		resp["name"] = a.Name
+	}
+	if !created {
+		resp["existing"] = true
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"id":   id,
-		"name": header.Filename,
-		"url":  "/api/assets/" + id,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // ── Asset serving ──
@@ -447,63 +1137,90 @@ func (s *srv) handleGetAsset(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	a, ok := s.assets.get(id)
 	if !ok {
-		http.NotFound(w, r)
+		writeNotFound(w, "asset", id)
 		return
 	}
 	w.Header().Set("Content-Type", a.Mime)
+	// An uploaded SVG opened directly must not run its scripts here.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
 	w.Write(a.Data)
 }
 
 func (s *srv) handleListAssets(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s.assets.listAll())
+	json.NewEncoder(w).Encode(s.assets.listAll(s.presets.assetRefs()))
+}
+
+// handleCanvasPresets lists the canvas preset names a preset may use,
+// including any registered from the CLI config file.
+func (s *srv) handleCanvasPresets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template.CanvasPresets())
 }
 
 func (s *srv) handleDeleteAsset(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	_, ok := s.assets.get(id)
 	if !ok {
-		http.NotFound(w, r)
+		writeNotFound(w, "asset", id)
+		return
+	}
+	if users := s.presets.assetRefs()[id]; len(users) > 0 {
+		writeError(w, http.StatusConflict, "ASSET_IN_USE",
+			fmt.Sprintf("asset %s is used by %d stored preset(s): %s", id, len(users), strings.Join(users, ", ")))
 		return
 	}
-	s.assets.remove(id)
+	if err := s.assets.remove(id); err != nil {
+		writeErr(w, err)
+		return
+	}
+	s.cache.invalidate(id)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
 }
 
-// ── Helpers ──
-
-func applyCompDefaults(c *template.Component) {
-	s := &c.Style
-	if s.FontSize <= 0 {
-		s.FontSize = 24
-	}
-	if s.Color == "" {
-		s.Color = "#ffffff"
-	}
-	if s.LineHeight <= 0 {
-		s.LineHeight = 1.5
-	}
-	if s.TextAlign == "" {
-		s.TextAlign = "left"
+// handlePruneAssets deletes every asset no stored preset references, as
+// DELETE /api/assets/{id} would one by one. ?dry_run=true lists them
+// without deleting. Assets uploaded for a preset not yet saved go too.
+func (s *srv) handlePruneAssets(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	unused := s.assets.unreferenced(s.presets.assetRefs())
+	var freed int
+	for _, a := range unused {
+		if !dryRun {
+			if err := s.assets.remove(a.ID); err != nil {
+				writeErr(w, err)
+				return
+			}
+			s.cache.invalidate(a.ID)
+		}
+		freed += a.Size
 	}
-	if c.Defaults.Visible == nil {
-		t := true
-		c.Defaults.Visible = &t
+	if !dryRun && len(unused) > 0 {
+		slog.Info("assets pruned", "count", len(unused), "bytes", freed)
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"dry_run": dryRun,
+		"deleted": unused,
+		"bytes":   freed,
+	})
 }
 
-func (s *srv) resolveAssetPath(path string) string {
-	if path == "" {
-		return ""
+// ── Helpers ──
+
+// validAssetID reports whether id has the form of the IDs the asset
+// manager hands out: 16 lowercase hex digits.
+func validAssetID(id string) bool {
+	if len(id) != 16 {
+		return false
 	}
-	a, ok := s.assets.get(path)
-	if !ok {
-		return path
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
 	}
-	tmpPath := filepath.Join(s.tmpDir, path+"_"+sanitizeFilename(a.Name))
-	os.WriteFile(tmpPath, a.Data, 0644)
-	return tmpPath
+	return true
 }
 
 func sanitizeFilename(name string) string {
@@ -512,29 +1229,3 @@ func sanitizeFilename(name string) string {
 	name = strings.ReplaceAll(name, " ", "_")
 	return name
 }
-
-func extensionForMime(m string) string {
-	switch {
-	case strings.Contains(m, "ttf"), strings.Contains(m, "font"):
-		return ".ttf"
-	case strings.Contains(m, "png"):
-		return ".png"
-	case strings.Contains(m, "jpeg"), strings.Contains(m, "jpg"):
-		return ".jpg"
-	default:
-		return ""
-	}
-}
-
-func openBrowser(url string) {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
-	case "darwin":
-		cmd = exec.Command("open", url)
-	default:
-		cmd = exec.Command("xdg-open", url)
-	}
-	cmd.Start()
-}
@@ -0,0 +1,191 @@
+// sysfonts.go — Fonts installed on the machine (--allow-system-fonts).
+//
+//	GET  /api/fonts/system  [{"family","style","path"}, …]
+//	POST /api/fonts/use     {"path"} → {"id","name","url"}, like an upload
+//
+// Off by default because the list exposes filesystem paths. The platform
+// font directories are scanned once, on first use. A used font is copied
+// into the asset store, so renders, bundles and --data-dir treat it like an
+// uploaded one; only paths from the scan are accepted.
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// systemFont is one installed font file.
+type systemFont struct {
+	Family string `json:"family"`
+	Style  string `json:"style"`
+	Path   string `json:"path"`
+}
+
+// systemFontIndex caches the font directory scan and the assets created
+// from it.
+type systemFontIndex struct {
+	once   sync.Once
+	fonts  []systemFont
+	byPath map[string]bool
+
+	mu     sync.Mutex
+	assets map[string]string // path → asset ID
+}
+
+func newSystemFontIndex() *systemFontIndex {
+	return &systemFontIndex{assets: make(map[string]string)}
+}
+
+// list scans the font directories on first call.
+func (x *systemFontIndex) list() []systemFont {
+	x.once.Do(func() {
+		start := time.Now()
+		x.fonts = scanSystemFonts(systemFontDirs())
+		x.byPath = make(map[string]bool, len(x.fonts))
+		for _, f := range x.fonts {
+			x.byPath[f.Path] = true
+		}
+		slog.Debug("system fonts scanned", "fonts", len(x.fonts), "elapsed", time.Since(start))
+	})
+	return x.fonts
+}
+
+// systemFontDirs returns the directories this platform installs fonts in.
+func systemFontDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		dirs := []string{filepath.Join(cmp.Or(os.Getenv("WINDIR"), `C:\Windows`), "Fonts")}
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			dirs = append(dirs, filepath.Join(local, "Microsoft", "Windows", "Fonts"))
+		}
+		return dirs
+	case "darwin":
+		return []string{"/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts")}
+	default:
+		dirs := []string{filepath.Join(cmp.Or(os.Getenv("XDG_DATA_HOME"), filepath.Join(home, ".local", "share")), "fonts")}
+		for _, d := range filepath.SplitList(cmp.Or(os.Getenv("XDG_DATA_DIRS"), "/usr/local/share:/usr/share")) {
+			dirs = append(dirs, filepath.Join(d, "fonts"))
+		}
+		return append(dirs, filepath.Join(home, ".fonts"))
+	}
+}
+
+// scanSystemFonts walks dirs for TTF/OTF files and reads their name tables.
+// Collections (.ttc) are skipped: an entry names one font, and using one
+// from a collection takes font.index or font.family as well as the file.
+func scanSystemFonts(dirs []string) []systemFont {
+	var fonts []systemFont
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			ext := strings.ToLower(filepath.Ext(path))
+			if err != nil || d.IsDir() || (ext != ".ttf" && ext != ".otf") || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			f, err := opentype.Parse(data)
+			if err != nil {
+				slog.Debug("skipping unparseable system font", "path", path, "error", err)
+				return nil
+			}
+			family, _ := f.Name(nil, sfnt.NameIDFamily)
+			style, _ := f.Name(nil, sfnt.NameIDSubfamily)
+			fonts = append(fonts, systemFont{
+				Family: cmp.Or(family, strings.TrimSuffix(d.Name(), filepath.Ext(path))),
+				Style:  style,
+				Path:   path,
+			})
+			return nil
+		})
+	}
+	slices.SortFunc(fonts, func(a, b systemFont) int {
+		return cmp.Or(cmp.Compare(a.Family, b.Family), cmp.Compare(a.Style, b.Style), cmp.Compare(a.Path, b.Path))
+	})
+	return fonts
+}
+
+// ── Handlers ──
+
+func (s *srv) handleSystemFonts(w http.ResponseWriter, r *http.Request) {
+	if s.sysFonts == nil {
+		writeSystemFontsDisabled(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.sysFonts.list())
+}
+
+func (s *srv) handleUseSystemFont(w http.ResponseWriter, r *http.Request) {
+	if s.sysFonts == nil {
+		writeSystemFontsDisabled(w)
+		return
+	}
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	x := s.sysFonts
+	x.list()
+	if !x.byPath[req.Path] {
+		writeNotFound(w, "system font", req.Path)
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	name := filepath.Base(req.Path)
+	id, ok := x.assets[req.Path]
+	if _, exists := s.assets.get(id); !ok || !exists {
+		data, err := os.ReadFile(req.Path)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		if int64(len(data)) > int64(s.maxUpload) {
+			writeError(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "font is larger than --max-upload")
+			return
+		}
+		mimeType, err := checkFont(data)
+		if err != nil {
+			writeError(w, http.StatusUnsupportedMediaType, "BAD_FONT", err.Error())
+			return
+		}
+		if id, _, err = s.assets.add(name, data, mimeType); err != nil {
+			writeErr(w, err)
+			return
+		}
+		x.assets[req.Path] = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":   id,
+		"name": name,
+		"url":  "/api/assets/" + id,
+	})
+}
+
+func writeSystemFontsDisabled(w http.ResponseWriter) {
+	writeError(w, http.StatusForbidden, "FORBIDDEN", "system fonts are disabled; start the server with --allow-system-fonts")
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// TestUploadErrors checks the status and code oversized and malformed
+// uploads get, and that none of them is stored.
+func TestUploadErrors(t *testing.T) {
+	png := testPNG(t, 64, 48)
+
+	tests := []struct {
+		name      string
+		path      string
+		data      []byte
+		field     string // form field; "" for "file"
+		cut       int    // bytes cut off the end of the form
+		rawType   string // if set, data is sent as the body with this type
+		maxCanvas int
+		status    int
+		code      string
+	}{
+		{name: "image", path: "/api/upload/image", data: png, status: http.StatusOK},
+		{name: "font", path: "/api/upload/font", data: goregular.TTF, status: http.StatusOK},
+
+		{name: "image over --max-upload", path: "/api/upload/image", data: bytes.Repeat(png, 1+(1<<20)/len(png)), status: http.StatusRequestEntityTooLarge, code: "TOO_LARGE"},
+		{name: "font over --max-upload", path: "/api/upload/font", data: make([]byte, 2<<20), status: http.StatusRequestEntityTooLarge, code: "TOO_LARGE"},
+		{name: "image over --max-canvas", path: "/api/upload/image", data: png, maxCanvas: 32, status: http.StatusUnsupportedMediaType, code: "BAD_IMAGE"},
+
+		{name: "not an image", path: "/api/upload/image", data: []byte("not a png"), status: http.StatusUnsupportedMediaType, code: "BAD_IMAGE"},
+		{name: "truncated image", path: "/api/upload/image", data: png[:len(png)/2], status: http.StatusUnsupportedMediaType, code: "BAD_IMAGE"},
+		{name: "not a font", path: "/api/upload/font", data: []byte("not a font"), status: http.StatusUnsupportedMediaType, code: "BAD_FONT"},
+		{name: "truncated font", path: "/api/upload/font", data: goregular.TTF[:1000], status: http.StatusUnsupportedMediaType, code: "BAD_FONT"},
+
+		{name: "not multipart", path: "/api/upload/image", data: png, rawType: "application/octet-stream", status: http.StatusBadRequest, code: "BAD_REQUEST"},
+		{name: "no boundary", path: "/api/upload/image", data: png, rawType: "multipart/form-data", status: http.StatusBadRequest, code: "BAD_REQUEST"},
+		{name: "wrong field", path: "/api/upload/image", data: png, field: "image", status: http.StatusBadRequest, code: "NO_FILE"},
+		{name: "cut short", path: "/api/upload/font", data: goregular.TTF, cut: 100, status: http.StatusBadRequest, code: "BAD_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.maxUpload = 1 << 20
+			s.maxCanvas = tt.maxCanvas
+
+			contentType, body := tt.rawType, tt.data
+			if tt.rawType == "" {
+				var form bytes.Buffer
+				mw := multipart.NewWriter(&form)
+				fw, _ := mw.CreateFormFile(cmp.Or(tt.field, "file"), "upload.bin")
+				fw.Write(tt.data)
+				mw.Close()
+				contentType, body = mw.FormDataContentType(), form.Bytes()[:form.Len()-tt.cut]
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			s.handler(fstest.MapFS{}, "", nil).ServeHTTP(rec, req)
+
+			var resp struct {
+				ID    string
+				Error struct{ Code, Message string }
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("status %d, body not JSON: %v", rec.Code, err)
+			}
+			if rec.Code != tt.status || resp.Error.Code != tt.code {
+				t.Fatalf("status %d %s (%s), want %d %s", rec.Code, resp.Error.Code, resp.Error.Message, tt.status, tt.code)
+			}
+			want := 0
+			if tt.status == http.StatusOK {
+				want = 1
+			}
+			if n := len(s.assets.listAll(nil)); n != want {
+				t.Errorf("%d assets stored, want %d", n, want)
+			}
+		})
+	}
+}
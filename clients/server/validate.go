@@ -0,0 +1,96 @@
+// validate.go — Lint, schema and resolve endpoints for the editor.
+//
+//	POST /api/validate  {preset, data, assets} → {"issues":[…],"warnings":n}
+//	POST /api/schema    {preset}                 → {"text":…,"jsonSchema":{…}}
+//	POST /api/resolve   {preset, data, assets} → template.Resolution
+//
+// They call the same template functions as `gostencil validate`,
+// `gostencil schema` and `gostencil resolve`, so the CLI and the editor
+// report the same problems.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+func (s *srv) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var req renderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	inline, err := s.checkInlineAssets(req.Assets)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	preset, err := parsePreset(req.Preset)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	issues := []template.Issue{}
+	data, err := parseData(req.Data)
+	if err != nil {
+		issues = append(issues, template.Issue{Severity: template.SeverityWarning, Field: "data", Message: err.Error()})
+	}
+	issues = append(issues, template.LintWithOptions(preset, data, template.LintOptions{Resolve: s.resolver(inline), NoAssetFiles: true, MaxCanvasSize: s.maxCanvas})...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"issues":   issues,
+		"warnings": template.CountWarnings(issues),
+	})
+}
+
+func (s *srv) handleResolve(w http.ResponseWriter, r *http.Request) {
+	var req renderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if _, err := s.checkInlineAssets(req.Assets); err != nil {
+		writeErr(w, err)
+		return
+	}
+	preset, err := parsePreset(req.Preset)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	// Unusable data resolves as none, as a render would, and says why.
+	data, dataErr := parseData(req.Data)
+	res := template.Resolve(preset, data)
+	if dataErr != nil {
+		res.Ignored = append([]template.IgnoredOverride{{Path: "data", Reason: dataErr.Error()}}, res.Ignored...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *srv) handleSchema(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Preset json.RawMessage `json:"preset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	var preset template.Preset
+	if err := json.Unmarshal(req.Preset, &preset); err != nil {
+		writeAPIError(w, errorf(http.StatusBadRequest, "BAD_PRESET", "parse preset: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"text":        template.FormatSchema(&preset),
+		"jsonSchema":  template.DataJSONSchema(&preset),
+		"description": template.DescribeSchema(&preset),
+	})
+}
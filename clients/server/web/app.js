@@ -9,6 +9,8 @@
   const previewImg = $('#preview-img');
   const previewLoading = $('#preview-loading');
   const previewError = $('#preview-error');
+  const previewWarnings = $('#preview-warnings');
+  const renderTime = $('#render-time');
   const exportMenu = $('#export-menu');
   const toastContainer = $('#toasts');
   const modalAvi = $('#modal-avi');
@@ -19,6 +21,7 @@
 
   // Default JSON
   const defaultPreset = {
+    formatVersion: 2,
     meta: { name: "My Preset", version: "1.0", author: "Author", description: "" },
     canvas: { preset: "1080p" },
     background: { type: "color", color: "#1a1a2e" },
@@ -62,6 +65,9 @@
         entry.visible = true;
         if (c.defaults && c.defaults.title) entry.title = c.defaults.title;
         if (c.defaults && c.defaults.items && c.defaults.items.length > 0) entry.items = c.defaults.items;
+        if (c.variants && Object.keys(c.variants).length > 0) {
+          entry.variant = (c.defaults && c.defaults.variant) || Object.keys(c.variants).sort()[0];
+        }
         entry.style = {};
         if (c.style) {
           if (c.style.fontSize) entry.style.fontSize = c.style.fontSize;
@@ -79,6 +85,7 @@
   // State
   let renderTimeout = null;
   let isRendering = false;
+  let videoFormat = 'avi';
 
   // Init
   function init() {
@@ -115,7 +122,7 @@
     $('#btn-zoom-fit').addEventListener('click', () => setZoom('fit'));
     $('#btn-zoom-100').addEventListener('click', () => setZoom('100'));
     $('#avi-cancel').addEventListener('click', () => modalAvi.style.display = 'none');
-    $('#avi-export').addEventListener('click', doExportAVI);
+    $('#avi-export').addEventListener('click', doExportVideo);
 
     initResize();
     render();
@@ -147,9 +154,11 @@
       const res = await fetch('/api/render', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ preset: parsed.preset, data: parsed.data })
+        body: JSON.stringify({ preset: parsed.preset, data: parsed.data, showMissingAssets: true })
       });
-      if (!res.ok) { showError(await res.text()); return; }
+      if (!res.ok) { showError(await errorMessage(res)); return; }
+      showWarnings(res.headers.get('X-GoStencil-Warnings'));
+      showProfile(res.headers.get('X-GoStencil-Profile'));
       const blob = await res.blob();
       const url = URL.createObjectURL(blob);
       if (previewImg.src && previewImg.src.startsWith('blob:')) URL.revokeObjectURL(previewImg.src);
@@ -163,6 +172,17 @@
     }
   }
 
+  // Extracts the message from a failed response: the JSON error envelope
+  // ({"error": {"code", "message"}}) or plain text.
+  async function errorMessage(res) {
+    const text = await res.text();
+    try {
+      const body = JSON.parse(text);
+      if (body && body.error && body.error.message) return body.error.message;
+    } catch (e) { }
+    return text || 'Server returned ' + res.status;
+  }
+
   // Import
 
   async function handleImport(e) {
@@ -171,12 +191,15 @@
     const form = new FormData(); form.append('file', file);
     try {
       const res = await fetch('/api/import/gspresets', { method: 'POST', body: form });
-      if (!res.ok) throw new Error(await res.text());
+      if (!res.ok) throw new Error(await errorMessage(res));
       const result = await res.json();
       let presetObj = typeof result.preset === 'string' ? JSON.parse(result.preset) : result.preset;
       presetEditor.value = JSON.stringify(presetObj, null, 2);
       dataEditor.value = JSON.stringify(buildDataTemplate(presetObj), null, 2);
       toast('Imported: ' + file.name, 'success');
+      (result.warnings || []).filter(w => w.severity === 'warning').forEach(w => {
+        toast((w.component ? w.component + ': ' : '') + w.message, 'warn');
+      });
       refreshAssetCount(); render();
     } catch (err) { toast('Import failed: ' + err.message, 'error'); }
   }
@@ -189,7 +212,7 @@
     const form = new FormData(); form.append('file', file);
     try {
       const res = await fetch('/api/upload/font', { method: 'POST', body: form });
-      if (!res.ok) throw new Error(await res.text());
+      if (!res.ok) throw new Error(await errorMessage(res));
       const result = await res.json();
       try {
         const preset = JSON.parse(presetEditor.value);
@@ -207,9 +230,10 @@
     const form = new FormData(); form.append('file', file);
     try {
       const res = await fetch('/api/upload/image', { method: 'POST', body: form });
-      if (!res.ok) throw new Error(await res.text());
+      if (!res.ok) throw new Error(await errorMessage(res));
       const result = await res.json();
-      toast('Image uploaded: ' + result.name + ' - Open Assets to use it', 'success');
+      if (result.existing) toast('Image already stored as ' + result.name + ' (' + result.id + ')');
+      else toast('Image uploaded: ' + result.name + ' - Open Assets to use it', 'success');
       refreshAssetCount();
     } catch (err) { toast('Image upload failed: ' + err.message, 'error'); }
   }
@@ -224,9 +248,15 @@
     if (parsed.error) { toast(parsed.error, 'error'); return; }
     switch (type) {
       case 'png':
-        downloadFromAPI('/api/export/png', { preset: parsed.preset, data: parsed.data }, 'output.png');
+      case 'jpeg':
+      case 'bmp':
+      case 'webp':
+        downloadFromAPI('/api/export/' + type, { preset: parsed.preset, data: parsed.data }, 'output.' + (type === 'jpeg' ? 'jpg' : type));
         break;
       case 'avi':
+      case 'gif':
+        videoFormat = type;
+        $('#avi-title').textContent = type === 'gif' ? 'Export Animated GIF' : 'Export AVI Video';
         modalAvi.style.display = 'flex';
         break;
       case 'preset-json':
@@ -244,13 +274,49 @@
     }
   }
 
-  async function doExportAVI() {
+  async function doExportVideo() {
     modalAvi.style.display = 'none';
-    const duration = parseInt($('#avi-duration').value) || 3;
+    const duration = parseFloat($('#avi-duration').value) || 3;
     const parsed = getEditorJSON();
     if (parsed.error) { toast(parsed.error, 'error'); return; }
-    toast('Generating AVI...', 'warn');
-    await downloadFromAPI('/api/export/avi', { preset: parsed.preset, data: parsed.data, duration: duration }, 'output.avi');
+    const label = videoFormat.toUpperCase();
+    const filename = 'output.' + videoFormat;
+    let job;
+    try {
+      const res = await fetch('/api/jobs', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ format: videoFormat, preset: parsed.preset, data: parsed.data, duration: duration })
+      });
+      if (!res.ok) throw new Error(await errorMessage(res));
+      job = await res.json();
+    } catch (err) { toast('Export failed: ' + err.message, 'error'); return; }
+
+    // Follow the background job's progress, then download its result.
+    const status = stickyToast('Generating ' + label + '...', 'warn');
+    const events = new EventSource('/api/jobs/' + job.id + '/events');
+    events.addEventListener('progress', e => {
+      const j = JSON.parse(e.data);
+      status.update(j.status === 'queued' ? 'Queued ' + label + '...' :
+        'Generating ' + label + '... ' + Math.floor(j.progress) + '%');
+    });
+    events.addEventListener('done', async e => {
+      events.close(); status.close();
+      const j = JSON.parse(e.data);
+      try {
+        const res = await fetch(j.resultUrl);
+        if (!res.ok) throw new Error(await errorMessage(res));
+        downloadBlob(await res.blob(), filename);
+        toast('Exported: ' + filename, 'success');
+      } catch (err) { toast('Export failed: ' + err.message, 'error'); }
+    });
+    events.addEventListener('failed', e => {
+      events.close(); status.close();
+      toast('Export failed: ' + JSON.parse(e.data).error, 'error');
+    });
+    events.onerror = () => {
+      if (events.readyState === EventSource.CLOSED) { status.close(); toast('Lost connection to export job', 'error'); }
+    };
   }
 
   async function downloadFromAPI(url, body, filename) {
@@ -261,8 +327,7 @@
         body: JSON.stringify(body)
       });
       if (!res.ok) {
-        const errText = await res.text();
-        throw new Error(errText || 'Server returned ' + res.status);
+        throw new Error(await errorMessage(res));
       }
       const blob = await res.blob();
       if (blob.size === 0) {
@@ -341,7 +406,7 @@
         + '<span class="asset-card-name" title="' + a.name + '">' + a.name + '</span>'
         + '<span class="asset-card-type ' + typeLabel + '">' + typeLabel + '</span>'
         + '</div>'
-        + '<div class="asset-card-meta">' + sizeKB + ' KB</div>'
+        + '<div class="asset-card-meta">' + sizeKB + ' KB' + (a.refCount ? ' · used by ' + a.refCount + ' preset' + (a.refCount === 1 ? '' : 's') : '') + '</div>'
         + previewHTML
         + '<div class="asset-card-id"><span>ID:</span> <code title="' + a.id + '">' + a.id + '</code></div>'
         + '<div class="asset-card-actions">' + actionsHTML + '</div>';
@@ -433,7 +498,7 @@
       case 'delete':
         try {
           var res = await fetch('/api/assets/' + id, { method: 'DELETE' });
-          if (!res.ok) throw new Error(await res.text());
+          if (!res.ok) throw new Error(await errorMessage(res));
           toast('Removed: ' + asset.name, 'success');
           loadAssets(); render();
         } catch (e) { toast('Delete failed: ' + e.message, 'error'); }
@@ -497,6 +562,35 @@
   function showError(msg) { previewError.textContent = msg; previewError.classList.add('active'); }
   function hideError() { previewError.classList.remove('active'); }
 
+  // Lists render warnings from the X-GoStencil-Warnings header (JSON array
+  // of {component, message}); hides the list when there are none.
+  function showWarnings(header) {
+    let warnings = [];
+    try { warnings = JSON.parse(header || '[]'); } catch (e) { }
+    previewWarnings.replaceChildren(...warnings.map(w => {
+      const li = document.createElement('li');
+      li.textContent = (w.component ? w.component + ': ' : '') + w.message;
+      return li;
+    }));
+    previewWarnings.classList.toggle('active', warnings.length > 0);
+  }
+
+  // Shows the render time and slowest component from the
+  // X-GoStencil-Profile header (durations in nanoseconds), with every
+  // component's phases in the tooltip.
+  function showProfile(header) {
+    let p = null;
+    try { p = JSON.parse(header || 'null'); } catch (e) { }
+    if (!p) { renderTime.textContent = ''; renderTime.title = ''; return; }
+    const ms = (ns) => (ns / 1e6).toFixed(1) + ' ms';
+    const comps = p.components || [];
+    const slowest = comps.reduce((a, c) => (!a || c.total > a.total ? c : a), null);
+    renderTime.textContent = ms(p.total) + (slowest ? ' · slowest: ' + slowest.id : '');
+    renderTime.title = ['background ' + ms(p.background)].concat(comps.map(c =>
+      c.id + ' ' + ms(c.total) + ': background ' + ms(c.background) + ', image ' + ms(c.image) +
+      ', border ' + ms(c.border) + ', text ' + ms(c.text))).join('\n');
+  }
+
   function toast(message, type) {
     var el = document.createElement('div');
     el.className = 'toast' + (type ? ' toast--' + type : '');
@@ -510,5 +604,17 @@
     }, 3500);
   }
 
+  // stickyToast stays up until closed; update replaces its text.
+  function stickyToast(message, type) {
+    var el = document.createElement('div');
+    el.className = 'toast' + (type ? ' toast--' + type : '');
+    el.textContent = message;
+    toastContainer.appendChild(el);
+    return {
+      update: function (text) { el.textContent = text; },
+      close: function () { el.remove(); }
+    };
+  }
+
   document.addEventListener('DOMContentLoaded', init);
 })();
@@ -0,0 +1,125 @@
+// async.go — Promise-returning exports that keep the page responsive.
+//
+// Work runs on a goroutine, but Go in the browser shares the main thread
+// with the page, so a long render still freezes it unless Go hands control
+// back. The render context does that: its Err method, which the renderer
+// checks before each component (and the AVI writer before each frame),
+// sleeps briefly once yieldInterval has passed, letting the browser paint
+// and run events in between. A single component is not interrupted, so a
+// slow one (a large image to scale) still holds the page for its duration.
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// yieldInterval is how long work may hold the main thread between yields.
+const yieldInterval = 25 * time.Millisecond
+
+var (
+	runsMu  sync.Mutex
+	runs    = make(map[int]context.CancelFunc)
+	lastRun int
+)
+
+// yieldingContext is a context whose Err yields to the browser when the
+// current slice of work has run for yieldInterval.
+type yieldingContext struct {
+	context.Context
+	last time.Time
+}
+
+func (c *yieldingContext) Err() error {
+	if time.Since(c.last) >= yieldInterval {
+		yieldToBrowser()
+		c.last = time.Now()
+	}
+	return c.Context.Err()
+}
+
+// yieldToBrowser pauses Go on a JS timeout, so the event loop runs meanwhile.
+func yieldToBrowser() {
+	time.Sleep(time.Millisecond)
+}
+
+// async runs work on a goroutine and returns a Promise resolved with its
+// result, or with {ok: false, error: "canceled"} after goCancelRender. The
+// Promise's id property is the handle goCancelRender takes.
+func async(work func(ctx context.Context) js.Value) js.Value {
+	ctx, cancel := context.WithCancel(context.Background())
+	runsMu.Lock()
+	lastRun++
+	id := lastRun
+	runs[id] = cancel
+	runsMu.Unlock()
+
+	executor := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolve := args[0]
+		go func() {
+			// Go keeps running this goroutine before returning to JS until it
+			// blocks; yield once so the caller gets its Promise (and can
+			// cancel it) before any work is done.
+			yieldToBrowser()
+			defer func() {
+				runsMu.Lock()
+				delete(runs, id)
+				runsMu.Unlock()
+				cancel()
+			}()
+			res := work(&yieldingContext{Context: ctx, last: time.Now()})
+			if ctx.Err() != nil {
+				res = fail("canceled")
+			}
+			resolve.Invoke(res)
+		}()
+		return nil
+	})
+	promise := js.Global().Get("Promise").New(executor)
+	executor.Release()
+	promise.Set("id", id)
+	return promise
+}
+
+// goCancelRender(id) — cancel a pending goRenderImage/goExportAVI by its
+// Promise's id. Returns {ok: false} if it already finished.
+func cancelRender(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return fail("need id")
+	}
+	runsMu.Lock()
+	cancel, found := runs[args[0].Int()]
+	runsMu.Unlock()
+	if !found {
+		return fail("no pending render %d", args[0].Int())
+	}
+	cancel()
+	return result(nil)
+}
+
+// progressFunc adapts an optional JS callback to generator.Config.Progress,
+// calling it with the percent complete. It also checks ctx once per frame,
+// which yields to the browser between frames.
+func progressFunc(ctx context.Context, callback js.Value) func(done, total int) {
+	return func(done, total int) {
+		if ctx.Err() == nil && callback.Type() == js.TypeFunction {
+			callback.Invoke(float64(done) * 100 / float64(total))
+		}
+	}
+}
+
+// ctxWriter fails writes once ctx is done, aborting an encoder mid-file.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
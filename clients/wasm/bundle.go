@@ -0,0 +1,146 @@
+// bundle.go — Client-side .gspresets import.
+//
+// goImportGSPresets does in memory what POST /api/import/gspresets does on
+// the server: every file in the bundle becomes an asset, the preset's
+// bundle-relative references are pointed at the new IDs, and the result
+// has the same shape as the server's response, so the editor handles both
+// the same way.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"syscall/js"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// goImportGSPresets(data) — unpack a .gspresets ZIP (a Uint8Array),
+// register its assets and return {ok, preset, assets: [{id, name,
+// originalPath, mime, size, url}], warnings, preview?} like the server's
+// import; url is a blob: URL of the asset, and preview a data: URL of the
+// bundle's preview image. Nothing is registered if any entry is unusable.
+func importGSPresets(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return fail("need data")
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+
+	presetJSON, preview, entries, err := readBundle(data)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	imported := make([]interface{}, 0, len(entries))
+	ids := make(map[string]string, len(entries))
+	for _, e := range entries {
+		id := storeAsset(e)
+		ids[e.path] = id
+		imported = append(imported, map[string]interface{}{
+			"id":           id,
+			"name":         e.Name,
+			"originalPath": e.path,
+			"mime":         e.Mime,
+			"size":         len(e.Data),
+			"url":          blobURL(e.Data, e.Mime),
+		})
+	}
+
+	// Point bundle-relative references at the new asset IDs, then check the
+	// result the way a render would see it.
+	presetJSON = template.RewriteAssetRefs(presetJSON, ids)
+	warnings := []template.Issue{}
+	if preset, err := parsePreset(string(presetJSON)); err == nil {
+		warnings = append(warnings, template.LintWithResolver(preset, nil, resolveAsset)...)
+	}
+	fields := map[string]interface{}{
+		"preset":   jsonValue(json.RawMessage(presetJSON)),
+		"assets":   imported,
+		"warnings": jsonValue(warnings),
+	}
+	if preview != nil {
+		fields["preview"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(preview)
+	}
+	return result(fields)
+}
+
+// bundleEntry is one asset file read from a bundle.
+type bundleEntry struct {
+	template.BundleAsset
+	path string // path inside the bundle
+}
+
+// readBundle reads a bundle with template.LoadPresetFromReader, within
+// template.MaxBundle, and returns its preset.json, preview image and asset
+// files, checking that fonts parse and images decode.
+func readBundle(data []byte) (presetJSON, preview []byte, entries []bundleEntry, err error) {
+	bundle, err := template.LoadPresetFromReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// A preview that does not decode is dropped, not an error.
+	if m, err := template.ImageMime(bundle.Preset.Preview); err == nil && m == "image/png" {
+		preview = bundle.Preset.Preview
+	}
+
+	for _, name := range bundle.Files() {
+		if name == "preset.json" || name == template.PreviewName {
+			continue
+		}
+		fdata := bundle.Resolve(name)
+		mimeType := mime.TypeByExtension(filepath.Ext(name))
+		switch ext := strings.ToLower(filepath.Ext(name)); {
+		case ext == ".ttf" || ext == ".otf" || ext == ".ttc" || ext == ".woff" || ext == ".woff2":
+			mimeType = template.FontMime(template.FontFormat(fdata))
+			if _, err = template.ParseFont(fdata, 0, ""); err != nil {
+				err = fmt.Errorf("not a usable font: %w", err)
+			}
+		case strings.HasPrefix(mimeType, "image/"):
+			if mimeType, err = template.ImageMime(fdata); err != nil {
+				err = fmt.Errorf("not a supported image: %w", err)
+			}
+		case mimeType == "":
+			mimeType = "application/octet-stream"
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		entries = append(entries, bundleEntry{
+			BundleAsset: template.BundleAsset{Name: filepath.Base(name), Mime: mimeType, Data: fdata},
+			path:        name,
+		})
+	}
+	return bundle.Resolve("preset.json"), preview, entries, nil
+}
+
+// storeAsset registers an imported asset under an ID derived from its
+// content, as the server does, so importing a bundle twice reuses the
+// assets from the first time.
+func storeAsset(e bundleEntry) string {
+	sum := sha256.Sum256(e.Data)
+	id := hex.EncodeToString(sum[:8])
+
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+	if a, ok := assets[id]; ok && !bytes.Equal(a.Data, e.Data) {
+		id = hex.EncodeToString(sum[:])
+	}
+	assets[id] = assetEntry{Name: e.Name, Data: e.Data, Mime: e.Mime}
+	return id
+}
+
+// blobURL returns a blob: URL holding a copy of data.
+func blobURL(data []byte, mimeType string) string {
+	arr := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(arr, data)
+	blob := js.Global().Get("Blob").New([]interface{}{arr}, map[string]interface{}{"type": mimeType})
+	return js.Global().Get("URL").Call("createObjectURL", blob).String()
+}
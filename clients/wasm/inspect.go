@@ -0,0 +1,133 @@
+// inspect.go — Validation, schema and measurement helpers for the editor.
+//
+// They call the same pkg/template functions as `gostencil validate`,
+// `gostencil schema` and the renderer, so the browser reports exactly what
+// a native run would. All are synchronous.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// jsonValue converts v to a JS value through JSON, for structures js.ValueOf
+// does not accept (typed slices, structs).
+func jsonValue(v interface{}) js.Value {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return js.Null()
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
+
+// goValidate(presetJSON, dataJSON) — lint the preset and data; returns
+// {ok, issues: [{severity, component, field, message}], warnings}, like
+// POST /api/validate. Registered assets count as present.
+func validate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return fail("need presetJSON, dataJSON")
+	}
+	preset, err := parsePreset(args[0].String())
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	issues := []template.Issue{}
+	data, err := parseData(args[1].String())
+	if err != nil {
+		issues = append(issues, template.Issue{Severity: template.SeverityWarning, Field: "data", Message: err.Error()})
+	}
+	issues = append(issues, template.LintWithResolver(preset, data, resolveAsset)...)
+
+	return result(map[string]interface{}{
+		"issues":   jsonValue(issues),
+		"warnings": template.CountWarnings(issues),
+	})
+}
+
+// goGetSchema(presetJSON) — returns {ok, text, jsonSchema}: the schema
+// `gostencil schema` prints and the JSON Schema for data files.
+func getSchema(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return fail("need presetJSON")
+	}
+	var preset template.Preset
+	if err := json.Unmarshal([]byte(args[0].String()), &preset); err != nil {
+		return fail("parse preset: %v", err)
+	}
+	return result(map[string]interface{}{
+		"text":       template.FormatSchema(&preset),
+		"jsonSchema": jsonValue(template.DataJSONSchema(&preset)),
+	})
+}
+
+// goPaintOrder(presetJSON, dataJSON) — returns {ok, order}: the IDs of the
+// components a render would draw, bottom to top (by zIndex, then ID), like
+// paint_order from POST /api/render?format=json.
+func paintOrder(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return fail("need presetJSON, dataJSON")
+	}
+	preset, err := parsePreset(args[0].String())
+	if err != nil {
+		return fail("%v", err)
+	}
+	data, _ := parseData(args[1].String())
+
+	order := []interface{}{}
+	for _, c := range template.MergeData(preset, data) {
+		order = append(order, c.ID)
+	}
+	return result(map[string]interface{}{"order": order})
+}
+
+// goMeasureComponent(presetJSON, componentID, dataJSON) — lay out one
+// component's text as a render would and return {ok, visible, lines,
+// hidden, height, available, overflow, warnings}; hidden counts the lines
+// maxLines dropped. A component hidden by its data
+// returns visible: false and no metrics.
+func measureComponent(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return fail("need presetJSON, componentID, dataJSON")
+	}
+	preset, err := parsePreset(args[0].String())
+	if err != nil {
+		return fail("%v", err)
+	}
+	id := args[1].String()
+	data, _ := parseData(args[2].String())
+
+	known := false
+	for _, c := range preset.Components {
+		known = known || c.ID == id
+	}
+	if !known {
+		return fail("no component %q", id)
+	}
+
+	for _, comp := range template.MergeData(preset, data) {
+		if comp.ID != id {
+			continue
+		}
+		renderer, err := newRenderer(preset)
+		if err != nil {
+			return fail("%v", err)
+		}
+		m, err := renderer.MeasureComponent(comp)
+		if err != nil {
+			return fail("%v", err)
+		}
+		return result(map[string]interface{}{
+			"visible":   true,
+			"lines":     m.Lines,
+			"hidden":    m.Hidden,
+			"height":    m.Height,
+			"available": m.Available,
+			"overflow":  m.Overflow,
+			"warnings":  jsonValue(append([]template.RenderWarning{}, renderer.Warnings()...)),
+		})
+	}
+	return result(map[string]interface{}{"visible": false})
+}
@@ -1,12 +1,20 @@
 // GoStencil WASM — Client-side renderer.
 // Compiled with: GOOS=js GOARCH=wasm go build -o gostencil.wasm ./clients/wasm/
+//
+// Every exported function returns an object: {ok: true, ...} on success,
+// {ok: false, error: "..."} on failure. Binary results are Uint8Arrays
+// ({ok, data, width, height}); web/compat.js maps them back to the
+// base64 strings earlier versions returned. Rendering functions return a
+// Promise of that object instead (see async.go).
 package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"image"
 	"image/png"
 	"sync"
 	"syscall/js"
@@ -22,6 +30,7 @@ var (
 )
 
 type assetEntry struct {
+	Name string // file name, for bundle entries
 	Data []byte
 	Mime string
 }
@@ -33,7 +42,20 @@ func main() {
 	js.Global().Set("goRenderImage", js.FuncOf(renderImage))
 	js.Global().Set("goRegisterAsset", js.FuncOf(registerAsset))
 	js.Global().Set("goRemoveAsset", js.FuncOf(removeAsset))
+	js.Global().Set("goListAssets", js.FuncOf(listAssets))
+	js.Global().Set("goAssetURL", js.FuncOf(assetURL))
+	js.Global().Set("goClearAssets", js.FuncOf(clearAssets))
+	js.Global().Set("goClearCaches", js.FuncOf(clearCaches))
+	js.Global().Set("goMemStats", js.FuncOf(memStats))
 	js.Global().Set("goExportAVI", js.FuncOf(exportAVI))
+	js.Global().Set("goExportGIF", js.FuncOf(exportGIF))
+	js.Global().Set("goExportGSPresets", js.FuncOf(exportGSPresets))
+	js.Global().Set("goImportGSPresets", js.FuncOf(importGSPresets))
+	js.Global().Set("goCancelRender", js.FuncOf(cancelRender))
+	js.Global().Set("goValidate", js.FuncOf(validate))
+	js.Global().Set("goGetSchema", js.FuncOf(getSchema))
+	js.Global().Set("goPaintOrder", js.FuncOf(paintOrder))
+	js.Global().Set("goMeasureComponent", js.FuncOf(measureComponent))
 	js.Global().Set("goReady", js.ValueOf(true))
 
 	// Block forever (WASM must not exit).
@@ -51,171 +73,243 @@ func resolveAsset(id string) []byte {
 	return nil
 }
 
-// goRegisterAsset(id, base64Data, mime) — store an asset in Go memory.
+// result returns a success result carrying fields.
+func result(fields map[string]interface{}) js.Value {
+	res := map[string]interface{}{"ok": true}
+	for k, v := range fields {
+		res[k] = v
+	}
+	return js.ValueOf(res)
+}
+
+// fail returns an error result.
+func fail(format string, args ...interface{}) js.Value {
+	return js.ValueOf(map[string]interface{}{"ok": false, "error": fmt.Sprintf(format, args...)})
+}
+
+// binary returns data as a Uint8Array success result, with the dimensions
+// of the image it was made from (if any).
+func binary(data []byte, img image.Image) js.Value {
+	arr := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(arr, data)
+	fields := map[string]interface{}{"data": arr}
+	if img != nil {
+		fields["width"] = img.Bounds().Dx()
+		fields["height"] = img.Bounds().Dy()
+	}
+	return result(fields)
+}
+
+// goRegisterAsset(id, data, mime, name?) — store an asset in Go memory.
+// data is a Uint8Array, or a base64 string as accepted by earlier
+// versions; name names the file in exported bundles.
 func registerAsset(this js.Value, args []js.Value) interface{} {
 	if len(args) < 3 {
-		return js.ValueOf("error: need id, base64Data, mime")
+		return fail("need id, data, mime")
 	}
 	id := args[0].String()
-	b64 := args[1].String()
 	mimeType := args[2].String()
+	name := ""
+	if len(args) > 3 && args[3].Type() == js.TypeString {
+		name = args[3].String()
+	}
 
-	data, err := base64.StdEncoding.DecodeString(b64)
-	if err != nil {
-		return js.ValueOf("error: invalid base64: " + err.Error())
+	var data []byte
+	if args[1].Type() == js.TypeString {
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(args[1].String()); err != nil {
+			return fail("invalid base64: %v", err)
+		}
+	} else {
+		data = make([]byte, args[1].Get("length").Int())
+		js.CopyBytesToGo(data, args[1])
 	}
 
 	assetsMu.Lock()
-	assets[id] = assetEntry{Data: data, Mime: mimeType}
+	assets[id] = assetEntry{Name: name, Data: data, Mime: mimeType}
 	assetsMu.Unlock()
 
-	return js.ValueOf("ok")
+	return result(nil)
 }
 
 // goRemoveAsset(id) — remove an asset from Go memory.
 func removeAsset(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
-		return js.ValueOf("error: need id")
+		return fail("need id")
 	}
 	id := args[0].String()
 	assetsMu.Lock()
 	delete(assets, id)
 	assetsMu.Unlock()
-	return js.ValueOf("ok")
+	return result(nil)
 }
 
-// goRenderImage(presetJSON, dataJSON) — render and return base64 PNG.
+// goRenderImage(presetJSON, dataJSON, options?) — render; resolves with
+// the PNG. options may set showMissingAssets and strictAssets, as in the
+// server's render request.
 func renderImage(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
-		return js.ValueOf("error: need presetJSON, dataJSON")
-	}
-
-	presetStr := args[0].String()
-	dataStr := args[1].String()
-
-	var preset template.Preset
-	if err := json.Unmarshal([]byte(presetStr), &preset); err != nil {
-		return js.ValueOf("error: parse preset: " + err.Error())
-	}
-
-	// Apply canvas preset.
-	if dims, ok := template.Presets[preset.Canvas.Preset]; ok {
-		preset.Canvas.Width = dims[0]
-		preset.Canvas.Height = dims[1]
-	}
-	if preset.Canvas.Width < 320 {
-		preset.Canvas.Width = 320
+		return fail("need presetJSON, dataJSON")
 	}
-	if preset.Canvas.Height < 240 {
-		preset.Canvas.Height = 240
-	}
-	if preset.Background.Color == "" {
-		preset.Background.Color = "#1a1a2e"
-	}
-
-	// Resolve assets: background images and component images are
-	// loaded via the asset resolver, not from the filesystem.
-	fontData := resolveAsset(preset.Font.Path)
-
-	for i := range preset.Components {
-		applyDefaults(&preset.Components[i])
+	presetStr, dataStr := args[0].String(), args[1].String()
+	var opts renderOptions
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		opts.showMissing = args[2].Get("showMissingAssets").Truthy()
+		opts.strict = args[2].Get("strictAssets").Truthy()
 	}
+	return async(func(ctx context.Context) js.Value {
+		img, err := render(ctx, presetStr, dataStr, opts)
+		if err != nil {
+			return fail("%v", err)
+		}
 
-	// Parse data.
-	var data *template.DataSpec
-	if dataStr != "" && dataStr != "null" && dataStr != "{}" {
-		var d template.DataSpec
-		if err := json.Unmarshal([]byte(dataStr), &d); err == nil {
-			data = &d
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return fail("encode: %v", err)
 		}
-	}
+		return binary(buf.Bytes(), img)
+	})
+}
 
-	// Merge.
-	components := template.MergeData(&preset, data)
+// renderOptions are the optional renderer settings of goRenderImage.
+type renderOptions struct {
+	showMissing, strict bool
+}
 
-	// Create renderer with font.
-	var renderer *template.Renderer
-	var err error
-	if fontData != nil {
-		renderer, err = template.NewRendererFromBytes(fontData)
-	} else {
-		renderer, err = template.NewRenderer("") // embedded fallback
-	}
+// render parses a preset and data the way the server does and renders them.
+func render(ctx context.Context, presetStr, dataStr string, opts renderOptions) (*image.RGBA, error) {
+	preset, err := parsePreset(presetStr)
 	if err != nil {
-		return js.ValueOf("error: renderer: " + err.Error())
+		return nil, err
 	}
+	data, _ := parseData(dataStr) // malformed data renders the defaults
+	components := template.MergeData(preset, data)
 
-	// Set asset resolver so the renderer can load images from WASM memory.
-	renderer.SetAssetResolver(resolveAsset)
-
-	img, err := renderer.RenderPreset(&preset, components)
+	renderer, err := newRenderer(preset)
 	if err != nil {
-		return js.ValueOf("error: render: " + err.Error())
+		return nil, err
 	}
-
-	// Encode to PNG.
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return js.ValueOf("error: encode: " + err.Error())
+	renderer.SetShowMissingAssets(opts.showMissing)
+	renderer.SetStrictAssets(opts.strict)
+	img, err := renderer.RenderPresetContext(ctx, preset, components)
+	if err != nil {
+		return nil, fmt.Errorf("render: %w", err)
 	}
-
-	return js.ValueOf(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return img, nil
 }
 
-// goExportAVI(presetJSON, dataJSON, duration) — render and return base64 AVI.
-func exportAVI(this js.Value, args []js.Value) interface{} {
-	if len(args) < 3 {
-		return js.ValueOf("error: need presetJSON, dataJSON, duration")
+// parsePreset decodes and normalizes a preset, as the server does.
+func parsePreset(presetStr string) (*template.Preset, error) {
+	preset, err := template.DecodePreset([]byte(presetStr))
+	if err != nil {
+		return nil, fmt.Errorf("parse preset: %w", err)
 	}
+	return preset, nil
+}
 
-	// First render the image.
-	imgResult := renderImage(this, args[:2])
-	resultStr := imgResult.(js.Value).String()
-	if len(resultStr) > 6 && resultStr[:6] == "error:" {
-		return js.ValueOf(resultStr)
+// parseData decodes data; empty data is nil. The error is a "data ignored"
+// message, as from the server.
+func parseData(dataStr string) (*template.DataSpec, error) {
+	if dataStr == "" || dataStr == "null" || dataStr == "{}" {
+		return nil, nil
 	}
-
-	pngData, err := base64.StdEncoding.DecodeString(resultStr)
-	if err != nil {
-		return js.ValueOf("error: decode PNG: " + err.Error())
+	var d template.DataSpec
+	if err := json.Unmarshal([]byte(dataStr), &d); err != nil {
+		return nil, fmt.Errorf("data ignored: %v", err)
 	}
+	return &d, nil
+}
 
-	img, err := png.Decode(bytes.NewReader(pngData))
+// newRenderer creates a renderer for preset that reads assets from the
+// in-memory store: the global font here, images and component fonts
+// through the asset resolver.
+func newRenderer(preset *template.Preset) (*template.Renderer, error) {
+	// An unknown font ID falls back to the embedded font and is reported
+	// as a render warning ("global font ... unavailable"), as in native
+	// renders.
+	renderer, err := template.NewRendererForFont(preset.Font, resolveAsset)
 	if err != nil {
-		return js.ValueOf("error: decode image: " + err.Error())
+		return nil, fmt.Errorf("renderer: %w", err)
 	}
+	return renderer, nil
+}
 
-	duration := args[2].Int()
-	if duration < 1 {
-		duration = 1
+// goExportAVI(presetJSON, dataJSON, duration, onProgress?) — render and
+// encode; resolves with the AVI. onProgress is called with the percent of
+// frames written.
+func exportAVI(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return fail("need presetJSON, dataJSON, duration")
 	}
-
-	// Generate AVI in memory.
-	var aviBuf bytes.Buffer
-	cfg := generator.Config{Image: img, Duration: duration}
-	if err := generator.GenerateToWriter(&aviBuf, ".avi", cfg); err != nil {
-		return js.ValueOf("error: generate AVI: " + err.Error())
+	presetStr, dataStr := args[0].String(), args[1].String()
+	duration := args[2].Float() // seconds; ≤ 0 means 1
+	onProgress := js.Undefined()
+	if len(args) > 3 {
+		onProgress = args[3]
 	}
+	return async(func(ctx context.Context) js.Value {
+		img, err := render(ctx, presetStr, dataStr, renderOptions{})
+		if err != nil {
+			return fail("%v", err)
+		}
 
-	return js.ValueOf(base64.StdEncoding.EncodeToString(aviBuf.Bytes()))
+		// Generate AVI in memory.
+		var aviBuf bytes.Buffer
+		cfg := generator.Config{Image: img, DurationSeconds: duration, Progress: progressFunc(ctx, onProgress)}
+		if err := generator.GenerateToWriter(ctxWriter{ctx, &aviBuf}, ".avi", cfg); err != nil {
+			return fail("generate AVI: %v", err)
+		}
+		return binary(aviBuf.Bytes(), img)
+	})
 }
 
-func applyDefaults(c *template.Component) {
-	s := &c.Style
-	if s.FontSize <= 0 {
-		s.FontSize = 24
+// goExportGIF(presetJSON, dataJSON, duration, fps, onProgress?) — render
+// and encode; resolves with the GIF. fps ≤ 0 uses the generator default.
+func exportGIF(this js.Value, args []js.Value) interface{} {
+	if len(args) < 4 {
+		return fail("need presetJSON, dataJSON, duration, fps")
 	}
-	if s.Color == "" {
-		s.Color = "#ffffff"
+	presetStr, dataStr := args[0].String(), args[1].String()
+	duration := args[2].Float() // seconds; ≤ 0 means 1
+	fps := args[3].Int()
+	onProgress := js.Undefined()
+	if len(args) > 4 {
+		onProgress = args[4]
 	}
-	if s.LineHeight <= 0 {
-		s.LineHeight = 1.5
-	}
-	if s.TextAlign == "" {
-		s.TextAlign = "left"
+	return async(func(ctx context.Context) js.Value {
+		img, err := render(ctx, presetStr, dataStr, renderOptions{})
+		if err != nil {
+			return fail("%v", err)
+		}
+
+		var gifBuf bytes.Buffer
+		cfg := generator.Config{Image: img, DurationSeconds: duration, FPS: fps, Progress: progressFunc(ctx, onProgress)}
+		if err := generator.GenerateToWriter(ctxWriter{ctx, &gifBuf}, ".gif", cfg); err != nil {
+			return fail("generate GIF: %v", err)
+		}
+		return binary(gifBuf.Bytes(), img)
+	})
+}
+
+// goExportGSPresets(presetJSON) — build a .gspresets bundle holding the
+// preset, the registered assets it references and a preview, like the
+// server's /api/export/gspresets. Returns {ok, data, warnings} directly,
+// not a Promise; warnings say why a preview was left out.
+func exportGSPresets(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return fail("need presetJSON")
 	}
-	if c.Defaults.Visible == nil {
-		t := true
-		c.Defaults.Visible = &t
+	var buf bytes.Buffer
+	warnings, err := template.SavePreset(&buf, []byte(args[0].String()), func(id string) (template.BundleAsset, bool) {
+		assetsMu.RLock()
+		defer assetsMu.RUnlock()
+		a, ok := assets[id]
+		return template.BundleAsset{Name: a.Name, Mime: a.Mime, Data: a.Data}, ok
+	})
+	if err != nil {
+		return fail("%v", err)
 	}
+	res := binary(buf.Bytes(), nil)
+	res.Set("warnings", jsonValue(append([]string{}, warnings...)))
+	return res
 }
@@ -0,0 +1,109 @@
+// memory.go — Asset store and heap inspection for long editor sessions.
+//
+// Registered assets live in the Go heap until removed, and a WebAssembly
+// instance's memory never shrinks, so the editor needs a way to see what
+// it holds and to let go of it without reloading the page.
+package main
+
+import (
+	"cmp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"syscall/js"
+)
+
+// goListAssets() — returns {ok, assets: [{id, name, mime, size}]}, sorted
+// by ID.
+func listAssets(this js.Value, args []js.Value) interface{} {
+	assetsMu.RLock()
+	list := make([]map[string]interface{}, 0, len(assets))
+	for id, a := range assets {
+		list = append(list, map[string]interface{}{"id": id, "name": a.Name, "mime": a.Mime, "size": len(a.Data)})
+	}
+	assetsMu.RUnlock()
+
+	slices.SortFunc(list, func(a, b map[string]interface{}) int {
+		return cmp.Compare(a["id"].(string), b["id"].(string))
+	})
+	return result(map[string]interface{}{"assets": jsonValue(list)})
+}
+
+// goAssetURL(id) — returns {ok, url}, a blob: URL holding a copy of the
+// asset, for the asset panel's previews. The caller revokes it.
+func assetURL(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return fail("need id")
+	}
+	assetsMu.RLock()
+	a, ok := assets[args[0].String()]
+	assetsMu.RUnlock()
+	if !ok {
+		return fail("no asset %q", args[0].String())
+	}
+	return result(map[string]interface{}{"url": blobURL(a.Data, a.Mime)})
+}
+
+// goClearAssets() — removes every registered asset and returns
+// {ok, removed, freed}: the asset count and the bytes they held.
+func clearAssets(this js.Value, args []js.Value) interface{} {
+	assetsMu.Lock()
+	removed, freed := len(assets), 0
+	for _, a := range assets {
+		freed += len(a.Data)
+	}
+	clear(assets)
+	assetsMu.Unlock()
+
+	collect()
+	return result(map[string]interface{}{"removed": removed, "freed": freed})
+}
+
+// goClearCaches() — collects garbage and returns the freed pages to the
+// runtime, reporting {ok, heapBefore, heapAfter}. Renders keep no decoded
+// images or font faces between calls (each builds its own renderer), so
+// what a finished render leaves behind is garbage, dropped here without
+// waiting for the next collection.
+func clearCaches(this js.Value, args []js.Value) interface{} {
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	after := collect()
+	return result(map[string]interface{}{
+		"heapBefore": before.HeapAlloc,
+		"heapAfter":  after.HeapAlloc,
+	})
+}
+
+// goMemStats() — returns {ok, heapAlloc, heapSys, sys, numGC, assets,
+// assetBytes}: live heap bytes, heap bytes obtained from the instance, all
+// bytes the runtime obtained (close to the instance's memory size),
+// completed collections, and the registered asset count and size.
+func memStats(this js.Value, args []js.Value) interface{} {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	assetsMu.RLock()
+	count, size := len(assets), 0
+	for _, a := range assets {
+		size += len(a.Data)
+	}
+	assetsMu.RUnlock()
+
+	return result(map[string]interface{}{
+		"heapAlloc":  ms.HeapAlloc,
+		"heapSys":    ms.HeapSys,
+		"sys":        ms.Sys,
+		"numGC":      ms.NumGC,
+		"assets":     count,
+		"assetBytes": size,
+	})
+}
+
+// collect runs a full collection, returns freed memory to the runtime and
+// reports the heap afterwards.
+func collect() runtime.MemStats {
+	debug.FreeOSMemory()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// TestRenderOversizedCanvas checks that the WASM build refuses the canvas
+// the CLI and server tests refuse, with the same message.
+func TestRenderOversizedCanvas(t *testing.T) {
+	preset := fmt.Sprintf(`{"canvas": {"width": %d, "height": 16}, "font": {}, "components": []}`, template.MaxCanvasSize+1)
+	want := fmt.Sprintf("exceeds the maximum of %d pixels per side", template.MaxCanvasSize)
+	if _, err := render(context.Background(), preset, "", renderOptions{}); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("render error %v, want one saying %q", err, want)
+	}
+
+	preset = fmt.Sprintf(`{"canvas": {"width": %d, "height": 16}, "font": {}, "components": []}`, template.MaxCanvasSize)
+	if _, err := render(context.Background(), preset, "", renderOptions{}); err != nil {
+		t.Errorf("render at the maximum: %v", err)
+	}
+}
+
+// TestMemoryAssetsMatchFiles renders a preset with two images and two
+// custom fonts from the in-memory asset store, and the same preset with
+// the assets as files the way the CLI renders it, and expects the same
+// image.
+func TestMemoryAssetsMatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"backdrop.png": bandsPNG(t, 320, 180, 0x00),
+		"sticker.png":  bandsPNG(t, 48, 48, 0xa5),
+		"mono.ttf":     gomono.TTF,
+		"italic.ttf":   goitalic.TTF,
+	}
+	const preset = `{
+  "canvas": {"width": 240, "height": 135},
+  "background": {"type": "image", "source": "backdrop.png", "fit": "cover"},
+  "font": {"path": "mono.ttf"},
+  "components": [
+    {"id": "card", "x": 0.05, "y": 0.1, "width": 0.5, "height": 0.6, "padding": 6,
+     "style": {"backgroundImage": "sticker.png", "fontSize": 16, "color": "#ffffff"},
+     "defaults": {"visible": true, "title": "Mono 0123"}},
+    {"id": "note", "x": 0.55, "y": 0.6, "width": 0.4, "height": 0.3,
+     "style": {"fontPath": "italic.ttf", "fontSize": 14, "color": "#ffe000"},
+     "defaults": {"visible": true, "title": "Italic"}}
+  ]
+}`
+
+	assetsMu.Lock()
+	for name, data := range files {
+		assets[name] = assetEntry{Name: name, Data: data}
+	}
+	assetsMu.Unlock()
+	t.Cleanup(func() {
+		assetsMu.Lock()
+		for name := range files {
+			delete(assets, name)
+		}
+		assetsMu.Unlock()
+	})
+	got, err := render(context.Background(), preset, "", renderOptions{strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The native renderer reads the same names as files.
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	native, err := template.DecodePreset([]byte(preset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := template.NewRendererForFont(native.Font, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer.SetStrictAssets(true)
+	want, err := renderer.RenderPreset(native, template.MergeData(native, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Rect != want.Rect {
+		t.Fatalf("memory render is %v, file render %v", got.Rect, want.Rect)
+	}
+	if n := countDiff(got, want); n > 0 {
+		t.Errorf("%d pixels differ between the memory and file renders", n)
+	}
+}
+
+// bandsPNG is a w×h PNG of color bands, distinct per seed.
+func bandsPNG(t *testing.T, w, h int, seed uint8) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(255 * y / h), uint8(255*x/w) ^ seed, seed, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// countDiff counts the pixels that differ between two same-sized images.
+func countDiff(a, b *image.RGBA) int {
+	var n int
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// TestRawPresetMatchesNative renders the raw preset the CLI and server
+// tests render (cmd/gostencil/testdata/raw.json) through the WASM entry
+// point and through the library, as they do, and expects the same image.
+func TestRawPresetMatchesNative(t *testing.T) {
+	raw, err := os.ReadFile("../../cmd/gostencil/testdata/raw.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := render(context.Background(), string(raw), "", renderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preset, err := template.ParsePresetFile("../../cmd/gostencil/testdata/raw.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := template.NewRendererForFont(preset.Font, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := renderer.RenderPreset(preset, template.MergeData(preset, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Rect != want.Rect {
+		t.Fatalf("WASM render is %v, native render %v", got.Rect, want.Rect)
+	}
+	if n := countDiff(got, want); n > 0 {
+		t.Errorf("%d pixels differ between the WASM and native renders", n)
+	}
+}
@@ -13,16 +13,23 @@
     const exportMenu = $('#export-menu');
     const toastContainer = $('#toasts');
     const modalAvi = $('#modal-avi');
+    const modalGif = $('#modal-gif');
     const assetPanel = $('#asset-panel');
     const assetBackdrop = $('#asset-backdrop');
     const assetList = $('#asset-list');
     const assetCountEl = $('#asset-count');
+    const assetMemoryEl = $('#asset-memory');
 
-    // In-memory asset registry (mirrors Go-side assets).
-    const jsAssets = {};
+    // blob: URLs of the asset panel's image previews, revoked on redraw.
+    let previewURLs = [];
+
+    // Warn once when the Go heap passes this many bytes.
+    const MEMORY_WARN_BYTES = 512 * 1024 * 1024;
+    let memoryWarned = false;
 
     // Default JSON
     const defaultPreset = {
+        formatVersion: 2,
         meta: { name: "My Preset", version: "1.0", author: "Author", description: "" },
         canvas: { preset: "1080p" },
         background: { type: "color", color: "#1a1a2e" },
@@ -63,6 +70,9 @@
                 entry.visible = true;
                 if (c.defaults && c.defaults.title) entry.title = c.defaults.title;
                 if (c.defaults && c.defaults.items && c.defaults.items.length > 0) entry.items = c.defaults.items;
+                if (c.variants && Object.keys(c.variants).length > 0) {
+                    entry.variant = (c.defaults && c.defaults.variant) || Object.keys(c.variants).sort()[0];
+                }
                 entry.style = {};
                 if (c.style) {
                     if (c.style.fontSize) entry.style.fontSize = c.style.fontSize;
@@ -77,7 +87,7 @@
 
     // State
     let renderTimeout = null;
-    let isRendering = false;
+    let pendingRender = null; // Promise from goRenderImage, superseded by newer edits
 
     // Init
     function init() {
@@ -95,6 +105,7 @@
         $('#btn-export').addEventListener('click', toggleExportMenu);
         $('#btn-assets').addEventListener('click', openAssetPanel);
         $('#btn-close-assets').addEventListener('click', closeAssetPanel);
+        $('#btn-clear-assets').addEventListener('click', clearAllAssets);
         $('#btn-help').addEventListener('click', () => $('#modal-help').style.display = 'flex');
         $('#help-close').addEventListener('click', () => $('#modal-help').style.display = 'none');
         assetBackdrop.addEventListener('click', closeAssetPanel);
@@ -115,6 +126,8 @@
         $('#btn-zoom-100').addEventListener('click', () => setZoom('100'));
         $('#avi-cancel').addEventListener('click', () => modalAvi.style.display = 'none');
         $('#avi-export').addEventListener('click', doExportAVI);
+        $('#gif-cancel').addEventListener('click', () => modalGif.style.display = 'none');
+        $('#gif-export').addEventListener('click', doExportGIF);
 
         initResize();
 
@@ -140,244 +153,68 @@
         return { preset, data };
     }
 
-    function render() {
-        if (isRendering || !window.goReady) return;
+    async function render() {
+        if (!window.goReady) return;
         const parsed = getEditorJSON();
         if (parsed.error) { showError(parsed.error); return; }
         hideError();
-        isRendering = true;
+        if (pendingRender) window.goCancelRender(pendingRender.id);
         previewLoading.classList.add('active');
 
-        // Run in a microtask to avoid blocking UI
-        setTimeout(() => {
-            try {
-                const result = window.goRenderImage(
-                    JSON.stringify(parsed.preset),
-                    JSON.stringify(parsed.data)
-                );
-
-                if (typeof result === 'string' && result.startsWith('error:')) {
-                    showError(result.substring(6));
-                } else {
-                    // result is base64-encoded PNG
-                    if (previewImg.src && previewImg.src.startsWith('blob:')) URL.revokeObjectURL(previewImg.src);
-                    previewImg.src = 'data:image/png;base64,' + result;
-                    previewImg.style.opacity = '1';
-                }
-            } catch (e) {
-                showError('Render failed: ' + e.message);
-            } finally {
-                isRendering = false;
+        const job = window.goRenderImage(
+            JSON.stringify(parsed.preset),
+            JSON.stringify(parsed.data),
+            { showMissingAssets: true }
+        );
+        pendingRender = job;
+        try {
+            const result = await job;
+            if (pendingRender !== job) return; // a newer render replaced this one
+            if (!result.ok) {
+                showError(result.error);
+            } else {
+                if (previewImg.src && previewImg.src.startsWith('blob:')) URL.revokeObjectURL(previewImg.src);
+                previewImg.src = URL.createObjectURL(new Blob([result.data], { type: 'image/png' }));
+                previewImg.style.opacity = '1';
+                checkMemory();
+            }
+        } catch (e) {
+            showError('Render failed: ' + e.message);
+        } finally {
+            if (pendingRender === job) {
+                pendingRender = null;
                 previewLoading.classList.remove('active');
             }
-        }, 10);
+        }
     }
 
-    // Import (.gspresets is a ZIP — handle client-side)
+    // Import (.gspresets is unpacked by goImportGSPresets, which answers like
+    // the server's /api/import/gspresets)
 
     async function handleImport(e) {
         const file = e.target.files[0]; if (!file) return;
         e.target.value = '';
 
         try {
-            const arrayBuf = await file.arrayBuffer();
-            const entries = await readZip(new Uint8Array(arrayBuf));
-
-            let presetJSON = null;
-            for (const entry of entries) {
-                if (entry.name === 'preset.json') {
-                    presetJSON = new TextDecoder().decode(entry.data);
-                } else if (entry.name.startsWith('assets/')) {
-                    // Import asset
-                    const assetName = entry.name.split('/').pop();
-                    if (!assetName) continue;
-                    const ext = assetName.split('.').pop().toLowerCase();
-                    const mime = ext === 'ttf' ? 'font/ttf' : (ext === 'png' ? 'image/png' : (ext === 'jpg' || ext === 'jpeg' ? 'image/jpeg' : 'application/octet-stream'));
-                    const id = assetName.replace(/\.[^.]+$/, ''); // use filename without ext as ID
-                    registerAssetInBoth(id, entry.data, mime, assetName);
-                }
-            }
+            const result = window.goImportGSPresets(new Uint8Array(await file.arrayBuffer()));
+            if (!result.ok) throw new Error(result.error);
 
-            if (presetJSON) {
-                const presetObj = JSON.parse(presetJSON);
-                presetEditor.value = JSON.stringify(presetObj, null, 2);
-                dataEditor.value = JSON.stringify(buildDataTemplate(presetObj), null, 2);
-                toast('Imported: ' + file.name, 'success');
-                refreshAssetCount();
-                render();
-            } else {
-                toast('No preset.json found in archive', 'error');
-            }
+            // The assets are registered in Go; the panel lists them from there.
+            result.assets.forEach(a => URL.revokeObjectURL(a.url));
+
+            presetEditor.value = JSON.stringify(result.preset, null, 2);
+            dataEditor.value = JSON.stringify(buildDataTemplate(result.preset), null, 2);
+            toast('Imported: ' + file.name, 'success');
+            result.warnings.filter(w => w.severity === 'warning').forEach(w => {
+                toast((w.component ? w.component + ': ' : '') + w.message, 'warn');
+            });
+            refreshAssetCount();
+            render();
         } catch (err) {
             toast('Import failed: ' + err.message, 'error');
         }
     }
 
-    // ZIP reader — parses central directory (reliable, handles Go's data descriptors)
-    async function readZip(data) {
-        const entries = [];
-        const view = new DataView(data.buffer, data.byteOffset, data.byteLength);
-
-        // Find End of Central Directory record (search backwards)
-        let eocdPos = -1;
-        for (let i = data.length - 22; i >= 0; i--) {
-            if (view.getUint32(i, true) === 0x06054b50) {
-                eocdPos = i;
-                break;
-            }
-        }
-        if (eocdPos < 0) throw new Error('Not a valid ZIP file');
-
-        const cdEntries = view.getUint16(eocdPos + 10, true);
-        const cdOffset = view.getUint32(eocdPos + 16, true);
-
-        // Parse central directory entries
-        let pos = cdOffset;
-        for (let i = 0; i < cdEntries; i++) {
-            if (view.getUint32(pos, true) !== 0x02014b50) break;
-
-            const method = view.getUint16(pos + 10, true);
-            const compSize = view.getUint32(pos + 20, true);
-            const nameLen = view.getUint16(pos + 28, true);
-            const extraLen = view.getUint16(pos + 30, true);
-            const commentLen = view.getUint16(pos + 32, true);
-            const localOffset = view.getUint32(pos + 42, true);
-            const name = new TextDecoder().decode(data.subarray(pos + 46, pos + 46 + nameLen));
-
-            // Read data from local file header position
-            const localNameLen = view.getUint16(localOffset + 26, true);
-            const localExtraLen = view.getUint16(localOffset + 28, true);
-            const dataStart = localOffset + 30 + localNameLen + localExtraLen;
-            const rawData = data.slice(dataStart, dataStart + compSize);
-
-            if (name.endsWith('/')) {
-                // Directory entry, skip
-            } else if (method === 0) {
-                entries.push({ name, data: rawData });
-            } else if (method === 8) {
-                try {
-                    const decompressed = await inflateRaw(rawData);
-                    entries.push({ name, data: new Uint8Array(decompressed) });
-                } catch (err) {
-                    console.warn('Failed to decompress:', name, err);
-                }
-            }
-
-            pos += 46 + nameLen + extraLen + commentLen;
-        }
-        return entries;
-    }
-
-    // Decompress raw deflate data using DecompressionStream API
-    async function inflateRaw(compressedData) {
-        const ds = new DecompressionStream('deflate-raw');
-        const writer = ds.writable.getWriter();
-        const reader = ds.readable.getReader();
-        writer.write(compressedData);
-        writer.close();
-        const chunks = [];
-        while (true) {
-            const { done, value } = await reader.read();
-            if (done) break;
-            chunks.push(value);
-        }
-        const totalLen = chunks.reduce((s, c) => s + c.length, 0);
-        const result = new Uint8Array(totalLen);
-        let offset = 0;
-        for (const c of chunks) {
-            result.set(c, offset);
-            offset += c.length;
-        }
-        return result.buffer;
-    }
-
-    // ZIP writer — creates a minimal ZIP with Stored entries (no compression)
-    function createZip(files) {
-        // files: [{ name: string, data: Uint8Array }]
-        const centralDir = [];
-        const parts = [];
-        let offset = 0;
-
-        for (const file of files) {
-            const nameBytes = new TextEncoder().encode(file.name);
-            const crc = crc32(file.data);
-
-            // Local file header (30 + nameLen)
-            const localHeader = new ArrayBuffer(30 + nameBytes.length);
-            const lv = new DataView(localHeader);
-            lv.setUint32(0, 0x04034b50, true);  // signature
-            lv.setUint16(4, 20, true);            // version needed
-            lv.setUint16(8, 0, true);             // compression: stored
-            lv.setUint32(14, crc, true);          // crc32
-            lv.setUint32(18, file.data.length, true); // compressed size
-            lv.setUint32(22, file.data.length, true); // uncompressed size
-            lv.setUint16(26, nameBytes.length, true);
-            new Uint8Array(localHeader, 30).set(nameBytes);
-
-            // Central directory entry
-            const cdEntry = new ArrayBuffer(46 + nameBytes.length);
-            const cv = new DataView(cdEntry);
-            cv.setUint32(0, 0x02014b50, true);   // signature
-            cv.setUint16(4, 20, true);             // version made by
-            cv.setUint16(6, 20, true);             // version needed
-            cv.setUint16(12, 0, true);             // compression: stored
-            cv.setUint32(16, crc, true);
-            cv.setUint32(20, file.data.length, true);
-            cv.setUint32(24, file.data.length, true);
-            cv.setUint16(28, nameBytes.length, true);
-            cv.setUint32(42, offset, true);        // local header offset
-            new Uint8Array(cdEntry, 46).set(nameBytes);
-            centralDir.push(new Uint8Array(cdEntry));
-
-            parts.push(new Uint8Array(localHeader));
-            parts.push(file.data);
-            offset += localHeader.byteLength + file.data.length;
-        }
-
-        // Central directory
-        const cdOffset = offset;
-        let cdSize = 0;
-        for (const cd of centralDir) {
-            parts.push(cd);
-            cdSize += cd.length;
-        }
-
-        // End of central directory
-        const eocd = new ArrayBuffer(22);
-        const ev = new DataView(eocd);
-        ev.setUint32(0, 0x06054b50, true);
-        ev.setUint16(8, files.length, true);  // entries on disk
-        ev.setUint16(10, files.length, true); // total entries
-        ev.setUint32(12, cdSize, true);
-        ev.setUint32(16, cdOffset, true);
-        parts.push(new Uint8Array(eocd));
-
-        // Combine
-        const totalLen = parts.reduce((s, p) => s + p.length, 0);
-        const result = new Uint8Array(totalLen);
-        let pos = 0;
-        for (const p of parts) {
-            result.set(p, pos);
-            pos += p.length;
-        }
-        return result;
-    }
-
-    // CRC-32 (used by ZIP format)
-    function crc32(data) {
-        if (!crc32.table) {
-            crc32.table = new Uint32Array(256);
-            for (let i = 0; i < 256; i++) {
-                let c = i;
-                for (let j = 0; j < 8; j++) c = (c & 1) ? (0xEDB88320 ^ (c >>> 1)) : (c >>> 1);
-                crc32.table[i] = c;
-            }
-        }
-        let crc = 0xFFFFFFFF;
-        for (let i = 0; i < data.length; i++) crc = crc32.table[(crc ^ data[i]) & 0xFF] ^ (crc >>> 8);
-        return (crc ^ 0xFFFFFFFF) >>> 0;
-    }
-
     // Upload
 
     async function handleUploadFont(e) {
@@ -385,7 +222,7 @@
         e.target.value = '';
         const data = new Uint8Array(await file.arrayBuffer());
         const id = randomId();
-        registerAssetInBoth(id, data, 'font/ttf', file.name);
+        registerAsset(id, data, 'font/ttf', file.name);
 
         try {
             const preset = JSON.parse(presetEditor.value);
@@ -404,21 +241,16 @@
         const data = new Uint8Array(await file.arrayBuffer());
         const mime = file.type || 'image/png';
         const id = randomId();
-        registerAssetInBoth(id, data, mime, file.name);
+        registerAsset(id, data, mime, file.name);
         toast('Image uploaded: ' + file.name + ' - Open Assets to use it', 'success');
         refreshAssetCount();
     }
 
-    // Register asset in both JS memory and Go WASM memory
-    function registerAssetInBoth(id, uint8Data, mime, name) {
-        // Store in JS
-        jsAssets[id] = { name, data: uint8Data, mime, size: uint8Data.length };
-
-        // Send to Go WASM as base64
-        const b64 = uint8ArrayToBase64(uint8Data);
-        const result = window.goRegisterAsset(id, b64, mime);
-        if (result !== 'ok') {
-            console.warn('goRegisterAsset failed:', result);
+    // Copy an asset into Go WASM memory, the only copy the page keeps
+    function registerAsset(id, uint8Data, mime, name) {
+        const result = window.goRegisterAsset(id, uint8Data, mime, name);
+        if (!result.ok) {
+            console.warn('goRegisterAsset failed:', result.error);
         }
     }
 
@@ -428,14 +260,6 @@
         return Array.from(arr).map(b => b.toString(16).padStart(2, '0')).join('');
     }
 
-    function uint8ArrayToBase64(bytes) {
-        let binary = '';
-        for (let i = 0; i < bytes.length; i++) {
-            binary += String.fromCharCode(bytes[i]);
-        }
-        return btoa(binary);
-    }
-
     // Export
 
     function toggleExportMenu(e) { e.stopPropagation(); exportMenu.classList.toggle('open'); }
@@ -451,6 +275,9 @@
             case 'avi':
                 modalAvi.style.display = 'flex';
                 break;
+            case 'gif':
+                modalGif.style.display = 'flex';
+                break;
             case 'preset-json':
                 downloadBlob(new Blob([JSON.stringify(parsed.preset, null, 2)], { type: 'application/json' }), 'preset.json');
                 toast('Exported: preset.json', 'success');
@@ -465,49 +292,32 @@
         }
     }
 
+    // Only the assets the preset references are bundled (see goExportGSPresets).
     function exportGSPresets(parsed) {
         try {
-            const files = [];
-            // Add preset.json
-            const presetData = new TextEncoder().encode(JSON.stringify(parsed.preset, null, 2));
-            files.push({ name: 'preset.json', data: presetData });
-            // Add all assets
-            for (const [id, asset] of Object.entries(jsAssets)) {
-                const ext = mimeToExt(asset.mime);
-                files.push({ name: 'assets/' + id + ext, data: asset.data });
+            const result = window.goExportGSPresets(JSON.stringify(parsed.preset));
+            if (!result.ok) {
+                toast('Export failed: ' + result.error, 'error');
+                return;
             }
-            const zipData = createZip(files);
-            downloadBlob(new Blob([zipData], { type: 'application/zip' }), 'preset.gspresets');
+            downloadBlob(new Blob([result.data], { type: 'application/zip' }), 'preset.gspresets');
             toast('Exported: preset.gspresets', 'success');
         } catch (e) {
             toast('Export failed: ' + e.message, 'error');
         }
     }
 
-    function mimeToExt(mime) {
-        if (!mime) return '';
-        if (mime.indexOf('png') >= 0) return '.png';
-        if (mime.indexOf('jpeg') >= 0 || mime.indexOf('jpg') >= 0) return '.jpg';
-        if (mime.indexOf('webp') >= 0) return '.webp';
-        if (mime.indexOf('ttf') >= 0 || mime.indexOf('font') >= 0) return '.ttf';
-        if (mime.indexOf('otf') >= 0) return '.otf';
-        return '';
-    }
-
-    function exportPNG(parsed) {
+    async function exportPNG(parsed) {
         try {
-            const result = window.goRenderImage(
+            const result = await window.goRenderImage(
                 JSON.stringify(parsed.preset),
                 JSON.stringify(parsed.data)
             );
-            if (typeof result === 'string' && result.startsWith('error:')) {
-                toast('Export failed: ' + result, 'error');
+            if (!result.ok) {
+                toast('Export failed: ' + result.error, 'error');
                 return;
             }
-            const binary = atob(result);
-            const bytes = new Uint8Array(binary.length);
-            for (let i = 0; i < binary.length; i++) bytes[i] = binary.charCodeAt(i);
-            downloadBlob(new Blob([bytes], { type: 'image/png' }), 'output.png');
+            downloadBlob(new Blob([result.data], { type: 'image/png' }), 'output.png');
             toast('Exported: output.png', 'success');
         } catch (e) {
             toast('PNG export failed: ' + e.message, 'error');
@@ -516,31 +326,55 @@
 
     function doExportAVI() {
         modalAvi.style.display = 'none';
-        const duration = parseInt($('#avi-duration').value) || 3;
+        const duration = parseFloat($('#avi-duration').value) || 3;
         const parsed = getEditorJSON();
         if (parsed.error) { toast(parsed.error, 'error'); return; }
-        toast('Generating AVI...', 'warn');
+        const status = stickyToast('Generating AVI...', 'warn');
+
+        window.goExportAVI(
+            JSON.stringify(parsed.preset),
+            JSON.stringify(parsed.data),
+            duration,
+            (percent) => status.update('Generating AVI... ' + Math.round(percent) + '%')
+        ).then((result) => {
+            status.close();
+            if (!result.ok) {
+                toast('AVI failed: ' + result.error, 'error');
+                return;
+            }
+            downloadBlob(new Blob([result.data], { type: 'video/avi' }), 'output.avi');
+            toast('Exported: output.avi', 'success');
+        }).catch((e) => {
+            status.close();
+            toast('AVI export failed: ' + e.message, 'error');
+        });
+    }
 
-        setTimeout(() => {
-            try {
-                const result = window.goExportAVI(
-                    JSON.stringify(parsed.preset),
-                    JSON.stringify(parsed.data),
-                    duration
-                );
-                if (typeof result === 'string' && result.startsWith('error:')) {
-                    toast('AVI failed: ' + result, 'error');
-                    return;
-                }
-                const binary = atob(result);
-                const bytes = new Uint8Array(binary.length);
-                for (let i = 0; i < binary.length; i++) bytes[i] = binary.charCodeAt(i);
-                downloadBlob(new Blob([bytes], { type: 'video/avi' }), 'output.avi');
-                toast('Exported: output.avi', 'success');
-            } catch (e) {
-                toast('AVI export failed: ' + e.message, 'error');
+    function doExportGIF() {
+        modalGif.style.display = 'none';
+        const duration = parseFloat($('#gif-duration').value) || 3;
+        const fps = parseInt($('#gif-fps').value) || 10;
+        const parsed = getEditorJSON();
+        if (parsed.error) { toast(parsed.error, 'error'); return; }
+        const status = stickyToast('Generating GIF...', 'warn');
+
+        window.goExportGIF(
+            JSON.stringify(parsed.preset),
+            JSON.stringify(parsed.data),
+            duration,
+            fps
+        ).then((result) => {
+            status.close();
+            if (!result.ok) {
+                toast('GIF failed: ' + result.error, 'error');
+                return;
             }
-        }, 50);
+            downloadBlob(new Blob([result.data], { type: 'image/gif' }), 'output.gif');
+            toast('Exported: output.gif', 'success');
+        }).catch((e) => {
+            status.close();
+            toast('GIF export failed: ' + e.message, 'error');
+        });
     }
 
     function downloadBlob(blob, filename) {
@@ -562,19 +396,58 @@
     function openAssetPanel() { assetPanel.classList.add('open'); assetBackdrop.classList.add('open'); loadAssets(); }
     function closeAssetPanel() { assetPanel.classList.remove('open'); assetBackdrop.classList.remove('open'); }
 
+    function listAssets() {
+        const result = window.goListAssets();
+        return result.ok ? result.assets : [];
+    }
+
     function refreshAssetCount() {
-        assetCountEl.textContent = Object.keys(jsAssets).length;
+        assetCountEl.textContent = listAssets().length;
     }
 
     function loadAssets() {
-        const list = Object.entries(jsAssets).map(([id, a]) => ({
-            id, name: a.name, mime: a.mime, size: a.size
-        }));
+        const list = listAssets();
         assetCountEl.textContent = list.length;
         renderAssetList(list);
+        showMemory();
+    }
+
+    // Memory
+
+    function formatMB(bytes) {
+        return (bytes / (1024 * 1024)).toFixed(1) + ' MB';
+    }
+
+    function showMemory() {
+        const m = window.goMemStats();
+        assetMemoryEl.textContent = 'Heap ' + formatMB(m.heapAlloc) + ' - assets ' + formatMB(m.assetBytes);
+    }
+
+    function checkMemory() {
+        const m = window.goMemStats();
+        if (m.heapAlloc < MEMORY_WARN_BYTES) {
+            memoryWarned = false;
+            return;
+        }
+        if (!memoryWarned) {
+            memoryWarned = true;
+            toast('Memory use is high (' + formatMB(m.heapAlloc) + '). Remove unused assets or reload the page.', 'warn');
+        }
+    }
+
+    function clearAllAssets() {
+        if (listAssets().length === 0) return;
+        if (!confirm('Remove all assets from this session?')) return;
+        const result = window.goClearAssets();
+        window.goClearCaches();
+        toast('Removed ' + result.removed + ' assets (' + formatMB(result.freed) + ')', 'success');
+        loadAssets();
+        render();
     }
 
     function renderAssetList(assets) {
+        previewURLs.forEach(url => URL.revokeObjectURL(url));
+        previewURLs = [];
         if (!assets || assets.length === 0) {
             assetList.innerHTML = '<div class="asset-empty">No assets uploaded yet. Use Font or Image buttons to upload.</div>';
             return;
@@ -591,10 +464,11 @@
 
             let previewHTML = '';
             if (isImage) {
-                // Create a blob URL for preview
-                const blob = new Blob([jsAssets[a.id].data], { type: a.mime });
-                const url = URL.createObjectURL(blob);
-                previewHTML = '<img class="asset-card-preview" src="' + url + '" alt="' + a.name + '">';
+                const preview = window.goAssetURL(a.id);
+                if (preview.ok) {
+                    previewURLs.push(preview.url);
+                    previewHTML = '<img class="asset-card-preview" src="' + preview.url + '" alt="' + a.name + '">';
+                }
             }
 
             let actionsHTML = '<button class="asset-btn" data-action="copy-id" data-id="' + a.id + '" title="Copy asset ID">[ID] Copy ID</button>';
@@ -702,10 +576,7 @@
                 break;
 
             case 'delete':
-                // Remove from JS memory
-                delete jsAssets[id];
-                // Remove from Go WASM memory
-                if (window.goRemoveAsset) window.goRemoveAsset(id);
+                window.goRemoveAsset(id);
                 toast('Removed: ' + asset.name, 'success');
                 loadAssets();
                 render();
@@ -782,5 +653,17 @@
         }, 3500);
     }
 
+    // A toast that stays until closed, for progress.
+    function stickyToast(message, type) {
+        var el = document.createElement('div');
+        el.className = 'toast' + (type ? ' toast--' + type : '');
+        el.textContent = message;
+        toastContainer.appendChild(el);
+        return {
+            update: function (text) { el.textContent = text; },
+            close: function () { el.remove(); }
+        };
+    }
+
     document.addEventListener('DOMContentLoaded', init);
 })();
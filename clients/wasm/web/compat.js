@@ -0,0 +1,29 @@
+// compat.js — String results for pages written against the old WASM API.
+//
+// gostencil.wasm now resolves renders with {ok, data: Uint8Array, width,
+// height} or {ok: false, error}. These wrappers give the previous shapes
+// back: a base64 string, or "error: ..." on failure. Rendering is
+// asynchronous, so they return a Promise of that string. Load after
+// wasm_exec.js; they can be called once goReady is set.
+
+(() => {
+    'use strict';
+
+    function bytesToBase64(bytes) {
+        let binary = '';
+        for (let i = 0; i < bytes.length; i += 0x8000) {
+            binary += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+        }
+        return btoa(binary);
+    }
+
+    function legacy(result) {
+        return result.ok ? bytesToBase64(result.data) : 'error: ' + result.error;
+    }
+
+    window.goRenderImageBase64 = (presetJSON, dataJSON) =>
+        Promise.resolve(window.goRenderImage(presetJSON, dataJSON)).then(legacy);
+
+    window.goExportAVIBase64 = (presetJSON, dataJSON, duration) =>
+        Promise.resolve(window.goExportAVI(presetJSON, dataJSON, duration)).then(legacy);
+})();
@@ -0,0 +1,273 @@
+// batch.go — Render one output per CSV row.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// namePlaceholder matches {column} tokens in a batch output filename pattern.
+var namePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+
+	var (
+		presetPath string
+		canvasName string
+		csvPath    string
+		mapSpec    string
+		outDir     string
+		name       string
+		duration   secondsFlag = 3
+		captions   captionsFlag
+		oddSize    string
+		matte      string
+		dpi        float64
+		strict     bool
+		seed       uint64
+		skip       bool
+	)
+
+	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets bundle or preset JSON")
+	fs.StringVar(&canvasName, "canvas", "", "Render at this canvas preset instead of the preset's own canvas")
+	fs.StringVar(&csvPath, "csv", "", "Path to CSV data (header row required)")
+	fs.StringVar(&mapSpec, "map", "", "Column to data path mapping: col=path,col=path")
+	fs.StringVar(&outDir, "out-dir", ".", "Output directory")
+	fs.StringVar(&name, "name", "{_row}.png", "Output filename pattern ({column}, {_row})")
+	fs.Var(&duration, "duration", "Duration in seconds or as 1500ms (AVI and GIF only)")
+	fs.Var(&captions, "caption", "Caption shown over part of the video, as START-END:text (repeatable; AVI and GIF only)")
+	fs.StringVar(&oddSize, "odd-size", generator.OddSizePad, "Make odd AVI dimensions even: pad or crop")
+	fs.StringVar(&matte, "matte", generator.DefaultMatte, "Color transparent pixels are drawn over in JPEG, GIF and AVI output")
+	fs.Float64Var(&dpi, "dpi", 0, "Font resolution, recorded in PNG output (default 72, not recorded)")
+	fs.BoolVar(&strict, "strict-assets", false, "Fail if an image or font cannot be loaded instead of substituting it")
+	fs.Uint64Var(&seed, "seed", 0, "Seed for {{_rand}} and {{_uuid}} placeholders (default: random)")
+	fs.BoolVar(&skip, "skip-unchanged", false, "Skip rows whose output exists and would render the same as in the previous run")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkOddSize(oddSize); err != nil {
+		return err
+	}
+	if err := checkMatte(matte); err != nil {
+		return err
+	}
+	if err := checkDPI(dpi); err != nil {
+		return err
+	}
+
+	if presetPath == "" || csvPath == "" {
+		return usageErrorf("--preset and --csv are required for batch command")
+	}
+
+	mapping, err := template.ParseDataMapping(mapSpec)
+	if err != nil {
+		return usageError{err}
+	}
+
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return &template.InputError{Path: csvPath, Err: fmt.Errorf("open CSV: %w", err)}
+	}
+	records, err := template.LoadCSVData(f, mapping)
+	f.Close()
+	if err != nil {
+		return &template.InputError{Path: csvPath, Err: fmt.Errorf("%s: %w", csvPath, err)}
+	}
+	if len(records) == 0 {
+		return &template.InputError{Path: csvPath, Err: fmt.Errorf("%s has no data rows", csvPath)}
+	}
+
+	// Check the filename pattern against the header before rendering anything.
+	for _, m := range namePlaceholder.FindAllStringSubmatch(name, -1) {
+		if _, ok := records[0].Fields[m[1]]; !ok && m[1] != "_row" {
+			return usageErrorf("--name references unknown column %q", m[1])
+		}
+	}
+
+	preset, resolve, err := loadPreset(presetPath)
+	if err != nil {
+		return fmt.Errorf("load preset: %w", err)
+	}
+	if canvasName != "" {
+		if err := preset.UseCanvasPreset(canvasName); err != nil {
+			return usageError{err}
+		}
+	}
+
+	renderer, err := template.NewRendererForFont(preset.Font, resolve)
+	if err != nil {
+		return fmt.Errorf("renderer: %w", err)
+	}
+	renderer.SetDPI(dpi)
+	renderer.SetStrictAssets(strict)
+	base := generator.Config{DurationSeconds: float64(duration), Captions: captions, OddSize: oddSize, Matte: matte, DPI: dpi, Seed: runSeed(seed)}
+	tokens, err := renderTokens(base)
+	if err != nil {
+		return err
+	}
+
+	if err := makeOutputDir(outDir); err != nil {
+		return err
+	}
+	// One check covers the directory and the pattern's extension for
+	// every row.
+	if err := generator.CheckOutput(filepath.Join(outDir, expandOutputName(name, records[0]))); err != nil {
+		return err
+	}
+
+	// Each run records the fingerprints of the outputs it wrote, up to an
+	// error if there is one, for the next run to compare against.
+	options := fmt.Sprintf("duration=%v captions=%q odd-size=%s matte=%s dpi=%g", float64(duration), captions.String(), oddSize, matte, dpi)
+	prev := readBatchManifest(outDir)
+	next := batchManifest{Options: options, Outputs: make(map[string]string)}
+	defer func() {
+		if err := next.write(outDir); err != nil {
+			slog.Warn("could not write batch manifest: " + err.Error())
+		}
+	}()
+	digests := make(template.AssetDigests)
+
+	slog.Info(fmt.Sprintf("Rendering preset: %s (%d rows)", preset.Meta.Name, len(records)))
+	var canvas *image.RGBA // reused: each row is written out before the next is drawn
+	unchanged := 0
+	bar := newProgress("rows", len(records))
+	defer bar.finish()
+	for i, rec := range records {
+		for _, w := range template.ValidateData(rec.Data, preset) {
+			if w.Level == template.SeverityInfo {
+				slog.Debug(fmt.Sprintf("row %d: %s", rec.Row, w.Message)) // repeats on every row
+			} else {
+				slog.Warn(fmt.Sprintf("row %d: %s", rec.Row, w.Message))
+			}
+		}
+
+		components := template.MergeData(preset, rec.Data)
+		tokens.Seq = rec.Row
+		for _, w := range template.ExpandTokens(components, tokens) {
+			slog.Warn(fmt.Sprintf("row %d: %s", rec.Row, w))
+		}
+
+		file := expandOutputName(name, rec)
+		output := filepath.Join(outDir, file)
+		bar.update(i, output)
+		digests.AddResolved(resolve, template.ResolvedAssets(preset, components)...)
+		fingerprint := template.FingerprintComponents(preset, components, digests)
+		if skip && prev.Options == options && prev.Outputs[file] == fingerprint && fileExists(output) {
+			next.Outputs[file] = fingerprint
+			unchanged++
+			slog.Info(fmt.Sprintf("[%d/%d] %s (unchanged)", rec.Row, len(records), output))
+			bar.update(i+1, "")
+			continue
+		}
+
+		img, err := renderer.RenderPresetInto(context.Background(), canvas, preset, components)
+		if err != nil {
+			return fmt.Errorf("row %d: render: %w", rec.Row, err)
+		}
+		canvas = img
+		for _, w := range renderer.Warnings() {
+			slog.Warn(fmt.Sprintf("row %d: %s", rec.Row, w))
+		}
+
+		cfg := base
+		cfg.Image = img
+		cfg.Warn = func(msg string) { slog.Warn(fmt.Sprintf("row %d: %s", rec.Row, msg)) }
+		cfg.Progress = func(done, total int) {
+			if total > 1 {
+				bar.update(i, fmt.Sprintf("%s (frame %d/%d)", output, done, total))
+			}
+		}
+		if err := generator.Generate(output, cfg); err != nil {
+			return fmt.Errorf("row %d: %w", rec.Row, err)
+		}
+		next.Outputs[file] = fingerprint
+		slog.Info(fmt.Sprintf("[%d/%d] %s", rec.Row, len(records), output))
+		bar.update(i+1, "")
+	}
+
+	if unchanged > 0 {
+		slog.Info(fmt.Sprintf("Done: %d files in %s, %d unchanged", len(records), outDir, unchanged))
+	} else {
+		slog.Info(fmt.Sprintf("Done: %d files in %s", len(records), outDir))
+	}
+	return nil
+}
+
+// batchManifestName is the file in the output directory where a batch run
+// records what it wrote.
+const batchManifestName = ".gostencil-batch.json"
+
+// batchManifest records a batch run's output settings and, for each output
+// file, the fingerprint of its render, for --skip-unchanged.
+type batchManifest struct {
+	Options string            `json:"options"`
+	Outputs map[string]string `json:"outputs"` // file name → template.FingerprintComponents
+}
+
+// readBatchManifest reads dir's manifest. A missing or unreadable one is
+// empty, so that nothing is skipped.
+func readBatchManifest(dir string) batchManifest {
+	var m batchManifest
+	raw, err := os.ReadFile(filepath.Join(dir, batchManifestName))
+	if err == nil {
+		err = json.Unmarshal(raw, &m)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn(fmt.Sprintf("ignoring %s: %v", batchManifestName, err))
+	}
+	return m
+}
+
+func (m batchManifest) write(dir string) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, batchManifestName), append(raw, '\n'), 0644)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// expandOutputName substitutes {column} tokens with the row's cell values,
+// replacing characters that are unsafe in filenames.
+func expandOutputName(pattern string, rec template.DataRecord) string {
+	return namePlaceholder.ReplaceAllStringFunc(pattern, func(tok string) string {
+		key := tok[1 : len(tok)-1]
+		if key == "_row" {
+			return strconv.Itoa(rec.Row)
+		}
+		return sanitizeName(rec.Fields[key])
+	})
+}
+
+// sanitizeName makes a cell value safe to use as part of a filename.
+func sanitizeName(s string) string {
+	s = strings.TrimSpace(s)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// oversizedPreset asks for a canvas one pixel wider than renderers draw by
+// default, as the server and WASM tests also do.
+var oversizedPreset = fmt.Sprintf(`{"canvas": {"width": %d, "height": 16}, "font": {}, "components": []}`, template.MaxCanvasSize+1)
+
+// TestOversizedCanvas checks that the CLI refuses a canvas over the
+// maximum as invalid input, with the renderer's message.
+func TestOversizedCanvas(t *testing.T) {
+	dir := t.TempDir()
+	preset := writeFile(t, dir, "big.json", oversizedPreset)
+
+	err := run([]string{"--preset", preset, "-o", filepath.Join(dir, "out.png")})
+	if code := exitCode(err); code != exitInput {
+		t.Errorf("render: exit code %d (error %v), want %d", code, err, exitInput)
+	}
+	if want := fmt.Sprintf("exceeds the maximum of %d pixels per side", template.MaxCanvasSize); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("render error %v, want it to say %q", err, want)
+	}
+
+	err = runValidate([]string{"--preset", preset, "--strict"})
+	if code := exitCode(err); code != exitWarnings {
+		t.Errorf("validate --strict: exit code %d (error %v), want %d", code, err, exitWarnings)
+	}
+}
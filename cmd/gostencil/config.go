@@ -0,0 +1,382 @@
+// config.go — Default flag values from gostencil.json.
+//
+// A config file is a JSON object. Top-level scalar (or array) keys set the
+// default of the same-named flag in every command that defines it; object
+// values named after a command apply only to that command ("render" is the
+// default generate mode). "canvas-presets" is not a flag: it adds canvas
+// preset names, usable in every preset, for the whole run:
+//
+//	{
+//	  "duration": 5,
+//	  "batch":  { "out-dir": "out", "name": "{title}.png" },
+//	  "serve":  { "port": "9000" },
+//	  "canvas-presets": { "banner": { "width": 1500, "height": 500 } }
+//	}
+//
+// Lookup order: --config <path>, ./gostencil.json, ./.gostencil.json,
+// $XDG_CONFIG_HOME/gostencil/gostencil.json. --no-config skips the lookup.
+// Flags given on the command line always win.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xob0t/GoStencil/clients/server"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// commands maps the config section names (one per subcommand) to the
+// functions that run them. It is filled in init: the commands read the
+// config, which refers back to it.
+var commands map[string]func(args []string) error
+
+func init() {
+	commands = map[string]func([]string) error{
+		"render": run, "batch": runBatch, "schema": runSchema, "validate": runValidate,
+		"fonts": runFonts, "preview": runPreview, "init": runInit,
+		"serve":   func(args []string) error { return server.RunServe(args, applyConfig) },
+		"presets": runPresets, "resolve": runResolve,
+	}
+}
+
+// errProbe stops a command at its config step while flagNames collects
+// its flags.
+var errProbe = errors.New("probing flags")
+
+// probedFlags, while probeFlags runs, collects the flags of each command
+// that reaches applyConfig.
+var probedFlags []*flag.Flag
+
+// probeFlags returns the flags of every command. Each command is run up to
+// the point it applies the config, which every command does right after
+// defining its flags, before it acts on anything.
+func probeFlags() []*flag.Flag {
+	probedFlags = []*flag.Flag{}
+	defer func() { probedFlags = nil }()
+	for _, runCommand := range commands {
+		if err := runCommand(nil); !errors.Is(err, errProbe) {
+			panic(fmt.Sprintf("gostencil: a command returned %v before applying the config", err))
+		}
+	}
+	return probedFlags
+}
+
+// flagNames returns the names of the flags of every command.
+func flagNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, f := range probeFlags() {
+		names[f.Name] = true
+	}
+	return names
+}
+
+// valueFlags holds the names of the flags that take a value ("--data x")
+// in some command, as opposed to booleans ("--open").
+var valueFlags = sync.OnceValue(func() map[string]bool {
+	names := map[string]bool{"config": true} // setupConfig's own
+	for _, f := range probeFlags() {
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			names[f.Name] = true
+		}
+	}
+	return names
+})
+
+// takesValue reports whether arg is a command flag whose value is the next
+// argument: "--data" or "-o", but not "--data=x" or "--open". The global
+// flags are stripped before a command parses its own, so their scans must
+// pass over such values: in "--data -v", -v is the data file.
+func takesValue(arg string) bool {
+	name, ok := strings.CutPrefix(arg, "-")
+	if !ok {
+		return false
+	}
+	name = strings.TrimPrefix(name, "-")
+	return name != "" && !strings.Contains(name, "=") && valueFlags()[name]
+}
+
+// canvasPresetsKey is the config key holding user-defined canvas presets.
+const canvasPresetsKey = "canvas-presets"
+
+// cliConfig holds the parsed config file.
+type cliConfig struct {
+	path     string
+	strict   bool
+	global   map[string]json.RawMessage
+	sections map[string]map[string]json.RawMessage
+
+	canvasPresets map[string]canvasSize
+}
+
+// canvasSize is one "canvas-presets" entry.
+type canvasSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// activeConfig is the config loaded by setupConfig (nil = none).
+var activeConfig *cliConfig
+
+// setupConfig strips --config <path>, --no-config and --strict-config from
+// args, except where they are another flag's value or follow "--", loads
+// the config file (if any) into activeConfig, and returns the remaining
+// arguments. With --strict-config unknown keys are errors rather than
+// warnings.
+func setupConfig(args []string) ([]string, error) {
+	var (
+		explicit string
+		disabled bool
+		strict   bool
+	)
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--":
+			rest = append(rest, args[i:]...)
+			i = len(args)
+		case a == "--config" || a == "-config":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("--config requires a path")
+			}
+			i++
+			explicit = args[i]
+		case len(a) > 9 && a[:9] == "--config=":
+			explicit = a[9:]
+		case a == "--no-config" || a == "-no-config":
+			disabled = true
+		case a == "--strict-config" || a == "-strict-config":
+			strict = true
+		case takesValue(a) && i+1 < len(args):
+			rest = append(rest, a, args[i+1])
+			i++
+		default:
+			rest = append(rest, a)
+		}
+	}
+
+	if disabled {
+		return rest, nil
+	}
+
+	path := explicit
+	if path == "" {
+		path = findConfig()
+		if path == "" {
+			return rest, nil
+		}
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.strict = strict
+	if err := cfg.checkGlobal(flagNames()); err != nil {
+		return nil, err
+	}
+	activeConfig = cfg
+	for name, size := range cfg.canvasPresets {
+		template.RegisterCanvasPreset(name, size.Width, size.Height)
+	}
+	slog.Debug("config loaded", "path", path)
+	return rest, nil
+}
+
+// findConfig returns the first existing default config path, or "".
+func findConfig() string {
+	candidates := []string{"gostencil.json", ".gostencil.json"}
+
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			xdg = filepath.Join(home, ".config")
+		}
+	}
+	if xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "gostencil", "gostencil.json"))
+	}
+
+	for _, p := range candidates {
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadConfig parses a config file, splitting command sections from flat keys.
+func loadConfig(path string) (*cliConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	cfg := &cliConfig{
+		path:     path,
+		global:   make(map[string]json.RawMessage),
+		sections: make(map[string]map[string]json.RawMessage),
+	}
+	for key, val := range top {
+		if key == canvasPresetsKey {
+			if err := json.Unmarshal(val, &cfg.canvasPresets); err != nil {
+				return nil, fmt.Errorf("config %s: key %q: expected {\"name\": {\"width\": w, \"height\": h}}", path, key)
+			}
+			for name, size := range cfg.canvasPresets {
+				if name == "" || size.Width <= 0 || size.Height <= 0 {
+					return nil, fmt.Errorf("config %s: %s %q: need a name and a positive width and height", path, key, name)
+				}
+			}
+			continue
+		}
+		if _, ok := commands[key]; ok {
+			var section map[string]json.RawMessage
+			if err := json.Unmarshal(val, &section); err != nil {
+				return nil, fmt.Errorf("config %s: key %q: expected an object of flag values", path, key)
+			}
+			cfg.sections[key] = section
+			continue
+		}
+		cfg.global[key] = val
+	}
+	return cfg, nil
+}
+
+// parseFlags applies config defaults for fs's command, then parses args so
+// that command-line values override them.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := applyConfig(fs); err != nil {
+		return err
+	}
+	return fs.Parse(args)
+}
+
+// applyConfig sets config defaults on fs without parsing; it is handed to
+// commands that parse their own flags (serve).
+func applyConfig(fs *flag.FlagSet) error {
+	if probedFlags != nil {
+		fs.VisitAll(func(f *flag.Flag) { probedFlags = append(probedFlags, f) })
+		return errProbe
+	}
+	if activeConfig == nil {
+		return nil
+	}
+	if err := activeConfig.apply(fs); err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+// checkGlobal reports the top-level keys that name no flag of any command,
+// given the names of all of them: warnings, or an error with
+// --strict-config.
+func (c *cliConfig) checkGlobal(flags map[string]bool) error {
+	for _, key := range slices.Sorted(maps.Keys(c.global)) {
+		if flags[key] {
+			continue
+		}
+		msg := fmt.Sprintf("config %s: unknown key %q: no command has a --%s flag", c.path, key, key)
+		if c.strict {
+			return fmt.Errorf("%s", msg)
+		}
+		slog.Warn(msg)
+	}
+	return nil
+}
+
+// apply sets flag values on fs from the flat keys and the command's section.
+func (c *cliConfig) apply(fs *flag.FlagSet) error {
+	cmd := fs.Name()
+	if cmd == "gostencil" {
+		cmd = "render"
+	}
+
+	for key, val := range c.global {
+		if fs.Lookup(key) == nil {
+			continue // belongs to another command, or none (see checkGlobal)
+		}
+		if err := c.set(fs, key, val); err != nil {
+			return err
+		}
+	}
+
+	for key, val := range c.sections[cmd] {
+		if fs.Lookup(key) == nil {
+			msg := fmt.Sprintf("config %s: unknown key %q for %s", c.path, cmd+"."+key, cmd)
+			if c.strict {
+				return fmt.Errorf("%s", msg)
+			}
+			slog.Warn(msg)
+			continue
+		}
+		if err := c.set(fs, key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set assigns one JSON value to a flag. Arrays set the flag once per
+// element, for repeatable flags.
+func (c *cliConfig) set(fs *flag.FlagSet, key string, val json.RawMessage) error {
+	var values []string
+	if bytes.HasPrefix(bytes.TrimSpace(val), []byte("[")) {
+		var list []json.RawMessage
+		if err := json.Unmarshal(val, &list); err != nil {
+			return fmt.Errorf("config %s: key %q: %w", c.path, key, err)
+		}
+		for _, v := range list {
+			s, err := configScalar(v)
+			if err != nil {
+				return fmt.Errorf("config %s: key %q: %w", c.path, key, err)
+			}
+			values = append(values, s)
+		}
+	} else {
+		s, err := configScalar(val)
+		if err != nil {
+			return fmt.Errorf("config %s: key %q: %w", c.path, key, err)
+		}
+		values = []string{s}
+	}
+
+	for _, v := range values {
+		if err := fs.Set(key, v); err != nil {
+			return fmt.Errorf("config %s: key %q: invalid value %q: %w", c.path, key, v, err)
+		}
+	}
+	return nil
+}
+
+// configScalar renders a JSON string, number, or bool as flag text.
+func configScalar(v json.RawMessage) (string, error) {
+	var x any
+	if err := json.Unmarshal(v, &x); err != nil {
+		return "", err
+	}
+	switch t := x.(type) {
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+	return "", fmt.Errorf("unsupported value %s (want string, number, or bool)", v)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xob0t/GoStencil/clients/server"
+	"github.com/xob0t/GoStencil/pkg/template"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// bandsPNG is a w×h PNG of color bands, distinct per seed.
+func bandsPNG(t *testing.T, w, h int, seed uint8) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(255 * y / h), uint8(255*x/w) ^ seed, seed, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// startServer runs "gostencil serve" in this process on a free local port
+// and returns its base URL. The server is stopped with an interrupt, as
+// from Ctrl-C, when the test ends.
+func startServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := fmt.Sprint(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- server.RunServe([]string{"--port", port, "--no-browser", "--render-cache", "0"}) }()
+	base := "http://127.0.0.1:" + port
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		resp, err := http.Get(base + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("serve: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Cleanup(func() {
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			err = p.Signal(os.Interrupt)
+		}
+		if err != nil {
+			return // no interrupts here (Windows): the server ends with the test binary
+		}
+		select {
+		case err := <-done:
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				t.Errorf("serve: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("server did not stop on interrupt")
+		}
+	})
+	return base
+}
+
+// upload stores data on the server at base and returns its asset ID.
+func upload(t *testing.T, base, kind, name string, data []byte) string {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	resp, err := http.Post(base+"/api/upload/"+kind, mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct{ ID string }
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload %s: status %d, %v", name, resp.StatusCode, err)
+	}
+	return out.ID
+}
+
+// serverRender renders preset through POST /api/render.
+func serverRender(t *testing.T, base, preset string) *image.RGBA {
+	t.Helper()
+	resp, err := http.Post(base+"/api/render", "application/json", strings.NewReader(`{"preset": `+preset+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("server render: status %d: %s", resp.StatusCode, body)
+	}
+	return decodeRGBA(t, body)
+}
+
+// cliRender renders the preset file at path as "gostencil --preset path"
+// does.
+func cliRender(t *testing.T, path string) *image.RGBA {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out.png")
+	if err := run([]string{"--preset", path, "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeRGBA(t, data)
+}
+
+// libraryRender renders the preset file at path with pkg/template alone.
+func libraryRender(t *testing.T, path string) *image.RGBA {
+	t.Helper()
+	preset, err := template.ParsePresetFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := template.NewRendererForFont(preset.Font, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := renderer.RenderPreset(preset, template.MergeData(preset, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func decodeRGBA(t *testing.T, data []byte) *image.RGBA {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+// sameImage reports how got differs from want: in size, or in the first
+// differing pixel and the number of differing pixels.
+func sameImage(t *testing.T, name string, got, want *image.RGBA) {
+	t.Helper()
+	if got.Rect.Size() != want.Rect.Size() {
+		t.Errorf("%s: %v, want %v", name, got.Rect.Size(), want.Rect.Size())
+		return
+	}
+	var diff int
+	first := image.Point{-1, -1}
+	for y := range want.Rect.Dy() {
+		for x := range want.Rect.Dx() {
+			g := got.RGBAAt(got.Rect.Min.X+x, got.Rect.Min.Y+y)
+			w := want.RGBAAt(want.Rect.Min.X+x, want.Rect.Min.Y+y)
+			if g != w {
+				if diff == 0 {
+					first = image.Pt(x, y)
+				}
+				diff++
+			}
+		}
+	}
+	if diff > 0 {
+		t.Errorf("%s: %d pixels differ, first at %v: %v, want %v", name, diff, first,
+			got.RGBAAt(got.Rect.Min.X+first.X, got.Rect.Min.Y+first.Y), want.RGBAAt(want.Rect.Min.X+first.X, want.Rect.Min.Y+first.Y))
+	}
+}
+
+// assetsPreset draws two images and text in a custom global font and a
+// custom component font. The references are replaced before use.
+const assetsPreset = `{
+  "canvas": {"width": 240, "height": 135},
+  "background": {"type": "image", "source": "BACKDROP", "fit": "cover"},
+  "font": {"path": "MONO"},
+  "components": [
+    {"id": "card", "x": 0.05, "y": 0.1, "width": 0.5, "height": 0.6, "padding": 6,
+     "style": {"backgroundImage": "STICKER", "fontSize": 16, "color": "#ffffff"},
+     "defaults": {"visible": true, "title": "Mono 0123"}},
+    {"id": "note", "x": 0.55, "y": 0.6, "width": 0.4, "height": 0.3,
+     "style": {"fontPath": "ITALIC", "fontSize": 14, "color": "#ffe000"},
+     "defaults": {"visible": true, "title": "Italic"}}
+  ]
+}`
+
+// TestEntryPointsAgreeOnAssets renders a preset with two images and two
+// custom fonts through the CLI and the library, reading files, and through
+// the server, reading uploaded assets, and expects the same image from
+// all three.
+func TestEntryPointsAgreeOnAssets(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"backdrop.png": bandsPNG(t, 320, 180, 0x00),
+		"sticker.png":  bandsPNG(t, 48, 48, 0xa5),
+		"mono.ttf":     gomono.TTF,
+		"italic.ttf":   goitalic.TTF,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refs := func(backdrop, sticker, mono, italic string) string {
+		quote := func(s string) string { b, _ := json.Marshal(s); return string(b) }
+		return strings.NewReplacer(
+			`"BACKDROP"`, quote(backdrop), `"STICKER"`, quote(sticker),
+			`"MONO"`, quote(mono), `"ITALIC"`, quote(italic),
+		).Replace(assetsPreset)
+	}
+	path := writeFile(t, dir, "preset.json", refs(
+		filepath.Join(dir, "backdrop.png"), filepath.Join(dir, "sticker.png"),
+		filepath.Join(dir, "mono.ttf"), filepath.Join(dir, "italic.ttf")))
+
+	base := startServer(t)
+	served := serverRender(t, base, refs(
+		upload(t, base, "image", "backdrop.png", files["backdrop.png"]),
+		upload(t, base, "image", "sticker.png", files["sticker.png"]),
+		upload(t, base, "font", "mono.ttf", files["mono.ttf"]),
+		upload(t, base, "font", "italic.ttf", files["italic.ttf"])))
+
+	want := libraryRender(t, path)
+	if want.Rect.Dx() != 240 || want.Rect.Dy() != 135 {
+		t.Fatalf("library render is %v, want 240x135", want.Rect.Size())
+	}
+	sameImage(t, "CLI", cliRender(t, path), want)
+	sameImage(t, "server", served, want)
+}
+
+// TestEntryPointsAgreeOnRawPreset renders testdata/raw.json, which leaves
+// out the font, format version and canvas size and leans on classes,
+// responsive overrides and invalid colors, through the CLI, the server
+// and the library, and expects the same image from all three. The WASM
+// build's test renders the same file.
+func TestEntryPointsAgreeOnRawPreset(t *testing.T) {
+	const path = "testdata/raw.json"
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := libraryRender(t, path)
+	if want.Rect.Dx() != 1200 || want.Rect.Dy() != 630 {
+		t.Fatalf("library render is %v, want the og_image canvas 1200x630", want.Rect.Size())
+	}
+	sameImage(t, "CLI", cliRender(t, path), want)
+	sameImage(t, "server", serverRender(t, startServer(t), string(raw)), want)
+}
@@ -0,0 +1,64 @@
+// exit.go — Process exit codes.
+//
+//	0  success
+//	1  internal or render failure
+//	2  invalid usage (flags, arguments, config file, an output path that
+//	   cannot be written)
+//	3  input file problem (preset, data, or CSV missing or unparseable, or
+//	   an asset missing with --strict-assets)
+//	4  completed, but warnings were logged and --strict-warnings is set
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+const (
+	exitOK       = 0
+	exitInternal = 1
+	exitUsage    = 2
+	exitInput    = 3
+	exitWarnings = 4
+)
+
+var (
+	// errUsage marks errors caused by how the CLI was invoked.
+	errUsage = errors.New("invalid usage")
+	// errWarnings is returned when a command treats warnings as failure.
+	errWarnings = errors.New("warnings reported")
+)
+
+// usageError wraps an error so it matches errUsage without changing its text.
+type usageError struct{ err error }
+
+func (e usageError) Error() string        { return e.err.Error() }
+func (e usageError) Unwrap() error        { return e.err }
+func (e usageError) Is(target error) bool { return target == errUsage }
+
+// usageErrorf formats an error that maps to exitUsage.
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// exitCode maps an error returned by a command to its exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage),
+		errors.Is(err, generator.ErrUnsupportedFormat),
+		errors.Is(err, generator.ErrInvalidColor),
+		errors.Is(err, generator.ErrInvalidCaption),
+		errors.Is(err, generator.ErrOutputPath):
+		return exitUsage
+	case errors.Is(err, template.ErrInput), errors.Is(err, template.ErrMissingAsset):
+		return exitInput
+	case errors.Is(err, errWarnings):
+		return exitWarnings
+	}
+	return exitInternal
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile writes content to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const (
+	okPreset = `{"canvas": {"width": 64, "height": 48}, "background": {"type": "color", "color": "#204060"}, "font": {},
+  "components": [{"id": "t", "x": 0.1, "y": 0.1, "width": 0.8, "height": 0.8, "defaults": {"visible": true, "title": "Hi"}}]}`
+
+	// missingImagePreset renders with a warning, or fails with
+	// --strict-assets.
+	missingImagePreset = `{"canvas": {"width": 64, "height": 48}, "background": {"type": "image", "source": "missing.png"}, "font": {}, "components": []}`
+)
+
+// TestExitCodes runs commands the way main does and checks the exit code
+// each outcome maps to.
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeFile(t, dir, "ok.json", okPreset)
+	missingImage := writeFile(t, dir, "missing-image.json", missingImagePreset)
+	broken := writeFile(t, dir, "broken.json", `{"canvas": `)
+	badData := writeFile(t, dir, "bad-data.json", `[1, 2`)
+	out := filepath.Join(dir, "out.png")
+
+	tests := []struct {
+		name string
+		cmd  func([]string) error
+		args []string
+		want int
+	}{
+		{"render", run, []string{"--preset", ok, "-o", out}, exitOK},
+		{"solid color", run, []string{"--color", "#ff0000", "-w", "32", "-h", "32", "-o", out}, exitOK},
+		{"validate", runValidate, []string{"--preset", ok}, exitOK},
+
+		{"no output", run, []string{"--preset", ok}, exitUsage},
+		{"bad depth", run, []string{"--preset", ok, "--depth", "12", "-o", out}, exitUsage},
+		{"unknown format", run, []string{"--preset", ok, "-o", filepath.Join(dir, "out.tiff")}, exitUsage},
+		{"missing output dir", run, []string{"--preset", ok, "-o", filepath.Join(dir, "no", "out.png")}, exitUsage},
+		{"validate without preset", runValidate, nil, exitUsage},
+
+		{"missing preset", run, []string{"--preset", filepath.Join(dir, "nope.json"), "-o", out}, exitInput},
+		{"unparseable preset", run, []string{"--preset", broken, "-o", out}, exitInput},
+		{"unparseable data", run, []string{"--preset", ok, "--data", badData, "-o", out}, exitInput},
+		{"missing asset, strict", run, []string{"--preset", missingImage, "--strict-assets", "-o", out}, exitInput},
+
+		{"validate --strict", runValidate, []string{"--preset", missingImage, "--strict"}, exitWarnings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cmd(tt.args)
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("exit code %d (error %v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestExitCodeStrictWarnings checks that --strict-warnings turns a render
+// that only warned into exit code 4, and leaves a clean one at 0.
+func TestExitCodeStrictWarnings(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeFile(t, dir, "ok.json", okPreset)
+	missingImage := writeFile(t, dir, "missing-image.json", missingImagePreset)
+	out := filepath.Join(dir, "out.png")
+
+	defer func(s bool) { strictWarnings = s }(strictWarnings)
+	args := setupLogging([]string{"--strict-warnings", "-q", "--preset", ok, "-o", out})
+
+	warningCount.Store(0)
+	if err := run(args); err != nil {
+		t.Fatal(err)
+	}
+	if code := exitCode(strictWarningsError()); code != exitOK {
+		t.Errorf("clean render: exit code %d, want %d", code, exitOK)
+	}
+
+	warningCount.Store(0)
+	if err := run([]string{"--preset", missingImage, "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	if code := exitCode(strictWarningsError()); code != exitWarnings {
+		t.Errorf("render with warnings: exit code %d, want %d", code, exitWarnings)
+	}
+}
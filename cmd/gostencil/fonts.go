@@ -0,0 +1,47 @@
+// fonts.go — List and check the fonts a preset references.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+func runFonts(args []string) error {
+	fs := flag.NewFlagSet("fonts", flag.ExitOnError)
+	var presetPath string
+	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets or preset JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if presetPath == "" {
+		return usageErrorf("--preset is required for fonts command")
+	}
+
+	preset, resolve, err := loadPreset(presetPath)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range template.InspectFontsWithResolver(preset, resolve) {
+		name := "embedded default"
+		if !r.Embedded {
+			name = filepath.Base(r.Path)
+		}
+		fmt.Printf("[%s] %s\n", r.Use, name)
+
+		if r.Error != "" {
+			fmt.Printf("    status:   MISSING — %s\n", r.Error)
+			continue
+		}
+		fmt.Printf("    status:   ok\n")
+		fmt.Printf("    family:   %s (%s)\n", r.Family, r.Style)
+		for _, c := range r.Coverage {
+			fmt.Printf("    coverage: %-24s %5d/%d\n", c.Name, c.Covered, c.Total)
+		}
+	}
+	return nil
+}
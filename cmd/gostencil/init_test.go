@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// TestInitStartersRender writes every starter template with init, in a
+// fresh directory as a user would, and renders it with --strict-assets so
+// that a missing image or font fails the test instead of being
+// substituted.
+func TestInitStartersRender(t *testing.T) {
+	starters := template.Starters()
+	if len(starters) < 5 {
+		t.Fatalf("%d starters, want at least 5", len(starters))
+	}
+	for _, st := range starters {
+		t.Run(st.Name, func(t *testing.T) {
+			if st.Description == "" {
+				t.Error("no meta.description")
+			}
+			t.Chdir(t.TempDir())
+			if err := runInit([]string{"--template", st.Name}); err != nil {
+				t.Fatal(err)
+			}
+			if err := run([]string{"--preset", "preset.json", "--data", "data.json", "--strict-assets", "-o", "out.png"}); err != nil {
+				t.Fatal(err)
+			}
+			if fi, err := os.Stat("out.png"); err != nil || fi.Size() == 0 {
+				t.Errorf("out.png: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// log.go — CLI log output: --quiet / --verbose and a compact stderr handler.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+var (
+	// strictWarnings makes a run that logged warnings exit with exitWarnings.
+	strictWarnings bool
+	// warningCount counts Warn-level records, including suppressed ones.
+	warningCount atomic.Int64
+)
+
+// setupLogging strips the global --quiet/-q, --verbose/-v and
+// --strict-warnings flags from args, except where they are another flag's
+// value or follow "--", installs the matching logger for the CLI and
+// library packages, and returns the remaining arguments.
+//
+//	--quiet    errors only
+//	(default)  progress and warnings
+//	--verbose  plus per-component timings by phase, asset resolution, face cache activity
+func setupLogging(args []string) []string {
+	level := slog.LevelInfo
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--":
+			rest = append(rest, args[i:]...)
+			i = len(args)
+		case a == "--quiet" || a == "-quiet" || a == "-q":
+			level = slog.LevelError
+		case a == "--verbose" || a == "-verbose" || a == "-v":
+			level = slog.LevelDebug
+		case a == "--strict-warnings" || a == "-strict-warnings":
+			strictWarnings = true
+		case takesValue(a) && i+1 < len(args):
+			rest = append(rest, a, args[i+1])
+			i++
+		default:
+			rest = append(rest, a)
+		}
+	}
+
+	l := slog.New(&cliHandler{mu: new(sync.Mutex), w: stderr, level: level})
+	slog.SetDefault(l)
+	template.SetLogger(l)
+	generator.SetLogger(l)
+	return rest
+}
+
+// strictWarningsError returns an errWarnings error if --strict-warnings is
+// set and the run logged warnings.
+func strictWarningsError() error {
+	if n := warningCount.Load(); strictWarnings && n > 0 {
+		return fmt.Errorf("%w: %d warning(s) with --strict-warnings", errWarnings, n)
+	}
+	return nil
+}
+
+// cliHandler formats records as single human-readable lines:
+// "Warning: msg key=value", plain "msg" for progress.
+type cliHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func (h *cliHandler) Enabled(_ context.Context, l slog.Level) bool {
+	// Warnings are always handled so --quiet --strict-warnings still counts them.
+	return l >= h.level || l == slog.LevelWarn
+}
+
+func (h *cliHandler) Handle(_ context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn && r.Level < slog.LevelError {
+		warningCount.Add(1)
+	}
+	if r.Level < h.level {
+		return nil
+	}
+
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case r.Level < slog.LevelInfo:
+		b.WriteString("  · ")
+	}
+	b.WriteString(r.Message)
+
+	write := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value.Any())
+		return true
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(write)
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *cliHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &c
+}
+
+func (h *cliHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -3,274 +3,881 @@
 // Usage:
 //
 //	gostencil -o <file> --preset <path> [--data <path>] [options]
+//	gostencil batch --preset <path> --csv <path> --out-dir <dir>
 //	gostencil schema --preset <path>
+//	gostencil validate --preset <path> [--data <path>] [--strict]
+//	gostencil fonts --preset <path>
+//	gostencil preview --dir <dir> [--out sheet.png] [--render]
+//	gostencil presets [--json]
+//	gostencil resolve --preset <path> [--data <path>] [--assets]
 //	gostencil serve [--port 8080]
 //	gostencil init
+//
+// Global flags --quiet/-q (errors only) and --verbose/-v (debug detail)
+// may appear anywhere on the command line.
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"log/slog"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xob0t/GoStencil/clients/server"
+	"github.com/xob0t/GoStencil/internal/desktop"
 	"github.com/xob0t/GoStencil/pkg/generator"
 	"github.com/xob0t/GoStencil/pkg/template"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	args, err := setupConfig(setupLogging(os.Args[1:]))
+	if err != nil {
+		fatal(usageError{err})
+	}
+	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "init":
-		if err := runInit(os.Args[2:]); err != nil {
+		if err := runInit(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "batch":
+		if err := runBatch(args[1:]); err != nil {
 			fatal(err)
 		}
 	case "schema":
-		if err := runSchema(os.Args[2:]); err != nil {
+		if err := runSchema(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "validate":
+		if err := runValidate(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "fonts":
+		if err := runFonts(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "preview":
+		if err := runPreview(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "presets":
+		if err := runPresets(args[1:]); err != nil {
+			fatal(err)
+		}
+	case "resolve":
+		if err := runResolve(args[1:]); err != nil {
 			fatal(err)
 		}
 	case "serve":
-		if err := server.RunServe(os.Args[2:]); err != nil {
+		if err := server.RunServe(args[1:], applyConfig); err != nil {
 			fatal(err)
 		}
 	case "help", "-h", "--help":
 		printUsage()
 	default:
 		// Default: generate mode (all flags on root).
-		if err := run(os.Args[1:]); err != nil {
+		if err := run(args); err != nil {
 			fatal(err)
 		}
 	}
+
+	if err := strictWarningsError(); err != nil {
+		fatal(err)
+	}
+}
+
+// presetOptions collects the flags that drive a preset render.
+type presetOptions struct {
+	presetPath string
+	dataPath   string
+	output     string
+	canvas     string
+	over       string      // --over image path
+	overFit    string      // --over-fit
+	base       image.Image // decoded --over image, set by runPreset
+	duration   secondsFlag
+	captions   captionsFlag
+	oddSize    string
+	matte      string
+	dpi        float64
+	depth      int
+	only       []string // --only-component IDs; none means all
+	crop       bool
+	cropPad    int
+	strict     bool
+	expand     bool
+	locale     string
+	allLocales bool
+	seed       uint64
+	mkdir      bool
+	open       bool            // --open the output after writing it
+	copy       bool            // --copy the PNG output to the clipboard
+	desktop    desktop.Desktop // where --open and --copy hand the output
+	tokens     template.Tokens // from seed, set by runPreset
 }
 
 func run(args []string) error {
+	return runOn(desktop.System{}, args)
+}
+
+// runOn is run handing its output to d for --open and --copy.
+func runOn(d desktop.Desktop, args []string) error {
 	fs := flag.NewFlagSet("gostencil", flag.ExitOnError)
 
 	var (
-		output     string
-		presetPath string
-		dataPath   string
-		width      int
-		height     int
-		duration   int
-		color      string
+		opts   = presetOptions{desktop: d}
+		width  int
+		height int
+		color  string
+		only   string
 	)
 
-	fs.StringVar(&output, "o", "", "Output file path (.png or .avi)")
-	fs.StringVar(&output, "output", "", "Output file path (.png or .avi)")
-	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets bundle or preset JSON")
-	fs.StringVar(&dataPath, "data", "", "Path to data.json (optional)")
+	fs.StringVar(&opts.output, "o", "", "Output file path ("+outputFormats()+")")
+	fs.StringVar(&opts.output, "output", "", "Output file path ("+outputFormats()+")")
+	fs.StringVar(&opts.presetPath, "preset", "", "Path to .gspresets bundle or preset JSON")
+	fs.StringVar(&opts.dataPath, "data", "", "Path to data.json (optional)")
+	fs.StringVar(&opts.canvas, "canvas", "", "Render at this canvas preset instead of the preset's own canvas")
 	fs.IntVar(&width, "w", 1280, "Width in pixels")
 	fs.IntVar(&width, "width", 1280, "Width in pixels")
 	fs.IntVar(&height, "h", 720, "Height in pixels")
 	fs.IntVar(&height, "height", 720, "Height in pixels")
-	fs.IntVar(&duration, "duration", 3, "Duration in seconds (AVI only)")
+	opts.duration = 3
+	fs.StringVar(&opts.over, "over", "", "Draw the components over this image instead of the preset's background")
+	fs.StringVar(&opts.overFit, "over-fit", "stretch", "How --over's image fits the canvas: stretch, contain or cover")
+	fs.Var(&opts.duration, "duration", "Duration in seconds or as 1500ms (AVI and GIF only)")
+	fs.Var(&opts.captions, "caption", "Caption shown over part of the video, as START-END:text (repeatable; AVI and GIF only)")
+	fs.StringVar(&opts.oddSize, "odd-size", generator.OddSizePad, "Make odd AVI dimensions even: pad or crop")
+	fs.StringVar(&opts.matte, "matte", generator.DefaultMatte, "Color transparent pixels are drawn over in JPEG, GIF and AVI output")
+	fs.Float64Var(&opts.dpi, "dpi", 0, "Font resolution, recorded in PNG output (default 72, not recorded)")
+	fs.IntVar(&opts.depth, "depth", 8, "Bits per channel to render at: 8 or 16 (16 is kept in PNG output)")
+	fs.BoolVar(&opts.strict, "strict-assets", false, "Fail if an image or font cannot be loaded instead of substituting it")
+	fs.StringVar(&only, "only-component", "", "Draw only these components (comma-separated IDs) over the background")
+	fs.BoolVar(&opts.crop, "crop", false, "Crop the output to the drawn components' boxes")
+	fs.IntVar(&opts.cropPad, "crop-padding", 0, "Pixels of canvas kept around the boxes with --crop")
 	fs.StringVar(&color, "color", "random", "Background color: hex or 'random'")
+	fs.BoolVar(&opts.expand, "expand", false, "Expand ${env:NAME} and ${file:path} in data values")
+	fs.StringVar(&opts.locale, "locale", "", "Render with the named locale overlay from data.json")
+	fs.BoolVar(&opts.allLocales, "all-locales", false, "Render every locale in data.json (suffixes the filename)")
+	fs.Uint64Var(&opts.seed, "seed", 0, "Seed for {{_rand}} and {{_uuid}} placeholders and --color random (default: random)")
+	fs.BoolVar(&opts.mkdir, "mkdir", false, "Create the output file's directory if it is missing")
+	fs.BoolVar(&opts.open, "open", false, "Open the output in the default viewer after writing it (terminal only)")
+	fs.BoolVar(&opts.copy, "copy", false, "Copy PNG output to the clipboard after writing it (terminal only)")
 
 	fs.Usage = printUsage
-	if err := fs.Parse(args); err != nil {
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	}
 
-	if output == "" {
+	if opts.output == "" {
 		printUsage()
-		return fmt.Errorf("output file is required (-o)")
+		return usageErrorf("output file is required (-o)")
+	}
+	if err := checkOddSize(opts.oddSize); err != nil {
+		return err
+	}
+	if err := checkMatte(opts.matte); err != nil {
+		return err
+	}
+	if err := checkDPI(opts.dpi); err != nil {
+		return err
+	}
+	if opts.depth != 8 && opts.depth != 16 {
+		return usageErrorf("--depth must be 8 or 16, got %d", opts.depth)
+	}
+	if opts.over != "" {
+		if opts.presetPath == "" {
+			return usageErrorf("--over requires --preset")
+		}
+		if opts.depth == 16 {
+			return usageErrorf("--over renders at 8 bits per channel; drop --depth 16")
+		}
+	}
+	switch opts.overFit {
+	case "stretch", "contain", "cover":
+	default:
+		return usageErrorf("--over-fit must be stretch, contain or cover, got %q", opts.overFit)
+	}
+	if opts.cropPad < 0 {
+		return usageErrorf("--crop-padding must not be negative, got %d", opts.cropPad)
+	}
+	if err := checkOutput(opts.output, opts.mkdir); err != nil {
+		return err
+	}
+	if err := checkDesktop(&opts); err != nil {
+		return err
+	}
+	for _, id := range strings.Split(only, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			opts.only = append(opts.only, id)
+		}
 	}
 
 	// Preset mode.
-	if presetPath != "" {
-		return runPreset(presetPath, dataPath, output, duration)
+	if opts.presetPath != "" {
+		return runPreset(opts)
 	}
 
-	// Simple solid-color mode.
-	cfg := generator.Config{
-		Width:    width,
-		Height:   height,
-		Duration: duration,
-		Color:    color,
+	// Simple solid-color mode, within the canvas limits presets get.
+	if width > template.MaxCanvasSize || height > template.MaxCanvasSize {
+		return usageErrorf("-w/-h must be at most %d pixels", template.MaxCanvasSize)
 	}
-
-	fmt.Printf("Generating: %s\n", output)
-	if err := generator.Generate(output, cfg); err != nil {
+	width = max(width, template.MinCanvasSize)
+	height = max(height, template.MinCanvasSize)
+	cfg := opts.config()
+	cfg.Width, cfg.Height, cfg.Color = width, height, color
+
+	slog.Info("Generating: " + opts.output)
+	finish := reportFrames(&cfg, opts.output)
+	err := generator.Generate(opts.output, cfg)
+	finish()
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Done: %s\n", output)
-	return nil
+	slog.Info("Done: " + opts.output)
+	return handOff(opts, opts.output)
 }
 
-func runPreset(presetPath, dataPath, output string, duration int) error {
+func runPreset(opts presetOptions) error {
 	// Load preset.
-	var preset *template.Preset
-	var cleanup func()
-	var err error
-
-	ext := strings.ToLower(filepath.Ext(presetPath))
-	switch ext {
-	case ".gspresets":
-		preset, cleanup, err = template.LoadPreset(presetPath)
-		if err != nil {
-			return fmt.Errorf("load preset: %w", err)
+	preset, resolve, err := loadPreset(opts.presetPath)
+	if err != nil {
+		return fmt.Errorf("load preset: %w", err)
+	}
+	if opts.canvas != "" {
+		if err := preset.UseCanvasPreset(opts.canvas); err != nil {
+			return usageError{err}
 		}
-		defer cleanup()
-	default:
-		// Treat as standalone JSON.
-		preset, err = template.ParsePresetFile(presetPath)
-		if err != nil {
-			return fmt.Errorf("load preset: %w", err)
+	}
+	if opts.over != "" {
+		if opts.base, err = loadOverImage(opts.over); err != nil {
+			return err
+		}
+		if preset.Canvas.Defaulted {
+			b := opts.base.Bounds()
+			preset.Canvas = template.Canvas{Width: b.Dx(), Height: b.Dy(), Snap: preset.Canvas.Snap}
+			if err := preset.Normalize(); err != nil {
+				return usageErrorf("--over %s: %v", opts.over, err)
+			}
+			slog.Info(fmt.Sprintf("Canvas %dx%d from %s", preset.Canvas.Width, preset.Canvas.Height, opts.over))
 		}
 	}
 
 	// Load data (optional).
 	var data *template.DataSpec
-	if dataPath != "" {
+	if opts.dataPath != "" {
 		var warnings []string
-		data, warnings, err = template.LoadData(dataPath)
+		data, warnings, err = template.LoadDataWithOptions(opts.dataPath, template.DataOptions{Expand: opts.expand})
 		if err != nil {
 			return fmt.Errorf("load data: %w", err)
 		}
 		for _, w := range warnings {
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+			slog.Warn(w)
 		}
+	}
 
-		// Validate.
-		for _, w := range template.ValidateData(data, preset) {
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	// Select locale(s).
+	if opts.locale != "" || opts.allLocales {
+		if data == nil || len(data.Locales) == 0 {
+			return usageErrorf("--locale/--all-locales require a data file with a \"locales\" map")
+		}
+		if opts.locale != "" {
+			if _, ok := data.Locales[opts.locale]; !ok {
+				return usageErrorf("locale %q not defined in %s (available: %s)", opts.locale, opts.dataPath, strings.Join(data.LocaleNames(), ", "))
+			}
+			data.Locale = opts.locale
 		}
 	}
 
-	// Merge defaults + data → resolved components.
-	components := template.MergeData(preset, data)
+	// Validate.
+	for _, w := range template.ValidateData(data, preset) {
+		if w.Level == template.SeverityInfo {
+			slog.Info(w.Message)
+		} else {
+			slog.Warn(w.Message)
+		}
+	}
+	if len(opts.only) > 0 {
+		_, warnings := template.OnlyComponents(preset, nil, opts.only)
+		for _, w := range warnings {
+			slog.Warn(w)
+		}
+	}
+	opts.seed = runSeed(opts.seed)
+	if opts.tokens, err = renderTokens(opts.config()); err != nil {
+		return err
+	}
 
 	// Render.
-	renderer, err := template.NewRenderer(preset.Font.Path)
+	renderer, err := template.NewRendererForFont(preset.Font, resolve)
 	if err != nil {
 		return fmt.Errorf("renderer: %w", err)
 	}
+	renderer.SetDPI(opts.dpi)
+	renderer.SetDepth16(opts.depth == 16)
+	renderer.SetStrictAssets(opts.strict)
+	renderer.SetOverFit(opts.overFit)
+	renderer.SetProfile(slog.Default().Enabled(context.Background(), slog.LevelDebug))
+
+	slog.Info("Rendering preset: " + preset.Meta.Name)
 
-	fmt.Printf("Rendering preset: %s\n", preset.Meta.Name)
-	img, err := renderer.RenderPreset(preset, components)
+	if !opts.allLocales {
+		if err := renderPresetTo(renderer, preset, data, opts.output, opts); err != nil {
+			return err
+		}
+		return handOff(opts, opts.output)
+	}
+	var outputs []string
+	for _, name := range data.LocaleNames() {
+		data.Locale = name
+		output := localeOutput(opts.output, name)
+		if err := renderPresetTo(renderer, preset, data, output, opts); err != nil {
+			return fmt.Errorf("locale %s: %w", name, err)
+		}
+		outputs = append(outputs, output)
+	}
+	return handOff(opts, outputs...)
+}
+
+// renderPresetTo merges data onto the preset, renders, and writes output
+// with opts' encoder settings.
+func renderPresetTo(renderer *template.Renderer, preset *template.Preset, data *template.DataSpec, output string, opts presetOptions) error {
+	// Merge defaults + data → resolved components.
+	components := template.MergeData(preset, data)
+	if len(opts.only) > 0 {
+		components, _ = template.OnlyComponents(preset, components, opts.only) // warned in runPreset
+	}
+	for _, w := range template.ExpandTokens(components, opts.tokens) {
+		slog.Warn(w)
+	}
+
+	var img image.Image
+	var err error
+	if opts.base != nil {
+		img, err = renderer.RenderPresetOver(opts.base, preset, components)
+	} else {
+		img, err = renderer.RenderPresetImage(context.Background(), preset, components)
+	}
 	if err != nil {
 		return fmt.Errorf("render: %w", err)
 	}
+	for _, w := range renderer.Warnings() {
+		slog.Warn(w.String())
+	}
+	if p := renderer.Profile(); p != nil {
+		slog.Debug("render profile: " + p.String())
+	}
+	if opts.crop {
+		if box := template.ComponentBounds(preset, components, opts.cropPad); !box.Empty() {
+			img = img.(interface {
+				SubImage(image.Rectangle) image.Image
+			}).SubImage(box)
+		} else {
+			slog.Warn("--crop: no components drawn; writing the whole canvas")
+		}
+	}
 
 	// Output.
-	cfg := generator.Config{
-		Image:    img,
-		Duration: duration,
-	}
+	cfg := opts.config()
+	cfg.Image = img
 
-	if err := generator.Generate(output, cfg); err != nil {
+	finish := reportFrames(&cfg, output)
+	err = generator.Generate(output, cfg)
+	finish()
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Done: %s\n", output)
+	slog.Info("Done: " + output)
 	return nil
 }
 
-func runSchema(args []string) error {
-	fs := flag.NewFlagSet("schema", flag.ExitOnError)
-	var presetPath string
-	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets or preset JSON")
-	if err := fs.Parse(args); err != nil {
-		return err
+// secondsFlag is a --duration: seconds ("2.5") or a Go duration
+// ("1500ms", "1m30s").
+type secondsFlag float64
+
+func (s *secondsFlag) String() string { return strconv.FormatFloat(float64(*s), 'g', -1, 64) }
+
+func (s *secondsFlag) Set(v string) error {
+	sec, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		d, derr := time.ParseDuration(v)
+		if derr != nil {
+			return fmt.Errorf("want seconds (2.5) or a duration (1500ms)")
+		}
+		sec = d.Seconds()
+	}
+	// Checked at the highest frame rate, so that any format can take it.
+	if !(sec > 0) || sec*generator.MaxGIFFPS > generator.MaxFrames {
+		return fmt.Errorf("must be positive and at most %d seconds", generator.MaxFrames/generator.MaxGIFFPS)
 	}
+	*s = secondsFlag(sec)
+	return nil
+}
 
-	if presetPath == "" {
-		return fmt.Errorf("--preset is required for schema command")
+// captionsFlag collects repeated --caption values, "START-END:text" in
+// seconds; END may be left out for "until the end".
+type captionsFlag []generator.Caption
+
+func (c *captionsFlag) String() string {
+	var parts []string
+	for _, caption := range *c {
+		end := ""
+		if caption.End > 0 {
+			end = strconv.FormatFloat(caption.End, 'g', -1, 64)
+		}
+		parts = append(parts, strconv.FormatFloat(caption.Start, 'g', -1, 64)+"-"+end+":"+caption.Text)
 	}
+	return strings.Join(parts, ", ")
+}
 
-	var preset *template.Preset
+func (c *captionsFlag) Set(v string) error {
+	span, text, ok := strings.Cut(v, ":")
+	start, end, ok2 := strings.Cut(span, "-")
+	if !ok || !ok2 || strings.TrimSpace(text) == "" {
+		return fmt.Errorf("want START-END:text, e.g. 0-1.5:Hello")
+	}
+	var caption generator.Caption
 	var err error
+	if caption.Start, err = strconv.ParseFloat(strings.TrimSpace(start), 64); err != nil {
+		return fmt.Errorf("start %q: want seconds", start)
+	}
+	if end = strings.TrimSpace(end); end != "" {
+		if caption.End, err = strconv.ParseFloat(end, 64); err != nil {
+			return fmt.Errorf("end %q: want seconds", end)
+		}
+		if !(caption.End > caption.Start) {
+			return fmt.Errorf("end %g is not after start %g", caption.End, caption.Start)
+		}
+	}
+	if !(caption.Start >= 0) {
+		return fmt.Errorf("start %g must not be negative", caption.Start)
+	}
+	caption.Text = text
+	*c = append(*c, caption)
+	return nil
+}
 
-	ext := strings.ToLower(filepath.Ext(presetPath))
-	switch ext {
-	case ".gspresets":
-		var cleanup func()
-		preset, cleanup, err = template.LoadPreset(presetPath)
-		if err != nil {
+// checkOddSize rejects an --odd-size other than pad or crop.
+func checkOddSize(mode string) error {
+	if mode != generator.OddSizePad && mode != generator.OddSizeCrop {
+		return usageErrorf("--odd-size must be %s or %s, got %q", generator.OddSizePad, generator.OddSizeCrop, mode)
+	}
+	return nil
+}
+
+// checkOutput fails before any rendering if output cannot be written,
+// first creating its directory if mkdir is set.
+func checkOutput(output string, mkdir bool) error {
+	if mkdir {
+		if err := makeOutputDir(filepath.Dir(output)); err != nil {
 			return err
 		}
-		defer cleanup()
-	default:
-		preset, err = template.ParsePresetFile(presetPath)
+	}
+	err := generator.CheckOutput(output)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w (--mkdir creates it)", err)
+	}
+	return err
+}
+
+// makeOutputDir creates dir and its parents, as needed.
+func makeOutputDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%w: %w", generator.ErrOutputPath, err)
+	}
+	return nil
+}
+
+// checkMatte rejects a --matte that is not "#rrggbb".
+func checkMatte(matte string) error {
+	if _, err := generator.ParseMatte(matte); err != nil {
+		return usageErrorf("--matte: %v", err)
+	}
+	return nil
+}
+
+// maxDPI bounds --dpi: beyond it a 12pt font is already taller than the
+// largest canvas's text can usefully be.
+const maxDPI = 2400
+
+// checkDPI rejects a negative or implausibly large --dpi. Zero means unset.
+func checkDPI(dpi float64) error {
+	if dpi < 0 || dpi > maxDPI {
+		return usageErrorf("--dpi must be between 1 and %d, got %g", maxDPI, dpi)
+	}
+	return nil
+}
+
+// config is the output configuration opts ask for, without an image.
+func (opts presetOptions) config() generator.Config {
+	return generator.Config{
+		DurationSeconds: float64(opts.duration),
+		Captions:        opts.captions,
+		OddSize:         opts.oddSize,
+		Matte:           opts.matte,
+		DPI:             opts.dpi,
+		Seed:            opts.seed,
+	}
+}
+
+// runSeed returns seed, or for 0 a random seed, logged so the run can be
+// repeated.
+func runSeed(seed uint64) uint64 {
+	if seed == 0 {
+		seed = rand.Uint64()
+		slog.Debug("placeholder seed", "seed", seed)
+	}
+	return seed
+}
+
+// renderTokens returns the placeholder values for a run with cfg's seed.
+// SOURCE_DATE_EPOCH, if set, fixes {{_date}} for reproducible output.
+func renderTokens(cfg generator.Config) (template.Tokens, error) {
+	tokens := cfg.Tokens(0)
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
 		if err != nil {
-			return err
+			return tokens, usageErrorf("SOURCE_DATE_EPOCH must be Unix seconds, got %q", epoch)
+		}
+		tokens.Now = time.Unix(sec, 0).UTC()
+	}
+	return tokens, nil
+}
+
+// localeOutput inserts a locale suffix before the extension: card.png → card.de.png.
+func localeOutput(output, locale string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "." + locale + ext
+}
+
+// loadPreset opens a .gspresets bundle or a standalone preset JSON file.
+// A bundle's assets are read in memory through the returned resolver; a
+// standalone preset's asset paths are files, and its resolver is nil.
+func loadPreset(path string) (*template.Preset, template.AssetResolverFunc, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".gspresets" {
+		bundle, err := template.LoadPreset(path)
+		if err != nil {
+			return nil, nil, err
 		}
+		return bundle.Preset, bundle.Resolve, nil
+	}
+	// Treat as standalone JSON.
+	preset, err := template.ParsePresetFile(path)
+	return preset, nil, err
+}
+
+// loadOverImage reads and decodes the image given to --over.
+func loadOverImage(path string) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var img image.Image
+		if img, err = template.DecodeImage(data); err == nil {
+			return img, nil
+		}
+	}
+	return nil, &template.InputError{Path: path, Err: fmt.Errorf("--over %s: %w", path, err)}
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	var (
+		presetPath string
+		jsonSchema bool
+		format     string
+	)
+	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets or preset JSON")
+	fs.BoolVar(&jsonSchema, "json-schema", false, "Print a JSON Schema for data.json instead of text")
+	fs.StringVar(&format, "format", "text", "Output format: text, or json for a description scripts can read")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if presetPath == "" {
+		return usageErrorf("--preset is required for schema command")
+	}
+	if format != "text" && format != "json" {
+		return usageErrorf("--format must be text or json, got %q", format)
+	}
+	if jsonSchema && format == "json" {
+		return usageErrorf("--json-schema and --format json are different outputs; choose one")
+	}
+
+	preset, _, err := loadPreset(presetPath)
+	if err != nil {
+		return err
 	}
 
+	if jsonSchema {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(template.DataJSONSchema(preset))
+	}
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(template.DescribeSchema(preset))
+	}
 	fmt.Print(template.FormatSchema(preset))
 	return nil
 }
 
 func runInit(args []string) error {
 	fs := flag.NewFlagSet("init", flag.ExitOnError)
-	var presetOut, dataOut string
+	var presetOut, dataOut, starter string
+	var list bool
 	fs.StringVar(&presetOut, "preset", "preset.json", "Output path for sample preset")
 	fs.StringVar(&dataOut, "data", "data.json", "Output path for sample data")
-	if err := fs.Parse(args); err != nil {
+	fs.StringVar(&starter, "template", template.DefaultStarter, "Starter template (see --list)")
+	fs.BoolVar(&list, "list", false, "List available starter templates")
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	}
 
-	p, d := template.GetExampleJSON()
-
-	if err := os.WriteFile(presetOut, []byte(p), 0644); err != nil {
-		return fmt.Errorf("write preset: %w", err)
+	if list {
+		for _, st := range template.Starters() {
+			fmt.Printf("  %-18s %s\n", st.Name, st.Description)
+		}
+		return nil
 	}
-	if err := os.WriteFile(dataOut, []byte(d), 0644); err != nil {
-		return fmt.Errorf("write data: %w", err)
+
+	written, err := template.WriteStarter(starter, presetOut, dataOut)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Created: %s, %s\n", presetOut, dataOut)
-	fmt.Println("Run: gostencil -o output.png --preset preset.json --data data.json")
+	slog.Info("Created: " + strings.Join(written, ", "))
+	slog.Info(fmt.Sprintf("Run: gostencil -o output.png --preset %s --data %s", presetOut, dataOut))
 	return nil
 }
 
+// fatal reports err and exits with the code for its category (see exit.go).
 func fatal(err error) {
 	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-	os.Exit(1)
+	os.Exit(exitCode(err))
+}
+
+// outputFormats lists the registered output extensions for help text.
+func outputFormats() string {
+	exts := generator.SupportedFormats()
+	last := len(exts) - 1
+	return strings.Join(exts[:last], ", ") + " or " + exts[last]
 }
 
 func printUsage() {
-	fmt.Print(`GoStencil — Programmable Media Generation (Pure Go)
+	fmt.Print(strings.ReplaceAll(`GoStencil — Programmable Media Generation (Pure Go)
 
 USAGE:
     gostencil -o <file> --preset <path> [--data <path>] [options]
     gostencil -o <file> --color <hex> [options]
-    gostencil schema --preset <path>
+    gostencil batch --preset <path> --csv <path> --out-dir <dir> [options]
+    gostencil schema --preset <path> [--json-schema | --format json]
+    gostencil validate --preset <path> [--data <path>] [--strict]
+    gostencil fonts --preset <path>
+    gostencil preview --dir <dir> [--out sheet.png] [--cols 4] [--thumb-width 320] [--render]
+    gostencil presets [--json]
+    gostencil resolve --preset <path> [--data <path>] [--locale <name>] [--canvas <name>] [--assets]
     gostencil serve [--port 8080]
-    gostencil init [options]
+    gostencil init [--template <name>] [--list]
 
 PRESET MODE:
     --preset <path>        .gspresets bundle or standalone preset JSON
     --data <path>          Data JSON with overrides (optional)
-    -o, --output <path>    Output file (.png or .avi)
-    --duration <sec>       Video duration in seconds (default: 3)
+    --canvas <name>        Render at a canvas preset (e.g. instagram_story)
+                           instead of the preset's canvas; components'
+                           "responsive" overrides follow the canvas
+    -o, --output <path>    Output file ({formats})
+    --mkdir                Create the output file's directory if it is
+                           missing (otherwise a missing directory is an
+                           error, reported before rendering)
+    --open                 Open the output in the default viewer once it
+                           is written (every file with --all-locales)
+    --copy                 Copy the PNG output to the clipboard once it is
+                           written (wl-copy or xclip on Linux); --open and
+                           --copy do nothing unless stdout is a terminal
+    --duration <sec>       Video duration in seconds, fractions allowed, or
+                           with a unit such as 1500ms (default: 3)
+    --caption <s-e:text>   Show text on a band at the bottom of an AVI or
+                           GIF from s to e seconds, e.g. "0-1.5:Part 1";
+                           leave out e for "until the end" ("4-:Outro").
+                           Repeatable; captions shown at once overlap
+    --odd-size pad|crop    Make an odd AVI width or height even by adding
+                           (default) or dropping a pixel
+    --matte <hex>          Color translucent pixels are composited over in
+                           JPEG, GIF and AVI output, which have no alpha
+                           (default: #000000); PNG, BMP and WebP keep alpha
+    --dpi <n>              Render font sizes as points at n DPI and record
+                           the density in PNG output (default: 72, where a
+                           point is a pixel, not recorded)
+    --depth 8|16           Bits per channel to render at (default: 8); at 16
+                           images keep their depth, and so does PNG output
+    --over <image>         Draw the components over this PNG or JPEG
+                           instead of the preset's background; a preset
+                           with no canvas size takes the image's
+    --over-fit <fit>       Fit --over's image to the canvas: stretch,
+                           contain or cover (default: stretch)
+    --only-component <ids> Draw only these components (comma-separated),
+                           at their positions over the background
+    --crop                 Crop the output to the drawn components' boxes
+    --crop-padding <px>    Canvas kept around the boxes with --crop
+                           (default: 0)
+    --strict-assets        Fail (exit 3) if an image or font cannot be
+                           loaded instead of substituting it with a warning
+    --expand               Expand ${env:NAME} and ${file:path} in data values
+                           (files limited to the data file's directory)
+    --locale <name>        Apply the named locale overlay from data.json
+    --all-locales          Render every locale (card.png → card.de.png, ...)
+    --seed <n>             Seed for {{_rand:N}} and {{_uuid}} in titles and
+                           items (default: random; see PLACEHOLDERS)
 
 SIMPLE MODE:
-    -o, --output <path>    Output file (.png or .avi)
-    --color <hex>          Background color or 'random' (default: random)
+    -o, --output <path>    Output file ({formats})
+    --color <hex>          Background color or 'random' (default: random;
+                           --seed repeats the same color)
     -w, --width <px>       Width in pixels (default: 1280)
     -h, --height <px>      Height in pixels (default: 720)
-    --duration <sec>       Video duration (default: 3)
+    --duration <sec>       As in preset mode
+    --caption <s-e:text>   As in preset mode
+    --odd-size pad|crop    As in preset mode
+    --dpi <n>              Density recorded in PNG output
+    --mkdir                As in preset mode
+    --open, --copy         As in preset mode
+
+BATCH MODE:
+    --preset <path>        .gspresets bundle or standalone preset JSON
+    --canvas <name>        As in preset mode
+    --csv <path>           CSV with a header row; one render per data row
+    --map <col=path,...>   Column → data path mapping, e.g.
+                           "title=components.title.title"
+                           (default: columns named "components.…")
+    --out-dir <dir>        Output directory (created if missing)
+    --name <pattern>       Output filename; {column} and {_row} are
+                           replaced per row (default: "{_row}.png")
+    --duration <sec>       As in preset mode (AVI and GIF only)
+    --caption <s-e:text>   As in preset mode
+    --odd-size pad|crop    As in preset mode
+    --matte <hex>          As in preset mode
+    --dpi <n>              As in preset mode
+    --strict-assets        As in preset mode
+    --seed <n>             As in preset mode; {{_seq}} is the row number
+    --skip-unchanged       Skip rows whose output exists and whose render
+                           fingerprint matches the previous run's, kept in
+                           <out-dir>/.gostencil-batch.json
+
+PLACEHOLDERS (titles and item text, preset or data):
+    {{_seq}}               Batch row number (0 for a single render)
+    {{_date:<layout>}}     Render date in a Go time layout
+                           (default: 2006-01-02); SOURCE_DATE_EPOCH fixes it
+    {{_rand:<n>}}          n random digits/capitals (default: 6)
+    {{_uuid}}              Random UUID (version 4)
+                           Random values depend only on --seed, the row and
+                           the component, so a fixed seed repeats them
 
 UI SERVER:
     gostencil serve [--port 8080]       Start the web UI editor
+        --host <addr>                   Listen address (default: 127.0.0.1)
+        --tls-cert <file>               TLS certificate; serve HTTPS
+        --tls-key <file>                TLS private key
+        --token <secret>                Require "Authorization: Bearer <secret>" on /api/
+        --cors-origin <origin>          Allow browser API calls from origin (repeatable, or *)
+        --no-browser                    Don't open the browser
+        --data-dir <dir>                Persist uploaded assets and presets across restarts
+        --allow-system-fonts            Offer installed fonts via /api/fonts/system
+        --max-body <size>               JSON request limit (default: 20MB)
+        --max-upload <size>             Upload/import limit (default: 50MB)
+        --max-canvas <px>               Largest canvas width or height (default: 8192)
+        --max-image-size <px>           Reduce larger images drawn much smaller, 0 disables (default: 4096)
+        --max-concurrent <n>            Renders at once (default: CPU count)
+        --render-timeout <dur>          Per-render time limit (default: 30s)
+        --render-cache <n>              Cached renders, 0 disables (default: 64)
+        --workers <n>                   Background export workers (default: 2)
+        --job-ttl <dur>                 Keep finished job results (default: 30m)
+        --shutdown-timeout <dur>        Drain time on Ctrl-C/SIGTERM (default: 10s)
+
+INIT:
+    gostencil init --list               List starter templates
+    gostencil init --template <name>    Write a starter (default: minimal):
+                                        youtube-thumb, quote-card,
+                                        instagram-story, product-card
+        --preset <path>                 Preset output (default: preset.json)
+        --data <path>                   Data output (default: data.json)
 
 SCHEMA:
     gostencil schema --preset <path>    Print preset's data.json format
+        --json-schema                   Emit a JSON Schema (draft 2020-12)
+                                        for editors and CI checks instead
+        --format text|json              json: every component and its fields,
+                                        types, defaults and required flags
+                                        (default: text)
+
+PREVIEW:
+    gostencil preview --dir <dir>       Contact sheet of every .gspresets in dir
+        --out <path>                    Output PNG, JPEG or BMP (default: sheet.png)
+        --cols <n>                      Thumbnails per row (default: 4)
+        --thumb-width <px>              Thumbnail width (default: 320)
+
+CANVAS PRESETS:
+    gostencil presets                   List canvas preset names and sizes,
+                                        including those from the config file
+        --json                          Print them as JSON
+
+VALIDATION:
+    gostencil validate --preset <path> [--data <path>] [--strict]
+                                        Check data IDs, fonts, colors, image
+                                        files and overlaps; --strict fails
+                                        on any warning
+    gostencil fonts --preset <path>     List referenced fonts: availability,
+                                        family/style, Unicode coverage
+    gostencil resolve --preset <path> [--data <path>] [--locale <name>]
+                                        Print the merged components as JSON:
+                                        pixel boxes, final styles, which
+                                        fields data or the locale set, and
+                                        hidden components and ignored
+                                        overrides with the reason
+        --assets                        Render instead, and print the fonts
+                                        and images it read, where from, and
+                                        which components used them
+
+GLOBAL FLAGS:
+    -q, --quiet            Errors only
+    -v, --verbose          Also log per-component timings by phase, asset resolution,
+                           and font face cache activity
+    --config <path>        Read default flag values from this JSON file
+                           (default: ./gostencil.json, ./.gostencil.json,
+                           $XDG_CONFIG_HOME/gostencil/gostencil.json);
+                           its "canvas-presets" object adds canvas sizes
+    --no-config            Ignore config files
+    --strict-config        Fail on unknown config keys instead of warning
+    --strict-warnings      Exit with code 4 if any warning was reported
+
+EXIT CODES:
+    0  success
+    1  internal or render error
+    2  invalid usage (flags, arguments, config file)
+    3  input file problem (preset/data/CSV missing or unparseable, or
+       an asset missing with --strict-assets)
+    4  completed with warnings (--strict-warnings)
 
 EXAMPLES:
     gostencil init
+    gostencil init --template youtube-thumb
     gostencil serve
     gostencil -o card.png --preset theme.gspresets
     gostencil -o card.png --preset theme.gspresets --data data.json
     gostencil -o video.avi --preset theme.gspresets --duration 5
     gostencil schema --preset theme.gspresets
+    gostencil batch --preset theme.gspresets --csv cards.csv --out-dir out/ --name "{title}.png"
     gostencil -o solid.png --color "#ff0000" -w 1920 -h 1080
-`)
+`, "{formats}", outputFormats()))
 }
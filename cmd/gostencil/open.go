@@ -0,0 +1,56 @@
+// open.go — --open and --copy: hand the written file to the desktop after
+// a successful render, when someone is at a terminal to see it.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// checkDesktop validates --open and --copy before rendering, so a render
+// is not wasted on a copy that cannot happen. Outside a terminal both are
+// turned off, with a note.
+func checkDesktop(opts *presetOptions) error {
+	if !opts.open && !opts.copy {
+		return nil
+	}
+	if !opts.desktop.Interactive() {
+		slog.Info("--open and --copy are ignored: standard output is not a terminal")
+		opts.open, opts.copy = false, false
+		return nil
+	}
+	if !opts.copy {
+		return nil
+	}
+	if ext := strings.ToLower(filepath.Ext(opts.output)); ext != ".png" {
+		return usageErrorf("--copy puts PNG output on the clipboard; -o %s is %s", opts.output, ext)
+	}
+	if opts.allLocales {
+		return usageErrorf("--copy takes one image; drop --all-locales")
+	}
+	if err := opts.desktop.CheckCopyImage(); err != nil {
+		return usageErrorf("--copy: %v", err)
+	}
+	return nil
+}
+
+// handOff opens and copies the files a successful run wrote, as --open
+// and --copy ask. The files stay written if either fails.
+func handOff(opts presetOptions, outputs ...string) error {
+	if opts.copy {
+		if err := opts.desktop.CopyImage(outputs[0]); err != nil {
+			return fmt.Errorf("--copy: %w", err)
+		}
+		slog.Info("Copied to the clipboard: " + outputs[0])
+	}
+	if opts.open {
+		for _, output := range outputs {
+			if err := opts.desktop.Open(output); err != nil {
+				return fmt.Errorf("--open: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xob0t/GoStencil/internal/desktop/desktoptest"
+)
+
+// TestOpenAndCopy runs renders with --open and --copy against a fake
+// desktop and checks what each hands over: the output after a successful
+// render at a terminal, and nothing otherwise.
+func TestOpenAndCopy(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeFile(t, dir, "ok.json", okPreset)
+	missingImage := writeFile(t, dir, "missing-image.json", missingImagePreset)
+	png := filepath.Join(dir, "out.png")
+	jpg := filepath.Join(dir, "out.jpg")
+	noClipboard := errors.New("no clipboard here")
+	openFailed := errors.New("no viewer")
+
+	tests := []struct {
+		name    string
+		fake    desktoptest.Fake
+		args    []string
+		usage   bool     // the run fails with a usage error
+		fails   bool     // the run fails otherwise
+		written string   // output the run leaves, "" for none
+		opened  []string // what the fake is handed
+		copied  []string
+	}{
+		{name: "open and copy",
+			fake: desktoptest.Fake{Attended: true}, args: []string{"--preset", ok, "-o", png, "--open", "--copy"},
+			written: png, opened: []string{png}, copied: []string{png}},
+		{name: "open without a preset",
+			fake: desktoptest.Fake{Attended: true}, args: []string{"-w", "64", "-h", "48", "-o", jpg, "--open"},
+			written: jpg, opened: []string{jpg}},
+		{name: "not at a terminal",
+			fake: desktoptest.Fake{}, args: []string{"--preset", ok, "-o", png, "--open", "--copy"},
+			written: png},
+		{name: "failed render",
+			fake: desktoptest.Fake{Attended: true}, args: []string{"--preset", missingImage, "--strict-assets", "-o", png, "--open", "--copy"},
+			fails: true},
+		{name: "copy of a JPEG",
+			fake: desktoptest.Fake{Attended: true}, args: []string{"--preset", ok, "-o", jpg, "--copy"},
+			usage: true},
+		{name: "copy without a clipboard",
+			fake: desktoptest.Fake{Attended: true, CheckErr: noClipboard}, args: []string{"--preset", ok, "-o", png, "--copy"},
+			usage: true},
+		{name: "viewer fails",
+			fake: desktoptest.Fake{Attended: true, OpenErr: openFailed}, args: []string{"--preset", ok, "-o", png, "--open"},
+			fails: true, written: png, opened: []string{png}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(png)
+			os.Remove(jpg)
+
+			err := runOn(&tt.fake, tt.args)
+			var usage usageError
+			switch {
+			case tt.usage && !errors.As(err, &usage):
+				t.Errorf("error %v, want a usage error", err)
+			case tt.fails && (err == nil || errors.As(err, &usage)):
+				t.Errorf("error %v, want a failed run", err)
+			case !tt.usage && !tt.fails && err != nil:
+				t.Errorf("error %v", err)
+			}
+			for _, out := range []string{png, jpg} {
+				if _, err := os.Stat(out); (err == nil) != (out == tt.written) {
+					t.Errorf("%s written: %v, want %v", filepath.Base(out), err == nil, out == tt.written)
+				}
+			}
+			if !reflect.DeepEqual(tt.fake.Opened, tt.opened) {
+				t.Errorf("opened %q, want %q", tt.fake.Opened, tt.opened)
+			}
+			if !reflect.DeepEqual(tt.fake.Copied, tt.copied) {
+				t.Errorf("copied %q, want %q", tt.fake.Copied, tt.copied)
+			}
+		})
+	}
+}
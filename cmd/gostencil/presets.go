@@ -0,0 +1,31 @@
+// presets.go — List the canvas preset names and their sizes.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+func runPresets(args []string) error {
+	fs := flag.NewFlagSet("presets", flag.ExitOnError)
+	var asJSON bool
+	fs.BoolVar(&asJSON, "json", false, "Print a JSON array of {name, width, height}")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	list := template.CanvasPresets()
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	for _, p := range list {
+		fmt.Printf("  %-18s %d×%d\n", p.Name, p.Width, p.Height)
+	}
+	return nil
+}
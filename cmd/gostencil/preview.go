@@ -0,0 +1,178 @@
+// preview.go — Contact sheet of every .gspresets bundle in a directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+var (
+	errorTileColor = color.RGBA{0x5c, 0x1a, 0x1a, 0xff}
+	labelColor     = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+)
+
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	var (
+		dir        string
+		output     string
+		cols       int
+		thumbWidth int
+		render     bool
+	)
+	fs.StringVar(&dir, "dir", ".", "Directory containing .gspresets bundles")
+	fs.StringVar(&output, "out", "sheet.png", "Output contact sheet (.png, .jpg or .bmp)")
+	fs.IntVar(&cols, "cols", 4, "Thumbnails per row")
+	fs.IntVar(&thumbWidth, "thumb-width", 320, "Thumbnail width in pixels")
+	fs.BoolVar(&render, "render", false, "Render every bundle, even those with a stored preview")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if cols < 1 || thumbWidth < 16 {
+		return usageErrorf("--cols must be ≥ 1 and --thumb-width ≥ 16")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.gspresets"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return &template.InputError{Path: dir, Err: fmt.Errorf("no .gspresets files in %s", dir)}
+	}
+	sort.Strings(paths)
+
+	fm, err := template.NewFontManagerFromBytes(nil)
+	if err != nil {
+		return err
+	}
+	face, err := fm.GetFace(template.DefaultGridLabelSize, 72)
+	if err != nil {
+		return err
+	}
+
+	thumbs := make([]image.Image, 0, len(paths))
+	names := make([]string, 0, len(paths))
+	for _, p := range paths {
+		thumb, name, err := renderThumbnail(p, thumbWidth, render)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("%s: %v", filepath.Base(p), err))
+			thumb = errorTile(thumbWidth, err, face)
+			name = filepath.Base(p)
+		}
+		thumbs = append(thumbs, thumb)
+		names = append(names, name)
+		slog.Info(fmt.Sprintf("[%d/%d] %s", len(thumbs), len(paths), filepath.Base(p)))
+	}
+
+	sheet, err := template.ComposeGrid(thumbs, template.GridOptions{
+		Cols:      min(cols, len(thumbs)),
+		CellWidth: thumbWidth,
+		Labels:    names,
+	})
+	if err != nil {
+		return err
+	}
+	if err := template.SaveImage(sheet, output); err != nil {
+		return err
+	}
+	slog.Info("Done: " + output)
+	return nil
+}
+
+// renderThumbnail scales a bundle's stored preview to width, or renders the
+// bundle with its default data if it has none or render is set.
+func renderThumbnail(path string, width int, render bool) (image.Image, string, error) {
+	bundle, err := template.LoadPreset(path)
+	if err != nil {
+		return nil, "", err
+	}
+	preset := bundle.Preset
+
+	var img image.Image
+	if preset.Preview != nil && !render {
+		if img, err = png.Decode(bytes.NewReader(preset.Preview)); err != nil {
+			slog.Warn(fmt.Sprintf("%s: unreadable %s, rendering instead: %v", filepath.Base(path), template.PreviewName, err))
+			img = nil
+		}
+	}
+	if img == nil {
+		renderer, err := template.NewRendererForFont(preset.Font, bundle.Resolve)
+		if err != nil {
+			return nil, "", err
+		}
+		if img, err = renderer.RenderPreset(preset, template.MergeData(preset, nil)); err != nil {
+			return nil, "", err
+		}
+		for _, w := range renderer.Warnings() {
+			slog.Warn(fmt.Sprintf("%s: %s", filepath.Base(path), w))
+		}
+	}
+
+	b := img.Bounds()
+	height := max(b.Dy()*width/b.Dx(), 1)
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(thumb, thumb.Bounds(), img, b, xdraw.Src, nil)
+
+	name := preset.Meta.Name
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	return thumb, name, nil
+}
+
+// errorTile is a 16:9 placeholder describing why a bundle could not be rendered.
+func errorTile(width int, err error, face font.Face) image.Image {
+	tile := image.NewRGBA(image.Rect(0, 0, width, width*9/16))
+	xdraw.Draw(tile, tile.Bounds(), image.NewUniform(errorTileColor), image.Point{}, xdraw.Src)
+
+	lineH := face.Metrics().Height.Ceil()
+	y := 8 + lineH
+	for _, line := range wrapLabel("error: "+err.Error(), width-16, face) {
+		if y > tile.Bounds().Dy()-4 {
+			break
+		}
+		drawLabel(tile, line, 8, y, face)
+		y += lineH
+	}
+	return tile
+}
+
+// drawLabel draws text with its baseline at (x, y).
+func drawLabel(dst *image.RGBA, text string, x, y int, face font.Face) {
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(labelColor), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(text)
+}
+
+// wrapLabel breaks text into lines no wider than maxW pixels.
+func wrapLabel(text string, maxW int, face font.Face) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	var lines []string
+	cur := words[0]
+	for _, w := range words[1:] {
+		if font.MeasureString(face, cur+" "+w).Ceil() > maxW {
+			lines = append(lines, template.Ellipsize(cur, maxW, face))
+			cur = w
+		} else {
+			cur += " " + w
+		}
+	}
+	return append(lines, template.Ellipsize(cur, maxW, face))
+}
@@ -0,0 +1,184 @@
+// progress.go — Progress of batch runs and video export: a live line on a
+// terminal, kept below the log output, or a log line every few seconds
+// when stderr is redirected. --quiet turns it off.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+)
+
+const (
+	// progressRedraw and progressLogEvery bound how often the live line is
+	// redrawn and how often a line is logged without a terminal.
+	progressRedraw   = 100 * time.Millisecond
+	progressLogEvery = 5 * time.Second
+
+	progressBarWidth = 24
+	progressNameMax  = 40 // runes of the current item's name shown
+)
+
+// stderr is where the CLI logs. On a terminal it also holds the live
+// progress line.
+var stderr = &console{w: os.Stderr}
+
+// console is a writer shared by log lines and a live status line: each
+// write erases the status line, writes, and draws it again below.
+type console struct {
+	mu     sync.Mutex
+	w      io.Writer
+	status string // the status line on screen, "" for none
+}
+
+func (c *console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == "" {
+		return c.w.Write(p)
+	}
+	c.erase()
+	n, err := c.w.Write(p)
+	io.WriteString(c.w, c.status)
+	return n, err
+}
+
+// setStatus replaces the status line; "" removes it.
+func (c *console) setStatus(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.erase()
+	c.status = s
+	io.WriteString(c.w, s)
+}
+
+// erase blanks the status line with spaces rather than an escape code,
+// which not every Windows console understands.
+func (c *console) erase() {
+	if n := utf8.RuneCountInString(c.status); n > 0 {
+		io.WriteString(c.w, "\r"+strings.Repeat(" ", n)+"\r")
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal that can redraw
+// a line.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progress reports how many of a known number of items (rows, frames)
+// are done. Its methods are safe for concurrent use.
+type progress struct {
+	mu      sync.Mutex
+	unit    string // what is counted, plural: "rows", "frames"
+	total   int
+	done    int
+	current string // the item under way, if named
+	start   time.Time
+	last    time.Time // of the last redraw or log line
+	live    bool      // redraw a status line on the terminal
+	off     bool      // --quiet
+}
+
+// newProgress starts reporting on total items of unit.
+func newProgress(unit string, total int) *progress {
+	p := &progress{unit: unit, total: total, start: time.Now()}
+	switch {
+	case !slog.Default().Enabled(context.Background(), slog.LevelInfo):
+		p.off = true
+	case isTerminal(os.Stderr):
+		p.live = true
+	}
+	p.last = p.start
+	return p
+}
+
+// update records that done items are finished and current, which may be
+// "", is under way.
+func (p *progress) update(done int, current string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done, p.current = done, current
+	if p.off {
+		return
+	}
+	now := time.Now()
+	switch {
+	case p.live && (now.Sub(p.last) >= progressRedraw || done == p.total):
+		stderr.setStatus(p.line(now, true))
+	case !p.live && now.Sub(p.last) >= progressLogEvery && done < p.total:
+		slog.Info(p.line(now, false))
+	default:
+		return
+	}
+	p.last = now
+}
+
+// finish removes the live line, leaving the log to say what was done.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.live && !p.off {
+		stderr.setStatus("")
+	}
+}
+
+// line formats the progress: a bar for the terminal, plain text for a log.
+func (p *progress) line(now time.Time, bar bool) string {
+	var b strings.Builder
+	if bar {
+		filled := progressBarWidth * p.done / max(p.total, 1)
+		b.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "] ")
+	} else {
+		b.WriteString("Progress: ")
+	}
+	fmt.Fprintf(&b, "%d/%d %s", p.done, p.total, p.unit)
+	if p.done > 0 && p.done < p.total {
+		elapsed := now.Sub(p.start)
+		eta := elapsed * time.Duration(p.total-p.done) / time.Duration(p.done)
+		fmt.Fprintf(&b, ", ETA %s", eta.Round(time.Second))
+	}
+	if p.current != "" {
+		b.WriteString("  " + shortName(p.current, progressNameMax))
+	}
+	return b.String()
+}
+
+// shortName keeps the last n runes of s, which end in the file name.
+func shortName(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return "…" + string(r[len(r)-n+1:])
+}
+
+// reportFrames makes cfg report the frames of an AVI or GIF export of
+// output as progress. The returned function ends the report; call it once
+// Generate returns.
+func reportFrames(cfg *generator.Config, output string) (finish func()) {
+	var bar *progress
+	cfg.Progress = func(done, total int) {
+		if bar == nil {
+			bar = newProgress("frames", total)
+		}
+		bar.update(done, output)
+	}
+	return func() {
+		if bar != nil {
+			bar.finish()
+		}
+	}
+}
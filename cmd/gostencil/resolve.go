@@ -0,0 +1,94 @@
+// resolve.go — Print the merged components as JSON, with where each
+// overridden value came from, or with --assets the assets a render of them
+// uses.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+func runResolve(args []string) error {
+	fs := flag.NewFlagSet("resolve", flag.ExitOnError)
+	var presetPath, dataPath, locale, canvas string
+	var assets bool
+	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets or preset JSON")
+	fs.StringVar(&canvas, "canvas", "", "Resolve at this canvas preset instead of the preset's own canvas")
+	fs.StringVar(&dataPath, "data", "", "Path to data.json (optional)")
+	fs.StringVar(&locale, "locale", "", "Apply the named locale overlay from data.json")
+	fs.BoolVar(&assets, "assets", false, "Render, and print the assets the render used instead of the components")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if presetPath == "" {
+		return usageErrorf("--preset is required for resolve command")
+	}
+
+	preset, resolve, err := loadPreset(presetPath)
+	if err != nil {
+		return err
+	}
+	if canvas != "" {
+		if err := preset.UseCanvasPreset(canvas); err != nil {
+			return usageError{err}
+		}
+	}
+
+	var data *template.DataSpec
+	if dataPath != "" {
+		var warnings []string
+		if data, warnings, err = template.LoadData(dataPath); err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			slog.Warn(w)
+		}
+	}
+	if locale != "" {
+		if data == nil || len(data.Locales) == 0 {
+			return usageErrorf("--locale requires a data file with a \"locales\" map")
+		}
+		if _, ok := data.Locales[locale]; !ok {
+			return usageErrorf("locale %q not defined in %s (available: %s)", locale, dataPath, strings.Join(data.LocaleNames(), ", "))
+		}
+		data.Locale = locale
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if assets {
+		used, err := usedAssets(preset, data, resolve)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(used)
+	}
+	return enc.Encode(template.Resolve(preset, data))
+}
+
+// usedAssets renders preset with data, discarding the image, and returns
+// the assets the render read. A missing asset is listed, not an error.
+func usedAssets(preset *template.Preset, data *template.DataSpec, resolve template.AssetResolverFunc) ([]template.AssetUse, error) {
+	renderer, err := template.NewRendererForFont(preset.Font, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("renderer: %w", err)
+	}
+	if _, err := renderer.RenderPresetContext(context.Background(), preset, template.MergeData(preset, data)); err != nil {
+		return nil, err
+	}
+	for _, w := range renderer.Warnings() {
+		slog.Warn(w.String())
+	}
+	used := renderer.AssetsUsed()
+	if used == nil {
+		used = []template.AssetUse{} // print [], not null
+	}
+	return used, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSchemaFormatJSONSnapshot expects `gostencil schema --format json`
+// to print pkg/template's schema snapshot byte for byte.
+func TestSchemaFormatJSONSnapshot(t *testing.T) {
+	want, err := os.ReadFile("../../pkg/template/testdata/snapshots/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	err = runSchema([]string{"--preset", "../../pkg/template/testdata/schema/preset.json", "--format", "json"})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("schema --format json printed:\n%s\nwant the snapshot:\n%s", got, want)
+	}
+}
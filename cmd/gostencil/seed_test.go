@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSeedDeterminism renders random placeholders and a random color twice
+// with the same --seed, expecting identical files, and once with another
+// seed, expecting a difference.
+func TestSeedDeterminism(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	dir := t.TempDir()
+	preset := writeFile(t, dir, "tokens.json", `{"canvas": {"width": 160, "height": 48}, "background": {"type": "color", "color": "#204060"}, "font": {},
+  "components": [{"id": "t", "x": 0, "y": 0, "width": 1, "height": 1, "defaults": {"visible": true, "title": "{{_rand:8}} {{_date}}", "items": [{"type": "text", "text": "{{_uuid}}"}]}}]}`)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"placeholders", []string{"--preset", preset}},
+		{"random color", []string{"--color", "random", "-w", "32", "-h", "32"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render := func(seed string) []byte {
+				t.Helper()
+				out := filepath.Join(t.TempDir(), "out.png")
+				if err := run(append(tt.args, "--seed", seed, "-o", out)); err != nil {
+					t.Fatal(err)
+				}
+				data, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			}
+			first := render("42")
+			if !bytes.Equal(first, render("42")) {
+				t.Error("two renders with --seed 42 differ")
+			}
+			if bytes.Equal(first, render("43")) {
+				t.Error("renders with --seed 42 and 43 are identical")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// validate.go — Check a preset (and optional data) without rendering.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var (
+		presetPath string
+		dataPath   string
+		strict     bool
+	)
+	fs.StringVar(&presetPath, "preset", "", "Path to .gspresets or preset JSON")
+	fs.StringVar(&dataPath, "data", "", "Path to data.json (optional)")
+	fs.BoolVar(&strict, "strict", false, "Fail on any warning (e.g. missing fonts)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if presetPath == "" {
+		return usageErrorf("--preset is required for validate command")
+	}
+
+	preset, resolve, err := loadPreset(presetPath)
+	if err != nil {
+		return err
+	}
+
+	var data *template.DataSpec
+	var loadWarnings []string
+	if dataPath != "" {
+		data, loadWarnings, err = template.LoadData(dataPath)
+		if err != nil {
+			return err
+		}
+	}
+	for _, w := range loadWarnings {
+		slog.Warn(w)
+	}
+
+	issues := template.LintWithResolver(preset, data, resolve)
+	for _, i := range issues {
+		if i.Severity == template.SeverityWarning {
+			slog.Warn(i.String())
+		} else {
+			slog.Info(i.String())
+		}
+	}
+
+	warnings := len(loadWarnings) + template.CountWarnings(issues)
+	if strict && warnings > 0 {
+		return fmt.Errorf("%w: %d problem(s) found (strict mode)", errWarnings, warnings)
+	}
+	fmt.Printf("OK: %s (%d warning(s))\n", presetPath, warnings)
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package desktop hands files to the user's desktop: opening a file or URL
+// in the application the system associates with it, and copying a PNG
+// image to the clipboard. The server opens its UI with it, and the CLI
+// its output for --open and --copy.
+//
+// Each operation runs the system's own tool (open, xdg-open, osascript,
+// wl-copy, …), picked from the OS name and environment alone, apart from
+// running it. Callers hold a Desktop, System for the real one, so tests
+// can substitute a fake (see desktoptest).
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported is returned when this system has no way to do what was
+// asked, such as copying an image without a clipboard tool installed.
+var ErrUnsupported = errors.New("not supported on this system")
+
+// Desktop is what the CLI and server hand files to.
+type Desktop interface {
+	// Interactive reports whether someone is at a terminal to see what
+	// Open and CopyImage do.
+	Interactive() bool
+	// Open launches the application the desktop associates with target,
+	// a file path or URL, without waiting for it to exit.
+	Open(target string) error
+	// CheckCopyImage reports whether CopyImage can work here, so a caller
+	// can fail before producing the image.
+	CheckCopyImage() error
+	// CopyImage puts the PNG image in the file at path on the clipboard.
+	CopyImage(path string) error
+}
+
+// System is the Desktop of the system this program runs on.
+type System struct{}
+
+// command is a program to run for an operation.
+type command struct {
+	name  string
+	args  []string
+	stdin string // file fed to standard input, "" for none
+}
+
+// Interactive reports whether standard output is a terminal. Scripts, CI
+// jobs and pipes redirect it, and expect no windows to open and the
+// clipboard to be left alone.
+func (System) Interactive() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Open launches the application the desktop associates with target, a
+// file path or URL, without waiting for it to exit. The error reports
+// only a launcher that could not be started.
+func (System) Open(target string) error {
+	c := openCommand(runtime.GOOS, target)
+	cmd := exec.Command(c.name, c.args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open %s: %w", target, err)
+	}
+	go cmd.Wait() // reap the launcher; the application outlives it
+	return nil
+}
+
+// openCommand is the launcher for target on goos.
+func openCommand(goos, target string) command {
+	switch goos {
+	case "windows":
+		return command{name: "rundll32", args: []string{"url.dll,FileProtocolHandler", target}}
+	case "darwin":
+		return command{name: "open", args: []string{target}}
+	}
+	return command{name: "xdg-open", args: []string{target}}
+}
+
+// CheckCopyImage reports whether CopyImage can work here, so a caller can
+// fail before producing the image. Its error names what is missing.
+func (System) CheckCopyImage() error {
+	_, err := copyCommand(runtime.GOOS, "", exec.LookPath, os.Getenv)
+	return err
+}
+
+// CopyImage puts the PNG image in the file at path on the clipboard. It
+// returns an error wrapping ErrUnsupported when this system has no
+// clipboard tool it knows.
+func (System) CopyImage(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", path, err)
+	}
+	c, err := copyCommand(runtime.GOOS, abs, exec.LookPath, os.Getenv)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(c.name, c.args...)
+	if c.stdin != "" {
+		f, err := os.Open(c.stdin)
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", path, err)
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	// Standard output and error are left unconnected: wl-copy and xclip
+	// stay in the background to serve the clipboard, and would hold a
+	// pipe open until another program takes it.
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("copy %s to the clipboard: %s: %w", path, c.name, err)
+	}
+	return nil
+}
+
+// copyCommand is the command that copies the PNG file at path, absolute,
+// to the clipboard on goos, found with lookPath and getenv.
+func copyCommand(goos, path string, lookPath func(string) (string, error), getenv func(string) string) (command, error) {
+	switch goos {
+	case "windows":
+		// The clipboard needs a single-threaded apartment.
+		script := "Add-Type -AssemblyName System.Windows.Forms, System.Drawing; " +
+			"[System.Windows.Forms.Clipboard]::SetImage([System.Drawing.Image]::FromFile('" + strings.ReplaceAll(path, "'", "''") + "'))"
+		return command{name: "powershell", args: []string{"-NoProfile", "-NonInteractive", "-STA", "-Command", script}}, nil
+	case "darwin":
+		return command{name: "osascript", args: []string{
+			"-e", "on run argv",
+			"-e", "set the clipboard to (read (POSIX file (item 1 of argv)) as «class PNGf»)",
+			"-e", "end run",
+			path,
+		}}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		if getenv("WAYLAND_DISPLAY") != "" {
+			if _, err := lookPath("wl-copy"); err == nil {
+				return command{name: "wl-copy", args: []string{"--type", "image/png"}, stdin: path}, nil
+			}
+		}
+		if getenv("DISPLAY") != "" {
+			if _, err := lookPath("xclip"); err == nil {
+				return command{name: "xclip", args: []string{"-selection", "clipboard", "-t", "image/png", "-i", path}}, nil
+			}
+		}
+		return command{}, fmt.Errorf("%w: copying an image needs a display and wl-copy (Wayland, from wl-clipboard) or xclip (X11)", ErrUnsupported)
+	}
+	return command{}, fmt.Errorf("%w: no clipboard support for images on %s", ErrUnsupported, goos)
+}
@@ -0,0 +1,86 @@
+package desktop
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	for goos, want := range map[string]command{
+		"windows": {name: "rundll32", args: []string{"url.dll,FileProtocolHandler", "C:\\out.png"}},
+		"darwin":  {name: "open", args: []string{"C:\\out.png"}},
+		"linux":   {name: "xdg-open", args: []string{"C:\\out.png"}},
+		"freebsd": {name: "xdg-open", args: []string{"C:\\out.png"}},
+	} {
+		if got := openCommand(goos, "C:\\out.png"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %+v, want %+v", goos, got, want)
+		}
+	}
+}
+
+// TestCopyCommand picks the clipboard tool for each system from a fake
+// environment and set of installed programs.
+func TestCopyCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		env       map[string]string
+		installed []string
+		want      string // command name, "" for ErrUnsupported
+	}{
+		{"windows", "windows", nil, nil, "powershell"},
+		{"macOS", "darwin", nil, nil, "osascript"},
+		{"Wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, "wl-copy"},
+		{"XWayland without wl-copy", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"xclip"}, "xclip"},
+		{"X11", "openbsd", map[string]string{"DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, "xclip"},
+		{"X11 without xclip", "linux", map[string]string{"DISPLAY": ":0"}, []string{"wl-copy"}, ""},
+		{"no display", "linux", nil, []string{"wl-copy", "xclip"}, ""},
+		{"other system", "plan9", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(name string) (string, error) {
+				for _, p := range tt.installed {
+					if p == name {
+						return "/usr/bin/" + name, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+			getenv := func(key string) string { return tt.env[key] }
+
+			c, err := copyCommand(tt.goos, "/tmp/o'ut.png", lookPath, getenv)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("got %+v, %v; want ErrUnsupported", c, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.name != tt.want {
+				t.Fatalf("command %s, want %s", c.name, tt.want)
+			}
+			// The path reaches the tool whole, quoted where a script
+			// carries it.
+			switch c.name {
+			case "wl-copy":
+				if c.stdin != "/tmp/o'ut.png" {
+					t.Errorf("stdin %q, want the image", c.stdin)
+				}
+			case "powershell":
+				if script := c.args[len(c.args)-1]; !strings.Contains(script, "'/tmp/o''ut.png'") {
+					t.Errorf("script does not quote the path: %s", script)
+				}
+			default:
+				if c.args[len(c.args)-1] != "/tmp/o'ut.png" || c.stdin != "" {
+					t.Errorf("args %q, stdin %q; want the path as the last argument", c.args, c.stdin)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package desktoptest provides a fake desktop.Desktop that records what
+// it is handed instead of opening windows or touching the clipboard.
+package desktoptest
+
+import "github.com/xob0t/GoStencil/internal/desktop"
+
+// Fake is a desktop.Desktop for tests. Its zero value is a desktop no one
+// is watching, where every operation succeeds.
+type Fake struct {
+	Attended bool  // what Interactive reports
+	CheckErr error // returned by CheckCopyImage
+	OpenErr  error // returned by Open
+	CopyErr  error // returned by CopyImage
+
+	Opened []string // targets passed to Open, in order
+	Copied []string // paths passed to CopyImage, in order
+}
+
+var _ desktop.Desktop = (*Fake)(nil)
+
+func (f *Fake) Interactive() bool { return f.Attended }
+
+func (f *Fake) Open(target string) error {
+	f.Opened = append(f.Opened, target)
+	return f.OpenErr
+}
+
+func (f *Fake) CheckCopyImage() error { return f.CheckErr }
+
+func (f *Fake) CopyImage(path string) error {
+	f.Copied = append(f.Copied, path)
+	return f.CopyErr
+}
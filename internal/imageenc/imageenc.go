@@ -0,0 +1,146 @@
+// Package imageenc holds the still-image encoders shared by the generator
+// and template packages, so that generator.Generate and
+// template.SaveImage write the same bytes without either importing the
+// other.
+package imageenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+
+	"golang.org/x/image/bmp"
+)
+
+// ErrUnsupportedFormat is generator.ErrUnsupportedFormat and
+// template.ErrUnsupportedFormat: an extension with no encoder.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// DefaultJPEGQuality is the JPEG quality when none is given.
+const DefaultJPEGQuality = 90
+
+// Options are the settings the still formats read.
+type Options struct {
+	DPI     float64    // recorded in PNG output when > 0
+	Quality int        // JPEG quality, 1–100 (default: DefaultJPEGQuality)
+	Matte   color.RGBA // what JPEG draws translucent pixels over
+}
+
+// Encode writes img in the still format of ext, one of ".png", ".jpg",
+// ".jpeg", ".bmp" or ".webp" in lower case.
+func Encode(w io.Writer, img image.Image, ext string, opts Options) error {
+	switch ext {
+	case ".png":
+		return PNG(w, img, opts.DPI)
+	case ".jpg", ".jpeg":
+		return JPEG(w, Flatten(img, opts.Matte), opts.Quality)
+	case ".bmp":
+		return BMP(w, img)
+	case ".webp":
+		return WebP(w, img)
+	}
+	return fmt.Errorf("%w %q: use .bmp, .jpeg, .jpg, .png or .webp", ErrUnsupportedFormat, ext)
+}
+
+// Flatten composites img over matte for formats without transparency.
+// Opaque images are returned as they are.
+func Flatten(img image.Image, matte color.RGBA) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, image.NewUniform(matte), image.Point{}, draw.Src)
+	draw.Draw(out, b, img, b.Min, draw.Over)
+	return out
+}
+
+// JPEG writes img, which should be opaque, at quality; 0 or less means
+// DefaultJPEGQuality.
+func JPEG(w io.Writer, img image.Image, quality int) error {
+	if quality <= 0 {
+		quality = DefaultJPEGQuality
+	}
+	if err := jpeg.Encode(w, img, &jpeg.Options{Quality: min(quality, 100)}); err != nil {
+		return fmt.Errorf("encode JPEG: %w", err)
+	}
+	return nil
+}
+
+// BMP writes img as a BMP, keeping its alpha channel.
+func BMP(w io.Writer, img image.Image) error {
+	if err := bmp.Encode(w, img); err != nil {
+		return fmt.Errorf("encode BMP: %w", err)
+	}
+	return nil
+}
+
+// pngEncoder reuses its compression buffers across encodes, which
+// otherwise cost several allocations per image.
+var pngEncoder = png.Encoder{BufferPool: new(pngBufferPool)}
+
+// pngBufferPool is a png.EncoderBufferPool backed by a sync.Pool.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+func (p *pngBufferPool) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
+
+// PNG writes img as a PNG. A positive dpi is recorded in a pHYs chunk so
+// viewers and print tools know the intended density.
+func PNG(w io.Writer, img image.Image, dpi float64) error {
+	if dpi <= 0 {
+		if err := pngEncoder.Encode(w, img); err != nil {
+			return fmt.Errorf("encode PNG: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := pngEncoder.Encode(&buf, img); err != nil {
+		return fmt.Errorf("encode PNG: %w", err)
+	}
+	// The encoder always writes the 8-byte signature and then IHDR (13
+	// bytes of data plus length, type and CRC); pHYs must precede IDAT, so
+	// it goes straight after.
+	const afterIHDR = 8 + 4 + 4 + 13 + 4
+	encoded := buf.Bytes()
+	if _, err := w.Write(encoded[:afterIHDR]); err != nil {
+		return err
+	}
+	if _, err := w.Write(physChunk(dpi)); err != nil {
+		return err
+	}
+	_, err := w.Write(encoded[afterIHDR:])
+	return err
+}
+
+// physChunk builds a pHYs chunk giving dpi in pixels per metre, the only
+// unit PNG has.
+func physChunk(dpi float64) []byte {
+	ppm := uint32(math.Round(dpi / 0.0254))
+	chunk := make([]byte, 0, 4+4+9+4)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = append(chunk, 1) // unit: metre
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
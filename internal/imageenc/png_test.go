@@ -0,0 +1,43 @@
+package imageenc
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// benchImage is a 1920x1080 gradient, compressible like a rendered card.
+func benchImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := range 1080 {
+		for x := range 1920 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x / 8), uint8(y / 8), 0x80, 0xff})
+		}
+	}
+	return img
+}
+
+// BenchmarkPNG encodes with PNG, whose encoder reuses its buffers.
+func BenchmarkPNG(b *testing.B) {
+	img := benchImage()
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := PNG(io.Discard, img, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPNGUnpooled encodes with png.Encode, which allocates its
+// buffers for every image.
+func BenchmarkPNGUnpooled(b *testing.B) {
+	img := benchImage()
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := png.Encode(io.Discard, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
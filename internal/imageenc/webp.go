@@ -0,0 +1,473 @@
+// webp.go — Lossless WebP (VP8L): the subtract-green transform, LZ77
+// backward references and one set of prefix codes for the whole image.
+// The format is specified in RFC 9649.
+package imageenc
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"slices"
+)
+
+// WebPMaxSize is the largest width or height a WebP image can have.
+const WebPMaxSize = 16384
+
+const (
+	webpMaxLength   = 4096        // longest backward reference
+	webpMaxDistance = 1<<20 - 120 // farthest one, in pixels
+	webpMinLength   = 3           // shorter matches are written as literals
+	webpHashBits    = 16          // size of the match finder's table
+	webpLengthCodes = 24          // length prefixes after the 256 green literals
+	webpDistCodes   = 40          // distance prefixes
+	webpMaxCodeLen  = 15          // longest prefix code
+	webpMaxCLCLen   = 7           // longest code-length code
+	webpSignature   = 0x2f        // first byte of a VP8L bitstream
+	webpSubtractGrn = 2           // transform type
+)
+
+// webpCodeLengthOrder is the order code-length code lengths are written in.
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// WebP writes img as a lossless WebP, keeping its alpha channel.
+func WebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > WebPMaxSize || height > WebPMaxSize {
+		return fmt.Errorf("encode WebP: %dx%d image: width and height must be 1 to %d", width, height, WebPMaxSize)
+	}
+	argb, translucent := webpPixels(img)
+
+	var bw bitWriter
+	bw.write(webpSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(b2u(translucent), 1)
+	bw.write(0, 3) // version
+	bw.write(1, 1) // a transform follows
+	bw.write(webpSubtractGrn, 2)
+	bw.write(0, 1) // no more transforms
+	for i, p := range argb {
+		g := p >> 8 & 0xff
+		argb[i] = p&0xff00ff00 | (p>>16-g)&0xff<<16 | (p-g)&0xff
+	}
+	writeWebPImage(&bw, argb, width)
+	data := bw.bytes()
+
+	pad := len(data) & 1
+	header := make([]byte, 0, 20)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+len(data)+pad))
+	header = append(header, "WEBPVP8L"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// webpPixels returns img's pixels as non-premultiplied ARGB, row by row,
+// and whether any of them is not opaque.
+func webpPixels(img image.Image) ([]uint32, bool) {
+	b := img.Bounds()
+	argb := make([]uint32, 0, b.Dx()*b.Dy())
+	translucent := false
+	add := func(c color.NRGBA) {
+		translucent = translucent || c.A != 0xff
+		argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		switch img := img.(type) {
+		case *image.RGBA:
+			for x := b.Min.X; x < b.Max.X; x++ {
+				add(color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA))
+			}
+		case *image.NRGBA:
+			for x := b.Min.X; x < b.Max.X; x++ {
+				add(img.NRGBAAt(x, y))
+			}
+		default:
+			for x := b.Min.X; x < b.Max.X; x++ {
+				add(color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA))
+			}
+		}
+	}
+	return argb, translucent
+}
+
+// webpToken is a literal pixel (length 0) or a backward reference.
+type webpToken struct {
+	argb   uint32 // the literal
+	dist   uint32 // distance code
+	length uint16
+}
+
+// writeWebPImage writes the entropy-coded pixels of a width-wide image:
+// no color cache, no meta prefix codes, then the five prefix codes and
+// the pixels coded with them.
+func writeWebPImage(bw *bitWriter, argb []uint32, width int) {
+	tokens := webpTokens(argb, width)
+
+	var (
+		green = make([]uint32, 256+webpLengthCodes)
+		red   = make([]uint32, 256)
+		blue  = make([]uint32, 256)
+		alpha = make([]uint32, 256)
+		dist  = make([]uint32, webpDistCodes)
+	)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.argb>>8&0xff]++
+			red[t.argb>>16&0xff]++
+			blue[t.argb&0xff]++
+			alpha[t.argb>>24]++
+			continue
+		}
+		lc, _, _ := webpPrefix(int(t.length))
+		dc, _, _ := webpPrefix(int(t.dist))
+		green[256+lc]++
+		dist[dc]++
+	}
+
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // no meta prefix codes
+	codes := make([]prefixCode, 5)
+	for i, hist := range [][]uint32{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(hist, webpMaxCodeLen)
+		codes[i].writeTo(bw)
+	}
+
+	cg, cr, cb, ca, cd := codes[0], codes[1], codes[2], codes[3], codes[4]
+	for _, t := range tokens {
+		if t.length == 0 {
+			cg.put(bw, int(t.argb>>8&0xff))
+			cr.put(bw, int(t.argb>>16&0xff))
+			cb.put(bw, int(t.argb&0xff))
+			ca.put(bw, int(t.argb>>24))
+			continue
+		}
+		code, n, extra := webpPrefix(int(t.length))
+		cg.put(bw, 256+code)
+		bw.write(uint32(extra), uint(n))
+		code, n, extra = webpPrefix(int(t.dist))
+		cd.put(bw, code)
+		bw.write(uint32(extra), uint(n))
+	}
+}
+
+// webpTokens splits argb into literals and backward references, greedily
+// taking the longest match among the pixel to the left, the one above and
+// the last position with the same two pixels.
+func webpTokens(argb []uint32, width int) []webpToken {
+	n := len(argb)
+	hash := func(i int) uint32 {
+		return (argb[i]*0x1e35a7bd ^ argb[i+1]*0x9e3779b1) >> (32 - webpHashBits)
+	}
+	last := make([]int32, 1<<webpHashBits)
+	for i := range last {
+		last[i] = -1
+	}
+	insert := func(i int) {
+		if i+1 < n {
+			last[hash(i)] = int32(i)
+		}
+	}
+
+	var tokens []webpToken
+	for i := 0; i < n; {
+		bestLen, bestDist := 0, 0
+		try := func(d int) {
+			if d < 1 || d > i || d > webpMaxDistance {
+				return
+			}
+			limit := min(webpMaxLength, n-i)
+			l := 0
+			for l < limit && argb[i-d+l] == argb[i+l] {
+				l++
+			}
+			if l > bestLen {
+				bestLen, bestDist = l, d
+			}
+		}
+		try(1)
+		try(width)
+		if i+1 < n {
+			if j := last[hash(i)]; j >= 0 {
+				try(i - int(j))
+			}
+		}
+
+		if bestLen < webpMinLength {
+			tokens = append(tokens, webpToken{argb: argb[i]})
+			insert(i)
+			i++
+			continue
+		}
+		tokens = append(tokens, webpToken{dist: webpDistanceCode(bestDist, width), length: uint16(bestLen)})
+		for j := i; j < i+bestLen; j++ {
+			insert(j)
+		}
+		i += bestLen
+	}
+	return tokens
+}
+
+// webpDistanceCode is the code for a backward reference of d pixels on
+// an image width wide: the short codes for the pixel above and the one to
+// the left, else d offset past the 120 two-dimensional codes.
+func webpDistanceCode(d, width int) uint32 {
+	switch d {
+	case width:
+		return 1
+	case 1:
+		return 2
+	}
+	return uint32(d + 120)
+}
+
+// webpPrefix splits v ≥ 1, a length or distance code, into its prefix
+// symbol and the count and value of the extra bits that follow it.
+func webpPrefix(v int) (code, n, extra int) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := bits.Len(uint(d)) - 1
+	return 2*h + d>>(h-1)&1, h - 1, d & (1<<(h-1) - 1)
+}
+
+// prefixCode is a canonical Huffman code over an alphabet.
+type prefixCode struct {
+	lengths []uint8  // code length of each symbol, as written in the stream
+	codes   []uint32 // each code, bit-reversed for the LSB-first stream
+	sizes   []uint8  // bits written per symbol: lengths, or 0 with one symbol
+}
+
+// newPrefixCode builds a code of at most maxLen bits for the symbol
+// frequencies hist. A lone symbol takes no bits.
+func newPrefixCode(hist []uint32, maxLen int) prefixCode {
+	c := prefixCode{
+		lengths: huffmanLengths(hist, maxLen),
+		codes:   make([]uint32, len(hist)),
+		sizes:   make([]uint8, len(hist)),
+	}
+	used := 0
+	for _, l := range c.lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		return c
+	}
+	copy(c.sizes, c.lengths)
+
+	var count [webpMaxCodeLen + 1]uint32
+	for _, l := range c.lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [webpMaxCodeLen + 1]uint32
+	for l, code := 1, uint32(0); l <= webpMaxCodeLen; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range c.lengths {
+		if l > 0 {
+			c.codes[s] = bits.Reverse32(next[l]) >> (32 - l)
+			next[l]++
+		}
+	}
+	return c
+}
+
+// put writes symbol s.
+func (c prefixCode) put(bw *bitWriter, s int) {
+	bw.write(c.codes[s], uint(c.sizes[s]))
+}
+
+// writeTo writes the code: as a simple code when it has at most two
+// symbols, both under 256; otherwise as code lengths, themselves coded.
+func (c prefixCode) writeTo(bw *bitWriter) {
+	var used []int
+	for s, l := range c.lengths {
+		if l > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+		}
+		return
+	}
+	bw.write(0, 1)
+
+	// Code lengths, with runs of zeros as 17 (3–10) and 18 (11–138).
+	type clToken struct{ sym, extra uint8 }
+	var toks []clToken
+	for i := 0; i < len(c.lengths); {
+		if c.lengths[i] != 0 {
+			toks = append(toks, clToken{c.lengths[i], 0})
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(c.lengths) && c.lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			toks = append(toks, clToken{18, uint8(run - 11)})
+		case run >= 3:
+			toks = append(toks, clToken{17, uint8(run - 3)})
+		default:
+			run = 1
+			toks = append(toks, clToken{0, 0})
+		}
+		i += run
+	}
+	clHist := make([]uint32, len(webpCodeLengthOrder))
+	for _, t := range toks {
+		clHist[t.sym]++
+	}
+	cl := newPrefixCode(clHist, webpMaxCLCLen)
+
+	n := len(webpCodeLengthOrder)
+	for n > 4 && cl.lengths[webpCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	bw.write(uint32(n-4), 4)
+	for _, s := range webpCodeLengthOrder[:n] {
+		bw.write(uint32(cl.lengths[s]), 3)
+	}
+	bw.write(0, 1) // a length for every symbol follows
+	for _, t := range toks {
+		cl.put(bw, int(t.sym))
+		switch t.sym {
+		case 17:
+			bw.write(uint32(t.extra), 3)
+		case 18:
+			bw.write(uint32(t.extra), 7)
+		}
+	}
+}
+
+// huffmanLengths returns Huffman code lengths for the frequencies hist,
+// none over maxLen: while the tree is too deep, the frequencies are
+// halved, which flattens it. A lone symbol gets length 1.
+func huffmanLengths(hist []uint32, maxLen int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	freq := slices.Clone(hist)
+	for {
+		type node struct {
+			weight      uint64
+			left, right int // children, or -1 for a leaf
+			sym         int
+		}
+		var nodes []node
+		var queue []int // node indices, kept sorted by weight
+		for s, f := range freq {
+			if f > 0 {
+				nodes = append(nodes, node{uint64(f), -1, -1, s})
+				queue = append(queue, len(nodes)-1)
+			}
+		}
+		switch len(queue) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[nodes[0].sym] = 1
+			return lengths
+		}
+		byWeight := func(a, b int) int {
+			if nodes[a].weight != nodes[b].weight {
+				return cmp.Compare(nodes[a].weight, nodes[b].weight)
+			}
+			return a - b
+		}
+		slices.SortFunc(queue, byWeight)
+		for len(queue) > 1 {
+			a, b := queue[0], queue[1]
+			queue = queue[2:]
+			nodes = append(nodes, node{nodes[a].weight + nodes[b].weight, a, b, -1})
+			i, _ := slices.BinarySearchFunc(queue, len(nodes)-1, byWeight)
+			queue = slices.Insert(queue, i, len(nodes)-1)
+		}
+
+		tooDeep := false
+		var walk func(i, depth int)
+		walk = func(i, depth int) {
+			if nodes[i].left < 0 {
+				lengths[nodes[i].sym] = uint8(min(depth, 255))
+				tooDeep = tooDeep || depth > maxLen
+				return
+			}
+			walk(nodes[i].left, depth+1)
+			walk(nodes[i].right, depth+1)
+		}
+		walk(queue[0], 0)
+		if !tooDeep {
+			return lengths
+		}
+		for s, f := range freq {
+			if f > 0 {
+				freq[s] = (f + 1) / 2
+			}
+		}
+	}
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// write appends the low n bits of v; n is at most 32.
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v&(1<<n-1)) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+// bytes flushes the last partial byte and returns the stream.
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
@@ -0,0 +1,76 @@
+package imageenc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// TestWebPRoundTrip encodes images of several kinds and sizes and checks
+// that decoding gives back every pixel.
+func TestWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	images := map[string]*image.NRGBA{
+		"1x1":   image.NewNRGBA(image.Rect(0, 0, 1, 1)),
+		"1x300": image.NewNRGBA(image.Rect(0, 0, 1, 300)),
+		"300x1": image.NewNRGBA(image.Rect(0, 0, 300, 1)),
+	}
+	flat := image.NewNRGBA(image.Rect(0, 0, 200, 120))
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 64))
+	noise := image.NewNRGBA(image.Rect(0, 0, 97, 53))
+	translucent := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 120 {
+		for x := range 200 {
+			c := color.NRGBA{0x1a, 0x1a, 0x2e, 0xff}
+			if x > 40 && x < 160 && y > 30 && y < 90 {
+				c = color.NRGBA{0x00, 0xff, 0xcc, 0xff}
+			}
+			flat.SetNRGBA(x, y, c)
+		}
+	}
+	for y := range 64 {
+		for x := range 256 {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y * 4), uint8(x ^ y), 0xff})
+		}
+		for x := range 64 {
+			translucent.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), 0x80, uint8(y * 4), uint8(x + y)})
+		}
+	}
+	rng.Read(noise.Pix)
+	images["flat"], images["gradient"], images["noise"], images["translucent"] = flat, gradient, noise, translucent
+
+	for name, img := range images {
+		var buf bytes.Buffer
+		if err := WebP(&buf, img); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+		if got.Bounds() != img.Bounds() {
+			t.Fatalf("%s: decoded bounds %v, want %v", name, got.Bounds(), img.Bounds())
+		}
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if g, w := color.NRGBAModel.Convert(got.At(x, y)), img.NRGBAAt(x, y); g != w {
+					t.Fatalf("%s: pixel (%d, %d) is %v, want %v", name, x, y, g, w)
+				}
+			}
+		}
+	}
+}
+
+// TestWebPSize refuses images WebP cannot hold.
+func TestWebPSize(t *testing.T) {
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 0, 10), image.Rect(0, 0, WebPMaxSize+1, 1)} {
+		if err := WebP(new(bytes.Buffer), image.NewNRGBA(r)); err == nil {
+			t.Errorf("%v: no error", r)
+		}
+	}
+}
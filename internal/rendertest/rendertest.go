@@ -0,0 +1,166 @@
+// Package rendertest checks renders against reference PNGs ("goldens")
+// kept in the calling package's testdata/golden directory.
+//
+//	img := rendertest.Render(t, "testdata/golden/cards/preset.json", "")
+//	rendertest.Check(t, "cards", img, rendertest.Perceptual)
+//
+// Run the tests with -update to write the goldens from the current
+// renders, and the snapshots CheckSnapshot compares text output with. When a render does not match, it is written to
+// testdata/failed with a heatmap of the differences next to it (see
+// template.CompareImages).
+package rendertest
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+var update = flag.Bool("update", false, "write the golden images from the current renders")
+
+const (
+	GoldenDir   = "testdata/golden"    // reference images, NAME.png
+	FailedDir   = "testdata/failed"    // renders that did not match, NAME.png and NAME.diff.png
+	SnapshotDir = "testdata/snapshots" // reference text output, NAME
+)
+
+var (
+	// Exact requires every pixel to be the same.
+	Exact = template.CompareOptions{}
+
+	// Perceptual absorbs the small antialiasing shifts a font rasterizer
+	// or blur change causes, but not a moved box or a changed color.
+	Perceptual = template.CompareOptions{Tolerance: 24, MaxDiffPixels: 64}
+)
+
+// Render renders the preset file at presetPath with the data file at
+// dataPath ("" for the preset's defaults). The test fails if anything
+// cannot be loaded or the render warns.
+func Render(t testing.TB, presetPath, dataPath string) *image.RGBA {
+	t.Helper()
+	preset, err := template.ParsePresetFile(presetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data *template.DataSpec
+	if dataPath != "" {
+		if data, _, err = template.LoadData(dataPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renderer, err := template.NewRendererForFont(preset.Font, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := renderer.RenderPreset(preset, template.MergeData(preset, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range renderer.Warnings() {
+		t.Errorf("render warning: %s", w)
+	}
+	return img
+}
+
+// Check compares img with the golden image name under opts, or with
+// -update writes img as that golden. A render that matches without being
+// identical is logged with its differences.
+func Check(t testing.TB, name string, img image.Image, opts template.CompareOptions) {
+	t.Helper()
+	golden := filepath.Join(GoldenDir, name+".png")
+	if *update {
+		if err := writePNG(golden, img); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %s", golden)
+		return
+	}
+
+	raw, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run the test with -update to create it)", err)
+	}
+	want, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("%s: %v", golden, err)
+	}
+
+	opts.Heatmap = true
+	diff := template.CompareImages(want, img, opts)
+	if diff.Match {
+		if diff.MaxDelta > 0 {
+			t.Logf("%s: matches; %d pixels differ beyond tolerance %d, largest difference %d", name, diff.DiffPixels, opts.Tolerance, diff.MaxDelta)
+		}
+		return
+	}
+
+	got := filepath.Join(FailedDir, name+".png")
+	heatmap := filepath.Join(FailedDir, name+".diff.png")
+	if err := writePNG(got, img); err != nil {
+		t.Error(err)
+	}
+	if err := writePNG(heatmap, diff.Heatmap); err != nil {
+		t.Error(err)
+	}
+	t.Errorf("%s: %d pixels differ beyond tolerance %d (largest difference %d, %d allowed); render in %s, differences in %s",
+		name, diff.DiffPixels, opts.Tolerance, diff.MaxDelta, opts.MaxDiffPixels, got, heatmap)
+}
+
+// CheckSnapshot compares got with the snapshot name, byte for byte, or
+// with -update writes got as that snapshot. It pins the exact shape of
+// output other programs read, such as JSON.
+func CheckSnapshot(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(SnapshotDir, name)
+	if *update {
+		if err := os.MkdirAll(SnapshotDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %s", path)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the test with -update to create it)", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("%s: line %d is\n\t%s\nwant\n\t%s\n(run the test with -update after an intended change)", path, i+1, g, w)
+			return
+		}
+	}
+}
+
+// writePNG writes img to path, creating its directory.
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
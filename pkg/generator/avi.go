@@ -1,7 +1,10 @@
 // avi.go — Pure Go AVI/MJPEG writer.
 //
 // Creates a valid AVI container with a single MJPEG video stream.
-// The input is always an image.Image (the "PNG-first" pipeline).
+// The input is always an image.Image (the "PNG-first" pipeline). JPEG
+// encodes in 8×8 (or 16×16) blocks and some MJPEG decoders reject frames
+// whose width or height is odd, so odd images are first padded or cropped
+// to even dimensions and the headers describe the frames actually stored.
 package generator
 
 import (
@@ -9,9 +12,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/jpeg"
 	"io"
-	"os"
+	"math"
 )
 
 // binaryWriter wraps an io.Writer and accumulates the first error,
@@ -49,51 +53,120 @@ func (bw *binaryWriter) bytes(data []byte) {
 	_, bw.err = bw.w.Write(data)
 }
 
-// writeAVI creates a valid AVI (MJPEG) file from a single image repeated
-// for the given duration at 15 fps.
-func writeAVI(output string, img image.Image, durationSec int) error {
-	f, err := os.Create(output)
-	if err != nil {
-		return fmt.Errorf("create %s: %w", output, err)
+// evenImage returns img with an even width and height, padding (repeating
+// the last row or column) or cropping one pixel per odd side as
+// cfg.OddSize says, and reports any change through cfg.warn.
+func (cfg Config) evenImage(img image.Image) (image.Image, error) {
+	mode := cfg.OddSize
+	switch mode {
+	case "":
+		mode = OddSizePad
+	case OddSizePad, OddSizeCrop:
+	default:
+		return nil, fmt.Errorf("%w %q: use %q or %q", ErrInvalidOddSize, mode, OddSizePad, OddSizeCrop)
 	}
-	defer f.Close()
 
-	if err := writeAVITo(f, img, durationSec); err != nil {
-		return err
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w%2 == 0 && h%2 == 0 {
+		return img, nil
 	}
-	return f.Sync()
+	if mode == OddSizeCrop && (w < 2 || h < 2) {
+		mode = OddSizePad // nothing left to crop to
+	}
+
+	var out *image.RGBA
+	if mode == OddSizeCrop {
+		out = image.NewRGBA(image.Rect(0, 0, w&^1, h&^1))
+		draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	} else {
+		out = image.NewRGBA(image.Rect(0, 0, w+w%2, h+h%2))
+		draw.Draw(out, b.Sub(b.Min), img, b.Min, draw.Src)
+		if w%2 != 0 {
+			draw.Draw(out, image.Rect(w, 0, w+1, h), out, image.Pt(w-1, 0), draw.Src)
+		}
+		if h%2 != 0 {
+			draw.Draw(out, image.Rect(0, h, out.Bounds().Dx(), h+1), out, image.Pt(0, h-1), draw.Src)
+		}
+	}
+
+	verb := "padded"
+	if mode == OddSizeCrop {
+		verb = "cropped"
+	}
+	cfg.warn(fmt.Sprintf("AVI frames need even dimensions: %dx%d %s to %dx%d", w, h, verb, out.Bounds().Dx(), out.Bounds().Dy()))
+	return out, nil
 }
 
-// writeAVITo writes AVI data to any io.Writer.
-func writeAVITo(w io.Writer, img image.Image, durationSec int) error {
-	// Encode source image to JPEG once.
-	buf := new(bytes.Buffer)
-	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 95}); err != nil {
-		return fmt.Errorf("encode JPEG frame: %w", err)
+// aviFPS is the AVI frame rate.
+const aviFPS = 15
+
+// writeAVITo writes a valid AVI (MJPEG) stream of runs of frames at
+// aviFPS, each run its image repeated. Each distinct image is encoded to
+// JPEG once. segs, in frame order, add a text stream; see segments.go.
+// progress (may be nil) is called after each frame is written.
+func writeAVITo(w io.Writer, runs []frameRun, segs []aviSegment, progress func(done, total int)) error {
+	// Encode each distinct image to JPEG once.
+	jpegs := make(map[string][]byte)
+	var (
+		frames    uint32
+		moviBytes uint64 // frame chunks, headers and padding included
+		maxSize   uint32
+	)
+	for _, run := range runs {
+		data, ok := jpegs[run.key]
+		if !ok {
+			buf := new(bytes.Buffer)
+			if err := jpeg.Encode(buf, run.img, &jpeg.Options{Quality: 95}); err != nil {
+				return fmt.Errorf("encode JPEG frame: %w", err)
+			}
+			data = buf.Bytes()
+			jpegs[run.key] = data
+		}
+		frames += uint32(run.count)
+		moviBytes += uint64(run.count) * (8 + uint64(len(data)+len(data)%2)) // AVI requires even-aligned chunks
+		maxSize = max(maxSize, uint32(len(data)))
 	}
-	jpegData := buf.Bytes()
-	jpegSize := uint32(len(jpegData))
 
-	// AVI requires even-aligned chunks.
-	paddedSize := jpegSize
-	if jpegSize%2 != 0 {
-		paddedSize++
+	var maxSeg uint32
+	for _, s := range segs {
+		moviBytes += 8 + uint64(len(s.data)+len(s.data)%2)
+		maxSeg = max(maxSeg, uint32(len(s.data)))
 	}
+	chunks := frames + uint32(len(segs))
 
 	// Video parameters.
-	imgW := uint32(img.Bounds().Dx())
-	imgH := uint32(img.Bounds().Dy())
-	const fps = 15
+	imgW := uint32(runs[0].img.Bounds().Dx())
+	imgH := uint32(runs[0].img.Bounds().Dy())
+	const fps = aviFPS
 	usPerFrame := uint32(1_000_000 / fps)
-	frames := uint32(durationSec) * fps
+
+	// The segment stream's strl: strh, an empty strf and strn.
+	streams, textStrl := uint32(1), uint32(0)
+	strn := []byte(segmentStreamName + "\x00")
+	if len(strn)%2 != 0 {
+		strn = append(strn, 0)
+	}
+	if len(segs) > 0 {
+		streams, textStrl = 2, 4+64+8+8+uint32(len(strn))
+	}
+
+	// RIFF sizes are 32-bit.
+	hdrlSize := uint32(4 + 64 + 124) // "hdrl" + avih + strl
+	if textStrl > 0 {
+		hdrlSize += 8 + textStrl
+	}
+	if total := 4 + 8 + uint64(hdrlSize) + 8 + 4 + moviBytes + uint64(chunks)*16 + 8; total > math.MaxUint32 {
+		return fmt.Errorf("%w: %d frames of up to %d bytes exceed the 4 GB AVI limit", ErrTooLong, frames, maxSize)
+	}
 
 	// Chunk sizes.
-	frameChunk := 8 + paddedSize          // "00dc" + size + data
-	moviSize := 4 + (frames * frameChunk) // "movi" + frames
-	idx1Size := 8 + (frames * 16)         // "idx1" header + entries
-	hdrlSize := uint32(4 + 64 + 124)      // "hdrl" + avih + strl
+	moviSize := 4 + uint32(moviBytes) // "movi" + frames and segments
+	idx1Size := 8 + (chunks * 16)     // "idx1" header + entries
 	fileSize := 4 + (8 + hdrlSize) + (8 + moviSize) + idx1Size
 
+	logger().Debug("writing AVI", "width", imgW, "height", imgH, "frames", frames, "images", len(jpegs), "frameBytes", maxSize, "segments", len(segs))
+
 	bw := &binaryWriter{w: w}
 
 	// ── RIFF Header ──
@@ -110,13 +183,13 @@ func writeAVITo(w io.Writer, img image.Image, durationSec int) error {
 	bw.fourCC("avih")
 	bw.u32(56)
 	bw.u32(usPerFrame)
-	bw.u32(uint32(float64(jpegSize) * fps)) // max bytes/sec
-	bw.u32(0)                               // padding granularity
-	bw.u32(0x10)                            // AVIF_HASINDEX
+	bw.u32(uint32(float64(maxSize) * fps)) // max bytes/sec
+	bw.u32(0)                              // padding granularity
+	bw.u32(0x10)                           // AVIF_HASINDEX
 	bw.u32(frames)
-	bw.u32(0)        // initial frames
-	bw.u32(1)        // streams
-	bw.u32(jpegSize) // suggested buffer
+	bw.u32(0) // initial frames
+	bw.u32(streams)
+	bw.u32(maxSize) // suggested buffer
 	bw.u32(imgW)
 	bw.u32(imgH)
 	bw.u32(0) // reserved ×4
@@ -142,11 +215,11 @@ func writeAVITo(w io.Writer, img image.Image, durationSec int) error {
 	bw.u32(fps)
 	bw.u32(0) // start
 	bw.u32(frames)
-	bw.u32(jpegSize) // suggested buffer
-	bw.u32(0)        // quality
-	bw.u32(0)        // sample size
-	bw.u16(0)        // rect left
-	bw.u16(0)        // rect top
+	bw.u32(maxSize) // suggested buffer
+	bw.u32(0)       // quality
+	bw.u32(0)       // sample size
+	bw.u16(0)       // rect left
+	bw.u16(0)       // rect top
 	bw.u16(uint16(imgW))
 	bw.u16(uint16(imgH))
 
@@ -165,33 +238,91 @@ func writeAVITo(w io.Writer, img image.Image, durationSec int) error {
 	bw.u32(0) // clr used
 	bw.u32(0) // clr important
 
+	if textStrl > 0 {
+		bw.fourCC("LIST")
+		bw.u32(textStrl)
+		bw.fourCC("strl")
+
+		// strh (56 bytes): one sample per segment, timed by its data
+		bw.fourCC("strh")
+		bw.u32(56)
+		bw.fourCC("txts")
+		bw.u32(0) // handler
+		bw.u32(0) // flags
+		bw.u16(0) // priority
+		bw.u16(0) // language
+		bw.u32(0) // initial frames
+		bw.u32(1) // scale
+		bw.u32(fps)
+		bw.u32(0) // start
+		bw.u32(uint32(len(segs)))
+		bw.u32(maxSeg) // suggested buffer
+		bw.u32(0)      // quality
+		bw.u32(0)      // sample size
+		bw.u16(0)      // rect
+		bw.u16(0)
+		bw.u16(0)
+		bw.u16(0)
+
+		bw.fourCC("strf")
+		bw.u32(0)
+
+		bw.fourCC("strn")
+		bw.u32(uint32(len(strn)))
+		bw.bytes(strn)
+	}
+
 	// ── movi LIST ──
 	bw.fourCC("LIST")
 	bw.u32(moviSize)
 	bw.fourCC("movi")
 
+	// eachChunk calls fn for the movi chunks in order: each frame, after
+	// the segments that start at it.
+	eachChunk := func(fn func(id string, data []byte)) {
+		frame, next := 0, 0
+		for _, run := range runs {
+			data := jpegs[run.key]
+			for range run.count {
+				for ; next < len(segs) && segs[next].frame == frame; next++ {
+					fn("01tx", segs[next].data)
+				}
+				fn("00dc", data)
+				frame++
+			}
+		}
+	}
+
 	padByte := []byte{0}
-	for range frames {
-		bw.fourCC("00dc")
-		bw.u32(jpegSize)
-		bw.bytes(jpegData)
-		if jpegSize%2 != 0 {
+	done := 0
+	eachChunk(func(id string, data []byte) {
+		bw.fourCC(id)
+		bw.u32(uint32(len(data)))
+		bw.bytes(data)
+		if len(data)%2 != 0 {
 			bw.bytes(padByte)
 		}
-	}
+		if id == "00dc" {
+			done++
+			if progress != nil {
+				progress(done, int(frames))
+			}
+		}
+	})
 
 	// ── idx1 ──
 	bw.fourCC("idx1")
-	bw.u32(frames * 16)
+	bw.u32(chunks * 16)
 
 	offset := uint32(4) // from movi start
-	for range frames {
-		bw.fourCC("00dc")
+	eachChunk(func(id string, data []byte) {
+		size := uint32(len(data))
+		bw.fourCC(id)
 		bw.u32(0x10) // AVIIF_KEYFRAME
 		bw.u32(offset)
-		bw.u32(jpegSize)
-		offset += frameChunk
-	}
+		bw.u32(size)
+		offset += 8 + size + size%2
+	})
 
 	if bw.err != nil {
 		return fmt.Errorf("write AVI: %w", bw.err)
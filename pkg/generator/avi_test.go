@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+// riffChunk is a chunk of a parsed RIFF file; LIST chunks keep their list
+// type in id ("LIST movi") and their children in sub.
+type riffChunk struct {
+	id   string
+	data []byte
+	sub  []riffChunk
+}
+
+// parseRIFF parses data as chunks, recursing into LISTs, and fails on any
+// size that overruns its parent.
+func parseRIFF(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%d trailing bytes", len(data))
+		}
+		id, size := string(data[:4]), int(binary.LittleEndian.Uint32(data[4:8]))
+		if size > len(data)-8 {
+			return nil, fmt.Errorf("%s chunk of %d bytes overruns its parent (%d left)", id, size, len(data)-8)
+		}
+		c := riffChunk{id: id, data: data[8 : 8+size]}
+		if id == "LIST" {
+			if size < 4 {
+				return nil, fmt.Errorf("LIST of %d bytes", size)
+			}
+			c.id += " " + string(c.data[:4])
+			sub, err := parseRIFF(c.data[4:])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.id, err)
+			}
+			c.sub = sub
+		}
+		chunks = append(chunks, c)
+		data = data[8+size+size%2:]
+	}
+	return chunks, nil
+}
+
+// find returns the first chunk under chunks with the given path of ids.
+func find(chunks []riffChunk, path ...string) *riffChunk {
+	for i := range chunks {
+		if chunks[i].id != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return &chunks[i]
+		}
+		if c := find(chunks[i].sub, path[1:]...); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// gradient is a w×h test image.
+func gradient(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / w), uint8(y * 255 / h), 90, 255})
+		}
+	}
+	return img
+}
+
+// TestAVIOddSize writes AVIs from odd-sized images and checks that the
+// file parses as RIFF, that the avih, strh and strf dimensions agree with
+// every JPEG frame and are even, and that the adjustment is reported.
+func TestAVIOddSize(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		mode         string
+		wantW, wantH int
+		warning      string
+	}{
+		{33, 21, "", 34, 22, "33x21 padded to 34x22"},
+		{33, 21, OddSizePad, 34, 22, "33x21 padded to 34x22"},
+		{33, 21, OddSizeCrop, 32, 20, "33x21 cropped to 32x20"},
+		{1080, 1081, OddSizeCrop, 1080, 1080, "1080x1081 cropped to 1080x1080"},
+		{17, 16, OddSizePad, 18, 16, "17x16 padded to 18x16"},
+		{1, 1, OddSizeCrop, 2, 2, "1x1 padded to 2x2"},
+		{40, 30, OddSizeCrop, 40, 30, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%dx%d_%s", tt.w, tt.h, tt.mode), func(t *testing.T) {
+			var warnings []string
+			var buf bytes.Buffer
+			err := GenerateToWriter(&buf, ".avi", Config{
+				Image:   gradient(tt.w, tt.h),
+				OddSize: tt.mode,
+				Warn:    func(msg string) { warnings = append(warnings, msg) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case tt.warning == "" && len(warnings) > 0:
+				t.Errorf("warnings %q, want none", warnings)
+			case tt.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning)):
+				t.Errorf("warnings %q, want one containing %q", warnings, tt.warning)
+			}
+
+			file, err := parseRIFF(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(file) != 1 || file[0].id != "RIFF" || string(file[0].data[:4]) != "AVI " {
+				t.Fatalf("not a single RIFF AVI chunk")
+			}
+			chunks, err := parseRIFF(file[0].data[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			avih := find(chunks, "LIST hdrl", "avih")
+			strh := find(chunks, "LIST hdrl", "LIST strl", "strh")
+			strf := find(chunks, "LIST hdrl", "LIST strl", "strf")
+			movi := find(chunks, "LIST movi")
+			if avih == nil || strh == nil || strf == nil || movi == nil || find(chunks, "idx1") == nil {
+				t.Fatalf("missing avih, strh, strf, movi or idx1")
+			}
+			u32 := func(b []byte, off int) int { return int(binary.LittleEndian.Uint32(b[off:])) }
+			u16 := func(b []byte, off int) int { return int(binary.LittleEndian.Uint16(b[off:])) }
+			dims := map[string][2]int{
+				"avih": {u32(avih.data, 32), u32(avih.data, 36)},
+				"strh": {u16(strh.data, 52), u16(strh.data, 54)},
+				"strf": {u32(strf.data, 4), u32(strf.data, 8)},
+			}
+			frames := 0
+			for _, c := range movi.sub {
+				if c.id != "00dc" {
+					continue
+				}
+				cfg, err := jpeg.DecodeConfig(bytes.NewReader(c.data))
+				if err != nil {
+					t.Fatalf("frame %d: %v", frames, err)
+				}
+				dims[fmt.Sprintf("frame %d", frames)] = [2]int{cfg.Width, cfg.Height}
+				frames++
+			}
+			if frames != aviFPS {
+				t.Errorf("%d frames, want %d", frames, aviFPS)
+			}
+			for name, d := range dims {
+				if d != [2]int{tt.wantW, tt.wantH} {
+					t.Errorf("%s is %dx%d, want %dx%d", name, d[0], d[1], tt.wantW, tt.wantH)
+				}
+			}
+		})
+	}
+}
+
+// TestAVIOddSizeInvalid rejects an unknown OddSize, even for an image
+// that needs no adjustment.
+func TestAVIOddSizeInvalid(t *testing.T) {
+	err := GenerateToWriter(&bytes.Buffer{}, ".avi", Config{Image: gradient(4, 4), OddSize: "stretch"})
+	if err == nil || !strings.Contains(err.Error(), "stretch") {
+		t.Errorf("error %v, want one naming the invalid mode", err)
+	}
+}
@@ -0,0 +1,189 @@
+// captions.go — Text burned into part of an AVI or GIF (Config.Captions),
+// drawn with the template package's text rendering. Frames are grouped
+// into runs that show the same captions; each distinct set is composited
+// and encoded once, and frames without captions reuse the base image.
+package generator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// ErrInvalidCaption is returned for a Caption with an unknown position, a
+// bad color or an empty time range.
+var ErrInvalidCaption = errors.New("invalid caption")
+
+// Caption positions.
+const (
+	CaptionTop    = "top"
+	CaptionCenter = "center"
+	CaptionBottom = "bottom"
+)
+
+// captionBand is the translucent band behind caption text, which keeps it
+// readable over any frame.
+const captionBand = "#00000099"
+
+// Caption is a line of text shown over part of an animation, on a band
+// across the frame.
+type Caption struct {
+	Text     string
+	Start    float64 // seconds from the start
+	End      float64 // seconds; 0 means until the end
+	Position string  // CaptionTop, CaptionCenter or CaptionBottom (default)
+	Size     float64 // font size in pixels (default: a twelfth of the height)
+	Color    string  // "#rrggbb" (default: white)
+}
+
+// shownAt reports whether c is on screen at t seconds.
+func (c Caption) shownAt(t float64) bool {
+	return t >= c.Start && (c.End <= 0 || t < c.End)
+}
+
+// check reports a caption that cannot be drawn.
+func (c Caption) check() error {
+	switch c.Position {
+	case "", CaptionTop, CaptionCenter, CaptionBottom:
+	default:
+		return fmt.Errorf("%w %q: position %q: use %s, %s or %s", ErrInvalidCaption, c.Text, c.Position, CaptionTop, CaptionCenter, CaptionBottom)
+	}
+	if c.Start < 0 || c.End < 0 || (c.End > 0 && c.End <= c.Start) || math.IsNaN(c.Start+c.End+c.Size) {
+		return fmt.Errorf("%w %q: time range %g–%g is empty", ErrInvalidCaption, c.Text, c.Start, c.End)
+	}
+	if c.Color != "" {
+		if _, err := ParseMatte(c.Color); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrInvalidCaption, c.Text, err)
+		}
+	}
+	return nil
+}
+
+// frameRun is a stretch of consecutive frames that show the same image.
+type frameRun struct {
+	count int
+	key   string // which captions are shown, one '0' or '1' each
+	img   image.Image
+}
+
+// frameRuns divides the frames of cfg's duration at fps into runs, with
+// img under the captions each shows. Runs with the same captions share an
+// image, so that encoders can convert it once.
+func (cfg Config) frameRuns(img image.Image, fps int) ([]frameRun, error) {
+	frames, err := FrameCount(cfg.seconds(), fps)
+	if err != nil {
+		return nil, err
+	}
+	none := strings.Repeat("0", len(cfg.Captions))
+	if len(cfg.Captions) == 0 {
+		return []frameRun{{frames, none, img}}, nil
+	}
+	for _, c := range cfg.Captions {
+		if err := c.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		runs     []frameRun
+		images   = map[string]image.Image{none: img}
+		overlays = make([]*image.RGBA, len(cfg.Captions))
+		renderer *template.Renderer
+		key      = make([]byte, len(cfg.Captions))
+	)
+	for i := range frames {
+		t := float64(i) / float64(fps)
+		for j, c := range cfg.Captions {
+			key[j] = '0'
+			if c.shownAt(t) {
+				key[j] = '1'
+			}
+		}
+		if n := len(runs); n > 0 && runs[n-1].key == string(key) {
+			runs[n-1].count++
+			continue
+		}
+
+		frame, ok := images[string(key)]
+		if !ok {
+			if renderer == nil {
+				if renderer, err = template.NewRenderer(""); err != nil {
+					return nil, fmt.Errorf("captions: %w", err)
+				}
+			}
+			out := image.NewRGBA(img.Bounds())
+			draw.Draw(out, out.Bounds(), img, out.Bounds().Min, draw.Src)
+			for j, c := range cfg.Captions {
+				if key[j] != '1' {
+					continue
+				}
+				if overlays[j] == nil {
+					if overlays[j], err = renderCaption(renderer, c, img.Bounds().Size()); err != nil {
+						return nil, err
+					}
+				}
+				draw.Draw(out, out.Bounds(), overlays[j], image.Point{}, draw.Over)
+			}
+			frame = out
+			images[string(key)] = frame
+		}
+		runs = append(runs, frameRun{1, string(key), frame})
+	}
+	logger().Debug("captions laid out", "runs", len(runs), "images", len(images))
+	return runs, nil
+}
+
+// renderCaption draws c, centered on its band, on a transparent image of
+// the given size.
+func renderCaption(r *template.Renderer, c Caption, size image.Point) (*image.RGBA, error) {
+	fontSize := c.Size
+	if fontSize <= 0 {
+		fontSize = math.Max(float64(size.Y)/12, 8)
+	}
+	pad := int(fontSize / 2)
+	comp := template.ResolvedComponent{
+		ID:      "caption",
+		Width:   size.X,
+		Height:  size.Y,
+		Padding: pad,
+		Style: template.ComponentStyle{
+			FontSize:        fontSize,
+			BackgroundColor: captionBand,
+			Color:           cmp.Or(c.Color, "#ffffff"),
+			LineHeight:      1.25,
+			TextAlign:       "center",
+		},
+		Data: template.ComponentData{Items: []template.TextItem{{Type: "text", Text: c.Text}}},
+	}
+
+	// Measure the wrapped text to place its box.
+	m, err := r.MeasureComponent(comp)
+	if err != nil {
+		return nil, fmt.Errorf("caption %q: %w", c.Text, err)
+	}
+	comp.Height = min(m.Height+2*pad, size.Y)
+	switch c.Position {
+	case CaptionTop:
+	case CaptionCenter:
+		comp.Y = (size.Y - comp.Height) / 2
+	default:
+		comp.Y = size.Y - comp.Height
+	}
+
+	preset := &template.Preset{
+		Canvas:     template.Canvas{Width: size.X, Height: size.Y},
+		Background: template.Transparent(),
+	}
+	img, err := r.RenderPresetContext(context.Background(), preset, []template.ResolvedComponent{comp})
+	if err != nil {
+		return nil, fmt.Errorf("caption %q: %w", c.Text, err)
+	}
+	return img, nil
+}
@@ -24,25 +24,41 @@ func ParseColor(s string) (r, g, b uint8, err error) {
 
 	hex := strings.TrimPrefix(s, "#")
 	if len(hex) != 6 {
-		return 0, 0, 0, fmt.Errorf("invalid color %q: expected 6-char hex", s)
+		return 0, 0, 0, fmt.Errorf("%w %q: expected 6-char hex", ErrInvalidColor, s)
 	}
 
 	rv, err := strconv.ParseUint(hex[0:2], 16, 8)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid red channel in %q: %w", s, err)
+		return 0, 0, 0, fmt.Errorf("%w %q: red channel: %w", ErrInvalidColor, s, err)
 	}
 	gv, err := strconv.ParseUint(hex[2:4], 16, 8)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid green channel in %q: %w", s, err)
+		return 0, 0, 0, fmt.Errorf("%w %q: green channel: %w", ErrInvalidColor, s, err)
 	}
 	bv, err := strconv.ParseUint(hex[4:6], 16, 8)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid blue channel in %q: %w", s, err)
+		return 0, 0, 0, fmt.Errorf("%w %q: blue channel: %w", ErrInvalidColor, s, err)
 	}
 
 	return uint8(rv), uint8(gv), uint8(bv), nil
 }
 
+// ParseMatte parses a Config.Matte color: "#rrggbb", or "" for
+// DefaultMatte. Unlike ParseColor it has no "random".
+func ParseMatte(s string) (color.RGBA, error) {
+	if s == "" {
+		s = DefaultMatte
+	}
+	if s == "random" {
+		return color.RGBA{}, fmt.Errorf("%w %q: the matte must be \"#rrggbb\"", ErrInvalidColor, s)
+	}
+	r, g, b, err := ParseColor(s)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return toRGBA(r, g, b), nil
+}
+
 // ParseHexRGBA converts a "#rrggbb" string to color.RGBA.
 // Returns white on any parse error (safe default for rendering).
 func ParseHexRGBA(hex string) color.RGBA {
@@ -59,3 +75,8 @@ func NewSolidImage(w, h int, c color.RGBA) *image.RGBA {
 	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
 	return img
 }
+
+// toRGBA is a convenience to construct color.RGBA with full alpha.
+func toRGBA(r, g, b uint8) color.RGBA {
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
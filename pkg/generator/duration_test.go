@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/gif"
+	"math"
+	"testing"
+)
+
+// TestFrameCount checks the rounding and the one-frame minimum at several
+// frame rates.
+func TestFrameCount(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		fps     int
+		want    int
+	}{
+		{2.5, 15, 38}, // 37.5 rounds half away from zero
+		{0.5, 15, 8},
+		{1.5, 15, 23},
+		{1.0 / 3, 15, 5},
+		{0.01, 15, 1},
+		{0, 15, 1},
+		{2.5, 10, 25},
+		{1.05, 20, 21},
+		{0.5, 50, 25},
+		{1.5, 24, 36},
+		{0.75, 12, 9},
+	}
+	for _, tt := range tests {
+		got, err := FrameCount(tt.seconds, tt.fps)
+		if err != nil || got != tt.want {
+			t.Errorf("FrameCount(%g, %d) = %d, %v; want %d", tt.seconds, tt.fps, got, err, tt.want)
+		}
+	}
+	for _, seconds := range []float64{math.NaN(), math.Inf(1), float64(MaxFrames)/15 + 1} {
+		if _, err := FrameCount(seconds, 15); !errors.Is(err, ErrTooLong) {
+			t.Errorf("FrameCount(%g, 15) error %v, want ErrTooLong", seconds, err)
+		}
+	}
+}
+
+// TestAVIDurationHeaders writes AVIs of fractional durations and checks
+// that the avih frame count, the strh rate and length, the frames in movi
+// and the idx1 entries agree, and give the duration to the nearest frame.
+func TestAVIDurationHeaders(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want int
+	}{
+		{Config{DurationSeconds: 0.5}, 8},
+		{Config{DurationSeconds: 1.5}, 23},
+		{Config{DurationSeconds: 2.5}, 38},
+		{Config{DurationSeconds: 7.0 / 3}, 35},
+		{Config{DurationSeconds: 0.02}, 1},
+		{Config{Duration: 2}, 30},
+		{Config{Duration: 2, DurationSeconds: 0.2}, 3},
+		{Config{}, aviFPS},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%g", tt.cfg.Duration, tt.cfg.DurationSeconds), func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Image = gradient(16, 8)
+			var buf bytes.Buffer
+			if err := GenerateToWriter(&buf, ".avi", cfg); err != nil {
+				t.Fatal(err)
+			}
+			file, err := parseRIFF(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks, err := parseRIFF(file[0].data[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			avih := find(chunks, "LIST hdrl", "avih")
+			strh := find(chunks, "LIST hdrl", "LIST strl", "strh")
+			movi := find(chunks, "LIST movi")
+			idx1 := find(chunks, "idx1")
+			if avih == nil || strh == nil || movi == nil || idx1 == nil {
+				t.Fatal("missing avih, strh, movi or idx1")
+			}
+			u32 := func(b []byte, off int) int { return int(binary.LittleEndian.Uint32(b[off:])) }
+
+			frames := 0
+			for _, c := range movi.sub {
+				if c.id == "00dc" {
+					frames++
+				}
+			}
+			counts := map[string]int{
+				"avih frames":  u32(avih.data, 16),
+				"strh length":  u32(strh.data, 32),
+				"movi frames":  frames,
+				"idx1 entries": len(idx1.data) / 16,
+			}
+			for name, n := range counts {
+				if n != tt.want {
+					t.Errorf("%s = %d, want %d", name, n, tt.want)
+				}
+			}
+
+			scale, rate := u32(strh.data, 20), u32(strh.data, 24)
+			if scale != 1 || rate != aviFPS {
+				t.Errorf("strh scale %d, rate %d; want 1, %d", scale, rate, aviFPS)
+			}
+			if us := u32(avih.data, 0); us != 1_000_000/aviFPS {
+				t.Errorf("avih %d µs per frame, want %d", us, 1_000_000/aviFPS)
+			}
+			if got, seconds := float64(tt.want*scale)/float64(rate), cfg.seconds(); tt.want > 1 && math.Abs(got-seconds) > 0.5/aviFPS+1e-9 {
+				t.Errorf("stream lasts %gs, more than half a frame from %gs", got, seconds)
+			}
+		})
+	}
+}
+
+// TestGIFDuration writes GIFs of fractional durations at several frame
+// rates and checks the frame count and that every delay is the frame
+// interval, clamped at MaxGIFFPS.
+func TestGIFDuration(t *testing.T) {
+	for _, fps := range []int{5, 10, 12, 25, 50, 60} {
+		for _, seconds := range []float64{0.5, 1.25, 2.5} {
+			t.Run(fmt.Sprintf("%dfps_%gs", fps, seconds), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := GenerateToWriter(&buf, ".gif", Config{Image: gradient(8, 8), FPS: fps, DurationSeconds: seconds}); err != nil {
+					t.Fatal(err)
+				}
+				g, err := gif.DecodeAll(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rate := min(fps, MaxGIFFPS)
+				want, _ := FrameCount(seconds, rate)
+				if len(g.Image) != want || len(g.Delay) != want {
+					t.Errorf("%d frames, %d delays; want %d", len(g.Image), len(g.Delay), want)
+				}
+				for i, d := range g.Delay {
+					if d != 100/rate {
+						t.Fatalf("frame %d delay %d/100 s, want %d", i, d, 100/rate)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// format.go — Output formats by file extension: the built-in encoders and
+// RegisterFormat for programs that add their own.
+package generator
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/xob0t/GoStencil/internal/imageenc"
+)
+
+// EncodeFunc writes img in one output format. cfg is the Config passed to
+// Generate or GenerateToWriter; an encoder reads the options that apply to
+// it. One that never calls cfg.Progress is reported done when it returns.
+type EncodeFunc func(w io.Writer, img image.Image, cfg Config) error
+
+// formats maps normalized extensions to their encoders.
+var formats = make(map[string]EncodeFunc)
+
+func init() {
+	RegisterFormat(".png", func(w io.Writer, img image.Image, cfg Config) error {
+		return imageenc.PNG(w, img, cfg.DPI)
+	})
+	RegisterFormat(".jpg", encodeJPEG)
+	RegisterFormat(".jpeg", encodeJPEG)
+	RegisterFormat(".bmp", func(w io.Writer, img image.Image, cfg Config) error {
+		return imageenc.BMP(w, img)
+	})
+	RegisterFormat(".webp", func(w io.Writer, img image.Image, cfg Config) error {
+		return imageenc.WebP(w, img)
+	})
+	RegisterFormat(".gif", func(w io.Writer, img image.Image, cfg Config) error {
+		img, err := cfg.matted(img)
+		if err != nil {
+			return err
+		}
+		fps := cfg.FPS
+		if fps <= 0 {
+			fps = DefaultGIFFPS
+		}
+		fps = min(fps, MaxGIFFPS)
+		runs, err := cfg.frameRuns(img, fps)
+		if err != nil {
+			return err
+		}
+		return writeGIFTo(w, runs, fps, cfg.Progress)
+	})
+	RegisterFormat(".avi", func(w io.Writer, img image.Image, cfg Config) error {
+		img, err := cfg.matted(img)
+		if err != nil {
+			return err
+		}
+		if img, err = cfg.evenImage(img); err != nil {
+			return err
+		}
+		runs, err := cfg.frameRuns(img, aviFPS)
+		if err != nil {
+			return err
+		}
+		frames := 0
+		for _, run := range runs {
+			frames += run.count
+		}
+		segs, err := aviSegments(cfg.Segments, frames, aviFPS)
+		if err != nil {
+			return err
+		}
+		return writeAVITo(w, runs, segs, cfg.Progress)
+	})
+}
+
+func encodeJPEG(w io.Writer, img image.Image, cfg Config) error {
+	img, err := cfg.matted(img)
+	if err != nil {
+		return err
+	}
+	return imageenc.JPEG(w, img, cfg.Quality)
+}
+
+// RegisterFormat makes Generate and GenerateToWriter write files with the
+// extension ext using enc, replacing the encoder of a registered
+// extension. Like the built-in formats, it is meant to be called from an
+// init function; it is not safe to call while generating. It panics if
+// ext is empty or enc is nil.
+func RegisterFormat(ext string, enc EncodeFunc) {
+	ext = NormalizeExt(ext)
+	if ext == "." || enc == nil {
+		panic(fmt.Sprintf("generator: RegisterFormat(%q): need an extension and an encoder", ext))
+	}
+	formats[ext] = enc
+}
+
+// SupportedFormats lists the registered extensions, sorted, each with its
+// leading dot.
+func SupportedFormats() []string {
+	return slices.Sorted(maps.Keys(formats))
+}
+
+// NormalizeExt returns ext lowercased with a leading dot, the form
+// SupportedFormats lists: "PNG", ".png" and "png" are all ".png".
+func NormalizeExt(ext string) string {
+	return "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// lookupFormat returns the encoder for ext, or an ErrUnsupportedFormat
+// error listing the supported extensions.
+func lookupFormat(ext string) (EncodeFunc, error) {
+	if enc, ok := formats[NormalizeExt(ext)]; ok {
+		return enc, nil
+	}
+	list := SupportedFormats()
+	last := len(list) - 1
+	return nil, fmt.Errorf("%w %q: use %s or %s", ErrUnsupportedFormat, ext, strings.Join(list[:last], ", "), list[last])
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestNormalizeExt checks that any case, with or without the dot, gives
+// the form SupportedFormats lists.
+func TestNormalizeExt(t *testing.T) {
+	for in, want := range map[string]string{
+		"png":   ".png",
+		".png":  ".png",
+		"PNG":   ".png",
+		".JPeG": ".jpeg",
+		"FAKE":  ".fake",
+		"":      ".",
+	} {
+		if got := NormalizeExt(in); got != want {
+			t.Errorf("NormalizeExt(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestRegisterFormat registers "FAKE" and writes .fake files through
+// Generate, whatever the case of the output's extension.
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("FAKE", func(w io.Writer, img image.Image, cfg Config) error {
+		_, err := fmt.Fprintf(w, "fake %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+		return err
+	})
+	t.Cleanup(func() { delete(formats, ".fake") })
+
+	if !slices.Contains(SupportedFormats(), ".fake") {
+		t.Errorf("SupportedFormats() = %v, want .fake listed", SupportedFormats())
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"out.fake", "OUT.FAKE", "Out.FaKe"} {
+		output := filepath.Join(dir, name)
+		done := 0
+		cfg := Config{Width: 32, Height: 16, Color: "#102030", Progress: func(n, total int) { done = n }}
+		if err := Generate(output, cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "fake 32x16" {
+			t.Errorf("%s holds %q, want \"fake 32x16\"", name, data)
+		}
+		if done != 1 {
+			t.Errorf("%s: progress not reported done", name)
+		}
+	}
+
+	delete(formats, ".fake")
+	err := Generate(filepath.Join(dir, "again.fake"), Config{Width: 32, Height: 16, Color: "#102030"})
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("after unregistering: error %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+// TestRegisterFormatPanics checks that an empty extension or a nil
+// encoder is refused and not registered.
+func TestRegisterFormatPanics(t *testing.T) {
+	enc := func(io.Writer, image.Image, Config) error { return nil }
+	for _, tt := range []struct {
+		ext string
+		enc EncodeFunc
+	}{{"", enc}, {".", enc}, {".fake", nil}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterFormat(%q, %v) did not panic", tt.ext, tt.enc != nil)
+				}
+			}()
+			RegisterFormat(tt.ext, tt.enc)
+		}()
+	}
+	if slices.Contains(SupportedFormats(), ".fake") || slices.Contains(SupportedFormats(), ".") {
+		t.Errorf("SupportedFormats() = %v after refused registrations", SupportedFormats())
+	}
+}
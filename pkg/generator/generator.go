@@ -1,66 +1,209 @@
-// Package generator provides PNG and AVI media generation.
+// Package generator provides image (PNG, JPEG, BMP), GIF and AVI media
+// generation.
 //
 // All output follows a unified pipeline: create an image.Image first,
-// then write it as PNG or containerize it as an MJPEG AVI.
+// then encode it as a still image or repeat it as the frames of an
+// animated GIF or MJPEG AVI. Programs add output formats with
+// RegisterFormat.
 package generator
 
 import (
+	"errors"
 	"fmt"
 	"image"
-	"image/png"
 	"io"
+	"math"
+	"math/rand/v2"
+	"os"
 	"path/filepath"
-	"strings"
+
+	"github.com/xob0t/GoStencil/internal/imageenc"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// DefaultJPEGQuality is the JPEG quality when Config.Quality is unset.
+const DefaultJPEGQuality = imageenc.DefaultJPEGQuality
+
+// DefaultMatte is the color Config.Matte defaults to. Compositing over
+// black leaves a premultiplied image's color values as they are.
+const DefaultMatte = "#000000"
+
+// Errors callers can match with errors.Is.
+var (
+	ErrUnsupportedFormat = imageenc.ErrUnsupportedFormat     // output extension not one of SupportedFormats
+	ErrInvalidColor      = errors.New("invalid color")       // Config.Color is not "#rrggbb"/"random"
+	ErrInvalidOddSize    = errors.New("invalid odd size")    // Config.OddSize is not "", OddSizePad or OddSizeCrop
+	ErrTooLong           = errors.New("too long")            // the duration needs more than MaxFrames frames
+	ErrOutputPath        = errors.New("cannot write output") // see CheckOutput; a missing directory also matches os.ErrNotExist
+)
+
+// MaxFrames bounds an animation's frame count: about 19 hours of AVI.
+const MaxFrames = 1 << 20
+
+// Config.OddSize values: how AVI output makes an odd width or height even.
+const (
+	OddSizePad  = "pad"  // add one pixel, repeating the last row or column
+	OddSizeCrop = "crop" // drop the last row or column
 )
 
 // Config holds parameters for media generation.
 type Config struct {
 	Width    int         // Pixel width (default: 1280)
 	Height   int         // Pixel height (default: 720)
-	Duration int         // Seconds, AVI only (default: 1)
+	Duration int         // Whole seconds, AVI and GIF only (default: 1)
+	FPS      int         // Frames per second, GIF only (default: DefaultGIFFPS)
+	Quality  int         // 1–100, JPEG only (default: DefaultJPEGQuality)
 	Color    string      // Hex "#rrggbb" or "random"
 	Image    image.Image // Pre-rendered image; overrides Width/Height/Color
+	OddSize  string      // OddSizePad or OddSizeCrop, AVI only (default: OddSizePad)
+	DPI      float64     // Pixel density recorded in the file, PNG only (default: none)
+
+	// DurationSeconds is the duration in seconds for fractional lengths
+	// such as 2.5; when > 0 it overrides Duration.
+	DurationSeconds float64
+
+	// Matte is the "#rrggbb" color translucent pixels are composited over
+	// for formats without transparency: JPEG, GIF and AVI (default:
+	// DefaultMatte). PNG and BMP keep the alpha channel.
+	Matte string
+
+	// Seed, when nonzero, makes random choices repeatable: Color "random"
+	// picks the same color for the same seed, and Tokens passes it on to a
+	// preset's {{_rand}} and {{_uuid}} placeholders. Zero draws fresh
+	// random values.
+	Seed uint64
+
+	// Captions are lines of text shown over parts of an AVI or GIF; see
+	// Caption. Still images ignore them.
+	Captions []Caption
+
+	// Segments mark where the parts of an AVI start, in a text stream
+	// tools can split it by; ReadSegments reads them back. Other formats
+	// ignore them.
+	Segments []Segment
+
+	// Progress, if set, is called as output is written: once per frame for
+	// AVI, once on completion for the other formats.
+	Progress func(done, total int)
+
+	// Warn, if set, receives notes about adjustments made to the output
+	// (an AVI padded or cropped to even dimensions). Otherwise they are
+	// logged at Warn.
+	Warn func(msg string)
 }
 
-// Generate creates an output file. The format is inferred from the file extension:
+// Generate creates an output file. The format is inferred from the file
+// extension, one of SupportedFormats; the built-in ones are:
 //   - ".png" → PNG image
+//   - ".jpg", ".jpeg" → JPEG image
+//   - ".bmp" → BMP image
+//   - ".webp" → lossless WebP image
+//   - ".gif" → animated GIF
 //   - ".avi" → MJPEG AVI video
 //
 // If cfg.Image is nil, a solid-color image is created from cfg.Color/Width/Height.
+// output is checked with CheckOutput before anything is encoded.
 func Generate(output string, cfg Config) error {
+	ext := filepath.Ext(output)
+	if err := CheckOutput(output); err != nil {
+		return err
+	}
 	img, err := resolveImage(cfg)
 	if err != nil {
 		return err
 	}
 
-	switch ext := strings.ToLower(filepath.Ext(output)); ext {
-	case ".png":
-		return writePNG(output, img)
-	case ".avi":
-		dur := max(cfg.Duration, 1)
-		return writeAVI(output, img, dur)
-	default:
-		return fmt.Errorf("unsupported format %q: use .png or .avi", ext)
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", output, err)
 	}
+	defer f.Close()
+	cfg.Image = img
+	if err := GenerateToWriter(f, ext, cfg); err != nil {
+		return err
+	}
+	return f.Sync()
 }
 
-// GenerateToWriter writes media to an io.Writer. The format is specified by ext (".png" or ".avi").
+// GenerateToWriter writes media to an io.Writer. The format is specified by
+// ext, with the same extensions as Generate.
 // This is useful for in-memory generation (e.g., WASM).
 func GenerateToWriter(w io.Writer, ext string, cfg Config) error {
+	enc, err := lookupFormat(ext)
+	if err != nil {
+		return err
+	}
 	img, err := resolveImage(cfg)
 	if err != nil {
 		return err
 	}
 
-	switch strings.ToLower(ext) {
-	case ".png":
-		return png.Encode(w, img)
-	case ".avi":
-		dur := max(cfg.Duration, 1)
-		return writeAVITo(w, img, dur)
-	default:
-		return fmt.Errorf("unsupported format %q: use .png or .avi", ext)
+	progress, reported := cfg.Progress, false
+	if progress != nil {
+		cfg.Progress = func(done, total int) {
+			reported = true
+			progress(done, total)
+		}
 	}
+	if err := enc(w, img, cfg); err != nil {
+		return err
+	}
+	if !reported {
+		cfg.reportDone()
+	}
+	return nil
+}
+
+// seconds is the AVI or GIF duration: DurationSeconds if set, else
+// Duration, at least 1.
+func (cfg Config) seconds() float64 {
+	if cfg.DurationSeconds > 0 {
+		return cfg.DurationSeconds
+	}
+	return float64(max(cfg.Duration, 1))
+}
+
+// FrameCount is the number of frames seconds last at fps: rounded to the
+// nearest frame, and at least one. It fails with ErrTooLong beyond
+// MaxFrames.
+func FrameCount(seconds float64, fps int) (int, error) {
+	n := math.Round(seconds * float64(fps))
+	if !(n <= MaxFrames) { // also catches NaN
+		return 0, fmt.Errorf("%w: %g s at %d fps is over %d frames", ErrTooLong, seconds, fps, MaxFrames)
+	}
+	return max(int(n), 1), nil
+}
+
+// Tokens returns the placeholder values for render seq of a run made
+// with cfg, for template.ExpandTokens.
+func (cfg Config) Tokens(seq int) template.Tokens {
+	return template.Tokens{Seq: seq, Seed: cfg.Seed}
+}
+
+// reportDone signals single-frame completion to cfg.Progress.
+func (cfg Config) reportDone() {
+	if cfg.Progress != nil {
+		cfg.Progress(1, 1)
+	}
+}
+
+// warn reports msg through cfg.Warn or the package logger.
+func (cfg Config) warn(msg string) {
+	if cfg.Warn != nil {
+		cfg.Warn(msg)
+		return
+	}
+	logger().Warn(msg)
+}
+
+// matted returns img composited over cfg.Matte, for a format that drops
+// the alpha channel. An opaque img is returned as it is.
+func (cfg Config) matted(img image.Image) (image.Image, error) {
+	matte, err := ParseMatte(cfg.Matte)
+	if err != nil {
+		return nil, fmt.Errorf("matte: %w", err)
+	}
+	return imageenc.Flatten(img, matte), nil
 }
 
 // resolveImage returns the source image from config, creating a solid-color
@@ -70,9 +213,18 @@ func resolveImage(cfg Config) (image.Image, error) {
 		return cfg.Image, nil
 	}
 
-	w := max(cfg.Width, 1280)
-	h := max(cfg.Height, 720)
+	w, h := cfg.Width, cfg.Height
+	if w <= 0 {
+		w = 1280
+	}
+	if h <= 0 {
+		h = 720
+	}
 
+	if cfg.Seed != 0 && (cfg.Color == "" || cfg.Color == "random") {
+		v := rand.New(rand.NewPCG(cfg.Seed, 0)).Uint32()
+		return NewSolidImage(w, h, toRGBA(uint8(v>>16), uint8(v>>8), uint8(v))), nil
+	}
 	r, g, b, err := ParseColor(cfg.Color)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,56 @@
+// gif.go — Animated GIF writer.
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// GIF frame rates: the default when Config.FPS is unset, and the highest
+// allowed, a 2/100 s frame delay.
+const (
+	DefaultGIFFPS = 10
+	MaxGIFFPS     = 50
+)
+
+// writeGIFTo writes runs of frames as a looping GIF at fps.
+// Each distinct image is quantized to the web-safe palette once. Only the
+// first frame of a run carries it; the rest are 1×1 updates that leave it
+// on screen, so file size does not grow with the duration.
+func writeGIFTo(w io.Writer, runs []frameRun, fps int, progress func(done, total int)) error {
+	b := runs[0].img.Bounds()
+	delay := max(100/fps, 2) // hundredths of a second; browsers clamp lower values
+	anim := &gif.GIF{}
+	quantized := make(map[string]*image.Paletted)
+	for _, run := range runs {
+		frame, ok := quantized[run.key]
+		if !ok {
+			frame = image.NewPaletted(b, palette.Plan9)
+			draw.FloydSteinberg.Draw(frame, b, run.img, b.Min)
+			quantized[run.key] = frame
+		}
+		hold := frame.SubImage(image.Rectangle{Min: b.Min, Max: b.Min.Add(image.Pt(1, 1))}).(*image.Paletted)
+		for i := range run.count {
+			if i == 0 {
+				anim.Image = append(anim.Image, frame)
+			} else {
+				anim.Image = append(anim.Image, hold)
+			}
+			anim.Delay = append(anim.Delay, delay)
+		}
+	}
+	frames := len(anim.Image)
+
+	logger().Debug("writing GIF", "width", b.Dx(), "height", b.Dy(), "frames", frames, "images", len(quantized), "fps", fps)
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return fmt.Errorf("encode GIF: %w", err)
+	}
+	if progress != nil {
+		progress(frames, frames)
+	}
+	return nil
+}
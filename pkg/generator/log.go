@@ -0,0 +1,23 @@
+// log.go — Logging seam for the generator package.
+package generator
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var pkgLogger atomic.Pointer[slog.Logger]
+
+// SetLogger routes the package's diagnostics (encoding details at Debug)
+// to l. A nil l restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	pkgLogger.Store(l)
+}
+
+// logger returns the configured logger or slog.Default().
+func logger() *slog.Logger {
+	if l := pkgLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// halfTransparent is a 64×32 image: its left half fully transparent, its
+// right half red at half opacity. The halves are flat, so lossy formats
+// reproduce their centers closely.
+func halfTransparent() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := range 32 {
+		for x := 32; x < 64; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{200, 40, 40, 128})
+		}
+	}
+	return img
+}
+
+// aviFrame decodes the first frame of an AVI.
+func aviFrame(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	file, err := parseRIFF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := parseRIFF(file[0].data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	movi := find(chunks, "LIST movi")
+	if movi == nil {
+		t.Fatal("no movi list")
+	}
+	for _, c := range movi.sub {
+		if c.id == "00dc" {
+			img, err := jpeg.Decode(bytes.NewReader(c.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return img
+		}
+	}
+	t.Fatal("no frames")
+	return nil
+}
+
+// TestMatte exports a half-transparent image to the formats without
+// alpha and checks that both halves come out composited over the matte,
+// and to PNG, which must keep the alpha. GIF is dithered, so colors are
+// compared as block averages.
+func TestMatte(t *testing.T) {
+	decode := map[string]func(*testing.T, []byte) image.Image{
+		".avi": aviFrame,
+		".jpg": func(t *testing.T, data []byte) image.Image {
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return img
+		},
+		".gif": func(t *testing.T, data []byte) image.Image {
+			g, err := gif.DecodeAll(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return g.Image[0]
+		},
+	}
+	// Within JPEG's error on flat areas and what remains of GIF's
+	// dithering once averaged.
+	tolerance := map[string]int{".avi": 4, ".jpg": 4, ".gif": 6}
+
+	for _, matte := range []string{"", "#ffffff", "#3366cc"} {
+		m, err := ParseMatte(matte)
+		if err != nil {
+			t.Fatal(err)
+		}
+		over := func(c uint8, mc uint8) uint8 { return uint8((int(c)*128 + int(mc)*127 + 127) / 255) }
+		want := [2]color.RGBA{
+			{m.R, m.G, m.B, 255},
+			{over(200, m.R), over(40, m.G), over(40, m.B), 255},
+		}
+		for _, ext := range []string{".avi", ".jpg", ".gif"} {
+			t.Run(ext+"_"+matte, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := GenerateToWriter(&buf, ext, Config{Image: halfTransparent(), Matte: matte}); err != nil {
+					t.Fatal(err)
+				}
+				img := decode[ext](t, buf.Bytes())
+				for i, x := range []int{8, 40} {
+					if got := average(img, image.Rect(x, 8, x+16, 24)); !near(got, want[i], tolerance[ext]) {
+						t.Errorf("pixels %d-%d average %v, want %v", x, x+16, got, want[i])
+					}
+				}
+			})
+		}
+	}
+
+	t.Run(".png", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := GenerateToWriter(&buf, ".png", Config{Image: halfTransparent(), Matte: "#ffffff"}); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, want := range map[int]color.NRGBA{16: {}, 48: {200, 40, 40, 128}} {
+			if got := color.NRGBAModel.Convert(img.At(x, 16)); got != want {
+				t.Errorf("pixel (%d, 16) is %v, want %v unchanged", x, got, want)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, ext := range []string{".avi", ".jpg", ".gif"} {
+			err := GenerateToWriter(&bytes.Buffer{}, ext, Config{Image: halfTransparent(), Matte: "random"})
+			if !errors.Is(err, ErrInvalidColor) {
+				t.Errorf("%s: error %v, want ErrInvalidColor", ext, err)
+			}
+		}
+	})
+}
+
+// average is the mean color of img over rect.
+func average(img image.Image, rect image.Rectangle) color.RGBA {
+	var sum [4]uint32
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			sum[0], sum[1], sum[2], sum[3] = sum[0]+r>>8, sum[1]+g>>8, sum[2]+b>>8, sum[3]+a>>8
+		}
+	}
+	n := uint32(rect.Dx() * rect.Dy())
+	return color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), uint8(sum[3] / n)}
+}
+
+// near reports whether every channel of a and b is within tol.
+func near(a, b color.RGBA, tol int) bool {
+	d := func(x, y uint8) bool { return max(int(x)-int(y), int(y)-int(x)) <= tol }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
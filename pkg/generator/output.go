@@ -0,0 +1,67 @@
+// output.go — Checking an output path before the work of rendering to it,
+// so a missing or read-only directory is reported up front rather than
+// by the final os.Create.
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CheckOutput reports whether Generate can write output: its extension is
+// one of SupportedFormats, it is not a directory or a read-only file, and
+// CheckOutputDir accepts its directory. Errors name the absolute path.
+func CheckOutput(output string) error {
+	if _, err := lookupFormat(filepath.Ext(output)); err != nil {
+		return err
+	}
+	abs := absPath(output)
+	switch fi, err := os.Stat(abs); {
+	case err == nil && fi.IsDir():
+		return fmt.Errorf("%w: %s is a directory", ErrOutputPath, abs)
+	case err == nil:
+		// Opened without truncating: an existing file is left as it is.
+		f, err := os.OpenFile(abs, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not writable", ErrOutputPath, abs)
+		}
+		f.Close()
+	}
+	return CheckOutputDir(filepath.Dir(abs))
+}
+
+// CheckOutputDir reports whether files can be created in dir: it exists,
+// is a directory and is writable. Errors name the absolute path.
+func CheckOutputDir(dir string) error {
+	abs := absPath(dir)
+	fi, err := os.Stat(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("%w: directory %s: %w", ErrOutputPath, abs, os.ErrNotExist)
+	case err != nil:
+		return fmt.Errorf("%w: %w", ErrOutputPath, err)
+	case !fi.IsDir():
+		return fmt.Errorf("%w: %s is not a directory", ErrOutputPath, abs)
+	}
+
+	// Permission bits don't tell for ACLs, read-only mounts or root;
+	// creating a file does.
+	f, err := os.CreateTemp(abs, ".gostencil-*")
+	if err != nil {
+		return fmt.Errorf("%w: directory %s is not writable", ErrOutputPath, abs)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// absPath is path made absolute, or path itself if the working directory
+// is unknown.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
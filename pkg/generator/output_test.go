@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckOutput checks the paths CheckOutput and CheckOutputDir refuse,
+// that errors name the absolute path, and that an accepted path's
+// directory is left as it was.
+func TestCheckOutput(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.png")
+	if err := os.WriteFile(file, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.png"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		msg    string // "" for success
+	}{
+		{"new file", filepath.Join(dir, "out.png"), nil, ""},
+		{"existing file", file, nil, ""},
+		{"missing directory", filepath.Join(dir, "missing", "out.png"), os.ErrNotExist, "directory " + filepath.Join(dir, "missing")},
+		{"output is a directory", filepath.Join(dir, "sub.png"), ErrOutputPath, filepath.Join(dir, "sub.png") + " is a directory"},
+		{"parent is a file", filepath.Join(file, "out.png"), ErrOutputPath, file + " is not a directory"},
+		{"unknown extension", filepath.Join(dir, "out.tiff"), ErrUnsupportedFormat, `".tiff"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckOutput(tt.output)
+			if tt.msg == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("error %v, want %v mentioning %q", err, tt.err, tt.msg)
+			}
+		})
+	}
+
+	if data, _ := os.ReadFile(file); string(data) != "keep" {
+		t.Errorf("existing file now holds %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory holds %d entries after the checks, want the 2 it started with", len(entries))
+	}
+
+	t.Run("relative path", func(t *testing.T) {
+		t.Chdir(dir)
+		err := CheckOutputDir("missing")
+		if !errors.Is(err, ErrOutputPath) || !strings.Contains(err.Error(), filepath.Join(dir, "missing")) {
+			t.Errorf("error %v, want one naming %s", err, filepath.Join(dir, "missing"))
+		}
+	})
+}
+
+// TestCheckOutputReadOnly checks that a read-only directory and a
+// read-only file are refused. Permissions do not bind root, so the test
+// skips where it can write to them anyway.
+func TestCheckOutputReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ro")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file.png")
+	if err := os.WriteFile(file, nil, 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+	if f, err := os.Create(filepath.Join(dir, "probe")); err == nil {
+		f.Close()
+		t.Skip("permissions are not enforced for this user")
+	}
+
+	if err := CheckOutputDir(dir); !errors.Is(err, ErrOutputPath) || !strings.Contains(err.Error(), dir+" is not writable") {
+		t.Errorf("CheckOutputDir: error %v, want %s not writable", err, dir)
+	}
+	if err := CheckOutput(filepath.Join(dir, "out.png")); !errors.Is(err, ErrOutputPath) {
+		t.Errorf("CheckOutput in a read-only directory: error %v, want ErrOutputPath", err)
+	}
+	if err := CheckOutput(file); !errors.Is(err, ErrOutputPath) || !strings.Contains(err.Error(), file+" is not writable") {
+		t.Errorf("CheckOutput of a read-only file: error %v, want %s not writable", err, file)
+	}
+}
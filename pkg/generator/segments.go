@@ -0,0 +1,198 @@
+// segments.go — Segment markers in AVI output (Config.Segments), for tools
+// that split a video into its parts, and ReadSegments to get them back.
+//
+// The markers are a text stream ("txts", named segmentStreamName) next to
+// the video. It has one "01tx" chunk per segment, stored just before the
+// video frame the segment starts at. AVI text samples have no timestamps
+// of their own, so each chunk's data is the frame number in decimal, a
+// tab, and the label in UTF-8.
+package generator
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSegment is returned for a Segment without a label or outside
+// the video, and by ReadSegments for a segment chunk it cannot parse.
+var ErrInvalidSegment = errors.New("invalid segment")
+
+// segmentStreamName names the text stream that holds the segments, in its
+// strn chunk, so ReadSegments can tell it from other text streams.
+const segmentStreamName = "GoStencil segments"
+
+// maxSegmentChunk bounds the segment chunks ReadSegments reads.
+const maxSegmentChunk = 64 << 10
+
+// Segment marks where a part of an AVI starts.
+type Segment struct {
+	Start float64 // seconds from the start of the video
+	Label string
+}
+
+// aviSegment is a Segment placed on a frame.
+type aviSegment struct {
+	frame int
+	data  []byte // the chunk's content
+}
+
+// aviSegments places segs on the frames of a video of frames frames at
+// fps: each starts at the first frame shown at or after its Start, as a
+// Caption does. They are returned in frame order.
+func aviSegments(segs []Segment, frames, fps int) ([]aviSegment, error) {
+	out := make([]aviSegment, 0, len(segs))
+	for _, s := range segs {
+		if strings.TrimSpace(s.Label) == "" {
+			return nil, fmt.Errorf("%w at %gs: the label is empty", ErrInvalidSegment, s.Start)
+		}
+		if !(s.Start >= 0) {
+			return nil, fmt.Errorf("%w %q: start %g must not be negative", ErrInvalidSegment, s.Label, s.Start)
+		}
+		frame := int(math.Ceil(s.Start * float64(fps)))
+		if frame > 0 && float64(frame-1)/float64(fps) >= s.Start {
+			frame--
+		}
+		if frame >= frames {
+			return nil, fmt.Errorf("%w %q: starts at %gs, after the last frame (%gs)", ErrInvalidSegment, s.Label, s.Start, float64(frames-1)/float64(fps))
+		}
+		out = append(out, aviSegment{frame, []byte(strconv.Itoa(frame) + "\t" + s.Label)})
+	}
+	slices.SortStableFunc(out, func(a, b aviSegment) int { return a.frame - b.frame })
+	return out, nil
+}
+
+// ReadSegments returns the segments Config.Segments stored in an AVI, in
+// the order they were written: by start time. Each Start is the time of
+// the segment's first frame. An AVI without segments has none. Only the
+// headers and segment chunks are read, not the video frames.
+func ReadSegments(r io.ReaderAt) ([]Segment, error) {
+	var head [12]byte
+	if _, err := r.ReadAt(head[:], 0); err != nil {
+		return nil, fmt.Errorf("read AVI header: %w", err)
+	}
+	if string(head[:4]) != "RIFF" || string(head[8:]) != "AVI " {
+		return nil, errors.New("not an AVI file")
+	}
+	end := int64(math.MaxInt64)
+	if n := int64(binary.LittleEndian.Uint32(head[4:8])); n > 0 {
+		end = 8 + n
+	}
+	sr := &segmentReader{r: r}
+	if err := sr.walk(12, end, ""); err != nil {
+		return nil, err
+	}
+	return sr.segments, nil
+}
+
+// segmentReader walks an AVI's chunks for ReadSegments.
+type segmentReader struct {
+	r       io.ReaderAt
+	streams int // strl lists seen
+
+	// The strl list being read.
+	kind, name  string
+	scale, rate uint32
+
+	chunkID  string // "NNtx" of the segment stream, once its strl is read
+	segScale uint32
+	segRate  uint32
+	segments []Segment
+}
+
+// walk reads the chunks from off to end inside a list of type list ("" at
+// the top level), descending into LIST chunks. A truncated file ends the
+// walk without an error.
+func (sr *segmentReader) walk(off, end int64, list string) error {
+	var hdr [8]byte
+	for off+8 <= end {
+		if _, err := sr.r.ReadAt(hdr[:], off); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read AVI: %w", err)
+		}
+		id := string(hdr[:4])
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		body := off + 8
+
+		switch {
+		case id == "LIST" && size >= 4:
+			kind, err := sr.read(body, 4)
+			if err != nil {
+				return err
+			}
+			if string(kind) == "strl" {
+				sr.kind, sr.name = "", ""
+			}
+			if err := sr.walk(body+4, min(body+size, end), string(kind)); err != nil {
+				return err
+			}
+			if string(kind) == "strl" {
+				if sr.kind == "txts" && sr.name == segmentStreamName && sr.chunkID == "" {
+					sr.chunkID = fmt.Sprintf("%02dtx", sr.streams)
+					sr.segScale, sr.segRate = sr.scale, sr.rate
+				}
+				sr.streams++
+			}
+		case list == "strl" && id == "strh" && size >= 28:
+			strh, err := sr.read(body, 28)
+			if err != nil {
+				return err
+			}
+			sr.kind = string(strh[:4])
+			sr.scale = binary.LittleEndian.Uint32(strh[20:24])
+			sr.rate = binary.LittleEndian.Uint32(strh[24:28])
+		case list == "strl" && id == "strn" && size <= 256:
+			name, err := sr.read(body, int(size))
+			if err != nil {
+				return err
+			}
+			sr.name = strings.TrimRight(string(name), "\x00")
+		case (list == "movi" || list == "rec ") && id == sr.chunkID && sr.chunkID != "":
+			if size > maxSegmentChunk {
+				return fmt.Errorf("%w: a %d-byte segment chunk", ErrInvalidSegment, size)
+			}
+			data, err := sr.read(body, int(size))
+			if err != nil {
+				return err
+			}
+			if err := sr.add(data); err != nil {
+				return err
+			}
+		}
+
+		// Chunks are padded to an even length.
+		off = body + size + size&1
+	}
+	return nil
+}
+
+// add parses a segment chunk's data.
+func (sr *segmentReader) add(data []byte) error {
+	frame, label, ok := strings.Cut(string(data), "\t")
+	n, err := strconv.Atoi(frame)
+	if !ok || err != nil || n < 0 {
+		return fmt.Errorf("%w: chunk %q is not FRAME<tab>LABEL", ErrInvalidSegment, data)
+	}
+	start := float64(n)
+	if sr.segScale > 0 && sr.segRate > 0 {
+		start = start * float64(sr.segScale) / float64(sr.segRate)
+	}
+	sr.segments = append(sr.segments, Segment{Start: start, Label: label})
+	return nil
+}
+
+// read returns n bytes at off.
+func (sr *segmentReader) read(off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := sr.r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("read AVI: %w", err)
+	}
+	return buf, nil
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestSegmentsRoundTrip writes segments whose labels hold commas, quotes,
+// tabs and non-ASCII text, given out of order, and reads them back with
+// ReadSegments: in start order, each at its first frame's time.
+func TestSegmentsRoundTrip(t *testing.T) {
+	segs := []Segment{
+		{Start: 2, Label: `Outro, "thanks"`},
+		{Start: 0, Label: "Intro, part 1"},
+		{Start: 0.5, Label: `She said "hi", then 'bye'`},
+		{Start: 1.01, Label: "tab\tinside, \"quoted\"\ttoo"},
+		{Start: 1.5, Label: "Ünïcödé — «quotes», 引用"},
+	}
+	want := []Segment{
+		{Start: 0, Label: "Intro, part 1"},
+		{Start: 8.0 / aviFPS, Label: `She said "hi", then 'bye'`}, // the first frame at or after 0.5 s
+		{Start: 16.0 / aviFPS, Label: "tab\tinside, \"quoted\"\ttoo"},
+		{Start: 23.0 / aviFPS, Label: "Ünïcödé — «quotes», 引用"},
+		{Start: 2, Label: `Outro, "thanks"`},
+	}
+
+	var buf bytes.Buffer
+	cfg := Config{Width: 32, Height: 16, Color: "#102030", DurationSeconds: 3, Segments: segs}
+	if err := GenerateToWriter(&buf, ".avi", cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSegments(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(got, want, func(a, b Segment) bool {
+		return a.Label == b.Label && a.Start > b.Start-1e-9 && a.Start < b.Start+1e-9
+	}) {
+		t.Errorf("ReadSegments = %+v\nwant %+v", got, want)
+	}
+
+	buf.Reset()
+	cfg.Segments = nil
+	if err := GenerateToWriter(&buf, ".avi", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadSegments(bytes.NewReader(buf.Bytes())); err != nil || len(got) != 0 {
+		t.Errorf("without segments: ReadSegments = %v, %v; want none", got, err)
+	}
+}
+
+// TestSegmentsInvalid checks that segments without a label or outside the
+// video are refused.
+func TestSegmentsInvalid(t *testing.T) {
+	for _, s := range []Segment{
+		{Start: 0, Label: ""},
+		{Start: 0, Label: " \t"},
+		{Start: -1, Label: "before"},
+		{Start: 3, Label: "after"},
+	} {
+		cfg := Config{Width: 32, Height: 16, Color: "#102030", DurationSeconds: 3, Segments: []Segment{s}}
+		var buf bytes.Buffer
+		if err := GenerateToWriter(&buf, ".avi", cfg); !errors.Is(err, ErrInvalidSegment) {
+			t.Errorf("segment %q at %g: error %v, want ErrInvalidSegment", s.Label, s.Start, err)
+		}
+	}
+}
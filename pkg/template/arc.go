@@ -0,0 +1,145 @@
+// arc.go — Text set along a circle (style.arc), for badges and seals.
+package template
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// arcMinSpacing is how far, as a fraction of the font size, the space
+// between letters may shrink to fit a line into its arc before the line is
+// reported as too long.
+const arcMinSpacing = 0.1
+
+// arcText is one line of arc text.
+type arcText struct {
+	text  string
+	title bool
+}
+
+// arcTexts lists comp's arc lines: the title, then each item with its
+// bullet or number, unwrapped.
+func arcTexts(comp ResolvedComponent) []arcText {
+	var texts []arcText
+	if comp.Data.Title != "" {
+		texts = append(texts, arcText{comp.Data.Title, true})
+	}
+	num := 1
+	for _, item := range comp.Data.Items {
+		text := item.Text
+		switch item.Type {
+		case "bullet":
+			text = "• " + text
+		case "numbered":
+			text = fmt.Sprintf("%d. %s", num, text)
+			num++
+		}
+		texts = append(texts, arcText{text, false})
+	}
+	return texts
+}
+
+// drawArcText draws comp's text along concentric circles centered on its
+// box, each glyph rotated to the circle's tangent. A line longer than its
+// arc has its letters drawn closer together, down to arcMinSpacing, and
+// then is drawn overlong with a warning.
+func (r *Renderer) drawArcText(img *image.RGBA, comp ResolvedComponent) error {
+	texts := arcTexts(comp)
+	if len(texts) == 0 {
+		return nil
+	}
+	fm, err := r.componentFont(comp)
+	if err != nil {
+		return err
+	}
+
+	arc := comp.Style.Arc
+	dir := 1.0 // +1 clockwise, -1 counterclockwise
+	if arc.Direction == "counterclockwise" {
+		dir = -1
+	}
+	sweep := arc.Sweep
+	if sweep <= 0 || sweep > 360 {
+		sweep = 360
+	}
+	start := arc.StartAngle * math.Pi / 180
+	cx := float64(comp.X) + float64(comp.Width)/2
+	cy := float64(comp.Y) + float64(comp.Height)/2
+
+	radius := arc.Radius
+	offset := 0.0 // from the first line's baseline to this one's, away from the letters' tops
+	for i, t := range texts {
+		size, hex, getFace := comp.Style.FontSize, comp.Style.Color, fm.GetFace
+		if t.title {
+			size, hex, getFace = comp.Style.titleSize(), comp.Style.titleColor(), fm.GetBoldFace
+		}
+		face, err := getFace(size, r.dpi)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 && radius <= 0 {
+			// Fit the first line inside the content box: clockwise letters
+			// stand outside their baseline, counterclockwise ones inside.
+			m := face.Metrics()
+			extent := m.Ascent
+			if dir < 0 {
+				extent = m.Descent
+			}
+			radius = float64(min(comp.Width, comp.Height)-2*comp.Padding)/2 - fix2f(extent)
+		}
+		if i > 0 {
+			offset += r.lineHeight(&comp.Style, size)
+			if texts[i-1].title {
+				offset += r.px(comp.Style.titleSpacing())
+			} else {
+				offset += r.px(comp.Style.itemSpacing())
+			}
+		}
+		lineR := radius - dir*offset
+		if lineR <= 0 {
+			r.warn(comp.ID, "arc line %q is inside the circle's center, not drawn", t.text)
+			continue
+		}
+
+		run := newGlyphRun(face, t.text)
+		avail := lineR * sweep * math.Pi / 180
+		spacing := 0.0
+		if n := len(run.runes); run.width > avail && n > 1 {
+			spacing = max((avail-run.width)/float64(n-1), -arcMinSpacing*r.px(size))
+		}
+		length := run.widthWith(spacing)
+		if length > avail+0.5 {
+			r.warn(comp.ID, "arc line %q is %.0f px longer than its %g° arc at the tightest letter spacing", t.text, length-avail, sweep)
+		}
+
+		var s0 float64 // arc length from StartAngle to the line's start
+		switch comp.Style.TextAlign {
+		case "center":
+			s0 = -length / 2
+		case "right":
+			s0 = -length
+		}
+
+		c := parseHexColorAlpha(hex)
+		for g, ch := range run.runes {
+			mid := run.adv[g] / 2
+			theta := start + dir*(s0+run.at(g, spacing)+mid)/lineR
+			phi := theta
+			if dir < 0 {
+				phi += math.Pi // letters upright toward the center
+			}
+			sin, cos := math.Sincos(phi)
+			px := cx + lineR*math.Sin(theta)
+			py := cy - lineR*math.Cos(theta)
+			drawGlyph(img, face, ch, c, f64.Aff3{
+				cos, -sin, px - cos*mid,
+				sin, cos, py - sin*mid,
+			})
+		}
+	}
+	return nil
+}
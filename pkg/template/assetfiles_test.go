@@ -0,0 +1,145 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// TestNoAssetFiles renders presets whose font and image references name
+// files outside the working directory, by absolute path and by "../"
+// traversal: read with files allowed, missing with NoAssetFiles.
+func TestNoAssetFiles(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	pngPath := filepath.Join(dir, "bg.png")
+	ttfPath := filepath.Join(dir, "font.ttf")
+	if err := os.WriteFile(pngPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ttfPath, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const component = `{"id": "c", "x": 0.1, "y": 0.1, "width": 0.8, "height": 0.8, "style": %s, "defaults": {"visible": true, "title": "Hi"}}`
+	tests := []struct {
+		field  string // as Lint reports it
+		kind   string // AssetFont or AssetImage
+		file   string
+		preset string // with %s for the quoted reference
+	}{
+		{"font.path", AssetFont, ttfPath, `{"font": {"path": %s}, "components": [` + strings.Replace(component, "%s", `{}`, 1) + `]}`},
+		{"background.source", AssetImage, pngPath, `{"background": {"type": "image", "source": %s}, "font": {}, "components": []}`},
+		{"style.backgroundImage", AssetImage, pngPath, `{"font": {}, "components": [` + strings.Replace(component, "%s", `{"backgroundImage": %s}`, 1) + `]}`},
+		{"style.maskImage", AssetImage, pngPath, `{"font": {}, "components": [` + strings.Replace(component, "%s", `{"backgroundColor": "#ff0000", "maskImage": %s}`, 1) + `]}`},
+		{"style.fontPath", AssetFont, ttfPath, `{"font": {}, "components": [` + strings.Replace(component, "%s", `{"fontPath": %s}`, 1) + `]}`},
+	}
+	for _, tt := range tests {
+		traversal, err := filepath.Rel(wd, tt.file)
+		if err != nil || !strings.HasPrefix(traversal, "..") {
+			t.Fatalf("no ../ path from %s to %s", wd, tt.file)
+		}
+		for _, ref := range []string{tt.file, traversal} {
+			t.Run(tt.field+"/"+ref, func(t *testing.T) {
+				quoted, _ := json.Marshal(ref)
+				preset, err := DecodePreset([]byte(strings.Replace(tt.preset, "%s", string(quoted), 1)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				r, err := NewRendererWithOptions(RendererOptions{Font: preset.Font})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := r.RenderPreset(preset, MergeData(preset, nil)); err != nil {
+					t.Fatal(err)
+				}
+				if !usedFrom(r.AssetsUsed(), tt.kind, ref, AssetFromFile) {
+					t.Errorf("with files: assets used %+v, want %s read from the file", r.AssetsUsed(), ref)
+				}
+				if issues := LintWithOptions(preset, nil, LintOptions{}); hasIssue(issues, tt.field) {
+					t.Errorf("with files: lint reports %s: %v", tt.field, issues)
+				}
+
+				r, err = NewRendererWithOptions(RendererOptions{Font: preset.Font, NoAssetFiles: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.SetStrictAssets(true)
+				if _, err := r.RenderPreset(preset, MergeData(preset, nil)); !errors.Is(err, ErrMissingAsset) {
+					t.Errorf("NoAssetFiles: render error %v, want ErrMissingAsset", err)
+				}
+				if issues := LintWithOptions(preset, nil, LintOptions{NoAssetFiles: true}); !hasIssue(issues, tt.field) {
+					t.Errorf("NoAssetFiles: lint issues %v, want one for %s", issues, tt.field)
+				}
+			})
+		}
+	}
+}
+
+// usedFrom reports whether used lists the kind asset ref as read from
+// source.
+func usedFrom(used []AssetUse, kind, ref, source string) bool {
+	return slices.ContainsFunc(used, func(u AssetUse) bool {
+		return u.Kind == kind && u.Ref == ref && u.Source == source
+	})
+}
+
+// hasIssue reports whether issues has a warning for field.
+func hasIssue(issues []Issue, field string) bool {
+	return slices.ContainsFunc(issues, func(i Issue) bool {
+		return i.Severity == SeverityWarning && i.Field == field
+	})
+}
+
+// TestContainedPath checks that ${file:...} references cannot leave the
+// data file's directory, by path or through a symlink.
+func TestContainedPath(t *testing.T) {
+	base, outside := t.TempDir(), t.TempDir()
+	for _, f := range []string{filepath.Join(base, "in.txt"), filepath.Join(outside, "secret.txt")} {
+		if err := os.WriteFile(f, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(base, "in.txt"), filepath.Join(base, "alias.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rel := range []string{"in.txt", "alias.txt", "./in.txt"} {
+		if _, err := containedPath(base, rel); err != nil {
+			t.Errorf("containedPath(%q): %v", rel, err)
+		}
+	}
+	for _, rel := range []string{
+		"../" + filepath.Base(outside) + "/secret.txt",
+		filepath.Join(outside, "secret.txt"),
+		"link/secret.txt",
+	} {
+		if path, err := containedPath(base, rel); err == nil {
+			t.Errorf("containedPath(%q) = %s, want an error", rel, path)
+		}
+	}
+}
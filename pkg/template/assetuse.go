@@ -0,0 +1,84 @@
+// assetuse.go — The assets a render read: fonts, the embedded default
+// included, and background, component and mask images, with where each
+// came from. Renderer.AssetsUsed reports them after a render.
+//
+// ReferencedAssets lists every asset a preset can use, whatever the data;
+// a render uses the part of it its data selects (variants, responsive
+// overrides, data styles) and draws text with.
+package template
+
+import (
+	"cmp"
+	"slices"
+)
+
+// AssetUse kinds.
+const (
+	AssetFont  = "font"
+	AssetImage = "image"
+)
+
+// AssetUse sources: where an asset was read from.
+const (
+	AssetFromResolver = "resolver" // the renderer's asset resolver
+	AssetFromFile     = "file"     // the filesystem
+	AssetEmbedded     = "embedded" // the embedded default font
+	AssetMissing      = "missing"  // not found or not decodable; a fallback was drawn
+)
+
+// AssetUse is an asset a render read, or tried to.
+type AssetUse struct {
+	Kind       string   `json:"kind"`                 // AssetFont or AssetImage
+	Ref        string   `json:"ref"`                  // as the renderer was given it; "" for the embedded default font
+	Source     string   `json:"source"`               // AssetFromResolver, AssetFromFile, AssetEmbedded or AssetMissing
+	Background bool     `json:"background,omitempty"` // drawn as the preset background
+	Components []string `json:"components,omitempty"` // IDs of the components that drew with it, sorted
+}
+
+// AssetsUsed returns the assets the most recent render read, sorted by
+// kind, then reference. A font is listed when text is drawn with it, and
+// a missing font with the font drawn in its place.
+func (r *Renderer) AssetsUsed() []AssetUse {
+	out := make([]AssetUse, len(r.used))
+	for i, u := range r.used {
+		u.Components = slices.Sorted(slices.Values(u.Components))
+		out[i] = u
+	}
+	slices.SortFunc(out, func(a, b AssetUse) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Ref, b.Ref), cmp.Compare(a.Source, b.Source))
+	})
+	return out
+}
+
+// useAsset records that component ("" for the preset background) read
+// the asset ref of kind from source.
+func (r *Renderer) useAsset(kind, ref, source, component string) {
+	i := slices.IndexFunc(r.used, func(u AssetUse) bool {
+		return u.Kind == kind && u.Ref == ref && u.Source == source
+	})
+	if i < 0 {
+		r.used = append(r.used, AssetUse{Kind: kind, Ref: ref, Source: source})
+		i = len(r.used) - 1
+	}
+	u := &r.used[i]
+	switch {
+	case component == "":
+		u.Background = true
+	case !slices.Contains(u.Components, component):
+		u.Components = append(u.Components, component)
+	}
+}
+
+// useFont records that component drew text with fm, and with the font fm
+// stands in for when that one is missing. A family given as bytes is not
+// an asset.
+func (r *Renderer) useFont(component string, fm *FontManager) {
+	switch fm.from {
+	case "":
+	case AssetMissing:
+		r.useAsset(AssetFont, fm.ref, AssetMissing, component)
+		r.useAsset(AssetFont, "", AssetEmbedded, component)
+	default:
+		r.useAsset(AssetFont, fm.ref, fm.from, component)
+	}
+}
@@ -0,0 +1,332 @@
+// autocolor.go — "auto" and "auto-contrast" text and border colors, picked
+// at render time from what is drawn under the component.
+//
+// The area under the box is reduced to a palette of at most paletteSize
+// colors: the pixels are averaged over a grid of at most
+// paletteGrid×paletteGrid cells, and median cut splits the cells into
+// groups of similar color, each weighted by the area it covers. A palette
+// color covering at least paletteShare of the area counts as background.
+//
+//   - "auto-contrast" is white or black, whichever contrasts more with
+//     the background colors (WCAG 2 contrast ratio).
+//   - "auto" keeps the hue and saturation of the most common background
+//     color that has a hue, and moves its lightness toward that white or
+//     black until it contrasts enough: textContrast for text,
+//     borderContrast for borders. An area with no hue gets the
+//     auto-contrast color.
+//
+// A color that falls short of its contrast is still used, with a warning.
+// The same pixels always give the same color.
+package template
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Automatic color values for ComponentStyle.Color and BorderColor.
+const (
+	ColorAuto         = "auto"
+	ColorAutoContrast = "auto-contrast"
+)
+
+const (
+	paletteGrid  = 32   // cells per side the area is averaged over, at most
+	paletteSize  = 5    // colors median cut splits the area into, at most
+	paletteShare = 0.1  // share of the area a background color covers, at least
+	minHueSat    = 0.15 // HSL saturation of a color "auto" takes its hue from
+
+	// WCAG 2 contrast ratios "auto" aims for: level AA for text and for
+	// graphical objects.
+	textContrast   = 4.5
+	borderContrast = 3.0
+)
+
+// isAutoColor reports whether c is "auto" or "auto-contrast", in any case.
+func isAutoColor(c string) bool {
+	return strings.EqualFold(c, ColorAuto) || strings.EqualFold(c, ColorAutoContrast)
+}
+
+// resolveAutoColors returns comp with its automatic colors replaced by hex
+// colors picked from what is drawn under its box: layers composited in
+// order, nil ones skipped.
+func (r *Renderer) resolveAutoColors(comp ResolvedComponent, box componentBox, layers ...image.Image) ResolvedComponent {
+	s := &comp.Style
+	if !isAutoColor(s.Color) && !isAutoColor(s.BorderColor) {
+		return comp
+	}
+	under := image.NewRGBA(box.bounds)
+	for _, l := range layers {
+		if l != nil {
+			draw.Draw(under, box.bounds, l, box.bounds.Min, draw.Over)
+		}
+	}
+	palette := extractPalette(under)
+	if isAutoColor(s.Color) {
+		s.Color = r.autoColor(comp.ID, "color", s.Color, palette, textContrast)
+	}
+	if isAutoColor(s.BorderColor) && box.borderWidth > 0 {
+		s.BorderColor = r.autoColor(comp.ID, "borderColor", s.BorderColor, palette, borderContrast)
+	}
+	return comp
+}
+
+// autoColor picks the color for the automatic value auto of field from
+// palette, aiming for contrast ratio target, and warns when it cannot.
+func (r *Renderer) autoColor(id, field, auto string, palette []paletteColor, target float64) string {
+	if len(palette) == 0 {
+		r.warn(id, "%s %q: nothing is drawn under the component to pick from, using white", field, auto)
+		return "#ffffff"
+	}
+	bg := backgroundColors(palette)
+	extreme, ratio := color.RGBA{0xff, 0xff, 0xff, 0xff}, minContrast(color.RGBA{0xff, 0xff, 0xff, 0xff}, bg)
+	if black := minContrast(color.RGBA{A: 0xff}, bg); black > ratio {
+		extreme, ratio = color.RGBA{A: 0xff}, black
+	}
+	c := extreme
+	if strings.EqualFold(auto, ColorAuto) {
+		c, ratio = tintToward(bg, extreme, target)
+	}
+	if ratio < target {
+		r.warn(id, "%s %q: %s has a contrast of only %.1f:1 with the background (%.1f:1 wanted)", field, auto, hexColor(c), ratio, target)
+	}
+	return hexColor(c)
+}
+
+// tintToward returns the color "auto" picks against the background colors
+// bg, with its contrast ratio: the hue of the first of bg with one, at the
+// lightness nearest its own, toward extreme (white or black), that reaches
+// target. Without a hue it is extreme.
+func tintToward(bg []paletteColor, extreme color.RGBA, target float64) (color.RGBA, float64) {
+	i := slices.IndexFunc(bg, func(p paletteColor) bool {
+		_, s, _ := rgbToHSL(p.rgba())
+		return s >= minHueSat
+	})
+	if i < 0 {
+		return extreme, minContrast(extreme, bg)
+	}
+	h, s, l := rgbToHSL(bg[i].rgba())
+	step := 0.01
+	if extreme.R == 0 {
+		step = -step
+	}
+	for ; l >= 0 && l <= 1; l += step {
+		c := hslToRGB(h, s, l)
+		if ratio := minContrast(c, bg); ratio >= target {
+			return c, ratio
+		}
+	}
+	return extreme, minContrast(extreme, bg)
+}
+
+// paletteColor is one color of an area's palette, with the share of the
+// area it covers.
+type paletteColor struct {
+	c      [3]float64 // straight RGB, 0–255
+	weight float64
+}
+
+func (p paletteColor) rgba() color.RGBA {
+	ch := func(v float64) uint8 { return uint8(math.Round(min(max(v, 0), 255))) }
+	return color.RGBA{ch(p.c[0]), ch(p.c[1]), ch(p.c[2]), 0xff}
+}
+
+// extractPalette reduces img to at most paletteSize colors, heaviest first,
+// with weights summing to 1. Transparent pixels count for nothing; a fully
+// transparent img has no palette.
+func extractPalette(img *image.RGBA) []paletteColor {
+	b := img.Rect
+	gw, gh := min(b.Dx(), paletteGrid), min(b.Dy(), paletteGrid)
+	var cells []paletteColor
+	for gy := range gh {
+		y0, y1 := b.Min.Y+gy*b.Dy()/gh, b.Min.Y+(gy+1)*b.Dy()/gh
+		for gx := range gw {
+			x0, x1 := b.Min.X+gx*b.Dx()/gw, b.Min.X+(gx+1)*b.Dx()/gw
+			var sum [4]float64
+			for y := y0; y < y1; y++ {
+				row := img.Pix[img.PixOffset(x0, y):img.PixOffset(x1, y)]
+				for i := 0; i < len(row); i += 4 {
+					for k := range sum {
+						sum[k] += float64(row[i+k])
+					}
+				}
+			}
+			if sum[3] == 0 {
+				continue
+			}
+			// Premultiplied sums over the alpha sum: the cell's straight color.
+			cells = append(cells, paletteColor{
+				c:      [3]float64{sum[0] * 255 / sum[3], sum[1] * 255 / sum[3], sum[2] * 255 / sum[3]},
+				weight: sum[3] / 255,
+			})
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	palette := medianCut(cells, paletteSize)
+	var total float64
+	for _, p := range palette {
+		total += p.weight
+	}
+	for i := range palette {
+		palette[i].weight /= total
+	}
+	slices.SortStableFunc(palette, func(a, b paletteColor) int {
+		return cmp.Compare(b.weight, a.weight)
+	})
+	return palette
+}
+
+// medianCut splits cells into at most n groups, each time halving, by
+// weight, the group with the widest channel along that channel, and
+// returns each group's weighted mean color and total weight.
+func medianCut(cells []paletteColor, n int) []paletteColor {
+	groups := [][]paletteColor{cells}
+	for len(groups) < n {
+		widest, channel, spread := -1, 0, 0.0
+		for i, g := range groups {
+			if len(g) < 2 {
+				continue
+			}
+			for ch := range 3 {
+				lo, hi := g[0].c[ch], g[0].c[ch]
+				for _, p := range g[1:] {
+					lo, hi = min(lo, p.c[ch]), max(hi, p.c[ch])
+				}
+				if hi-lo > spread {
+					widest, channel, spread = i, ch, hi-lo
+				}
+			}
+		}
+		if widest < 0 {
+			break // every group is one color
+		}
+		g := groups[widest]
+		slices.SortStableFunc(g, func(a, b paletteColor) int { return cmp.Compare(a.c[channel], b.c[channel]) })
+		var total, acc float64
+		for _, p := range g {
+			total += p.weight
+		}
+		cut := 1
+		for cut < len(g)-1 {
+			if acc += g[cut-1].weight; acc >= total/2 {
+				break
+			}
+			cut++
+		}
+		groups[widest] = g[:cut]
+		groups = append(groups, g[cut:])
+	}
+
+	palette := make([]paletteColor, len(groups))
+	for i, g := range groups {
+		var p paletteColor
+		for _, cell := range g {
+			for ch := range 3 {
+				p.c[ch] += cell.c[ch] * cell.weight
+			}
+			p.weight += cell.weight
+		}
+		for ch := range 3 {
+			p.c[ch] /= p.weight
+		}
+		palette[i] = p
+	}
+	return palette
+}
+
+// backgroundColors is the part of palette, heaviest first, that covers at
+// least paletteShare of the area each; the heaviest color always counts.
+func backgroundColors(palette []paletteColor) []paletteColor {
+	n := 1
+	for n < len(palette) && palette[n].weight >= paletteShare {
+		n++
+	}
+	return palette[:n]
+}
+
+// minContrast is the lowest WCAG contrast ratio between c and the colors
+// of bg.
+func minContrast(c color.RGBA, bg []paletteColor) float64 {
+	ratio := math.Inf(1)
+	for _, p := range bg {
+		ratio = min(ratio, contrastRatio(c, p.rgba()))
+	}
+	return ratio
+}
+
+// contrastRatio is the WCAG 2 contrast ratio of two opaque colors, from 1
+// to 21.
+func contrastRatio(a, b color.RGBA) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// relativeLuminance is c's WCAG 2 relative luminance, from 0 to 1.
+func relativeLuminance(c color.RGBA) float64 {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(c.R) + 0.7152*lin(c.G) + 0.0722*lin(c.B)
+}
+
+// rgbToHSL converts c to hue in degrees and saturation and lightness from
+// 0 to 1.
+func rgbToHSL(c color.RGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := max(r, g, b), min(r, g, b)
+	l = (hi + lo) / 2
+	d := hi - lo
+	if d == 0 {
+		return 0, 0, l
+	}
+	s = d / (1 - math.Abs(2*l-1))
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// hslToRGB converts hue in degrees and saturation and lightness from 0 to
+// 1 to an opaque color.
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	ch := func(v float64) uint8 { return uint8(math.Round(min(max(v+m, 0), 1) * 255)) }
+	return color.RGBA{ch(r), ch(g), ch(b), 0xff}
+}
@@ -0,0 +1,257 @@
+package template
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// fillRects is a w×h image painted with each color over its rectangle, in
+// order; what no rectangle covers stays transparent.
+func fillRects(w, h int, fills ...any) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(fills); i += 2 {
+		draw.Draw(img, fills[i].(image.Rectangle), image.NewUniform(fills[i+1].(color.RGBA)), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+// grain adds ±amp of noise to every opaque pixel of img, the same for a
+// given seed.
+func grain(img *image.RGBA, amp int, seed uint64) *image.RGBA {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] == 0 {
+			continue
+		}
+		for k := range 3 {
+			v := int(img.Pix[i+k]) + rng.IntN(2*amp+1) - amp
+			img.Pix[i+k] = uint8(min(max(v, 0), 255))
+		}
+	}
+	return img
+}
+
+func colorDistance(a, b color.RGBA) int {
+	d := 0
+	for _, v := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+		d = max(d, v, -v)
+	}
+	return d
+}
+
+var (
+	navy      = color.RGBA{0x14, 0x21, 0x3d, 0xff}
+	paleSand  = color.RGBA{0xf6, 0xe7, 0xa1, 0xff}
+	brick     = color.RGBA{0xc0, 0x30, 0x20, 0xff}
+	orange    = color.RGBA{0xf0, 0x90, 0x20, 0xff}
+	leafGreen = color.RGBA{0x30, 0xa0, 0x40, 0xff}
+	paleMint  = color.RGBA{0xcf, 0xe8, 0xd0, 0xff}
+	midGray   = color.RGBA{0x80, 0x80, 0x80, 0xff}
+)
+
+// TestExtractPalette reduces synthetic areas of known colors and checks
+// that the palette finds them, heaviest first, with the area each covers.
+func TestExtractPalette(t *testing.T) {
+	type entry struct {
+		c      color.RGBA
+		weight float64
+	}
+	tests := []struct {
+		name string
+		img  *image.RGBA
+		want []entry // the leading palette colors, heaviest first
+		tol  int     // per channel
+	}{
+		{"solid", fillRects(200, 100, image.Rect(0, 0, 200, 100), brick),
+			[]entry{{brick, 1}}, 0},
+		{"three quarters and a stripe", fillRects(200, 100, image.Rect(0, 0, 200, 100), navy, image.Rect(150, 0, 200, 100), orange),
+			[]entry{{navy, 0.75}, {orange, 0.25}}, 4},
+		{"stripe on top", fillRects(90, 320, image.Rect(0, 0, 90, 320), paleSand, image.Rect(0, 0, 90, 80), leafGreen),
+			[]entry{{paleSand, 0.75}, {leafGreen, 0.25}}, 4},
+		{"grainy", grain(fillRects(320, 180, image.Rect(0, 0, 320, 180), leafGreen, image.Rect(0, 120, 320, 180), navy), 24, 1),
+			[]entry{{leafGreen, 2.0 / 3}, {navy, 1.0 / 3}}, 6},
+		{"transparent half", fillRects(100, 100, image.Rect(50, 0, 100, 100), orange),
+			[]entry{{orange, 1}}, 0},
+		{"tiny", fillRects(3, 2, image.Rect(0, 0, 3, 2), midGray),
+			[]entry{{midGray, 1}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			palette := extractPalette(tt.img)
+			if len(palette) < len(tt.want) || len(palette) > paletteSize {
+				t.Fatalf("%d colors, want %d to %d", len(palette), len(tt.want), paletteSize)
+			}
+			var total float64
+			for i, p := range palette {
+				total += p.weight
+				if i > 0 && p.weight > palette[i-1].weight {
+					t.Errorf("color %d weighs %v, more than the one before", i, p.weight)
+				}
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Errorf("weights sum to %v, want 1", total)
+			}
+			// The wanted colors may be split across palette entries of
+			// nearly the same color; sum those. Cells across an edge
+			// average both sides, so the weights are near, not exact.
+			for _, w := range tt.want {
+				var weight float64
+				for _, p := range palette {
+					if colorDistance(p.rgba(), w.c) <= tt.tol {
+						weight += p.weight
+					}
+				}
+				if math.Abs(weight-w.weight) > 0.05 {
+					t.Errorf("%s covers %.3f, want %.3f (palette %v)", hexColor(w.c), weight, w.weight, palette)
+				}
+			}
+			if palette[0].rgba() != tt.want[0].c && colorDistance(palette[0].rgba(), tt.want[0].c) > tt.tol {
+				t.Errorf("heaviest color %s, want %s", hexColor(palette[0].rgba()), hexColor(tt.want[0].c))
+			}
+		})
+	}
+
+	if p := extractPalette(image.NewRGBA(image.Rect(0, 0, 40, 40))); p != nil {
+		t.Errorf("transparent area has palette %v, want none", p)
+	}
+}
+
+// TestAutoColors resolves "auto" and "auto-contrast" over synthetic
+// backgrounds with known dominant colors.
+func TestAutoColors(t *testing.T) {
+	white, black := color.RGBA{0xff, 0xff, 0xff, 0xff}, color.RGBA{A: 0xff}
+	tests := []struct {
+		name     string
+		under    *image.RGBA
+		contrast color.RGBA // what "auto-contrast" picks
+		hueOf    color.RGBA // whose hue "auto" keeps; contrast for none
+		warns    bool
+	}{
+		{"dark", fillRects(240, 120, image.Rect(0, 0, 240, 120), navy), white, navy, false},
+		{"light", fillRects(240, 120, image.Rect(0, 0, 240, 120), paleSand), black, paleSand, false},
+		{"red, mostly", grain(fillRects(240, 120, image.Rect(0, 0, 240, 120), brick, image.Rect(0, 0, 240, 10), paleSand), 12, 2), white, brick, false},
+		{"sand with a mint band", fillRects(240, 120, image.Rect(0, 0, 240, 120), paleSand, image.Rect(0, 80, 240, 120), paleMint), black, paleSand, false},
+		{"green with a navy band", fillRects(240, 120, image.Rect(0, 0, 240, 120), leafGreen, image.Rect(0, 80, 240, 120), navy), white, leafGreen, true},
+		{"gray", fillRects(240, 120, image.Rect(0, 0, 240, 120), midGray), black, midGray, false},
+		{"black and white", fillRects(240, 120, image.Rect(0, 0, 120, 120), black, image.Rect(120, 0, 240, 120), white), white, black, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Renderer{}
+			box := componentBox{bounds: image.Rect(20, 10, 220, 110), borderWidth: 2}
+			resolve := func(textColor, borderColor string) ResolvedComponent {
+				comp := ResolvedComponent{ID: "c", Style: ComponentStyle{Color: textColor, BorderColor: borderColor}}
+				return r.resolveAutoColors(comp, box, tt.under)
+			}
+
+			got := resolve(ColorAutoContrast, ColorAutoContrast)
+			if got.Style.Color != hexColor(tt.contrast) || got.Style.BorderColor != hexColor(tt.contrast) {
+				t.Errorf("auto-contrast: color %s, border %s, want %s", got.Style.Color, got.Style.BorderColor, hexColor(tt.contrast))
+			}
+
+			area := image.NewRGBA(box.bounds)
+			draw.Draw(area, box.bounds, tt.under, box.bounds.Min, draw.Src)
+			bg := backgroundColors(extractPalette(area))
+			got = resolve(ColorAuto, "AUTO")
+			for _, c := range []struct {
+				field, value string
+				target       float64
+			}{{"color", got.Style.Color, textContrast}, {"borderColor", got.Style.BorderColor, borderContrast}} {
+				rgba := parseHexColorAlpha(c.value)
+				ratio := minContrast(rgba, bg)
+				if !tt.warns && ratio < c.target {
+					t.Errorf("auto %s %s contrasts %.2f:1, want %.1f:1", c.field, c.value, ratio, c.target)
+				}
+				h, s, _ := rgbToHSL(rgba)
+				wantH, wantS, _ := rgbToHSL(tt.hueOf)
+				switch {
+				case wantS < minHueSat || ratio < c.target:
+					if c.value != hexColor(tt.contrast) {
+						t.Errorf("auto %s over a gray area, or one no tint contrasts with, is %s; want the auto-contrast %s", c.field, c.value, hexColor(tt.contrast))
+					}
+				case s < minHueSat || math.Abs(math.Remainder(h-wantH, 360)) > 4:
+					t.Errorf("auto %s %s has hue %.0f and saturation %.2f, want the hue of %s, %.0f", c.field, c.value, h, s, hexColor(tt.hueOf), wantH)
+				}
+			}
+
+			if warned := len(r.Warnings()) > 0; warned != tt.warns {
+				t.Errorf("warnings %v, want some: %v", r.Warnings(), tt.warns)
+			}
+
+			// Without a border there is no border color to pick.
+			flat := box
+			flat.borderWidth = 0
+			if got := r.resolveAutoColors(ResolvedComponent{Style: ComponentStyle{BorderColor: ColorAuto}}, flat, tt.under); got.Style.BorderColor != ColorAuto {
+				t.Errorf("borderless auto border resolved to %s", got.Style.BorderColor)
+			}
+
+			// The same pixels give the same colors.
+			again := (&Renderer{}).resolveAutoColors(ResolvedComponent{ID: "c", Style: ComponentStyle{Color: ColorAuto, BorderColor: ColorAuto}}, box, tt.under)
+			if again.Style.Color != got.Style.Color || again.Style.BorderColor != got.Style.BorderColor {
+				t.Errorf("second resolve gave %s/%s, first %s/%s", again.Style.Color, again.Style.BorderColor, got.Style.Color, got.Style.BorderColor)
+			}
+		})
+	}
+}
+
+// TestAutoColorsRender renders "auto-contrast" text and borders over a
+// dark and a light synthetic background, at 8 and 16 bits, and expects
+// white ink on the dark one and black on the light one.
+func TestAutoColorsRender(t *testing.T) {
+	for _, bg := range []struct {
+		color string
+		want  color.RGBA
+	}{{hexColor(navy), color.RGBA{0xff, 0xff, 0xff, 0xff}}, {hexColor(paleSand), color.RGBA{A: 0xff}}} {
+		for _, depth16 := range []bool{false, true} {
+			renderer, err := NewRenderer("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			renderer.SetDepth16(depth16)
+			preset := &Preset{
+				Canvas:     Canvas{Width: 160, Height: 80},
+				Background: Background{Type: "color", Color: bg.color},
+				Components: []Component{{
+					ID: "c", X: 0.05, Y: 0.1, Width: 0.9, Height: 0.8, Padding: 8,
+					Style: ComponentStyle{
+						FontSize: 40, TitleFontSize: 40, LineHeight: 1.2,
+						Color: ColorAutoContrast, BorderColor: ColorAutoContrast, BorderWidth: 3,
+					},
+					Defaults: ComponentData{Title: "HHH"},
+				}},
+			}
+			if err := preset.Normalize(); err != nil {
+				t.Fatal(err)
+			}
+			img, err := renderer.RenderPresetImage(context.Background(), preset, MergeData(preset, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The border's top edge and the ink farthest from the
+			// background are the picked color.
+			if got := color.RGBAModel.Convert(img.At(80, 9)).(color.RGBA); got != bg.want {
+				t.Errorf("over %s, depth16 %v: border %v, want %v", bg.color, depth16, got, bg.want)
+			}
+			bgc := parseHexColorAlpha(bg.color)
+			ink := bgc
+			for y := 20; y < 60; y++ {
+				for x := 20; x < 140; x++ {
+					if c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); colorDistance(c, bgc) > colorDistance(ink, bgc) {
+						ink = c
+					}
+				}
+			}
+			if ink != bg.want {
+				t.Errorf("over %s, depth16 %v: text ink %v, want %v", bg.color, depth16, ink, bg.want)
+			}
+			if w := renderer.Warnings(); len(w) > 0 {
+				t.Errorf("over %s, depth16 %v: warnings %v", bg.color, depth16, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// avi.go — The first frame of an MJPEG AVI as an image, so a cover video
+// written by pkg/generator can be used as a background and stenciled over
+// again.
+package template
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errNoAVIFrame is returned for an AVI with no compressed video frame.
+var errNoAVIFrame = errors.New("AVI has no MJPEG video frame")
+
+// isAVI reports whether data is a RIFF AVI file.
+func isAVI(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "AVI "
+}
+
+// firstAVIFrame returns the first compressed video frame, a "##dc" chunk,
+// in an AVI's movi list: a JPEG image for the MJPEG files pkg/generator
+// writes. The walk follows chunk sizes rather than the idx1 index, which
+// may be missing or count offsets from either the file or the movi list.
+// A RIFF size of zero, as streaming writers leave it, or one past the end
+// of a truncated file means the rest of the data.
+func firstAVIFrame(data []byte) ([]byte, error) {
+	if !isAVI(data) {
+		return nil, errors.New("not an AVI file")
+	}
+	end := len(data)
+	if n := int64(binary.LittleEndian.Uint32(data[4:8])); n > 0 && 8+n < int64(end) {
+		end = int(8 + n)
+	}
+	if frame := findAVIFrame(data[12:end], false); frame != nil {
+		return frame, nil
+	}
+	return nil, errNoAVIFrame
+}
+
+// findAVIFrame walks the chunks in data, descending into LIST chunks, and
+// returns the data of the first "##dc" chunk inside a movi list; inMovi
+// says whether data already is inside one.
+func findAVIFrame(data []byte, inMovi bool) []byte {
+	for len(data) >= 8 {
+		id := data[:4]
+		size := int64(binary.LittleEndian.Uint32(data[4:8]))
+		body := data[8:]
+		if size < int64(len(body)) {
+			body = body[:size]
+		}
+
+		switch {
+		case string(id) == "LIST" && len(body) >= 4:
+			if frame := findAVIFrame(body[4:], inMovi || string(body[:4]) == "movi"); frame != nil {
+				return frame
+			}
+		case inMovi && isStreamChunk(id) && string(id[2:]) == "dc" && len(body) > 0:
+			return body
+		}
+
+		// Chunks are padded to an even length.
+		next := 8 + size + size&1
+		if next > int64(len(data)) {
+			break
+		}
+		data = data[next:]
+	}
+	return nil
+}
+
+// isStreamChunk reports whether id names a stream's data chunk, "NNxx"
+// with NN the two-digit stream number.
+func isStreamChunk(id []byte) bool {
+	return id[0] >= '0' && id[0] <= '9' && id[1] >= '0' && id[1] <= '9'
+}
@@ -0,0 +1,96 @@
+package template_test
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// TestAVIBackgroundRoundTrip writes a photo as an AVI with pkg/generator,
+// renders a preset over the AVI as its background, and compares that with
+// the same render over the photo itself. The frame is a JPEG, so pixels
+// may differ by its quantization error, but nothing may move. The
+// captioned cover checks that the first frame, before the caption, is
+// the one used.
+func TestAVIBackgroundRoundTrip(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "golden", "assets", "photo.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	original := filepath.Join(dir, "photo.png")
+	out, err := os.Create(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(out, photo); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	covers := map[string]generator.Config{
+		"plain":    {Image: photo, DurationSeconds: 0.5},
+		"segments": {Image: photo, DurationSeconds: 2, Segments: []generator.Segment{{Start: 0, Label: "intro"}, {Start: 1, Label: "main"}}},
+		"captions": {Image: photo, DurationSeconds: 2, Captions: []generator.Caption{{Start: 1, End: 2, Text: "later"}}},
+	}
+	quote := func(s string) string { b, _ := json.Marshal(s); return string(b) }
+	render := func(t *testing.T, background string) *image.RGBA {
+		t.Helper()
+		preset, err := template.DecodePreset([]byte(`{
+  "canvas": {"width": 240, "height": 160},
+  "background": {"type": "image", "source": ` + quote(background) + `},
+  "font": {},
+  "components": [
+    {"id": "title", "x": 0.05, "y": 0.05, "width": 0.9, "height": 0.3,
+     "style": {"backgroundColor": "#00000080", "color": "#ffffff", "fontSize": 20},
+     "defaults": {"visible": true, "title": "Stenciled again"}}
+  ]
+}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		renderer, err := template.NewRendererForFont(preset.Font, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renderer.SetStrictAssets(true)
+		img, err := renderer.RenderPreset(preset, template.MergeData(preset, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range renderer.Warnings() {
+			t.Errorf("render warning: %s", w)
+		}
+		return img
+	}
+	want := render(t, original)
+
+	for name, cfg := range covers {
+		t.Run(name, func(t *testing.T) {
+			cover := filepath.Join(dir, name+".avi")
+			if err := generator.Generate(cover, cfg); err != nil {
+				t.Fatal(err)
+			}
+			got := render(t, cover)
+			if got.Rect != want.Rect {
+				t.Fatalf("render is %v, want %v", got.Rect, want.Rect)
+			}
+			diff := template.CompareImages(got, want, template.CompareOptions{Tolerance: 8})
+			if !diff.Match {
+				t.Errorf("%d pixels differ by more than 8 (largest %d) from the render over the photo", diff.DiffPixels, diff.MaxDelta)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// benchFontPreset is a 1280x720 preset of ten text components, each in
+// its own custom font reference, all served by benchResolve.
+func benchFontPreset(b *testing.B) (*Preset, []ResolvedComponent) {
+	b.Helper()
+	preset := &Preset{Canvas: Canvas{Width: 1280, Height: 720}}
+	for i := range 10 {
+		preset.Components = append(preset.Components, Component{
+			ID: fmt.Sprintf("c%d", i), X: 0.05, Y: 0.02 + 0.095*float64(i), Width: 0.9, Height: 0.09,
+			Style:    ComponentStyle{FontPath: fmt.Sprintf("font%d.ttf", i), FontSize: 24},
+			Defaults: ComponentData{Title: "The quick brown fox jumps over the lazy dog"},
+		})
+	}
+	if err := preset.Normalize(); err != nil {
+		b.Fatal(err)
+	}
+	return preset, MergeData(preset, nil)
+}
+
+// benchResolve serves Go Regular for every font reference.
+func benchResolve(ref string) []byte {
+	if strings.HasSuffix(ref, ".ttf") {
+		return goregular.TTF
+	}
+	return nil
+}
+
+// BenchmarkRenderComponentFonts renders the ten-font preset with one
+// renderer, whose font cache parses each font once.
+func BenchmarkRenderComponentFonts(b *testing.B) {
+	preset, components := benchFontPreset(b)
+	r, err := NewRendererWithOptions(RendererOptions{Resolve: benchResolve})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := r.RenderPreset(preset, components); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderComponentFontsUncached clears the font cache before each
+// render, so every component parses its font again, as every render did
+// before the cache.
+func BenchmarkRenderComponentFontsUncached(b *testing.B) {
+	preset, components := benchFontPreset(b)
+	r, err := NewRendererWithOptions(RendererOptions{Resolve: benchResolve})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		r.SetAssetResolver(benchResolve)
+		if _, err := r.RenderPreset(preset, components); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// namedImage is a benchPhoto source.
+type namedImage struct {
+	name string
+	img  image.Image
+}
+
+// benchPhoto is a 1920x1080 gradient, JPEG-encoded and decoded as a photo
+// background would be, and the same picture as NRGBA, Gray and Paletted.
+func benchPhoto(tb testing.TB) []namedImage {
+	tb.Helper()
+	src := image.NewNRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := range 1080 {
+		for x := range 1920 {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		tb.Fatal(err)
+	}
+	photo, err := jpeg.Decode(&buf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	gray := image.NewGray(src.Rect)
+	paletted := image.NewPaletted(src.Rect, color.Palette{color.Black, color.White, color.RGBA{0xff, 0, 0, 0xff}})
+	for y := range 1080 {
+		for x := range 1920 {
+			gray.Set(x, y, src.At(x, y))
+			paletted.Set(x, y, src.At(x, y))
+		}
+	}
+	return []namedImage{{"YCbCr", photo}, {"NRGBA", src}, {"Gray", gray}, {"Paletted", paletted}}
+}
+
+// opaqueImage hides an image's type, so rgbaSampler falls back to At, the
+// per-pixel path drawFit took for every image before the sampler.
+type opaqueImage struct{ image.Image }
+
+// BenchmarkDrawFit draws each kind of 1920x1080 source onto a 1280x720
+// canvas with each fit, through rgbaSampler's direct readers and through
+// At.
+func BenchmarkDrawFit(b *testing.B) {
+	dst := image.NewRGBA(image.Rect(0, 0, 1280, 720))
+	for _, src := range benchPhoto(b) {
+		name := src.name
+		for _, fit := range []string{"stretch", "contain", "cover"} {
+			b.Run(name+"/"+fit, func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					drawFit(dst, src.img, fit)
+				}
+			})
+			b.Run(name+"/"+fit+"/At", func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					drawFit(dst, opaqueImage{src.img}, fit)
+				}
+			})
+		}
+	}
+}
+
+// TestRGBASampler checks that the direct readers return what At does, so
+// BenchmarkDrawFit compares equal output.
+func TestRGBASampler(t *testing.T) {
+	for _, src := range benchPhoto(t) {
+		at := rgbaSampler(src.img)
+		b := src.img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y += 7 {
+			for x := b.Min.X; x < b.Max.X; x += 3 {
+				if got, want := at(x, y), rgba8(src.img.At(x, y)); got != want {
+					t.Fatalf("%s: pixel %d,%d is %v, At gives %v", src.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// benchCanvasPreset is a 1920x1080 card preset: a color background and
+// three text components.
+func benchCanvasPreset(b *testing.B) (*Preset, []ResolvedComponent) {
+	b.Helper()
+	preset := &Preset{
+		Canvas:     Canvas{Width: 1920, Height: 1080},
+		Background: Background{Type: "color", Color: "#1a1a2e"},
+		Components: []Component{
+			{ID: "title", X: 0.1, Y: 0.1, Width: 0.8, Height: 0.2, Style: ComponentStyle{FontSize: 64, Color: "#ffffff"}, Defaults: ComponentData{Title: "Quarterly report"}},
+			{ID: "body", X: 0.1, Y: 0.35, Width: 0.8, Height: 0.4, Style: ComponentStyle{BackgroundColor: "#ffffff20", CornerRadius: 24}, Defaults: ComponentData{Items: []TextItem{{Type: "bullet", Text: "Revenue up"}, {Type: "bullet", Text: "Costs down"}}}},
+			{ID: "footer", X: 0.1, Y: 0.85, Width: 0.8, Height: 0.08, Defaults: ComponentData{Title: "example.com"}},
+		},
+	}
+	if err := preset.Normalize(); err != nil {
+		b.Fatal(err)
+	}
+	return preset, MergeData(preset, nil)
+}
+
+// BenchmarkRenderPreset allocates a new 1920x1080 canvas per render.
+func BenchmarkRenderPreset(b *testing.B) {
+	preset, components := benchCanvasPreset(b)
+	r, err := NewRenderer("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := r.RenderPreset(preset, components); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderPresetInto renders into one canvas, as batch does.
+func BenchmarkRenderPresetInto(b *testing.B) {
+	preset, components := benchCanvasPreset(b)
+	r, err := NewRenderer("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var canvas *image.RGBA
+	b.ReportAllocs()
+	for b.Loop() {
+		if canvas, err = r.RenderPresetInto(context.Background(), canvas, preset, components); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderPresetPool renders into canvases from an ImagePool, as
+// the server does.
+func BenchmarkRenderPresetPool(b *testing.B) {
+	preset, components := benchCanvasPreset(b)
+	r, err := NewRenderer("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var pool ImagePool
+	b.ReportAllocs()
+	for b.Loop() {
+		img, err := r.RenderPresetInto(context.Background(), pool.Get(1920, 1080), preset, components)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(img)
+	}
+}
@@ -0,0 +1,76 @@
+// blur.go — Gaussian blur, approximated by three successive box blurs.
+package template
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// MaxBackdropBlur caps style.backdropBlur; beyond it the blur is a flat
+// average anyway, and the sampled margin (3 × the blur) would cover the
+// largest canvas.
+const MaxBackdropBlur = 256
+
+// gaussianBlur returns a copy of img's area r blurred with a standard
+// deviation of sigma pixels; beyond r the edge pixels repeat. Each box
+// pass keeps a running sum, so the cost does not grow with sigma.
+func gaussianBlur(img *image.RGBA, r image.Rectangle, sigma float64) *image.RGBA {
+	buf := image.NewRGBA(r)
+	draw.Draw(buf, r, img, r.Min, draw.Src)
+	if sigma <= 0 || r.Empty() {
+		return buf
+	}
+	tmp := image.NewRGBA(r)
+	for _, size := range boxesForGauss(sigma, 3) {
+		rad := (size - 1) / 2
+		boxBlur(tmp.Pix, buf.Pix, r.Dx(), r.Dy(), 4, buf.Stride, rad)
+		boxBlur(buf.Pix, tmp.Pix, r.Dy(), r.Dx(), buf.Stride, 4, rad)
+	}
+	return buf
+}
+
+// boxesForGauss returns n odd box widths whose successive box blurs
+// approximate a Gaussian of standard deviation sigma.
+func boxesForGauss(sigma float64, n int) []int {
+	ideal := math.Sqrt(12*sigma*sigma/float64(n) + 1)
+	wl := int(ideal)
+	if wl%2 == 0 {
+		wl--
+	}
+	wu := wl + 2
+	m := int(math.Round((12*sigma*sigma - float64(n*wl*wl+4*n*wl+3*n)) / float64(-4*wl-4)))
+	sizes := make([]int, n)
+	for i := range sizes {
+		if i < m {
+			sizes[i] = wl
+		} else {
+			sizes[i] = wu
+		}
+	}
+	return sizes
+}
+
+// boxBlur averages each RGBA pixel of src with the rad pixels on either
+// side along one axis, writing dst. There are lines lines of length
+// pixels; step is the byte distance between pixels along a line and
+// lineStep between the starts of lines, so the same loop blurs rows
+// (step 4) or columns (step = stride).
+func boxBlur(dst, src []uint8, length, lines, step, lineStep, rad int) {
+	n := 2*rad + 1
+	last := length - 1
+	for l := range lines {
+		base := l * lineStep
+		for c := range 4 {
+			at := func(i int) int { return int(src[base+min(max(i, 0), last)*step+c]) }
+			sum := 0
+			for k := -rad; k <= rad; k++ {
+				sum += at(k)
+			}
+			for i := range length {
+				dst[base+i*step+c] = uint8((sum + n/2) / n)
+				sum += at(i+rad+1) - at(i-rad)
+			}
+		}
+	}
+}
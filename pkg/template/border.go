@@ -0,0 +1,137 @@
+// border.go — Dashed and dotted borders (style.borderStyle).
+//
+// Solid borders are filled span by span in renderer.go. A patterned border
+// covers the same ring of pixels, but keeps only those whose position
+// along the border's center line falls in a dash or dot; corners are
+// measured by arc length, so the pattern flows around them evenly.
+package template
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// borderPath is the center line of a border: a rounded rectangle halfway
+// through its width. A point is located on it by its arc length s,
+// clockwise from the left end of the top edge.
+type borderPath struct {
+	x0, y0, x1, y1 float64 // corner arc centers: the straight edges run between them
+	rc             float64 // corner radius of the center line
+	perimeter      float64
+}
+
+func newBorderPath(bounds image.Rectangle, radius, width int) borderPath {
+	half := float64(width) / 2
+	rc := max(float64(radius)-half, 0)
+	p := borderPath{
+		x0: float64(bounds.Min.X) + half + rc,
+		y0: float64(bounds.Min.Y) + half + rc,
+		x1: float64(bounds.Max.X) - half - rc,
+		y1: float64(bounds.Max.Y) - half - rc,
+		rc: rc,
+	}
+	p.x1, p.y1 = max(p.x1, p.x0), max(p.y1, p.y0)
+	p.perimeter = 2*(p.x1-p.x0) + 2*(p.y1-p.y0) + 2*math.Pi*rc
+	return p
+}
+
+// locate returns the arc length s of the point on the path nearest to
+// (x, y), and how far (x, y) lies from the path.
+func (p borderPath) locate(x, y float64) (s, off float64) {
+	qx, qy := min(max(x, p.x0), p.x1), min(max(y, p.y0), p.y1)
+	dx, dy := x-qx, y-qy
+	off = math.Abs(math.Hypot(dx, dy) - p.rc)
+	if dx == 0 && dy == 0 {
+		// Inside the straight edges' rectangle, as the inner half of a
+		// square-cornered border is: measure from the nearest edge.
+		e := min(x-p.x0, p.x1-x, y-p.y0, p.y1-y)
+		off = p.rc + e
+		switch e {
+		case y - p.y0:
+			dy = -1
+		case p.x1 - x:
+			dx = 1
+		case p.y1 - y:
+			dy = 1
+		default:
+			dx = -1
+		}
+	}
+
+	w, h := p.x1-p.x0, p.y1-p.y0
+	quarter := p.rc * math.Pi / 2
+	switch {
+	case dx > 0 && dy < 0: // top right corner
+		return w + p.rc*math.Atan2(dx, -dy), off
+	case dx > 0 && dy > 0: // bottom right
+		return w + quarter + h + p.rc*math.Atan2(dy, dx), off
+	case dx < 0 && dy > 0: // bottom left
+		return 2*w + 2*quarter + h + p.rc*math.Atan2(-dx, dy), off
+	case dx < 0 && dy < 0: // top left
+		return 2*w + 3*quarter + 2*h + p.rc*math.Atan2(-dy, -dx), off
+	case dx > 0: // right edge
+		return w + quarter + (y - p.y0), off
+	case dy > 0: // bottom edge
+		return w + 2*quarter + h + (p.x1 - x), off
+	case dx < 0: // left edge
+		return 2*w + 3*quarter + h + (p.y1 - y), off
+	default: // top edge
+		return x - p.x0, off
+	}
+}
+
+// borderPattern returns the dash (or dot) and gap lengths a border style
+// draws with, stretched so that a whole number of them fits the
+// perimeter. ok is false for a solid border.
+func borderPattern(s *ComponentStyle, width int, perimeter float64) (dash, gap float64, ok bool) {
+	w := float64(width)
+	switch s.BorderStyle {
+	case "dashed":
+		dash, gap = 3*w, 2*w
+		if s.DashLength > 0 {
+			dash = s.DashLength
+		}
+	case "dotted":
+		dash, gap = w, w
+	default:
+		return 0, 0, false
+	}
+	if s.GapLength > 0 {
+		gap = s.GapLength
+	}
+	period := dash + gap
+	n := max(math.Round(perimeter/period), 1)
+	scale := perimeter / (n * period)
+	return dash * scale, gap * scale, true
+}
+
+// drawPatternedBorder draws the pixels of a border ring that fall in a
+// dash: for "dotted", round dots the width of the border; otherwise
+// dashes dash long. The pattern starts at the left end of the top edge.
+func drawPatternedBorder(img *image.RGBA, bounds image.Rectangle, c color.RGBA, radius, width int, style *ComponentStyle) {
+	radius = cornerRadius(bounds, Radius(radius))
+	path := newBorderPath(bounds, radius, width)
+	dash, gap, ok := borderPattern(style, width, path.perimeter)
+	if !ok {
+		return
+	}
+	period := dash + gap
+	dotted := style.BorderStyle == "dotted"
+	dotR := min(float64(width), period) / 2
+	borderSpans(bounds, radius, width, func(y, x0, x1 int) {
+		for x := x0; x < x1; x++ {
+			s, off := path.locate(float64(x)+0.5, float64(y)+0.5)
+			if dotted {
+				// Distance along the path to the nearest dot's center.
+				d := math.Mod(s-dash/2+period/2, period) - period/2
+				if d*d+off*off > dotR*dotR {
+					continue
+				}
+			} else if math.Mod(s, period) >= dash {
+				continue
+			}
+			blendPixel(img, x, y, c)
+		}
+	})
+}
@@ -0,0 +1,350 @@
+// builder.go — Build presets in Go code.
+//
+// A builder produces the same *Preset a loaded preset.json would: Build
+// applies Normalize's defaults and runs Lint, so a program gets the
+// fallbacks and the checks the CLI, server and WASM client use.
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PresetBuilder assembles a Preset. Each method returns the builder, so
+// calls chain:
+//
+//	preset, err := template.NewPresetBuilder("card").
+//		Canvas(1280, 720).
+//		Background(template.Color("#1a1a2e")).
+//		AddComponent(template.NewText("title", "Hello").At(0.1, 0.1, 0.8, 0.2).FontSize(48)).
+//		Build()
+type PresetBuilder struct {
+	p     Preset
+	comps []*ComponentBuilder
+}
+
+// NewPresetBuilder starts a preset named name.
+func NewPresetBuilder(name string) *PresetBuilder {
+	return &PresetBuilder{p: Preset{Meta: Meta{Name: name}}}
+}
+
+// Meta sets the preset's version, author and description.
+func (b *PresetBuilder) Meta(version, author, description string) *PresetBuilder {
+	b.p.Meta.Version, b.p.Meta.Author, b.p.Meta.Description = version, author, description
+	return b
+}
+
+// Canvas sets the canvas size in pixels.
+func (b *PresetBuilder) Canvas(width, height int) *PresetBuilder {
+	b.p.Canvas = Canvas{Width: width, Height: height, Snap: b.p.Canvas.Snap}
+	return b
+}
+
+// CanvasPreset sets the canvas size by one of the names in Presets
+// ("1080p", "instagram_story", ...); Build fails on an unknown name.
+func (b *PresetBuilder) CanvasPreset(name string) *PresetBuilder {
+	b.p.Canvas = Canvas{Preset: name, Snap: b.p.Canvas.Snap}
+	return b
+}
+
+// Snap sets how component boxes are snapped to whole pixels: SnapRound
+// (the default), SnapFloor or SnapNone.
+func (b *PresetBuilder) Snap(mode string) *PresetBuilder {
+	b.p.Canvas.Snap = mode
+	return b
+}
+
+// Background sets the canvas fill; see Color and Image.
+func (b *PresetBuilder) Background(bg Background) *PresetBuilder {
+	b.p.Background = bg
+	return b
+}
+
+// Font sets the global font.
+func (b *PresetBuilder) Font(fc FontConfig) *PresetBuilder {
+	b.p.Font = fc
+	return b
+}
+
+// Describe sets the schema description of the data the preset takes.
+func (b *PresetBuilder) Describe(description string) *PresetBuilder {
+	b.p.Schema.Description = description
+	return b
+}
+
+// AddComponent appends components, drawn in zIndex order and then by ID.
+func (b *PresetBuilder) AddComponent(comps ...*ComponentBuilder) *PresetBuilder {
+	b.comps = append(b.comps, comps...)
+	return b
+}
+
+// Build returns the preset with Normalize's defaults applied. It fails
+// with a *BuildError if a component has no ID or Lint reports a warning
+// (a bad color, a missing image or font, components that overlap at the
+// same zIndex, ...); give overlapping components distinct zIndex values
+// to fix the last. The builder may be changed and built again.
+func (b *PresetBuilder) Build() (*Preset, error) {
+	p := b.p
+	p.FormatVersion = FormatVersion
+	p.Components = make([]Component, 0, len(b.comps))
+	for _, cb := range b.comps {
+		c := cb.c
+		c.Defaults.Items = append([]TextItem(nil), c.Defaults.Items...)
+		p.Components = append(p.Components, c)
+		if cb.schema != nil {
+			if p.Schema.Components == nil {
+				p.Schema.Components = make(map[string]SchemaComponent)
+			}
+			p.Schema.Components[c.ID] = *cb.schema
+		}
+	}
+	if err := p.Normalize(); err != nil {
+		return nil, fmt.Errorf("build preset %q: %w", p.Meta.Name, err)
+	}
+
+	var issues []Issue
+	for _, c := range p.Components {
+		if c.ID == "" {
+			issues = append(issues, Issue{Severity: SeverityWarning, Field: "id", Message: "component has no ID"})
+		}
+	}
+	for _, i := range Lint(&p, nil) {
+		if i.Severity == SeverityWarning {
+			issues = append(issues, i)
+		}
+	}
+	if len(issues) > 0 {
+		return nil, &BuildError{Preset: p.Meta.Name, Issues: issues}
+	}
+	return &p, nil
+}
+
+// BuildError lists the problems that stopped PresetBuilder.Build.
+type BuildError struct {
+	Preset string
+	Issues []Issue
+}
+
+func (e *BuildError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return fmt.Sprintf("build preset %q: %s", e.Preset, strings.Join(msgs, "; "))
+}
+
+// Color is a solid background of a hex color.
+func Color(hex string) Background {
+	return Background{Type: "color", Color: hex}
+}
+
+// Transparent leaves the canvas clear: PNG output keeps the transparency,
+// and formats without alpha draw it over their matte color.
+func Transparent() Background {
+	return Background{Type: "transparent"}
+}
+
+// Image is a background image (a path or asset ID), stretched to the
+// canvas.
+func Image(source string) Background {
+	return Background{Type: "image", Source: source}
+}
+
+// ComponentBuilder assembles one component for PresetBuilder.AddComponent.
+// Unset style fields get Normalize's defaults (24pt white left-aligned
+// text, line height 1.5).
+type ComponentBuilder struct {
+	c      Component
+	schema *SchemaComponent
+}
+
+// NewBox starts an empty component: a container for a background color,
+// border or image.
+func NewBox(id string) *ComponentBuilder {
+	return &ComponentBuilder{c: Component{ID: id}}
+}
+
+// NewText starts a component holding text items, one per argument.
+func NewText(id string, text ...string) *ComponentBuilder {
+	return NewBox(id).Text(text...)
+}
+
+// NewImage starts a component showing an image (a path or asset ID),
+// scaled to fit inside it.
+func NewImage(id, source string) *ComponentBuilder {
+	return NewBox(id).BackgroundImage(source, "contain")
+}
+
+// At places the component, as fractions (0–1) of the canvas.
+func (cb *ComponentBuilder) At(x, y, width, height float64) *ComponentBuilder {
+	cb.c.X, cb.c.Y, cb.c.Width, cb.c.Height = x, y, width, height
+	return cb
+}
+
+// ZIndex sets the drawing order; higher is on top.
+func (cb *ComponentBuilder) ZIndex(z int) *ComponentBuilder {
+	cb.c.ZIndex = z
+	return cb
+}
+
+// Padding sets the inner padding in pixels.
+func (cb *ComponentBuilder) Padding(px int) *ComponentBuilder {
+	cb.c.Padding = px
+	return cb
+}
+
+// Title sets the default title, drawn in bold above the items.
+func (cb *ComponentBuilder) Title(title string) *ComponentBuilder {
+	cb.c.Defaults.Title = title
+	return cb
+}
+
+// Text appends plain text items.
+func (cb *ComponentBuilder) Text(text ...string) *ComponentBuilder {
+	return cb.items("text", text)
+}
+
+// Bullets appends bulleted items.
+func (cb *ComponentBuilder) Bullets(text ...string) *ComponentBuilder {
+	return cb.items("bullet", text)
+}
+
+// Numbered appends numbered items.
+func (cb *ComponentBuilder) Numbered(text ...string) *ComponentBuilder {
+	return cb.items("numbered", text)
+}
+
+func (cb *ComponentBuilder) items(kind string, text []string) *ComponentBuilder {
+	for _, t := range text {
+		cb.c.Defaults.Items = append(cb.c.Defaults.Items, TextItem{Type: kind, Text: t})
+	}
+	return cb
+}
+
+// Hidden makes the component invisible unless data shows it.
+func (cb *ComponentBuilder) Hidden() *ComponentBuilder {
+	visible := false
+	cb.c.Defaults.Visible = &visible
+	return cb
+}
+
+// FontSize sets the text size in points.
+func (cb *ComponentBuilder) FontSize(size float64) *ComponentBuilder {
+	cb.c.Style.FontSize = size
+	return cb
+}
+
+// Font sets a component font (a path or asset ID).
+func (cb *ComponentBuilder) Font(path string) *ComponentBuilder {
+	cb.c.Style.FontPath = path
+	return cb
+}
+
+// Color sets the text color: a hex color, ColorAuto or ColorAutoContrast.
+func (cb *ComponentBuilder) Color(hex string) *ComponentBuilder {
+	cb.c.Style.Color = hex
+	return cb
+}
+
+// LineHeight sets the line height as a multiple of the font size, or in
+// pixels above MaxLineHeightMultiplier.
+func (cb *ComponentBuilder) LineHeight(multiplier float64) *ComponentBuilder {
+	cb.c.Style.LineHeight = LineHeight(multiplier)
+	return cb
+}
+
+// Align sets the text alignment: "left", "center" or "right".
+func (cb *ComponentBuilder) Align(align string) *ComponentBuilder {
+	cb.c.Style.TextAlign = align
+	return cb
+}
+
+// TitleStyle sets the title's size in points and color; zero values keep
+// the defaults (1.4 × the font size, the text color).
+func (cb *ComponentBuilder) TitleStyle(size float64, hex string) *ComponentBuilder {
+	cb.c.Style.TitleFontSize, cb.c.Style.TitleColor = size, hex
+	return cb
+}
+
+// Arc sets the text along a circle centered on the box; see ArcStyle.
+// radius 0 fits the first line inside the box.
+func (cb *ComponentBuilder) Arc(radius, startAngle float64, direction string) *ComponentBuilder {
+	cb.c.Style.Arc = &ArcStyle{Radius: radius, StartAngle: startAngle, Direction: direction}
+	return cb
+}
+
+// MaxLines limits the text to n lines, title included; when lines are
+// dropped the last one reads moreFormat with %d replaced by their count
+// ("" for "+%d more").
+func (cb *ComponentBuilder) MaxLines(n int, moreFormat string) *ComponentBuilder {
+	cb.c.Style.MaxLines, cb.c.Style.MoreFormat = n, moreFormat
+	return cb
+}
+
+// BackdropBlur blurs whatever is drawn behind the box, sigma pixels
+// (standard deviation); a translucent Fill then tints it, for a frosted
+// glass look.
+func (cb *ComponentBuilder) BackdropBlur(sigma float64) *ComponentBuilder {
+	cb.c.Style.BackdropBlur = sigma
+	return cb
+}
+
+// Fill sets the background color ("#rrggbb" or "#rrggbbaa").
+func (cb *ComponentBuilder) Fill(hex string) *ComponentBuilder {
+	cb.c.Style.BackgroundColor = hex
+	return cb
+}
+
+// BackgroundImage sets a background image (a path or asset ID) and how it
+// fits: "stretch", "contain" or "cover".
+func (cb *ComponentBuilder) BackgroundImage(source, fit string) *ComponentBuilder {
+	cb.c.Style.BackgroundImage, cb.c.Style.BackgroundFit = source, fit
+	return cb
+}
+
+// Mask clips the component to an image (a path or asset ID): its alpha,
+// or its brightness if it is opaque. fit is as for BackgroundImage.
+func (cb *ComponentBuilder) Mask(source, fit string) *ComponentBuilder {
+	cb.c.Style.MaskImage, cb.c.Style.MaskFit = source, fit
+	return cb
+}
+
+// Border sets the border color, which may be automatic as for Color, and
+// width in pixels.
+func (cb *ComponentBuilder) Border(hex string, width int) *ComponentBuilder {
+	cb.c.Style.BorderColor, cb.c.Style.BorderWidth = hex, width
+	return cb
+}
+
+// BorderStyle sets the border to "solid", "dashed" or "dotted", with dash
+// and gap lengths in pixels; zero keeps the defaults, which scale with the
+// border width.
+func (cb *ComponentBuilder) BorderStyle(style string, dash, gap float64) *ComponentBuilder {
+	cb.c.Style.BorderStyle, cb.c.Style.DashLength, cb.c.Style.GapLength = style, dash, gap
+	return cb
+}
+
+// Radius sets the corner radius in pixels, or RadiusFull.
+func (cb *ComponentBuilder) Radius(r Radius) *ComponentBuilder {
+	cb.c.Style.CornerRadius = r
+	return cb
+}
+
+// Describe documents the component's data fields in the preset schema.
+func (cb *ComponentBuilder) Describe(description string, fields map[string]string) *ComponentBuilder {
+	if cb.schema == nil {
+		cb.schema = &SchemaComponent{}
+	}
+	cb.schema.Description, cb.schema.Fields = description, fields
+	return cb
+}
+
+// Require lists data fields (RequirableFields) that data.json must set for
+// the component; see SchemaComponent.Required.
+func (cb *ComponentBuilder) Require(fields ...string) *ComponentBuilder {
+	if cb.schema == nil {
+		cb.schema = &SchemaComponent{}
+	}
+	cb.schema.Required = append(cb.schema.Required, fields...)
+	return cb
+}
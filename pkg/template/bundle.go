@@ -0,0 +1,336 @@
+// bundle.go — Write .gspresets (ZIP) bundles, the inverse of LoadPreset.
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// PreviewName is the bundle entry holding a preset's preview image.
+const PreviewName = "preview.png"
+
+// PreviewWidth is the width of the previews SavePreset renders.
+const PreviewWidth = 320
+
+// BundleAsset is an asset stored in a bundle.
+type BundleAsset struct {
+	Name string // original file name, used for the entry name where possible
+	Mime string // picks an extension when Name has none
+	Data []byte
+}
+
+// WriteBundle writes a .gspresets ZIP holding presetJSON and the assets it
+// references. Every reference lookup resolves is stored as assets/<name>
+// and rewritten in preset.json to that path, so LoadPreset finds it
+// relative to the bundle; other references are kept as they are.
+func WriteBundle(w io.Writer, presetJSON []byte, lookup func(ref string) (BundleAsset, bool)) error {
+	return WriteBundleWithPreview(w, presetJSON, lookup, nil)
+}
+
+// WriteBundleWithPreview is WriteBundle that also stores preview, a PNG,
+// as preview.png. A nil preview is left out.
+func WriteBundleWithPreview(w io.Writer, presetJSON []byte, lookup func(ref string) (BundleAsset, bool), preview []byte) error {
+	var p Preset
+	if err := json.Unmarshal(presetJSON, &p); err != nil {
+		return fmt.Errorf("parse preset: %w", err)
+	}
+
+	type file struct {
+		path string
+		data []byte
+	}
+	var (
+		files []file
+		paths = make(map[string]string)
+		used  = make(map[string]bool)
+	)
+	for _, ref := range ReferencedAssets(&p) {
+		if a, ok := lookup(ref); ok {
+			paths[ref] = bundlePath(ref, a, used)
+			files = append(files, file{paths[ref], a.Data})
+		}
+	}
+
+	zw := zip.NewWriter(w)
+
+	// Write preset.json (pretty-printed).
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, RewriteAssetRefs(presetJSON, paths), "", "  "); err != nil {
+		return fmt.Errorf("format preset: %w", err)
+	}
+	files = append([]file{{"preset.json", pretty.Bytes()}}, files...)
+	if preview != nil {
+		files = append(files, file{PreviewName, preview})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.path)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// SavePreset writes a bundle as WriteBundle does, with a PreviewWidth
+// preview rendered from the preset's default data and the assets lookup
+// resolves. A preset that fails to render is saved without a preview, and
+// the returned warnings say why.
+func SavePreset(w io.Writer, presetJSON []byte, lookup func(ref string) (BundleAsset, bool)) ([]string, error) {
+	var warnings []string
+	preview, err := RenderPreview(presetJSON, func(ref string) []byte {
+		if a, ok := lookup(ref); ok {
+			return a.Data
+		}
+		return nil
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("preview omitted: %v", err))
+	}
+	return warnings, WriteBundleWithPreview(w, presetJSON, lookup, preview)
+}
+
+// RenderPreview renders presetJSON with its default data, reading assets
+// through resolve, and returns it as a PreviewWidth PNG.
+func RenderPreview(presetJSON []byte, resolve AssetResolverFunc) ([]byte, error) {
+	preset, err := DecodePreset(presetJSON)
+	if err != nil {
+		return nil, fmt.Errorf("parse preset: %w", err)
+	}
+	renderer, err := NewRendererForFont(preset.Font, resolve)
+	if err != nil {
+		return nil, err
+	}
+	img, err := renderer.RenderPreset(preset, MergeData(preset, nil))
+	if err != nil {
+		return nil, err
+	}
+	return EncodePreview(img, PreviewWidth)
+}
+
+// EncodePreview scales img to width, keeping its aspect ratio, and
+// encodes it as PNG.
+func EncodePreview(img image.Image, width int) ([]byte, error) {
+	b := img.Bounds()
+	height := max(b.Dy()*width/max(b.Dx(), 1), 1)
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(thumb, thumb.Bounds(), img, b, xdraw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, thumb); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReferencedAssets lists, sorted, every asset reference in a preset: the
+// global font, background image, and each style class's and component's
+// (and its defaults.style's, responsive styles' and variants') background
+// image, mask image and font.
+func ReferencedAssets(p *Preset) []string {
+	refs := make(map[string]bool)
+	add := func(ref string) {
+		if ref != "" {
+			refs[ref] = true
+		}
+	}
+	add(p.Font.Path)
+	add(p.Background.Source)
+	for _, s := range p.Styles {
+		add(s.BackgroundImage)
+		add(s.MaskImage)
+		add(s.FontPath)
+	}
+	for _, c := range p.Components {
+		add(c.Style.BackgroundImage)
+		add(c.Style.MaskImage)
+		add(c.Style.FontPath)
+		if st := c.Defaults.Style; st != nil {
+			add(st.BackgroundImage)
+			add(st.MaskImage)
+			add(st.FontPath)
+		}
+		for _, o := range c.Responsive {
+			if o.Style != nil {
+				add(o.Style.BackgroundImage)
+				add(o.Style.MaskImage)
+				add(o.Style.FontPath)
+			}
+		}
+		for _, v := range c.Variants {
+			add(v.BackgroundImage)
+			add(v.MaskImage)
+			add(v.FontPath)
+		}
+	}
+	return slices.Sorted(maps.Keys(refs))
+}
+
+// assetRefPaths are the JSON paths of the preset fields ReferencedAssets
+// reads; "*" stands for any object key or array index.
+var assetRefPaths = func() [][]string {
+	paths := [][]string{{"font", "path"}, {"background", "source"}}
+	for _, style := range [][]string{
+		{"styles", "*"},
+		{"components", "*", "style"},
+		{"components", "*", "defaults", "style"},
+		{"components", "*", "responsive", "*", "style"},
+		{"components", "*", "variants", "*"},
+	} {
+		for _, field := range []string{"backgroundImage", "maskImage", "fontPath"} {
+			paths = append(paths, append(slices.Clone(style), field))
+		}
+	}
+	return paths
+}()
+
+// isAssetRefPath reports whether path is one of assetRefPaths. Names match
+// without regard to case, as they do when the preset is decoded.
+func isAssetRefPath(path []string) bool {
+	return slices.ContainsFunc(assetRefPaths, func(p []string) bool {
+		return slices.EqualFunc(p, path, func(want, got string) bool {
+			return want == "*" || strings.EqualFold(want, got)
+		})
+	})
+}
+
+// RewriteAssetRefs replaces the asset references in a preset (the fields
+// ReferencedAssets reads) that are keys of refs with their mapped values,
+// each once, leaving the rest of the document (other strings, key order,
+// formatting) untouched. raw is returned as it is if it is not valid JSON.
+func RewriteAssetRefs(raw []byte, refs map[string]string) []byte {
+	// level is an object or array being read: the key or index of its
+	// current value, and for an object whether a key comes next.
+	type level struct {
+		object, wantKey bool
+		name            string
+		index           int
+	}
+	var (
+		stack []*level
+		out   []byte
+		last  int // raw[:last] is in out
+	)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return raw
+		}
+		var top *level
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if top != nil && top.wantKey {
+			top.name, top.wantKey = tok.(string), false
+			continue
+		}
+
+		// tok starts a value: the current one of top.
+		var path []string
+		if top != nil {
+			if top.object {
+				top.wantKey = true
+			} else {
+				top.name = strconv.Itoa(top.index)
+				top.index++
+			}
+			for _, l := range stack {
+				path = append(path, l.name)
+			}
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			stack = append(stack, &level{object: v == '{', wantKey: v == '{'})
+		case string:
+			to, ok := refs[v]
+			if !ok || !isAssetRefPath(path) {
+				continue
+			}
+			// Separators and spaces hold no quotes: the string starts
+			// at the first one after the previous token.
+			from := int(start) + bytes.IndexByte(raw[start:], '"')
+			out = append(append(out, raw[last:from]...), jsonString(to)...)
+			last = int(dec.InputOffset())
+		}
+	}
+	if out == nil {
+		return raw
+	}
+	return append(out, raw[last:]...)
+}
+
+// bundlePath picks a unique "assets/<name>" entry for an asset, keeping
+// its file name where possible.
+func bundlePath(ref string, a BundleAsset, used map[string]bool) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", " ", "_").Replace(a.Name)
+	if name == "" || strings.HasPrefix(name, ".") {
+		name = ref
+	}
+	ext := filepath.Ext(name)
+	if ext == "" {
+		ext = extensionForMime(a.Mime)
+		name += ext
+	}
+	base := strings.TrimSuffix(name, ext)
+	path := "assets/" + name
+	for n := 2; used[path]; n++ {
+		path = fmt.Sprintf("assets/%s_%d%s", base, n, ext)
+	}
+	used[path] = true
+	return path
+}
+
+func extensionForMime(m string) string {
+	switch {
+	case strings.Contains(m, "collection"):
+		return ".ttc"
+	case strings.Contains(m, "otf"):
+		return ".otf"
+	case strings.Contains(m, "ttf"), strings.Contains(m, "font"):
+		return ".ttf"
+	case strings.Contains(m, "png"):
+		return ".png"
+	case strings.Contains(m, "jpeg"), strings.Contains(m, "jpg"):
+		return ".jpg"
+	case strings.Contains(m, "svg"):
+		return ".svg"
+	case strings.Contains(m, "msvideo"):
+		return ".avi"
+	default:
+		return ""
+	}
+}
+
+// jsonString encodes s as a JSON string without HTML escaping, matching
+// what editors write.
+func jsonString(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,101 @@
+// canvas.go — Named canvas sizes: registration, listing and the check for
+// unknown names.
+package template
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// NamedCanvas is one entry of Presets.
+type NamedCanvas struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// RegisterCanvasPreset adds a canvas preset name, or changes the size of
+// an existing one. It is meant to be set up before presets are loaded; it
+// is not safe to call while rendering. It panics if name is empty or a
+// dimension is not positive. Sizes over a renderer's maximum register but
+// fail to render, as an explicit width or height would.
+func RegisterCanvasPreset(name string, w, h int) {
+	if name == "" || w <= 0 || h <= 0 {
+		panic(fmt.Sprintf("template: RegisterCanvasPreset(%q, %d, %d): need a name and a positive size", name, w, h))
+	}
+	Presets[name] = [2]int{w, h}
+}
+
+// CanvasPresets lists Presets sorted by name.
+func CanvasPresets() []NamedCanvas {
+	list := make([]NamedCanvas, 0, len(Presets))
+	for _, name := range slices.Sorted(maps.Keys(Presets)) {
+		dims := Presets[name]
+		list = append(list, NamedCanvas{Name: name, Width: dims[0], Height: dims[1]})
+	}
+	return list
+}
+
+// unknownCanvasPreset describes a canvas.preset that names no entry of
+// Presets, or returns "" if the name is empty or known. Normalize then
+// keeps the explicit width and height, so a typo would otherwise go
+// unnoticed.
+func unknownCanvasPreset(c Canvas) string {
+	if _, ok := Presets[c.Preset]; ok || c.Preset == "" {
+		return ""
+	}
+	msg := fmt.Sprintf("unknown canvas preset %q", c.Preset)
+	if near := nearestCanvasPreset(c.Preset); near != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", near)
+	}
+	return msg + fmt.Sprintf(" — using %dx%d", c.Width, c.Height)
+}
+
+// unknownSnap describes a canvas.snap that is not a Snap value, or
+// returns "" for one that is.
+func unknownSnap(c Canvas) string {
+	switch c.Snap {
+	case "", SnapRound, SnapFloor, SnapNone:
+		return ""
+	}
+	return fmt.Sprintf("unknown snap mode %q (want %s, %s or %s) — using %s", c.Snap, SnapRound, SnapFloor, SnapNone, SnapRound)
+}
+
+// nearestCanvasPreset is the preset name with the smallest edit distance
+// to name, the alphabetically first on ties.
+func nearestCanvasPreset(name string) string {
+	return nearestName(name, slices.Sorted(maps.Keys(Presets)))
+}
+
+// nearestName is the candidate with the smallest edit distance to name,
+// the first on ties, or "" if there are none.
+func nearestName(name string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		if d := editDistance(name, c); bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, by byte.
+func editDistance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			diag, row[j] = row[j], min(row[j]+1, row[j-1]+1, diag+cost)
+		}
+	}
+	return row[len(b)]
+}
@@ -0,0 +1,136 @@
+// classes.go — Style classes: named partial styles in the preset's
+// top-level "styles", shared by the components that list them, so a look
+// used across a preset is written once.
+//
+//	"styles": {
+//	  "caption": {"fontSize": 28, "color": "#ffffff", "textAlign": "center"},
+//	  "boxed":   {"backgroundColor": "#00000080", "cornerRadius": 12}
+//	},
+//	"components": [
+//	  {"id": "title", "classes": ["caption", "boxed"], "style": {"fontSize": 48}}
+//	]
+//
+// A component's classes are merged in order, each over the ones before it,
+// and its own style over them all, field by field as data's style merges
+// over the preset's. Normalize does this before the style fallbacks, so a
+// class can set what would otherwise default. Responsive overrides,
+// variants, defaults.style and data's style all apply on top.
+//
+// data.json replaces a component's class list with "classes"; [] removes
+// them all. An unknown name is skipped, with a warning.
+package template
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// StyleNames returns the names of p's style classes, sorted.
+func (p *Preset) StyleNames() []string {
+	return slices.Sorted(maps.Keys(p.Styles))
+}
+
+// applyClasses merges c's classes beneath its style, keeping the style as
+// written for classStyle. Applying them again changes nothing.
+func (p *Preset) applyClasses(c *Component) {
+	if c.own == nil {
+		own := c.Style
+		c.own = &own
+	}
+	if len(c.Classes) > 0 {
+		c.Style = p.classStyle(c.Classes, c.Style)
+	}
+}
+
+// classStyle is the style of the classes names, in order, with own merged
+// over them. Names p does not define are skipped.
+func (p *Preset) classStyle(names []string, own ComponentStyle) ComponentStyle {
+	var s ComponentStyle
+	for _, name := range names {
+		if class, ok := p.Styles[name]; ok {
+			mergeComponentStyle(&s, class)
+		}
+	}
+	mergeComponentStyle(&s, own)
+	return s
+}
+
+// ownStyle is c's style as the preset wrote it, without its classes;
+// c.Style when c has not been normalized.
+func (c *Component) ownStyle() *ComponentStyle {
+	if c.own != nil {
+		return c.own
+	}
+	return &c.Style
+}
+
+// selectedClasses is the class list the active locale's overlay selects,
+// else data, else the component's defaults; nil when none of them sets
+// one and c keeps its own.
+func (c *Component) selectedClasses(override, localized ComponentData) []string {
+	for _, classes := range [][]string{localized.Classes, override.Classes, c.Defaults.Classes} {
+		if classes != nil {
+			return classes
+		}
+	}
+	return nil
+}
+
+// withClasses returns c restyled with the classes names in place of its
+// own, from its style as the preset wrote it.
+func (p *Preset) withClasses(c Component, names []string) Component {
+	c.Classes = names
+	c.Style = p.classStyle(names, *c.ownStyle())
+	ApplyComponentDefaults(&c)
+	return c
+}
+
+// unknownClasses describes each of names p has no style class for.
+func (p *Preset) unknownClasses(names []string) []string {
+	var msgs []string
+	for _, name := range names {
+		if _, ok := p.Styles[name]; ok {
+			continue
+		}
+		if len(p.Styles) == 0 {
+			msgs = append(msgs, fmt.Sprintf("unknown class %q: the preset has no styles", name))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("unknown class %q (available: %s)", name, strings.Join(p.StyleNames(), ", ")))
+		}
+	}
+	return msgs
+}
+
+// unusedClasses returns, sorted, the style classes no component lists and
+// data (when given) selects nowhere.
+func (p *Preset) unusedClasses(data *DataSpec) []string {
+	used := make(map[string]bool)
+	mark := func(names []string) {
+		for _, name := range names {
+			used[name] = true
+		}
+	}
+	for _, c := range p.Components {
+		mark(c.Classes)
+		mark(c.Defaults.Classes)
+	}
+	if data != nil {
+		for _, d := range data.Components {
+			mark(d.Classes)
+		}
+		for _, l := range data.Locales {
+			for _, d := range l.Components {
+				mark(d.Classes)
+			}
+		}
+	}
+	var unused []string
+	for _, name := range p.StyleNames() {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,113 @@
+// compare.go — Pixel comparison of rendered images, for visual regression
+// checks of presets.
+package template
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// CompareOptions controls CompareImages. The zero value asks for an exact
+// match.
+type CompareOptions struct {
+	// Tolerance is the largest difference in any one channel (0–255) for
+	// which two pixels still count as equal. Small values absorb
+	// antialiasing shifts between font rasterizer versions.
+	Tolerance uint8
+
+	// MaxDiffPixels is how many pixels may differ beyond Tolerance with
+	// the images still matching.
+	MaxDiffPixels int
+
+	// Heatmap asks for ImageDiff.Heatmap.
+	Heatmap bool
+}
+
+// ImageDiff is the result of CompareImages.
+type ImageDiff struct {
+	Match      bool  // at most MaxDiffPixels pixels differ beyond Tolerance
+	DiffPixels int   // pixels differing beyond Tolerance
+	MaxDelta   uint8 // largest channel difference found
+
+	// Heatmap, if requested, is a dimmed grayscale copy of a with pixels
+	// differing beyond Tolerance in red and smaller differences in yellow,
+	// brighter for larger differences.
+	Heatmap *image.RGBA
+}
+
+// CompareImages compares a and b pixel by pixel, both taken from their
+// top-left corner. Where their sizes differ, the area covered by only one
+// of them counts as differing at full strength, so differently sized
+// images never match unless MaxDiffPixels allows for it.
+func CompareImages(a, b image.Image, opts CompareOptions) ImageDiff {
+	ra, rb := toRGBA(a), toRGBA(b)
+	aw, ah := ra.Bounds().Dx(), ra.Bounds().Dy()
+	bw, bh := rb.Bounds().Dx(), rb.Bounds().Dy()
+	w, h := max(aw, bw), max(ah, bh)
+
+	var diff ImageDiff
+	if opts.Heatmap {
+		diff.Heatmap = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	for y := range h {
+		for x := range w {
+			var delta uint8
+			inA, inB := x < aw && y < ah, x < bw && y < bh
+			if inA && inB {
+				delta = pixelDelta(ra.Pix[ra.PixOffset(x, y):], rb.Pix[rb.PixOffset(x, y):])
+			} else {
+				delta = 255
+			}
+			diff.MaxDelta = max(diff.MaxDelta, delta)
+			if delta > opts.Tolerance {
+				diff.DiffPixels++
+			}
+			if diff.Heatmap != nil {
+				diff.Heatmap.SetRGBA(x, y, heatColor(ra, x, y, inA, delta, opts.Tolerance))
+			}
+		}
+	}
+	diff.Match = diff.DiffPixels <= opts.MaxDiffPixels
+	return diff
+}
+
+// toRGBA returns img as an *image.RGBA whose bounds start at (0, 0),
+// converting only when needed.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// pixelDelta is the largest channel difference between two RGBA pixels.
+func pixelDelta(p, q []uint8) uint8 {
+	var d uint8
+	for i := range 4 {
+		if p[i] > q[i] {
+			d = max(d, p[i]-q[i])
+		} else {
+			d = max(d, q[i]-p[i])
+		}
+	}
+	return d
+}
+
+// heatColor is a heatmap pixel: differences over a dimmed grayscale of a.
+func heatColor(a *image.RGBA, x, y int, inA bool, delta, tolerance uint8) color.RGBA {
+	switch {
+	case delta > tolerance:
+		return color.RGBA{R: 128 + delta/2, A: 255}
+	case delta > 0:
+		v := 128 + delta/2
+		return color.RGBA{R: v, G: v, A: 255}
+	case !inA:
+		return color.RGBA{A: 255}
+	}
+	gray := color.GrayModel.Convert(a.RGBAAt(x, y)).(color.Gray).Y / 3
+	return color.RGBA{R: gray, G: gray, B: gray, A: 255}
+}
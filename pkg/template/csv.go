@@ -0,0 +1,122 @@
+// csv.go — Tabular (CSV) data sources converted to DataSpec records.
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DataRecord is one row of a tabular data source converted to a DataSpec.
+type DataRecord struct {
+	Row    int               // 1-based data row number (header excluded)
+	Fields map[string]string // raw cell values keyed by column header
+	Data   *DataSpec
+}
+
+// ParseDataMapping parses a "column=path,column=path" mapping string into
+// column → data path pairs. Each path is checked with ValidateDataPath.
+func ParseDataMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		col, path, ok := strings.Cut(pair, "=")
+		col, path = strings.TrimSpace(col), strings.TrimSpace(path)
+		if !ok || col == "" || path == "" {
+			return nil, fmt.Errorf("invalid mapping %q: expected column=path", pair)
+		}
+		if err := ValidateDataPath(path); err != nil {
+			return nil, fmt.Errorf("mapping for column %q: %w", col, err)
+		}
+		mapping[col] = path
+	}
+	return mapping, nil
+}
+
+// LoadCSVData reads a CSV with a mandatory header row and converts each data
+// row into a DataSpec by assigning cells to data paths via SetDataPath.
+// If mapping is empty, columns whose header is itself a data path
+// ("components.…") are used directly. Empty cells are skipped so the preset
+// default stays in effect. A leading UTF-8 BOM is ignored.
+func LoadCSVData(r io.Reader, mapping map[string]string) ([]DataRecord, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("read CSV: missing header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	// Resolve column index → data path.
+	paths := make(map[int]string)
+	if len(mapping) == 0 {
+		for i, h := range header {
+			if strings.HasPrefix(h, "components.") {
+				if err := ValidateDataPath(h); err != nil {
+					return nil, fmt.Errorf("CSV column %q: %w", h, err)
+				}
+				paths[i] = h
+			}
+		}
+	} else {
+		index := make(map[string]int, len(header))
+		for i, h := range header {
+			index[h] = i
+		}
+		for col, path := range mapping {
+			i, ok := index[col]
+			if !ok {
+				return nil, fmt.Errorf("mapped column %q not found in CSV header %v", col, header)
+			}
+			paths[i] = path
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no CSV columns map to data paths")
+	}
+
+	var records []DataRecord
+	for row := 1; ; row++ {
+		cells, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV row %d: %w", row, err)
+		}
+
+		rec := DataRecord{
+			Row:    row,
+			Fields: make(map[string]string, len(header)),
+			Data:   &DataSpec{Components: make(map[string]ComponentData)},
+		}
+		for i, cell := range cells {
+			rec.Fields[header[i]] = cell
+			path, ok := paths[i]
+			if !ok || cell == "" {
+				continue
+			}
+			if err := SetDataPath(rec.Data, path, cell); err != nil {
+				return nil, fmt.Errorf("CSV row %d, column %q: %w", row, header[i], err)
+			}
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLoadCSVData checks quoting, a leading BOM, empty cells and rows with
+// the wrong number of cells.
+func TestLoadCSVData(t *testing.T) {
+	preset := &Preset{Components: []Component{
+		{ID: "card", Width: 1, Height: 1, Defaults: ComponentData{Title: "Default title"}},
+		{ID: "note", Width: 1, Height: 1, Defaults: ComponentData{Title: "Default note"}},
+	}}
+	if err := preset.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	const header = "components.card.title,components.note.title\n"
+
+	tests := []struct {
+		name    string
+		csv     string
+		mapping map[string]string
+		title   string // card's and note's titles in the first row, merged onto the preset
+		note    string
+		err     string // "" for success
+	}{
+		{"plain", header + "Hello,World\n", nil, "Hello", "World", ""},
+		{"quoted comma", header + `"Hello, there","Say ""hi"""` + "\n", nil, "Hello, there", `Say "hi"`, ""},
+		{"quoted newline", header + "\"Line one\nLine two\",x\n", nil, "Line one\nLine two", "x", ""},
+		{"BOM", "\ufeff" + header + "Hello,World\n", nil, "Hello", "World", ""},
+		{"BOM with mapping", "\ufeffName,Role\nAda,Engineer\n", map[string]string{"Name": "components.card.title"}, "Ada", "Default note", ""},
+		{"empty cell", header + ",World\n", nil, "Default title", "World", ""},
+		{"empty quoted cell", header + `"",""` + "\n", nil, "Default title", "Default note", ""},
+		{"short row", header + "Hello\n", nil, "", "", "wrong number of fields"},
+		{"long row", header + "Hello,World,extra\n", nil, "", "", "wrong number of fields"},
+		{"bare quote", header + `Hel"lo,World` + "\n", nil, "", "", `bare " in non-quoted-field`},
+		{"no header", "", nil, "", "", "missing header row"},
+		{"no mapped columns", "Name\nAda\n", nil, "", "", "no CSV columns map"},
+		{"unknown mapped column", header + "a,b\n", map[string]string{"Name": "components.card.title"}, "", "", `mapped column "Name" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := LoadCSVData(strings.NewReader(tt.csv), tt.mapping)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Row != 1 {
+				t.Fatalf("records %+v, want row 1 only", records)
+			}
+			merged := MergeData(preset, records[0].Data)
+			if len(merged) != 2 {
+				t.Fatalf("%d components merged, want 2", len(merged))
+			}
+			if title, note := merged[0].Data.Title, merged[1].Data.Title; title != tt.title || note != tt.note {
+				t.Errorf("titles %q, %q; want %q, %q", title, note, tt.title, tt.note)
+			}
+		})
+	}
+}
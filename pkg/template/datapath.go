@@ -0,0 +1,183 @@
+// datapath.go — Dot-path assignment into DataSpec (e.g. "components.title.title").
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// errInvalidValue marks a well-formed path whose value failed type conversion.
+var errInvalidValue = errors.New("invalid value")
+
+// pathSegment is one dot-separated element of a data path, with an optional
+// list index ("items[2]" → name "items", index 2). index is -1 when absent.
+type pathSegment struct {
+	name  string
+	index int
+}
+
+// SetDataPath assigns value to the field addressed by a dot path such as
+// "components.title.title", "components.list.items[0].text" or
+// "components.card.style.fontSize". Missing components, style blocks and list
+// entries are created on demand, and value is converted to the field's type.
+func SetDataPath(spec *DataSpec, path, value string) error {
+	segs, err := parseDataPath(path)
+	if err != nil {
+		return err
+	}
+	if len(segs) < 3 || segs[0].name != "components" || segs[0].index >= 0 || segs[1].index >= 0 {
+		return fmt.Errorf("invalid data path %q: expected components.<id>.<field>", path)
+	}
+
+	if spec.Components == nil {
+		spec.Components = make(map[string]ComponentData)
+	}
+
+	id := segs[1].name
+	cd := spec.Components[id]
+	if err := setDataField(reflect.ValueOf(&cd).Elem(), segs[2:], value); err != nil {
+		return fmt.Errorf("set %s: %w", path, err)
+	}
+	spec.Components[id] = cd
+	return nil
+}
+
+// ValidateDataPath reports whether path is well-formed and addresses a known field,
+// without modifying anything.
+func ValidateDataPath(path string) error {
+	var scratch DataSpec
+	err := SetDataPath(&scratch, path, "")
+	if errors.Is(err, errInvalidValue) {
+		return nil // path is fine; the empty probe value just doesn't convert
+	}
+	return err
+}
+
+// parseDataPath splits a dot path into segments.
+func parseDataPath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty data path")
+	}
+
+	parts := strings.Split(path, ".")
+	segs := make([]pathSegment, 0, len(parts))
+	for _, p := range parts {
+		seg := pathSegment{name: p, index: -1}
+		if i := strings.IndexByte(p, '['); i >= 0 {
+			if !strings.HasSuffix(p, "]") {
+				return nil, fmt.Errorf("invalid data path %q: unterminated index in %q", path, p)
+			}
+			n, err := strconv.Atoi(p[i+1 : len(p)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid data path %q: bad index in %q", path, p)
+			}
+			seg.name, seg.index = p[:i], n
+		}
+		if seg.name == "" {
+			return nil, fmt.Errorf("invalid data path %q: empty segment", path)
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// setDataField walks segs through the struct v (matching JSON field names)
+// and assigns the converted value at the leaf.
+func setDataField(v reflect.Value, segs []pathSegment, value string) error {
+	seg := segs[0]
+
+	f, ok := fieldByJSONName(v, seg.name)
+	if !ok {
+		return fmt.Errorf("unknown field %q", seg.name)
+	}
+
+	if seg.index >= 0 {
+		if f.Kind() != reflect.Slice {
+			return fmt.Errorf("field %q is not a list", seg.name)
+		}
+		if seg.index >= f.Len() {
+			grown := reflect.MakeSlice(f.Type(), seg.index+1, seg.index+1)
+			reflect.Copy(grown, f)
+			f.Set(grown)
+		}
+		f = f.Index(seg.index)
+	}
+
+	if len(segs) == 1 {
+		return assignString(f, value)
+	}
+
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		f = f.Elem()
+	}
+	if f.Kind() != reflect.Struct {
+		return fmt.Errorf("field %q has no sub-fields", seg.name)
+	}
+	return setDataField(f, segs[1:], value)
+}
+
+// fieldByJSONName finds the struct field whose json tag matches name.
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// assignString converts s to f's type and stores it.
+func assignString(f reflect.Value, s string) error {
+	// Types with their own JSON form, such as Radius and LineHeight, take
+	// a number or their string form ("full", "28px").
+	if u, ok := f.Addr().Interface().(json.Unmarshaler); ok && f.Kind() != reflect.Pointer {
+		raw := []byte(s)
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			raw, _ = json.Marshal(s)
+		}
+		if err := u.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("%w %q: %v", errInvalidValue, s, err)
+		}
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.Pointer:
+		p := reflect.New(f.Type().Elem())
+		if err := assignString(p.Elem(), s); err != nil {
+			return err
+		}
+		f.Set(p)
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%w %q: expected true/false", errInvalidValue, s)
+		}
+		f.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%w %q: expected integer", errInvalidValue, s)
+		}
+		f.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%w %q: expected number", errInvalidValue, s)
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("field of type %s cannot be set from a string", f.Type())
+	}
+	return nil
+}
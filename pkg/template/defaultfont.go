@@ -0,0 +1,18 @@
+//go:build !customfont
+
+// defaultfont.go — The embedded default font family: Go Regular and Go Bold.
+//
+// Building with -tags customfont embeds a house font instead (see
+// defaultfont_custom.go); SetDefaultFont replaces it at run time.
+package template
+
+import (
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// embeddedFontFamily returns the TTF data of the default regular and bold
+// fonts.
+func embeddedFontFamily() (regular, bold []byte) {
+	return goregular.TTF, gobold.TTF
+}
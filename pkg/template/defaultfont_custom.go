@@ -0,0 +1,21 @@
+//go:build customfont
+
+// defaultfont_custom.go — A house font family embedded at build time.
+//
+// Put regular.ttf (required) and bold.ttf (optional) in
+// pkg/template/customfont/ and build with -tags customfont; they replace
+// Go Regular and Go Bold as the default family.
+package template
+
+import "embed"
+
+//go:embed customfont/*.ttf
+var customFont embed.FS
+
+// embeddedFontFamily returns the TTF data of customfont/regular.ttf and, if
+// present, customfont/bold.ttf.
+func embeddedFontFamily() (regular, bold []byte) {
+	regular, _ = customFont.ReadFile("customfont/regular.ttf")
+	bold, _ = customFont.ReadFile("customfont/bold.ttf")
+	return regular, bold
+}
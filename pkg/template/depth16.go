@@ -0,0 +1,212 @@
+// depth16.go — 16-bit rendering for print work (SetDepth16): a 16-bit
+// background or sticker keeps its depth instead of being truncated to an
+// 8-bit canvas.
+package template
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+)
+
+// SetDepth16 makes RenderPresetImage render on an *image.RGBA64 canvas.
+// Images, the preset background and the compositing of everything else
+// are done at 16 bits per channel; colors, borders and text are drawn at
+// 8, their colors having no more precision than that, and so is a
+// backdrop blur. RenderPreset, RenderPresetContext and RenderPresetInto,
+// which return an *image.RGBA, are not affected.
+func (r *Renderer) SetDepth16(on bool) {
+	r.depth16 = on
+}
+
+// Depth16 reports whether SetDepth16 is on.
+func (r *Renderer) Depth16() bool {
+	return r.depth16
+}
+
+// RenderPresetImage is RenderPresetContext at the renderer's depth: it
+// returns an *image.RGBA64 when SetDepth16 is on, else an *image.RGBA.
+// PNG output of a 16-bit image keeps its depth.
+func (r *Renderer) RenderPresetImage(ctx context.Context, preset *Preset, components []ResolvedComponent) (image.Image, error) {
+	if !r.depth16 {
+		return r.RenderPresetContext(ctx, preset, components)
+	}
+	if err := r.beginRender(preset, components); err != nil {
+		return nil, err
+	}
+	rect := image.Rect(0, 0, preset.Canvas.Width, preset.Canvas.Height)
+	img := image.NewRGBA64(rect)
+	if err := r.drawPresetBackground64(img, preset); err != nil {
+		return nil, err
+	}
+	r.lap(phaseBackground)
+
+	// What is drawn at 8 bits goes on a transparent layer that is then
+	// composited onto the canvas, and cleared, before the next step.
+	layer := image.NewRGBA(rect)
+	for _, comp := range components {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		r.startComponentProfile(comp.ID, start)
+		if err := r.drawComponent64(img, layer, comp); err != nil {
+			return nil, &ComponentError{ID: comp.ID, Err: err}
+		}
+		r.endComponentProfile(start)
+		logger().Debug("component rendered", "id", comp.ID, "elapsed", time.Since(start))
+	}
+	r.endProfile()
+	return img, nil
+}
+
+// drawPresetBackground64 is drawPresetBackground on a 16-bit canvas.
+func (r *Renderer) drawPresetBackground64(img *image.RGBA64, preset *Preset) error {
+	if preset.Background.Type == "transparent" {
+		return nil // the new canvas is already clear
+	}
+	if preset.Background.Type == "image" && preset.Background.Source != "" {
+		bgImg, err := r.resolveImage("", preset.Background.Source, img.Bounds().Size(), "stretch")
+		if err == nil {
+			drawFit64(img, bgImg, "stretch") // onto the new, empty canvas
+			return nil
+		}
+		if err := r.missingAsset("", preset.Background.Source, err, "could not load background image %q, using color: %v"); err != nil {
+			return err
+		}
+	}
+	c := color.NRGBA(parseHexColorAlpha(preset.Background.Color))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	return nil
+}
+
+// drawComponent64 is drawComponent on a 16-bit canvas, with layer, which
+// must be transparent, for the steps drawn at 8 bits.
+func (r *Renderer) drawComponent64(img *image.RGBA64, layer *image.RGBA, comp ResolvedComponent) error {
+	box, ok := boxOf(comp, img.Bounds())
+	if !ok {
+		return nil
+	}
+	if comp.Style.MaskImage != "" {
+		mask, err := r.componentMask(comp, box)
+		r.lap(phaseImage)
+		if err != nil {
+			return err
+		}
+		if mask != nil {
+			// A masked component is drawn at 8 bits, then masked and
+			// composited at 16.
+			masked := image.NewRGBA(box.bounds)
+			if under := backdropArea(comp, box, img.Bounds()); !under.Empty() {
+				tmp := image.NewRGBA(under)
+				draw.Draw(tmp, under, img, under.Min, draw.Src)
+				drawBackdrop(masked, tmp, comp, box)
+			}
+			if err := r.drawComponentBody(masked, img, comp, box); err != nil {
+				return err
+			}
+			drawMasked64(img, masked, mask)
+			r.lap(phaseImage)
+			return nil
+		}
+	}
+
+	// 0. Backdrop, blurred on an 8-bit copy of the area it reads.
+	if area := backdropArea(comp, box, img.Bounds()); !area.Empty() {
+		tmp := image.NewRGBA(area)
+		draw.Draw(tmp, area, img, area.Min, draw.Src)
+		drawBackdrop(tmp, tmp, comp, box)
+		for y := box.bounds.Min.Y; y < box.bounds.Max.Y; y++ {
+			x0, x1 := roundedSpan(box.bounds, box.radius, y)
+			for x := x0; x < x1; x++ {
+				img.Set(x, y, tmp.RGBAAt(x, y))
+			}
+		}
+	}
+
+	// 1. Container background.
+	drawContainer(layer, comp, box)
+	compositeLayer(img, layer)
+	r.lap(phaseBackground)
+
+	// 2. Background image, at 16 bits.
+	src, fit, err := r.componentImage(comp, box)
+	switch {
+	case err != nil:
+		return err
+	case src != nil:
+		drawFit64(img.SubImage(box.bounds).(*image.RGBA64), src, fit)
+	case comp.Style.BackgroundImage != "" && r.showMissing:
+		r.drawMissingAsset(layer.SubImage(box.bounds).(*image.RGBA), comp.Style.BackgroundImage)
+	}
+	r.lap(phaseImage)
+
+	// 3–4. Border and text; compositing them counts as text.
+	comp = r.resolveAutoColors(comp, box, img, layer)
+	err = r.drawComponentFront(layer, comp, box)
+	compositeLayer(img, layer)
+	r.lap(phaseText)
+	return err
+}
+
+// compositeLayer draws layer over dst at 16 bits and clears it. Both are
+// premultiplied and have the same bounds.
+func compositeLayer(dst *image.RGBA64, layer *image.RGBA) {
+	b := dst.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := layer.Pix[layer.PixOffset(b.Min.X, y):layer.PixOffset(b.Max.X, y)]
+		d := dst.Pix[dst.PixOffset(b.Min.X, y):]
+		for i, j := 0, 0; i < len(src); i, j = i+4, j+8 {
+			if src[i+3] == 0 {
+				continue
+			}
+			inv := 0xffff - uint32(src[i+3])*0x101
+			for k := range 4 {
+				v := uint32(src[i+k])*0x101 + (uint32(d[j+2*k])<<8|uint32(d[j+2*k+1]))*inv/0xffff
+				d[j+2*k], d[j+2*k+1] = uint8(v>>8), uint8(v)
+				src[i+k] = 0
+			}
+		}
+	}
+}
+
+// blendPremul64 is blendPremul on a 16-bit canvas.
+func blendPremul64(img *image.RGBA64, x, y int, c color.RGBA64) {
+	if !(image.Point{x, y}.In(img.Rect)) || c.A == 0 {
+		return
+	}
+	if c.A == 0xffff {
+		img.SetRGBA64(x, y, c)
+		return
+	}
+	d := img.RGBA64At(x, y)
+	inv := 0xffff - uint32(c.A)
+	img.SetRGBA64(x, y, color.RGBA64{
+		R: uint16(uint32(c.R) + uint32(d.R)*inv/0xffff),
+		G: uint16(uint32(c.G) + uint32(d.G)*inv/0xffff),
+		B: uint16(uint32(c.B) + uint32(d.B)*inv/0xffff),
+		A: uint16(uint32(c.A) + uint32(d.A)*inv/0xffff),
+	})
+}
+
+// drawFit64 is drawFit on a 16-bit canvas, reading src at full depth.
+func drawFit64(dst *image.RGBA64, src image.Image, fit string) {
+	at := rgba64Sampler(src)
+	fitPixels(dst.Bounds(), src.Bounds(), fit, func(x, y, sx, sy int) {
+		blendPremul64(dst, x, y, at(sx, sy))
+	})
+}
+
+// rgba64Sampler returns a function reading src's pixels as premultiplied
+// color.RGBA64. The standard library's image types read them directly.
+func rgba64Sampler(src image.Image) func(x, y int) color.RGBA64 {
+	if s, ok := src.(image.RGBA64Image); ok {
+		return s.RGBA64At
+	}
+	return func(x, y int) color.RGBA64 {
+		r, g, b, a := src.At(x, y).RGBA()
+		return color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}
+	}
+}
@@ -0,0 +1,62 @@
+// errors.go — Error categories callers can branch on.
+package template
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInput matches (via errors.Is) any *InputError: a preset, data, or CSV
+// file that is missing or cannot be parsed, as opposed to a render failure.
+var ErrInput = errors.New("invalid input file")
+
+// InputError reports a problem with a user-supplied input file.
+type InputError struct {
+	Path string // offending file
+	Err  error  // full description, already mentioning Path
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+
+func (e *InputError) Unwrap() error { return e.Err }
+
+func (e *InputError) Is(target error) bool { return target == ErrInput }
+
+// ErrBundleTooLarge matches (via errors.Is) a bundle error for an archive
+// over its BundleLimits: too many entries, a file too large, or too much
+// in total.
+var ErrBundleTooLarge = errors.New("bundle too large")
+
+// bundleLimitError is a readZip error that matches ErrBundleTooLarge.
+type bundleLimitError struct{ msg string }
+
+func (e *bundleLimitError) Error() string { return e.msg }
+
+func (e *bundleLimitError) Is(target error) bool { return target == ErrBundleTooLarge }
+
+// ComponentError reports a render failure attributable to one component.
+type ComponentError struct {
+	ID  string
+	Err error
+}
+
+func (e *ComponentError) Error() string { return "component " + e.ID + ": " + e.Err.Error() }
+
+func (e *ComponentError) Unwrap() error { return e.Err }
+
+// ErrMissingAsset matches (via errors.Is) any *AssetError.
+var ErrMissingAsset = errors.New("missing asset")
+
+// AssetError reports an image or font a preset references that could not
+// be loaded. Renders substitute a fallback and warn instead, unless
+// Renderer.SetStrictAssets is on.
+type AssetError struct {
+	Ref string // the path or asset ID as the preset gives it
+	Err error
+}
+
+func (e *AssetError) Error() string { return fmt.Sprintf("asset %q: %v", e.Ref, e.Err) }
+
+func (e *AssetError) Unwrap() error { return e.Err }
+
+func (e *AssetError) Is(target error) bool { return target == ErrMissingAsset }
@@ -0,0 +1,225 @@
+// exif.go — JPEG metadata the decoder ignores: the EXIF orientation, which
+// phone photos rely on to display upright, and the ICC color profile.
+package template
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// decodeImage decodes PNG or JPEG data, turning a JPEG upright according
+// to its EXIF orientation.
+func decodeImage(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "jpeg" {
+		return img, format, err
+	}
+	if o := readJPEGMeta(data).orientation; o > 1 && o <= 8 {
+		logger().Debug("applying EXIF orientation", "orientation", o)
+		img = orient(img, o)
+	}
+	return img, format, nil
+}
+
+// jpegMeta is what readJPEGMeta finds in a JPEG's APP segments.
+type jpegMeta struct {
+	orientation int    // EXIF tag 0x0112, 1–8; 0 when absent
+	icc         []byte // ICC profile, its APP2 chunks joined in order
+}
+
+// readJPEGMeta scans the segments before the image data for an EXIF
+// orientation and an ICC profile. Malformed segments are skipped.
+func readJPEGMeta(data []byte) jpegMeta {
+	var meta jpegMeta
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return meta
+	}
+	chunks := map[byte][]byte{}
+	for p := 2; p+4 <= len(data) && data[p] == 0xFF; {
+		marker := data[p+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if n < 2 || p+2+n > len(data) {
+			break
+		}
+		seg := data[p+4 : p+2+n]
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")):
+			meta.orientation = exifOrientation(seg[6:])
+		case marker == 0xE2 && bytes.HasPrefix(seg, []byte("ICC_PROFILE\x00")) && len(seg) > 14:
+			chunks[seg[12]] = seg[14:] // sequence number (from 1), chunk count, data
+		}
+		p += 2 + n
+	}
+	seqs := make([]int, 0, len(chunks))
+	for seq := range chunks {
+		seqs = append(seqs, int(seq))
+	}
+	sort.Ints(seqs)
+	for _, seq := range seqs {
+		meta.icc = append(meta.icc, chunks[byte(seq)]...)
+	}
+	return meta
+}
+
+// exifOrientation reads the orientation tag from IFD0 of a TIFF-format
+// EXIF block, or returns 0.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := range count {
+		e := ifd + 2 + 12*i
+		if e+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[e:]) == 0x0112 && order.Uint16(tiff[e+2:]) == 3 { // orientation, SHORT
+			return int(order.Uint16(tiff[e+8:]))
+		}
+	}
+	return 0
+}
+
+// orient returns src transformed for EXIF orientation o (2–8): mirrored,
+// rotated, or both, so that it displays upright.
+func orient(src image.Image, o int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	at := rgbaSampler(src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			// (sx, sy) is the source pixel shown at (x, y).
+			var sx, sy int
+			switch o {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotate 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90° counterclockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			dst.SetRGBA(x, y, at(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// sRGBPrimaries are the red, green and blue colorant XYZ values of an
+// sRGB ICC profile, adapted to D50 as ICC profiles store them.
+var sRGBPrimaries = [3][3]float64{
+	{0.4361, 0.2225, 0.0139},
+	{0.3851, 0.7169, 0.0971},
+	{0.1431, 0.0606, 0.7141},
+}
+
+// nonSRGBProfile returns the description of data's ICC profile if it is
+// an RGB profile whose primaries are not sRGB's (Display P3, Adobe RGB,
+// ...), or "". Profiles without primaries, such as gray ones, pass.
+// Only JPEG profiles are read.
+func nonSRGBProfile(data []byte) string {
+	icc := readJPEGMeta(data).icc
+	if len(icc) < 132 || string(icc[16:20]) != "RGB " {
+		return ""
+	}
+	tags := iccTags(icc)
+	for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		t := tags[sig]
+		if len(t) < 20 || string(t[:4]) != "XYZ " {
+			return ""
+		}
+		for j := range 3 {
+			v := float64(int32(binary.BigEndian.Uint32(t[8+4*j:]))) / 65536
+			if math.Abs(v-sRGBPrimaries[i][j]) > 0.01 {
+				if name := iccDescription(tags["desc"]); name != "" {
+					return name
+				}
+				return "unnamed"
+			}
+		}
+	}
+	return ""
+}
+
+// iccTags maps an ICC profile's tag signatures to their data.
+func iccTags(icc []byte) map[string][]byte {
+	tags := map[string][]byte{}
+	n := int(binary.BigEndian.Uint32(icc[128:]))
+	for i := range n {
+		e := 132 + 12*i
+		if e+12 > len(icc) {
+			break
+		}
+		off, size := int(binary.BigEndian.Uint32(icc[e+4:])), int(binary.BigEndian.Uint32(icc[e+8:]))
+		if off < 0 || size < 0 || off+size > len(icc) {
+			continue
+		}
+		tags[string(icc[e:e+4])] = icc[off : off+size]
+	}
+	return tags
+}
+
+// iccDescription decodes a profile description tag: ICC v2 "desc" (ASCII)
+// or v4 "mluc" (UTF-16, first record).
+func iccDescription(t []byte) string {
+	if len(t) < 12 {
+		return ""
+	}
+	switch string(t[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(t[8:]))
+		if n < 0 || 12+n > len(t) {
+			return ""
+		}
+		return strings.TrimRight(string(t[12:12+n]), "\x00")
+	case "mluc":
+		if len(t) < 28 || binary.BigEndian.Uint32(t[8:]) == 0 {
+			return ""
+		}
+		n, off := int(binary.BigEndian.Uint32(t[20:])), int(binary.BigEndian.Uint32(t[24:]))
+		if n < 0 || off < 0 || off+n > len(t) {
+			return ""
+		}
+		u := make([]uint16, n/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(t[off+2*i:])
+		}
+		return string(utf16.Decode(u))
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package template
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEXIFOrientation loads testdata/exif/orientation-N.jpg, the same
+// 48x32 picture stored as each EXIF orientation value would have a camera
+// store it, and checks that each comes out upright: red, green, blue and
+// yellow quarters from the top left, reading across. It checks the decoded
+// image and a render with it as the background of a canvas twice its size.
+func TestEXIFOrientation(t *testing.T) {
+	quarters := []struct {
+		x, y float64 // center, as a fraction of the size
+		want color.RGBA
+	}{
+		{0.25, 0.25, color.RGBA{0xff, 0, 0, 0xff}},
+		{0.75, 0.25, color.RGBA{0, 0xff, 0, 0xff}},
+		{0.25, 0.75, color.RGBA{0, 0, 0xff, 0xff}},
+		{0.75, 0.75, color.RGBA{0xff, 0xff, 0, 0xff}},
+	}
+	check := func(t *testing.T, what string, img image.Image) {
+		t.Helper()
+		b := img.Bounds()
+		for _, q := range quarters {
+			x, y := b.Min.X+int(q.x*float64(b.Dx())), b.Min.Y+int(q.y*float64(b.Dy()))
+			if got := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); !near(got, q.want, 24) {
+				t.Errorf("%s: pixel %d,%d is %v, want %v", what, x, y, got, q.want)
+			}
+		}
+	}
+
+	for o := 1; o <= 8; o++ {
+		t.Run(fmt.Sprint(o), func(t *testing.T) {
+			path := filepath.Join("testdata", "exif", fmt.Sprintf("orientation-%d.jpg", o))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readJPEGMeta(data).orientation; got != o {
+				t.Fatalf("orientation read as %d, want %d", got, o)
+			}
+
+			img, _, err := decodeImage(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := img.Bounds().Size(); size != image.Pt(48, 32) {
+				t.Fatalf("decoded size %v, want 48x32", size)
+			}
+			check(t, "decoded", img)
+
+			preset := &Preset{
+				Canvas:     Canvas{Width: 96, Height: 64},
+				Background: Background{Type: "image", Source: path},
+			}
+			if err := preset.Normalize(); err != nil {
+				t.Fatal(err)
+			}
+			r, err := NewRenderer("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered, err := r.RenderPreset(preset, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, "rendered", rendered)
+		})
+	}
+}
+
+// near reports whether each channel of a and b is within tolerance.
+func near(a, b color.RGBA, tolerance int) bool {
+	d := func(x, y uint8) bool { return max(int(x)-int(y), int(y)-int(x)) <= tolerance }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
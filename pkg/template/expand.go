@@ -0,0 +1,119 @@
+// expand.go — Opt-in ${env:NAME} / ${file:path} substitution in data values.
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// expandPattern matches ${env:NAME} and ${file:relative/path} references.
+var expandPattern = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// ExpandDataValues replaces ${env:NAME} and ${file:path} references in every
+// string value of spec (titles, item text, style overrides), in the base
+// components and in every locale overlay. File references
+// are resolved relative to baseDir and must not escape it. Unresolvable
+// references are left in place and reported as warnings.
+func ExpandDataValues(spec *DataSpec, baseDir string) []string {
+	if spec == nil {
+		return nil
+	}
+
+	var warnings []string
+	expand := func(s string) string {
+		return expandPattern.ReplaceAllStringFunc(s, func(ref string) string {
+			m := expandPattern.FindStringSubmatch(ref)
+			val, err := resolveExpansion(m[1], m[2], baseDir)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("cannot expand %s: %v — left as-is", ref, err))
+				return ref
+			}
+			return val
+		})
+	}
+
+	expandComponents(spec.Components, expand)
+	for _, name := range spec.LocaleNames() {
+		expandComponents(spec.Locales[name].Components, expand)
+	}
+	return warnings
+}
+
+// expandComponents applies expand to the string values of each entry of
+// comps.
+func expandComponents(comps map[string]ComponentData, expand func(string) string) {
+	for id, cd := range comps {
+		cd.Title = expand(cd.Title)
+		if cd.Items != nil {
+			items := make([]TextItem, len(cd.Items))
+			for i, item := range cd.Items {
+				item.Text = expand(item.Text)
+				items[i] = item
+			}
+			cd.Items = items
+		}
+		if cd.Style != nil {
+			style := *cd.Style
+			expandStringFields(reflect.ValueOf(&style).Elem(), expand)
+			cd.Style = &style
+		}
+		comps[id] = cd
+	}
+}
+
+// resolveExpansion looks up a single env or file reference.
+func resolveExpansion(kind, name, baseDir string) (string, error) {
+	switch kind {
+	case "env":
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return val, nil
+	case "file":
+		path, err := containedPath(baseDir, name)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", fmt.Errorf("unknown source %q", kind)
+}
+
+// containedPath joins rel onto baseDir, rejecting absolute paths and any
+// result that would fall outside baseDir. Symlinks are resolved first, so
+// a link inside baseDir cannot point out of it.
+func containedPath(baseDir, rel string) (string, error) {
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("absolute path %q not allowed", rel)
+	}
+	base, err := filepath.EvalSymlinks(filepath.Clean(baseDir))
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(base, rel))
+	if err != nil {
+		return "", err
+	}
+	if r, err := filepath.Rel(base, path); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path %q escapes %s", rel, base)
+	}
+	return path, nil
+}
+
+// expandStringFields applies fn to every string field of struct v.
+func expandStringFields(v reflect.Value, fn func(string) string) {
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String {
+			f.SetString(fn(f.String()))
+		}
+	}
+}
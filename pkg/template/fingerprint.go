@@ -0,0 +1,135 @@
+// fingerprint.go — Content hashes of renders, so an unchanged render can
+// be recognized without drawing it: for caching, ETags and skipping
+// unchanged batch outputs.
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// fingerprintVersion is hashed first; changing it invalidates every
+// fingerprint, as a change to what a render depends on should.
+const fingerprintVersion = "gostencil-render-1\x00"
+
+// AssetDigests maps asset references, as a preset and its data name them,
+// to a digest of the asset's content. A render fingerprint hashes the
+// digest in place of the reference, so an asset renamed keeps its
+// fingerprint.
+type AssetDigests map[string]string
+
+// AddFiles records the hex SHA-256 of each file in refs that is not
+// already in d. Files that cannot be read are left out; fingerprints then
+// hash the reference itself.
+func (d AssetDigests) AddFiles(refs ...string) {
+	d.AddResolved(nil, refs...)
+}
+
+// AddResolved is AddFiles for references resolve may know, such as a
+// bundle's files: resolve is consulted before the filesystem, as in
+// rendering.
+func (d AssetDigests) AddResolved(resolve AssetResolverFunc, refs ...string) {
+	for _, ref := range refs {
+		if _, ok := d[ref]; ok {
+			continue
+		}
+		if data := resolveAsset(resolve, ref); data != nil {
+			sum := sha256.Sum256(data)
+			d[ref] = hex.EncodeToString(sum[:])
+			continue
+		}
+		f, err := os.Open(ref)
+		if err != nil {
+			continue
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err == nil {
+			d[ref] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+}
+
+// RenderFingerprint hashes what rendering data onto preset depends on: the
+// canvas size, background, font and the components MergeData resolves,
+// with asset references replaced by their digests from assets. Field
+// order, defaults spelled out or left implicit, and data overrides for
+// hidden components make no difference. Renderer settings such as the DPI
+// are not included.
+func RenderFingerprint(preset *Preset, data *DataSpec, assets AssetDigests) string {
+	return FingerprintComponents(preset, MergeData(preset, data), assets)
+}
+
+// FingerprintComponents is RenderFingerprint for components already
+// resolved, and possibly changed since, such as by ExpandTokens.
+func FingerprintComponents(preset *Preset, components []ResolvedComponent, assets AssetDigests) string {
+	doc := struct {
+		Width, Height int
+		Background    Background
+		Font          FontConfig
+		Components    []ResolvedComponent
+	}{preset.Canvas.Width, preset.Canvas.Height, preset.Background, preset.Font, components}
+
+	// A round trip through any sorts map keys and lets the references be
+	// swapped for digests wherever they appear.
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return ""
+	}
+	canon, err := json.Marshal(substituteDigests(v, assets))
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	io.WriteString(h, fingerprintVersion)
+	h.Write(canon)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// ResolvedAssets lists the asset references a render of components reads:
+// the preset's font and background image, and each component's background
+// image, mask image and font, after data overrides. Entries may repeat.
+func ResolvedAssets(preset *Preset, components []ResolvedComponent) []string {
+	var refs []string
+	for _, ref := range []string{preset.Font.Path, preset.Background.Source} {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	for _, c := range components {
+		for _, ref := range []string{c.Style.BackgroundImage, c.Style.MaskImage, c.Style.FontPath} {
+			if ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// substituteDigests replaces every string in v that is a key of assets
+// with its digest.
+func substituteDigests(v any, assets AssetDigests) any {
+	switch v := v.(type) {
+	case string:
+		if d, ok := assets[v]; ok {
+			return "digest:" + d
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = substituteDigests(e, assets)
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = substituteDigests(e, assets)
+		}
+	}
+	return v
+}
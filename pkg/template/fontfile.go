@@ -0,0 +1,99 @@
+// fontfile.go — Font file formats: TrueType, OpenType (CFF), collections,
+// and the web font formats that are recognized only to be refused.
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Font file formats, as FontFormat reports them. Only FontTTF, FontOTF and
+// FontTTC can be rendered.
+const (
+	FontTTF   = "ttf"   // TrueType outlines
+	FontOTF   = "otf"   // CFF (PostScript) outlines
+	FontTTC   = "ttc"   // collection of TrueType or OpenType fonts
+	FontWOFF  = "woff"  // compressed web font
+	FontWOFF2 = "woff2" // Brotli-compressed web font
+)
+
+// FontFormat identifies a font file by its signature, or returns "" when
+// data is not a font.
+func FontFormat(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+		return FontTTF
+	case "OTTO":
+		return FontOTF
+	case "ttcf":
+		return FontTTC
+	case "wOFF":
+		return FontWOFF
+	case "wOF2":
+		return FontWOFF2
+	}
+	return ""
+}
+
+// FontMime returns the MIME type of a FontFormat.
+func FontMime(format string) string {
+	switch format {
+	case FontOTF:
+		return "font/otf"
+	case FontTTC:
+		return "font/collection"
+	case FontWOFF, FontWOFF2:
+		return "font/" + format
+	}
+	return "font/ttf"
+}
+
+// ParseFont parses a TrueType or OpenType font file. From a collection it
+// takes the font whose family or full name is family (ignoring case) when
+// family is set, else the one at index; both are ignored for single fonts.
+// WOFF and WOFF2 files get an error asking for conversion.
+func ParseFont(data []byte, index int, family string) (*opentype.Font, error) {
+	switch format := FontFormat(data); format {
+	case FontWOFF, FontWOFF2:
+		return nil, fmt.Errorf("%s is a web font format, which cannot be rendered: convert the font to TTF or OTF", strings.ToUpper(format))
+	case FontTTC:
+		return parseCollection(data, index, family)
+	}
+	return opentype.Parse(data)
+}
+
+// parseCollection picks one font from a .ttc file, as ParseFont describes.
+func parseCollection(data []byte, index int, family string) (*opentype.Font, error) {
+	c, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	n := c.NumFonts()
+	if family == "" {
+		if index < 0 || index >= n {
+			return nil, fmt.Errorf("font index %d out of range: the collection has %d fonts", index, n)
+		}
+		return c.Font(index)
+	}
+
+	names := make([]string, 0, n)
+	for i := range n {
+		f, err := c.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		fam, _ := f.Name(nil, sfnt.NameIDFamily)
+		full, _ := f.Name(nil, sfnt.NameIDFull)
+		if strings.EqualFold(fam, family) || strings.EqualFold(full, family) {
+			return f, nil
+		}
+		names = append(names, fmt.Sprintf("%q", full))
+	}
+	return nil, fmt.Errorf("no font named %q in the collection (it has %s)", family, strings.Join(names, ", "))
+}
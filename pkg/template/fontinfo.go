@@ -0,0 +1,181 @@
+// fontinfo.go — Inspect the fonts a preset references (names, coverage, availability).
+package template
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// FontReport describes one font reference in a preset.
+type FontReport struct {
+	Use      string          `json:"use"`  // "global", "fallback", or "component:<id>", maybe + " (defaults)" or " (variant <name>)"
+	Path     string          `json:"path"` // as resolved by the loader ("" for embedded)
+	Found    bool            `json:"found"`
+	Embedded bool            `json:"embedded"`
+	Family   string          `json:"family,omitempty"`
+	Style    string          `json:"style,omitempty"`
+	Coverage []RangeCoverage `json:"coverage,omitempty"`
+	Error    string          `json:"error,omitempty"` // why the font cannot be used
+}
+
+// RangeCoverage reports how many code points of a Unicode block have glyphs.
+type RangeCoverage struct {
+	Name    string `json:"name"`
+	Covered int    `json:"covered"`
+	Total   int    `json:"total"`
+}
+
+// unicodeBlock is a named, inclusive code point range.
+type unicodeBlock struct {
+	name   string
+	lo, hi rune
+}
+
+// reportedBlocks are the Unicode blocks checked for font coverage.
+var reportedBlocks = []unicodeBlock{
+	{"Basic Latin", 0x0020, 0x007E},
+	{"Latin-1 Supplement", 0x00A0, 0x00FF},
+	{"Latin Extended-A", 0x0100, 0x017F},
+	{"Latin Extended-B", 0x0180, 0x024F},
+	{"Greek", 0x0370, 0x03FF},
+	{"Cyrillic", 0x0400, 0x04FF},
+	{"Hebrew", 0x0590, 0x05FF},
+	{"Arabic", 0x0600, 0x06FF},
+	{"Devanagari", 0x0900, 0x097F},
+	{"Thai", 0x0E00, 0x0E7F},
+	{"General Punctuation", 0x2000, 0x206F},
+	{"Currency Symbols", 0x20A0, 0x20CF},
+	{"Arrows", 0x2190, 0x21FF},
+	{"Mathematical Operators", 0x2200, 0x22FF},
+	{"Box Drawing", 0x2500, 0x257F},
+	{"Hiragana", 0x3040, 0x309F},
+	{"Katakana", 0x30A0, 0x30FF},
+	{"CJK Unified Ideographs", 0x4E00, 0x9FFF},
+	{"Hangul Syllables", 0xAC00, 0xD7A3},
+	{"Emoji & Pictographs", 0x1F300, 0x1F5FF},
+}
+
+// InspectFonts lists every font the preset references — the global font,
+// the embedded fallback, style class fonts, and per-component fonts (style,
+// defaults.style and variants) — with availability, name-table family/style, and Unicode block
+// coverage.
+// Each distinct file is parsed once.
+func InspectFonts(preset *Preset) []FontReport {
+	return inspectFonts(preset, nil, false)
+}
+
+// InspectFontsWithResolver is InspectFonts for presets whose fonts may be
+// in-memory assets, such as a bundle's: resolve is consulted before the
+// filesystem.
+func InspectFontsWithResolver(preset *Preset, resolve AssetResolverFunc) []FontReport {
+	return inspectFonts(preset, resolve, false)
+}
+
+// inspectFonts is InspectFonts reading through resolve first, if set, and
+// with noFiles reading no files.
+func inspectFonts(preset *Preset, resolve AssetResolverFunc, noFiles bool) []FontReport {
+	cache := make(map[FontConfig]FontReport)
+	inspect := func(use string, fc FontConfig) FontReport {
+		r, ok := cache[fc]
+		if !ok {
+			r = inspectFontFile(fc, resolve, noFiles)
+			cache[fc] = r
+		}
+		r.Use = use
+		return r
+	}
+
+	var reports []FontReport
+	if preset.Font.Path != "" {
+		reports = append(reports, inspect("global", FontConfig{Path: preset.Font.Path, Index: preset.Font.Index, Family: preset.Font.Family}))
+	}
+	reports = append(reports, inspect("fallback", FontConfig{}))
+	for _, name := range preset.StyleNames() {
+		if p := preset.Styles[name].FontPath; p != "" {
+			reports = append(reports, inspect("style:"+name, FontConfig{Path: p}))
+		}
+	}
+
+	for _, c := range preset.Components {
+		if p := c.ownStyle().FontPath; p != "" {
+			reports = append(reports, inspect("component:"+c.ID, FontConfig{Path: p}))
+		}
+		if c.Defaults.Style != nil && c.Defaults.Style.FontPath != "" && c.Defaults.Style.FontPath != c.Style.FontPath {
+			reports = append(reports, inspect("component:"+c.ID+" (defaults)", FontConfig{Path: c.Defaults.Style.FontPath}))
+		}
+		for _, name := range c.VariantNames() {
+			if p := c.Variants[name].FontPath; p != "" && p != c.Style.FontPath {
+				reports = append(reports, inspect("component:"+c.ID+" (variant "+name+")", FontConfig{Path: p}))
+			}
+		}
+	}
+	return reports
+}
+
+// FontProblems returns one message per unusable font in reports, suitable
+// for treating as validation warnings (or errors in strict mode).
+func FontProblems(reports []FontReport) []string {
+	var problems []string
+	for _, r := range reports {
+		if r.Error != "" {
+			problems = append(problems, fmt.Sprintf("%s font %q: %s — default font will be substituted", r.Use, r.Path, r.Error))
+		}
+	}
+	return problems
+}
+
+// inspectFontFile reads and describes the font fc selects; path "" means
+// the default family's regular font. An asset resolve knows takes
+// precedence over a file, as in the renderer; with noFiles there is none.
+func inspectFontFile(fc FontConfig, resolve AssetResolverFunc, noFiles bool) FontReport {
+	path := fc.Path
+	r := FontReport{Path: path, Embedded: path == ""}
+
+	f := defaultFonts().regular
+	data := resolveAsset(resolve, path)
+	if data == nil && path != "" {
+		b, err := readAssetFile(path, noFiles)
+		if err != nil {
+			r.Error = "not found"
+			if !os.IsNotExist(err) {
+				r.Error = err.Error()
+			}
+			return r
+		}
+		data = b
+	}
+	r.Found = true
+
+	if data != nil {
+		var err error
+		if f, err = ParseFont(data, fc.Index, fc.Family); err != nil {
+			r.Error = fmt.Sprintf("unparseable: %v", err)
+			return r
+		}
+	}
+
+	r.Family, _ = f.Name(nil, sfnt.NameIDFamily)
+	r.Style, _ = f.Name(nil, sfnt.NameIDSubfamily)
+	r.Coverage = fontCoverage(f)
+	return r
+}
+
+// fontCoverage counts mapped glyphs per reported block, omitting empty blocks.
+func fontCoverage(f *sfnt.Font) []RangeCoverage {
+	var buf sfnt.Buffer
+	var cov []RangeCoverage
+	for _, b := range reportedBlocks {
+		n := 0
+		for c := b.lo; c <= b.hi; c++ {
+			if gi, err := f.GlyphIndex(&buf, c); err == nil && gi != 0 {
+				n++
+			}
+		}
+		if n > 0 {
+			cov = append(cov, RangeCoverage{Name: b.name, Covered: n, Total: int(b.hi-b.lo) + 1})
+		}
+	}
+	return cov
+}
@@ -1,61 +1,186 @@
-// fonts.go — Font loading with embedded Go Regular fallback.
+// fonts.go — Font loading with an embedded default family (Go Regular and
+// Go Bold unless replaced; see defaultfont.go and SetDefaultFont).
 package template
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"sync"
+	"sync/atomic"
 
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/opentype"
 )
 
-// FontManager loads and caches a parsed OpenType font.
-type FontManager struct {
-	parsed *opentype.Font
+// fontFamily is a regular face and its optional bold companion.
+type fontFamily struct {
+	regular *opentype.Font
+	bold    *opentype.Font // nil: bold text uses regular
 }
 
-// NewFontManager creates a font manager. If customPath is empty or unreadable,
-// the embedded Go Regular font is used as fallback.
-func NewFontManager(customPath string) (*FontManager, error) {
-	data := goregular.TTF // default
+// defaultFamily is the family used when no font file is given or the given
+// one is unusable. It is parsed from the embedded fonts on first use.
+var defaultFamily atomic.Pointer[fontFamily]
 
-	if customPath != "" {
-		if custom, err := os.ReadFile(customPath); err != nil {
-			fmt.Printf("Warning: font %q unavailable, using default: %v\n", customPath, err)
-		} else {
-			data = custom
-		}
+// SetDefaultFont replaces the embedded default font family for renderers
+// created afterwards, so a binary can ship its own house font. regular is
+// required; bold may be nil, and titles then use regular. Existing
+// renderers keep the family they were created with.
+func SetDefaultFont(regular, bold []byte) error {
+	fam, err := parseFamily(regular, bold)
+	if err != nil {
+		return err
 	}
+	defaultFamily.Store(fam)
+	return nil
+}
 
-	parsed, err := opentype.Parse(data)
+// defaultFonts returns the default family, parsing the embedded fonts the
+// first time.
+func defaultFonts() *fontFamily {
+	if fam := defaultFamily.Load(); fam != nil {
+		return fam
+	}
+	fam, err := parseFamily(embeddedFontFamily())
 	if err != nil {
+		panic("template: embedded default font: " + err.Error())
+	}
+	defaultFamily.CompareAndSwap(nil, fam)
+	return defaultFamily.Load()
+}
+
+// parseFamily parses a regular font and an optional bold one.
+func parseFamily(regular, bold []byte) (*fontFamily, error) {
+	if len(regular) == 0 {
+		return nil, errors.New("parse font: no regular font data")
+	}
+	fam := &fontFamily{}
+	var err error
+	if fam.regular, err = ParseFont(regular, 0, ""); err != nil {
 		return nil, fmt.Errorf("parse font: %w", err)
 	}
+	if len(bold) > 0 {
+		if fam.bold, err = ParseFont(bold, 0, ""); err != nil {
+			return nil, fmt.Errorf("parse bold font: %w", err)
+		}
+	}
+	return fam, nil
+}
+
+// FontManager loads and caches a small font family (a regular face and an
+// optional bold companion) and the faces created from it.
+type FontManager struct {
+	family fontFamily
+
+	// fallback records why a requested font file was not used (nil when it
+	// was, or when none was requested).
+	fallback error
+
+	// ref is the reference the family was loaded from, and from how, as
+	// AssetUse reports them; from is "" for a family given as bytes.
+	ref, from string
 
-	return &FontManager{parsed: parsed}, nil
+	mu    sync.Mutex
+	faces map[faceKey]font.Face
 }
 
-// NewFontManagerFromBytes creates a font manager from raw TTF data.
-// If data is nil or empty, the embedded Go Regular font is used.
+// faceKey identifies a cached face by size, DPI and weight.
+type faceKey struct {
+	size, dpi float64
+	bold      bool
+}
+
+// NewFontManager creates a font manager. If customPath is empty or unreadable,
+// the embedded default family is used as fallback. A font file is a family
+// of one: its text is never bold. From a collection the first font is used.
+func NewFontManager(customPath string) (*FontManager, error) {
+	return loadFontManager(FontConfig{Path: customPath}, nil, false)
+}
+
+// loadFontManager is NewFontManager for fc.Path, read through resolve
+// first if set, with fc.Index and fc.Family picking from a collection.
+// With noFiles a path resolve does not know is missing.
+func loadFontManager(fc FontConfig, resolve AssetResolverFunc, noFiles bool) (*FontManager, error) {
+	if data := resolveAsset(resolve, fc.Path); data != nil {
+		return loadedFontManager(data, fc, AssetFromResolver)
+	}
+	if fc.Path == "" {
+		return &FontManager{family: *defaultFonts(), from: AssetEmbedded}, nil
+	}
+	custom, err := readAssetFile(fc.Path, noFiles)
+	if err == nil {
+		return loadedFontManager(custom, fc, AssetFromFile)
+	}
+	logger().Debug("font unavailable, using default", "path", fc.Path, "err", err)
+	return &FontManager{family: *defaultFonts(), fallback: err, ref: fc.Path, from: AssetMissing}, nil
+}
+
+// loadedFontManager is fontManagerFromBytes for the font fc.Path refers
+// to, read from from.
+func loadedFontManager(data []byte, fc FontConfig, from string) (*FontManager, error) {
+	fm, err := fontManagerFromBytes(data, fc)
+	if err == nil && fm.from == "" {
+		fm.ref, fm.from = fc.Path, from
+	}
+	return fm, err
+}
+
+// NewFontManagerFromBytes creates a font manager from raw TTF, OTF or TTC
+// data (the first font of a collection). If data is nil or empty, the
+// embedded default family is used.
 func NewFontManagerFromBytes(data []byte) (*FontManager, error) {
+	return fontManagerFromBytes(data, FontConfig{})
+}
+
+func fontManagerFromBytes(data []byte, fc FontConfig) (*FontManager, error) {
 	if len(data) == 0 {
-		data = goregular.TTF
+		return &FontManager{family: *defaultFonts(), from: AssetEmbedded}, nil
 	}
 
-	parsed, err := opentype.Parse(data)
+	parsed, err := ParseFont(data, fc.Index, fc.Family)
 	if err != nil {
 		return nil, fmt.Errorf("parse font: %w", err)
 	}
 
-	return &FontManager{parsed: parsed}, nil
+	return &FontManager{family: fontFamily{regular: parsed}}, nil
+}
+
+// HasBold reports whether the family has a bold face; without one
+// GetBoldFace returns regular faces.
+func (fm *FontManager) HasBold() bool {
+	return fm.family.bold != nil
 }
 
 // GetFace returns a font.Face at the given size. DPI defaults to 72 if ≤ 0.
+// Faces are cached per size/DPI for the lifetime of the manager.
 func (fm *FontManager) GetFace(size, dpi float64) (font.Face, error) {
-	dpi = max(dpi, 72)
+	return fm.face(size, dpi, false)
+}
 
-	face, err := opentype.NewFace(fm.parsed, &opentype.FaceOptions{
+// GetBoldFace is GetFace for the family's bold face, or its regular face
+// when it has none.
+func (fm *FontManager) GetBoldFace(size, dpi float64) (font.Face, error) {
+	return fm.face(size, dpi, fm.HasBold())
+}
+
+func (fm *FontManager) face(size, dpi float64, bold bool) (font.Face, error) {
+	if dpi <= 0 {
+		dpi = 72
+	}
+	key := faceKey{size, dpi, bold}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if face, ok := fm.faces[key]; ok {
+		logger().Debug("face cache hit", "size", size, "dpi", dpi, "bold", bold)
+		return face, nil
+	}
+
+	parsed := fm.family.regular
+	if bold {
+		parsed = fm.family.bold
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{
 		Size:    size,
 		DPI:     dpi,
 		Hinting: font.HintingFull,
@@ -63,5 +188,10 @@ func (fm *FontManager) GetFace(size, dpi float64) (font.Face, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create font face at %.1fpt: %w", size, err)
 	}
+	if fm.faces == nil {
+		fm.faces = make(map[faceKey]font.Face)
+	}
+	fm.faces[key] = face
+	logger().Debug("face created", "size", size, "dpi", dpi, "bold", bold)
 	return face, nil
 }
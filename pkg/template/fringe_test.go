@@ -0,0 +1,119 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// softSticker is a 40×40 white disc whose edge fades out over 8 pixels.
+// Its transparent pixels carry garbage, the color an editor leaves behind
+// where alpha is zero; straight (non-premultiplied) alpha lets it through
+// to the partly transparent edge when resampled, as a dark or tinted
+// fringe.
+func softSticker(t *testing.T, deep bool, garbage color.NRGBA) []byte {
+	t.Helper()
+	const size = 40
+	var img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	if deep {
+		img = image.NewNRGBA64(image.Rect(0, 0, size, size))
+	} else {
+		img = image.NewNRGBA(image.Rect(0, 0, size, size))
+	}
+	for y := range size {
+		for x := range size {
+			d := math.Hypot(float64(x)+0.5-size/2, float64(y)+0.5-size/2)
+			a := min(max((18-d)/8, 0), 1)
+			c := garbage
+			if a > 0 {
+				c = color.NRGBA{255, 255, 255, uint8(math.Round(a * 255))}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestNRGBAStickerNoFringe draws a soft-edged white NRGBA sticker at its
+// own size, enlarged and reduced, over white and gray backgrounds, at 8
+// and 16 bits. White over a background can only lighten it, so no channel
+// of any pixel may come out below the background's.
+func TestNRGBAStickerNoFringe(t *testing.T) {
+	const preset = `{
+  "canvas": {"width": 240, "height": 120},
+  "background": {"type": "color", "color": "%s"},
+  "font": {},
+  "components": [
+    {"id": "same", "x": 0, "y": 0, "width": 0.16666667, "height": 0.33333333, "style": {"backgroundImage": "sticker.png"}, "defaults": {"visible": true}},
+    {"id": "larger", "x": 0.25, "y": 0, "width": 0.5, "height": 1, "style": {"backgroundImage": "sticker.png"}, "defaults": {"visible": true}},
+    {"id": "smaller", "x": 0.8, "y": 0.5, "width": 0.08333333, "height": 0.16666667, "style": {"backgroundImage": "sticker.png"}, "defaults": {"visible": true}}
+  ]
+}`
+	for _, deep := range []bool{false, true} {
+		for _, garbage := range []color.NRGBA{{0, 0, 0, 0}, {255, 0, 255, 0}} {
+			sticker := softSticker(t, deep, garbage)
+			for _, bg := range []uint8{255, 128} {
+				for _, depth16 := range []bool{false, true} {
+					name := fmt.Sprintf("deep=%v/garbage=%v/bg=%d/depth16=%v", deep, garbage, bg, depth16)
+					t.Run(name, func(t *testing.T) {
+						p, err := DecodePreset(fmt.Appendf(nil, preset, fmt.Sprintf("#%02x%02x%02x", bg, bg, bg)))
+						if err != nil {
+							t.Fatal(err)
+						}
+						renderer, err := NewRendererForFont(p.Font, func(ref string) []byte {
+							if ref == "sticker.png" {
+								return sticker
+							}
+							return nil
+						})
+						if err != nil {
+							t.Fatal(err)
+						}
+						renderer.SetStrictAssets(true)
+						renderer.SetDepth16(depth16)
+						img, err := renderer.RenderPresetImage(context.Background(), p, MergeData(p, nil))
+						if err != nil {
+							t.Fatal(err)
+						}
+
+						low := uint32(bg)*0x101 - 0x101 // one 8-bit step of rounding
+						var bad int
+						b := img.Bounds()
+						for y := b.Min.Y; y < b.Max.Y; y++ {
+							for x := b.Min.X; x < b.Max.X; x++ {
+								r, g, bl, _ := img.At(x, y).RGBA()
+								if r < low || g < low || bl < low {
+									if bad == 0 {
+										t.Errorf("pixel (%d, %d) is %v, darker than the background %d", x, y, img.At(x, y), bg)
+									}
+									bad++
+								}
+							}
+						}
+						if bad > 0 {
+							t.Errorf("%d fringe pixels", bad)
+						}
+						// The sticker is drawn: its centers are white.
+						for _, pt := range []image.Point{{20, 20}, {120, 60}, {200, 70}} {
+							if r, g, bl, _ := img.At(pt.X, pt.Y).RGBA(); r < 0xfe00 || g < 0xfe00 || bl < 0xfe00 {
+								t.Errorf("sticker center %v is %v, want white", pt, img.At(pt.X, pt.Y))
+							}
+						}
+					})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// glyph.go — Glyph-at-a-time text drawing.
+//
+// font.Drawer draws a string along a horizontal baseline. Text that needs
+// each glyph placed on its own — rotated along an arc, or spaced apart —
+// is laid out as a glyphRun and drawn with drawGlyph.
+package template
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+// glyphRun is one line of text split into glyphs, with each glyph's pen
+// position and advance in pixels, kerning applied. Extra spacing between
+// glyphs is added at draw time; see at.
+type glyphRun struct {
+	face  font.Face
+	runes []rune
+	pos   []float64 // pen x of each glyph from the start of the run
+	adv   []float64 // advance of each glyph
+	width float64   // natural width: the pen position after the last glyph
+}
+
+// newGlyphRun lays out text in face.
+func newGlyphRun(face font.Face, text string) glyphRun {
+	g := glyphRun{face: face}
+	var pen fixed.Int26_6
+	prev := rune(-1)
+	for _, r := range text {
+		if prev >= 0 {
+			pen += face.Kern(prev, r)
+		}
+		adv, _ := face.GlyphAdvance(r)
+		g.runes = append(g.runes, r)
+		g.pos = append(g.pos, fix2f(pen))
+		g.adv = append(g.adv, fix2f(adv))
+		pen += adv
+		prev = r
+	}
+	g.width = fix2f(pen)
+	return g
+}
+
+// at is glyph i's pen position with spacing added between glyphs.
+func (g glyphRun) at(i int, spacing float64) float64 {
+	return g.pos[i] + float64(i)*spacing
+}
+
+// widthWith is the run's width with spacing added between glyphs.
+func (g glyphRun) widthWith(spacing float64) float64 {
+	if len(g.runes) < 2 {
+		return g.width
+	}
+	return g.width + float64(len(g.runes)-1)*spacing
+}
+
+// drawGlyph draws r from face in color c onto dst through m, which maps
+// glyph space (the pen at the origin, baseline along +x, y down) to dst.
+// Glyphs are resampled bilinearly, so rotated text stays antialiased.
+func drawGlyph(dst draw.Image, face font.Face, r rune, c color.Color, m f64.Aff3) {
+	dr, mask, maskp, _, ok := face.Glyph(fixed.Point26_6{}, r)
+	if !ok || dr.Empty() {
+		return
+	}
+	// A transparent margin keeps the resampler from smearing edge pixels.
+	src := image.NewRGBA(dr.Inset(-1))
+	draw.DrawMask(src, dr, image.NewUniform(c), image.Point{}, mask, maskp, draw.Over)
+	xdraw.BiLinear.Transform(dst, m, src, src.Bounds(), xdraw.Over, nil)
+}
+
+func fix2f(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
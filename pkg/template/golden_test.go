@@ -0,0 +1,42 @@
+package template_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xob0t/GoStencil/internal/rendertest"
+)
+
+// TestGolden renders each testdata/golden/NAME/preset.json, with the
+// data.json beside it if there is one, and compares it with NAME.png.
+// Run with -update after an intended rendering change.
+//
+// The pill and circle cases lock the "full" corner radius shapes exactly;
+// the others allow rasterizer drift.
+func TestGolden(t *testing.T) {
+	exact := map[string]bool{"pill": true, "circle": true}
+	presets, err := filepath.Glob(filepath.Join(rendertest.GoldenDir, "*", "preset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(presets) == 0 {
+		t.Fatal("no golden presets")
+	}
+	for _, preset := range presets {
+		dir := filepath.Dir(preset)
+		name := filepath.Base(dir)
+		t.Run(name, func(t *testing.T) {
+			data := filepath.Join(dir, "data.json")
+			if _, err := os.Stat(data); err != nil {
+				data = ""
+			}
+			img := rendertest.Render(t, preset, data)
+			opts := rendertest.Perceptual
+			if exact[name] {
+				opts = rendertest.Exact
+			}
+			rendertest.Check(t, name, img, opts)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// grid.go — Compose several images into one labeled grid, for contact
+// sheets and side-by-side review of variants.
+package template
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Grid defaults, those of `gostencil preview` sheets.
+const (
+	DefaultGridGap        = 16
+	DefaultGridBackground = "#1a1a2e"
+	DefaultGridLabelColor = "#e0e0e0"
+	DefaultGridLabelSize  = 14
+)
+
+// ErrGridTooLarge is returned by ComposeGrid for a grid over its size
+// limit.
+var ErrGridTooLarge = errors.New("grid too large")
+
+// GridOptions controls ComposeGrid. The zero value lays images out in a
+// roughly square grid, each row as tall as its tallest image.
+type GridOptions struct {
+	// Cols and Rows fix the grid's shape. With only one of them set the
+	// other follows from the number of images; with neither, Cols is the
+	// square root of it, rounded up. Cells beyond the images stay empty.
+	Cols, Rows int
+
+	// CellWidth and CellHeight size every cell, in pixels. A zero
+	// CellWidth takes the widest image; a zero CellHeight makes each row
+	// as tall as its tallest image.
+	CellWidth, CellHeight int
+
+	// Gap is the space between cells and around the grid, in pixels;
+	// nil means DefaultGridGap.
+	Gap *int
+
+	// Background is the "#rrggbb" or "#rrggbbaa" color behind the cells
+	// (default DefaultGridBackground).
+	Background string
+
+	// Fit scales each image into its cell: "contain" (default) keeps all
+	// of it, centered; "cover" fills the cell and crops the overflow.
+	Fit string
+
+	// Labels are drawn centered under the cells, in the embedded font,
+	// ellipsized to the cell width. Labels[i] belongs to images[i]; a grid
+	// with no labels reserves no room for them.
+	Labels     []string
+	LabelSize  float64 // in pixels (default DefaultGridLabelSize)
+	LabelColor string  // default DefaultGridLabelColor
+
+	// MaxCanvasSize bounds the grid as RendererOptions.MaxCanvasSize
+	// bounds a canvas; 0 means the package's MaxCanvasSize.
+	MaxCanvasSize int
+}
+
+// ComposeGrid draws images into a grid, row by row. A nil image leaves its
+// cell empty but keeps its label. The grid may cover at most as many
+// pixels as an opts.MaxCanvasSize square canvas.
+func ComposeGrid(images []image.Image, opts GridOptions) (*image.RGBA, error) {
+	n := len(images)
+	if n == 0 {
+		return nil, errors.New("grid: no images")
+	}
+	if len(opts.Labels) > n {
+		return nil, fmt.Errorf("grid: %d labels for %d images", len(opts.Labels), n)
+	}
+	if opts.Cols < 0 || opts.Rows < 0 || opts.CellWidth < 0 || opts.CellHeight < 0 || opts.LabelSize < 0 {
+		return nil, errors.New("grid: cols, rows, cell size and label size must not be negative")
+	}
+	gap := DefaultGridGap
+	if opts.Gap != nil {
+		if gap = *opts.Gap; gap < 0 {
+			return nil, errors.New("grid: gap must not be negative")
+		}
+	}
+	fit := opts.Fit
+	switch fit {
+	case "":
+		fit = "contain"
+	case "contain", "cover":
+	default:
+		return nil, fmt.Errorf("grid: unknown fit %q (want contain or cover)", fit)
+	}
+	bg, err := gridColor("background", opts.Background, DefaultGridBackground)
+	if err != nil {
+		return nil, err
+	}
+	fg, err := gridColor("label color", opts.LabelColor, DefaultGridLabelColor)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, rows := opts.Cols, opts.Rows
+	switch {
+	case cols == 0 && rows == 0:
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+		rows = (n + cols - 1) / cols
+	case cols == 0:
+		cols = (n + rows - 1) / rows
+	case rows == 0:
+		rows = (n + cols - 1) / cols
+	}
+	if cols*rows < n {
+		return nil, fmt.Errorf("grid: %d images do not fit in %d×%d cells", n, cols, rows)
+	}
+
+	cellW := opts.CellWidth
+	rowH := make([]int, rows)
+	for i, img := range images {
+		if img == nil {
+			continue
+		}
+		b := img.Bounds()
+		if opts.CellWidth == 0 {
+			cellW = max(cellW, b.Dx())
+		}
+		rowH[i/cols] = max(rowH[i/cols], b.Dy())
+	}
+	for r := range rowH {
+		if opts.CellHeight > 0 {
+			rowH[r] = opts.CellHeight
+		}
+		rowH[r] = max(rowH[r], 1)
+	}
+	if cellW == 0 {
+		return nil, errors.New("grid: cell width is zero (no images with area, and no cell width given)")
+	}
+
+	var face font.Face
+	labelH := 0
+	if slices.ContainsFunc(opts.Labels, func(l string) bool { return l != "" }) {
+		size := cmp.Or(opts.LabelSize, DefaultGridLabelSize)
+		fm, err := NewFontManagerFromBytes(nil)
+		if err != nil {
+			return nil, err
+		}
+		if face, err = fm.GetFace(size, 72); err != nil {
+			return nil, err
+		}
+		labelH = face.Metrics().Height.Ceil() + gap/2 + 4
+	}
+
+	w := gap + cols*(cellW+gap)
+	h := gap
+	rowY := make([]int, rows)
+	for r, rh := range rowH {
+		rowY[r] = h
+		h += rh + labelH + gap
+	}
+	if limit := cmp.Or(opts.MaxCanvasSize, MaxCanvasSize); int64(w)*int64(h) > int64(limit)*int64(limit) {
+		return nil, fmt.Errorf("%w: %dx%d is larger than a %d×%d canvas", ErrGridTooLarge, w, h, limit, limit)
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.Draw(sheet, sheet.Bounds(), image.NewUniform(bg), image.Point{}, xdraw.Src)
+	for i, img := range images {
+		x := gap + (i%cols)*(cellW+gap)
+		y := rowY[i/cols]
+		cell := image.Rect(x, y, x+cellW, y+rowH[i/cols])
+		if img != nil && !img.Bounds().Empty() {
+			drawFitted(sheet, cell, img, fit)
+		}
+		if i < len(opts.Labels) && opts.Labels[i] != "" {
+			label := Ellipsize(opts.Labels[i], cellW, face)
+			baseline := cell.Max.Y + labelH - face.Metrics().Descent.Ceil() - 2
+			d := &font.Drawer{Dst: sheet, Src: image.NewUniform(fg), Face: face, Dot: fixed.Point26_6{X: alignX(x, cellW, label, face, "center"), Y: fixed.I(baseline)}}
+			d.DrawString(label)
+		}
+	}
+	return sheet, nil
+}
+
+// drawFitted scales src into cell of dst, over what is there.
+func drawFitted(dst *image.RGBA, cell image.Rectangle, src image.Image, fit string) {
+	sb := src.Bounds()
+	sx := float64(cell.Dx()) / float64(sb.Dx())
+	sy := float64(cell.Dy()) / float64(sb.Dy())
+	if fit == "cover" {
+		// Crop the source to the cell's aspect ratio, centered.
+		scale := max(sx, sy)
+		cw := min(sb.Dx(), int(math.Round(float64(cell.Dx())/scale)))
+		ch := min(sb.Dy(), int(math.Round(float64(cell.Dy())/scale)))
+		ox, oy := sb.Min.X+(sb.Dx()-cw)/2, sb.Min.Y+(sb.Dy()-ch)/2
+		xdraw.CatmullRom.Scale(dst, cell, src, image.Rect(ox, oy, ox+cw, oy+ch), xdraw.Over, nil)
+		return
+	}
+	scale := min(sx, sy)
+	w := max(int(math.Round(float64(sb.Dx())*scale)), 1)
+	h := max(int(math.Round(float64(sb.Dy())*scale)), 1)
+	x, y := cell.Min.X+(cell.Dx()-w)/2, cell.Min.Y+(cell.Dy()-h)/2
+	xdraw.CatmullRom.Scale(dst, image.Rect(x, y, x+w, y+h), src, sb, xdraw.Over, nil)
+}
+
+// gridColor parses a GridOptions color, or def when it is empty.
+func gridColor(what, c, def string) (color.RGBA, error) {
+	if c == "" {
+		c = def
+	}
+	if !validHexColor(c) {
+		return color.RGBA{}, fmt.Errorf("grid: invalid %s %q (want #rrggbb, #rrggbbaa or transparent)", what, c)
+	}
+	return parseHexColorAlpha(c), nil
+}
@@ -0,0 +1,233 @@
+// imagescale.go — Images much larger than they are drawn: with
+// Renderer.SetMaxImageSize, a 50-megapixel photo in a 400-pixel box is
+// reduced once, after decoding, to a few times the drawn size, and an
+// ImageCache keeps the reduced image so later renders skip the decode.
+package template
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"image"
+	"image/draw"
+	"sync"
+)
+
+// DefaultImageCacheBytes is a reasonable ImageCache size for a server.
+const DefaultImageCacheBytes = 64 << 20
+
+// ImageCache holds the images Renderer.SetMaxImageSize reduced, by the
+// digest of their file and the size they were reduced to, so renders that
+// draw the same large image decode it once. It is safe for concurrent use;
+// share one between renderers with Renderer.SetImageCache. A nil
+// *ImageCache caches nothing.
+type ImageCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List // front = most recently used
+	entries  map[imageKey]*list.Element
+}
+
+// imageKey identifies a reduced image.
+type imageKey struct {
+	digest [sha256.Size]byte // of the encoded file
+	side   int               // the reduced image's longer side
+}
+
+type cachedImage struct {
+	key   imageKey
+	img   image.Image
+	bytes int
+}
+
+// NewImageCache returns a cache holding up to maxBytes of decoded pixels,
+// dropping the least recently used images first.
+func NewImageCache(maxBytes int) *ImageCache {
+	return &ImageCache{maxBytes: maxBytes, order: list.New(), entries: make(map[imageKey]*list.Element)}
+}
+
+func (c *ImageCache) get(key imageKey) (image.Image, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedImage).img, true
+}
+
+func (c *ImageCache) put(key imageKey, img image.Image) {
+	if c == nil {
+		return
+	}
+	e := &cachedImage{key, img, pixelBytes(img)}
+	if e.bytes > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+	c.entries[key] = c.order.PushFront(e)
+	c.bytes += e.bytes
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+func (c *ImageCache) removeLocked(el *list.Element) {
+	e := c.order.Remove(el).(*cachedImage)
+	delete(c.entries, e.key)
+	c.bytes -= e.bytes
+}
+
+// SetMaxImageSize makes images whose longer side exceeds px pixels, and
+// which are drawn at a quarter of their size or less, be reduced after
+// decoding to at least twice the drawn size, and cached in the renderer's
+// ImageCache. What is drawn barely changes; what a render
+// holds in memory, and what it spends scaling, does. 0, the default,
+// draws every image from its full decode.
+func (r *Renderer) SetMaxImageSize(px int) {
+	r.maxImageSize = max(px, 0)
+}
+
+// SetImageCache sets the cache SetMaxImageSize keeps reduced images in;
+// nil, the default, keeps none.
+func (r *Renderer) SetImageCache(c *ImageCache) {
+	r.imageCache = c
+}
+
+// decodeRaster decodes the PNG, JPEG, GIF or BMP image in data, to be
+// drawn in a box of the given size, reduced as SetMaxImageSize says.
+func (r *Renderer) decodeRaster(path string, data []byte, box image.Point) (image.Image, error) {
+	var key imageKey
+	if r.maxImageSize > 0 {
+		if key.side = reducedSide(data, box, r.maxImageSize); key.side > 0 {
+			key.digest = sha256.Sum256(data)
+			if img, ok := r.imageCache.get(key); ok {
+				logger().Debug("reduced image from cache", "ref", path, "side", key.side)
+				return img, nil
+			}
+		}
+	}
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	logger().Debug("image decoded", "ref", path, "format", format, "width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	if key.side == 0 {
+		return img, nil
+	}
+	img = reduceImage(img, key.side)
+	r.imageCache.put(key, img)
+	logger().Debug("image reduced", "ref", path, "width", img.Bounds().Dx(), "height", img.Bounds().Dy())
+	return img, nil
+}
+
+// reducedSide is the longer side to reduce the image in data to before
+// drawing it in a box of the given size, or 0 to keep it whole: when its
+// longer side is at most maxSide, or it is drawn at more than a quarter of
+// its size. The result keeps at least two source pixels per drawn pixel
+// along both axes, whatever the fit, and is a power of two so that boxes
+// of similar sizes share a cached image.
+func reducedSide(data []byte, box image.Point, maxSide int) int {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || box.X <= 0 || box.Y <= 0 {
+		return 0
+	}
+	w, h := cfg.Width, cfg.Height
+	if o := readJPEGMeta(data).orientation; o >= 5 && o <= 8 { // turned a quarter
+		w, h = h, w
+	}
+	long := max(w, h)
+	if long <= maxSide {
+		return 0
+	}
+	need := 2 * max(float64(box.X)/float64(w), float64(box.Y)/float64(h)) * float64(long)
+	side := 1
+	for float64(side) < need {
+		side <<= 1
+	}
+	if 2*side > long {
+		return 0
+	}
+	return side
+}
+
+// reduceImage shrinks img by the whole factor that brings its longer side
+// nearest to, but not under, side, averaging each block of source pixels.
+// The source is read a strip of rows at a time, so nothing the size of
+// img is allocated. Images with 16-bit channels keep them.
+func reduceImage(img image.Image, side int) image.Image {
+	b := img.Bounds()
+	k := max(max(b.Dx(), b.Dy())/side, 1)
+	out := image.Rect(0, 0, (b.Dx()+k-1)/k, (b.Dy()+k-1)/k)
+	stripR := image.Rect(0, 0, b.Dx(), k)
+
+	// Strip and result are RGBA or RGBA64, both premultiplied, so
+	// averaging their bytes averages the colors; depth is bytes per channel.
+	var (
+		strip, dst draw.Image
+		sPix, dPix []uint8
+		sStr, dStr int
+		depth      = 1
+	)
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		s, d := image.NewRGBA64(stripR), image.NewRGBA64(out)
+		strip, dst, sPix, dPix, sStr, dStr, depth = s, d, s.Pix, d.Pix, s.Stride, d.Stride, 2
+	default:
+		s, d := image.NewRGBA(stripR), image.NewRGBA(out)
+		strip, dst, sPix, dPix, sStr, dStr = s, d, s.Pix, d.Pix, s.Stride, d.Stride
+	}
+	at := func(pix []uint8, i int) uint64 {
+		if depth == 2 {
+			return uint64(pix[i])<<8 | uint64(pix[i+1])
+		}
+		return uint64(pix[i])
+	}
+
+	sums := make([]uint64, out.Dx()*4)
+	for oy := range out.Dy() {
+		y0 := b.Min.Y + oy*k
+		rows := min(k, b.Max.Y-y0)
+		draw.Draw(strip, image.Rect(0, 0, b.Dx(), rows), img, image.Pt(b.Min.X, y0), draw.Src)
+		clear(sums)
+		for y := range rows {
+			for x := range b.Dx() {
+				i, o := y*sStr+x*4*depth, x/k*4
+				for c := range 4 {
+					sums[o+c] += at(sPix, i+c*depth)
+				}
+			}
+		}
+		for ox := range out.Dx() {
+			n := uint64(rows * min(k, b.Dx()-ox*k))
+			for c := range 4 {
+				v := (sums[ox*4+c] + n/2) / n
+				i := oy*dStr + (ox*4+c)*depth
+				if depth == 2 {
+					dPix[i], dPix[i+1] = uint8(v>>8), uint8(v)
+				} else {
+					dPix[i] = uint8(v)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// pixelBytes estimates the memory img's pixels take.
+func pixelBytes(img image.Image) int {
+	n := img.Bounds().Dx() * img.Bounds().Dy()
+	if _, ok := img.(*image.RGBA64); ok {
+		return 8 * n
+	}
+	return 4 * n
+}
@@ -0,0 +1,133 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+	"os"
+	"testing"
+)
+
+// largePhoto is the photo fixture enlarged to w×h, smoothly, with grain
+// of its own at the new size, as a JPEG: a stand-in for a camera photo.
+func largePhoto(t *testing.T, w, h int) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/golden/assets/photo.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, sb.Dx(), sb.Dy()))
+	draw.Draw(rgba, rgba.Rect, src, sb.Min, draw.Src)
+	rng := rand.New(rand.NewPCG(7, 7))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		fy := (float64(y)+0.5)*float64(sb.Dy())/float64(h) - 0.5
+		y0 := min(max(int(math.Floor(fy)), 0), sb.Dy()-2)
+		ty := min(max(fy-float64(y0), 0), 1)
+		for x := range w {
+			fx := (float64(x)+0.5)*float64(sb.Dx())/float64(w) - 0.5
+			x0 := min(max(int(math.Floor(fx)), 0), sb.Dx()-2)
+			tx := min(max(fx-float64(x0), 0), 1)
+			var c [3]float64
+			for _, p := range []struct {
+				dx, dy int
+				k      float64
+			}{{0, 0, (1 - tx) * (1 - ty)}, {1, 0, tx * (1 - ty)}, {0, 1, (1 - tx) * ty}, {1, 1, tx * ty}} {
+				s := rgba.RGBAAt(x0+p.dx, y0+p.dy)
+				c[0] += p.k * float64(s.R)
+				c[1] += p.k * float64(s.G)
+				c[2] += p.k * float64(s.B)
+			}
+			n := rng.Float64()*12 - 6
+			v := func(f float64) uint8 { return uint8(min(max(f+n, 0), 255)) }
+			img.SetRGBA(x, y, color.RGBA{v(c[0]), v(c[1]), v(c[2]), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestMaxImageSizePerceptual draws a 2500×1875 photo, which does not
+// halve evenly, into boxes of several sizes and fits with SetMaxImageSize
+// on and off, and checks that the reduced renders stay within a
+// perceptual threshold of the full ones: no channel off by more than 24
+// beyond a few pixels, and a small mean difference.
+func TestMaxImageSizePerceptual(t *testing.T) {
+	photo := largePhoto(t, 2500, 1875)
+	resolve := func(ref string) []byte {
+		if ref == "photo.jpg" {
+			return photo
+		}
+		return nil
+	}
+	tests := []struct {
+		w, h int
+		fit  string
+	}{
+		{400, 300, "stretch"},
+		{400, 300, "contain"},
+		{150, 300, "cover"},
+		{320, 100, "cover"},
+		{120, 90, "stretch"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%dx%d_%s", tt.w, tt.h, tt.fit), func(t *testing.T) {
+			preset, err := DecodePreset(fmt.Appendf(nil, `{
+  "canvas": {"width": %d, "height": %d},
+  "background": {"type": "color", "color": "#000000"},
+  "font": {},
+  "components": [{"id": "photo", "x": 0, "y": 0, "width": 1, "height": 1,
+    "style": {"backgroundImage": "photo.jpg", "backgroundFit": %q}, "defaults": {"visible": true}}]
+}`, tt.w, tt.h, tt.fit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			render := func(maxSide int, cache *ImageCache) *image.RGBA {
+				t.Helper()
+				r, err := NewRendererForFont(preset.Font, resolve)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.SetStrictAssets(true)
+				r.SetMaxImageSize(maxSide)
+				r.SetImageCache(cache)
+				img, err := r.RenderPreset(preset, MergeData(preset, nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return img
+			}
+			full := render(0, nil)
+			cache := NewImageCache(DefaultImageCacheBytes)
+			reduced := render(1024, cache)
+			if len(cache.entries) != 1 {
+				t.Fatalf("%d reduced images cached, want 1: the image was not reduced", len(cache.entries))
+			}
+
+			diff := CompareImages(reduced, full, CompareOptions{Tolerance: 24, MaxDiffPixels: 64})
+			if !diff.Match {
+				t.Errorf("%d pixels differ by more than 24 (largest %d)", diff.DiffPixels, diff.MaxDelta)
+			}
+			var sum float64
+			for i := range full.Pix {
+				sum += math.Abs(float64(full.Pix[i]) - float64(reduced.Pix[i]))
+			}
+			if mean := sum / float64(len(full.Pix)); mean > 3 {
+				t.Errorf("mean channel difference %.2f, want at most 3", mean)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// jsonschema.go — Generate a JSON Schema for a preset's data.json.
+package template
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// colorPattern matches the colors parseHexColorAlpha accepts, with the
+// transparent keywords in lower case.
+const colorPattern = "^(#?([0-9a-fA-F]{6}|[0-9a-fA-F]{8})|transparent|none)$"
+
+// autoColorPattern is colorPattern with the automatic colors of
+// autocolor.go, for the fields that take them.
+const autoColorPattern = "^(#?([0-9a-fA-F]{6}|[0-9a-fA-F]{8})|transparent|none|auto|auto-contrast)$"
+
+// styleConstraints narrows ComponentStyle properties beyond their Go type.
+var styleConstraints = map[string]map[string]any{
+	"backgroundColor": {"pattern": colorPattern},
+	"borderColor":     {"pattern": autoColorPattern, "description": "Border color; auto or auto-contrast pick one from what is under the component"},
+	"color":           {"pattern": autoColorPattern, "description": "Text color; auto or auto-contrast pick one from what is under the component"},
+	"titleColor":      {"pattern": colorPattern, "description": "Title color (default: color)"},
+	"titleFontSize":   {"minimum": 0, "description": "Title font size (default: 1.4 × fontSize)"},
+	"titleSpacing":    {"minimum": 0, "description": "Pixels between the title and the items (default: half the title font size)"},
+	"backgroundFit":   {"enum": []string{"stretch", "contain", "cover"}},
+	"borderStyle":     {"enum": []string{"solid", "dashed", "dotted"}},
+	"dashLength":      {"minimum": 0, "description": "Dash length in pixels (default: 3 × borderWidth)"},
+	"gapLength":       {"minimum": 0, "description": "Gap between dashes or dots in pixels (default: 2 × borderWidth dashed, 1 × dotted)"},
+	"textAlign":       {"enum": []string{"left", "center", "right"}},
+	"cornerRadius":    {"type": []string{"integer", "string"}, "minimum": -1, "pattern": "^full$"},
+	"lineHeight":      {"type": []string{"number", "string"}, "minimum": 0, "pattern": "^[0-9]+(\\.[0-9]+)?px$", "description": "Multiple of the font size up to 4, pixels above; or pixels as \"28px\""},
+	"itemSpacing":     {"minimum": 0, "description": "Extra pixels between items, not between an item's wrapped lines"},
+}
+
+// DataJSONSchema returns a JSON Schema (draft 2020-12) describing data.json
+// for preset: one property per component ID, with the descriptions from the
+// preset's schema section. Unknown component IDs are rejected, matching the
+// warnings from ValidateData. Required fields must be set in the base
+// components, which a locale overlay then need not repeat.
+func DataJSONSchema(preset *Preset) map[string]any {
+	comps := make(map[string]any, len(preset.Components))
+	base := make(map[string]any, len(preset.Components))
+	var requiredIDs []string
+	for _, c := range preset.Components {
+		sc := preset.Schema.Components[c.ID]
+		comps[c.ID] = componentJSONSchema(sc, nil, c.VariantNames(), preset.StyleNames())
+		base[c.ID] = comps[c.ID]
+		if required := requiredFields(sc); len(required) > 0 {
+			base[c.ID] = componentJSONSchema(sc, required, c.VariantNames(), preset.StyleNames())
+			requiredIDs = append(requiredIDs, c.ID)
+		}
+	}
+	components := map[string]any{
+		"type":                 "object",
+		"properties":           comps,
+		"additionalProperties": false,
+	}
+	baseComponents := components
+	if len(requiredIDs) > 0 {
+		baseComponents = maps.Clone(components)
+		baseComponents["properties"] = base
+		baseComponents["required"] = slices.Compact(requiredIDs)
+	}
+
+	title := "data.json"
+	if preset.Meta.Name != "" {
+		title += " for " + preset.Meta.Name
+	}
+	s := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   title,
+		"type":    "object",
+		"properties": map[string]any{
+			"components": baseComponents,
+			"locales": map[string]any{
+				"type": "object",
+				"additionalProperties": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"components": components},
+				},
+			},
+			"locale": map[string]any{"type": "string"},
+		},
+		"$defs": map[string]any{"style": styleJSONSchema()},
+	}
+	if len(requiredIDs) > 0 {
+		s["required"] = []string{"components"}
+	}
+	if preset.Schema.Description != "" {
+		s["description"] = preset.Schema.Description
+	}
+	return s
+}
+
+// requiredFields returns the fields sc requires that data can set.
+func requiredFields(sc SchemaComponent) []string {
+	var fields []string
+	for _, f := range sc.Required {
+		if slices.Contains(RequirableFields, f) && !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// componentJSONSchema describes one component's data. A required title or
+// list of items must also be non-empty, as ValidateData expects. variant
+// is accepted only for a component with variants, and must name one;
+// classes only when the preset has style classes, naming them.
+func componentJSONSchema(sc SchemaComponent, required, variants, classes []string) map[string]any {
+	props := map[string]any{
+		"visible": map[string]any{"type": "boolean"},
+		"title":   map[string]any{"type": "string"},
+		"items": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type": map[string]any{"enum": []string{"text", "bullet", "numbered"}},
+					"text": map[string]any{"type": "string"},
+				},
+				"required":             []string{"text"},
+				"additionalProperties": false,
+			},
+		},
+		"style": map[string]any{"$ref": "#/$defs/style"},
+	}
+	if len(variants) > 0 {
+		props["variant"] = map[string]any{"enum": variants, "description": "Named style variant of this component"}
+	}
+	if len(classes) > 0 {
+		props["classes"] = map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"enum": classes},
+			"description": "Style classes replacing the component's own, applied in order",
+		}
+	}
+	for field, desc := range sc.Fields {
+		if p, ok := props[field].(map[string]any); ok {
+			p["description"] = desc
+		}
+	}
+
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+		if slices.Contains(required, "title") {
+			props["title"].(map[string]any)["minLength"] = 1
+		}
+		if slices.Contains(required, "items") {
+			props["items"].(map[string]any)["minItems"] = 1
+		}
+	}
+	if sc.Description != "" {
+		s["description"] = sc.Description
+	}
+	return s
+}
+
+// styleJSONSchema derives the style schema from ComponentStyle's JSON tags
+// so it cannot drift from the struct.
+func styleJSONSchema() map[string]any {
+	props := make(map[string]any)
+	t := reflect.TypeFor[ComponentStyle]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		p := map[string]any{}
+		kind := f.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = f.Type.Elem().Kind()
+		}
+		switch kind {
+		case reflect.String:
+			p["type"] = "string"
+		case reflect.Int, reflect.Int64:
+			p["type"] = "integer"
+		case reflect.Float32, reflect.Float64:
+			p["type"] = "number"
+		case reflect.Bool:
+			p["type"] = "boolean"
+		}
+		for k, v := range styleConstraints[name] {
+			p[k] = v
+		}
+		props[name] = p
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
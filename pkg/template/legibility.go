@@ -0,0 +1,115 @@
+// legibility.go — Text that renders but cannot be seen: a color too close
+// to what is behind it, a font too small to read, or padding that leaves
+// no room. Rendering warns about them and Lint reports them.
+package template
+
+import (
+	"fmt"
+	"image/color"
+)
+
+const (
+	// MinLegibleFontPx is the smallest text, in pixels, drawn without a
+	// warning.
+	MinLegibleFontPx = 6
+
+	// minTextContrast is the smallest difference, in any 0–255 channel,
+	// between text drawn over its background and the background alone.
+	minTextContrast = 16
+)
+
+// legibilityProblem is one reason a component's text may not be visible.
+type legibilityProblem struct {
+	field   string // the style field to change, as Lint names it
+	message string
+}
+
+// legibilityProblems checks comp's resolved text against bg, the preset's
+// background; px converts points to pixels. Components without text or
+// without area have none.
+func legibilityProblems(comp ResolvedComponent, bg Background, px func(float64) float64) []legibilityProblem {
+	hasTitle, hasItems := comp.Data.Title != "", len(comp.Data.Items) > 0
+	if (!hasTitle && !hasItems) || comp.Width <= 0 || comp.Height <= 0 {
+		return nil
+	}
+
+	var problems []legibilityProblem
+	add := func(field, format string, args ...any) {
+		problems = append(problems, legibilityProblem{field, fmt.Sprintf(format, args...)})
+	}
+	s := &comp.Style
+	if s.Arc == nil {
+		if w, h := comp.Width-2*comp.Padding, comp.Height-2*comp.Padding; w <= 0 || h <= 0 {
+			add("padding", "padding %d leaves no room for text in its %dx%d box", comp.Padding, comp.Width, comp.Height)
+		}
+	}
+	if hasTitle && px(s.titleSize()) < MinLegibleFontPx {
+		add("style.titleFontSize", "title is %.1f px tall (font size %g), too small to read", px(s.titleSize()), s.titleSize())
+	}
+	if hasItems && px(s.FontSize) < MinLegibleFontPx {
+		add("style.fontSize", "text is %.1f px tall (font size %g), too small to read", px(s.FontSize), s.FontSize)
+	}
+
+	behind, ok := textBackground(s, bg)
+	if !ok {
+		return problems
+	}
+	if hasTitle && s.TitleColor != "" && !visibleOn(s.titleColor(), behind) {
+		add("style.titleColor", "title color %q is nearly invisible on the background %s", s.titleColor(), hexColor(behind))
+	}
+	if (hasItems || (hasTitle && s.TitleColor == "")) && !isAutoColor(s.Color) && !visibleOn(s.Color, behind) {
+		add("style.color", "text color %q is nearly invisible on the background %s", s.Color, hexColor(behind))
+	}
+	return problems
+}
+
+// textBackground is the opaque color behind s's text: its background color
+// over the preset's. ok is false when an image, a backdrop blur or a
+// transparent canvas shows through, and the color is not known.
+func textBackground(s *ComponentStyle, bg Background) (c color.RGBA, ok bool) {
+	if s.BackgroundImage != "" || s.BackdropBlur > 0 {
+		return c, false
+	}
+	own := parseHexColorAlpha(s.BackgroundColor)
+	if s.BackgroundColor == "" {
+		own = color.RGBA{}
+	}
+	if own.A == 0xff {
+		return own, true
+	}
+	if bg.Type == "image" || bg.Type == "transparent" {
+		return c, false
+	}
+	canvas := parseHexColorAlpha(bg.Color)
+	if canvas.A != 0xff {
+		return c, false
+	}
+	return blendOver(own, canvas), true
+}
+
+// visibleOn reports whether text in fg, a style color, stands out from
+// the opaque bg.
+func visibleOn(fg string, bg color.RGBA) bool {
+	drawn := blendOver(parseHexColorAlpha(fg), bg)
+	d := max(absDiff(drawn.R, bg.R), absDiff(drawn.G, bg.G), absDiff(drawn.B, bg.B))
+	return d >= minTextContrast
+}
+
+// blendOver composites the straight-alpha fg over the opaque bg.
+func blendOver(fg, bg color.RGBA) color.RGBA {
+	a := uint32(fg.A)
+	mix := func(f, b uint8) uint8 { return uint8((uint32(f)*a + uint32(b)*(255-a) + 127) / 255) }
+	return color.RGBA{mix(fg.R, bg.R), mix(fg.G, bg.G), mix(fg.B, bg.B), 0xff}
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// hexColor formats an opaque color as "#rrggbb".
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
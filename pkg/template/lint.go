@@ -0,0 +1,368 @@
+// lint.go — Structured checks of a preset and its data, shared by
+// `gostencil validate`, POST /api/validate and the WASM client.
+package template
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Issue severities. Only warnings count as problems in strict mode.
+const (
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Issue is one finding from Lint.
+type Issue struct {
+	Severity  string `json:"severity"`            // SeverityWarning or SeverityInfo
+	Component string `json:"component,omitempty"` // component ID, "" for preset-wide issues
+	Field     string `json:"field,omitempty"`     // e.g. "style.color", "background.source"
+	Message   string `json:"message"`
+}
+
+// String formats the issue as a single log line.
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Component != "" {
+		fmt.Fprintf(&b, "component %q: ", i.Component)
+	}
+	if i.Field != "" {
+		b.WriteString(i.Field + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// Lint checks a preset and optional data for problems that rendering would
+// silently work around: an unknown canvas preset name or snap mode, unusable fonts,
+// unknown component IDs, required data fields left unset (when data is
+// given) or unknown to the schema, malformed colors, missing image files, duplicate
+// IDs, responsive keys that never apply, unknown variants and style classes,
+// style classes nothing uses (info), components with
+// no area on the canvas, text that would not be legible with the data merged (a color
+// too close to its background, a font under MinLegibleFontPx, or padding
+// that leaves no room), overlapping components that share a zIndex, and
+// components that partially overlap (reported as info, since layering may
+// be intended).
+// Positions are checked as placed on the preset's own canvas.
+func Lint(preset *Preset, data *DataSpec) []Issue {
+	return LintWithResolver(preset, data, nil)
+}
+
+// LintWithResolver is Lint for presets whose font and image references may
+// name in-memory assets, as in the WASM client: resolve is consulted before
+// the filesystem, the way Renderer.SetAssetResolver makes rendering do.
+func LintWithResolver(preset *Preset, data *DataSpec, resolve AssetResolverFunc) []Issue {
+	return LintWithOptions(preset, data, LintOptions{Resolve: resolve})
+}
+
+// LintOptions controls where LintWithOptions looks for assets, as
+// RendererOptions does for a render.
+type LintOptions struct {
+	Resolve       AssetResolverFunc // consulted before the filesystem
+	NoAssetFiles  bool              // references Resolve does not know are missing, not files
+	MaxCanvasSize int               // the renderer's; 0 means the package's MaxCanvasSize
+}
+
+// LintWithOptions is Lint with opts.
+func LintWithOptions(preset *Preset, data *DataSpec, opts LintOptions) []Issue {
+	var issues []Issue
+	add := func(severity, comp, field, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Component: comp, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if msg := unknownCanvasPreset(preset.Canvas); msg != "" {
+		add(SeverityWarning, "", "canvas.preset", "%s", msg)
+	}
+	if msg := unknownSnap(preset.Canvas); msg != "" {
+		add(SeverityWarning, "", "canvas.snap", "%s", msg)
+	}
+	if limit := cmp.Or(opts.MaxCanvasSize, MaxCanvasSize); preset.Canvas.Width > limit || preset.Canvas.Height > limit {
+		add(SeverityWarning, "", "canvas", "canvas %dx%d exceeds the maximum of %d pixels per side — it cannot be rendered", preset.Canvas.Width, preset.Canvas.Height, limit)
+	}
+
+	for _, r := range inspectFonts(preset, opts.Resolve, opts.NoAssetFiles) {
+		if r.Error == "" {
+			continue
+		}
+		comp, field := "", "font.path"
+		if name, ok := strings.CutPrefix(r.Use, "style:"); ok {
+			field = "styles." + name + ".fontPath"
+		} else if id, ok := strings.CutPrefix(r.Use, "component:"); ok {
+			comp, field = id, "style.fontPath"
+			if id, ok := strings.CutSuffix(id, " (defaults)"); ok {
+				comp, field = id, "defaults.style.fontPath"
+			} else if id, name, ok := strings.Cut(strings.TrimSuffix(id, ")"), " (variant "); ok {
+				comp, field = id, "variants."+name+".fontPath"
+			}
+		}
+		add(SeverityWarning, comp, field, "font %q: %s — default font will be substituted", r.Path, r.Error)
+	}
+
+	if data != nil { // a preset checked alone is not missing its data
+		for _, w := range ValidateData(data, preset) {
+			add(w.Level, "", "", "%s", w.Message)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(preset.Schema.Components)) {
+		for _, field := range preset.Schema.Components[id].Required {
+			if !slices.Contains(RequirableFields, field) {
+				add(SeverityWarning, id, "schema.required", "unknown field %q (use %s) — not enforced", field, strings.Join(RequirableFields, ", "))
+			}
+		}
+	}
+
+	if preset.Background.Type == "image" && preset.Background.Source != "" {
+		lintImage(add, opts, "", "background.source", preset.Background.Source)
+	}
+	lintColor(add, "", "background.color", preset.Background.Color)
+	lintClasses(add, opts, preset, data)
+
+	seen := make(map[string]bool, len(preset.Components))
+	for _, c := range preset.Components {
+		lintResponsive(add, opts, &c)
+		lintVariants(add, opts, &c)
+		c = c.forCanvas(preset.Canvas)
+		if seen[c.ID] {
+			add(SeverityWarning, c.ID, "id", "duplicate component ID — data applies to every component with it")
+		}
+		seen[c.ID] = true
+		if componentRect(c, preset.Canvas).Empty() {
+			add(SeverityWarning, c.ID, "", "no area on the canvas (x %g, y %g, width %g, height %g) — it is never drawn", c.X, c.Y, c.Width, c.Height)
+		}
+		lintStyle(add, opts, c.ID, "style.", c.ownStyle())
+		if c.Defaults.Style != nil {
+			lintStyle(add, opts, c.ID, "defaults.style.", c.Defaults.Style)
+		}
+	}
+
+	if data != nil {
+		lintDataStyles(add, "data.", data.Components)
+		for _, name := range data.LocaleNames() {
+			lintDataStyles(add, "locales."+name+".", data.Locales[name].Components)
+		}
+	}
+
+	// Capped font sizes, and legibility at the default DPI, with the data's
+	// base components.
+	for _, c := range MergeData(preset, data) {
+		for _, f := range c.capped {
+			add(SeverityWarning, c.ID, "style."+f.field, "font size %g is larger than the canvas is tall — drawn at %g", f.size, f.max)
+		}
+		for _, p := range legibilityProblems(c, preset.Background, func(pt float64) float64 { return pt }) {
+			add(SeverityWarning, c.ID, p.field, "%s", p.message)
+		}
+	}
+
+	issues = append(issues, lintOverlaps(preset)...)
+	return issues
+}
+
+// CountWarnings returns how many issues have SeverityWarning.
+func CountWarnings(issues []Issue) int {
+	n := 0
+	for _, i := range issues {
+		if i.Severity == SeverityWarning {
+			n++
+		}
+	}
+	return n
+}
+
+type addIssue func(severity, comp, field, format string, args ...any)
+
+func lintStyle(add addIssue, opts LintOptions, comp, prefix string, s *ComponentStyle) {
+	lintColor(add, comp, prefix+"backgroundColor", s.BackgroundColor)
+	lintAutoColor(add, comp, prefix+"borderColor", s.BorderColor)
+	lintAutoColor(add, comp, prefix+"color", s.Color)
+	lintColor(add, comp, prefix+"titleColor", s.TitleColor)
+	if s.BackgroundImage != "" {
+		lintImage(add, opts, comp, prefix+"backgroundImage", s.BackgroundImage)
+	}
+	if s.MaskImage != "" {
+		lintImage(add, opts, comp, prefix+"maskImage", s.MaskImage)
+	}
+	switch s.BorderStyle {
+	case "", "solid", "dashed", "dotted":
+	default:
+		add(SeverityWarning, comp, prefix+"borderStyle", "unknown border style %q (want solid, dashed or dotted) — drawn solid", s.BorderStyle)
+	}
+	if s.ItemSpacing < 0 {
+		add(SeverityWarning, comp, prefix+"itemSpacing", "negative item spacing — 0 is used")
+	}
+	if s.DashLength < 0 || s.GapLength < 0 {
+		add(SeverityWarning, comp, prefix+"dashLength", "negative dash or gap length — the default is used")
+	}
+	if s.BackdropBlur < 0 || s.BackdropBlur > MaxBackdropBlur {
+		add(SeverityWarning, comp, prefix+"backdropBlur", "%g is outside 0–%d — negative values are ignored, larger ones capped", s.BackdropBlur, MaxBackdropBlur)
+	}
+	if a := s.Arc; a != nil {
+		if a.Direction != "" && a.Direction != "clockwise" && a.Direction != "counterclockwise" {
+			add(SeverityWarning, comp, prefix+"arc.direction", "unknown direction %q (want clockwise or counterclockwise) — drawn clockwise", a.Direction)
+		}
+		if a.Sweep < 0 || a.Sweep > 360 {
+			add(SeverityWarning, comp, prefix+"arc.sweep", "sweep %g is outside 0–360 — a full circle is used", a.Sweep)
+		}
+	}
+}
+
+// lintResponsive checks each responsive key of c and the style it applies.
+func lintResponsive(add addIssue, opts LintOptions, c *Component) {
+	for _, key := range slices.Sorted(maps.Keys(c.Responsive)) {
+		field := "responsive." + key
+		if err := CheckResponsiveKey(key); err != nil {
+			add(SeverityWarning, c.ID, field, "%v — it never applies", err)
+		}
+		if s := c.Responsive[key].Style; s != nil {
+			lintStyle(add, opts, c.ID, field+".style.", s)
+		}
+	}
+}
+
+// lintVariants checks the style of each variant of c, and that the
+// variant its defaults select exists.
+func lintVariants(add addIssue, opts LintOptions, c *Component) {
+	for _, name := range c.VariantNames() {
+		s := c.Variants[name]
+		lintStyle(add, opts, c.ID, "variants."+name+".", &s)
+	}
+	if msg := c.unknownVariant(c.Defaults.Variant); msg != "" {
+		add(SeverityWarning, c.ID, "defaults.variant", "%s — none is applied", msg)
+	}
+}
+
+// lintClasses checks the style of each style class, that the classes
+// components list exist, and that each class is used by a component or,
+// when given, data.
+func lintClasses(add addIssue, opts LintOptions, preset *Preset, data *DataSpec) {
+	for _, name := range preset.StyleNames() {
+		s := preset.Styles[name]
+		lintStyle(add, opts, "", "styles."+name+".", &s)
+	}
+	for _, c := range preset.Components {
+		for _, msg := range preset.unknownClasses(c.Classes) {
+			add(SeverityWarning, c.ID, "classes", "%s — skipped", msg)
+		}
+		for _, msg := range preset.unknownClasses(c.Defaults.Classes) {
+			add(SeverityWarning, c.ID, "defaults.classes", "%s — skipped", msg)
+		}
+	}
+	for _, name := range preset.unusedClasses(data) {
+		add(SeverityInfo, "", "styles."+name, "no component uses this class")
+	}
+}
+
+// lintDataStyles checks colors in data style overrides. Image paths in data
+// are not checked: they are resolved relative to the caller, not the preset.
+func lintDataStyles(add addIssue, prefix string, comps map[string]ComponentData) {
+	ids := make([]string, 0, len(comps))
+	for id := range comps {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if s := comps[id].Style; s != nil {
+			lintColor(add, id, prefix+"style.backgroundColor", s.BackgroundColor)
+			lintAutoColor(add, id, prefix+"style.borderColor", s.BorderColor)
+			lintAutoColor(add, id, prefix+"style.color", s.Color)
+			lintColor(add, id, prefix+"style.titleColor", s.TitleColor)
+		}
+	}
+}
+
+func lintColor(add addIssue, comp, field, c string) {
+	if c != "" && !validHexColor(c) {
+		add(SeverityWarning, comp, field, "invalid color %q (want #rrggbb, #rrggbbaa or transparent) — renders as white", c)
+	}
+}
+
+// lintAutoColor is lintColor for a field that may also be "auto" or
+// "auto-contrast" (see autocolor.go).
+func lintAutoColor(add addIssue, comp, field, c string) {
+	if c != "" && !validHexColor(c) && !isAutoColor(c) {
+		add(SeverityWarning, comp, field, "invalid color %q (want #rrggbb, #rrggbbaa, transparent, auto or auto-contrast) — renders as white", c)
+	}
+}
+
+func lintImage(add addIssue, opts LintOptions, comp, field, path string) {
+	if resolveAsset(opts.Resolve, path) != nil {
+		return
+	}
+	if !opts.NoAssetFiles {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+	}
+	add(SeverityWarning, comp, field, "image %q not found — it will be skipped", path)
+}
+
+// validHexColor reports whether parseHexColorAlpha understands c.
+func validHexColor(c string) bool {
+	if isTransparentKeyword(c) {
+		return true
+	}
+	c = strings.TrimPrefix(c, "#")
+	if len(c) != 6 && len(c) != 8 {
+		return false
+	}
+	for _, r := range c {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// lintOverlaps reports pairs of components whose boxes intersect without
+// one containing the other. Full containment is the usual way to stack a
+// label on a panel, so it is not reported, unless the two share a zIndex:
+// then which is on top comes down to their IDs, which is worth a warning.
+func lintOverlaps(preset *Preset) []Issue {
+	var issues []Issue
+	cs := make([]Component, len(preset.Components))
+	for i, c := range preset.Components {
+		cs[i] = c.forCanvas(preset.Canvas)
+	}
+	for i := range cs {
+		for j := i + 1; j < len(cs); j++ {
+			a, b := cs[i], cs[j]
+			if !overlaps(a, b) {
+				continue
+			}
+			if a.ZIndex == b.ZIndex && a.ID != b.ID {
+				issues = append(issues, Issue{
+					Severity:  SeverityWarning,
+					Component: a.ID,
+					Field:     "zIndex",
+					Message:   fmt.Sprintf("overlaps component %q at the same zIndex (%d) — %q is drawn on top by ID order", b.ID, a.ZIndex, max(a.ID, b.ID)),
+				})
+				continue
+			}
+			if contains(a, b) || contains(b, a) {
+				continue
+			}
+			issues = append(issues, Issue{
+				Severity:  SeverityInfo,
+				Component: a.ID,
+				Message:   fmt.Sprintf("partially overlaps component %q", b.ID),
+			})
+		}
+	}
+	return issues
+}
+
+func overlaps(a, b Component) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width &&
+		a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+// contains reports whether b lies entirely inside a.
+func contains(a, b Component) bool {
+	return b.X >= a.X && b.Y >= a.Y &&
+		b.X+b.Width <= a.X+a.Width && b.Y+b.Height <= a.Y+a.Height
+}
@@ -4,93 +4,187 @@ package template
 import (
 	"archive/zip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
-// LoadPreset opens a .gspresets ZIP, extracts it to a temp directory,
-// parses preset.json, resolves all asset paths, and returns the preset.
-// The returned cleanup function removes the temp directory.
-func LoadPreset(path string) (*Preset, func(), error) {
-	noop := func() {}
-
-	r, err := zip.OpenReader(path)
+// LoadPreset reads the .gspresets ZIP at path with LoadPresetFromReader.
+// Render the preset with bundle.Resolve as the asset resolver.
+func LoadPreset(path string) (*PresetBundle, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, noop, fmt.Errorf("open %s: %w", path, err)
+		return nil, &InputError{Path: path, Err: fmt.Errorf("open %s: %w", path, err)}
 	}
-	defer r.Close()
-
-	// Extract to temp dir.
-	tmpDir, err := os.MkdirTemp("", "gspresets-*")
+	defer f.Close()
+	fi, err := f.Stat()
 	if err != nil {
-		return nil, noop, fmt.Errorf("create temp dir: %w", err)
+		return nil, &InputError{Path: path, Err: fmt.Errorf("open %s: %w", path, err)}
 	}
-	cleanup := func() { os.RemoveAll(tmpDir) }
-
-	if err := extractZip(r, tmpDir); err != nil {
-		cleanup()
-		return nil, noop, fmt.Errorf("extract %s: %w", path, err)
+	bundle, err := LoadPresetFromReader(f, fi.Size())
+	if err != nil {
+		return nil, &InputError{Path: path, Err: fmt.Errorf("%s: %w", path, errors.Unwrap(err))}
 	}
+	return bundle, nil
+}
+
+// PresetBundle is a .gspresets bundle read into memory by
+// LoadPresetFromReader. The preset's asset references are left relative
+// to the bundle; Resolve reads them.
+type PresetBundle struct {
+	Preset *Preset // with Preview set if the bundle has one
+
+	files map[string][]byte // by cleaned, slash-separated entry name
+}
 
-	// Parse preset.json.
-	presetPath := filepath.Join(tmpDir, "preset.json")
-	data, err := os.ReadFile(presetPath)
+// LoadPresetFromReader reads a .gspresets ZIP of size bytes from r, within
+// MaxBundle, and parses its preset.json. Nothing is written to disk and
+// there is nothing to clean up: render it with bundle.Resolve as the
+// asset resolver. Errors match ErrInput, and those for an archive over
+// the limits ErrBundleTooLarge too.
+func LoadPresetFromReader(r io.ReaderAt, size int64) (*PresetBundle, error) {
+	return LoadPresetFromReaderWithLimits(r, size, MaxBundle)
+}
+
+// LoadPresetFromReaderWithLimits is LoadPresetFromReader within limits
+// instead of MaxBundle.
+func LoadPresetFromReaderWithLimits(r io.ReaderAt, size int64, limits BundleLimits) (*PresetBundle, error) {
+	zr, err := zip.NewReader(r, size)
 	if err != nil {
-		cleanup()
-		return nil, noop, fmt.Errorf("read preset.json: %w", err)
+		return nil, &InputError{Err: fmt.Errorf("read bundle: %w", err)}
 	}
-
-	var preset Preset
-	if err := json.Unmarshal(data, &preset); err != nil {
-		cleanup()
-		return nil, noop, fmt.Errorf("parse preset.json: %w", err)
+	files, err := readZip(zr, limits)
+	if err != nil {
+		return nil, &InputError{Err: fmt.Errorf("read bundle: %w", err)}
 	}
 
-	// Apply canvas preset.
-	if dims, ok := Presets[preset.Canvas.Preset]; ok {
-		preset.Canvas.Width = dims[0]
-		preset.Canvas.Height = dims[1]
+	data, ok := files["preset.json"]
+	if !ok {
+		if len(files) == 0 {
+			return nil, &InputError{Err: errors.New("archive is empty; a .gspresets bundle needs preset.json at its root")}
+		}
+		found := slices.Sorted(maps.Keys(files))
+		if len(found) > 10 {
+			found = append(found[:10], "…")
+		}
+		return nil, &InputError{Err: fmt.Errorf("no preset.json at the archive root; found: %s", strings.Join(found, ", "))}
 	}
-	preset.Canvas.Width = max(preset.Canvas.Width, 1280)
-	preset.Canvas.Height = max(preset.Canvas.Height, 720)
+	preset, err := DecodePreset(data)
+	if err != nil {
+		return nil, &InputError{Err: fmt.Errorf("parse preset.json: %w", err)}
+	}
+	preset.Preview = files[PreviewName]
+	return &PresetBundle{Preset: preset, files: files}, nil
+}
 
-	if preset.Background.Color == "" {
-		preset.Background.Color = "#1a1a2e"
+// Resolve returns the bundle file ref names, relative to the bundle root
+// as preset.json refers to its assets, or nil if there is none. Pass it
+// to NewRendererForFont, Renderer.SetAssetResolver or LintWithResolver.
+func (b *PresetBundle) Resolve(ref string) []byte {
+	if ref == "" {
+		return nil
 	}
+	return b.files[path.Clean(ref)]
+}
 
-	// Resolve asset paths relative to tmpDir.
-	resolveAssetPaths(&preset, tmpDir)
+// Files lists the names of the files in the bundle, sorted.
+func (b *PresetBundle) Files() []string {
+	return slices.Sorted(maps.Keys(b.files))
+}
 
-	// Apply component style defaults.
-	for i := range preset.Components {
-		applyComponentDefaults(&preset.Components[i])
+// Canvas size limits applied by Normalize. A width or height left at zero
+// defaults to DefaultCanvasWidth × DefaultCanvasHeight.
+const (
+	MinCanvasSize       = 16
+	DefaultCanvasWidth  = 1280
+	DefaultCanvasHeight = 720
+)
+
+// MaxCanvasSize is the largest canvas width or height a renderer draws
+// unless RendererOptions.MaxCanvasSize says otherwise, bounding the image
+// a preset can make it allocate (8192×8192 RGBA is 256 MiB).
+const MaxCanvasSize = 8192
+
+// Normalize applies the defaults every entry point shares: canvas preset
+// names, the default and minimum canvas size, the background color, style
+// classes and component style fallbacks. A negative canvas dimension is an
+// error; one under MinCanvasSize is raised to it. The maximum is the
+// renderer's to check; see Renderer.CheckCanvas.
+func (p *Preset) Normalize() error {
+	c := &p.Canvas
+	if c.Width == 0 && c.Height == 0 && c.Preset == "" {
+		c.Defaulted = true
+	}
+	if dims, ok := Presets[c.Preset]; ok {
+		c.Width, c.Height = dims[0], dims[1]
+	}
+	if c.Width < 0 || c.Height < 0 {
+		return fmt.Errorf("canvas %dx%d: width and height must not be negative", c.Width, c.Height)
+	}
+	if c.Width == 0 {
+		c.Width = DefaultCanvasWidth
+	}
+	if c.Height == 0 {
+		c.Height = DefaultCanvasHeight
+	}
+	c.Width = max(c.Width, MinCanvasSize)
+	c.Height = max(c.Height, MinCanvasSize)
+
+	// A transparent background has no color to default.
+	if p.Background.Color == "" && p.Background.Type != "transparent" {
+		p.Background.Color = "#1a1a2e"
 	}
+	for i := range p.Components {
+		p.applyClasses(&p.Components[i])
+		ApplyComponentDefaults(&p.Components[i])
+	}
+	return nil
+}
 
-	return &preset, cleanup, nil
+// DataOptions controls optional processing performed by LoadDataWithOptions.
+type DataOptions struct {
+	// Expand enables ${env:NAME} and ${file:path} substitution in data values.
+	// File references are limited to the data file's directory. Off by default
+	// so untrusted data cannot read the environment or local files.
+	Expand bool
 }
 
 // LoadData reads and parses a data.json file. Returns warnings for issues.
+// A file that cannot be read or parsed is an *InputError: a caller that
+// names a data file needs it.
 func LoadData(path string) (*DataSpec, []string, error) {
+	return LoadDataWithOptions(path, DataOptions{})
+}
+
+// LoadDataWithOptions is LoadData with optional post-processing.
+func LoadDataWithOptions(path string, opts DataOptions) (*DataSpec, []string, error) {
 	var warnings []string
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("read data.json: %w", err)
+		return nil, nil, &InputError{Path: path, Err: fmt.Errorf("read data.json: %w", err)}
 	}
 
 	var spec DataSpec
 	if err := json.Unmarshal(data, &spec); err != nil {
-		warnings = append(warnings, fmt.Sprintf("malformed data.json: %v — using all defaults", err))
-		return &DataSpec{Components: make(map[string]ComponentData)}, warnings, nil
+		return nil, nil, &InputError{Path: path, Err: fmt.Errorf("parse data.json %s: %w", path, err)}
 	}
 
 	if spec.Components == nil {
 		spec.Components = make(map[string]ComponentData)
 	}
 
+	if opts.Expand {
+		warnings = append(warnings, ExpandDataValues(&spec, filepath.Dir(path))...)
+	}
+
 	return &spec, warnings, nil
 }
 
@@ -100,19 +194,51 @@ func resolveAssetPaths(preset *Preset, baseDir string) {
 		if p == "" || filepath.IsAbs(p) {
 			return p
 		}
-		return filepath.Join(baseDir, p)
+		abs := filepath.Join(baseDir, p)
+		logger().Debug("asset path resolved", "ref", p, "path", abs)
+		return abs
 	}
 
 	preset.Font.Path = resolve(preset.Font.Path)
 	preset.Background.Source = resolve(preset.Background.Source)
 
+	for name, s := range preset.Styles {
+		s.BackgroundImage = resolve(s.BackgroundImage)
+		s.MaskImage = resolve(s.MaskImage)
+		s.FontPath = resolve(s.FontPath)
+		preset.Styles[name] = s
+	}
 	for i := range preset.Components {
-		preset.Components[i].Style.BackgroundImage = resolve(preset.Components[i].Style.BackgroundImage)
+		c := &preset.Components[i]
+		c.Style.BackgroundImage = resolve(c.Style.BackgroundImage)
+		c.Style.MaskImage = resolve(c.Style.MaskImage)
+		c.Style.FontPath = resolve(c.Style.FontPath)
+		if o := c.own; o != nil {
+			o.BackgroundImage = resolve(o.BackgroundImage)
+			o.MaskImage = resolve(o.MaskImage)
+			o.FontPath = resolve(o.FontPath)
+		}
+		for _, o := range c.Responsive {
+			if o.Style != nil {
+				o.Style.BackgroundImage = resolve(o.Style.BackgroundImage)
+				o.Style.MaskImage = resolve(o.Style.MaskImage)
+				o.Style.FontPath = resolve(o.Style.FontPath)
+			}
+		}
+		for name, v := range c.Variants {
+			v.BackgroundImage = resolve(v.BackgroundImage)
+			v.MaskImage = resolve(v.MaskImage)
+			v.FontPath = resolve(v.FontPath)
+			c.Variants[name] = v
+		}
 	}
 }
 
-// applyComponentDefaults sets sane fallbacks for component style fields.
-func applyComponentDefaults(c *Component) {
+// ApplyComponentDefaults sets the fallbacks Normalize gives every
+// component: 24pt white left-aligned text, line height 1.5, and visible.
+// Programs that add components to an already normalized preset call it so
+// those render as a loaded preset's would.
+func ApplyComponentDefaults(c *Component) {
 	s := &c.Style
 	if s.FontSize <= 0 {
 		s.FontSize = 24
@@ -134,49 +260,70 @@ func applyComponentDefaults(c *Component) {
 	}
 }
 
-// extractZip extracts all files from a zip reader into destDir.
-func extractZip(r *zip.ReadCloser, destDir string) error {
-	for _, f := range r.File {
-		target := filepath.Join(destDir, f.Name)
+// BundleLimits bounds what LoadPreset and LoadPresetFromReader read from a
+// bundle, so that a small archive cannot expand into gigabytes of memory.
+type BundleLimits struct {
+	Entries   int   // files and directories
+	FileSize  int64 // uncompressed bytes of one file
+	TotalSize int64 // uncompressed bytes of all files
+}
+
+// MaxBundle is the BundleLimits bundles are read within. The defaults
+// match the web editor's import limits. Programs may change it before
+// loading presets.
+var MaxBundle = BundleLimits{Entries: 1000, FileSize: 32 << 20, TotalSize: 256 << 20}
 
+// readZip reads all files from a zip reader into memory, by cleaned
+// entry name, within limits. Sizes are checked against the headers first
+// and again while reading, in case the headers lie.
+func readZip(r *zip.Reader, limits BundleLimits) (map[string][]byte, error) {
+	if len(r.File) > limits.Entries {
+		return nil, &bundleLimitError{fmt.Sprintf("archive has %d entries (limit %d)", len(r.File), limits.Entries)}
+	}
+	files := make(map[string][]byte, len(r.File))
+	var total int64
+	for _, f := range r.File {
+		if path.IsAbs(f.Name) || filepath.IsAbs(f.Name) || strings.HasPrefix(f.Name, `\`) {
+			return nil, fmt.Errorf("illegal absolute path in zip: %s", f.Name)
+		}
+		if f.Mode()&fs.ModeSymlink != 0 {
+			return nil, fmt.Errorf("illegal symlink in zip: %s", f.Name)
+		}
 		// Guard against zip slip.
-		if !strings.HasPrefix(filepath.Clean(target), filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal path in zip: %s", f.Name)
+		name := path.Clean(f.Name)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("illegal path in zip: %s", f.Name)
 		}
-
 		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(target, 0755); err != nil {
-				return err
-			}
 			continue
 		}
 
-		// Ensure parent directory exists.
-		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-			return err
+		if f.UncompressedSize64 > uint64(limits.FileSize) {
+			return nil, &bundleLimitError{fmt.Sprintf("%s: %d bytes uncompressed exceeds the %d-byte file limit", f.Name, f.UncompressedSize64, limits.FileSize)}
 		}
-
-		if err := extractFile(f, target); err != nil {
-			return err
+		data, err := readZipFile(f, min(limits.FileSize, limits.TotalSize-total))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
 		}
+		n := int64(len(data))
+		if n > limits.FileSize {
+			return nil, &bundleLimitError{fmt.Sprintf("%s: more than %d bytes uncompressed, the file limit", f.Name, limits.FileSize)}
+		}
+		if total += n; total > limits.TotalSize {
+			return nil, &bundleLimitError{fmt.Sprintf("%s: archive contents exceed the %d-byte total limit", f.Name, limits.TotalSize)}
+		}
+		files[name] = data
 	}
-	return nil
+	return files, nil
 }
 
-// extractFile writes a single zip entry to disk.
-func extractFile(f *zip.File, target string) error {
+// readZipFile reads a single zip entry, stopping once it has read more
+// than limit bytes.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
 	rc, err := f.Open()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rc.Close()
-
-	out, err := os.Create(target)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, rc)
-	return err
+	return io.ReadAll(io.LimitReader(rc, limit+1))
 }
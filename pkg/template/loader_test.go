@@ -0,0 +1,122 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// zipEntry is a file in a crafted archive. A nonzero size is written to
+// the header in place of the real uncompressed size.
+type zipEntry struct {
+	name string
+	data string
+	size uint64
+	mode fs.FileMode
+}
+
+// craftZip writes entries into a ZIP archive as given, trusting names and
+// header sizes the way an attacker's tool would.
+func craftZip(t *testing.T, entries ...zipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		var compressed bytes.Buffer
+		fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+		fw.Write([]byte(e.data))
+		fw.Close()
+
+		h := &zip.FileHeader{
+			Name:               e.name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE([]byte(e.data)),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: uint64(len(e.data)),
+		}
+		if e.size != 0 {
+			h.UncompressedSize64 = e.size
+		}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		w, err := zw.CreateRaw(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(compressed.Bytes())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestLoadPresetFromReaderLimits feeds crafted archives to the bundle
+// reader: each must be refused, those over a limit with ErrBundleTooLarge.
+func TestLoadPresetFromReaderLimits(t *testing.T) {
+	const preset = `{"canvas": {"width": 32, "height": 32}, "font": {}, "components": []}`
+	limits := BundleLimits{Entries: 10, FileSize: 1 << 10, TotalSize: 4 << 10}
+	presetEntry := zipEntry{name: "preset.json", data: preset}
+
+	var bomb []zipEntry
+	for range limits.Entries + 1 {
+		bomb = append(bomb, zipEntry{name: "assets/x.txt"})
+	}
+
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		tooBig  bool   // want ErrBundleTooLarge
+		message string // in the error
+	}{
+		{"entry bomb", bomb, true, "11 entries (limit 10)"},
+		{"oversized file", []zipEntry{presetEntry, {name: "assets/big.txt", data: strings.Repeat("x", 2<<10)}}, true, "exceeds the 1024-byte file limit"},
+		{"compression bomb", []zipEntry{presetEntry, {name: "assets/zeros.bin", data: string(make([]byte, 1<<20))}}, true, "assets/zeros.bin"},
+		{"size header lies", []zipEntry{presetEntry, {name: "assets/liar.txt", data: strings.Repeat("x", 2<<10), size: 10}}, false, "assets/liar.txt"},
+		{"total over the limit", []zipEntry{
+			presetEntry,
+			{name: "assets/a.txt", data: strings.Repeat("a", 1000)},
+			{name: "assets/b.txt", data: strings.Repeat("b", 1000)},
+			{name: "assets/c.txt", data: strings.Repeat("c", 1000)},
+			{name: "assets/d.txt", data: strings.Repeat("d", 1000)},
+			{name: "assets/e.txt", data: strings.Repeat("e", 1000)},
+		}, true, "exceed the 4096-byte total limit"},
+		{"parent directory", []zipEntry{presetEntry, {name: "../evil.txt", data: "x"}}, false, "illegal path in zip: ../evil.txt"},
+		{"parent directory inside", []zipEntry{presetEntry, {name: "assets/../../evil.txt", data: "x"}}, false, "illegal path in zip: assets/../../evil.txt"},
+		{"parent directory alone", []zipEntry{presetEntry, {name: "..", data: "x"}}, false, "illegal path in zip: .."},
+		{"absolute path", []zipEntry{presetEntry, {name: "/etc/evil.txt", data: "x"}}, false, "illegal absolute path in zip: /etc/evil.txt"},
+		{"absolute Windows path", []zipEntry{presetEntry, {name: `\evil.txt`, data: "x"}}, false, `illegal absolute path in zip: \evil.txt`},
+		{"symlink", []zipEntry{presetEntry, {name: "assets/link", data: "/etc/passwd", mode: fs.ModeSymlink | 0o777}}, false, "illegal symlink in zip: assets/link"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := craftZip(t, tt.entries...)
+			bundle, err := LoadPresetFromReaderWithLimits(bytes.NewReader(data), int64(len(data)), limits)
+			if err == nil {
+				t.Fatalf("loaded %v, want an error", bundle.Files())
+			}
+			if !errors.Is(err, ErrInput) {
+				t.Errorf("error %v does not match ErrInput", err)
+			}
+			if got := errors.Is(err, ErrBundleTooLarge); got != tt.tooBig {
+				t.Errorf("error %v: matches ErrBundleTooLarge %t, want %t", err, got, tt.tooBig)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %v, want it to mention %q", err, tt.message)
+			}
+		})
+	}
+
+	t.Run("within the limits", func(t *testing.T) {
+		data := craftZip(t, presetEntry, zipEntry{name: "assets/a.txt", data: strings.Repeat("a", 1000)})
+		if _, err := LoadPresetFromReaderWithLimits(bytes.NewReader(data), int64(len(data)), limits); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
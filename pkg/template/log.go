@@ -0,0 +1,25 @@
+// log.go — Logging seam for the template package.
+package template
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var pkgLogger atomic.Pointer[slog.Logger]
+
+// SetLogger routes the package's diagnostics to l: asset resolution, face
+// cache activity, per-component timings and render warnings at Debug.
+// Render warnings (missing assets, unusable fonts) are returned by
+// Renderer.Warnings for the caller to report. A nil l restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	pkgLogger.Store(l)
+}
+
+// logger returns the configured logger or slog.Default().
+func logger() *slog.Logger {
+	if l := pkgLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,90 @@
+// mask.go — style.maskImage: clip a component to the shape of an image.
+//
+// A masked component, backdrop included, is drawn on a layer the size of
+// its box; each layer pixel is then scaled by the mask's coverage there
+// and composited onto the canvas. The mask is fitted to the box like a
+// background image, and is zero outside the fitted image and outside the
+// box's rounded corners, so a mask and a cornerRadius intersect.
+package template
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+)
+
+// componentMask loads comp's mask image and returns its coverage over the
+// box. It returns nil when the image cannot be loaded and missingAsset
+// lets the render go on; the component is then drawn unmasked.
+func (r *Renderer) componentMask(comp ResolvedComponent, box componentBox) (*image.Alpha, error) {
+	fit := cmp.Or(comp.Style.MaskFit, "stretch")
+	src, err := r.resolveImage(comp.ID, comp.Style.MaskImage, box.bounds.Size(), fit)
+	if err != nil {
+		return nil, r.missingAsset(comp.ID, comp.Style.MaskImage, err, "could not load mask image %q, drawing unmasked: %v")
+	}
+
+	// Opaque images (grayscale PNGs, JPEGs) mask by brightness, the
+	// others by alpha.
+	o, ok := src.(interface{ Opaque() bool })
+	byLuma := ok && o.Opaque()
+	at := rgbaSampler(src)
+	mask := image.NewAlpha(box.bounds)
+	fitPixels(box.bounds, src.Bounds(), fit, func(x, y, sx, sy int) {
+		c := at(sx, sy)
+		v := c.A
+		if byLuma {
+			v = uint8((299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B) + 500) / 1000)
+		}
+		mask.SetAlpha(x, y, color.Alpha{v})
+	})
+
+	if box.radius > 0 {
+		for y := box.bounds.Min.Y; y < box.bounds.Max.Y; y++ {
+			x0, x1 := roundedSpan(box.bounds, box.radius, y)
+			row := mask.Pix[mask.PixOffset(box.bounds.Min.X, y):mask.PixOffset(box.bounds.Max.X, y)]
+			clear(row[:x0-box.bounds.Min.X])
+			clear(row[max(x1, x0)-box.bounds.Min.X:])
+		}
+	}
+	return mask, nil
+}
+
+// drawMasked composites layer onto dst, each pixel scaled by mask's
+// coverage. layer and mask have the same bounds.
+func drawMasked(dst, layer *image.RGBA, mask *image.Alpha) {
+	b := layer.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			m := uint32(mask.AlphaAt(x, y).A)
+			if m == 0 {
+				continue
+			}
+			c := layer.RGBAAt(x, y)
+			if m < 255 {
+				c = color.RGBA{
+					uint8(uint32(c.R) * m / 255), uint8(uint32(c.G) * m / 255),
+					uint8(uint32(c.B) * m / 255), uint8(uint32(c.A) * m / 255),
+				}
+			}
+			blendPremul(dst, x, y, c)
+		}
+	}
+}
+
+// drawMasked64 is drawMasked onto a 16-bit canvas.
+func drawMasked64(dst *image.RGBA64, layer *image.RGBA, mask *image.Alpha) {
+	b := layer.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			m := uint32(mask.AlphaAt(x, y).A)
+			if m == 0 {
+				continue
+			}
+			c := layer.RGBAAt(x, y)
+			blendPremul64(dst, x, y, color.RGBA64{
+				uint16(uint32(c.R) * 0x101 * m / 255), uint16(uint32(c.G) * 0x101 * m / 255),
+				uint16(uint32(c.B) * 0x101 * m / 255), uint16(uint32(c.A) * 0x101 * m / 255),
+			})
+		}
+	}
+}
@@ -1,59 +1,166 @@
 // merge.go — Merge data.json overrides onto preset defaults.
 package template
 
-import "sort"
+import (
+	"cmp"
+	"image"
+	"math"
+	"slices"
+)
+
+// Canvas.Snap values.
+const (
+	SnapRound = "round" // round each box's position and size to whole pixels
+	SnapFloor = "floor" // truncate each edge, as before snapping was configurable
+	SnapNone  = "none"  // reserved for subpixel layout; rounds until boxes can be fractional
+)
 
 // MergeData combines preset component defaults with user-provided data overrides.
 // Components with visible=false are excluded from the result.
-// Position (X/Y/Width/Height) is always from the preset — data cannot override it.
+// Position (X/Y/Width/Height) is always from the preset — data cannot override it —
+// after the component's responsive overrides for the canvas are applied.
+// Data may replace the component's style classes (see classes.go). Style
+// layers the variant data selects (see variants.go) over the preset's,
+// then data's own style over both.
+// Boxes are clipped to the canvas and snapped to whole pixels as
+// preset.Canvas.Snap says, padding to half the box and font sizes to the
+// canvas height; components left with no area are dropped (RenderPreset
+// warns about them and the capped sizes).
+// When data.Locale is set, that locale's overlay is applied after the base overrides.
+// The result is in paint order: ascending zIndex, and by ID within a
+// zIndex, so stacking does not depend on the order of preset.Components.
 func MergeData(preset *Preset, data *DataSpec) []ResolvedComponent {
-	w := preset.Canvas.Width
-	h := preset.Canvas.Height
+	var locale map[string]ComponentData
+	if data != nil && data.Locale != "" {
+		locale = data.Locales[data.Locale].Components
+	}
 
 	var result []ResolvedComponent
 
 	for _, comp := range preset.Components {
-		merged := comp.Defaults
-
-		// Apply data overrides if present.
+		var override, localized ComponentData
 		if data != nil {
-			if override, ok := data.Components[comp.ID]; ok {
-				mergeComponentData(&merged, override)
-			}
+			override, localized = data.Components[comp.ID], locale[comp.ID]
+		}
+		if classes := comp.selectedClasses(override, localized); classes != nil {
+			comp = preset.withClasses(comp, classes)
 		}
+		comp = comp.forCanvas(preset.Canvas)
+		variant := comp.selectedVariant(override, localized)
+		comp = comp.withVariant(variant)
+
+		// Apply data overrides; zero values change nothing.
+		merged := comp.Defaults
+		mergeComponentData(&merged, override)
+		mergeLocaleData(&merged, localized)
+		merged.Variant = variant
+		merged.Classes = comp.Classes
 
 		// Check visibility.
 		if merged.Visible != nil && !*merged.Visible {
 			continue
 		}
+		box := componentRect(comp, preset.Canvas)
+		if box.Empty() {
+			continue
+		}
 
 		// Merge style: preset style + data style override.
 		finalStyle := comp.Style
 		if merged.Style != nil {
 			mergeComponentStyle(&finalStyle, *merged.Style)
 		}
+		capped := capFontSizes(&finalStyle, float64(preset.Canvas.Height))
 
 		result = append(result, ResolvedComponent{
 			ID:      comp.ID,
-			X:       int(comp.X * float64(w)),
-			Y:       int(comp.Y * float64(h)),
-			Width:   int(comp.Width * float64(w)),
-			Height:  int(comp.Height * float64(h)),
+			X:       box.Min.X,
+			Y:       box.Min.Y,
+			Width:   box.Dx(),
+			Height:  box.Dy(),
 			ZIndex:  comp.ZIndex,
-			Padding: max(comp.Padding, 0),
+			Padding: min(max(comp.Padding, 0), min(box.Dx(), box.Dy())/2),
 			Style:   finalStyle,
 			Data:    merged,
+			capped:  capped,
 		})
 	}
 
-	// Sort by z-index (lower renders first, higher renders on top).
-	sort.SliceStable(result, func(i, j int) bool {
-		return result[i].ZIndex < result[j].ZIndex
+	// Lower z-index renders first, higher on top; ties go by ID.
+	slices.SortStableFunc(result, func(a, b ResolvedComponent) int {
+		return cmp.Or(cmp.Compare(a.ZIndex, b.ZIndex), cmp.Compare(a.ID, b.ID))
 	})
-
 	return result
 }
 
+// componentRect converts a component's canvas fractions to a pixel box on
+// canvas, clipped to it and snapped as canvas.Snap says. The box is empty
+// when none of the component is on the canvas (including negative sizes).
+func componentRect(c Component, canvas Canvas) image.Rectangle {
+	x, width := span(c.X, c.Width, canvas.Width, canvas.Snap)
+	y, height := span(c.Y, c.Height, canvas.Height, canvas.Snap)
+	if width <= 0 || height <= 0 {
+		return image.Rectangle{}
+	}
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// span converts the fractional span [pos, pos+size) to a pixel start and
+// length on an axis of n pixels, clipped to the axis.
+//
+// SnapFloor truncates: spans already on the axis convert as unclipped ones
+// always have, so a 1px border can fall off one side of a box at 0.333.
+// Otherwise the start and the length are each rounded, and the end is
+// their sum, so boxes of the same size are the same number of pixels
+// wherever they are.
+func span(pos, size float64, n int, snap string) (int, int) {
+	lo, hi := clamp01(pos), clamp01(pos+size)
+	unclipped := lo == pos && hi == pos+size
+	if snap == SnapFloor {
+		if unclipped {
+			return int(pos * float64(n)), int(size * float64(n))
+		}
+		start := int(lo * float64(n))
+		return start, int(hi*float64(n)) - start
+	}
+	if !unclipped {
+		size = hi - lo
+	}
+	start := int(math.Round(lo * float64(n)))
+	return start, min(int(math.Round(size*float64(n))), n-start)
+}
+
+// cappedFontSize is a style font size capFontSizes lowered.
+type cappedFontSize struct {
+	field     string // "fontSize" or "titleFontSize"
+	size, max float64
+}
+
+// capFontSizes caps s's font sizes at limit, the canvas height, so that no
+// size can make the rasterizer allocate glyphs without bound, and returns
+// the sizes it lowered.
+func capFontSizes(s *ComponentStyle, limit float64) []cappedFontSize {
+	var capped []cappedFontSize
+	for _, f := range []struct {
+		field string
+		size  *float64
+	}{{"fontSize", &s.FontSize}, {"titleFontSize", &s.TitleFontSize}} {
+		if *f.size > limit {
+			capped = append(capped, cappedFontSize{f.field, *f.size, limit})
+			*f.size = limit
+		}
+	}
+	return capped
+}
+
+// clamp01 limits v to [0, 1]; NaN becomes 0.
+func clamp01(v float64) float64 {
+	if !(v > 0) {
+		return 0
+	}
+	return min(v, 1)
+}
+
 // mergeComponentData overlays user overrides onto defaults.
 func mergeComponentData(base *ComponentData, over ComponentData) {
 	if over.Visible != nil {
@@ -68,6 +175,35 @@ func mergeComponentData(base *ComponentData, over ComponentData) {
 	if over.Style != nil {
 		base.Style = over.Style
 	}
+	if over.Variant != "" {
+		base.Variant = over.Variant
+	}
+	if over.Classes != nil {
+		base.Classes = over.Classes // replace, not append
+	}
+}
+
+// mergeLocaleData overlays a locale's overrides. Unlike mergeComponentData,
+// a style override is layered on the base override rather than replacing it,
+// so a locale can adjust e.g. fontSize without restating the base colors.
+func mergeLocaleData(base *ComponentData, over ComponentData) {
+	style := base.Style
+	mergeComponentData(base, over)
+	if style != nil && over.Style != nil {
+		layered := *style
+		mergeComponentStyle(&layered, *over.Style)
+		base.Style = &layered
+	}
+}
+
+// LocaleNames returns the locales defined in data, sorted by name.
+func (d *DataSpec) LocaleNames() []string {
+	names := make([]string, 0, len(d.Locales))
+	for name := range d.Locales {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
 }
 
 // mergeComponentStyle applies non-zero style overrides.
@@ -87,12 +223,48 @@ func mergeComponentStyle(base *ComponentStyle, over ComponentStyle) {
 	if over.BorderWidth > 0 {
 		base.BorderWidth = over.BorderWidth
 	}
-	if over.CornerRadius > 0 {
+	if over.BorderStyle != "" {
+		base.BorderStyle = over.BorderStyle
+	}
+	if over.DashLength > 0 {
+		base.DashLength = over.DashLength
+	}
+	if over.GapLength > 0 {
+		base.GapLength = over.GapLength
+	}
+	if over.CornerRadius != 0 {
 		base.CornerRadius = over.CornerRadius
 	}
+	if over.BackdropBlur > 0 {
+		base.BackdropBlur = over.BackdropBlur
+	}
+	if over.MaskImage != "" {
+		base.MaskImage = over.MaskImage
+	}
+	if over.MaskFit != "" {
+		base.MaskFit = over.MaskFit
+	}
 	if over.FontPath != "" {
 		base.FontPath = over.FontPath
 	}
+	if over.TitleFontSize > 0 {
+		base.TitleFontSize = over.TitleFontSize
+	}
+	if over.TitleColor != "" {
+		base.TitleColor = over.TitleColor
+	}
+	if over.TitleSpacing != nil {
+		base.TitleSpacing = over.TitleSpacing
+	}
+	if over.Arc != nil {
+		base.Arc = over.Arc
+	}
+	if over.MaxLines > 0 {
+		base.MaxLines = over.MaxLines
+	}
+	if over.MoreFormat != "" {
+		base.MoreFormat = over.MoreFormat
+	}
 	if over.FontSize > 0 {
 		base.FontSize = over.FontSize
 	}
@@ -102,6 +274,9 @@ func mergeComponentStyle(base *ComponentStyle, over ComponentStyle) {
 	if over.LineHeight > 0 {
 		base.LineHeight = over.LineHeight
 	}
+	if over.ItemSpacing != 0 {
+		base.ItemSpacing = over.ItemSpacing
+	}
 	if over.TextAlign != "" {
 		base.TextAlign = over.TextAlign
 	}
@@ -0,0 +1,71 @@
+package template
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// FuzzRenderPresetGeometry renders a component with arbitrary geometry,
+// padding, border, corner radius and font size: none of it may panic, and
+// every resolved box must lie on the canvas.
+func FuzzRenderPresetGeometry(f *testing.F) {
+	f.Add(0.1, 0.1, 0.8, 0.8, 4, 2, 6, 24.0)
+	f.Add(-0.5, 1.5, -1.0, 2.0, 1000, -3, 1<<30, 0.0)
+	f.Add(0.9, 0.9, 1e308, 1e308, math.MaxInt32, math.MaxInt32, math.MaxInt32, 4e7)
+	f.Add(math.NaN(), math.Inf(1), math.Inf(-1), math.NaN(), -1, 0, -1, math.Inf(1))
+	f.Add(0.0, 0.0, 1e-9, 1e-9, 0, 0, 0, 1e-9)
+
+	renderer, err := NewRenderer("")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, x, y, w, h float64, padding, border, radius int, fontSize float64) {
+		preset := &Preset{
+			Canvas:     Canvas{Width: 64, Height: 48},
+			Background: Background{Type: "color", Color: "#000000"},
+			Components: []Component{{
+				ID: "c", X: x, Y: y, Width: w, Height: h, Padding: padding,
+				Style: ComponentStyle{
+					FontSize: fontSize, TitleFontSize: fontSize, LineHeight: 1.2,
+					Color: "#ffffff", BackgroundColor: "#336699",
+					BorderWidth: border, BorderColor: "#ff0000", CornerRadius: Radius(radius),
+				},
+				Defaults: ComponentData{Title: "Title", Items: []TextItem{{Type: "bullet", Text: "item"}}},
+			}},
+		}
+		if err := preset.Normalize(); err != nil {
+			t.Fatal(err)
+		}
+
+		components := MergeData(preset, nil)
+		for _, c := range components {
+			if c.X < 0 || c.Y < 0 || c.Width <= 0 || c.Height <= 0 || c.X+c.Width > 64 || c.Y+c.Height > 48 {
+				t.Errorf("box %d,%d %dx%d is not on the 64x48 canvas", c.X, c.Y, c.Width, c.Height)
+			}
+			if c.Style.FontSize > 48 || c.Style.TitleFontSize > 48 {
+				t.Errorf("font sizes %g, %g not capped at the canvas height", c.Style.FontSize, c.Style.TitleFontSize)
+			}
+		}
+		if _, err := renderer.RenderPreset(preset, components); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// TestMigratedStacking loads the bundled minimal_resume preset, a format 1
+// file whose header overflows onto the contact bar, and checks that the
+// upgrade keeps the contact bar on top, as file order drew it.
+func TestMigratedStacking(t *testing.T) {
+	bundle, err := LoadPreset("../../presets/minimal_resume.gspresets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, c := range MergeData(bundle.Preset, nil) {
+		order = append(order, c.ID)
+	}
+	if h, c := slices.Index(order, "name_header"), slices.Index(order, "contact"); h < 0 || c < h {
+		t.Errorf("paint order %v, want contact above name_header", order)
+	}
+}
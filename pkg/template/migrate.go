@@ -0,0 +1,122 @@
+// migrate.go — Preset format versions and the upgrades between them.
+//
+// A preset.json records the format it was written for in formatVersion
+// (absent means 1). Every entry point decodes presets through
+// DecodePreset, which upgrades older documents one version at a time
+// before unmarshaling, so a change in meaning never silently misreads an
+// old file, and refuses files from a newer build.
+package template
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// FormatVersion is the preset format this build reads and writes.
+//
+//	1  original format
+//	2  components with equal zIndex are drawn in ID order, not file order
+const FormatVersion = 2
+
+// ErrNewerFormat matches (via errors.Is) the error for a preset written for
+// a newer format than FormatVersion.
+var ErrNewerFormat = errors.New("preset format is newer than this build supports")
+
+// migrations[v-1] upgrades a version v document, decoded as generic JSON,
+// to version v+1.
+var migrations = []func(doc map[string]any) error{
+	migrateZIndexTies,
+}
+
+// DecodePreset parses preset.json, upgrading an older format first, and
+// applies Normalize's defaults. It is how the loader, the server and the
+// WASM client read presets.
+func DecodePreset(raw []byte) (*Preset, error) {
+	raw, err := MigratePreset(raw)
+	if err != nil {
+		return nil, err
+	}
+	var p Preset
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Normalize(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// MigratePreset upgrades raw preset JSON to FormatVersion, returning it
+// unchanged when it is already current. A formatVersion above FormatVersion
+// is an error wrapping ErrNewerFormat.
+func MigratePreset(raw []byte) ([]byte, error) {
+	var head struct {
+		FormatVersion int `json:"formatVersion"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+	version := max(head.FormatVersion, 1)
+	switch {
+	case version > FormatVersion:
+		return nil, fmt.Errorf("%w: it is version %d, this build reads up to %d — upgrade GoStencil to use it", ErrNewerFormat, version, FormatVersion)
+	case version == FormatVersion:
+		return raw, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for v := version; v < FormatVersion; v++ {
+		if err := migrations[v-1](doc); err != nil {
+			return nil, fmt.Errorf("upgrade preset format %d to %d: %w", v, v+1, err)
+		}
+		logger().Debug("preset format upgraded", "from", v, "to", v+1)
+	}
+	doc["formatVersion"] = FormatVersion
+	return json.Marshal(doc)
+}
+
+// migrateZIndexTies upgrades 1 → 2. Version 1 drew components with equal
+// zIndex in file order; version 2 draws them by ID. Raising each tied
+// component's zIndex just above the one before it (in version 1's drawing
+// order) keeps the old stacking. Presets without ties are unchanged.
+func migrateZIndexTies(doc map[string]any) error {
+	comps, _ := doc["components"].([]any)
+	type entry struct {
+		comp map[string]any
+		z    int64
+	}
+	var order []entry
+	for _, c := range comps {
+		comp, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		var z int64
+		if n, ok := comp["zIndex"].(json.Number); ok {
+			f, err := n.Float64()
+			if err != nil {
+				return fmt.Errorf("component %v: zIndex: %w", comp["id"], err)
+			}
+			z = int64(f)
+		}
+		order = append(order, entry{comp, z})
+	}
+	slices.SortStableFunc(order, func(a, b entry) int { return cmp.Compare(a.z, b.z) })
+
+	for i := 1; i < len(order); i++ {
+		if prev := order[i-1].z; order[i].z <= prev {
+			order[i].z = prev + 1
+			order[i].comp["zIndex"] = order[i].z
+		}
+	}
+	return nil
+}
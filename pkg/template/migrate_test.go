@@ -0,0 +1,70 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrateFixtures upgrades testdata/migrate/v0.json (no formatVersion)
+// and v1.json, two overlapping components with equal zIndex, and checks
+// that they render as v2.json, the same preset written for version 2, with
+// the later component in the file on top as version 1 drew it.
+func TestMigrateFixtures(t *testing.T) {
+	render := func(name string) []byte {
+		t.Helper()
+		raw, err := os.ReadFile(filepath.Join("testdata", "migrate", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		preset, err := DecodePreset(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preset.FormatVersion != FormatVersion {
+			t.Errorf("%s: formatVersion %d after loading, want %d", name, preset.FormatVersion, FormatVersion)
+		}
+		r, err := NewRenderer("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := r.RenderPreset(preset, MergeData(preset, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// zeta covers x 0–47 and alpha x 16–63; in version 1 alpha, later
+		// in the file, is drawn over zeta where they overlap. base, below
+		// both, never shows.
+		for _, p := range []struct {
+			x    int
+			want color.RGBA
+		}{
+			{8, color.RGBA{0xff, 0, 0, 0xff}},
+			{32, color.RGBA{0, 0, 0xff, 0xff}},
+			{56, color.RGBA{0, 0, 0xff, 0xff}},
+		} {
+			if got := img.RGBAAt(p.x, 40); got != p.want {
+				t.Errorf("%s: pixel at x %d is %v, want %v", name, p.x, got, p.want)
+			}
+		}
+		return img.Pix
+	}
+
+	want := render("v2.json")
+	for _, name := range []string{"v0.json", "v1.json"} {
+		if got := render(name); !bytes.Equal(got, want) {
+			t.Errorf("%s renders differently from v2.json", name)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join("testdata", "migrate", "v3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodePreset(raw); !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("v3.json: error %v, want ErrNewerFormat", err)
+	}
+}
@@ -1,16 +1,33 @@
 // Package template provides JSON-driven image generation via presets and components.
 package template
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // ── Preset types ──
 
 // Preset is the top-level structure of a preset.json file.
 type Preset struct {
+	FormatVersion int `json:"formatVersion,omitempty"` // see FormatVersion; 0 or absent means 1
+
 	Meta       Meta        `json:"meta"`
 	Canvas     Canvas      `json:"canvas"`
 	Background Background  `json:"background"`
 	Font       FontConfig  `json:"font"`
 	Components []Component `json:"components"`
 	Schema     Schema      `json:"schema"`
+
+	// Styles are named partial styles components share by listing them in
+	// Component.Classes; see classes.go.
+	Styles map[string]ComponentStyle `json:"styles,omitempty"`
+
+	// Preview is the bundle's preview.png as LoadPreset found it, or nil.
+	Preview []byte `json:"-"`
 }
 
 // Meta holds preset metadata.
@@ -26,19 +43,32 @@ type Canvas struct {
 	Width  int    `json:"width"`
 	Height int    `json:"height"`
 	Preset string `json:"preset"`
+
+	// Snap is how component boxes are snapped to whole pixels: SnapRound
+	// (the default), SnapFloor or SnapNone; see componentRect.
+	Snap string `json:"snap,omitempty"`
+
+	// Defaulted is set by Normalize when the preset gives neither a size
+	// nor a preset, and the canvas has the default size.
+	Defaulted bool `json:"-"`
 }
 
 // Background defines the canvas fill.
 type Background struct {
-	Type   string `json:"type"`   // "image" or "color"
+	Type   string `json:"type"`   // "image", "color" or "transparent"
 	Source string `json:"source"` // path to image file (resolved from assets)
 	Color  string `json:"color"`  // hex fallback
 }
 
 // FontConfig specifies the font source.
 type FontConfig struct {
-	Path     string `json:"path"`     // custom TTF path (resolved from assets)
+	Path     string `json:"path"`     // custom TTF/OTF/TTC path (resolved from assets)
 	Fallback string `json:"fallback"` // "embedded" for default
+
+	// Index or Family (a family or full name, which wins) picks the font
+	// from a collection (.ttc); single fonts ignore them.
+	Index  int    `json:"index,omitempty"`
+	Family string `json:"family,omitempty"`
 }
 
 // ── Component types ──
@@ -55,21 +85,181 @@ type Component struct {
 	Padding  int            `json:"padding"`
 	Style    ComponentStyle `json:"style"`
 	Defaults ComponentData  `json:"defaults"`
+
+	// Classes names entries of Preset.Styles applied, in order, beneath
+	// Style; see classes.go.
+	Classes []string `json:"classes,omitempty"`
+
+	// own is Style as the preset wrote it, before classes and defaults,
+	// kept by Normalize so data can pick other classes.
+	own *ComponentStyle
+
+	// Responsive adjusts the component for particular canvases, keyed by
+	// canvas preset name or aspect ratio range; see responsive.go.
+	Responsive map[string]ResponsiveOverride `json:"responsive,omitempty"`
+
+	// Variants are named partial styles data picks one of by name with
+	// ComponentData.Variant, such as a set of approved stickers; see
+	// variants.go.
+	Variants map[string]ComponentStyle `json:"variants,omitempty"`
+}
+
+// ResponsiveOverride is a partial position and style applied to a
+// component on the canvases its key matches. Unset fields keep the
+// component's own values.
+type ResponsiveOverride struct {
+	X       *float64        `json:"x,omitempty"`
+	Y       *float64        `json:"y,omitempty"`
+	Width   *float64        `json:"width,omitempty"`
+	Height  *float64        `json:"height,omitempty"`
+	ZIndex  *int            `json:"zIndex,omitempty"`
+	Padding *int            `json:"padding,omitempty"`
+	Style   *ComponentStyle `json:"style,omitempty"`
 }
 
 // ComponentStyle defines the visual appearance of a component container.
 type ComponentStyle struct {
-	BackgroundColor string  `json:"backgroundColor"` // "#rrggbb" or "#rrggbbaa"
-	BackgroundImage string  `json:"backgroundImage"` // path to PNG/JPG sticker
-	BackgroundFit   string  `json:"backgroundFit"`   // "stretch" (default), "contain", "cover"
-	BorderColor     string  `json:"borderColor"`
-	BorderWidth     int     `json:"borderWidth"`
-	CornerRadius    int     `json:"cornerRadius"`
-	FontPath        string  `json:"fontPath"` // per-component custom font (asset ID or path)
-	FontSize        float64 `json:"fontSize"`
-	Color           string  `json:"color"`      // text color
-	LineHeight      float64 `json:"lineHeight"` // multiplier
-	TextAlign       string  `json:"textAlign"`  // "left", "center", "right"
+	BackgroundColor string     `json:"backgroundColor"` // "#rrggbb" or "#rrggbbaa"
+	BackgroundImage string     `json:"backgroundImage"` // path to PNG/JPG sticker
+	BackgroundFit   string     `json:"backgroundFit"`   // "stretch" (default), "contain", "cover"
+	BorderColor     string     `json:"borderColor"`     // as Color, "auto" or "auto-contrast" too
+	BorderWidth     int        `json:"borderWidth"`
+	BorderStyle     string     `json:"borderStyle,omitempty"` // "solid" (default), "dashed", "dotted"
+	DashLength      float64    `json:"dashLength,omitempty"`  // dashed: dash length in pixels (default 3 × borderWidth)
+	GapLength       float64    `json:"gapLength,omitempty"`   // space between dashes or dots (default 2 × / 1 × borderWidth)
+	CornerRadius    Radius     `json:"cornerRadius"`
+	FontPath        string     `json:"fontPath"` // per-component custom font (asset ID or path)
+	FontSize        float64    `json:"fontSize"`
+	Color           string     `json:"color"`                 // text color; "auto" or "auto-contrast" pick one (autocolor.go)
+	LineHeight      LineHeight `json:"lineHeight"`            // multiplier, or pixels as "28px"
+	TextAlign       string     `json:"textAlign"`             // "left", "center", "right"
+	ItemSpacing     float64    `json:"itemSpacing,omitempty"` // extra pixels between items, not between an item's wrapped lines
+
+	TitleFontSize float64  `json:"titleFontSize"`          // default 1.4 × FontSize
+	TitleColor    string   `json:"titleColor"`             // default Color
+	TitleSpacing  *float64 `json:"titleSpacing,omitempty"` // pixels below the title; default half the title size
+
+	Arc *ArcStyle `json:"arc,omitempty"` // curve the text around the box center
+
+	// BackdropBlur blurs what is drawn behind the box, with this standard
+	// deviation in pixels, before backgroundColor tints it (frosted glass).
+	BackdropBlur float64 `json:"backdropBlur,omitempty"`
+
+	// MaskImage clips the whole component to an image (asset ID or path),
+	// fitted to the box by MaskFit as backgroundFit fits backgroundImage.
+	// An image with transparency masks by its alpha, an opaque one, such as
+	// a grayscale PNG, by its brightness. See mask.go.
+	MaskImage string `json:"maskImage,omitempty"`
+	MaskFit   string `json:"maskFit,omitempty"` // "stretch" (default), "contain", "cover"
+
+	MaxLines   int    `json:"maxLines,omitempty"`   // at most this many lines, title included; 0 = no limit
+	MoreFormat string `json:"moreFormat,omitempty"` // last line when lines are dropped; %d is their count (default "+%d more")
+}
+
+// DefaultMoreFormat is the line that replaces the lines a component's
+// maxLines drops, unless its style sets moreFormat.
+const DefaultMoreFormat = "+%d more"
+
+// moreLine is the line standing in for n dropped lines.
+func (s *ComponentStyle) moreLine(n int) string {
+	format := s.MoreFormat
+	if format == "" {
+		format = DefaultMoreFormat
+	}
+	return strings.ReplaceAll(format, "%d", strconv.Itoa(n))
+}
+
+// ArcStyle sets text along a circle centered on the component box. The
+// title and each item become one unwrapped line on its own concentric
+// circle, the title outermost (clockwise) or innermost (counterclockwise);
+// textAlign places each line: "left" starts it at StartAngle, "center"
+// centers it there, "right" ends it there.
+type ArcStyle struct {
+	Radius     float64 `json:"radius,omitempty"`    // baseline radius of the first line in pixels; default fits the box
+	StartAngle float64 `json:"startAngle"`          // degrees clockwise from 12 o'clock
+	Direction  string  `json:"direction,omitempty"` // "clockwise" (default; letters upright on top) or "counterclockwise" (upright on the bottom)
+	Sweep      float64 `json:"sweep,omitempty"`     // degrees of arc a line may fill (default 360)
+}
+
+// titleSize is the font size titles are drawn at.
+func (s *ComponentStyle) titleSize() float64 {
+	if s.TitleFontSize > 0 {
+		return s.TitleFontSize
+	}
+	return s.FontSize * 1.4
+}
+
+// titleColor is the color titles are drawn in.
+func (s *ComponentStyle) titleColor() string {
+	if s.TitleColor != "" {
+		return s.TitleColor
+	}
+	return s.Color
+}
+
+// titleSpacing is the gap in pixels between the title and the items.
+func (s *ComponentStyle) titleSpacing() float64 {
+	if s.TitleSpacing != nil {
+		return max(*s.TitleSpacing, 0)
+	}
+	return s.titleSize() * 0.5
+}
+
+// LineHeight is the distance between baselines. Up to
+// MaxLineHeightMultiplier it is a multiple of the font size; above it, a
+// number of pixels. JSON takes a number, or pixels written "28px".
+type LineHeight float64
+
+// MaxLineHeightMultiplier is the largest LineHeight taken as a multiplier.
+const MaxLineHeightMultiplier = 4
+
+// UnmarshalJSON accepts a number or a pixel string such as "28px".
+func (l *LineHeight) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) != nil {
+		var n float64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("lineHeight: want a multiplier or pixels such as \"28px\", got %s", b)
+		}
+		*l = LineHeight(n)
+		return nil
+	}
+	px, err := strconv.ParseFloat(strings.TrimSuffix(s, "px"), 64)
+	if err != nil || !strings.HasSuffix(s, "px") {
+		return fmt.Errorf("lineHeight: want a multiplier or pixels such as \"28px\", got %s", b)
+	}
+	if px <= MaxLineHeightMultiplier {
+		return fmt.Errorf("lineHeight %s: pixel line heights must be above %d", b, MaxLineHeightMultiplier)
+	}
+	*l = LineHeight(px)
+	return nil
+}
+
+// itemSpacing is the extra gap in pixels between items.
+func (s *ComponentStyle) itemSpacing() float64 {
+	return max(s.ItemSpacing, 0)
+}
+
+// Radius is a corner radius in pixels. RadiusFull, written "full" (or -1)
+// in JSON, asks for the largest radius the box allows: a pill, or a circle
+// for a square box. Larger radii are capped the same way when drawn.
+type Radius int
+
+// RadiusFull rounds the whole short side of a box.
+const RadiusFull Radius = -1
+
+// UnmarshalJSON accepts a number of pixels or "full".
+func (r *Radius) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte(`"full"`)) {
+		*r = RadiusFull
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("cornerRadius: want pixels or \"full\", got %s", b)
+	}
+	*r = Radius(n)
+	return nil
 }
 
 // ComponentData holds the content and visibility for a component.
@@ -78,7 +268,9 @@ type ComponentData struct {
 	Visible *bool           `json:"visible,omitempty"` // nil = inherit default (true)
 	Title   string          `json:"title,omitempty"`
 	Items   []TextItem      `json:"items,omitempty"`
-	Style   *ComponentStyle `json:"style,omitempty"` // per-component style override
+	Style   *ComponentStyle `json:"style,omitempty"`   // per-component style override
+	Variant string          `json:"variant,omitempty"` // a key of Component.Variants
+	Classes []string        `json:"classes,omitempty"` // keys of Preset.Styles, replacing Component.Classes
 }
 
 // TextItem defines a single text entry within a component.
@@ -92,6 +284,14 @@ type TextItem struct {
 // DataSpec is the top-level structure of data.json.
 type DataSpec struct {
 	Components map[string]ComponentData `json:"components"`
+	Locales    map[string]LocaleData    `json:"locales,omitempty"` // locale name → overlay
+	Locale     string                   `json:"locale,omitempty"`  // active locale ("" = base only)
+}
+
+// LocaleData holds per-locale component overrides applied on top of
+// DataSpec.Components when its locale is active.
+type LocaleData struct {
+	Components map[string]ComponentData `json:"components"`
 }
 
 // ── Schema types (self-documenting presets) ──
@@ -105,7 +305,8 @@ type Schema struct {
 // SchemaComponent documents one component's editable fields.
 type SchemaComponent struct {
 	Description string            `json:"description"`
-	Fields      map[string]string `json:"fields"` // field name → description
+	Fields      map[string]string `json:"fields"`             // field name → description
+	Required    []string          `json:"required,omitempty"` // fields data.json must set: title, items, style, visible
 }
 
 // ── Resolved types (after merging defaults + data) ──
@@ -120,11 +321,14 @@ type ResolvedComponent struct {
 	Padding int
 	Style   ComponentStyle
 	Data    ComponentData
+
+	capped []cappedFontSize // font sizes MergeData lowered, for warnings
 }
 
 // ── Presets for common resolutions ──
 
-// Presets maps preset names to [width, height].
+// Presets maps preset names to [width, height]. Add to it with
+// RegisterCanvasPreset.
 var Presets = map[string][2]int{
 	"720p":             {1280, 720},
 	"1080p":            {1920, 1080},
@@ -132,6 +336,10 @@ var Presets = map[string][2]int{
 	"instagram_square": {1080, 1080},
 	"instagram_story":  {1080, 1920},
 	"youtube_thumb":    {1280, 720},
+	"twitter_card":     {1200, 628},
+	"og_image":         {1200, 630},
+	"pinterest":        {1000, 1500},
+	"a4_print":         {2480, 3508}, // A4 at 300 dpi
 }
 
 // ── Legacy support ──
@@ -0,0 +1,41 @@
+// only.go — Render a subset of a preset's components, to iterate on one of
+// them, or test it, without drawing the rest of the canvas.
+package template
+
+import (
+	"fmt"
+	"image"
+	"slices"
+)
+
+// OnlyComponents returns the components of resolved, from MergeData, whose
+// IDs are in ids, in paint order and at their canvas positions; the preset
+// background is still drawn under them. Each ID that names no component of
+// the preset yields a warning, worded like ValidateData's. A hidden
+// component is known but not drawn.
+func OnlyComponents(preset *Preset, resolved []ResolvedComponent, ids []string) ([]ResolvedComponent, []string) {
+	var warnings []string
+	for _, id := range ids {
+		if !slices.ContainsFunc(preset.Components, func(c Component) bool { return c.ID == id }) {
+			warnings = append(warnings, fmt.Sprintf("component filter references unknown component %q — ignored", id))
+		}
+	}
+	kept := slices.DeleteFunc(slices.Clone(resolved), func(c ResolvedComponent) bool {
+		return !slices.Contains(ids, c.ID)
+	})
+	return kept, warnings
+}
+
+// ComponentBounds returns the union of the components' boxes, grown by pad
+// pixels on every side and clipped to preset's canvas: the area to crop a
+// render of just those components to. It is empty when components is.
+func ComponentBounds(preset *Preset, components []ResolvedComponent, pad int) image.Rectangle {
+	var r image.Rectangle
+	for _, c := range components {
+		r = r.Union(image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height))
+	}
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	return r.Inset(-pad).Intersect(image.Rect(0, 0, preset.Canvas.Width, preset.Canvas.Height))
+}
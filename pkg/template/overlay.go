@@ -0,0 +1,64 @@
+// overlay.go — Rendering onto an image the caller already has
+// (RenderPresetOver): a photo in memory takes the place of the preset's
+// background, and the components are drawn over it.
+package template
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// SetOverFit sets how RenderPresetOver fits its base image to the canvas:
+// "stretch" (the default), "contain" or "cover", as for backgroundFit.
+func (r *Renderer) SetOverFit(fit string) {
+	r.overFit = fit
+}
+
+// RenderPresetOver renders preset's components over base instead of the
+// preset's background. base is fitted to the canvas as SetOverFit says;
+// what it leaves uncovered is transparent. base is not modified.
+func (r *Renderer) RenderPresetOver(base image.Image, preset *Preset, components []ResolvedComponent) (*image.RGBA, error) {
+	return r.RenderPresetOverContext(context.Background(), base, preset, components)
+}
+
+// RenderPresetOverContext is RenderPresetOver with cancellation, as
+// RenderPresetContext is to RenderPreset.
+func (r *Renderer) RenderPresetOverContext(ctx context.Context, base image.Image, preset *Preset, components []ResolvedComponent) (*image.RGBA, error) {
+	fit := r.overFit
+	switch fit {
+	case "":
+		fit = "stretch"
+	case "stretch", "contain", "cover":
+	default:
+		return nil, fmt.Errorf("over fit %q: use stretch, contain or cover", fit)
+	}
+	if base == nil || base.Bounds().Empty() {
+		return nil, fmt.Errorf("base image is empty")
+	}
+
+	// Text is checked for legibility as over a background image: what is
+	// behind it is not a known color.
+	over := *preset
+	over.Background = Background{Type: "image"}
+	if err := r.beginRender(&over, components); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, preset.Canvas.Width, preset.Canvas.Height))
+	drawFit(img, base, fit)
+	r.lap(phaseBackground)
+
+	if err := r.drawComponents(ctx, img, components); err != nil {
+		return nil, err
+	}
+	r.endProfile()
+	return img, nil
+}
+
+// DecodeImage decodes PNG or JPEG data the way the renderer decodes
+// images, turning a JPEG upright according to its EXIF orientation. Other
+// formats decode if their package is registered with image.
+func DecodeImage(data []byte) (image.Image, error) {
+	img, _, err := decodeImage(data)
+	return img, err
+}
@@ -0,0 +1,35 @@
+// pool.go — Recycled render buffers for RenderPresetInto.
+package template
+
+import (
+	"image"
+	"sync"
+)
+
+// ImagePool recycles canvas-sized RGBA buffers between renders, so a
+// server or batch run does not allocate (and collect) a full canvas per
+// render. The zero value is ready to use, and it is safe for concurrent
+// use.
+type ImagePool struct {
+	pool sync.Pool // of *image.RGBA, reshaped by Get
+}
+
+// Get returns a width×height image with bounds at the origin, reusing a
+// pooled buffer when one is large enough. Its contents are undefined;
+// RenderPresetInto overwrites them.
+func (p *ImagePool) Get(width, height int) *image.RGBA {
+	n := 4 * width * height
+	if img, ok := p.pool.Get().(*image.RGBA); ok && cap(img.Pix) >= n {
+		img.Pix, img.Stride, img.Rect = img.Pix[:n], 4*width, image.Rect(0, 0, width, height)
+		return img
+	}
+	return image.NewRGBA(image.Rect(0, 0, width, height))
+}
+
+// Put returns img, from Get or image.NewRGBA, to the pool. img must not
+// be used afterwards.
+func (p *ImagePool) Put(img *image.RGBA) {
+	if img != nil {
+		p.pool.Put(img)
+	}
+}
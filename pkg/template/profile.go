@@ -0,0 +1,130 @@
+// profile.go — Per-component render timings (SetProfile), to find the
+// component that makes a preset slow without a profiler.
+package template
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RenderProfile is the wall time a render took, the preset background's
+// share, and each drawn component's, in paint order. Durations marshal to
+// JSON as nanoseconds.
+type RenderProfile struct {
+	Total      time.Duration      `json:"total"`
+	Background time.Duration      `json:"background"` // the preset background
+	Components []ComponentProfile `json:"components"`
+
+	start time.Time
+}
+
+// ComponentProfile is the time one component took to draw, by phase. The
+// phases add up to Total, less the little time spent between them.
+type ComponentProfile struct {
+	ID         string        `json:"id"`
+	Total      time.Duration `json:"total"`
+	Background time.Duration `json:"background"` // backdrop blur and background color
+	Image      time.Duration `json:"image"`      // loading and drawing the background image and mask
+	Border     time.Duration `json:"border"`
+	Text       time.Duration `json:"text"` // layout and drawing of title and items
+}
+
+// String formats the profile for logs: the totals, then a line per
+// component.
+func (p *RenderProfile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "render %v (background %v)", p.Total.Round(time.Microsecond), p.Background.Round(time.Microsecond))
+	for _, c := range p.Components {
+		fmt.Fprintf(&b, "\n  %s %v: background %v, image %v, border %v, text %v", c.ID,
+			c.Total.Round(time.Microsecond), c.Background.Round(time.Microsecond), c.Image.Round(time.Microsecond),
+			c.Border.Round(time.Microsecond), c.Text.Round(time.Microsecond))
+	}
+	return b.String()
+}
+
+// renderPhase selects a ComponentProfile field.
+type renderPhase int
+
+const (
+	phaseBackground renderPhase = iota
+	phaseImage
+	phaseBorder
+	phaseText
+)
+
+// SetProfile makes renders record how long the background and each
+// component took to draw, by phase, for Profile. When off, the default,
+// rendering reads no clocks for it and allocates nothing.
+func (r *Renderer) SetProfile(on bool) {
+	r.profiling = on
+	if !on {
+		r.profile = nil
+	}
+}
+
+// Profile returns the timings of the most recent render, or nil unless
+// SetProfile is on.
+func (r *Renderer) Profile() *RenderProfile {
+	return r.profile
+}
+
+// startProfile starts a render's profile when profiling.
+func (r *Renderer) startProfile() {
+	r.profile, r.current = nil, nil
+	if r.profiling {
+		now := time.Now()
+		r.profile = &RenderProfile{start: now}
+		r.lapStart = now
+	}
+}
+
+// startComponentProfile starts timing component id, at start.
+func (r *Renderer) startComponentProfile(id string, start time.Time) {
+	if r.profile == nil {
+		return
+	}
+	r.profile.Components = append(r.profile.Components, ComponentProfile{ID: id})
+	r.current = &r.profile.Components[len(r.profile.Components)-1]
+	r.lapStart = start
+}
+
+// endComponentProfile records the component's total, since start.
+func (r *Renderer) endComponentProfile(start time.Time) {
+	if r.current != nil {
+		r.current.Total = time.Since(start)
+		r.current = nil
+	}
+}
+
+// lap charges the time since the previous lap to phase of the component
+// being drawn, or, between components, to the preset background.
+func (r *Renderer) lap(phase renderPhase) {
+	if r.profile == nil {
+		return
+	}
+	now := time.Now()
+	d := now.Sub(r.lapStart)
+	r.lapStart = now
+	if r.current == nil {
+		r.profile.Background += d
+		return
+	}
+	switch phase {
+	case phaseBackground:
+		r.current.Background += d
+	case phaseImage:
+		r.current.Image += d
+	case phaseBorder:
+		r.current.Border += d
+	case phaseText:
+		r.current.Text += d
+	}
+}
+
+// endProfile records the render's total.
+func (r *Renderer) endProfile() {
+	if r.profile != nil {
+		r.profile.Total = time.Since(r.profile.start)
+	}
+}
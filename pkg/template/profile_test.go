@@ -0,0 +1,161 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestRenderProfile renders a preset with every phase in use and checks
+// that the profile lists the components in paint order and that the
+// phases add up to the totals, less the time between them.
+func TestRenderProfile(t *testing.T) {
+	preset := &Preset{
+		Canvas:     Canvas{Width: 640, Height: 360},
+		Background: Background{Type: "image", Source: "photo.png"},
+		Components: []Component{
+			{ID: "card", X: 0.1, Y: 0.1, Width: 0.5, Height: 0.5, ZIndex: 1,
+				Style:    ComponentStyle{BackgroundColor: "#ffffffc0", BackdropBlur: 6, BorderWidth: 3, BorderColor: "#000000", CornerRadius: 12},
+				Defaults: ComponentData{Title: "Card", Items: []TextItem{{Type: "bullet", Text: "one"}, {Type: "bullet", Text: "two"}}}},
+			{ID: "logo", X: 0.7, Y: 0.1, Width: 0.2, Height: 0.3,
+				Style: ComponentStyle{BackgroundImage: "photo.png", MaskImage: "photo.png"}},
+			{ID: "caption", X: 0.1, Y: 0.8, Width: 0.8, Height: 0.1,
+				Defaults: ComponentData{Title: "A caption in the default font"}},
+		},
+	}
+	if err := preset.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	photo := encodePNG(t, 320, 180)
+	r, err := NewRendererWithOptions(RendererOptions{Resolve: func(ref string) []byte {
+		if ref == "photo.png" {
+			return photo
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	components := MergeData(preset, nil)
+
+	if _, err := r.RenderPreset(preset, components); err != nil {
+		t.Fatal(err)
+	}
+	if p := r.Profile(); p != nil {
+		t.Fatalf("profile %v without SetProfile", p)
+	}
+
+	r.SetProfile(true)
+	if _, err := r.RenderPreset(preset, components); err != nil {
+		t.Fatal(err)
+	}
+	p := r.Profile()
+	if p == nil {
+		t.Fatal("no profile with SetProfile(true)")
+	}
+
+	var ids []string
+	for _, c := range components {
+		ids = append(ids, c.ID)
+	}
+	var got []string
+	for _, c := range p.Components {
+		got = append(got, c.ID)
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("profiled components %v, want %v in paint order", got, ids)
+	}
+
+	// The time between phases is a few clock reads and loop steps.
+	near := func(what string, parts, total time.Duration) {
+		t.Helper()
+		if parts > total || total-parts > time.Millisecond+total/10 {
+			t.Errorf("%s: phases add up to %v of %v", what, parts, total)
+		}
+	}
+	sum := p.Background
+	for _, c := range p.Components {
+		near(c.ID, c.Background+c.Image+c.Border+c.Text, c.Total)
+		if c.ID == "logo" && c.Image <= 0 || c.ID != "logo" && c.Text <= 0 {
+			t.Errorf("%s: phases %+v, want image time for the logo and text time for the others", c.ID, c)
+		}
+		sum += c.Total
+	}
+	if p.Background <= 0 {
+		t.Errorf("no time for the background image")
+	}
+	near("render", sum, p.Total)
+
+	r.SetProfile(false)
+	if _, err := r.RenderPreset(preset, components); err != nil {
+		t.Fatal(err)
+	}
+	if p := r.Profile(); p != nil {
+		t.Errorf("profile %v after SetProfile(false)", p)
+	}
+}
+
+// TestRenderProfileDisabledAllocs checks that the profiling hooks a
+// render calls allocate nothing when profiling is off, and that a render
+// allocates no more than with profiling on.
+func TestRenderProfileDisabledAllocs(t *testing.T) {
+	r, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		r.startProfile()
+		start := time.Now()
+		r.startComponentProfile("c", start)
+		r.lap(phaseBackground)
+		r.lap(phaseImage)
+		r.lap(phaseBorder)
+		r.lap(phaseText)
+		r.endComponentProfile(start)
+		r.endProfile()
+	}); n != 0 {
+		t.Errorf("profiling hooks allocate %v times per render when off, want 0", n)
+	}
+
+	preset := &Preset{
+		Canvas:     Canvas{Width: 64, Height: 48},
+		Components: []Component{{ID: "c", Width: 1, Height: 1, Style: ComponentStyle{BackgroundColor: "#336699"}}},
+	}
+	if err := preset.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	components := MergeData(preset, nil)
+	canvas := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	render := func() {
+		if _, err := r.RenderPresetInto(context.Background(), canvas, preset, components); err != nil {
+			t.Fatal(err)
+		}
+	}
+	off := testing.AllocsPerRun(20, render)
+	r.SetProfile(true)
+	on := testing.AllocsPerRun(20, render)
+	if off >= on {
+		t.Errorf("render allocates %v times with profiling off, %v with it on; want fewer off", off, on)
+	}
+}
+
+// encodePNG is a w×h PNG of color bands.
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(255 * x / w), uint8(255 * y / h), 0x80, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
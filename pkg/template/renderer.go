@@ -1,22 +1,25 @@
 // renderer.go — Rendering engine for presets and legacy templates.
 //
 // Preset pipeline: background → containers (bg, border, corner radius, image) → text content.
-// Supports: backgroundColor with alpha, backgroundImage (PNG/JPG), borderColor/Width,
+// Supports: backgroundColor with alpha, backgroundImage (PNG/JPG), borderColor/Width/Style,
 // cornerRadius, textAlign (left/center/right), bullet/numbered lists, text wrapping.
 package template
 
 import (
-	"bytes"
+	"cmp"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	_ "image/jpeg" // register JPEG decoder
-	"image/png"
-
+	"io/fs"
+	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
@@ -25,192 +28,656 @@ import (
 // AssetResolverFunc returns the raw bytes for an asset ID, or nil if not found.
 type AssetResolverFunc func(id string) []byte
 
+// resolveAsset calls resolve, if set, for a non-empty reference.
+func resolveAsset(resolve AssetResolverFunc, ref string) []byte {
+	if resolve == nil || ref == "" {
+		return nil
+	}
+	return resolve(ref)
+}
+
+// readAssetFile reads the file an asset reference names, unless noFiles
+// is set; then the file does not exist.
+func readAssetFile(path string, noFiles bool) ([]byte, error) {
+	if noFiles {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return os.ReadFile(path)
+}
+
 // Renderer composites images from presets or legacy templates.
 type Renderer struct {
 	fontManager   *FontManager
 	dpi           float64
 	assetResolver AssetResolverFunc
+	noAssetFiles  bool // see RendererOptions
+	maxCanvas     int  // see RendererOptions; 0 for MaxCanvasSize
+	warnings      []RenderWarning
+	used          []AssetUse // see AssetsUsed
+	strictAssets  bool
+	showMissing   bool
+	depth16       bool
+	maxImageSize  int         // see SetMaxImageSize
+	imageCache    *ImageCache // reduced images, shared between renderers
+	overFit       string      // see SetOverFit
+
+	// Profiling state; see profile.go.
+	profiling bool
+	profile   *RenderProfile
+	current   *ComponentProfile // the component being drawn
+	lapStart  time.Time
+
+	// fonts holds the component fonts loaded so far, by reference, so a
+	// font shared by several components or renders is parsed once and
+	// keeps its faces.
+	fonts map[string]*FontManager
+}
+
+// RenderWarning is a non-fatal problem met while rendering. The image is
+// still produced, with a fallback (default font, no image) substituted.
+type RenderWarning struct {
+	Component string `json:"component,omitempty"` // "" for preset-level problems
+	Message   string `json:"message"`
+}
+
+func (w RenderWarning) String() string {
+	if w.Component == "" {
+		return w.Message
+	}
+	return "component " + w.Component + ": " + w.Message
+}
+
+// Warnings returns the problems found by the most recent RenderPreset call.
+func (r *Renderer) Warnings() []RenderWarning {
+	return r.warnings
+}
+
+// warn records a warning for the current render.
+func (r *Renderer) warn(component, format string, args ...any) {
+	w := RenderWarning{Component: component, Message: fmt.Sprintf(format, args...)}
+	r.warnings = append(r.warnings, w)
+	logger().Debug("render warning", "component", component, "message", w.Message)
+}
+
+// DefaultDPI is the renderer's resolution unless SetDPI changes it: one
+// point of font size is one pixel.
+const DefaultDPI = 72
+
+// SetDPI sets the resolution font sizes are rendered at. Style font sizes,
+// title spacing and the line heights and indents derived from them are in
+// points, so at 300 DPI a 12pt font is 50 pixels tall; the canvas and the
+// box metrics (padding, border, radius) stay in pixels. dpi ≤ 0 restores
+// DefaultDPI.
+func (r *Renderer) SetDPI(dpi float64) {
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	r.dpi = dpi
+}
+
+// DPI returns the resolution set by SetDPI.
+func (r *Renderer) DPI() float64 {
+	return r.dpi
+}
+
+// px converts a length in points to pixels at the renderer's DPI.
+func (r *Renderer) px(pt float64) float64 {
+	return pt * r.dpi / 72
+}
+
+// lineHeight is the distance in pixels between baselines of s's text at
+// size points.
+func (r *Renderer) lineHeight(s *ComponentStyle, size float64) float64 {
+	if lh := float64(s.LineHeight); lh > MaxLineHeightMultiplier {
+		return r.px(lh)
+	}
+	return r.px(size) * float64(s.LineHeight)
+}
+
+// SetStrictAssets makes an image or font that cannot be loaded fail the
+// render with an *AssetError instead of being replaced and warned about.
+func (r *Renderer) SetStrictAssets(on bool) {
+	r.strictAssets = on
+}
+
+// SetShowMissingAssets draws a striped placeholder labeled with the
+// reference where a component image cannot be loaded, instead of leaving
+// the area empty, so an editor preview shows what is broken. The warning
+// is still recorded.
+func (r *Renderer) SetShowMissingAssets(on bool) {
+	r.showMissing = on
+}
+
+// missingAsset handles an image or font that could not be loaded: in
+// strict mode it returns an *AssetError, otherwise it records a warning
+// (format gets the reference and err) and returns nil.
+func (r *Renderer) missingAsset(component, ref string, err error, format string) error {
+	if r.strictAssets {
+		return &AssetError{Ref: ref, Err: err}
+	}
+	r.warn(component, format, ref, err)
+	return nil
 }
 
 // SetAssetResolver sets a callback to resolve asset IDs to in-memory bytes.
 // This is used by the WASM client where assets live in memory, not on disk.
 func (r *Renderer) SetAssetResolver(fn AssetResolverFunc) {
 	r.assetResolver = fn
+	clear(r.fonts)
 }
 
 // NewRenderer creates a renderer with the specified font (empty = embedded default).
 func NewRenderer(fontPath string) (*Renderer, error) {
-	fm, err := NewFontManager(fontPath)
+	return NewRendererForFont(FontConfig{Path: fontPath}, nil)
+}
+
+// NewRendererForFont creates a renderer with a preset's global font,
+// selecting from a collection by fc.Index or fc.Family. resolve, if set,
+// is consulted for fc.Path before the filesystem and becomes the
+// renderer's asset resolver, as with SetAssetResolver.
+func NewRendererForFont(fc FontConfig, resolve AssetResolverFunc) (*Renderer, error) {
+	return NewRendererWithOptions(RendererOptions{Font: fc, Resolve: resolve})
+}
+
+// RendererOptions configures a renderer made by NewRendererWithOptions.
+type RendererOptions struct {
+	Font    FontConfig        // the global font
+	Resolve AssetResolverFunc // consulted before the filesystem; see SetAssetResolver
+
+	// NoAssetFiles makes font and image references Resolve does not know
+	// missing assets instead of file paths. Programs that render untrusted
+	// presets, like the server, set it so that a reference such as
+	// "../../etc/passwd" is never read.
+	NoAssetFiles bool
+
+	// MaxCanvasSize is the largest canvas width or height the renderer
+	// draws; 0 means the package's MaxCanvasSize.
+	MaxCanvasSize int
+}
+
+// NewRendererWithOptions is NewRendererForFont with opts.
+func NewRendererWithOptions(opts RendererOptions) (*Renderer, error) {
+	fm, err := loadFontManager(opts.Font, opts.Resolve, opts.NoAssetFiles)
 	if err != nil {
 		return nil, err
 	}
-	return &Renderer{fontManager: fm, dpi: 72}, nil
+	return &Renderer{
+		fontManager:   fm,
+		dpi:           DefaultDPI,
+		assetResolver: opts.Resolve,
+		noAssetFiles:  opts.NoAssetFiles,
+		maxCanvas:     opts.MaxCanvasSize,
+	}, nil
 }
 
-// NewRendererFromBytes creates a renderer from raw TTF font data.
-// If fontData is nil or empty, the embedded Go Regular font is used.
+// CheckCanvas returns an *InputError if c is larger than the renderer
+// draws, so that callers allocating the canvas themselves can refuse it
+// first. Renders check it too.
+func (r *Renderer) CheckCanvas(c Canvas) error {
+	limit := cmp.Or(r.maxCanvas, MaxCanvasSize)
+	if c.Width > limit || c.Height > limit {
+		return &InputError{Err: fmt.Errorf("canvas %dx%d exceeds the maximum of %d pixels per side", c.Width, c.Height, limit)}
+	}
+	return nil
+}
+
+// NewRendererFromBytes creates a renderer from raw TTF, OTF or TTC font data.
+// If fontData is nil or empty, the embedded default family is used.
 func NewRendererFromBytes(fontData []byte) (*Renderer, error) {
 	fm, err := NewFontManagerFromBytes(fontData)
 	if err != nil {
 		return nil, err
 	}
-	return &Renderer{fontManager: fm, dpi: 72}, nil
+	return &Renderer{fontManager: fm, dpi: DefaultDPI}, nil
 }
 
 // ── Preset Rendering ──
 
 // RenderPreset creates an image from a preset and its resolved components.
+// Non-fatal problems are available from Warnings afterwards.
 func (r *Renderer) RenderPreset(preset *Preset, components []ResolvedComponent) (*image.RGBA, error) {
-	img := image.NewRGBA(image.Rect(0, 0, preset.Canvas.Width, preset.Canvas.Height))
+	return r.RenderPresetContext(context.Background(), preset, components)
+}
+
+// RenderPresetContext is RenderPreset with cancellation: ctx is checked
+// before each component, and its error is returned once it is done.
+func (r *Renderer) RenderPresetContext(ctx context.Context, preset *Preset, components []ResolvedComponent) (*image.RGBA, error) {
+	return r.RenderPresetInto(ctx, nil, preset, components)
+}
+
+// RenderPresetInto is RenderPresetContext drawing into dst, which is
+// overwritten, when its bounds are exactly the canvas (0, 0)–(width,
+// height); otherwise, or if dst is nil, a new image is allocated. It
+// returns the image drawn. Reusing one buffer across renders of the same
+// size saves a full-canvas allocation each time; see ImagePool.
+func (r *Renderer) RenderPresetInto(ctx context.Context, dst *image.RGBA, preset *Preset, components []ResolvedComponent) (*image.RGBA, error) {
+	if err := r.beginRender(preset, components); err != nil {
+		return nil, err
+	}
+	rect := image.Rect(0, 0, preset.Canvas.Width, preset.Canvas.Height)
+	img := dst
+	if img == nil || img.Rect != rect {
+		img = image.NewRGBA(rect)
+	}
 
 	// Draw background.
 	if err := r.drawPresetBackground(img, preset); err != nil {
 		return nil, err
 	}
+	r.lap(phaseBackground)
 
-	// Draw each visible component.
+	if err := r.drawComponents(ctx, img, components); err != nil {
+		return nil, err
+	}
+	r.endProfile()
+	return img, nil
+}
+
+// drawComponents draws each visible component onto img, in order.
+func (r *Renderer) drawComponents(ctx context.Context, img *image.RGBA, components []ResolvedComponent) error {
 	for _, comp := range components {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
+		r.startComponentProfile(comp.ID, start)
 		if err := r.drawComponent(img, comp); err != nil {
-			return nil, err
+			return &ComponentError{ID: comp.ID, Err: err}
 		}
+		r.endComponentProfile(start)
+		logger().Debug("component rendered", "id", comp.ID, "elapsed", time.Since(start))
 	}
+	return nil
+}
 
-	return img, nil
+// beginRender checks preset's canvas and starts a render's warnings with
+// the problems found before drawing, including text in components that
+// would not be legible.
+func (r *Renderer) beginRender(preset *Preset, components []ResolvedComponent) error {
+	w, h := preset.Canvas.Width, preset.Canvas.Height
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("canvas %dx%d out of range (see Preset.Normalize)", w, h)
+	}
+	if err := r.CheckCanvas(preset.Canvas); err != nil {
+		return err
+	}
+
+	r.warnings, r.used = nil, nil
+	r.startProfile()
+	if msg := unknownCanvasPreset(preset.Canvas); msg != "" {
+		r.warn("", "%s", msg)
+	}
+	if msg := unknownSnap(preset.Canvas); msg != "" {
+		r.warn("", "%s", msg)
+	}
+	if fb := r.fontManager.fallback; fb != nil {
+		if err := r.missingAsset("", preset.Font.Path, fb, "global font %q unavailable, using default: %v"); err != nil {
+			return err
+		}
+	}
+	for _, c := range preset.Components {
+		if componentRect(c, preset.Canvas).Empty() {
+			r.warn(c.ID, "no area on the canvas (x %g, y %g, width %g, height %g), not drawn", c.X, c.Y, c.Width, c.Height)
+		}
+	}
+	for _, c := range components {
+		for _, msg := range preset.unknownClasses(c.Data.Classes) {
+			r.warn(c.ID, "%s, skipped", msg)
+		}
+		for _, f := range c.capped {
+			r.warn(c.ID, "style.%s %g is larger than the canvas is tall, drawn at %g", f.field, f.size, f.max)
+		}
+		for _, p := range legibilityProblems(c, preset.Background, r.px) {
+			r.warn(c.ID, "%s", p.message)
+		}
+	}
+	return nil
 }
 
 // drawPresetBackground fills with an image or solid color.
 func (r *Renderer) drawPresetBackground(img *image.RGBA, preset *Preset) error {
+	if preset.Background.Type == "transparent" {
+		clear(img.Pix) // a reused canvas holds the previous render
+		return nil
+	}
 	if preset.Background.Type == "image" && preset.Background.Source != "" {
-		if bgImg, err := r.resolveImage(preset.Background.Source); err == nil {
-			drawScaled(img, bgImg)
+		bgImg, err := r.resolveImage("", preset.Background.Source, img.Bounds().Size(), "stretch")
+		if err == nil {
+			// Translucent image pixels blend onto what is there, which must
+			// be nothing, as in a new image.
+			clear(img.Pix)
+			drawFit(img, bgImg, "stretch")
 			return nil
 		}
+		if err := r.missingAsset("", preset.Background.Source, err, "could not load background image %q, using color: %v"); err != nil {
+			return err
+		}
 	}
 
-	c := parseHexColorAlpha(preset.Background.Color)
+	// The canvas is premultiplied; a translucent color must be converted.
+	c := color.NRGBA(parseHexColorAlpha(preset.Background.Color))
 	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
 	return nil
 }
 
-// drawComponent paints a component's container and content.
+// drawComponent paints a component's container and content. Boxes from
+// MergeData are already on the canvas; others are clipped to it here, and
+// their font sizes capped to its height. Corner radius and border width
+// are capped to the box.
 func (r *Renderer) drawComponent(img *image.RGBA, comp ResolvedComponent) error {
-	bounds := image.Rect(comp.X, comp.Y, comp.X+comp.Width, comp.Y+comp.Height)
-
-	// 1. Container background.
-	if comp.Style.BackgroundColor != "" {
-		bgColor := parseHexColorAlpha(comp.Style.BackgroundColor)
-		if bgColor.A > 0 {
-			if comp.Style.CornerRadius > 0 {
-				drawRoundedRect(img, bounds, bgColor, comp.Style.CornerRadius)
-			} else {
-				drawRect(img, bounds, bgColor)
+	box, ok := boxOf(comp, img.Bounds())
+	if !ok {
+		return nil
+	}
+	capFontSizes(&comp.Style, float64(img.Bounds().Dy()))
+	if comp.Style.MaskImage != "" {
+		mask, err := r.componentMask(comp, box)
+		r.lap(phaseImage)
+		if err != nil {
+			return err
+		}
+		if mask != nil {
+			layer := image.NewRGBA(box.bounds)
+			drawBackdrop(layer, img, comp, box)
+			if err := r.drawComponentBody(layer, img, comp, box); err != nil {
+				return err
 			}
+			drawMasked(img, layer, mask)
+			r.lap(phaseImage)
+			return nil
 		}
 	}
 
+	// 0. Backdrop.
+	drawBackdrop(img, img, comp, box)
+	return r.drawComponentBody(img, nil, comp, box)
+}
+
+// drawComponentBody draws a component over its backdrop: container,
+// image, border and text. When img is a layer of its own, under is the
+// canvas it goes on, for automatic colors to see.
+func (r *Renderer) drawComponentBody(img *image.RGBA, under image.Image, comp ResolvedComponent, box componentBox) error {
+	// 1. Container background.
+	drawContainer(img, comp, box)
+	r.lap(phaseBackground)
+
 	// 2. Background image (sticker/logo).
-	if comp.Style.BackgroundImage != "" {
-		if bgImg, err := r.resolveImage(comp.Style.BackgroundImage); err == nil {
-			subImg := img.SubImage(bounds).(*image.RGBA)
-			fit := comp.Style.BackgroundFit
-			if fit == "" {
-				fit = "stretch"
-			}
-			switch fit {
-			case "contain":
-				drawContain(subImg, bgImg)
-			case "cover":
-				drawCover(subImg, bgImg)
-			default: // "stretch"
-				drawScaled(subImg, bgImg)
-			}
-		} else {
-			fmt.Printf("Warning: could not load background image %q: %v\n", comp.Style.BackgroundImage, err)
+	src, fit, err := r.componentImage(comp, box)
+	switch {
+	case err != nil:
+		return err
+	case src != nil:
+		drawFit(img.SubImage(box.bounds).(*image.RGBA), src, fit)
+	case comp.Style.BackgroundImage != "" && r.showMissing:
+		r.drawMissingAsset(img.SubImage(box.bounds).(*image.RGBA), comp.Style.BackgroundImage)
+	}
+	r.lap(phaseImage)
+
+	// 3–4. Border and text, in colors picked from what is under them when
+	// they are automatic.
+	comp = r.resolveAutoColors(comp, box, under, img)
+	return r.drawComponentFront(img, comp, box)
+}
+
+// componentBox is the box a component is drawn in, clipped to the canvas,
+// with its corner radius and border width capped to it.
+type componentBox struct {
+	bounds      image.Rectangle
+	radius      int
+	borderWidth int
+}
+
+// boxOf returns comp's box on a canvas with the given bounds; ok is false
+// when none of it is on the canvas.
+func boxOf(comp ResolvedComponent, canvas image.Rectangle) (box componentBox, ok bool) {
+	if comp.Width <= 0 || comp.Height <= 0 {
+		return box, false
+	}
+	bounds := image.Rect(comp.X, comp.Y, comp.X+comp.Width, comp.Y+comp.Height).Intersect(canvas)
+	if bounds.Empty() {
+		return box, false
+	}
+	return componentBox{
+		bounds:      bounds,
+		radius:      cornerRadius(bounds, comp.Style.CornerRadius),
+		borderWidth: min(comp.Style.BorderWidth, (min(bounds.Dx(), bounds.Dy())+1)/2),
+	}, true
+}
+
+// drawBackdrop fills the box in dst with a blurred copy of what is drawn
+// under it in src, usually the same image, clipped to its corners; the
+// background color then tints it. The blur reads past the box so its
+// edges blend with their surroundings.
+func drawBackdrop(dst, src *image.RGBA, comp ResolvedComponent, box componentBox) {
+	area := backdropArea(comp, box, src.Bounds())
+	if area.Empty() {
+		return
+	}
+	blurred := gaussianBlur(src, area, min(comp.Style.BackdropBlur, MaxBackdropBlur))
+	for y := box.bounds.Min.Y; y < box.bounds.Max.Y; y++ {
+		if x0, x1 := roundedSpan(box.bounds, box.radius, y); x0 < x1 {
+			copy(dst.Pix[dst.PixOffset(x0, y):dst.PixOffset(x1, y)], blurred.Pix[blurred.PixOffset(x0, y):])
 		}
 	}
+}
+
+// backdropArea is the part of canvas a backdrop blur reads: the box and
+// three standard deviations around it. It is empty when comp has no
+// backdrop.
+func backdropArea(comp ResolvedComponent, box componentBox, canvas image.Rectangle) image.Rectangle {
+	sigma := min(comp.Style.BackdropBlur, MaxBackdropBlur)
+	if sigma <= 0 {
+		return image.Rectangle{}
+	}
+	return box.bounds.Inset(-int(math.Ceil(3 * sigma))).Intersect(canvas)
+}
+
+// drawContainer fills the box with the background color.
+func drawContainer(img *image.RGBA, comp ResolvedComponent, box componentBox) {
+	if comp.Style.BackgroundColor == "" {
+		return
+	}
+	bgColor := parseHexColorAlpha(comp.Style.BackgroundColor)
+	if bgColor.A == 0 {
+		return
+	}
+	if box.radius > 0 {
+		drawRoundedRect(img, box.bounds, bgColor, box.radius)
+	} else {
+		drawRect(img, box.bounds, bgColor)
+	}
+}
 
-	// 3. Border.
-	if comp.Style.BorderWidth > 0 && comp.Style.BorderColor != "" {
+// componentImage loads comp's background image and returns it with its
+// fit. It returns a nil image when there is none, or when it cannot be
+// loaded and missingAsset lets the render go on.
+func (r *Renderer) componentImage(comp ResolvedComponent, box componentBox) (image.Image, string, error) {
+	if comp.Style.BackgroundImage == "" {
+		return nil, "", nil
+	}
+	fit := comp.Style.BackgroundFit
+	if fit == "" {
+		fit = "stretch"
+	}
+	src, err := r.resolveImage(comp.ID, comp.Style.BackgroundImage, box.bounds.Size(), fit)
+	if err != nil {
+		return nil, "", r.missingAsset(comp.ID, comp.Style.BackgroundImage, err, "could not load background image %q: %v")
+	}
+	return src, fit, nil
+}
+
+// drawComponentFront draws what goes over a component's image: its border
+// and text.
+func (r *Renderer) drawComponentFront(img *image.RGBA, comp ResolvedComponent, box componentBox) error {
+	if box.borderWidth > 0 && comp.Style.BorderColor != "" {
 		borderColor := parseHexColorAlpha(comp.Style.BorderColor)
-		if comp.Style.CornerRadius > 0 {
-			drawRoundedBorder(img, bounds, borderColor, comp.Style.CornerRadius, comp.Style.BorderWidth)
+		if comp.Style.BorderStyle == "dashed" || comp.Style.BorderStyle == "dotted" {
+			drawPatternedBorder(img, box.bounds, borderColor, box.radius, box.borderWidth, &comp.Style)
+		} else if box.radius > 0 {
+			drawRoundedBorder(img, box.bounds, borderColor, box.radius, box.borderWidth)
 		} else {
-			drawBorder(img, bounds, borderColor, comp.Style.BorderWidth)
+			drawBorder(img, box.bounds, borderColor, box.borderWidth)
 		}
 	}
+	r.lap(phaseBorder)
+	err := r.drawComponentContent(img, comp)
+	r.lap(phaseText)
+	return err
+}
 
-	// 4. Text content (title + items).
-	return r.drawComponentContent(img, comp)
+// Missing-asset placeholder (SetShowMissingAssets): diagonal stripes of
+// missingStripe on missingGround, missingStripeWidth pixels wide.
+var (
+	missingStripe = color.RGBA{0xd0, 0x30, 0x60, 0xff}
+	missingGround = color.RGBA{0x2a, 0x10, 0x1c, 0xff}
+)
+
+const missingStripeWidth = 12
+
+// drawMissingAsset fills dst, a component's box, with the missing-asset
+// stripes and writes ref's base name across the middle, clipped to the box.
+func (r *Renderer) drawMissingAsset(dst *image.RGBA, ref string) {
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := dst.Pix[dst.PixOffset(b.Min.X, y):]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := missingGround
+			if (x+y)/missingStripeWidth%2 == 0 {
+				c = missingStripe
+			}
+			i := (x - b.Min.X) * 4
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	drawBorder(dst, b, missingStripe, min(2, b.Dx()/2, b.Dy()/2))
+
+	face, err := r.fontManager.GetFace(float64(min(max(b.Dy()/8, 10), 18)), DefaultDPI)
+	if err != nil {
+		return
+	}
+	label := filepath.Base(ref)
+	m := face.Metrics()
+	textH := (m.Ascent + m.Descent).Ceil()
+	top := b.Min.Y + (b.Dy()-textH)/2
+	drawRect(dst, image.Rect(b.Min.X, top-4, b.Max.X, top+textH+4).Intersect(b), color.RGBA{A: 0xc0})
+	x := b.Min.X + max((b.Dx()-font.MeasureString(face, label).Ceil())/2, 4)
+	r.drawString(dst, label, fixed.I(x), top+m.Ascent.Ceil(), color.White, face)
 }
 
 // drawComponentContent renders title and items within a component.
 func (r *Renderer) drawComponentContent(img *image.RGBA, comp ResolvedComponent) error {
-	if comp.Data.Title == "" && len(comp.Data.Items) == 0 {
-		return nil // image-only component
+	if comp.Style.Arc != nil {
+		return r.drawArcText(img, comp)
+	}
+	lines, _, _, err := r.layoutText(comp)
+	if err != nil {
+		return err
 	}
+	for _, l := range lines {
+		r.drawString(img, l.text, l.x, l.y, l.color, l.face)
+	}
+	return nil
+}
+
+// componentFont is the component's font, or the global font if it has none
+// or it cannot be loaded.
+func (r *Renderer) componentFont(comp ResolvedComponent) (*FontManager, error) {
+	if comp.Style.FontPath == "" {
+		r.useFont(comp.ID, r.fontManager)
+		return r.fontManager, nil
+	}
+	fm, err := r.resolveFont(comp.Style.FontPath)
+	if err == nil {
+		r.useFont(comp.ID, fm)
+		return fm, nil
+	}
+	r.useAsset(AssetFont, comp.Style.FontPath, AssetMissing, comp.ID)
+	r.useFont(comp.ID, r.fontManager)
+	return r.fontManager, r.missingAsset(comp.ID, comp.Style.FontPath, err, "font %q unavailable, using global font: %v")
+}
+
+// textLine is one positioned line of component text: x is its dot in
+// 26.6 fixed point, so aligned text keeps its subpixel position, and y is
+// its baseline.
+type textLine struct {
+	text  string
+	x     fixed.Int26_6
+	y     int
+	color color.Color
+	face  font.Face
+}
 
+// layoutText wraps and positions a component's title and items, then
+// applies the style's maxLines budget (see clampLines). It also returns
+// the flow position below the last line (the content top when there is no
+// text) and how many lines the budget dropped. Drawing and
+// MeasureComponent both use it.
+func (r *Renderer) layoutText(comp ResolvedComponent) ([]textLine, int, int, error) {
 	pad := comp.Padding
 	drawX := comp.X + pad
 	drawY := comp.Y + pad
 	drawW := comp.Width - 2*pad
+	if comp.Data.Title == "" && len(comp.Data.Items) == 0 {
+		return nil, drawY, 0, nil // image-only component
+	}
 	if drawW <= 0 {
-		return nil
+		return nil, drawY, 0, nil
 	}
 
+	var lines []textLine
 	currentY := drawY
 	align := comp.Style.TextAlign
 
-	// Resolve per-component font (with fallback to global).
-	fontMgr := r.fontManager
-	if comp.Style.FontPath != "" {
-		if compFM, err := NewFontManager(comp.Style.FontPath); err == nil {
-			fontMgr = compFM
-		} else {
-			fmt.Printf("Warning: component %q font %q unavailable, using global: %v\n", comp.ID, comp.Style.FontPath, err)
-		}
+	fontMgr, err := r.componentFont(comp)
+	if err != nil {
+		return nil, 0, 0, err
 	}
 
-	// Title.
+	// Title, in the family's bold face if it has one.
 	if comp.Data.Title != "" {
-		titleSize := comp.Style.FontSize * 1.4
-		face, err := fontMgr.GetFace(titleSize, r.dpi)
+		titleSize := comp.Style.titleSize()
+		face, err := fontMgr.GetBoldFace(titleSize, r.dpi)
 		if err != nil {
-			return err
+			return nil, 0, 0, err
 		}
 
-		titleColor := parseHexColorAlpha(comp.Style.Color)
-		lh := int(titleSize * comp.Style.LineHeight)
+		titleColor := parseHexColorAlpha(comp.Style.titleColor())
+		lh := int(r.lineHeight(&comp.Style, titleSize))
 
 		for _, line := range r.wrapText(comp.Data.Title, drawW, face) {
 			currentY += lh
 			x := alignX(drawX, drawW, line, face, align)
-			r.drawString(img, line, x, currentY, titleColor, face)
+			lines = append(lines, textLine{line, x, currentY, titleColor, face})
 		}
-		currentY += int(titleSize * 0.5)
+		currentY += int(r.px(comp.Style.titleSpacing()))
 	}
 
 	// Items.
 	face, err := fontMgr.GetFace(comp.Style.FontSize, r.dpi)
 	if err != nil {
-		return err
+		return nil, 0, 0, err
 	}
 
 	textColor := parseHexColorAlpha(comp.Style.Color)
-	lh := int(comp.Style.FontSize * comp.Style.LineHeight)
+	lh := int(r.lineHeight(&comp.Style, comp.Style.FontSize))
+	gap := int(r.px(comp.Style.itemSpacing()))
 	num := 1
 
-	for _, item := range comp.Data.Items {
+	for n, item := range comp.Data.Items {
+		if n > 0 {
+			currentY += gap
+		}
 		var text string
 		var indent int
 
 		switch item.Type {
 		case "bullet":
 			text = "• " + item.Text
-			indent = int(comp.Style.FontSize * 1.2)
+			indent = int(r.px(comp.Style.FontSize) * 1.2)
 		case "numbered":
 			text = fmt.Sprintf("%d. %s", num, item.Text)
 			num++
-			indent = int(comp.Style.FontSize * 1.5)
+			indent = int(r.px(comp.Style.FontSize) * 1.5)
 		default:
 			text = item.Text
 		}
@@ -222,32 +689,97 @@ func (r *Renderer) drawComponentContent(img *image.RGBA, comp ResolvedComponent)
 				dx += indent
 			}
 			x := alignX(dx, drawW, line, face, align)
-			r.drawString(img, line, x, currentY, textColor, face)
+			lines = append(lines, textLine{line, x, currentY, textColor, face})
 		}
 	}
 
-	return nil
+	lines, dropped := clampLines(lines, comp, drawX, drawW, face)
+	if dropped > 0 {
+		currentY = lines[len(lines)-1].y
+	}
+	return lines, currentY, dropped, nil
+}
+
+// clampLines keeps at most comp.Style.MaxLines lines. When lines must go,
+// the last kept slot becomes the moreFormat line, counting the lines
+// dropped (its own slot included), in the items' face and a dimmed text
+// color, where the first dropped line would have been.
+func clampLines(lines []textLine, comp ResolvedComponent, drawX, drawW int, face font.Face) ([]textLine, int) {
+	limit := comp.Style.MaxLines
+	if limit <= 0 || len(lines) <= limit {
+		return lines, 0
+	}
+	keep := limit - 1
+	dropped := len(lines) - keep
+	more := comp.Style.moreLine(dropped)
+	c := parseHexColorAlpha(comp.Style.Color)
+	dim := color.RGBA{c.R * 3 / 5, c.G * 3 / 5, c.B * 3 / 5, c.A * 3 / 5}
+	x := alignX(drawX, drawW, more, face, comp.Style.TextAlign)
+	return append(lines[:keep], textLine{more, x, lines[keep].y, dim, face}), dropped
+}
+
+// TextMetrics describes how a component's text lays out.
+type TextMetrics struct {
+	Lines     int  `json:"lines"`     // wrapped lines, title included
+	Hidden    int  `json:"hidden"`    // lines dropped by maxLines, counted in the "+N more" line
+	Height    int  `json:"height"`    // pixels of text flow from the top of the content box
+	Available int  `json:"available"` // content box height (component height minus padding)
+	Overflow  bool `json:"overflow"`  // Height > Available: the text runs past the box
+}
+
+// MeasureComponent lays out comp's text exactly as RenderPreset would,
+// without drawing. Font problems are reported by Warnings. Arc text is
+// not wrapped and does not flow down the box, so only its line count is
+// reported.
+func (r *Renderer) MeasureComponent(comp ResolvedComponent) (TextMetrics, error) {
+	r.warnings = nil
+	if comp.Style.Arc != nil {
+		return TextMetrics{Lines: len(arcTexts(comp)), Available: comp.Height - 2*comp.Padding}, nil
+	}
+	lines, bottom, hidden, err := r.layoutText(comp)
+	if err != nil {
+		return TextMetrics{}, err
+	}
+	m := TextMetrics{
+		Lines:     len(lines),
+		Hidden:    hidden,
+		Height:    bottom - (comp.Y + comp.Padding),
+		Available: comp.Height - 2*comp.Padding,
+	}
+	m.Overflow = m.Height > m.Available
+	return m, nil
 }
 
 // ── Drawing Primitives ──
 
-// drawRect fills a rectangle with alpha blending.
+// drawRect fills a rectangle with alpha blending. c is unpremultiplied,
+// as blendPixel takes it.
 func drawRect(img *image.RGBA, bounds image.Rectangle, c color.RGBA) {
 	if c.A == 255 {
 		draw.Draw(img, bounds, &image.Uniform{c}, image.Point{}, draw.Src)
 	} else {
-		draw.Draw(img, bounds, &image.Uniform{c}, image.Point{}, draw.Over)
+		draw.Draw(img, bounds, &image.Uniform{color.NRGBA(c)}, image.Point{}, draw.Over)
+	}
+}
+
+// cornerRadius returns the radius to draw for bounds: RadiusFull and radii
+// over half the short side become half of it, so the corner arcs never
+// overlap.
+func cornerRadius(bounds image.Rectangle, r Radius) int {
+	half := min(bounds.Dx(), bounds.Dy()) / 2
+	if r < 0 || int(r) > half {
+		return half
 	}
+	return int(r)
 }
 
-// drawRoundedRect fills a rectangle with rounded corners.
+// drawRoundedRect fills a rectangle with rounded corners, one row span at
+// a time; only rows crossing a corner arc need measuring.
 func drawRoundedRect(img *image.RGBA, bounds image.Rectangle, c color.RGBA, radius int) {
+	radius = cornerRadius(bounds, Radius(radius))
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			if insideRoundedRect(x, y, bounds, radius) {
-				blendPixel(img, x, y, c)
-			}
-		}
+		x0, x1 := roundedSpan(bounds, radius, y)
+		blendSpan(img, y, x0, x1, c)
 	}
 }
 
@@ -265,45 +797,96 @@ func drawBorder(img *image.RGBA, bounds image.Rectangle, c color.RGBA, w int) {
 
 // drawRoundedBorder draws a border with rounded corners.
 func drawRoundedBorder(img *image.RGBA, bounds image.Rectangle, c color.RGBA, radius, width int) {
+	radius = cornerRadius(bounds, Radius(radius))
+	borderSpans(bounds, radius, width, func(y, x0, x1 int) {
+		blendSpan(img, y, x0, x1, c)
+	})
+}
+
+// borderSpans calls fn with each row span of a border ring: on each row,
+// the outer shape's span minus the inner (inset) shape's. radius must
+// already be capped by cornerRadius.
+func borderSpans(bounds image.Rectangle, radius, width int, fn func(y, x0, x1 int)) {
+	inner := bounds.Inset(width)
+	innerRadius := cornerRadius(inner, Radius(max(radius-width, 0)))
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			outer := insideRoundedRect(x, y, bounds, radius)
-			inner := insideRoundedRect(x, y, bounds.Inset(width), max(radius-width, 0))
-			if outer && !inner {
-				blendPixel(img, x, y, c)
-			}
+		x0, x1 := roundedSpan(bounds, radius, y)
+		i0, i1 := roundedSpan(inner, innerRadius, y)
+		if i0 >= i1 {
+			fn(y, x0, x1)
+			continue
+		}
+		if x0 < min(x1, i0) {
+			fn(y, x0, min(x1, i0))
+		}
+		if max(x0, i1) < x1 {
+			fn(y, max(x0, i1), x1)
 		}
 	}
 }
 
-// insideRoundedRect checks if (x,y) is inside a rounded rectangle.
-func insideRoundedRect(x, y int, r image.Rectangle, radius int) bool {
-	// Check corners.
-	corners := [][2]int{
-		{r.Min.X + radius, r.Min.Y + radius}, // top-left
-		{r.Max.X - radius, r.Min.Y + radius}, // top-right
-		{r.Min.X + radius, r.Max.Y - radius}, // bottom-left
-		{r.Max.X - radius, r.Max.Y - radius}, // bottom-right
+// roundedSpan returns the columns [x0, x1) of row y that lie inside a
+// rounded rectangle whose radius cornerRadius has already capped: a pixel
+// in a corner square is inside when it is within radius of the arc's
+// center. x0 ≥ x1 when the row misses the rectangle.
+func roundedSpan(r image.Rectangle, radius, y int) (x0, x1 int) {
+	var dy int
+	switch {
+	case y < r.Min.Y || y >= r.Max.Y:
+		return 0, 0
+	case y < r.Min.Y+radius:
+		dy = r.Min.Y + radius - y
+	case y >= r.Max.Y-radius:
+		dy = y - (r.Max.Y - radius)
+	default:
+		return r.Min.X, r.Max.X
 	}
+	k := isqrt(radius*radius - dy*dy)
+	return max(r.Min.X, r.Min.X+radius-k), min(r.Max.X, r.Max.X-radius+k+1)
+}
 
-	for _, c := range corners {
-		dx := x - c[0]
-		dy := y - c[1]
-		// Only check if we're in the corner quadrant.
-		inCornerX := (c[0] == r.Min.X+radius && x < c[0]) || (c[0] == r.Max.X-radius && x >= c[0])
-		inCornerY := (c[1] == r.Min.Y+radius && y < c[1]) || (c[1] == r.Max.Y-radius && y >= c[1])
-		if inCornerX && inCornerY {
-			if dx*dx+dy*dy > radius*radius {
-				return false
-			}
-		}
+// isqrt returns the largest k with k*k ≤ n, for n ≥ 0.
+func isqrt(n int) int {
+	k := int(math.Sqrt(float64(n)))
+	for k*k > n {
+		k--
+	}
+	for (k+1)*(k+1) <= n {
+		k++
 	}
+	return k
+}
 
-	return x >= r.Min.X && x < r.Max.X && y >= r.Min.Y && y < r.Max.Y
+// blendSpan blends c onto the pixels [x0, x1) of row y exactly as
+// blendPixel would each; the part outside img is skipped.
+func blendSpan(img *image.RGBA, y, x0, x1 int, c color.RGBA) {
+	x0, x1 = max(x0, img.Rect.Min.X), min(x1, img.Rect.Max.X)
+	if y < img.Rect.Min.Y || y >= img.Rect.Max.Y || x0 >= x1 || c.A == 0 {
+		return
+	}
+	row := img.Pix[img.PixOffset(x0, y):img.PixOffset(x1, y)]
+	if c.A == 255 {
+		for i := 0; i < len(row); i += 4 {
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, 255
+		}
+		return
+	}
+	a := uint32(c.A)
+	inv := 255 - a
+	for i := 0; i < len(row); i += 4 {
+		row[i] = uint8((uint32(c.R)*a + uint32(row[i])*inv) / 255)
+		row[i+1] = uint8((uint32(c.G)*a + uint32(row[i+1])*inv) / 255)
+		row[i+2] = uint8((uint32(c.B)*a + uint32(row[i+2])*inv) / 255)
+		row[i+3] = uint8(min(uint32(row[i+3])+a, 255))
+	}
 }
 
-// blendPixel alpha-blends a color onto a pixel.
+// blendPixel alpha-blends a color onto a pixel; points outside img are
+// ignored.
 func blendPixel(img *image.RGBA, x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
 	if c.A == 255 {
 		img.SetRGBA(x, y, c)
 		return
@@ -322,135 +905,225 @@ func blendPixel(img *image.RGBA, x, y int, c color.RGBA) {
 	})
 }
 
-// drawScaled draws src into dst, stretching to fit.
-func drawScaled(dst *image.RGBA, src image.Image) {
-	dstB := dst.Bounds()
-	srcB := src.Bounds()
-
-	scaleX := float64(srcB.Dx()) / float64(dstB.Dx())
-	scaleY := float64(srcB.Dy()) / float64(dstB.Dy())
-
-	for y := dstB.Min.Y; y < dstB.Max.Y; y++ {
-		for x := dstB.Min.X; x < dstB.Max.X; x++ {
-			srcX := srcB.Min.X + int(float64(x-dstB.Min.X)*scaleX)
-			srcY := srcB.Min.Y + int(float64(y-dstB.Min.Y)*scaleY)
-			r, g, b, a := src.At(srcX, srcY).RGBA()
-			px := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
-			blendPixel(dst, x, y, px)
-		}
+// blendPremul composites a premultiplied color, as rgbaSampler yields,
+// onto a pixel; points outside img are ignored. Image pixels must not go
+// through blendPixel, which would multiply their color by alpha again
+// and darken translucent edges.
+func blendPremul(img *image.RGBA, x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(img.Rect)) || c.A == 0 {
+		return
 	}
+	if c.A == 255 {
+		img.SetRGBA(x, y, c)
+		return
+	}
+	p := img.Pix[img.PixOffset(x, y):]
+	inv := 255 - uint32(c.A)
+	p[0] = uint8(uint32(c.R) + uint32(p[0])*inv/255)
+	p[1] = uint8(uint32(c.G) + uint32(p[1])*inv/255)
+	p[2] = uint8(uint32(c.B) + uint32(p[2])*inv/255)
+	p[3] = uint8(uint32(c.A) + uint32(p[3])*inv/255)
 }
 
-// drawContain scales src to fit inside dst without stretching (letterbox).
-func drawContain(dst *image.RGBA, src image.Image) {
-	dstB := dst.Bounds()
-	srcB := src.Bounds()
-
-	scale := min(
-		float64(dstB.Dx())/float64(srcB.Dx()),
-		float64(dstB.Dy())/float64(srcB.Dy()),
-	)
+// drawFit draws src into dst, over what is there, scaled by fit:
+// "stretch", "contain" or "cover".
+func drawFit(dst *image.RGBA, src image.Image, fit string) {
+	at := rgbaSampler(src)
+	fitPixels(dst.Bounds(), src.Bounds(), fit, func(x, y, sx, sy int) {
+		blendPremul(dst, x, y, at(sx, sy))
+	})
+}
 
-	newW := int(float64(srcB.Dx()) * scale)
-	newH := int(float64(srcB.Dy()) * scale)
-	offX := dstB.Min.X + (dstB.Dx()-newW)/2
-	offY := dstB.Min.Y + (dstB.Dy()-newH)/2
+// fitPixels calls fn for each pixel (x, y) of dstB that src, with bounds
+// srcB, covers when drawn with fit, and the source pixel (sx, sy) shown
+// there. Sampling is nearest-neighbor. "stretch" fills dstB; "contain"
+// scales src to fit inside it without stretching (letterbox); "cover"
+// scales it to fill dstB, cropping the excess equally on both sides.
+func fitPixels(dstB, srcB image.Rectangle, fit string, fn func(x, y, sx, sy int)) {
+	switch fit {
+	case "contain":
+		scale := min(
+			float64(dstB.Dx())/float64(srcB.Dx()),
+			float64(dstB.Dy())/float64(srcB.Dy()),
+		)
+		// Rounded, so a source reduced by SetMaxImageSize, a fraction of a
+		// pixel off its original shape, fills the same box.
+		newW := int(math.Round(float64(srcB.Dx()) * scale))
+		newH := int(math.Round(float64(srcB.Dy()) * scale))
+		offX := dstB.Min.X + (dstB.Dx()-newW)/2
+		offY := dstB.Min.Y + (dstB.Dy()-newH)/2
+		for y := 0; y < newH; y++ {
+			sy := min(srcB.Min.Y+int(float64(y)/scale), srcB.Max.Y-1)
+			for x := 0; x < newW; x++ {
+				fn(offX+x, offY+y, min(srcB.Min.X+int(float64(x)/scale), srcB.Max.X-1), sy)
+			}
+		}
 
-	for y := 0; y < newH; y++ {
-		for x := 0; x < newW; x++ {
-			srcX := srcB.Min.X + int(float64(x)/scale)
-			srcY := srcB.Min.Y + int(float64(y)/scale)
-			if srcX >= srcB.Max.X {
-				srcX = srcB.Max.X - 1
+	case "cover":
+		scale := max(
+			float64(dstB.Dx())/float64(srcB.Dx()),
+			float64(dstB.Dy())/float64(srcB.Dy()),
+		)
+		offX := (int(math.Round(float64(srcB.Dx())*scale)) - dstB.Dx()) / 2
+		offY := (int(math.Round(float64(srcB.Dy())*scale)) - dstB.Dy()) / 2
+		for y := dstB.Min.Y; y < dstB.Max.Y; y++ {
+			sy := min(max(srcB.Min.Y+int(float64(y-dstB.Min.Y+offY)/scale), srcB.Min.Y), srcB.Max.Y-1)
+			for x := dstB.Min.X; x < dstB.Max.X; x++ {
+				fn(x, y, min(max(srcB.Min.X+int(float64(x-dstB.Min.X+offX)/scale), srcB.Min.X), srcB.Max.X-1), sy)
 			}
-			if srcY >= srcB.Max.Y {
-				srcY = srcB.Max.Y - 1
+		}
+
+	default: // "stretch"
+		scaleX := float64(srcB.Dx()) / float64(dstB.Dx())
+		scaleY := float64(srcB.Dy()) / float64(dstB.Dy())
+		for y := dstB.Min.Y; y < dstB.Max.Y; y++ {
+			sy := srcB.Min.Y + int(float64(y-dstB.Min.Y)*scaleY)
+			for x := dstB.Min.X; x < dstB.Max.X; x++ {
+				fn(x, y, srcB.Min.X+int(float64(x-dstB.Min.X)*scaleX), sy)
 			}
-			r, g, b, a := src.At(srcX, srcY).RGBA()
-			px := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
-			blendPixel(dst, offX+x, offY+y, px)
 		}
 	}
 }
 
-// drawCover scales src to fill dst, cropping excess.
-func drawCover(dst *image.RGBA, src image.Image) {
-	dstB := dst.Bounds()
-	srcB := src.Bounds()
-
-	scale := max(
-		float64(dstB.Dx())/float64(srcB.Dx()),
-		float64(dstB.Dy())/float64(srcB.Dy()),
-	)
-
-	newW := int(float64(srcB.Dx()) * scale)
-	newH := int(float64(srcB.Dy()) * scale)
-	// Center the crop.
-	offX := (newW - dstB.Dx()) / 2
-	offY := (newH - dstB.Dy()) / 2
-
-	for y := dstB.Min.Y; y < dstB.Max.Y; y++ {
-		for x := dstB.Min.X; x < dstB.Max.X; x++ {
-			srcX := srcB.Min.X + int(float64(x-dstB.Min.X+offX)/scale)
-			srcY := srcB.Min.Y + int(float64(y-dstB.Min.Y+offY)/scale)
-			if srcX < srcB.Min.X {
-				srcX = srcB.Min.X
-			}
-			if srcY < srcB.Min.Y {
-				srcY = srcB.Min.Y
-			}
-			if srcX >= srcB.Max.X {
-				srcX = srcB.Max.X - 1
-			}
-			if srcY >= srcB.Max.Y {
-				srcY = srcB.Max.Y - 1
-			}
-			r, g, b, a := src.At(srcX, srcY).RGBA()
-			px := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
-			blendPixel(dst, x, y, px)
+// rgbaSampler returns a function reading src's pixels as the 8-bit
+// premultiplied color.RGBA that src.At(x, y).RGBA() yields. For the types image decoders
+// produce it reads the pixel directly, skipping the per-pixel interface
+// call and allocation of At.
+func rgbaSampler(src image.Image) func(x, y int) color.RGBA {
+	switch s := src.(type) {
+	case *image.RGBA:
+		return s.RGBAAt
+	case *image.NRGBA:
+		return func(x, y int) color.RGBA { return rgba8(s.NRGBAAt(x, y)) }
+	case *image.YCbCr:
+		return func(x, y int) color.RGBA { return rgba8(s.YCbCrAt(x, y)) }
+	case *image.Gray:
+		return func(x, y int) color.RGBA {
+			v := s.GrayAt(x, y).Y
+			return color.RGBA{v, v, v, 255}
 		}
+	case *image.Paletted:
+		if len(s.Palette) == 0 {
+			break
+		}
+		palette := make([]color.RGBA, len(s.Palette))
+		for i, c := range s.Palette {
+			palette[i] = rgba8(c)
+		}
+		return func(x, y int) color.RGBA { return palette[s.ColorIndexAt(x, y)] }
 	}
+	return func(x, y int) color.RGBA { return rgba8(src.At(x, y)) }
+}
+
+// rgba8 converts c to 8-bit premultiplied RGBA.
+func rgba8[C color.Color](c C) color.RGBA {
+	r, g, b, a := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
 }
 
 // resolveImage tries the asset resolver first (for WASM), then falls back to filesystem.
-func (r *Renderer) resolveImage(path string) (image.Image, error) {
+// JPEGs are turned upright by their EXIF orientation, and one with a
+// wide-gamut color profile is drawn as sRGB with a warning. An SVG is
+// rasterized at the size it will be drawn: box pixels with the given fit.
+// An MJPEG AVI is drawn as its first frame. The image is recorded as used
+// by component, "" for the preset background.
+func (r *Renderer) resolveImage(component, path string, box image.Point, fit string) (img image.Image, err error) {
+	from := AssetFromResolver
+	defer func() {
+		if err != nil {
+			from = AssetMissing
+		}
+		r.useAsset(AssetImage, path, from, component)
+	}()
+
 	// Try in-memory asset resolver first.
+	var data []byte
 	if r.assetResolver != nil {
-		if data := r.assetResolver(path); data != nil {
-			fmt.Printf("[resolveImage] Found asset %q (%d bytes), decoding...\n", path, len(data))
-			img, format, err := image.Decode(bytes.NewReader(data))
-			if err != nil {
-				fmt.Printf("[resolveImage] Decode error for %q: %v\n", path, err)
-				return nil, err
-			}
-			fmt.Printf("[resolveImage] Decoded %q as %s (%dx%d)\n", path, format, img.Bounds().Dx(), img.Bounds().Dy())
-			return img, nil
+		if data = r.assetResolver(path); data != nil {
+			logger().Debug("image resolved from asset store", "ref", path, "bytes", len(data))
+		} else {
+			logger().Debug("asset not in resolver, trying filesystem", "ref", path)
 		}
-		fmt.Printf("[resolveImage] Asset %q NOT found in resolver\n", path)
 	}
 	// Fall back to filesystem.
-	return loadImage(path)
+	if data == nil {
+		from = AssetFromFile
+		if data, err = readAssetFile(path, r.noAssetFiles); err != nil {
+			return nil, err
+		}
+		logger().Debug("image loaded from file", "path", path)
+	}
+	if isAVI(data) {
+		frame, err := firstAVIFrame(data)
+		if err != nil {
+			return nil, err
+		}
+		logger().Debug("using the first AVI frame", "ref", path, "bytes", len(frame))
+		data = frame
+	}
+	if isSVG(data) {
+		svg, err := parseSVG(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(svg.skipped) > 0 {
+			r.warn(component, "SVG %q: skipped unsupported features: %s", filepath.Base(path), strings.Join(svg.skipped, ", "))
+		}
+		w, h, toPx := svg.fit(box, fit)
+		logger().Debug("rasterizing SVG", "ref", path, "shapes", len(svg.shapes), "width", w, "height", h)
+		return svg.rasterize(w, h, toPx), nil
+	}
+	img, err = r.decodeRaster(path, data, box)
+	if err != nil {
+		return nil, err
+	}
+	if name := nonSRGBProfile(data); name != "" {
+		r.warn(component, "image %q has a %q color profile; its colors are drawn as sRGB and may look washed out", filepath.Base(path), name)
+	}
+	return img, nil
 }
 
-// loadImage reads and decodes an image file (PNG or JPEG).
-func loadImage(path string) (image.Image, error) {
-	f, err := os.Open(path)
+// resolveFont loads a component font like resolveImage: from the asset
+// resolver if it knows the reference, else from the filesystem. Unlike
+// NewFontManager it reports an unreadable file instead of falling back.
+// Loaded fonts are kept for the renderer's lifetime; failures are not, so
+// each render reports them.
+func (r *Renderer) resolveFont(path string) (*FontManager, error) {
+	if fm, ok := r.fonts[path]; ok {
+		return fm, nil
+	}
+
+	from := AssetFromResolver
+	data := resolveAsset(r.assetResolver, path)
+	if data != nil {
+		logger().Debug("font resolved from asset store", "ref", path, "bytes", len(data))
+	} else {
+		from = AssetFromFile
+		var err error
+		if data, err = readAssetFile(path, r.noAssetFiles); err != nil {
+			return nil, err
+		}
+	}
+	fm, err := loadedFontManager(data, FontConfig{Path: path}, from)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	img, _, err := image.Decode(f)
-	return img, err
+	if r.fonts == nil {
+		r.fonts = make(map[string]*FontManager)
+	}
+	r.fonts[path] = fm
+	return fm, nil
 }
 
 // ── Text Helpers ──
 
-// wrapText splits text into lines fitting within maxWidth pixels.
+// wrapText splits text into lines fitting within maxWidth pixels,
+// measured in 26.6 fixed point as alignX and drawString place them.
 func (r *Renderer) wrapText(text string, maxWidth int, face font.Face) []string {
 	if maxWidth <= 0 {
 		return []string{text}
 	}
+	limit := fixed.I(maxWidth)
 
 	words := strings.Fields(text)
 	if len(words) == 0 {
@@ -461,7 +1134,7 @@ func (r *Renderer) wrapText(text string, maxWidth int, face font.Face) []string
 	cur := words[0]
 	for _, w := range words[1:] {
 		test := cur + " " + w
-		if font.MeasureString(face, test).Ceil() > maxWidth {
+		if font.MeasureString(face, test) > limit {
 			lines = append(lines, cur)
 			cur = w
 		} else {
@@ -471,36 +1144,60 @@ func (r *Renderer) wrapText(text string, maxWidth int, face font.Face) []string
 	return append(lines, cur)
 }
 
-// drawString renders text at (x, y).
-func (r *Renderer) drawString(img *image.RGBA, text string, x, y int, c color.Color, face font.Face) {
+// Ellipsize shortens text with a trailing "…" until it fits within
+// maxWidth pixels in face. Text that fits is returned as is; when not even
+// the ellipsis fits, the result is "".
+func Ellipsize(text string, maxWidth int, face font.Face) string {
+	limit := fixed.I(maxWidth)
+	if font.MeasureString(face, text) <= limit {
+		return text
+	}
+	r := []rune(text)
+	for len(r) > 0 {
+		r = r[:len(r)-1]
+		if s := strings.TrimRight(string(r), " ") + "…"; font.MeasureString(face, s) <= limit {
+			return s
+		}
+	}
+	return ""
+}
+
+// drawString renders text with its dot at x, in 26.6 fixed point, on
+// baseline y.
+func (r *Renderer) drawString(img *image.RGBA, text string, x fixed.Int26_6, y int, c color.Color, face font.Face) {
 	d := &font.Drawer{
 		Dst:  img,
 		Src:  image.NewUniform(c),
 		Face: face,
-		Dot:  fixed.P(x, y),
+		Dot:  fixed.Point26_6{X: x, Y: fixed.I(y)},
 	}
 	d.DrawString(text)
 }
 
-// alignX computes the x position based on text alignment.
-func alignX(baseX, areaWidth int, text string, face font.Face, align string) int {
+// alignX computes the x position of text's dot based on text alignment,
+// in 26.6 fixed point. Centered and right-aligned text is not rounded to
+// a whole pixel, so its center or right edge stays where alignment puts
+// it as its width changes.
+func alignX(baseX, areaWidth int, text string, face font.Face, align string) fixed.Int26_6 {
 	switch align {
 	case "center":
-		tw := font.MeasureString(face, text).Ceil()
-		return baseX + (areaWidth-tw)/2
+		return fixed.I(baseX) + (fixed.I(areaWidth)-font.MeasureString(face, text))/2
 	case "right":
-		tw := font.MeasureString(face, text).Ceil()
-		return baseX + areaWidth - tw
+		return fixed.I(baseX+areaWidth) - font.MeasureString(face, text)
 	default: // "left"
-		return baseX
+		return fixed.I(baseX)
 	}
 }
 
 // ── Color Parsing ──
 
-// parseHexColorAlpha converts "#rrggbb" or "#rrggbbaa" to color.RGBA.
+// parseHexColorAlpha converts "#rrggbb" or "#rrggbbaa" to color.RGBA;
+// the keywords "transparent" and "none" are fully transparent.
 // Returns white on error.
 func parseHexColorAlpha(hex string) color.RGBA {
+	if isTransparentKeyword(hex) {
+		return color.RGBA{}
+	}
 	hex = strings.TrimPrefix(hex, "#")
 
 	switch len(hex) {
@@ -520,19 +1217,8 @@ func parseHexColorAlpha(hex string) color.RGBA {
 	}
 }
 
-// ── Legacy PNG save ──
-
-// savePNGInline is used by SavePNG to save without import cycles.
-func savePNGInline(img image.Image, path string) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("create %s: %w", path, err)
-	}
-	defer f.Close()
-	return png.Encode(f, img)
-}
-
-// SavePNG saves an image to a PNG file.
-func SavePNG(img image.Image, path string) error {
-	return savePNGInline(img, path)
+// isTransparentKeyword reports whether c is "transparent" or "none", in
+// any case.
+func isTransparentKeyword(c string) bool {
+	return strings.EqualFold(c, "transparent") || strings.EqualFold(c, "none")
 }
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+)
+
+// refRoundedRect and refRoundedBorder are the per-pixel implementations
+// drawRoundedRect and drawRoundedBorder replaced, kept as the reference
+// the span versions must match pixel for pixel.
+func refRoundedRect(img *image.RGBA, bounds image.Rectangle, c color.RGBA, radius int) {
+	radius = cornerRadius(bounds, Radius(radius))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if refInsideRoundedRect(x, y, bounds, radius) {
+				blendPixel(img, x, y, c)
+			}
+		}
+	}
+}
+
+func refRoundedBorder(img *image.RGBA, bounds image.Rectangle, c color.RGBA, radius, width int) {
+	radius = cornerRadius(bounds, Radius(radius))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			outer := refInsideRoundedRect(x, y, bounds, radius)
+			inner := refInsideRoundedRect(x, y, bounds.Inset(width), max(radius-width, 0))
+			if outer && !inner {
+				blendPixel(img, x, y, c)
+			}
+		}
+	}
+}
+
+func refInsideRoundedRect(x, y int, r image.Rectangle, radius int) bool {
+	radius = cornerRadius(r, Radius(radius))
+	corners := [][2]int{
+		{r.Min.X + radius, r.Min.Y + radius},
+		{r.Max.X - radius, r.Min.Y + radius},
+		{r.Min.X + radius, r.Max.Y - radius},
+		{r.Max.X - radius, r.Max.Y - radius},
+	}
+	for _, c := range corners {
+		dx := x - c[0]
+		dy := y - c[1]
+		inCornerX := (c[0] == r.Min.X+radius && x < c[0]) || (c[0] == r.Max.X-radius && x >= c[0])
+		inCornerY := (c[1] == r.Min.Y+radius && y < c[1]) || (c[1] == r.Max.Y-radius && y >= c[1])
+		if inCornerX && inCornerY && dx*dx+dy*dy > radius*radius {
+			return false
+		}
+	}
+	return x >= r.Min.X && x < r.Max.X && y >= r.Min.Y && y < r.Max.Y
+}
+
+// noisyCanvas is a w×h canvas of random opaque and translucent pixels, so
+// that blending differences show.
+func noisyCanvas(rng *rand.Rand, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.IntN(256))
+	}
+	return img
+}
+
+// TestRoundedSpansMatchPerPixel draws random boxes, including ones that
+// hang off the canvas or are thinner than their border, with the span
+// drawers and the per-pixel reference and requires identical pixels.
+func TestRoundedSpansMatchPerPixel(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1162, 1))
+	const w, h = 96, 64
+	for i := range 2000 {
+		x0, y0 := rng.IntN(w+40)-20, rng.IntN(h+40)-20
+		bounds := image.Rect(x0, y0, x0+rng.IntN(80), y0+rng.IntN(60))
+		radius := rng.IntN(50) - 1
+		width := rng.IntN(12) + 1
+		c := color.RGBA{uint8(rng.IntN(256)), uint8(rng.IntN(256)), uint8(rng.IntN(256)), uint8(rng.IntN(256))}
+		if i%4 == 0 {
+			c.A = 255
+		}
+
+		base := noisyCanvas(rng, w, h)
+		want, got := image.NewRGBA(base.Rect), image.NewRGBA(base.Rect)
+
+		copy(want.Pix, base.Pix)
+		copy(got.Pix, base.Pix)
+		refRoundedRect(want, bounds, c, radius)
+		drawRoundedRect(got, bounds, c, radius)
+		if diff := CompareImages(want, got, CompareOptions{}); !diff.Match {
+			t.Fatalf("drawRoundedRect(%v, %v, radius %d): %d pixels differ from the per-pixel fill", bounds, c, radius, diff.DiffPixels)
+		}
+
+		copy(want.Pix, base.Pix)
+		copy(got.Pix, base.Pix)
+		refRoundedBorder(want, bounds, c, radius, width)
+		drawRoundedBorder(got, bounds, c, radius, width)
+		if diff := CompareImages(want, got, CompareOptions{}); !diff.Match {
+			t.Fatalf("drawRoundedBorder(%v, %v, radius %d, width %d): %d pixels differ from the per-pixel border", bounds, c, radius, width, diff.DiffPixels)
+		}
+	}
+}
+
+// The benchmarks draw a translucent full-canvas card at 4K, with the span
+// drawers and with the per-pixel reference they replaced.
+
+var (
+	benchBounds = image.Rect(0, 0, 3840, 2160)
+	benchColor  = color.RGBA{0x33, 0x66, 0xcc, 0xc0}
+)
+
+func BenchmarkDrawRoundedRect(b *testing.B) {
+	img := image.NewRGBA(benchBounds)
+	for b.Loop() {
+		drawRoundedRect(img, benchBounds, benchColor, 40)
+	}
+}
+
+func BenchmarkDrawRoundedRectPerPixel(b *testing.B) {
+	img := image.NewRGBA(benchBounds)
+	for b.Loop() {
+		refRoundedRect(img, benchBounds, benchColor, 40)
+	}
+}
+
+func BenchmarkDrawRoundedBorder(b *testing.B) {
+	img := image.NewRGBA(benchBounds)
+	for b.Loop() {
+		drawRoundedBorder(img, benchBounds, benchColor, 40, 6)
+	}
+}
+
+func BenchmarkDrawRoundedBorderPerPixel(b *testing.B) {
+	img := image.NewRGBA(benchBounds)
+	for b.Loop() {
+		refRoundedBorder(img, benchBounds, benchColor, 40, 6)
+	}
+}
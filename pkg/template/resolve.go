@@ -0,0 +1,266 @@
+// resolve.go — Explain a merge: the components MergeData resolves, where
+// each overridden value came from, and what it hid or ignored.
+package template
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Sources of a resolved value; values with no recorded source come from
+// the preset.
+const (
+	SourcePreset     = "preset"     // the component's style or defaults
+	SourceClass      = "class"      // a style class the component uses
+	SourceResponsive = "responsive" // a responsive override matching the canvas
+	SourceVariant    = "variant"    // the component variant the data selects
+	SourceData       = "data"       // data.json components
+	SourceLocale     = "locale"     // the active locale's overlay
+)
+
+// Resolution is MergeData's result with its reasons, as printed by
+// `gostencil resolve` and returned by POST /api/resolve.
+type Resolution struct {
+	Width      int               `json:"width"` // canvas size in pixels
+	Height     int               `json:"height"`
+	Locale     string            `json:"locale,omitempty"` // active locale overlay
+	Components []ResolvedInfo    `json:"components"`       // drawn components, in paint order
+	Hidden     []HiddenComponent `json:"hidden"`
+	Ignored    []IgnoredOverride `json:"ignored"`
+}
+
+// ResolvedInfo is one drawn component after the merge.
+type ResolvedInfo struct {
+	ID      string         `json:"id"`
+	X       int            `json:"x"` // pixel box, clipped to the canvas
+	Y       int            `json:"y"`
+	Width   int            `json:"width"`
+	Height  int            `json:"height"`
+	ZIndex  int            `json:"zIndex"`
+	Padding int            `json:"padding"`
+	Style   ComponentStyle `json:"style"`
+	Title   string         `json:"title,omitempty"`
+	Items   []TextItem     `json:"items,omitempty"`
+	Variant string         `json:"variant,omitempty"` // the variant applied
+	Classes []string       `json:"classes,omitempty"` // the style classes applied, in order
+
+	// Sources maps each overridden field ("title", "items", "visible",
+	// "style.color", "y", …) to SourceClass, SourceResponsive,
+	// SourceVariant, SourceData or SourceLocale. Fields not listed are the
+	// preset's.
+	Sources map[string]string `json:"sources"`
+}
+
+// HiddenComponent is a preset component MergeData left out.
+type HiddenComponent struct {
+	ID     string `json:"id"`
+	Source string `json:"source,omitempty"` // who set visible: false; "" when the box is off the canvas
+	Reason string `json:"reason"`
+}
+
+// IgnoredOverride is a data.json entry that changed nothing.
+type IgnoredOverride struct {
+	Path   string `json:"path"` // e.g. "components.card.style.borderWidth"
+	Reason string `json:"reason"`
+}
+
+// Resolve merges data onto preset as MergeData does and explains the
+// result.
+func Resolve(preset *Preset, data *DataSpec) *Resolution {
+	res := &Resolution{
+		Width:      preset.Canvas.Width,
+		Height:     preset.Canvas.Height,
+		Components: []ResolvedInfo{},
+		Hidden:     []HiddenComponent{},
+		Ignored:    []IgnoredOverride{},
+	}
+
+	var base, locale map[string]ComponentData
+	if data != nil {
+		base = data.Components
+		if data.Locale != "" {
+			res.Locale = data.Locale
+			if l, ok := data.Locales[data.Locale]; ok {
+				locale = l.Components
+			} else {
+				res.Ignored = append(res.Ignored, IgnoredOverride{"locale", fmt.Sprintf("locale %q is not defined in locales", data.Locale)})
+			}
+		}
+	}
+
+	byID := make(map[string]Component, len(preset.Components))
+	for _, comp := range preset.Components {
+		if _, dup := byID[comp.ID]; !dup {
+			byID[comp.ID] = comp
+		}
+	}
+
+	drawn := make(map[string]bool)
+	for _, c := range MergeData(preset, data) {
+		drawn[c.ID] = true
+		comp := byID[c.ID]
+		res.Components = append(res.Components, ResolvedInfo{
+			ID: c.ID, X: c.X, Y: c.Y, Width: c.Width, Height: c.Height,
+			ZIndex: c.ZIndex, Padding: c.Padding, Style: c.Style,
+			Title: c.Data.Title, Items: c.Data.Items, Variant: c.Data.Variant, Classes: c.Data.Classes,
+			Sources: overrideSources(preset, &comp, base[c.ID], locale[c.ID]),
+		})
+	}
+
+	for _, comp := range preset.Components {
+		if drawn[comp.ID] {
+			continue
+		}
+		h := HiddenComponent{ID: comp.ID, Reason: "the box has no area on the canvas"}
+		// The last layer to set visible decides, as in MergeData.
+		for _, layer := range []struct {
+			visible *bool
+			source  string
+		}{{locale[comp.ID].Visible, SourceLocale}, {base[comp.ID].Visible, SourceData}, {comp.Defaults.Visible, SourcePreset}} {
+			if layer.visible != nil {
+				if !*layer.visible {
+					h.Source, h.Reason = layer.source, "visible: false in the "+layer.source
+				}
+				break
+			}
+		}
+		res.Hidden = append(res.Hidden, h)
+	}
+
+	res.Ignored = append(res.Ignored, ignoredOverrides(preset, "components.", base, byID)...)
+	if data != nil {
+		for _, name := range data.LocaleNames() {
+			res.Ignored = append(res.Ignored, ignoredOverrides(preset, "locales."+name+".components.", data.Locales[name].Components, byID)...)
+		}
+	}
+	return res
+}
+
+// overrideSources records which fields of a component of preset its style
+// classes (where its own style leaves them), the responsive overrides
+// matching the canvas, the selected variant, the data and the active
+// locale overlay set, later layers winning as they do in MergeData.
+func overrideSources(preset *Preset, comp *Component, base, locale ComponentData) map[string]string {
+	sources := make(map[string]string)
+	classes := comp.Classes
+	if selected := comp.selectedClasses(base, locale); selected != nil {
+		classes = selected
+	}
+	for _, name := range classes {
+		if s, ok := preset.Styles[name]; ok {
+			applied, _ := styleOverrides(s)
+			for _, field := range applied {
+				sources["style."+field] = SourceClass
+			}
+		}
+	}
+	own, _ := styleOverrides(*comp.ownStyle())
+	for _, field := range own {
+		delete(sources, "style."+field)
+	}
+	for _, key := range comp.responsiveKeys(preset.Canvas) {
+		o := comp.Responsive[key]
+		for name, set := range map[string]bool{
+			"x": o.X != nil, "y": o.Y != nil, "width": o.Width != nil, "height": o.Height != nil,
+			"zIndex": o.ZIndex != nil, "padding": o.Padding != nil,
+		} {
+			if set {
+				sources[name] = SourceResponsive
+			}
+		}
+		if o.Style != nil {
+			applied, _ := styleOverrides(*o.Style)
+			for _, name := range applied {
+				sources["style."+name] = SourceResponsive
+			}
+		}
+	}
+	if v, ok := comp.Variants[comp.selectedVariant(base, locale)]; ok {
+		applied, _ := styleOverrides(v)
+		for _, name := range applied {
+			sources["style."+name] = SourceVariant
+		}
+	}
+	for _, layer := range []struct {
+		data   ComponentData
+		source string
+	}{{base, SourceData}, {locale, SourceLocale}} {
+		d := layer.data
+		if d.Visible != nil {
+			sources["visible"] = layer.source
+		}
+		if d.Title != "" {
+			sources["title"] = layer.source
+		}
+		if d.Items != nil {
+			sources["items"] = layer.source
+		}
+		if _, ok := comp.Variants[d.Variant]; ok {
+			sources["variant"] = layer.source
+		}
+		if d.Classes != nil {
+			sources["classes"] = layer.source
+		}
+		if d.Style != nil {
+			applied, _ := styleOverrides(*d.Style)
+			for _, name := range applied {
+				sources["style."+name] = layer.source
+			}
+		}
+	}
+	return sources
+}
+
+// ignoredOverrides lists the entries of comps, found at prefix in
+// data.json, that name no component of byID, select a variant it does not
+// have or a style class preset does not, or set a style field to a value
+// mergeComponentStyle does not apply.
+func ignoredOverrides(preset *Preset, prefix string, comps map[string]ComponentData, byID map[string]Component) []IgnoredOverride {
+	var ignored []IgnoredOverride
+	for _, id := range slices.Sorted(maps.Keys(comps)) {
+		comp, ok := byID[id]
+		if !ok {
+			ignored = append(ignored, IgnoredOverride{prefix + id, "no component has this ID"})
+			continue
+		}
+		if msg := comp.unknownVariant(comps[id].Variant); msg != "" {
+			ignored = append(ignored, IgnoredOverride{prefix + id + ".variant", msg})
+		}
+		for _, msg := range preset.unknownClasses(comps[id].Classes) {
+			ignored = append(ignored, IgnoredOverride{prefix + id + ".classes", msg})
+		}
+		if s := comps[id].Style; s != nil {
+			_, skipped := styleOverrides(*s)
+			for _, name := range skipped {
+				ignored = append(ignored, IgnoredOverride{prefix + id + ".style." + name, "value does not override (zero or out of range)"})
+			}
+		}
+	}
+	return ignored
+}
+
+// styleOverrides returns the JSON names of the fields over sets, split by
+// whether mergeComponentStyle applies them. Each field is merged alone
+// onto a zero style: an applied value always leaves it non-zero.
+func styleOverrides(over ComponentStyle) (applied, skipped []string) {
+	ov := reflect.ValueOf(over)
+	t := ov.Type()
+	for i := range t.NumField() {
+		if ov.Field(i).IsZero() {
+			continue
+		}
+		var only, probe ComponentStyle
+		reflect.ValueOf(&only).Elem().Field(i).Set(ov.Field(i))
+		mergeComponentStyle(&probe, only)
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if reflect.ValueOf(probe).Field(i).IsZero() {
+			skipped = append(skipped, name)
+		} else {
+			applied = append(applied, name)
+		}
+	}
+	return applied, skipped
+}
@@ -0,0 +1,150 @@
+// responsive.go — Per-canvas component overrides, so one preset can serve
+// several canvas sizes.
+//
+//	"responsive": {
+//	  "instagram_story": {"y": 0.6, "height": 0.3, "style": {"fontSize": 56}},
+//	  "aspect:..1":      {"style": {"textAlign": "center"}}
+//	}
+//
+// A key is a canvas preset name, matching when canvas.preset names it, or
+// an aspect ratio range "aspect:MIN..MAX" of width/height, MIN inclusive
+// and MAX exclusive, either bound optional. Every matching override is
+// applied after the component's own position and style and before data
+// overrides: ranges first, in key order, then the preset name, the most
+// specific match.
+package template
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// responsiveAspectPrefix starts an aspect ratio range key.
+const responsiveAspectPrefix = "aspect:"
+
+// aspectRange parses an "aspect:MIN..MAX" key. ok is false for a key that
+// is not a range; err reports a malformed one.
+func aspectRange(key string) (lo, hi float64, ok bool, err error) {
+	spec, ok := strings.CutPrefix(key, responsiveAspectPrefix)
+	if !ok {
+		return 0, 0, false, nil
+	}
+	from, to, found := strings.Cut(spec, "..")
+	if !found || (from == "" && to == "") {
+		return 0, 0, true, fmt.Errorf("aspect range %q: want aspect:MIN..MAX with at least one bound", key)
+	}
+	bound := func(s string, def float64) (float64, error) {
+		if s == "" {
+			return def, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(v > 0) {
+			return 0, fmt.Errorf("aspect range %q: %q is not a positive number", key, s)
+		}
+		return v, nil
+	}
+	if lo, err = bound(from, 0); err != nil {
+		return 0, 0, true, err
+	}
+	if hi, err = bound(to, 0); err != nil {
+		return 0, 0, true, err
+	}
+	if to != "" && hi <= lo {
+		return 0, 0, true, fmt.Errorf("aspect range %q: MAX must be above MIN", key)
+	}
+	return lo, hi, true, nil
+}
+
+// CheckResponsiveKey reports whether key names a canvas preset or is a
+// well-formed aspect ratio range.
+func CheckResponsiveKey(key string) error {
+	if _, _, isRange, err := aspectRange(key); isRange {
+		return err
+	}
+	if _, ok := Presets[key]; !ok {
+		return fmt.Errorf("%q is not a canvas preset name or an aspect:MIN..MAX range", key)
+	}
+	return nil
+}
+
+// responsiveKeys returns the keys of c.Responsive that match canvas, in
+// the order their overrides apply. Malformed keys match nothing.
+func (c *Component) responsiveKeys(canvas Canvas) []string {
+	if len(c.Responsive) == 0 || canvas.Height <= 0 {
+		return nil
+	}
+	aspect := float64(canvas.Width) / float64(canvas.Height)
+	var keys []string
+	named := false
+	for key := range c.Responsive {
+		lo, hi, isRange, err := aspectRange(key)
+		switch {
+		case !isRange:
+			named = named || (canvas.Preset != "" && key == canvas.Preset)
+		case err == nil && aspect >= lo && (hi == 0 || aspect < hi):
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	if named {
+		keys = append(keys, canvas.Preset)
+	}
+	return keys
+}
+
+// forCanvas returns c with the responsive overrides matching canvas
+// applied. c's preset is left untouched.
+func (c Component) forCanvas(canvas Canvas) Component {
+	for _, key := range c.responsiveKeys(canvas) {
+		o := c.Responsive[key]
+		if o.X != nil {
+			c.X = *o.X
+		}
+		if o.Y != nil {
+			c.Y = *o.Y
+		}
+		if o.Width != nil {
+			c.Width = *o.Width
+		}
+		if o.Height != nil {
+			c.Height = *o.Height
+		}
+		if o.ZIndex != nil {
+			c.ZIndex = *o.ZIndex
+		}
+		if o.Padding != nil {
+			c.Padding = *o.Padding
+		}
+		if o.Style != nil {
+			c.overrideStyle(*o.Style)
+		}
+	}
+	return c
+}
+
+// overrideStyle merges over onto c's style and its defaults.style, which
+// is merged over the style later: the override must win over both. The
+// preset's own defaults.style is left untouched.
+func (c *Component) overrideStyle(over ComponentStyle) {
+	mergeComponentStyle(&c.Style, over)
+	if c.Defaults.Style != nil {
+		s := *c.Defaults.Style
+		mergeComponentStyle(&s, over)
+		c.Defaults.Style = &s
+	}
+}
+
+// UseCanvasPreset switches p to the named canvas preset, as if its
+// canvas had been {"preset": name}; responsive overrides follow.
+func (p *Preset) UseCanvasPreset(name string) error {
+	if _, ok := Presets[name]; !ok {
+		if near := nearestCanvasPreset(name); near != "" {
+			return fmt.Errorf("unknown canvas preset %q (did you mean %q?)", name, near)
+		}
+		return fmt.Errorf("unknown canvas preset %q", name)
+	}
+	p.Canvas = Canvas{Preset: name, Snap: p.Canvas.Snap}
+	return p.Normalize()
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// responsivePreset has a banner moved and recolored for instagram_story,
+// recolored for youtube_thumb and bordered on tall canvases, and a badge
+// with no overrides.
+const responsivePreset = `{
+  "canvas": {"width": 320, "height": 180},
+  "background": {"type": "color", "color": "#ffffff"},
+  "font": {},
+  "components": [
+    {"id": "banner", "x": 0.1, "y": 0.1, "width": 0.8, "height": 0.2,
+     "style": {"backgroundColor": "#ff0000"},
+     "responsive": {
+       "instagram_story": {"y": 0.6, "height": 0.3, "style": {"backgroundColor": "#0000ff"}},
+       "youtube_thumb": {"style": {"backgroundColor": "#00ff00"}},
+       "aspect:..1": {"style": {"borderWidth": 10, "borderColor": "#000000"}}
+     },
+     "defaults": {"visible": true}},
+    {"id": "badge", "x": 0.05, "y": 0.8, "width": 0.1, "height": 0.1,
+     "style": {"backgroundColor": "#ffcc00"},
+     "defaults": {"visible": true}}
+  ]
+}`
+
+// TestResponsiveCanvases renders one preset at instagram_story and
+// youtube_thumb, in both orders, and checks each override changed the
+// banner only on the canvas it names and the badge not at all.
+func TestResponsiveCanvases(t *testing.T) {
+	type want struct {
+		banner image.Rectangle
+		fill   color.RGBA
+		border bool
+	}
+	wants := map[string]want{
+		"instagram_story": {image.Rect(108, 1152, 972, 1728), color.RGBA{0, 0, 255, 255}, true},
+		"youtube_thumb":   {image.Rect(128, 72, 1152, 216), color.RGBA{0, 255, 0, 255}, false},
+	}
+
+	preset, err := DecodePreset([]byte(responsivePreset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"instagram_story", "youtube_thumb", "instagram_story"} {
+		if err := preset.UseCanvasPreset(name); err != nil {
+			t.Fatal(err)
+		}
+		w := wants[name]
+		components := MergeData(preset, nil)
+		img, err := renderer.RenderPreset(preset, components)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var banner, badge ResolvedComponent
+		for _, c := range components {
+			switch c.ID {
+			case "banner":
+				banner = c
+			case "badge":
+				badge = c
+			}
+		}
+		if got := image.Rect(banner.X, banner.Y, banner.X+banner.Width, banner.Y+banner.Height); got != w.banner {
+			t.Errorf("%s: banner at %v, want %v", name, got, w.banner)
+		}
+		b := img.Bounds()
+		wantBadge := image.Rect(b.Dx()*5/100, b.Dy()*80/100, b.Dx()*15/100, b.Dy()*90/100)
+		if got := image.Rect(badge.X, badge.Y, badge.X+badge.Width, badge.Y+badge.Height); got != wantBadge {
+			t.Errorf("%s: badge at %v, want %v", name, got, wantBadge)
+		}
+		if badge.Style.BackgroundColor != "#ffcc00" || badge.Style.BorderWidth != 0 {
+			t.Errorf("%s: badge style changed: %+v", name, badge.Style)
+		}
+
+		center := image.Pt((w.banner.Min.X+w.banner.Max.X)/2, (w.banner.Min.Y+w.banner.Max.Y)/2)
+		if got := img.RGBAAt(center.X, center.Y); got != w.fill {
+			t.Errorf("%s: banner center %v, want %v", name, got, w.fill)
+		}
+		edge := img.RGBAAt(center.X, w.banner.Min.Y+2)
+		if isBorder := edge == (color.RGBA{0, 0, 0, 255}); isBorder != w.border {
+			t.Errorf("%s: banner top edge %v, border %v, want %v", name, edge, isBorder, w.border)
+		}
+		// Where the other canvas puts the banner is background here.
+		for other, ow := range wants {
+			if other == name {
+				continue
+			}
+			r := ow.banner
+			x, y := (r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2
+			if (image.Pt(x, y)).In(b) && !(image.Pt(x, y)).In(w.banner) {
+				if got := img.RGBAAt(x, y); got != (color.RGBA{255, 255, 255, 255}) {
+					t.Errorf("%s: %v, where %s puts the banner, is %v, want background", name, image.Pt(x, y), other, got)
+				}
+			}
+		}
+	}
+
+	// The preset itself keeps its base values.
+	if c := preset.Components[0]; c.Y != 0.1 || c.Height != 0.2 || c.Style.BackgroundColor != "#ff0000" || c.Style.BorderWidth != 0 {
+		t.Errorf("preset banner changed to y %g, height %g, style %+v", c.Y, c.Height, c.Style)
+	}
+}
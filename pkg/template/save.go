@@ -0,0 +1,69 @@
+// save.go — Writing a rendered image to a file. The encoders are the
+// generator package's own, shared through internal/imageenc, so a file
+// saved here matches one generator.Generate writes.
+package template
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xob0t/GoStencil/internal/imageenc"
+)
+
+// ErrUnsupportedFormat is returned by SaveImage for an extension it cannot
+// write. It is the same error as generator.ErrUnsupportedFormat.
+var ErrUnsupportedFormat = imageenc.ErrUnsupportedFormat
+
+// SaveOption sets how SaveImage encodes.
+type SaveOption func(*imageenc.Options)
+
+// WithDPI records dpi as the pixel density of PNG output, as --dpi does.
+func WithDPI(dpi float64) SaveOption {
+	return func(o *imageenc.Options) { o.DPI = dpi }
+}
+
+// WithQuality sets the JPEG quality, 1–100 (default 90).
+func WithQuality(quality int) SaveOption {
+	return func(o *imageenc.Options) { o.Quality = quality }
+}
+
+// SaveImage writes img to path in the still format its extension names:
+// .png, .jpg, .jpeg, .bmp or .webp (lossless), in any case. PNG, BMP and
+// WebP keep the alpha channel; JPEG draws translucent pixels over black. GIF, AVI and formats
+// registered with the generator package are written by generator.Generate.
+func SaveImage(img image.Image, path string, opts ...SaveOption) error {
+	var o imageenc.Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".png", ".jpg", ".jpeg", ".bmp", ".webp":
+	default:
+		return fmt.Errorf("%w %q: SaveImage writes .bmp, .jpeg, .jpg, .png or .webp", ErrUnsupportedFormat, filepath.Ext(path))
+	}
+	return saveAs(img, path, ext, o)
+}
+
+// SavePNG saves an image to a PNG file, whatever the extension of path.
+//
+// Deprecated: Use SaveImage, which also writes JPEG and BMP.
+func SavePNG(img image.Image, path string) error {
+	return saveAs(img, path, ".png", imageenc.Options{})
+}
+
+// saveAs writes img to path in the format of ext.
+func saveAs(img image.Image, path, ext string, o imageenc.Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := imageenc.Encode(f, img, ext, o); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
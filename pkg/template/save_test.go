@@ -0,0 +1,117 @@
+package template_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xob0t/GoStencil/pkg/generator"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// saveImages are the image types a render or a caller hands to SaveImage:
+// opaque and translucent RGBA, straight-alpha NRGBA, Gray and 16-bit.
+func saveImages() map[string]image.Image {
+	const w, h = 48, 32
+	opaque := image.NewRGBA(image.Rect(0, 0, w, h))
+	translucent := image.NewRGBA(image.Rect(0, 0, w, h))
+	nrgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	deep := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			r, g := uint8(x*255/w), uint8(y*255/h)
+			opaque.SetRGBA(x, y, color.RGBA{r, g, 90, 255})
+			a := uint8(x * 255 / w)
+			translucent.SetRGBA(x, y, color.RGBA{r / 2, g / 2, 0, 255 - a/2})
+			nrgba.SetNRGBA(x, y, color.NRGBA{r, g, 200, a})
+			gray.SetGray(x, y, color.Gray{r ^ g})
+			deep.SetRGBA64(x, y, color.RGBA64{uint16(x) * 1361, uint16(y) * 2039, 0x8001, 0xffff})
+		}
+	}
+	return map[string]image.Image{"opaque": opaque, "translucent": translucent, "NRGBA": nrgba, "Gray": gray, "RGBA64": deep}
+}
+
+// saved returns the file SaveImage writes for img at name in dir.
+func saved(t *testing.T, dir, name string, img image.Image, opts ...template.SaveOption) []byte {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := template.SaveImage(img, path, opts...); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestSaveImageMatchesGenerator checks that SaveImage writes, byte for
+// byte, what generator.GenerateToWriter writes for the same extension and
+// settings, and the same bytes again when repeated.
+func TestSaveImageMatchesGenerator(t *testing.T) {
+	dir := t.TempDir()
+	images := saveImages()
+	settings := []struct {
+		name string
+		opts []template.SaveOption
+		cfg  generator.Config
+	}{
+		{"defaults", nil, generator.Config{}},
+		{"dpi", []template.SaveOption{template.WithDPI(300)}, generator.Config{DPI: 300}},
+		{"quality", []template.SaveOption{template.WithQuality(55)}, generator.Config{Quality: 55}},
+	}
+	for _, ext := range []string{".png", ".jpg", ".JPEG", ".bmp", ".webp"} {
+		for _, s := range settings {
+			for name, img := range images {
+				t.Run(fmt.Sprintf("%s/%s/%s", ext, s.name, name), func(t *testing.T) {
+					cfg := s.cfg
+					cfg.Image = img
+					var want bytes.Buffer
+					if err := generator.GenerateToWriter(&want, ext, cfg); err != nil {
+						t.Fatal(err)
+					}
+					got := saved(t, dir, "out"+ext, img, s.opts...)
+					if !bytes.Equal(got, want.Bytes()) {
+						t.Errorf("SaveImage wrote %d bytes that differ from the generator's %d", len(got), want.Len())
+					}
+					// Another image in between must leave nothing behind.
+					saved(t, dir, "other"+ext, images["translucent"], s.opts...)
+					if again := saved(t, dir, "out"+ext, img, s.opts...); !bytes.Equal(again, got) {
+						t.Error("saving again wrote different bytes")
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestSavePNGUnchanged checks that the deprecated SavePNG still writes
+// what it wrote before the encoders were shared, png.Encode's output,
+// whatever the path's extension, and that SaveImage writes the same PNG.
+func TestSavePNGUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	for name, img := range saveImages() {
+		var want bytes.Buffer
+		if err := png.Encode(&want, img); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range []string{"a.png", "b.jpg", "c"} {
+			path := filepath.Join(dir, file)
+			if err := template.SavePNG(img, path); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("%s: SavePNG to %s differs from png.Encode (%v)", name, file, err)
+			}
+		}
+		if got := saved(t, dir, "d.png", img); !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("%s: SaveImage .png differs from png.Encode", name)
+		}
+	}
+}
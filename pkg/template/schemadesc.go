@@ -0,0 +1,88 @@
+// schemadesc.go — A preset's data.json fields as plain JSON for editors and
+// scripts: `gostencil schema --format json` and GET /api/presets/{id}/schema.
+// Unlike DataJSONSchema it describes each field directly, with its default,
+// instead of constraining a document.
+package template
+
+import "slices"
+
+// SchemaDescriptionVersion is SchemaDescription.Version. It changes only
+// when a key is removed or changes meaning; added keys keep it.
+const SchemaDescriptionVersion = 1
+
+// SchemaDescription describes the data a preset takes. Every key is always
+// present, empty when the preset leaves it out.
+type SchemaDescription struct {
+	Version     int                    `json:"version"` // SchemaDescriptionVersion
+	Meta        Meta                   `json:"meta"`
+	Description string                 `json:"description"` // the schema's
+	Components  []ComponentDescription `json:"components"`  // in preset order
+}
+
+// ComponentDescription is one component's entry in a SchemaDescription.
+type ComponentDescription struct {
+	ID          string             `json:"id"`
+	Description string             `json:"description"`
+	Fields      []FieldDescription `json:"fields"` // one per RequirableFields entry, in its order
+
+	// Variants are the names data's "variant" can select, sorted, and
+	// Variant the one used when it selects none ("" for none).
+	Variants []string `json:"variants"`
+	Variant  string   `json:"variant"`
+}
+
+// FieldDescription is one field data.json can set for a component.
+type FieldDescription struct {
+	Name        string `json:"name"`        // "title", "items", "style" or "visible"
+	Type        string `json:"type"`        // its JSON type: "string", "array", "object" or "boolean"
+	Description string `json:"description"` // from the preset's schema; "" when undocumented
+	Required    bool   `json:"required"`
+	Default     any    `json:"default"` // the value used when data does not set it
+}
+
+// fieldTypes are the JSON types of RequirableFields.
+var fieldTypes = map[string]string{"title": "string", "items": "array", "style": "object", "visible": "boolean"}
+
+// DescribeSchema describes every component of preset and the fields data
+// can set for it, with the descriptions and required fields from the
+// preset's schema section.
+func DescribeSchema(preset *Preset) SchemaDescription {
+	d := SchemaDescription{
+		Version:     SchemaDescriptionVersion,
+		Meta:        preset.Meta,
+		Description: preset.Schema.Description,
+		Components:  make([]ComponentDescription, 0, len(preset.Components)),
+	}
+	for _, c := range preset.Components {
+		sc := preset.Schema.Components[c.ID]
+		required := requiredFields(sc)
+
+		style := c.Style
+		if c.Defaults.Style != nil {
+			mergeComponentStyle(&style, *c.Defaults.Style)
+		}
+		items := c.Defaults.Items
+		if items == nil {
+			items = []TextItem{}
+		}
+		defaults := map[string]any{
+			"title":   c.Defaults.Title,
+			"items":   items,
+			"style":   style,
+			"visible": c.Defaults.Visible == nil || *c.Defaults.Visible,
+		}
+
+		cd := ComponentDescription{ID: c.ID, Description: sc.Description, Variants: c.VariantNames(), Variant: c.Defaults.Variant}
+		for _, name := range RequirableFields {
+			cd.Fields = append(cd.Fields, FieldDescription{
+				Name:        name,
+				Type:        fieldTypes[name],
+				Description: sc.Fields[name],
+				Required:    slices.Contains(required, name),
+				Default:     defaults[name],
+			})
+		}
+		d.Components = append(d.Components, cd)
+	}
+	return d
+}
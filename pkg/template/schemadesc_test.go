@@ -0,0 +1,27 @@
+package template_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xob0t/GoStencil/internal/rendertest"
+	"github.com/xob0t/GoStencil/pkg/template"
+)
+
+// TestDescribeSchemaSnapshot pins the JSON of DescribeSchema, as
+// `gostencil schema --format json` and GET /api/presets/{id}/schema print
+// it, for a preset with schema descriptions, required fields, defaults
+// styles, hidden components and variants. A changed snapshot means
+// scripts reading it may break: only added keys leave
+// SchemaDescriptionVersion as it is.
+func TestDescribeSchemaSnapshot(t *testing.T) {
+	preset, err := template.ParsePresetFile("testdata/schema/preset.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(template.DescribeSchema(preset), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendertest.CheckSnapshot(t, "schema.json", append(got, '\n'))
+}
@@ -0,0 +1,98 @@
+package template
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestSnapBorders renders 1px-bordered boxes at awkward fractional
+// positions and sizes and checks that each side of the border is drawn:
+// with rounding, boxes of one size are one number of pixels wide wherever
+// they are, and the border lies on the box's edges. "floor" keeps the old
+// truncated boxes.
+func TestSnapBorders(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canvases := []Canvas{{Width: 1280, Height: 720}, {Width: 1080, Height: 1350}, {Width: 333, Height: 257}}
+	positions := []float64{0.1, 0.1234, 0.333, 1.0 / 3, 0.4999, 0.6667}
+	sizes := []float64{0.0999, 0.25, 0.333, 1.0 / 7}
+	for _, snap := range []string{"", SnapRound, SnapNone, SnapFloor} {
+		for _, canvas := range canvases {
+			canvas.Snap = snap
+			widths := make(map[float64]int) // pixel width of each size, when rounding
+			for _, pos := range positions {
+				for _, size := range sizes {
+					name := fmt.Sprintf("snap %q, %dx%d, at %.4f size %.4f", snap, canvas.Width, canvas.Height, pos, size)
+					preset := &Preset{
+						Canvas:     canvas,
+						Background: Background{Type: "color", Color: "#ffffff"},
+						Components: []Component{{
+							ID: "box", X: pos, Y: 1 - pos - size, Width: size, Height: size,
+							Style: ComponentStyle{BorderWidth: 1, BorderColor: "#ff0000"},
+						}},
+					}
+					if err := preset.Normalize(); err != nil {
+						t.Fatal(err)
+					}
+					comps := MergeData(preset, nil)
+					if len(comps) != 1 {
+						t.Fatalf("%s: %d components", name, len(comps))
+					}
+					c := comps[0]
+					box := image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+
+					// Rounding rounds the start and the size; floor truncates both.
+					px := func(v float64, n int) int { return int(math.Round(v * float64(n))) }
+					if snap == SnapFloor {
+						px = func(v float64, n int) int { return int(v * float64(n)) }
+					}
+					want := image.Rectangle{Min: image.Pt(px(pos, canvas.Width), px(1-pos-size, canvas.Height))}
+					want.Max = want.Min.Add(image.Pt(px(size, canvas.Width), px(size, canvas.Height)))
+					if snap != SnapFloor {
+						if w, ok := widths[size]; ok && w != box.Dx() {
+							t.Errorf("%s: %d pixels wide, elsewhere %d", name, box.Dx(), w)
+						}
+						widths[size] = box.Dx()
+					}
+					if box != want {
+						t.Errorf("%s: box %v, want %v", name, box, want)
+					}
+
+					img, err := renderer.RenderPreset(preset, comps)
+					if err != nil {
+						t.Fatal(err)
+					}
+					mid := image.Pt((box.Min.X+box.Max.X)/2, (box.Min.Y+box.Max.Y)/2)
+					for _, side := range []struct {
+						name                string
+						edge, outside, next image.Point
+					}{
+						{"left", image.Pt(box.Min.X, mid.Y), image.Pt(box.Min.X-1, mid.Y), image.Pt(box.Min.X+1, mid.Y)},
+						{"right", image.Pt(box.Max.X-1, mid.Y), image.Pt(box.Max.X, mid.Y), image.Pt(box.Max.X-2, mid.Y)},
+						{"top", image.Pt(mid.X, box.Min.Y), image.Pt(mid.X, box.Min.Y-1), image.Pt(mid.X, box.Min.Y+1)},
+						{"bottom", image.Pt(mid.X, box.Max.Y-1), image.Pt(mid.X, box.Max.Y), image.Pt(mid.X, box.Max.Y-2)},
+					} {
+						if got := img.RGBAAt(side.edge.X, side.edge.Y); got != red {
+							t.Errorf("%s: %s border at %v is %v, want red", name, side.name, side.edge, got)
+						}
+						for _, p := range []image.Point{side.outside, side.next} {
+							if p.In(img.Rect) {
+								if got := img.RGBAAt(p.X, p.Y); got != white {
+									t.Errorf("%s: %s border is more than 1px: %v is %v", name, side.name, p, got)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// starters.go — Embedded starter templates written by gostencil init.
+package template
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//go:embed starters
+var startersFS embed.FS
+
+// DefaultStarter is the template used by gostencil init when none is named.
+const DefaultStarter = "minimal"
+
+// starterFonts are font files starters may reference by base name; they are
+// written from the gofont packages rather than embedded a second time.
+var starterFonts = map[string][]byte{
+	"GoRegular.ttf": goregular.TTF,
+	"GoBold.ttf":    gobold.TTF,
+	"GoItalic.ttf":  goitalic.TTF,
+}
+
+// Starter describes one embedded starter template.
+type Starter struct {
+	Name        string
+	Description string
+}
+
+// Starters lists the embedded starter templates, sorted by name.
+func Starters() []Starter {
+	entries, _ := startersFS.ReadDir("starters")
+	var list []Starter
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		var p Preset
+		if raw, err := startersFS.ReadFile(path.Join("starters", e.Name(), "preset.json")); err == nil {
+			json.Unmarshal(raw, &p)
+		}
+		list = append(list, Starter{Name: e.Name(), Description: p.Meta.Description})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// StarterJSON returns the preset.json and data.json of a starter template.
+func StarterJSON(name string) (presetJSON, dataJSON string, err error) {
+	dir := path.Join("starters", name)
+	p, err := startersFS.ReadFile(path.Join(dir, "preset.json"))
+	if err != nil {
+		return "", "", fmt.Errorf("unknown starter template %q", name)
+	}
+	d, err := startersFS.ReadFile(path.Join(dir, "data.json"))
+	if err != nil {
+		return "", "", fmt.Errorf("starter %q: %w", name, err)
+	}
+	return string(p), string(d), nil
+}
+
+// WriteStarter writes a starter's preset and data JSON to presetOut and
+// dataOut, and its assets (images plus any referenced Go fonts) to an
+// assets/ directory beside presetOut. It returns every path written.
+func WriteStarter(name, presetOut, dataOut string) ([]string, error) {
+	p, d, err := StarterJSON(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	write := func(dst string, data []byte) error {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+		written = append(written, dst)
+		return nil
+	}
+
+	if err := write(presetOut, []byte(p)); err != nil {
+		return written, fmt.Errorf("write preset: %w", err)
+	}
+	if err := write(dataOut, []byte(d)); err != nil {
+		return written, fmt.Errorf("write data: %w", err)
+	}
+
+	baseDir := filepath.Dir(presetOut)
+
+	// Bundled image assets.
+	assetsDir := path.Join("starters", name, "assets")
+	err = fs.WalkDir(startersFS, assetsDir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		data, err := startersFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel := p[len(path.Join("starters", name))+1:]
+		return write(filepath.Join(baseDir, filepath.FromSlash(rel)), data)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return written, fmt.Errorf("write assets: %w", err)
+	}
+
+	// Fonts referenced by the preset.
+	var preset Preset
+	if err := json.Unmarshal([]byte(p), &preset); err != nil {
+		return written, fmt.Errorf("parse starter preset: %w", err)
+	}
+	refs := []string{preset.Font.Path}
+	for _, c := range preset.Components {
+		refs = append(refs, c.Style.FontPath)
+	}
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		data, ok := starterFonts[path.Base(ref)]
+		if ref == "" || !ok || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		if err := write(filepath.Join(baseDir, filepath.FromSlash(ref)), data); err != nil {
+			return written, fmt.Errorf("write font: %w", err)
+		}
+	}
+
+	return written, nil
+}
@@ -0,0 +1,799 @@
+// svg.go — A small SVG renderer for logos and stickers.
+//
+// parseSVG reads paths, basic shapes, groups, <use>, transforms, solid
+// fills and strokes, and .class rules from <style> into shapes in viewBox
+// units; rasterize draws them at the pixel size the image is shown at, so
+// it stays sharp on any canvas. Anything else — text, embedded images,
+// filters, masks, clip paths — is left out and named in svgImage.skipped,
+// and gradients are drawn in one of their stop colors.
+package template
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const svgNS = "http://www.w3.org/2000/svg"
+
+// maxSVGUseDepth bounds <use> references to <use> elements, which could
+// otherwise recurse forever.
+const maxSVGUseDepth = 8
+
+// isSVG reports whether data looks like an SVG document.
+func isSVG(data []byte) bool {
+	head := data[:min(len(data), 1024)]
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimSpace(head)
+	return bytes.HasPrefix(head, []byte("<")) && bytes.Contains(head, []byte("<svg"))
+}
+
+// ImageMime checks that data is an image the renderer can draw — PNG, JPEG,
+// SVG, or an MJPEG AVI, of which the first frame is drawn — and returns
+// its MIME type. Raster images are decoded in full, so a truncated or
+// corrupt file is refused here rather than when it is drawn; one covering
+// more pixels than a MaxCanvasSize × MaxCanvasSize canvas is refused
+// before decoding.
+func ImageMime(data []byte) (string, error) {
+	return ImageMimeWithin(data, MaxCanvasSize)
+}
+
+// ImageMimeWithin is ImageMime refusing images covering more pixels than
+// a maxCanvas × maxCanvas canvas, for a renderer with that
+// RendererOptions.MaxCanvasSize; 0 means MaxCanvasSize.
+func ImageMimeWithin(data []byte, maxCanvas int) (string, error) {
+	maxCanvas = cmp.Or(maxCanvas, MaxCanvasSize)
+	if isAVI(data) {
+		frame, err := firstAVIFrame(data)
+		if err != nil {
+			return "", err
+		}
+		if _, err := checkRaster(frame, maxCanvas); err != nil {
+			return "", fmt.Errorf("AVI frame: %w", err)
+		}
+		return "video/x-msvideo", nil
+	}
+	if isSVG(data) {
+		if _, err := parseSVG(data); err != nil {
+			return "", err
+		}
+		return "image/svg+xml", nil
+	}
+	format, err := checkRaster(data, maxCanvas)
+	if err != nil {
+		return "", err
+	}
+	return "image/" + format, nil
+}
+
+// checkRaster decodes a raster image, after checking from its header that
+// it covers no more pixels than a maxCanvas square, and returns its format
+// name.
+func checkRaster(data []byte, maxCanvas int) (string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if limit := int64(maxCanvas) * int64(maxCanvas); int64(cfg.Width)*int64(cfg.Height) > limit {
+		return "", fmt.Errorf("%d×%d %s covers more pixels than a %d×%d canvas", cfg.Width, cfg.Height, format, maxCanvas, maxCanvas)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return format, nil
+}
+
+// svgPoint is a point in viewBox units.
+type svgPoint struct{ x, y float64 }
+
+// svgSeg is a path segment from the previous point: a line to p[2], or a
+// cubic Bézier through p[0] and p[1] to p[2].
+type svgSeg struct {
+	cubic bool
+	p     [3]svgPoint
+}
+
+// svgSubpath is one run of connected segments.
+type svgSubpath struct {
+	start  svgPoint
+	segs   []svgSeg
+	closed bool
+}
+
+// svgShape is one filled and/or stroked element, already transformed.
+type svgShape struct {
+	paths       []svgSubpath
+	fill        color.NRGBA // A == 0: no fill
+	stroke      color.NRGBA // A == 0: no stroke
+	strokeWidth float64     // viewBox units
+	evenOdd     bool
+	cap         string // "butt", "round" or "square"
+	join        string // "miter", "round" or "bevel"
+}
+
+// svgImage is a parsed SVG document.
+type svgImage struct {
+	viewBox [4]float64 // min x, min y, width, height
+	shapes  []svgShape
+	skipped []string // unsupported features left out, sorted
+}
+
+// svgNode is an element of the document tree.
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	children []*svgNode
+	text     string // character data, for <style>
+}
+
+// svgAffine is the matrix [a c e; b d f; 0 0 1].
+type svgAffine [6]float64
+
+var svgIdentity = svgAffine{1, 0, 0, 1, 0, 0}
+
+func (m svgAffine) apply(p svgPoint) svgPoint {
+	return svgPoint{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// mul returns m·n: n applied first.
+func (m svgAffine) mul(n svgAffine) svgAffine {
+	return svgAffine{
+		m[0]*n[0] + m[2]*n[1], m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3], m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4], m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// scale is how much m scales lengths on average.
+func (m svgAffine) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// svgPaint is a fill or stroke value.
+type svgPaint struct {
+	c    color.NRGBA
+	none bool
+}
+
+// svgState is the inherited presentation state at an element.
+type svgState struct {
+	fill, stroke                        svgPaint
+	fillOpacity, strokeOpacity, opacity float64
+	strokeWidth                         float64
+	evenOdd                             bool
+	cap, join                           string
+	m                                   svgAffine
+}
+
+// svgParser walks the document tree into shapes.
+type svgParser struct {
+	img     *svgImage
+	ids     map[string]*svgNode
+	classes map[string]map[string]string // .class → declarations
+	skipped map[string]bool
+}
+
+// parseSVG parses an SVG document.
+func parseSVG(data []byte) (*svgImage, error) {
+	root, err := parseSVGTree(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "svg" {
+		return nil, fmt.Errorf("svg: root element is <%s>", root.name)
+	}
+
+	img := &svgImage{}
+	w, h := svgLength(root.attrs["width"], 0), svgLength(root.attrs["height"], 0)
+	if vb := svgNumbers(root.attrs["viewBox"]); len(vb) == 4 && vb[2] > 0 && vb[3] > 0 {
+		img.viewBox = [4]float64{vb[0], vb[1], vb[2], vb[3]}
+	} else {
+		if w <= 0 || h <= 0 {
+			w, h = 300, 150 // the CSS default size of a replaced element
+		}
+		img.viewBox = [4]float64{0, 0, w, h}
+	}
+
+	p := &svgParser{
+		img:     img,
+		ids:     map[string]*svgNode{},
+		classes: map[string]map[string]string{},
+		skipped: map[string]bool{},
+	}
+	p.index(root)
+	state := svgState{
+		fill:        svgPaint{c: color.NRGBA{0, 0, 0, 255}},
+		stroke:      svgPaint{none: true},
+		fillOpacity: 1, strokeOpacity: 1, opacity: 1,
+		strokeWidth: 1,
+		cap:         "butt",
+		join:        "miter",
+		m:           svgIdentity,
+	}
+	p.walkChildren(root, state, 0)
+
+	for name := range p.skipped {
+		img.skipped = append(img.skipped, name)
+	}
+	slices.Sort(img.skipped)
+	return img, nil
+}
+
+// parseSVGTree decodes the XML into svgNodes, keeping only SVG-namespace
+// elements (editor metadata such as Inkscape's is dropped).
+func parseSVGTree(data []byte) (*svgNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	var root *svgNode
+	var stack []*svgNode
+	skip := 0 // depth inside a dropped element
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if root != nil && len(stack) == 0 {
+				return root, nil
+			}
+			return nil, fmt.Errorf("svg: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || (t.Name.Space != "" && t.Name.Space != svgNS) {
+				skip++
+				continue
+			}
+			n := &svgNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				if a.Name.Space == "" || a.Name.Space == svgNS || a.Name.Local == "href" {
+					n.attrs[a.Name.Local] = a.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 && root != nil {
+				return root, nil
+			}
+		case xml.CharData:
+			if skip == 0 && len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+}
+
+// index records element IDs and <style> class rules before drawing,
+// since they may be defined after their first use.
+func (p *svgParser) index(n *svgNode) {
+	if id := n.attrs["id"]; id != "" {
+		p.ids[id] = n
+	}
+	if n.name == "style" {
+		p.parseStyleSheet(n.text)
+	}
+	for _, c := range n.children {
+		p.index(c)
+	}
+}
+
+// gradientColor is the color a gradient is drawn in: its middle stop's.
+// A gradient without stops takes those of the one its href names.
+func (p *svgParser) gradientColor(id string) (color.NRGBA, bool) {
+	for range maxSVGUseDepth {
+		n := p.ids[id]
+		if n == nil || (n.name != "linearGradient" && n.name != "radialGradient") {
+			return color.NRGBA{}, false
+		}
+		var stops []color.NRGBA
+		for _, s := range n.children {
+			if s.name != "stop" {
+				continue
+			}
+			decl := parseSVGStyle(s.attrs["style"])
+			c, ok := parseSVGColor(cmp.Or(decl["stop-color"], s.attrs["stop-color"], "black"))
+			if ok {
+				c = withOpacity(c, svgOpacity(cmp.Or(decl["stop-opacity"], s.attrs["stop-opacity"])))
+				stops = append(stops, c)
+			}
+		}
+		if len(stops) > 0 {
+			return stops[len(stops)/2], true
+		}
+		id = strings.TrimPrefix(n.attrs["href"], "#")
+	}
+	return color.NRGBA{}, false
+}
+
+// parseStyleSheet keeps the rules of a <style> sheet whose selectors are
+// all plain class selectors (".a, .b").
+func (p *svgParser) parseStyleSheet(css string) {
+	for len(css) > 0 {
+		open := strings.IndexByte(css, '{')
+		end := strings.IndexByte(css, '}')
+		if open < 0 || end < open {
+			break
+		}
+		selectors, body := css[:open], css[open+1:end]
+		css = css[end+1:]
+		decl := parseSVGStyle(body)
+		for _, sel := range strings.Split(selectors, ",") {
+			sel = strings.TrimSpace(sel)
+			if name, ok := strings.CutPrefix(sel, "."); ok && name != "" && !strings.ContainsAny(name, " .#:>[*+~") {
+				if p.classes[name] == nil {
+					p.classes[name] = map[string]string{}
+				}
+				for k, v := range decl {
+					p.classes[name][k] = v
+				}
+			} else if sel != "" {
+				p.skipped["CSS selectors other than .class"] = true
+			}
+		}
+	}
+}
+
+func (p *svgParser) walkChildren(n *svgNode, state svgState, depth int) {
+	for _, c := range n.children {
+		p.walk(c, state, depth)
+	}
+}
+
+// walk draws n and its children.
+func (p *svgParser) walk(n *svgNode, state svgState, depth int) {
+	switch n.name {
+	case "defs", "title", "desc", "metadata", "style", "linearGradient", "radialGradient", "symbol":
+		return // indexed, or not drawn by themselves
+	case "g", "a", "svg", "switch", "path", "rect", "circle", "ellipse", "line", "polyline", "polygon", "use":
+	default:
+		p.skipped["<"+n.name+">"] = true
+		return
+	}
+
+	props := p.properties(n)
+	if props["display"] == "none" {
+		return
+	}
+	if !p.applyState(&state, n, props) {
+		return
+	}
+
+	switch n.name {
+	case "g", "a", "svg", "switch":
+		if n.name == "svg" {
+			state.m = state.m.mul(svgAffine{1, 0, 0, 1, svgLength(n.attrs["x"], 0), svgLength(n.attrs["y"], 0)})
+		}
+		p.walkChildren(n, state, depth)
+	case "use":
+		ref := strings.TrimPrefix(n.attrs["href"], "#")
+		target := p.ids[ref]
+		if target == nil || depth >= maxSVGUseDepth {
+			p.skipped["<use> of a missing or nested element"] = true
+			return
+		}
+		state.m = state.m.mul(svgAffine{1, 0, 0, 1, svgLength(n.attrs["x"], 0), svgLength(n.attrs["y"], 0)})
+		if target.name == "symbol" {
+			p.walkChildren(target, state, depth+1)
+		} else {
+			p.walk(target, state, depth+1)
+		}
+	default:
+		if props["visibility"] == "hidden" {
+			return
+		}
+		paths := p.shapePaths(n)
+		if len(paths) == 0 {
+			return
+		}
+		for i := range paths {
+			paths[i] = transformSubpath(paths[i], state.m)
+		}
+		sh := svgShape{paths: paths, evenOdd: state.evenOdd, cap: state.cap, join: state.join}
+		if !state.fill.none {
+			sh.fill = withOpacity(state.fill.c, state.fillOpacity*state.opacity)
+		}
+		if !state.stroke.none && state.strokeWidth > 0 {
+			sh.stroke = withOpacity(state.stroke.c, state.strokeOpacity*state.opacity)
+			sh.strokeWidth = state.strokeWidth * state.m.scale()
+		}
+		if sh.fill.A > 0 || sh.stroke.A > 0 {
+			p.img.shapes = append(p.img.shapes, sh)
+		}
+	}
+}
+
+// properties merges an element's presentation attributes, class rules and
+// style attribute, later ones winning.
+func (p *svgParser) properties(n *svgNode) map[string]string {
+	props := make(map[string]string)
+	for k, v := range n.attrs {
+		props[k] = v
+	}
+	for _, class := range strings.Fields(n.attrs["class"]) {
+		for k, v := range p.classes[class] {
+			props[k] = v
+		}
+	}
+	for k, v := range parseSVGStyle(n.attrs["style"]) {
+		props[k] = v
+	}
+	return props
+}
+
+// applyState updates state with n's properties and transform. It returns
+// false if n draws nothing (a zero opacity).
+func (p *svgParser) applyState(state *svgState, n *svgNode, props map[string]string) bool {
+	if v, ok := props["fill"]; ok {
+		state.fill = p.paint(v, state.fill)
+	}
+	if v, ok := props["stroke"]; ok {
+		state.stroke = p.paint(v, state.stroke)
+	}
+	if v, ok := props["fill-opacity"]; ok {
+		state.fillOpacity = svgOpacity(v)
+	}
+	if v, ok := props["stroke-opacity"]; ok {
+		state.strokeOpacity = svgOpacity(v)
+	}
+	if v, ok := props["opacity"]; ok {
+		state.opacity *= svgOpacity(v) // group opacity, applied to each shape
+	}
+	if v, ok := props["stroke-width"]; ok {
+		state.strokeWidth = svgLength(v, state.strokeWidth)
+	}
+	if v, ok := props["fill-rule"]; ok {
+		state.evenOdd = strings.TrimSpace(v) == "evenodd"
+	}
+	if v, ok := props["stroke-linecap"]; ok {
+		state.cap = strings.TrimSpace(v)
+	}
+	if v, ok := props["stroke-linejoin"]; ok {
+		state.join = strings.TrimSpace(v)
+	}
+	for _, prop := range []string{"filter", "mask", "clip-path", "stroke-dasharray", "marker-start", "marker-mid", "marker-end"} {
+		if v := strings.TrimSpace(props[prop]); v != "" && v != "none" {
+			p.skipped[prop] = true
+		}
+	}
+	if t := n.attrs["transform"]; t != "" {
+		m, ok := parseSVGTransform(t)
+		if !ok {
+			p.skipped["malformed transform"] = true
+		}
+		state.m = state.m.mul(m)
+	}
+	return state.opacity > 0
+}
+
+// paint parses a fill or stroke value; inherit keeps the parent's.
+func (p *svgParser) paint(v string, inherit svgPaint) svgPaint {
+	v = strings.TrimSpace(v)
+	switch {
+	case v == "none" || v == "transparent":
+		return svgPaint{none: true}
+	case v == "inherit" || v == "currentColor" || v == "":
+		return inherit
+	case strings.HasPrefix(v, "url("):
+		id, _, _ := strings.Cut(strings.TrimPrefix(v, "url("), ")")
+		id = strings.TrimPrefix(strings.Trim(id, " '\""), "#")
+		if c, ok := p.gradientColor(id); ok {
+			p.skipped["gradients (drawn in one stop color)"] = true
+			return svgPaint{c: c}
+		}
+		p.skipped["patterns"] = true
+		return svgPaint{none: true}
+	}
+	c, ok := parseSVGColor(v)
+	if !ok {
+		p.skipped["unknown color "+strconv.Quote(v)] = true
+		return inherit
+	}
+	return svgPaint{c: c}
+}
+
+// shapePaths converts a drawing element to subpaths in its own units.
+func (p *svgParser) shapePaths(n *svgNode) []svgSubpath {
+	vw, vh := p.img.viewBox[2], p.img.viewBox[3]
+	num := func(name string, ref float64) float64 {
+		v := n.attrs[name]
+		if s, ok := strings.CutSuffix(strings.TrimSpace(v), "%"); ok {
+			f, _ := strconv.ParseFloat(s, 64)
+			return f / 100 * ref
+		}
+		return svgLength(v, 0)
+	}
+	diag := math.Hypot(vw, vh) / math.Sqrt2
+
+	switch n.name {
+	case "path":
+		paths, ok := parseSVGPath(n.attrs["d"])
+		if !ok {
+			p.skipped["malformed path data"] = true
+		}
+		return paths
+	case "rect":
+		x, y, w, h := num("x", vw), num("y", vh), num("width", vw), num("height", vh)
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		_, hasRx := n.attrs["rx"]
+		_, hasRy := n.attrs["ry"]
+		rx, ry := num("rx", vw), num("ry", vh)
+		if !hasRx {
+			rx = ry
+		}
+		if !hasRy {
+			ry = rx
+		}
+		return []svgSubpath{roundedRectPath(x, y, w, h, min(rx, w/2), min(ry, h/2))}
+	case "circle":
+		r := num("r", diag)
+		if r <= 0 {
+			return nil
+		}
+		return []svgSubpath{ellipsePath(num("cx", vw), num("cy", vh), r, r)}
+	case "ellipse":
+		rx, ry := num("rx", vw), num("ry", vh)
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		return []svgSubpath{ellipsePath(num("cx", vw), num("cy", vh), rx, ry)}
+	case "line":
+		return []svgSubpath{{
+			start: svgPoint{num("x1", vw), num("y1", vh)},
+			segs:  []svgSeg{{p: [3]svgPoint{2: {num("x2", vw), num("y2", vh)}}}},
+		}}
+	case "polyline", "polygon":
+		pts := svgNumbers(n.attrs["points"])
+		if len(pts) < 4 {
+			return nil
+		}
+		sp := svgSubpath{start: svgPoint{pts[0], pts[1]}, closed: n.name == "polygon"}
+		for i := 2; i+1 < len(pts); i += 2 {
+			sp.segs = append(sp.segs, svgSeg{p: [3]svgPoint{2: {pts[i], pts[i+1]}}})
+		}
+		return []svgSubpath{sp}
+	}
+	return nil
+}
+
+// ── Geometry ──
+
+// kappa places cubic control points to approximate a quarter circle.
+const kappa = 0.5522847498
+
+func ellipsePath(cx, cy, rx, ry float64) svgSubpath {
+	kx, ky := rx*kappa, ry*kappa
+	return svgSubpath{
+		start:  svgPoint{cx + rx, cy},
+		closed: true,
+		segs: []svgSeg{
+			{true, [3]svgPoint{{cx + rx, cy + ky}, {cx + kx, cy + ry}, {cx, cy + ry}}},
+			{true, [3]svgPoint{{cx - kx, cy + ry}, {cx - rx, cy + ky}, {cx - rx, cy}}},
+			{true, [3]svgPoint{{cx - rx, cy - ky}, {cx - kx, cy - ry}, {cx, cy - ry}}},
+			{true, [3]svgPoint{{cx + kx, cy - ry}, {cx + rx, cy - ky}, {cx + rx, cy}}},
+		},
+	}
+}
+
+func roundedRectPath(x, y, w, h, rx, ry float64) svgSubpath {
+	if rx <= 0 || ry <= 0 {
+		sp := svgSubpath{start: svgPoint{x, y}, closed: true}
+		for _, pt := range []svgPoint{{x + w, y}, {x + w, y + h}, {x, y + h}} {
+			sp.segs = append(sp.segs, svgSeg{p: [3]svgPoint{2: pt}})
+		}
+		return sp
+	}
+	kx, ky := rx*kappa, ry*kappa
+	line := func(px, py float64) svgSeg { return svgSeg{p: [3]svgPoint{2: {px, py}}} }
+	curve := func(c1, c2, e svgPoint) svgSeg { return svgSeg{true, [3]svgPoint{c1, c2, e}} }
+	r, b := x+w, y+h
+	return svgSubpath{
+		start:  svgPoint{x + rx, y},
+		closed: true,
+		segs: []svgSeg{
+			line(r-rx, y),
+			curve(svgPoint{r - rx + kx, y}, svgPoint{r, y + ry - ky}, svgPoint{r, y + ry}),
+			line(r, b-ry),
+			curve(svgPoint{r, b - ry + ky}, svgPoint{r - rx + kx, b}, svgPoint{r - rx, b}),
+			line(x+rx, b),
+			curve(svgPoint{x + rx - kx, b}, svgPoint{x, b - ry + ky}, svgPoint{x, b - ry}),
+			line(x, y+ry),
+			curve(svgPoint{x, y + ry - ky}, svgPoint{x + rx - kx, y}, svgPoint{x + rx, y}),
+		},
+	}
+}
+
+func transformSubpath(sp svgSubpath, m svgAffine) svgSubpath {
+	out := svgSubpath{start: m.apply(sp.start), closed: sp.closed, segs: make([]svgSeg, len(sp.segs))}
+	for i, s := range sp.segs {
+		out.segs[i] = svgSeg{s.cubic, [3]svgPoint{m.apply(s.p[0]), m.apply(s.p[1]), m.apply(s.p[2])}}
+	}
+	return out
+}
+
+func withOpacity(c color.NRGBA, opacity float64) color.NRGBA {
+	c.A = uint8(math.Round(float64(c.A) * min(max(opacity, 0), 1)))
+	return c
+}
+
+// ── Attribute values ──
+
+// parseSVGStyle splits a style attribute or CSS rule body into
+// declarations.
+func parseSVGStyle(s string) map[string]string {
+	decl := map[string]string{}
+	for _, d := range strings.Split(s, ";") {
+		if k, v, ok := strings.Cut(d, ":"); ok {
+			decl[strings.TrimSpace(k)] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important"))
+		}
+	}
+	return decl
+}
+
+// svgUnits are the absolute units in px, which are user units.
+var svgUnits = map[string]float64{
+	"": 1, "px": 1, "pt": 96.0 / 72, "pc": 16, "in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4,
+}
+
+// svgLength parses a length in user units. Relative units (em, %) are
+// read as user units. def is used when s is empty or malformed.
+func svgLength(s string, def float64) float64 {
+	s = strings.TrimSpace(s)
+	num := strings.TrimRight(s, "abcdefghijklmnopqrstuvwxyz%")
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return def
+	}
+	if u, ok := svgUnits[s[len(num):]]; ok {
+		f *= u
+	}
+	return f
+}
+
+func svgOpacity(s string) float64 {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		return min(max(svgLength(pct, 100)/100, 0), 1)
+	}
+	return min(max(svgLength(s, 1), 0), 1)
+}
+
+// svgNumbers parses a list of numbers separated by commas and/or spaces.
+func svgNumbers(s string) []float64 {
+	sc := svgScanner{s: s}
+	var nums []float64
+	for {
+		v, ok := sc.number()
+		if !ok {
+			return nums
+		}
+		nums = append(nums, v)
+	}
+}
+
+// parseSVGTransform parses a transform list. ok is false if part of it
+// could not be read; the parts before are kept.
+func parseSVGTransform(s string) (svgAffine, bool) {
+	m := svgIdentity
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " \t\r\n,") {
+		open, end := strings.IndexByte(s, '('), strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m, false
+		}
+		name, args := strings.TrimSpace(s[:open]), svgNumbers(s[open+1:end])
+		s = s[end+1:]
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		var t svgAffine
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				return m, false
+			}
+			t = svgAffine(args)
+		case "translate":
+			t = svgAffine{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			t = svgAffine{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			sin, cos := math.Sincos(arg(0, 0) * math.Pi / 180)
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = svgAffine{1, 0, 0, 1, cx, cy}.mul(svgAffine{cos, sin, -sin, cos, 0, 0}).mul(svgAffine{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			t = svgAffine{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = svgAffine{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, false
+		}
+		m = m.mul(t)
+	}
+	return m, true
+}
+
+// svgNamedColors are the CSS color keywords most often found in exported
+// artwork.
+var svgNamedColors = map[string]color.NRGBA{
+	"black": {0, 0, 0, 255}, "white": {255, 255, 255, 255}, "red": {255, 0, 0, 255},
+	"green": {0, 128, 0, 255}, "blue": {0, 0, 255, 255}, "yellow": {255, 255, 0, 255},
+	"cyan": {0, 255, 255, 255}, "aqua": {0, 255, 255, 255}, "magenta": {255, 0, 255, 255},
+	"fuchsia": {255, 0, 255, 255}, "gray": {128, 128, 128, 255}, "grey": {128, 128, 128, 255},
+	"silver": {192, 192, 192, 255}, "maroon": {128, 0, 0, 255}, "olive": {128, 128, 0, 255},
+	"lime": {0, 255, 0, 255}, "navy": {0, 0, 128, 255}, "purple": {128, 0, 128, 255},
+	"teal": {0, 128, 128, 255}, "orange": {255, 165, 0, 255}, "pink": {255, 192, 203, 255},
+	"brown": {165, 42, 42, 255}, "gold": {255, 215, 0, 255}, "darkgray": {169, 169, 169, 255},
+	"lightgray": {211, 211, 211, 255}, "transparent": {},
+}
+
+// parseSVGColor parses #rgb, #rgba, #rrggbb, #rrggbbaa, rgb()/rgba() and
+// the names in svgNamedColors.
+func parseSVGColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := svgNamedColors[s]; ok {
+		return c, true
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 3 || len(hex) == 4 {
+			var long []byte
+			for i := range len(hex) {
+				long = append(long, hex[i], hex[i])
+			}
+			hex = string(long)
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 8 || err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+	}
+	if args, ok := strings.CutPrefix(s, "rgba("); ok {
+		s = "rgb(" + args
+	}
+	if args, ok := strings.CutPrefix(s, "rgb("); ok {
+		parts := strings.FieldsFunc(strings.TrimSuffix(args, ")"), func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(parts) < 3 {
+			return color.NRGBA{}, false
+		}
+		var c [4]uint8
+		c[3] = 255
+		for i, part := range parts[:min(len(parts), 4)] {
+			if i == 3 {
+				c[3] = uint8(math.Round(svgOpacity(part) * 255))
+				continue
+			}
+			v := svgLength(part, 0)
+			if strings.HasSuffix(part, "%") {
+				v *= 2.55
+			}
+			c[i] = uint8(min(max(math.Round(v), 0), 255))
+		}
+		return color.NRGBA{c[0], c[1], c[2], c[3]}, true
+	}
+	return color.NRGBA{}, false
+}
@@ -0,0 +1,297 @@
+// svgpath.go — SVG path data ("M10 10 h 5 a 2 2 0 0 1 ..."), parsed into
+// lines and cubic Béziers; quadratic curves and arcs are converted.
+package template
+
+import (
+	"math"
+	"strconv"
+)
+
+// svgScanner reads numbers and flags from path data and number lists.
+type svgScanner struct {
+	s string
+	i int
+}
+
+func (sc *svgScanner) skipSeparators() {
+	for sc.i < len(sc.s) {
+		switch sc.s[sc.i] {
+		case ' ', '\t', '\r', '\n', ',':
+			sc.i++
+		default:
+			return
+		}
+	}
+}
+
+// number reads the next number, which may follow the last without a
+// separator ("1-2", "0.5.5").
+func (sc *svgScanner) number() (float64, bool) {
+	sc.skipSeparators()
+	start := sc.i
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '+' || sc.s[sc.i] == '-') {
+		sc.i++
+	}
+	digits, dot := false, false
+	for sc.i < len(sc.s) {
+		c := sc.s[sc.i]
+		if c >= '0' && c <= '9' {
+			digits = true
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+		sc.i++
+	}
+	if digits && sc.i < len(sc.s) && (sc.s[sc.i] == 'e' || sc.s[sc.i] == 'E') {
+		j := sc.i + 1
+		if j < len(sc.s) && (sc.s[j] == '+' || sc.s[j] == '-') {
+			j++
+		}
+		if j < len(sc.s) && sc.s[j] >= '0' && sc.s[j] <= '9' {
+			for sc.i = j; sc.i < len(sc.s) && sc.s[sc.i] >= '0' && sc.s[sc.i] <= '9'; sc.i++ {
+			}
+		}
+	}
+	if !digits {
+		sc.i = start
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(sc.s[start:sc.i], 64)
+	return v, err == nil && !math.IsInf(v, 0)
+}
+
+// flag reads an arc flag, a single 0 or 1 that needs no separator.
+func (sc *svgScanner) flag() (bool, bool) {
+	sc.skipSeparators()
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '0' || sc.s[sc.i] == '1') {
+		sc.i++
+		return sc.s[sc.i-1] == '1', true
+	}
+	return false, false
+}
+
+// command reads a command letter, if one comes next.
+func (sc *svgScanner) command() (byte, bool) {
+	sc.skipSeparators()
+	if sc.i < len(sc.s) {
+		if c := sc.s[sc.i]; (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') && c != 'e' && c != 'E' {
+			sc.i++
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// svgArgCount is the number of arguments each path command takes.
+var svgArgCount = map[byte]int{
+	'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'T': 2, 'A': 7, 'Z': 0,
+}
+
+// parseSVGPath parses path data. As in browsers, an error ends the path
+// but keeps what came before it; ok reports whether all of d was read.
+func parseSVGPath(d string) (paths []svgSubpath, ok bool) {
+	sc := svgScanner{s: d}
+	var cur *svgSubpath
+	var pos, start svgPoint
+	var lastCtrl svgPoint // reflected by S and T
+	var prev byte         // previous command, upper case
+
+	flush := func() {
+		if cur != nil && len(cur.segs) > 0 {
+			paths = append(paths, *cur)
+		}
+		cur = nil
+	}
+	ensure := func() {
+		if cur == nil {
+			cur = &svgSubpath{start: pos}
+			start = pos
+		}
+	}
+
+	var cmd byte
+	for {
+		if c, found := sc.command(); found {
+			cmd = c
+		} else {
+			sc.skipSeparators()
+			if sc.i >= len(sc.s) {
+				flush()
+				return paths, true
+			}
+			if cmd == 0 || cmd == 'Z' || cmd == 'z' {
+				flush()
+				return paths, false
+			}
+			// Repeated arguments: a moveto's extra pairs are linetos.
+			switch cmd {
+			case 'M':
+				cmd = 'L'
+			case 'm':
+				cmd = 'l'
+			}
+		}
+
+		upper := cmd &^ 0x20
+		n, known := svgArgCount[upper]
+		if !known {
+			flush()
+			return paths, false
+		}
+		var a [7]float64
+		for i := range n {
+			var good bool
+			if upper == 'A' && (i == 3 || i == 4) {
+				var f bool
+				f, good = sc.flag()
+				if f {
+					a[i] = 1
+				}
+			} else {
+				a[i], good = sc.number()
+			}
+			if !good {
+				flush()
+				return paths, false
+			}
+		}
+
+		var base svgPoint
+		if cmd != upper { // relative
+			base = pos
+		}
+		pt := func(i int) svgPoint { return svgPoint{base.x + a[i], base.y + a[i+1]} }
+
+		switch upper {
+		case 'M':
+			flush()
+			pos = pt(0)
+			ensure()
+		case 'Z':
+			if cur != nil {
+				cur.closed = true
+				flush()
+			}
+			pos = start
+		case 'L', 'H', 'V':
+			ensure()
+			switch upper {
+			case 'L':
+				pos = pt(0)
+			case 'H':
+				pos.x = base.x + a[0]
+			case 'V':
+				pos.y = base.y + a[0]
+			}
+			cur.segs = append(cur.segs, svgSeg{p: [3]svgPoint{2: pos}})
+		case 'C', 'S':
+			ensure()
+			var c1, c2, end svgPoint
+			if upper == 'C' {
+				c1, c2, end = pt(0), pt(2), pt(4)
+			} else {
+				c1 = pos
+				if prev == 'C' || prev == 'S' {
+					c1 = svgPoint{2*pos.x - lastCtrl.x, 2*pos.y - lastCtrl.y}
+				}
+				c2, end = pt(0), pt(2)
+			}
+			cur.segs = append(cur.segs, svgSeg{true, [3]svgPoint{c1, c2, end}})
+			lastCtrl, pos = c2, end
+		case 'Q', 'T':
+			ensure()
+			var q, end svgPoint
+			if upper == 'Q' {
+				q, end = pt(0), pt(2)
+			} else {
+				q = pos
+				if prev == 'Q' || prev == 'T' {
+					q = svgPoint{2*pos.x - lastCtrl.x, 2*pos.y - lastCtrl.y}
+				}
+				end = pt(0)
+			}
+			// The cubic with the same curve as the quadratic.
+			c1 := svgPoint{pos.x + 2.0/3*(q.x-pos.x), pos.y + 2.0/3*(q.y-pos.y)}
+			c2 := svgPoint{end.x + 2.0/3*(q.x-end.x), end.y + 2.0/3*(q.y-end.y)}
+			cur.segs = append(cur.segs, svgSeg{true, [3]svgPoint{c1, c2, end}})
+			lastCtrl, pos = q, end
+		case 'A':
+			ensure()
+			end := svgPoint{base.x + a[5], base.y + a[6]}
+			cur.segs = append(cur.segs, arcSegs(pos, end, a[0], a[1], a[2], a[3] == 1, a[4] == 1)...)
+			pos = end
+		}
+		prev = upper
+	}
+}
+
+// arcSegs converts an elliptical arc to cubics, following the endpoint
+// to center conversion in the SVG specification (appendix B.2.4).
+func arcSegs(from, to svgPoint, rx, ry, angle float64, large, sweep bool) []svgSeg {
+	if from == to {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return []svgSeg{{p: [3]svgPoint{2: to}}}
+	}
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	dx, dy := (from.x-to.x)/2, (from.y-to.y)/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+
+	// Scale up radii too small to reach the end point.
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(max(num/den, 0))
+	if large == sweep {
+		k = -k
+	}
+	cx1, cy1 := k*rx*y1/ry, -k*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (from.x+to.x)/2
+	cy := sin*cx1 + cos*cy1 + (from.y+to.y)/2
+
+	vecAngle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := vecAngle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := vecAngle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// At most a quarter turn per cubic.
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	t := 4.0 / 3 * math.Tan(step/4)
+	point := func(a float64) (svgPoint, svgPoint) {
+		sa, ca := math.Sincos(a)
+		ex, ey := rx*ca, ry*sa  // on the unrotated ellipse
+		tx, ty := -rx*sa, ry*ca // its tangent
+		rot := func(x, y float64) (float64, float64) { return cos*x - sin*y, sin*x + cos*y }
+		px, py := rot(ex, ey)
+		vx, vy := rot(tx, ty)
+		return svgPoint{cx + px, cy + py}, svgPoint{vx, vy}
+	}
+	segs := make([]svgSeg, n)
+	p0, d0 := point(theta)
+	for i := range n {
+		p1, d1 := point(theta + step*float64(i+1))
+		if i == n-1 {
+			p1 = to
+		}
+		segs[i] = svgSeg{true, [3]svgPoint{
+			{p0.x + t*d0.x, p0.y + t*d0.y},
+			{p1.x - t*d1.x, p1.y - t*d1.y},
+			p1,
+		}}
+		p0, d0 = p1, d1
+	}
+	return segs
+}
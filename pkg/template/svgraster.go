@@ -0,0 +1,302 @@
+// svgraster.go — Draw a parsed SVG at a pixel size.
+//
+// Shapes are flattened to polygons in pixel space and filled with
+// golang.org/x/image/vector, whose accumulation is the nonzero rule.
+// Even-odd fills combine each subpath's coverage; strokes are outlined as
+// one polygon per segment, join and cap, all wound the same way so that
+// they fill as a union.
+package template
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/vector"
+)
+
+// svgMiterLimit is SVG's default stroke-miterlimit.
+const svgMiterLimit = 4
+
+// svgPolygon is a flattened subpath in pixels.
+type svgPolygon struct {
+	pts    []svgPoint
+	closed bool
+}
+
+// fit sizes img for a box of box pixels drawn with a backgroundFit: the
+// raster's size, and toPx from viewBox units to its pixels. A contained
+// image keeps its aspect ratio; a covering one is already cropped to the
+// box, as drawFit would crop it.
+func (img *svgImage) fit(box image.Point, fit string) (w, h int, toPx svgAffine) {
+	vb := img.viewBox
+	bw, bh := float64(max(box.X, 1)), float64(max(box.Y, 1))
+	sx, sy := bw/vb[2], bh/vb[3]
+	var ox, oy float64
+	switch fit {
+	case "contain":
+		sx = min(sx, sy)
+		sy = sx
+		bw, bh = max(math.Round(vb[2]*sx), 1), max(math.Round(vb[3]*sy), 1)
+	case "cover":
+		sx = max(sx, sy)
+		sy = sx
+		ox, oy = (bw-vb[2]*sx)/2, (bh-vb[3]*sy)/2
+	}
+	return int(bw), int(bh), svgAffine{sx, 0, 0, sy, ox - vb[0]*sx, oy - vb[1]*sy}
+}
+
+// rasterize draws img into a w×h image, toPx mapping its viewBox.
+func (img *svgImage) rasterize(w, h int, toPx svgAffine) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for _, sh := range img.shapes {
+		polys := make([]svgPolygon, 0, len(sh.paths))
+		for _, sp := range sh.paths {
+			polys = append(polys, flattenSubpath(transformSubpath(sp, toPx)))
+		}
+		if sh.fill.A > 0 {
+			var mask *image.Alpha
+			if sh.evenOdd {
+				mask = evenOddCoverage(polys, dst.Rect)
+			} else {
+				mask = svgCoverage(polys, dst.Rect)
+			}
+			svgPaintMask(dst, mask, sh.fill)
+		}
+		if sh.stroke.A > 0 {
+			outline := strokePolygons(polys, sh.strokeWidth*toPx.scale()/2, sh.cap, sh.join)
+			svgPaintMask(dst, svgCoverage(outline, dst.Rect), sh.stroke)
+		}
+	}
+	return dst
+}
+
+func svgPaintMask(dst *image.RGBA, mask *image.Alpha, c color.NRGBA) {
+	if mask == nil {
+		return
+	}
+	draw.DrawMask(dst, mask.Rect, image.NewUniform(c), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// flattenSubpath replaces cubics with enough line segments that they stay
+// smooth at any size, dropping repeated points.
+func flattenSubpath(sp svgSubpath) svgPolygon {
+	poly := svgPolygon{pts: []svgPoint{sp.start}, closed: sp.closed}
+	add := func(p svgPoint) {
+		last := poly.pts[len(poly.pts)-1]
+		if math.Abs(p.x-last.x) > 1e-6 || math.Abs(p.y-last.y) > 1e-6 {
+			poly.pts = append(poly.pts, p)
+		}
+	}
+	cur := sp.start
+	for _, s := range sp.segs {
+		if s.cubic {
+			// The control polygon's length bounds the curve's.
+			l := math.Hypot(s.p[0].x-cur.x, s.p[0].y-cur.y) +
+				math.Hypot(s.p[1].x-s.p[0].x, s.p[1].y-s.p[0].y) +
+				math.Hypot(s.p[2].x-s.p[1].x, s.p[2].y-s.p[1].y)
+			n := int(min(max(math.Ceil(math.Sqrt(8*l)), 1), 100))
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+				add(svgPoint{
+					a*cur.x + b*s.p[0].x + c*s.p[1].x + d*s.p[2].x,
+					a*cur.y + b*s.p[0].y + c*s.p[1].y + d*s.p[2].y,
+				})
+			}
+		} else {
+			add(s.p[2])
+		}
+		cur = s.p[2]
+	}
+	if poly.closed && len(poly.pts) > 1 {
+		first, last := poly.pts[0], poly.pts[len(poly.pts)-1]
+		if math.Abs(first.x-last.x) <= 1e-6 && math.Abs(first.y-last.y) <= 1e-6 {
+			poly.pts = poly.pts[:len(poly.pts)-1]
+		}
+	}
+	return poly
+}
+
+// svgCoverage fills polys, each implicitly closed, with the nonzero rule
+// into a mask over their bounding box within clip. It returns nil if
+// nothing falls inside clip.
+func svgCoverage(polys []svgPolygon, clip image.Rectangle) *image.Alpha {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range polys {
+		for _, p := range poly.pts {
+			minX, minY = min(minX, p.x), min(minY, p.y)
+			maxX, maxY = max(maxX, p.x), max(maxY, p.y)
+		}
+	}
+	if minX > maxX {
+		return nil
+	}
+	// Clamp before converting: off-canvas coordinates can be huge.
+	lim := func(v float64, lo, hi int) int { return int(min(max(v, float64(lo)), float64(hi))) }
+	b := image.Rect(
+		lim(math.Floor(minX), clip.Min.X, clip.Max.X), lim(math.Floor(minY), clip.Min.Y, clip.Max.Y),
+		lim(math.Ceil(maxX), clip.Min.X, clip.Max.X), lim(math.Ceil(maxY), clip.Min.Y, clip.Max.Y),
+	)
+	if b.Empty() {
+		return nil
+	}
+
+	z := vector.NewRasterizer(b.Dx(), b.Dy())
+	ox, oy := float64(b.Min.X), float64(b.Min.Y)
+	for _, poly := range polys {
+		if len(poly.pts) < 3 {
+			continue
+		}
+		z.MoveTo(float32(poly.pts[0].x-ox), float32(poly.pts[0].y-oy))
+		for _, p := range poly.pts[1:] {
+			z.LineTo(float32(p.x-ox), float32(p.y-oy))
+		}
+		z.ClosePath()
+	}
+	mask := image.NewAlpha(b)
+	z.Draw(mask, b, image.Opaque, image.Point{})
+	return mask
+}
+
+// evenOddCoverage fills polys with the even-odd rule: each polygon is
+// filled alone and their coverages combined as an exclusive or.
+func evenOddCoverage(polys []svgPolygon, clip image.Rectangle) *image.Alpha {
+	if len(polys) == 1 {
+		return svgCoverage(polys, clip)
+	}
+	var masks []*image.Alpha
+	var b image.Rectangle
+	for _, poly := range polys {
+		if m := svgCoverage([]svgPolygon{poly}, clip); m != nil {
+			masks = append(masks, m)
+			b = b.Union(m.Rect)
+		}
+	}
+	if len(masks) == 0 {
+		return nil
+	}
+	out := image.NewAlpha(b)
+	for _, m := range masks {
+		for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				c := float64(m.Pix[m.PixOffset(x, y)]) / 255
+				if c == 0 {
+					continue
+				}
+				i := out.PixOffset(x, y)
+				a := float64(out.Pix[i]) / 255
+				out.Pix[i] = uint8(math.Round((a + c - 2*a*c) * 255))
+			}
+		}
+	}
+	return out
+}
+
+// strokePolygons outlines polys stroked with half-width hw. Every polygon
+// it returns winds the same way, so the nonzero fill of all of them is
+// their union.
+func strokePolygons(polys []svgPolygon, hw float64, lineCap, join string) []svgPolygon {
+	if hw <= 0 {
+		return nil
+	}
+	var out []svgPolygon
+	emit := func(pts ...svgPoint) {
+		if len(pts) < 3 {
+			return
+		}
+		var area float64
+		for i, p := range pts {
+			q := pts[(i+1)%len(pts)]
+			area += p.x*q.y - q.x*p.y
+		}
+		if area > 0 {
+			for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		out = append(out, svgPolygon{pts: pts, closed: true})
+	}
+	circle := func(c svgPoint) {
+		n := int(min(max(math.Ceil(math.Sqrt(16*math.Pi*hw)), 8), 200))
+		pts := make([]svgPoint, n)
+		for i := range pts {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+			pts[i] = svgPoint{c.x + hw*cos, c.y + hw*sin}
+		}
+		emit(pts...)
+	}
+	// dir is the unit direction from p to q, and its normal.
+	dir := func(p, q svgPoint) (d, n svgPoint) {
+		l := math.Hypot(q.x-p.x, q.y-p.y)
+		d = svgPoint{(q.x - p.x) / l, (q.y - p.y) / l}
+		return d, svgPoint{-d.y, d.x}
+	}
+	off := func(p, n svgPoint, s float64) svgPoint { return svgPoint{p.x + s*n.x, p.y + s*n.y} }
+
+	for _, poly := range polys {
+		pts := poly.pts
+		if len(pts) < 2 {
+			continue
+		}
+		segs := len(pts) - 1
+		if poly.closed {
+			segs = len(pts)
+		}
+		for i := range segs {
+			p, q := pts[i], pts[(i+1)%len(pts)]
+			_, n := dir(p, q)
+			emit(off(p, n, hw), off(q, n, hw), off(q, n, -hw), off(p, n, -hw))
+		}
+
+		// Joins, at every vertex of a closed path and the inner ones of
+		// an open path.
+		for i := range pts {
+			if !poly.closed && (i == 0 || i == len(pts)-1) {
+				continue
+			}
+			prev, v, next := pts[(i+len(pts)-1)%len(pts)], pts[i], pts[(i+1)%len(pts)]
+			d0, n0 := dir(prev, v)
+			d1, n1 := dir(v, next)
+			cross := d0.x*d1.y - d0.y*d1.x
+			if math.Abs(cross) < 1e-9 && d0.x*d1.x+d0.y*d1.y > 0 {
+				continue // straight on
+			}
+			if join == "round" {
+				circle(v)
+				continue
+			}
+			s := hw // the outer side, away from the turn
+			if cross > 0 {
+				s = -hw
+			}
+			a, b := off(v, n0, s), off(v, n1, s)
+			sum := svgPoint{n0.x + n1.x, n0.y + n1.y}
+			l2 := sum.x*sum.x + sum.y*sum.y
+			if join == "bevel" || l2 < 1e-12 || 2/math.Sqrt(l2) > svgMiterLimit {
+				emit(v, a, b)
+				continue
+			}
+			emit(v, a, off(v, svgPoint{sum.x * 2 / l2, sum.y * 2 / l2}, s), b)
+		}
+
+		if poly.closed {
+			continue
+		}
+		switch lineCap {
+		case "round":
+			circle(pts[0])
+			circle(pts[len(pts)-1])
+		case "square":
+			for _, end := range [][2]svgPoint{{pts[1], pts[0]}, {pts[len(pts)-2], pts[len(pts)-1]}} {
+				d, n := dir(end[0], end[1])
+				tip := off(end[1], d, hw)
+				emit(off(end[1], n, hw), off(tip, n, hw), off(tip, n, -hw), off(end[1], n, -hw))
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+	"testing"
+)
+
+// inkCentroidX is the x of the center of the ink in area of img, white
+// text on black, weighted by coverage.
+func inkCentroidX(img *image.RGBA, area image.Rectangle) float64 {
+	var sum, weight float64
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			v := float64(img.RGBAAt(x, y).G)
+			sum += (float64(x) + 0.5) * v
+			weight += v
+		}
+	}
+	return sum / weight
+}
+
+// TestCenteredTextStable renders center-aligned strings of symmetric
+// glyphs, one glyph longer each time, at whole and fractional font sizes,
+// and expects the ink's center to stay put: text placed at whole pixels
+// moved by up to a pixel as its width changed.
+func TestCenteredTextStable(t *testing.T) {
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []float64{24, 31.5, 40.25} {
+		for _, glyph := range []string{"I", "H", "O"} {
+			var centers []float64
+			for n := 3; n <= 10; n++ {
+				preset := &Preset{
+					Canvas:     Canvas{Width: 401, Height: 80},
+					Background: Background{Type: "color", Color: "#000000"},
+					Components: []Component{{
+						ID: "t", X: 0, Y: 0, Width: 1, Height: 1,
+						Style:    ComponentStyle{FontSize: size, TitleFontSize: size, LineHeight: 1.2, Color: "#ffffff", TextAlign: "center"},
+						Defaults: ComponentData{Title: strings.Repeat(glyph, n)},
+					}},
+				}
+				if err := preset.Normalize(); err != nil {
+					t.Fatal(err)
+				}
+				img, err := renderer.RenderPreset(preset, MergeData(preset, nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				centers = append(centers, inkCentroidX(img, img.Rect))
+			}
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, c := range centers {
+				lo, hi = min(lo, c), max(hi, c)
+			}
+			if hi-lo > 0.1 {
+				t.Errorf("%q at %gpx: ink centers %s spread over %.2fpx, want at most 0.1", glyph, size, formatCenters(centers), hi-lo)
+			}
+			if mid := (lo + hi) / 2; math.Abs(mid-200.5) > 1 {
+				t.Errorf("%q at %gpx: ink centered at %.2f, want the canvas center 200.5", glyph, size, mid)
+			}
+		}
+	}
+}
+
+func formatCenters(cs []float64) string {
+	s := make([]string, len(cs))
+	for i, c := range cs {
+		s[i] = fmt.Sprintf("%.2f", c)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,119 @@
+// tokens.go — Built-in {{_name}} placeholders in titles and items: a
+// render's sequence number, date, and seeded random serials.
+package template
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// tokenPattern matches {{_name}} and {{_name:arg}}.
+var tokenPattern = regexp.MustCompile(`\{\{_([a-z]+)(?::([^}]*))?\}\}`)
+
+// Defaults for tokens given without an argument.
+const (
+	DefaultTokenDate = "2006-01-02" // {{_date}}
+	DefaultTokenRand = 6            // {{_rand}}
+	maxTokenRand     = 64
+)
+
+// tokenAlphabet is what {{_rand:N}} draws from.
+const tokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Tokens are the values behind the built-in placeholders:
+//
+//	{{_seq}}          Seq
+//	{{_date:layout}}  Now in a Go time layout (default DefaultTokenDate)
+//	{{_rand:N}}       N random digits and capital letters (default 6)
+//	{{_uuid}}         a random version 4 UUID
+//
+// Random values depend only on Seed, Seq and the component they appear in,
+// drawn in order through its title and then its items. The same preset,
+// data and Tokens therefore always render the same text, and editing one
+// component does not change another's values.
+type Tokens struct {
+	Seq  int       // render number within a batch; 0 for a single render
+	Seed uint64    // seeds {{_rand}} and {{_uuid}}
+	Now  time.Time // time for {{_date}}; the zero value means time.Now()
+}
+
+// ExpandTokens replaces the built-in placeholders in the titles and item
+// text of components, as MergeData returns them. Unknown {{_name}}
+// placeholders are left in place and reported as warnings.
+func ExpandTokens(components []ResolvedComponent, t Tokens) []string {
+	now := t.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var warnings []string
+	for i := range components {
+		comp := &components[i]
+		h := fnv.New64a()
+		h.Write([]byte(comp.ID))
+		rng := rand.New(rand.NewPCG(t.Seed, h.Sum64()^uint64(t.Seq)))
+
+		expand := func(s string) string {
+			return tokenPattern.ReplaceAllStringFunc(s, func(tok string) string {
+				m := tokenPattern.FindStringSubmatch(tok)
+				val, err := tokenValue(m[1], m[2], t.Seq, now, rng)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("component %q: %s: %v — left as-is", comp.ID, tok, err))
+					return tok
+				}
+				return val
+			})
+		}
+
+		comp.Data.Title = expand(comp.Data.Title)
+		if comp.Data.Items != nil {
+			// Items may still share the preset defaults' slice.
+			items := make([]TextItem, len(comp.Data.Items))
+			for j, item := range comp.Data.Items {
+				item.Text = expand(item.Text)
+				items[j] = item
+			}
+			comp.Data.Items = items
+		}
+	}
+	return warnings
+}
+
+// tokenValue is the text for one placeholder.
+func tokenValue(name, arg string, seq int, now time.Time, rng *rand.Rand) (string, error) {
+	switch name {
+	case "seq":
+		return strconv.Itoa(seq), nil
+	case "date":
+		if arg == "" {
+			arg = DefaultTokenDate
+		}
+		return now.Format(arg), nil
+	case "rand":
+		n := DefaultTokenRand
+		if arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n < 1 || n > maxTokenRand {
+				return "", fmt.Errorf("length must be 1–%d", maxTokenRand)
+			}
+		}
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = tokenAlphabet[rng.IntN(len(tokenAlphabet))]
+		}
+		return string(b), nil
+	case "uuid":
+		var u [16]byte
+		for i := range u {
+			u[i] = byte(rng.Uint32())
+		}
+		u[6] = u[6]&0x0f | 0x40 // version 4
+		u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+	}
+	return "", fmt.Errorf("unknown placeholder")
+}
@@ -1,34 +1,166 @@
 // validator.go — Validate data.json against a preset's schema.
 package template
 
-import "fmt"
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
-// ValidateData checks that data.json references only known component IDs.
-// Returns warnings (never fatal errors) for graceful degradation.
-func ValidateData(data *DataSpec, preset *Preset) []string {
+// RequirableFields are the data fields a preset's schema can list in
+// SchemaComponent.Required.
+var RequirableFields = []string{"title", "items", "style", "visible"}
+
+// DataWarning is one finding from ValidateData.
+type DataWarning struct {
+	Level     string // SeverityWarning, or SeverityInfo for data the schema does not document
+	Component string // the component ID it concerns, "" for none
+	Message   string // complete, naming the component
+}
+
+func (w DataWarning) String() string { return w.Message }
+
+// ValidateData checks that data.json (including every locale overlay)
+// references only known component IDs, suggesting the closest known ID
+// for an unknown one, that the variants and style classes it selects
+// exist, listing those that do, that the active locale exists, and that it sets the fields the
+// preset's schema requires; nil data sets none. Fields a component's
+// schema entry does not document are reported at SeverityInfo, naming
+// those it does. Returns warnings (never fatal errors) for graceful
+// degradation.
+func ValidateData(data *DataSpec, preset *Preset) []DataWarning {
 	if data == nil {
-		return nil
+		return missingRequired(&DataSpec{}, preset)
 	}
 
 	// Build ID set from preset components.
 	known := make(map[string]struct{}, len(preset.Components))
-	for _, c := range preset.Components {
+	byID := make(map[string]*Component, len(preset.Components))
+	for i, c := range preset.Components {
 		known[c.ID] = struct{}{}
+		if _, dup := byID[c.ID]; !dup {
+			byID[c.ID] = &preset.Components[i]
+		}
 	}
 
-	var warnings []string
-	for id := range data.Components {
+	var warnings []DataWarning
+	add := func(level, comp, format string, args ...any) {
+		warnings = append(warnings, DataWarning{level, comp, fmt.Sprintf(format, args...)})
+	}
+	for _, id := range slices.Sorted(maps.Keys(data.Components)) {
 		if _, ok := known[id]; !ok {
-			warnings = append(warnings, fmt.Sprintf("data references unknown component %q — ignored", id))
+			add(SeverityWarning, id, "data references unknown component %q%s — ignored", id, suggestID(id, known))
+			continue
+		}
+		undocumentedFields(add, "data", id, data.Components[id], preset)
+		if msg := byID[id].unknownVariant(data.Components[id].Variant); msg != "" {
+			add(SeverityWarning, id, "data selects %s — ignored", msg)
+		}
+		for _, msg := range preset.unknownClasses(data.Components[id].Classes) {
+			add(SeverityWarning, id, "data selects %s for component %q — skipped", msg, id)
+		}
+	}
+
+	for _, name := range data.LocaleNames() {
+		components := data.Locales[name].Components
+		for _, id := range slices.Sorted(maps.Keys(components)) {
+			if _, ok := known[id]; !ok {
+				add(SeverityWarning, id, "locale %q references unknown component %q%s — ignored", name, id, suggestID(id, known))
+				continue
+			}
+			undocumentedFields(add, fmt.Sprintf("locale %q", name), id, components[id], preset)
+			if msg := byID[id].unknownVariant(components[id].Variant); msg != "" {
+				add(SeverityWarning, id, "locale %q selects %s — ignored", name, msg)
+			}
+			for _, msg := range preset.unknownClasses(components[id].Classes) {
+				add(SeverityWarning, id, "locale %q selects %s for component %q — skipped", name, msg, id)
+			}
 		}
 	}
 
+	if data.Locale != "" {
+		if _, ok := data.Locales[data.Locale]; !ok {
+			add(SeverityWarning, "", "locale %q not defined in data — using base values", data.Locale)
+		}
+	}
+
+	return append(warnings, missingRequired(data, preset)...)
+}
+
+// suggestID is " (did you mean %q?)" naming the known ID closest to id,
+// or "" if none is within a third of its length of edits.
+func suggestID(id string, known map[string]struct{}) string {
+	near := nearestName(id, slices.Sorted(maps.Keys(known)))
+	if near == "" || editDistance(id, near) > max(len(id)/3, 1) {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", near)
+}
+
+// undocumentedFields reports the fields set reaches that the component's
+// schema entry does not list, when it lists any.
+func undocumentedFields(add func(level, comp, format string, args ...any), source, id string, set ComponentData, preset *Preset) {
+	documented := preset.Schema.Components[id].Fields
+	if len(documented) == 0 {
+		return
+	}
+	for _, field := range RequirableFields {
+		if _, ok := documented[field]; ok || !fieldSet(set, field) {
+			continue
+		}
+		add(SeverityInfo, id, "%s sets field %q of component %q, which its schema does not document (documented: %s)",
+			source, field, id, strings.Join(slices.Sorted(maps.Keys(documented)), ", "))
+	}
+}
+
+// missingRequired reports each field a component's schema entry requires
+// that neither data's base values nor its active locale set. A component
+// the data hides needs none.
+func missingRequired(data *DataSpec, preset *Preset) []DataWarning {
+	var warnings []DataWarning
+	for _, c := range preset.Components {
+		required := preset.Schema.Components[c.ID].Required
+		if len(required) == 0 {
+			continue
+		}
+		var set ComponentData
+		mergeComponentData(&set, data.Components[c.ID])
+		if data.Locale != "" {
+			mergeLocaleData(&set, data.Locales[data.Locale].Components[c.ID])
+		}
+		if set.Visible != nil && !*set.Visible {
+			continue
+		}
+		for _, field := range required {
+			if !slices.Contains(RequirableFields, field) || fieldSet(set, field) {
+				continue
+			}
+			warnings = append(warnings, DataWarning{SeverityWarning, c.ID, fmt.Sprintf("missing required field %q of component %q — the preset default is used", field, c.ID)})
+		}
+	}
 	return warnings
 }
 
+// fieldSet reports whether data sets field, one of RequirableFields.
+func fieldSet(data ComponentData, field string) bool {
+	switch field {
+	case "title":
+		return data.Title != ""
+	case "items":
+		return len(data.Items) > 0
+	case "style":
+		return data.Style != nil
+	case "visible":
+		return data.Visible != nil
+	}
+	return false
+}
+
 // FormatSchema returns a human-readable description of the preset's schema.
 func FormatSchema(preset *Preset) string {
-	if preset.Schema.Description == "" && len(preset.Schema.Components) == 0 {
+	variants := slices.ContainsFunc(preset.Components, func(c Component) bool { return len(c.Variants) > 0 })
+	if preset.Schema.Description == "" && len(preset.Schema.Components) == 0 && !variants {
 		return "This preset has no schema documentation.\n"
 	}
 
@@ -46,10 +178,56 @@ func FormatSchema(preset *Preset) string {
 	s += "Components:\n"
 	for id, sc := range preset.Schema.Components {
 		s += fmt.Sprintf("\n  [%s] %s\n", id, sc.Description)
+		required := requiredFields(sc)
+		i := slices.IndexFunc(preset.Components, func(c Component) bool { return c.ID == id })
 		for field, desc := range sc.Fields {
+			if field == "variant" && i >= 0 && len(preset.Components[i].Variants) > 0 {
+				continue // with the variants, below
+			}
+			if slices.Contains(required, field) {
+				desc += " (required)"
+			}
 			s += fmt.Sprintf("    %-12s %s\n", field+":", desc)
 		}
+		for _, field := range required {
+			if _, ok := sc.Fields[field]; !ok {
+				s += fmt.Sprintf("    %-12s (required)\n", field+":")
+			}
+		}
+		_, titled := sc.Fields["title"]
+		for _, c := range preset.Components {
+			if c.ID == id && (titled || c.Defaults.Title != "") {
+				st := &c.Style
+				s += fmt.Sprintf("    %-12s %.4gpx %s, %.4gpx gap (style.titleFontSize, titleColor, titleSpacing)\n",
+					"title style:", st.titleSize(), st.titleColor(), st.titleSpacing())
+				break
+			}
+		}
+		if i >= 0 {
+			s += formatVariants(&preset.Components[i], sc.Fields["variant"])
+		}
+	}
+	for _, c := range preset.Components {
+		if _, documented := preset.Schema.Components[c.ID]; !documented && len(c.Variants) > 0 {
+			s += fmt.Sprintf("\n  [%s]\n", c.ID) + formatVariants(&c, "")
+		}
 	}
 
 	return s
 }
+
+// formatVariants is FormatSchema's line listing c's variants, after the
+// schema's description of the field, if c has any.
+func formatVariants(c *Component, desc string) string {
+	if len(c.Variants) == 0 {
+		return ""
+	}
+	if desc != "" {
+		desc += "; "
+	}
+	line := fmt.Sprintf("    %-12s %sone of %s", "variant:", desc, strings.Join(c.VariantNames(), ", "))
+	if c.Defaults.Variant != "" {
+		line += fmt.Sprintf(" (default %s)", c.Defaults.Variant)
+	}
+	return line + "\n"
+}
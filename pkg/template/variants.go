@@ -0,0 +1,60 @@
+// variants.go — Named style variants data picks by name, so data can
+// switch a component between approved looks without knowing file paths
+// or asset IDs.
+//
+//	"variants": {
+//	  "logo_dark":  {"backgroundImage": "assets/logo-dark.png"},
+//	  "logo_light": {"backgroundImage": "assets/logo-light.png", "backgroundFit": "contain"}
+//	}
+//
+// data.json selects one with "variant": "logo_dark"; defaults.variant sets
+// the one used when data does not. The variant's style is applied after
+// the component's own style, defaults.style and responsive overrides, and
+// before data's style, which still wins. An unknown name is skipped, as if
+// it were not set.
+package template
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// VariantNames returns the names of c's variants, sorted.
+func (c *Component) VariantNames() []string {
+	return slices.Sorted(maps.Keys(c.Variants))
+}
+
+// selectedVariant is the variant the active locale's overlay selects, else
+// data, else the component's defaults, skipping names c does not define;
+// "" when none is left.
+func (c *Component) selectedVariant(override, localized ComponentData) string {
+	for _, name := range []string{localized.Variant, override.Variant, c.Defaults.Variant} {
+		if _, ok := c.Variants[name]; ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// withVariant returns c with the style of its variant name applied; c is
+// returned unchanged when it has no such variant.
+func (c Component) withVariant(name string) Component {
+	if style, ok := c.Variants[name]; ok {
+		c.overrideStyle(style)
+	}
+	return c
+}
+
+// unknownVariant describes why c cannot apply variant name, or returns ""
+// when it can.
+func (c *Component) unknownVariant(name string) string {
+	if _, ok := c.Variants[name]; ok || name == "" {
+		return ""
+	}
+	if len(c.Variants) == 0 {
+		return fmt.Sprintf("unknown variant %q: component %q has no variants", name, c.ID)
+	}
+	return fmt.Sprintf("unknown variant %q of component %q (available: %s)", name, c.ID, strings.Join(c.VariantNames(), ", "))
+}